@@ -1,14 +1,37 @@
 package cleaner
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"strconv"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"text/tabwriter"
 
+	"unifi-port-forward/pkg/cleanup"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/manifests"
 	"unifi-port-forward/pkg/routers"
+
+	"github.com/filipowm/go-unifi/unifi"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Output formats
+const (
+	OutputText = "text"
+	OutputJSON = "json"
 )
 
+// ErrAborted is returned when the deletion was not confirmed
+var ErrAborted = errors.New("clean aborted, no rules deleted")
+
 // Config holds cleaner configuration
 type Config struct {
 	Host     string
@@ -16,59 +39,211 @@ type Config struct {
 	Password string
 	Site     string
 	APIKey   string
+
+	// Controller is the controller configuration orphans are detected with
+	Controller *config.Config
+
+	// Mappings select rules by forward port and destination IP, keyed by forward port
+	Mappings map[string]string
+
+	// NamePrefix and NameRegex select rules by name
+	NamePrefix string
+	NameRegex  string
+
+	// ManagedOnly selects rules named like the controller names its rules
+	ManagedOnly bool
+
+	// DestinationCIDRs select rules forwarding into one of the networks
+	DestinationCIDRs []string
+
+	// Ports select rules by external port or port range such as 8000-8100
+	Ports []string
+
+	// Orphans selects managed rules whose Service or rule no longer exists in the cluster
+	Orphans bool
+
+	// DryRun reports the rules that would be deleted without deleting them
+	DryRun bool
+
+	// Yes deletes without asking for confirmation
+	Yes bool
+
+	// Output is the output format of the results, "text" or "json"
+	Output string
+}
+
+// report is the JSON output of a clean
+type report struct {
+	DryRun  bool             `json:"dryRun"`
+	Results []cleanup.Result `json:"results"`
 }
 
-// Run executes cleaner with given configuration and port mappings
-func Run(cfg Config, portMaps map[string]string) error {
-	// Validate port maps is not empty
-	if len(portMaps) == 0 {
-		return fmt.Errorf("port mappings cannot be empty")
+// Run deletes the router rules passing every given selector, after showing them and asking
+// for confirmation on in unless cfg.Yes is set. Results are written to out, the confirmation
+// prompt to log.
+func Run(ctx context.Context, cfg Config, in io.Reader, out, log io.Writer) error {
+	if cfg.Output != OutputText && cfg.Output != OutputJSON {
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", cfg.Output, OutputText, OutputJSON)
+	}
+
+	selector, err := buildSelector(cfg)
+	if err != nil {
+		return err
+	}
+	if selector.Empty() && !cfg.Orphans {
+		return fmt.Errorf("no rules selected: use --port-mappings, --name-prefix, --name-regex, --managed-only, --dest-cidr, --port or --orphans")
 	}
 
-	// Create router
 	router, err := routers.CreateUnifiRouter(cfg.Host, cfg.Username, cfg.Password, cfg.Site, cfg.APIKey)
 	if err != nil {
 		return fmt.Errorf("failed to create router: %w", err)
 	}
-
-	// List all port forwards
-	portforwards, err := router.ListAllPortForwards(context.Background())
+	routerRules, err := router.ListAllPortForwards(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list port forward rules: %w", err)
 	}
 
-	// Delete each rule
-	for dstPort, dstIP := range portMaps {
-		for _, pf := range portforwards {
-			fmt.Printf("Checking rule: %+v\n", pf)
-			if pf.FwdPort == dstPort && pf.Fwd == dstIP {
-				fmt.Println("port matched")
-
-				// Convert external port from string to int
-				extPort, err := strconv.Atoi(pf.DstPort)
-				if err != nil {
-					log.Printf("failed to parse external port %s: %v", pf.DstPort, err)
-					continue
-				}
-
-				// Construct PortConfig for removal
-				portConfig := routers.PortConfig{
-					Name:     pf.Name,
-					Enabled:  pf.Enabled,
-					DstPort:  extPort, // External port (what users connect to)
-					Protocol: pf.Proto,
-					DstIP:    pf.Fwd,
-				}
-
-				err = router.RemovePort(context.Background(), portConfig)
-				if err != nil {
-					log.Printf("failed to delete port-forward rule %s: %v", pf.ID, err)
-				} else {
-					log.Printf("deleted port-forward rule %s successfully", pf.ID)
-				}
-			}
+	if cfg.Orphans {
+		selector.IDs, err = findOrphans(ctx, cfg.Controller, routerRules)
+		if err != nil {
+			return err
 		}
 	}
 
+	selected := cleanup.Select(routerRules, selector)
+	if len(selected) > 0 && !cfg.DryRun && !cfg.Yes {
+		confirmed, err := confirm(in, log, selected, cfg.Site)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return ErrAborted
+		}
+	}
+
+	results := cleanup.Delete(ctx, router, selected, cfg.DryRun)
+
+	if cfg.Output == OutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report{DryRun: cfg.DryRun, Results: results}); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+	} else {
+		writeText(out, results, cfg.DryRun)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Action == cleanup.ActionFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rules could not be deleted", failed, len(results))
+	}
 	return nil
 }
+
+// buildSelector parses the selection flags
+func buildSelector(cfg Config) (cleanup.Selector, error) {
+	selector := cleanup.Selector{
+		Mappings:    cfg.Mappings,
+		NamePrefix:  cfg.NamePrefix,
+		ManagedOnly: cfg.ManagedOnly,
+	}
+	if cfg.NameRegex != "" {
+		re, err := regexp.Compile(cfg.NameRegex)
+		if err != nil {
+			return selector, fmt.Errorf("invalid name regex: %w", err)
+		}
+		selector.Name = re
+	}
+	for _, cidr := range cfg.DestinationCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return selector, fmt.Errorf("invalid destination CIDR: %w", err)
+		}
+		selector.DestinationCIDRs = append(selector.DestinationCIDRs, network)
+	}
+	for _, value := range cfg.Ports {
+		portRange, err := cleanup.ParsePortRange(value)
+		if err != nil {
+			return selector, err
+		}
+		selector.Ports = append(selector.Ports, portRange)
+	}
+	return selector, nil
+}
+
+// findOrphans returns the IDs of the managed router rules whose owner is gone from the cluster
+func findOrphans(ctx context.Context, controllerConfig *config.Config, routerRules []*unifi.PortForward) (map[string]bool, error) {
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build scheme: %w", err)
+	}
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	cluster, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	reporter := &controller.StatusReporter{Client: cluster, Config: controllerConfig}
+	statuses, err := reporter.Status(ctx, routerRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rule owners: %w", err)
+	}
+	return cleanup.Orphans(statuses), nil
+}
+
+// confirm shows the selected rules on log and asks whether to delete them
+func confirm(in io.Reader, log io.Writer, rules []*unifi.PortForward, site string) (bool, error) {
+	fmt.Fprintf(log, "The following %d rules will be deleted from site %s:\n\n", len(rules), site)
+	w := tabwriter.NewWriter(log, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPORT\tPROTOCOL\tDESTINATION")
+	for _, rule := range rules {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s:%s\n", rule.ID, rule.Name, rule.DstPort, rule.Proto, rule.Fwd, rule.FwdPort)
+	}
+	if err := w.Flush(); err != nil {
+		return false, err
+	}
+
+	fmt.Fprintf(log, "\nDelete %d rules? [y/N]: ", len(rules))
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// writeText writes a line per rule followed by a summary
+func writeText(out io.Writer, results []cleanup.Result, dryRun bool) {
+	deleted, failed := 0, 0
+	for _, result := range results {
+		if result.Action == cleanup.ActionFail {
+			failed++
+		} else {
+			deleted++
+		}
+
+		fmt.Fprintf(out, "%-6s %s/%s %q -> %s:%s", result.Action, result.DstPort, result.Protocol, result.Name,
+			result.Destination, result.ForwardPort)
+		if result.Reason != "" {
+			fmt.Fprintf(out, ": %s", result.Reason)
+		}
+		fmt.Fprintln(out)
+	}
+
+	if len(results) > 0 {
+		fmt.Fprintln(out)
+	}
+	if dryRun {
+		fmt.Fprintf(out, "Dry run: %d rules would be deleted.\n", deleted)
+		return
+	}
+	fmt.Fprintf(out, "Clean: %d deleted, %d failed.\n", deleted, failed)
+}
@@ -0,0 +1,156 @@
+package diagnoser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/doctor"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/manifests"
+
+	"github.com/filipowm/go-unifi/unifi"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Output formats
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// CheckKubeconfig is the name of the check loading the kubeconfig of the cluster checks
+const CheckKubeconfig = "kubeconfig"
+
+// Config holds doctor configuration
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	Site     string
+	APIKey   string
+
+	// ReadOnly skips creating and deleting a disabled test rule on the router
+	ReadOnly bool
+
+	// SkipCluster skips the cluster checks, for checking router credentials before installing
+	SkipCluster bool
+
+	// Output is the output format of the checks, "text" or "json"
+	Output string
+}
+
+// report is the JSON output of the checks
+type report struct {
+	Checks []doctor.Check `json:"checks"`
+}
+
+// Run checks the router and the cluster and writes the checks to out. It returns an error when
+// any check failed.
+func Run(ctx context.Context, cfg Config, out io.Writer) error {
+	if cfg.Output != OutputText && cfg.Output != OutputJSON {
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", cfg.Output, OutputText, OutputJSON)
+	}
+
+	routerDoctor := &doctor.RouterDoctor{
+		Config: doctor.RouterConfig{
+			URL:      cfg.Host,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			APIKey:   cfg.APIKey,
+			Site:     cfg.Site,
+		},
+		ReadOnly: cfg.ReadOnly,
+	}
+	checks, routerRules := routerDoctor.Run(ctx)
+
+	if !cfg.SkipCluster {
+		clusterChecks, err := runCluster(ctx, routerRules)
+		if err != nil {
+			clusterChecks = []doctor.Check{{
+				Name:    CheckKubeconfig,
+				Status:  doctor.StatusFail,
+				Message: err.Error(),
+				Hint:    "Set KUBECONFIG or the current context to the cluster of the controller, or pass --skip-cluster to check the router only",
+			}}
+		}
+		checks = append(checks, clusterChecks...)
+	}
+
+	if cfg.Output == OutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report{Checks: checks}); err != nil {
+			return fmt.Errorf("failed to write checks: %w", err)
+		}
+	} else {
+		writeText(out, checks)
+	}
+
+	if failed := doctor.Failed(checks); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// runCluster runs the cluster checks with the current kubeconfig context
+func runCluster(ctx context.Context, routerRules []*unifi.PortForward) ([]doctor.Check, error) {
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build scheme: %w", err)
+	}
+	if err := authorizationv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to build scheme: %w", err)
+	}
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	cluster, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	clusterDoctor := &doctor.ClusterDoctor{
+		Client: cluster,
+		CRDAvailable: func(ctx context.Context, crdName string) bool {
+			if crdName == config.ClusterPortForwardRulesCRDName {
+				return helpers.IsClusterPortForwardRuleCRDAvailable(ctx, restConfig, scheme)
+			}
+			return helpers.IsPortForwardRuleCRDAvailable(ctx, restConfig, scheme)
+		},
+	}
+	return clusterDoctor.Run(ctx, routerRules), nil
+}
+
+// writeText writes a line per check, its hint below failed and warned checks, and a summary
+func writeText(out io.Writer, checks []doctor.Check) {
+	counts := make(map[doctor.Status]int)
+	for _, check := range checks {
+		counts[check.Status]++
+		fmt.Fprintf(out, "[%-4s] %s: %s\n", statusLabel(check.Status), check.Name, check.Message)
+		if check.Hint != "" && (check.Status == doctor.StatusFail || check.Status == doctor.StatusWarn) {
+			fmt.Fprintf(out, "       hint: %s\n", check.Hint)
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d passed, %d warnings, %d failed, %d skipped.\n",
+		counts[doctor.StatusPass], counts[doctor.StatusWarn], counts[doctor.StatusFail], counts[doctor.StatusSkip])
+}
+
+func statusLabel(status doctor.Status) string {
+	switch status {
+	case doctor.StatusPass:
+		return "PASS"
+	case doctor.StatusWarn:
+		return "WARN"
+	case doctor.StatusFail:
+		return "FAIL"
+	default:
+		return "SKIP"
+	}
+}
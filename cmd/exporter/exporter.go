@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"unifi-port-forward/pkg/backup"
+	"unifi-port-forward/pkg/routers"
+)
+
+// Config holds exporter configuration
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	Site     string
+	APIKey   string
+
+	// File receives the backup; without it the backup is written to out
+	File string
+
+	// Format is the backup format, "yaml" or "json"
+	Format string
+}
+
+// Run writes a backup of every port forward rule on the site to File or out. Progress is
+// reported to log.
+func Run(ctx context.Context, cfg Config, out, log io.Writer) error {
+	if cfg.Format != backup.FormatYAML && cfg.Format != backup.FormatJSON {
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", cfg.Format, backup.FormatYAML, backup.FormatJSON)
+	}
+
+	router, err := routers.CreateUnifiRouter(cfg.Host, cfg.Username, cfg.Password, cfg.Site, cfg.APIKey)
+	if err != nil {
+		return fmt.Errorf("failed to create router: %w", err)
+	}
+	rules, err := router.ListAllPortForwards(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+
+	snapshot := backup.New(rules, cfg.Host, cfg.Site, time.Now())
+
+	if cfg.File == "" {
+		return backup.Encode(out, snapshot, cfg.Format)
+	}
+
+	// Backups name every rule and destination on the site, so they are kept private
+	file, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", cfg.File, err)
+	}
+	if err := backup.Encode(file, snapshot, cfg.Format); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", cfg.File, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", cfg.File, err)
+	}
+
+	fmt.Fprintf(log, "exported %d port forward rules to %s\n", len(snapshot.Rules), cfg.File)
+	return nil
+}
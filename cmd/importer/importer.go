@@ -0,0 +1,212 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/pkg/routers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds importer configuration
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	Site     string
+	APIKey   string
+
+	// Namespace is the namespace of rules that do not forward to a Service
+	Namespace string
+
+	// Ports, NameRegex and Subnet select the router rules to import
+	Ports     []int
+	NameRegex string
+	Subnet    string
+
+	// Offline maps every rule to a destination IP without reading Services from the cluster
+	Offline bool
+
+	// OutputDir receives one manifest per rule; without it the manifests are written to out
+	OutputDir string
+
+	// Rename renames the router rules to the names the controller gives them
+	Rename bool
+}
+
+// manifest is the part of a PortForwardRule written by the importer
+type manifest struct {
+	APIVersion string                       `json:"apiVersion"`
+	Kind       string                       `json:"kind"`
+	Metadata   manifestMetadata             `json:"metadata"`
+	Spec       v1alpha1.PortForwardRuleSpec `json:"spec"`
+}
+
+type manifestMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// Run imports the selected router rules as PortForwardRule manifests, writing them to out or
+// OutputDir. Progress and skipped rules are reported to log.
+func Run(ctx context.Context, cfg Config, out, log io.Writer) error {
+	filter := controller.ImportFilter{Ports: cfg.Ports}
+	if cfg.NameRegex != "" {
+		re, err := regexp.Compile(cfg.NameRegex)
+		if err != nil {
+			return fmt.Errorf("invalid name regex: %w", err)
+		}
+		filter.Name = re
+	}
+	if cfg.Subnet != "" {
+		_, subnet, err := net.ParseCIDR(cfg.Subnet)
+		if err != nil {
+			return fmt.Errorf("invalid subnet: %w", err)
+		}
+		filter.Subnet = subnet
+	}
+
+	var services []corev1.Service
+	var existing []v1alpha1.PortForwardRule
+	if !cfg.Offline {
+		var err error
+		services, existing, err = readCluster(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	router, err := routers.CreateUnifiRouter(cfg.Host, cfg.Username, cfg.Password, cfg.Site, cfg.APIKey)
+	if err != nil {
+		return fmt.Errorf("failed to create router: %w", err)
+	}
+	routerRules, err := router.ListAllPortForwards(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+
+	imported, skipped := controller.ImportRouterRules(routerRules, services, existing, cfg.Namespace, filter)
+	for _, skip := range skipped {
+		fmt.Fprintf(log, "skipped router rule %q (%s/%s): %s\n", skip.RouterRule.Name, skip.RouterRule.DstPort, skip.RouterRule.Proto, skip.Reason)
+	}
+	if len(imported) == 0 {
+		fmt.Fprintln(log, "no router rules to import")
+		return nil
+	}
+
+	if err := writeManifests(cfg.OutputDir, imported, out, log); err != nil {
+		return err
+	}
+
+	if !cfg.Rename {
+		return nil
+	}
+	failed := 0
+	for _, rule := range imported {
+		if err := router.UpdatePortByID(ctx, rule.RouterRule.ID, rule.AdoptedConfig()); err != nil {
+			fmt.Fprintf(log, "failed to rename router rule %q to %q: %v\n", rule.RouterRule.Name, rule.RouterName, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(log, "renamed router rule %q to %q\n", rule.RouterRule.Name, rule.RouterName)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d router rules could not be renamed", failed, len(imported))
+	}
+	return nil
+}
+
+// readCluster returns the Services whose LoadBalancer IPs rules can be matched to and the
+// existing PortForwardRules whose names imported rules must not take
+func readCluster(ctx context.Context) ([]corev1.Service, []v1alpha1.PortForwardRule, error) {
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build scheme: %w", err)
+	}
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	cluster, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	var services corev1.ServiceList
+	if err := cluster.List(ctx, &services); err != nil {
+		return nil, nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	var rules v1alpha1.PortForwardRuleList
+	if err := cluster.List(ctx, &rules); err != nil && !meta.IsNoMatchError(err) {
+		return nil, nil, fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+
+	return services.Items, rules.Items, nil
+}
+
+// writeManifests writes a manifest per imported rule to dir, or all of them to out as a
+// multi-document YAML stream when dir is empty
+func writeManifests(dir string, imported []controller.ImportedRule, out, log io.Writer) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	for i, rule := range imported {
+		data, err := renderManifest(rule)
+		if err != nil {
+			return err
+		}
+
+		if dir == "" {
+			if i > 0 {
+				fmt.Fprintln(out, "---")
+			}
+			if _, err := out.Write(data); err != nil {
+				return err
+			}
+			continue
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%s-%s.yaml", rule.Rule.Namespace, rule.Rule.Name))
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Fprintf(log, "wrote %s\n", path)
+	}
+	return nil
+}
+
+// renderManifest renders an imported rule as YAML, headed by the router rule it came from
+func renderManifest(rule controller.ImportedRule) ([]byte, error) {
+	data, err := yaml.Marshal(manifest{
+		APIVersion: rule.Rule.APIVersion,
+		Kind:       rule.Rule.Kind,
+		Metadata:   manifestMetadata{Name: rule.Rule.Name, Namespace: rule.Rule.Namespace},
+		Spec:       rule.Rule.Spec,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s/%s: %w", rule.Rule.Namespace, rule.Rule.Name, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Router rule %s %q\n", rule.RouterRule.ID, rule.RouterRule.Name)
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
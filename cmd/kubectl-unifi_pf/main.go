@@ -0,0 +1,335 @@
+// Command kubectl-unifi_pf is a kubectl plugin, run as kubectl unifi-pf, giving users without
+// router credentials a view of the router and control over their own port forwards. Cluster
+// data is read and written with the user's kubeconfig; router state comes from the read-only
+// state API of the controller.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"unifi-port-forward/cmd/reporter"
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/pkg/plugin"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	kubeconfig     string
+	kubeContext    string
+	token          string
+	namespace      string
+	stateNamespace string
+	stateService   string
+	stateAccount   string
+	stateURL       string
+	stateCAFile    string
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "kubectl-unifi_pf [command]",
+	Short: "Inspect and toggle UniFi port forwards without router credentials",
+	Long: `Inspect the port forward rules on the UniFi router and expose, enable and disable the
+port forwards of your Services and rules. Router state is read from the state API of the
+unifi-port-forward controller through the API server, so no router credentials are needed.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	// kubectl runs the plugin as kubectl unifi-pf
+	Annotations: map[string]string{cobra.CommandDisplayNameAnnotation: "kubectl unifi-pf"},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file (env: KUBECONFIG)")
+	rootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Kubeconfig context to use")
+	rootCmd.PersistentFlags().StringVar(&token, "token", "", "Bearer token to authenticate to the API server with")
+	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the Service to expose and of the owners to list (default: the namespace of the kubeconfig context)")
+	rootCmd.PersistentFlags().StringVar(&stateNamespace, "state-namespace", plugin.DefaultStateNamespace, "Namespace of the controller's state API Service")
+	rootCmd.PersistentFlags().StringVar(&stateService, "state-service", plugin.DefaultStateService, "State API Service, as scheme:name:port")
+	rootCmd.PersistentFlags().StringVar(&stateAccount, "state-service-account", plugin.DefaultStateServiceAccount, "ServiceAccount in --state-namespace to request state API tokens for")
+	rootCmd.PersistentFlags().StringVar(&stateURL, "state-url", "", "URL of the state API, bypassing the API server proxy (for example https://localhost:8085 with kubectl port-forward)")
+	rootCmd.PersistentFlags().StringVar(&stateCAFile, "state-ca-file", "", "CA certificate to verify the state API with at --state-url, which must be issued for the state API Service")
+
+	listCmd.Flags().StringSlice("owner", nil, "Only list rules with these owner kinds: Service, PortForwardRule, ClusterPortForwardRule, manual or orphaned")
+	listCmd.Flags().Bool("drifted", false, "Only list rules that drifted from their desired state")
+	listCmd.Flags().StringP("output", "o", "", "Output format: wide, json or yaml (default: table)")
+	listCmd.Flags().BoolP("all-namespaces", "A", false, "List rules of every namespace, even with --namespace set")
+	driftCmd.Flags().StringP("output", "o", "wide", "Output format: wide, json or yaml")
+
+	rootCmd.AddCommand(exposeCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(describeCmd)
+	rootCmd.AddCommand(disableCmd)
+	rootCmd.AddCommand(enableCmd)
+	rootCmd.AddCommand(whoOwnsCmd)
+	rootCmd.AddCommand(driftCmd)
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+var exposeCmd = &cobra.Command{
+	Use:   "expose svc/NAME MAPPING...",
+	Short: "Forward router ports to a Service",
+	Long: `Add port mappings such as 8080:http (external port 8080 to the Service port named http)
+or https (the Service port number as external port) to the mapping annotation of a Service. A
+mapping replaces an existing one for the same Service port.`,
+	Example: `  kubectl unifi-pf expose svc/web 8080:http
+  kubectl unifi-pf expose svc/web 8443:https -n apps`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := plugin.ParseServiceRef(args[0])
+		if err != nil {
+			return err
+		}
+		kubeClient, ns, err := newKubeClient()
+		if err != nil {
+			return err
+		}
+
+		value, err := plugin.Expose(cmd.Context(), kubeClient, types.NamespacedName{Namespace: ns, Name: name}, args[1:])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "service/%s exposed: %s\n", name, value)
+		return nil
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List router rules with their owners and drift",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		owners, _ := cmd.Flags().GetStringSlice("owner")
+		drifted, _ := cmd.Flags().GetBool("drifted")
+		output, _ := cmd.Flags().GetString("output")
+		allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
+
+		filter := controller.StatusFilter{OwnerKinds: owners, DriftedOnly: drifted}
+		if cmd.Flags().Changed("namespace") && !allNamespaces {
+			filter.Namespace = namespace
+		}
+		statuses, err := routerRules(cmd, filter)
+		if err != nil {
+			return err
+		}
+		return reporter.Write(cmd.OutOrStdout(), statuses, output)
+	},
+}
+
+var describeCmd = &cobra.Command{
+	Use:   "describe PORT",
+	Short: "Show the router rules on an external port in detail",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		statuses, err := portRules(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		return plugin.Describe(cmd.OutOrStdout(), statuses)
+	},
+}
+
+var disableCmd = &cobra.Command{
+	Use:   "disable PORT",
+	Short: "Disable the port forward on an external port",
+	Long: `Disable the port forward on an external port by changing the Service or rule owning it.
+The router rule is kept, disabled, until it is enabled again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setEnabled(cmd, args[0], false)
+	},
+}
+
+var enableCmd = &cobra.Command{
+	Use:   "enable PORT",
+	Short: "Enable the port forward on an external port",
+	Long:  `Enable a port forward disabled with disable, by changing the Service or rule owning it.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setEnabled(cmd, args[0], true)
+	},
+}
+
+var whoOwnsCmd = &cobra.Command{
+	Use:   "who-owns PORT",
+	Short: "Show the Service or rule owning the router rules on an external port",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		statuses, err := portRules(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		plugin.WhoOwns(cmd.OutOrStdout(), statuses)
+		return nil
+	},
+}
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "List router rules that drifted from their desired state",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+
+		statuses, err := routerRules(cmd, controller.StatusFilter{DriftedOnly: true})
+		if err != nil {
+			return err
+		}
+		if len(statuses) == 0 && output == reporter.OutputWide {
+			fmt.Fprintln(cmd.OutOrStdout(), "No drift: every managed router rule matches its desired state")
+			return nil
+		}
+		return reporter.Write(cmd.OutOrStdout(), statuses, output)
+	},
+}
+
+// setEnabled enables or disables the port forwards on an external port through their owners
+func setEnabled(cmd *cobra.Command, arg string, enabled bool) error {
+	port, err := plugin.ParsePort(arg)
+	if err != nil {
+		return err
+	}
+	statuses, err := portRules(cmd, arg)
+	if err != nil {
+		return err
+	}
+	kubeClient, _, err := newKubeClient()
+	if err != nil {
+		return err
+	}
+
+	action := "disabled"
+	if enabled {
+		action = "enabled"
+	}
+
+	// Rules for tcp and udp on one port usually share an owner
+	done := make(map[string]bool)
+	for _, status := range statuses {
+		owner := status.OwnerKind + " " + status.Owner
+		if done[owner] {
+			continue
+		}
+		done[owner] = true
+
+		changed, err := plugin.SetEnabled(cmd.Context(), kubeClient, status.OwnerKind, status.Owner, port, enabled)
+		if err != nil {
+			return err
+		}
+		if changed {
+			fmt.Fprintf(cmd.OutOrStdout(), "port %d %s on %s\n", port, action, owner)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "port %d already %s on %s\n", port, action, owner)
+		}
+	}
+	return nil
+}
+
+// portRules returns the router rules on the external port arg, failing when there are none
+func portRules(cmd *cobra.Command, arg string) ([]controller.RouterRuleStatus, error) {
+	port, err := plugin.ParsePort(arg)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := routerRules(cmd, controller.StatusFilter{Ports: []int{port}})
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("no router rule forwards port %d", port)
+	}
+	return statuses, nil
+}
+
+// routerRules reads the router rules passing filter from the state API
+func routerRules(cmd *cobra.Command, filter controller.StatusFilter) ([]controller.RouterRuleStatus, error) {
+	restConfig, err := clientConfig().ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	stateToken, err := plugin.RequestStateToken(cmd.Context(), restConfig, stateNamespace, stateAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	var stateClient *plugin.StateClient
+	if stateURL != "" {
+		tlsConfig, err := stateTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		stateClient = plugin.NewStateClient(stateURL, stateToken, tlsConfig)
+	} else {
+		stateClient, err = plugin.NewProxyStateClient(restConfig, stateNamespace, stateService, stateToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stateClient.Rules(cmd.Context(), filter)
+}
+
+// stateTLSConfig verifies the state API at --state-url with the system roots, or with
+// --state-ca-file as the in-cluster name of the state API Service
+func stateTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if stateCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(stateCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state API CA: %w", err)
+	}
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificate found in %s", stateCAFile)
+	}
+	tlsConfig.ServerName = plugin.StateServerName(stateNamespace, stateService)
+	return tlsConfig, nil
+}
+
+// newKubeClient returns a client acting as the kubeconfig user and the namespace to work in
+func newKubeClient() (client.Client, string, error) {
+	loader := clientConfig()
+	restConfig, err := loader.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	ns, _, err := loader.Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build scheme: %w", err)
+	}
+	kubeClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create client: %w", err)
+	}
+	return kubeClient, ns, nil
+}
+
+// clientConfig loads the kubeconfig the way kubectl does, honoring the global flags
+func clientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	overrides.AuthInfo.Token = token
+	overrides.Context.Namespace = namespace
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
@@ -0,0 +1,106 @@
+package linter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"unifi-port-forward/pkg/lint"
+	"unifi-port-forward/pkg/manifests"
+)
+
+// Output formats
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputSARIF = "sarif"
+)
+
+// Config holds linter configuration
+type Config struct {
+	// Files are manifest files or directories to lint, "-" reads stdin
+	Files []string
+
+	// Namespace is the namespace of manifest objects that do not set one
+	Namespace string
+
+	// AllowedPorts and DeniedPorts are ports or port ranges, such as 8000-9000
+	AllowedPorts []string
+	DeniedPorts  []string
+
+	// Output is the output format of the findings, "text", "json" or "sarif"
+	Output string
+}
+
+// report is the JSON output of the findings
+type report struct {
+	Findings []lint.Finding `json:"findings"`
+}
+
+// Run lints the manifests and writes the findings to out. It returns an error when any finding
+// is an error; warnings alone pass.
+func Run(cfg Config, in io.Reader, out io.Writer) error {
+	if cfg.Output != OutputText && cfg.Output != OutputJSON && cfg.Output != OutputSARIF {
+		return fmt.Errorf("unsupported output format %q (expected %q, %q or %q)", cfg.Output, OutputText, OutputJSON, OutputSARIF)
+	}
+	if len(cfg.Files) == 0 {
+		return fmt.Errorf("no manifests given, pass files or directories with --filename or '-' for stdin")
+	}
+
+	allowed, err := lint.ParsePortRanges(cfg.AllowedPorts)
+	if err != nil {
+		return fmt.Errorf("invalid --allow-ports: %w", err)
+	}
+	denied, err := lint.ParsePortRanges(cfg.DeniedPorts)
+	if err != nil {
+		return fmt.Errorf("invalid --deny-ports: %w", err)
+	}
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		return fmt.Errorf("failed to build scheme: %w", err)
+	}
+	objects, err := manifests.Load(scheme, cfg.Files, in, cfg.Namespace)
+	if err != nil {
+		return err
+	}
+
+	findings := lint.Lint(objects, lint.Options{AllowedPorts: allowed, DeniedPorts: denied})
+
+	switch cfg.Output {
+	case OutputJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		if err := encoder.Encode(report{Findings: findings}); err != nil {
+			return fmt.Errorf("failed to write findings: %w", err)
+		}
+	case OutputSARIF:
+		if err := lint.WriteSARIF(out, findings); err != nil {
+			return fmt.Errorf("failed to write findings: %w", err)
+		}
+	default:
+		writeText(out, findings, len(objects))
+	}
+
+	if errors := lint.Errors(findings); errors > 0 {
+		return fmt.Errorf("%d errors found in %d objects", errors, len(objects))
+	}
+	return nil
+}
+
+// writeText writes a line per finding followed by a summary
+func writeText(out io.Writer, findings []lint.Finding, objects int) {
+	for _, finding := range findings {
+		fmt.Fprintf(out, "%s:%d: %s: %s [%s]: %s\n",
+			finding.Source, finding.Document, finding.Severity, finding.Object, finding.Rule, finding.Message)
+	}
+
+	if len(findings) > 0 {
+		fmt.Fprintln(out)
+	}
+	errors := lint.Errors(findings)
+	fmt.Fprintf(out, "%d objects checked: %d errors, %d warnings.\n", objects, errors, len(findings)-errors)
+}
@@ -0,0 +1,253 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/pkg/routers"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrChangesPending is returned when the plan contains changes, so callers can exit with a
+// distinct code
+var ErrChangesPending = errors.New("changes pending")
+
+// Output formats
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Config holds planner configuration
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	Site     string
+	APIKey   string
+
+	// Controller is the controller configuration the plan is computed for
+	Controller *config.Config
+
+	// Files are manifest files or directories whose objects replace those in the cluster
+	Files []string
+
+	// Namespace is the namespace of manifest objects that do not set one
+	Namespace string
+
+	// Offline plans the manifests alone, without reading the cluster
+	Offline bool
+
+	// Output is the output format, "text" or "json"
+	Output string
+}
+
+// Run computes the plan and writes it to out. It returns ErrChangesPending when the router
+// would be changed.
+func Run(ctx context.Context, cfg Config, out io.Writer) error {
+	if cfg.Output != OutputText && cfg.Output != OutputJSON {
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", cfg.Output, OutputText, OutputJSON)
+	}
+	if cfg.Offline && len(cfg.Files) == 0 {
+		return fmt.Errorf("--offline requires manifests to be given with --filename")
+	}
+
+	store, err := buildStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	router, err := routers.CreateUnifiRouter(cfg.Host, cfg.Username, cfg.Password, cfg.Site, cfg.APIKey)
+	if err != nil {
+		return fmt.Errorf("failed to create router: %w", err)
+	}
+	routerRules, err := router.ListAllPortForwards(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+
+	planner := &controller.Planner{Client: store, Config: cfg.Controller}
+	plan, err := planner.Plan(ctx, routerRules)
+	if err != nil {
+		return fmt.Errorf("failed to compute plan: %w", err)
+	}
+
+	if cfg.Output == OutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plan); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+	} else {
+		writeText(out, plan)
+	}
+
+	if plan.HasChanges() {
+		return ErrChangesPending
+	}
+	return nil
+}
+
+// buildStore collects the objects to plan: those in the cluster, unless offline, replaced by
+// the objects in the manifests
+func buildStore(ctx context.Context, cfg Config) (*manifests.Store, error) {
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build scheme: %w", err)
+	}
+
+	store, err := manifests.NewStore(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	var cluster client.Client
+	if !cfg.Offline {
+		restConfig, err := ctrl.GetConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+		}
+		cluster, err = client.New(restConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client: %w", err)
+		}
+		if err := copyClusterObjects(ctx, cluster, store); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.Files) == 0 {
+		return store, nil
+	}
+
+	objects, err := manifests.Load(scheme, cfg.Files, os.Stdin, cfg.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		// A Service applied from a manifest keeps the LoadBalancer IP it has in the cluster
+		if service, ok := obj.Object.(*corev1.Service); ok && cluster != nil && helpers.GetLBIP(service) == "" {
+			var existing corev1.Service
+			if err := cluster.Get(ctx, types.NamespacedName{Namespace: service.Namespace, Name: service.Name}, &existing); err == nil {
+				service.Status = existing.Status
+			}
+		}
+		if err := store.Add(obj.Object); err != nil {
+			return nil, fmt.Errorf("%s: %w", obj.Source, err)
+		}
+	}
+	return store, nil
+}
+
+// copyClusterObjects adds the objects the controllers read to store. Kinds whose CRD is not
+// installed are left out.
+func copyClusterObjects(ctx context.Context, cluster client.Client, store *manifests.Store) error {
+	lists := []client.ObjectList{
+		&corev1.ServiceList{},
+		&corev1.NamespaceList{},
+		&discoveryv1.EndpointSliceList{},
+		&v1alpha1.PortForwardRuleList{},
+		&v1alpha1.ClusterPortForwardRuleList{},
+		&v1alpha1.PortForwardReferenceGrantList{},
+		&v1alpha1.PortForwardPolicyList{},
+	}
+
+	for _, list := range lists {
+		if err := cluster.List(ctx, list); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return fmt.Errorf("failed to list %T: %w", list, err)
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := addObject(store, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addObject adds a listed object to store
+func addObject(store *manifests.Store, item runtime.Object) error {
+	obj, ok := item.(client.Object)
+	if !ok {
+		return fmt.Errorf("unexpected list item %T", item)
+	}
+	return store.Add(obj)
+}
+
+// writeText writes the plan in a Terraform-like format
+func writeText(out io.Writer, plan *controller.Plan) {
+	symbols := map[controller.OperationType]string{
+		controller.OpCreate: "+",
+		controller.OpUpdate: "~",
+		controller.OpDelete: "-",
+	}
+
+	owner := ""
+	for _, change := range plan.Changes {
+		if current := change.Kind + " " + change.Owner; current != owner {
+			if owner != "" {
+				fmt.Fprintln(out)
+			}
+			owner = current
+			fmt.Fprintf(out, "%s:\n", owner)
+		}
+
+		fmt.Fprintf(out, "  %s %s %d/%s %q (%s)\n", symbols[change.Action], change.Action,
+			change.ExternalPort, strings.ToLower(change.Protocol), change.RouterRule, change.Reason)
+		for _, field := range change.Diff {
+			switch change.Action {
+			case controller.OpCreate:
+				fmt.Fprintf(out, "      + %-9s = %s\n", field.Field, field.To)
+			case controller.OpDelete:
+				fmt.Fprintf(out, "      - %-9s = %s\n", field.Field, field.From)
+			default:
+				fmt.Fprintf(out, "      ~ %-9s = %s -> %s\n", field.Field, field.From, field.To)
+			}
+		}
+	}
+
+	if len(plan.Skipped) > 0 {
+		if owner != "" {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, "Not planned:")
+		for _, skipped := range plan.Skipped {
+			fmt.Fprintf(out, "  %s %s: %s\n", skipped.Kind, skipped.Owner, skipped.Reason)
+		}
+	}
+
+	if len(plan.Changes) > 0 || len(plan.Skipped) > 0 {
+		fmt.Fprintln(out)
+	}
+	if !plan.HasChanges() {
+		fmt.Fprintln(out, "No changes. The router matches the desired state.")
+		return
+	}
+	create, update, remove := plan.Counts()
+	fmt.Fprintf(out, "Plan: %d to create, %d to update, %d to delete.\n", create, update, remove)
+}
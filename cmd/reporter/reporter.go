@@ -0,0 +1,177 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/pkg/routers"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Output formats
+const (
+	OutputTable = ""
+	OutputWide  = "wide"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// Config holds reporter configuration
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	Site     string
+	APIKey   string
+
+	// Controller is the controller configuration drift is analyzed for
+	Controller *config.Config
+
+	// Filter selects the router rules to report
+	Filter controller.StatusFilter
+
+	// Output is the output format: a table, "wide", "json" or "yaml"
+	Output string
+}
+
+// Run writes the status of every router rule passing the filter to out
+func Run(ctx context.Context, cfg Config, out io.Writer) error {
+	switch cfg.Output {
+	case OutputTable, OutputWide, OutputJSON, OutputYAML:
+	default:
+		return fmt.Errorf("unsupported output format %q (expected %q, %q or %q)", cfg.Output, OutputWide, OutputJSON, OutputYAML)
+	}
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		return fmt.Errorf("failed to build scheme: %w", err)
+	}
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	cluster, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	router, err := routers.CreateUnifiRouter(cfg.Host, cfg.Username, cfg.Password, cfg.Site, cfg.APIKey)
+	if err != nil {
+		return fmt.Errorf("failed to create router: %w", err)
+	}
+	routerRules, err := router.ListAllPortForwards(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+
+	reporter := &controller.StatusReporter{Client: cluster, Config: cfg.Controller}
+	statuses, err := reporter.Status(ctx, routerRules)
+	if err != nil {
+		return fmt.Errorf("failed to report router rules: %w", err)
+	}
+
+	selected := make([]controller.RouterRuleStatus, 0, len(statuses))
+	for _, status := range statuses {
+		if cfg.Filter.Matches(status) {
+			selected = append(selected, status)
+		}
+	}
+
+	return Write(out, selected, cfg.Output)
+}
+
+// Write renders router rule statuses to out in the given output format
+func Write(out io.Writer, statuses []controller.RouterRuleStatus, output string) error {
+	switch output {
+	case OutputJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(statuses)
+	case OutputYAML:
+		data, err := yaml.Marshal(statuses)
+		if err != nil {
+			return fmt.Errorf("failed to render router rules: %w", err)
+		}
+		_, err = out.Write(data)
+		return err
+	case OutputTable, OutputWide:
+		return writeTable(out, statuses, output == OutputWide, time.Now())
+	default:
+		return fmt.Errorf("unsupported output format %q (expected %q, %q or %q)", output, OutputWide, OutputJSON, OutputYAML)
+	}
+}
+
+// writeTable writes a row per router rule, with the router ID, name, interface, source and
+// drifted fields added when wide
+func writeTable(out io.Writer, statuses []controller.RouterRuleStatus, wide bool, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+
+	header := "PORT\tPROTOCOL\tDESTINATION\tENABLED\tOWNER\tDRIFT\tLAST APPLIED"
+	if wide {
+		header += "\tID\tNAME\tINTERFACE\tSOURCE"
+	}
+	fmt.Fprintln(w, header)
+
+	for _, status := range statuses {
+		destination := status.Destination
+		if status.ForwardPort != "" {
+			destination += ":" + status.ForwardPort
+		}
+
+		owner := status.OwnerKind
+		if status.Owner != "" {
+			owner += " " + status.Owner
+		}
+
+		drift := orDash(status.Drift)
+		if wide && len(status.DriftFields) > 0 {
+			drift += " (" + strings.Join(status.DriftFields, ",") + ")"
+		}
+
+		lastApplied := "-"
+		if status.LastApplied != nil {
+			lastApplied = age(now.Sub(status.LastApplied.Time)) + " ago"
+		}
+
+		row := fmt.Sprintf("%s\t%s\t%s\t%t\t%s\t%s\t%s", status.ExternalPort, status.Protocol, destination,
+			status.Enabled, owner, drift, lastApplied)
+		if wide {
+			row += fmt.Sprintf("\t%s\t%s\t%s\t%s", status.ID, status.Name, orDash(status.Interface), orDash(status.Source))
+		}
+		fmt.Fprintln(w, row)
+	}
+
+	return w.Flush()
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// age formats a duration in the largest whole unit, as kubectl shows ages
+func age(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,160 @@
+package restorer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"unifi-port-forward/pkg/backup"
+	"unifi-port-forward/pkg/routers"
+)
+
+// Output formats
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Config holds restorer configuration
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	Site     string
+	APIKey   string
+
+	// File is the backup to restore; "-" reads stdin
+	File string
+
+	// IDs, Ports and NameRegex select the backed up rules to restore
+	IDs       []string
+	Ports     []int
+	NameRegex string
+
+	// Reset overwrites rules that changed since the backup
+	Reset bool
+
+	// DryRun reports what would be restored without changing the router
+	DryRun bool
+
+	// Output is the output format of the results, "text" or "json"
+	Output string
+}
+
+// report is the JSON output of a restore
+type report struct {
+	DryRun  bool            `json:"dryRun"`
+	Results []backup.Result `json:"results"`
+}
+
+// Run restores the selected rules of the backup and writes a result per rule to out
+func Run(ctx context.Context, cfg Config, stdin io.Reader, out io.Writer) error {
+	if cfg.Output != OutputText && cfg.Output != OutputJSON {
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", cfg.Output, OutputText, OutputJSON)
+	}
+	if cfg.File == "" {
+		return fmt.Errorf("a backup file is required")
+	}
+
+	opts := backup.RestoreOptions{
+		Selector: backup.Selector{IDs: cfg.IDs, Ports: cfg.Ports},
+		Reset:    cfg.Reset,
+		DryRun:   cfg.DryRun,
+	}
+	if cfg.NameRegex != "" {
+		re, err := regexp.Compile(cfg.NameRegex)
+		if err != nil {
+			return fmt.Errorf("invalid name regex: %w", err)
+		}
+		opts.Selector.Name = re
+	}
+
+	snapshot, err := readBackup(cfg.File, stdin)
+	if err != nil {
+		return err
+	}
+
+	router, err := routers.CreateUnifiRouter(cfg.Host, cfg.Username, cfg.Password, cfg.Site, cfg.APIKey)
+	if err != nil {
+		return fmt.Errorf("failed to create router: %w", err)
+	}
+
+	results, err := backup.Restore(ctx, router, snapshot, opts)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == OutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report{DryRun: cfg.DryRun, Results: results}); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+	} else {
+		writeText(out, results, cfg.DryRun)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Action == backup.ActionFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rules could not be restored", failed, len(results))
+	}
+	return nil
+}
+
+// readBackup reads and decodes the backup in path, or stdin for "-"
+func readBackup(path string, stdin io.Reader) (*backup.Backup, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	snapshot, err := backup.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// writeText writes a line per rule followed by a summary
+func writeText(out io.Writer, results []backup.Result, dryRun bool) {
+	counts := make(map[backup.Action]int)
+	for _, result := range results {
+		counts[result.Action]++
+
+		fmt.Fprintf(out, "%-9s %s/%s %q", result.Action, result.DstPort, result.Protocol, result.Name)
+		if len(result.Fields) > 0 {
+			fmt.Fprintf(out, " (%s)", strings.Join(result.Fields, ", "))
+		}
+		if result.Reason != "" {
+			fmt.Fprintf(out, ": %s", result.Reason)
+		}
+		fmt.Fprintln(out)
+	}
+
+	if len(results) > 0 {
+		fmt.Fprintln(out)
+	}
+	if dryRun {
+		fmt.Fprintf(out, "Dry run: %d to create, %d to reset, %d unchanged, %d skipped.\n",
+			counts[backup.ActionCreate], counts[backup.ActionReset], counts[backup.ActionUnchanged], counts[backup.ActionSkip])
+		return
+	}
+	fmt.Fprintf(out, "Restore: %d created, %d reset, %d unchanged, %d skipped, %d failed.\n",
+		counts[backup.ActionCreate], counts[backup.ActionReset], counts[backup.ActionUnchanged],
+		counts[backup.ActionSkip], counts[backup.ActionFail])
+}
@@ -0,0 +1,157 @@
+package simulator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"unifi-port-forward/pkg/unifisim"
+)
+
+// Config holds simulator configuration
+type Config struct {
+	// Listen is the address the simulator serves HTTPS on, such as 127.0.0.1:8443
+	Listen string
+
+	// Simulator configures the simulated gateway
+	Simulator unifisim.Config
+
+	// Quiet skips logging a line per request
+	Quiet bool
+}
+
+// Run serves a simulated UniFi gateway over HTTPS with a self-signed certificate until ctx is
+// done, writing the address to connect to and, unless quiet, a line per request to out
+func Run(ctx context.Context, cfg Config, out io.Writer) error {
+	simulator, err := unifisim.New(cfg.Simulator)
+	if err != nil {
+		return fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.Listen, err)
+	}
+	address := routerAddress(listener.Addr().(*net.TCPAddr))
+
+	certificate, err := selfSignedCertificate(address, time.Now())
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	handler := simulator.Handler()
+	if !cfg.Quiet {
+		handler = logRequests(handler, out)
+	}
+	server := &http.Server{
+		Handler:           handler,
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12},
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	version := cfg.Simulator.Version
+	if version == "" {
+		version = unifisim.DefaultVersion
+	}
+	fmt.Fprintf(out, "Simulating a UniFi OS gateway with Network %s on https://%s\n", version, address)
+	fmt.Fprintf(out, "Connect with UNIFI_ROUTER_IP=%s and the same credentials, or --router-ip %s\n", address, address)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ServeTLS(listener, "", "")
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("simulator stopped: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to stop simulator: %w", err)
+		}
+		return nil
+	}
+}
+
+// routerAddress returns the address clients connect to, the loopback address when listening
+// on every interface
+func routerAddress(addr *net.TCPAddr) string {
+	host := addr.IP.String()
+	if addr.IP.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(addr.Port))
+}
+
+// selfSignedCertificate creates a certificate for localhost and the host of address. go-unifi
+// does not verify certificates by default, as gateways ship with self-signed ones.
+func selfSignedCertificate(address string, now time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "unifi-simulator"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		if ip := net.ParseIP(host); ip != nil && !ip.IsLoopback() {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests writes the method, path, status and duration of every request to out
+func logRequests(next http.Handler, out io.Writer) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, "%s %-6s %s %d %s\n", start.Format(time.TimeOnly), r.Method, r.URL.Path,
+			recorder.status, time.Since(start).Round(time.Millisecond))
+	})
+}
@@ -0,0 +1,205 @@
+package uninstaller
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/pkg/routers"
+	"unifi-port-forward/pkg/uninstall"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Output formats
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// ErrAborted is returned when the uninstall was not confirmed
+var ErrAborted = errors.New("uninstall aborted, nothing removed")
+
+// Config holds uninstaller configuration
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	Site     string
+	APIKey   string
+
+	// Controller is the controller configuration rule owners are found with
+	Controller *config.Config
+
+	// DeleteCRDs also deletes the CRDs of the port forward API
+	DeleteCRDs bool
+
+	// DryRun reports what would be removed without changing anything
+	DryRun bool
+
+	// Yes removes without asking for confirmation
+	Yes bool
+
+	// Output is the output format of the results, "text" or "json"
+	Output string
+}
+
+// report is the JSON output of an uninstall
+type report struct {
+	DryRun  bool               `json:"dryRun"`
+	Results []uninstall.Result `json:"results"`
+}
+
+// Run removes the router rules, finalizers and cleanup annotations of the installation, after
+// showing what will be removed and asking for confirmation on in unless cfg.Yes is set.
+// Results are written to out, the confirmation prompt to log.
+func Run(ctx context.Context, cfg Config, in io.Reader, out, log io.Writer) error {
+	if cfg.Output != OutputText && cfg.Output != OutputJSON {
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", cfg.Output, OutputText, OutputJSON)
+	}
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		return fmt.Errorf("failed to build scheme: %w", err)
+	}
+	for _, add := range []func(*runtime.Scheme) error{apiextensionsv1.AddToScheme, admissionregistrationv1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			return fmt.Errorf("failed to build scheme: %w", err)
+		}
+	}
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	cluster, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	router, err := routers.CreateUnifiRouter(cfg.Host, cfg.Username, cfg.Password, cfg.Site, cfg.APIKey)
+	if err != nil {
+		return fmt.Errorf("failed to create router: %w", err)
+	}
+
+	// Rules are read in their stored version, as the conversion webhook stops with the controller
+	uninstaller := &uninstall.Uninstaller{Client: uninstall.NewHubClient(cluster), Router: router, Config: cfg.Controller}
+	opts := uninstall.Options{DeleteCRDs: cfg.DeleteCRDs, DryRun: cfg.DryRun}
+
+	if !cfg.DryRun && !cfg.Yes {
+		planned, err := uninstaller.Run(ctx, uninstall.Options{DeleteCRDs: cfg.DeleteCRDs, DryRun: true})
+		if err != nil {
+			return err
+		}
+		if len(planned) > 0 {
+			confirmed, err := confirm(in, log, planned)
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return ErrAborted
+			}
+		}
+	}
+
+	results, err := uninstaller.Run(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == OutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report{DryRun: cfg.DryRun, Results: results}); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+	} else {
+		writeText(out, results, cfg.DryRun)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Action == uninstall.ActionFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d objects could not be removed", failed, len(results))
+	}
+	return nil
+}
+
+// confirm summarizes what will be removed on log and asks whether to go ahead
+func confirm(in io.Reader, log io.Writer, planned []uninstall.Result) (bool, error) {
+	routerRules, webhooks, released, crds := 0, 0, 0, 0
+	for _, result := range planned {
+		switch {
+		case result.Kind == uninstall.KindRouterRule:
+			routerRules++
+		case result.Kind == uninstall.KindWebhook, result.Kind == uninstall.KindMutatingWebhook:
+			webhooks++
+		case result.Kind == uninstall.KindCRD:
+			crds++
+		default:
+			released++
+		}
+	}
+
+	fmt.Fprintln(log, "The uninstall will:")
+	fmt.Fprintf(log, "  delete %d router rules\n", routerRules)
+	if webhooks > 0 {
+		fmt.Fprintf(log, "  delete %d webhook configurations\n", webhooks)
+	}
+	fmt.Fprintf(log, "  remove the finalizer and cleanup annotations from %d Services and rules\n", released)
+	if crds > 0 {
+		fmt.Fprintf(log, "  delete %d CRDs, and with them every PortForwardRule and ClusterPortForwardRule\n", crds)
+	}
+
+	fmt.Fprint(log, "\nContinue? [y/N]: ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// writeText writes a line per object followed by a summary
+func writeText(out io.Writer, results []uninstall.Result, dryRun bool) {
+	removed, failed := 0, 0
+	for _, result := range results {
+		if result.Action == uninstall.ActionFail {
+			failed++
+		} else {
+			removed++
+		}
+
+		name := result.Name
+		if result.ID != "" {
+			name = fmt.Sprintf("%s (%s)", result.Name, result.ID)
+		}
+		fmt.Fprintf(out, "%-7s %s %s", result.Action, result.Kind, name)
+		if result.Reason != "" {
+			fmt.Fprintf(out, ": %s", result.Reason)
+		}
+		fmt.Fprintln(out)
+	}
+
+	if len(results) > 0 {
+		fmt.Fprintln(out)
+	}
+	if dryRun {
+		fmt.Fprintf(out, "Dry run: %d objects would be removed.\n", removed)
+		return
+	}
+	fmt.Fprintf(out, "Uninstall: %d removed, %d failed.\n", removed, failed)
+}
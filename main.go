@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -17,18 +18,38 @@ import (
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
 	"unifi-port-forward/cmd/cleaner"
+	"unifi-port-forward/cmd/diagnoser"
+	"unifi-port-forward/cmd/exporter"
+	"unifi-port-forward/cmd/importer"
+	"unifi-port-forward/cmd/linter"
+	"unifi-port-forward/cmd/planner"
+	"unifi-port-forward/cmd/reporter"
+	"unifi-port-forward/cmd/restorer"
+	"unifi-port-forward/cmd/simulator"
+	"unifi-port-forward/cmd/uninstaller"
 	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/api/v1beta1"
+	"unifi-port-forward/pkg/backup"
 	"unifi-port-forward/pkg/config"
 	"unifi-port-forward/pkg/controller"
 	"unifi-port-forward/pkg/helpers"
 	"unifi-port-forward/pkg/routers"
+	"unifi-port-forward/pkg/stateapi"
+	"unifi-port-forward/pkg/unifisim"
+	"unifi-port-forward/pkg/webhook"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 var (
@@ -46,34 +67,39 @@ var rootCmd = &cobra.Command{
 		return runController(cmd, args)
 	},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Load configuration (env vars first, then defaults)
-		cfg.Load()
-
-		// Override with CLI flags if they were explicitly set
-		if cmd.Flags().Changed("router-ip") {
-			cfg.RouterIP, _ = cmd.Flags().GetString("router-ip")
-		}
-		if cmd.Flags().Changed("username") {
-			cfg.Username, _ = cmd.Flags().GetString("username")
-		}
-		if cmd.Flags().Changed("password") {
-			cfg.Password, _ = cmd.Flags().GetString("password")
-		}
-		if cmd.Flags().Changed("site") {
-			cfg.Site, _ = cmd.Flags().GetString("site")
-		}
-		if cmd.Flags().Changed("api-key") {
-			cfg.APIKey, _ = cmd.Flags().GetString("api-key")
-		}
-		if cmd.Flags().Changed("debug") {
-			cfg.Debug, _ = cmd.Flags().GetBool("debug")
-		}
+		loadConfig(cmd)
 
 		// Validate final configuration
 		return cfg.Validate()
 	},
 }
 
+// loadConfig loads the configuration from the environment, overridden by explicitly set flags
+func loadConfig(cmd *cobra.Command) {
+	// Load configuration (env vars first, then defaults)
+	cfg.Load()
+
+	// Override with CLI flags if they were explicitly set
+	if cmd.Flags().Changed("router-ip") {
+		cfg.RouterIP, _ = cmd.Flags().GetString("router-ip")
+	}
+	if cmd.Flags().Changed("username") {
+		cfg.Username, _ = cmd.Flags().GetString("username")
+	}
+	if cmd.Flags().Changed("password") {
+		cfg.Password, _ = cmd.Flags().GetString("password")
+	}
+	if cmd.Flags().Changed("site") {
+		cfg.Site, _ = cmd.Flags().GetString("site")
+	}
+	if cmd.Flags().Changed("api-key") {
+		cfg.APIKey, _ = cmd.Flags().GetString("api-key")
+	}
+	if cmd.Flags().Changed("debug") {
+		cfg.Debug, _ = cmd.Flags().GetBool("debug")
+	}
+}
+
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&cfg.RouterIP, "router-ip", "r", "192.168.1.1", "UniFi router IP address (env: UNIFI_ROUTER_IP, default: 192.168.1.1)")
@@ -86,6 +112,15 @@ func init() {
 	// Add subcommands
 	rootCmd.AddCommand(controllerCmd)
 	rootCmd.AddCommand(cleanCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(uninstallCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(simulateCmd)
 
 	// Set default command to controller when no command is specified
 	rootCmd.SetHelpCommand(&cobra.Command{Hidden: true})
@@ -93,6 +128,9 @@ func init() {
 
 func main() {
 	if err := Execute(); err != nil {
+		if errors.Is(err, planner.ErrChangesPending) {
+			os.Exit(2)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -108,16 +146,203 @@ var controllerCmd = &cobra.Command{
 
 func init() {
 	// Add clean-specific flags
-	cleanCmd.Flags().StringP("port-mappings", "m", "", "Port mappings to clean (format: 'external-port:dest-ip', comma-separated) [REQUIRED]")
+	cleanCmd.Flags().StringP("port-mappings", "m", "", "Port mappings to clean (format: 'external-port:dest-ip', comma-separated)")
 	cleanCmd.Flags().StringP("port-mappings-file", "f", "", "Path to port mappings configuration file (YAML/JSON)")
+	cleanCmd.Flags().String("name-prefix", "", "Only clean rules whose name starts with this prefix")
+	cleanCmd.Flags().String("name-regex", "", "Regular expression the rule names must match")
+	cleanCmd.Flags().Bool("managed-only", false, "Only clean rules named like the controller names its rules (namespace/name:port)")
+	cleanCmd.Flags().StringSlice("dest-cidr", nil, "Only clean rules forwarding into these networks (repeatable or comma-separated)")
+	cleanCmd.Flags().StringSlice("port", nil, "Only clean rules on these external ports or port ranges such as 8000-8100 (repeatable or comma-separated)")
+	cleanCmd.Flags().Bool("orphans", false, "Clean managed rules whose Service or rule no longer exists in the cluster")
+	cleanCmd.Flags().Bool("dry-run", false, "Show what would be deleted without changing the router")
+	cleanCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+	cleanCmd.Flags().StringP("output", "o", cleaner.OutputText, "Output format: text or json")
 }
 
 // cleanCmd runs the port forwarding rule cleaner
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Run the port forwarding rule cleaner",
-	Long:  `Clean up stale port forwarding rules from the router`,
-	RunE:  runClean,
+	Long: `Clean up stale port forwarding rules from the router. Rules are selected by port mapping,
+name, destination network, external port or, with --orphans, by a missing owner in the cluster;
+a rule must pass every given selector. The selected rules are shown and deleted after
+confirmation unless --yes or --dry-run is set.`,
+	RunE: runClean,
+}
+
+func init() {
+	planCmd.Flags().StringSliceP("filename", "f", nil, "Manifest files or directories to plan in place of the cluster objects they name ('-' reads stdin)")
+	planCmd.Flags().StringP("namespace", "n", "default", "Namespace of manifest objects that do not set one")
+	planCmd.Flags().Bool("offline", false, "Plan the manifests alone, without reading the cluster")
+	planCmd.Flags().StringP("output", "o", planner.OutputText, "Output format: text or json")
+}
+
+// planCmd shows the router changes the controller would make
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the router changes the controller would make",
+	Long: `Compare the desired state of Services and port forward rules, read from the cluster or from
+manifests, with the router rules and print the creates, updates and deletes the controller would
+make, without making them. Exits with code 2 when changes are pending.`,
+	RunE: runPlan,
+}
+
+func init() {
+	importCmd.Flags().IntSlice("port", nil, "External ports of the router rules to import (repeatable or comma-separated)")
+	importCmd.Flags().String("name-regex", "", "Regular expression the router rule names must match")
+	importCmd.Flags().String("subnet", "", "CIDR the router rule destinations must be in")
+	importCmd.Flags().StringP("namespace", "n", "default", "Namespace of rules that do not forward to a Service")
+	importCmd.Flags().Bool("offline", false, "Map every rule to its destination IP without reading Services from the cluster")
+	importCmd.Flags().StringP("output-dir", "o", "", "Directory to write one manifest per rule to (default: stdout)")
+	importCmd.Flags().Bool("rename", false, "Rename the router rules to the controller's naming scheme so it adopts them in place")
+}
+
+// importCmd adopts manual router rules into PortForwardRule manifests
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import manual router rules as PortForwardRule manifests",
+	Long: `Generate PortForwardRule manifests for existing router rules, forwarding to a Service when the
+destination is a Service LoadBalancer IP. With --rename the router rules are renamed to the
+controller's naming scheme so the controller adopts them without recreating them.`,
+	RunE: runImport,
+}
+
+func init() {
+	exportCmd.Flags().StringP("file", "f", "", "File to write the backup to (default: stdout)")
+	exportCmd.Flags().StringP("output", "o", backup.FormatYAML, "Backup format: yaml or json")
+}
+
+// exportCmd backs up the router port forward rules
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Back up every port forward rule on the router",
+	Long: `Write a versioned backup of every port forward rule on the site, enabled or disabled and
+managed by the controller or not, with every field the UniFi API returns.`,
+	RunE: runExport,
+}
+
+func init() {
+	restoreCmd.Flags().StringP("file", "f", "", "Backup file to restore ('-' reads stdin) [REQUIRED]")
+	restoreCmd.Flags().StringSlice("id", nil, "Router IDs of the backed up rules to restore (repeatable or comma-separated)")
+	restoreCmd.Flags().IntSlice("port", nil, "External ports of the backed up rules to restore (repeatable or comma-separated)")
+	restoreCmd.Flags().String("name-regex", "", "Regular expression the backed up rule names must match")
+	restoreCmd.Flags().Bool("reset", false, "Overwrite rules that changed since the backup")
+	restoreCmd.Flags().Bool("dry-run", false, "Show what would be restored without changing the router")
+	restoreCmd.Flags().StringP("output", "o", restorer.OutputText, "Output format: text or json")
+}
+
+// restoreCmd restores router port forward rules from a backup
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore port forward rules from a backup",
+	Long: `Recreate the backed up port forward rules missing from the router. With --reset, rules that
+changed since the backup are overwritten with their backed up version. Rules added since the
+backup are left alone.`,
+	RunE: runRestore,
+}
+
+func init() {
+	statusCmd.Flags().IntSlice("port", nil, "External ports of the router rules to show (repeatable or comma-separated)")
+	statusCmd.Flags().StringSlice("owner", nil, "Owner kinds to show: service, portforwardrule, clusterportforwardrule, manual or orphaned")
+	statusCmd.Flags().StringP("namespace", "n", "", "Namespace of the Services and rules whose router rules to show")
+	statusCmd.Flags().Bool("drifted", false, "Show only router rules that are drifted, extra or of unknown drift")
+	statusCmd.Flags().StringP("output", "o", reporter.OutputTable, "Output format: wide, json or yaml (default: table)")
+}
+
+// statusCmd lists the router rules with their owners
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Aliases: []string{"list", "ls"},
+	Short:   "List router rules with the Service or rule owning them",
+	Long: `List every port forward rule on the router with the Service, PortForwardRule or
+ClusterPortForwardRule owning it, or "manual" for rules made by hand and "orphaned" for managed
+rules whose owner no longer exists, along with its drift from the desired state.`,
+	RunE: runStatus,
+}
+
+func init() {
+	uninstallCmd.Flags().Bool("delete-crds", false, "Also delete the port forward CRDs, and with them every PortForwardRule and ClusterPortForwardRule")
+	uninstallCmd.Flags().Bool("dry-run", false, "Show what would be removed without changing the router or the cluster")
+	uninstallCmd.Flags().BoolP("yes", "y", false, "Uninstall without asking for confirmation")
+	uninstallCmd.Flags().StringP("output", "o", uninstaller.OutputText, "Output format: text or json")
+}
+
+// uninstallCmd removes everything the controller put on the router and in the cluster
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the controller's router rules, finalizers and annotations",
+	Long: `Delete every router rule owned by a Service or rule, or named like one, delete the
+validating webhook configuration, and remove the router-rule-protection finalizer and cleanup
+annotations from Services, PortForwardRules and ClusterPortForwardRules so they can be deleted
+without the controller. Manual router rules are left alone. Stop the controller first, or it
+puts the rules and finalizers back.`,
+	RunE: runUninstall,
+}
+
+func init() {
+	doctorCmd.Flags().Bool("read-only", false, "Skip creating and deleting a disabled test rule on the router")
+	doctorCmd.Flags().Bool("skip-cluster", false, "Check the router only, without a kubeconfig")
+	doctorCmd.Flags().StringP("output", "o", diagnoser.OutputText, "Output format: text or json")
+}
+
+// doctorCmd checks the router, the credentials and the cluster for setup problems
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the router, credentials and cluster for setup problems",
+	Long: `Check that the router is reachable over TLS, that the password and API key log in, that
+the site exists and that the account can create and delete a disabled test rule. In the cluster,
+check the CRDs, the controller's RBAC permissions, the LoadBalancer IPs of annotated Services and
+router rule naming collisions. Every failed check prints a hint on how to fix it.`,
+	RunE: runDoctor,
+}
+
+func init() {
+	lintCmd.Flags().StringSliceP("filename", "f", nil, "Manifest files or directories to lint ('-' reads stdin)")
+	lintCmd.Flags().StringP("namespace", "n", "default", "Namespace of manifest objects that do not set one")
+	lintCmd.Flags().StringSlice("allow-ports", nil, "Only allow these external ports or ranges, such as 8000-9000 (repeatable or comma-separated)")
+	lintCmd.Flags().StringSlice("deny-ports", nil, "Deny these external ports or ranges, such as 22 (repeatable or comma-separated)")
+	lintCmd.Flags().StringP("output", "o", linter.OutputText, "Output format: text, json or sarif")
+}
+
+// lintCmd checks manifests offline, for CI
+var lintCmd = &cobra.Command{
+	Use:   "lint [files...]",
+	Short: "Check Service annotations and port forward rules in manifests",
+	Long: `Check manifests without a cluster or router: the mapping annotations of Services against
+their ports, the specs of PortForwardRules and ClusterPortForwardRules as the admission webhook
+validates them, external ports forwarded by more than one object, and ports outside the allowed
+or inside the denied ranges. Files and directories may be given as arguments or with --filename,
+and '-' reads multi-document YAML such as kustomize build output from stdin.`,
+	// Linting reads no router, so the router configuration is not required
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	RunE:              runLint,
+}
+
+func init() {
+	simulateCmd.Flags().String("listen", "127.0.0.1:8443", "Address to serve the simulated gateway on over HTTPS")
+	simulateCmd.Flags().String("state-file", "", "Keep the port forward rules in this JSON file across restarts (default: in memory)")
+	simulateCmd.Flags().Duration("latency", 0, "Delay every response by this long")
+	simulateCmd.Flags().Duration("session-ttl", 0, "Expire login sessions after this long, answering with 401 until the client logs in again (default: never)")
+	simulateCmd.Flags().String("network-version", unifisim.DefaultVersion, "Network application version to report")
+	simulateCmd.Flags().Bool("quiet", false, "Do not log a line per request")
+}
+
+// simulateCmd serves a simulated UniFi gateway for development and integration tests
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Serve a simulated UniFi gateway for development and testing",
+	Long: `Serve the UniFi OS login and logout endpoints, CSRF tokens, API keys and the port forward
+API that the controller uses, keeping the rules in memory or in --state-file. Rules are validated
+and rejected like on a gateway, with PortForwardOverlaps for overlapping enabled rules and 401s once
+a session expires. The simulator accepts the --username and --password or --api-key it is started
+with and serves the --site and default sites; point the controller or any command at it with
+--router-ip and the same credentials.`,
+	// The simulator is the router, so the router configuration only supplies its credentials
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		loadConfig(cmd)
+		return nil
+	},
+	RunE: runSimulate,
 }
 
 func runController(cmd *cobra.Command, args []string) error {
@@ -134,6 +359,12 @@ func runController(cmd *cobra.Command, args []string) error {
 		Metrics: server.Options{
 			BindAddress: "0", // Disable metrics to avoid port conflicts
 		},
+		WebhookServer: ctrlwebhook.NewServer(ctrlwebhook.Options{
+			Port:     cfg.WebhookPort,
+			CertDir:  cfg.WebhookCertDir,
+			CertName: webhook.CertName,
+			KeyName:  webhook.KeyName,
+		}),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create manager: %w", err)
@@ -145,9 +376,21 @@ func runController(cmd *cobra.Command, args []string) error {
 	if err := apiextensionsv1.AddToScheme(mgr.GetScheme()); err != nil {
 		return fmt.Errorf("failed to add apiextensionsv1 to scheme: %w", err)
 	}
+	if err := authenticationv1.AddToScheme(mgr.GetScheme()); err != nil {
+		return fmt.Errorf("failed to add authenticationv1 to scheme: %w", err)
+	}
+	if err := authorizationv1.AddToScheme(mgr.GetScheme()); err != nil {
+		return fmt.Errorf("failed to add authorizationv1 to scheme: %w", err)
+	}
+	if err := discoveryv1.AddToScheme(mgr.GetScheme()); err != nil {
+		return fmt.Errorf("failed to add discoveryv1 to scheme: %w", err)
+	}
 	if err := v1alpha1.AddToScheme(mgr.GetScheme()); err != nil {
 		return fmt.Errorf("failed to add v1alpha1 to scheme: %w", err)
 	}
+	if err := v1beta1.AddToScheme(mgr.GetScheme()); err != nil {
+		return fmt.Errorf("failed to add v1beta1 to scheme: %w", err)
+	}
 
 	portforwardReconciler := &controller.PortForwardReconciler{
 		Client: mgr.GetClient(),
@@ -165,7 +408,8 @@ func runController(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to perform initial sync: %w", err)
 	}
 
-	if helpers.IsPortForwardRuleCRDAvailable(context.Background(), ctrl.GetConfigOrDie(), mgr.GetScheme()) {
+	portForwardRulesEnabled := helpers.IsPortForwardRuleCRDAvailable(context.Background(), ctrl.GetConfigOrDie(), mgr.GetScheme())
+	if portForwardRulesEnabled {
 		logger.Info("PortForwardRule CRD controller enabled")
 
 		ruleReconciler := &controller.PortForwardRuleReconciler{
@@ -174,6 +418,15 @@ func runController(cmd *cobra.Command, args []string) error {
 			Router:   router,
 			Config:   &cfg,
 			Recorder: mgr.GetEventRecorderFor("portforwardrule-controller"),
+
+			ReferenceGrantsEnabled: helpers.IsPortForwardReferenceGrantCRDAvailable(context.Background(), ctrl.GetConfigOrDie(), mgr.GetScheme()),
+			PoliciesEnabled:        helpers.IsPortForwardPolicyCRDAvailable(context.Background(), ctrl.GetConfigOrDie(), mgr.GetScheme()),
+		}
+		if !ruleReconciler.ReferenceGrantsEnabled {
+			logger.Info("PortForwardReferenceGrant CRD not found, cross-namespace service references will be rejected")
+		}
+		if !ruleReconciler.PoliciesEnabled {
+			logger.Info("PortForwardPolicy CRD not found, no port forward policies are enforced")
 		}
 
 		if err := ruleReconciler.SetupWithManager(mgr); err != nil {
@@ -183,6 +436,46 @@ func runController(cmd *cobra.Command, args []string) error {
 		logger.Info("PortForwardRule CRD not found, PortForwardRule controller disabled (annotation-based mode only)")
 	}
 
+	clusterRulesEnabled := helpers.IsClusterPortForwardRuleCRDAvailable(context.Background(), ctrl.GetConfigOrDie(), mgr.GetScheme())
+	if clusterRulesEnabled {
+		logger.Info("ClusterPortForwardRule CRD controller enabled")
+
+		clusterRuleReconciler := &controller.ClusterPortForwardRuleReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Router:   router,
+			Config:   &cfg,
+			Recorder: mgr.GetEventRecorderFor("clusterportforwardrule-controller"),
+		}
+
+		if err := clusterRuleReconciler.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to setup ClusterPortForwardRule controller: %w", err)
+		}
+	} else {
+		logger.Info("ClusterPortForwardRule CRD not found, ClusterPortForwardRule controller disabled")
+	}
+
+	if cfg.WebhookEnabled {
+		if err := setupWebhooks(mgr, clusterRulesEnabled); err != nil {
+			return fmt.Errorf("failed to setup admission webhooks: %w", err)
+		}
+		logger.Info("Validating admission webhooks enabled", "port", cfg.WebhookPort)
+	}
+
+	if cfg.StateAPIEnabled {
+		stateServer := &stateapi.Server{
+			Reporter: &controller.StatusReporter{Client: mgr.GetClient(), Config: &cfg},
+			Router:   router,
+			Client:   mgr.GetClient(),
+			Port:     cfg.StateAPIPort,
+			CertDir:  cfg.StateAPICertDir,
+		}
+		if err := mgr.Add(stateServer); err != nil {
+			return fmt.Errorf("failed to setup state API: %w", err)
+		}
+		logger.Info("Read-only router state API enabled", "port", cfg.StateAPIPort)
+	}
+
 	portforwardReconciler.PeriodicReconciler = controller.NewPeriodicReconciler(
 		mgr.GetClient(),
 		mgr.GetScheme(),
@@ -191,6 +484,7 @@ func runController(cmd *cobra.Command, args []string) error {
 		portforwardReconciler.EventPublisher,
 		portforwardReconciler.Recorder,
 	)
+	portforwardReconciler.PeriodicReconciler.PortForwardRulesEnabled = portForwardRulesEnabled
 
 	go func() {
 		logger := logr.FromSlogHandler(slog.Default().Handler()).WithValues("component", "periodic-reconciler-main")
@@ -221,10 +515,278 @@ func runController(cmd *cobra.Command, args []string) error {
 	return mgr.Start(ctrl.SetupSignalHandler())
 }
 
+// setupWebhooks bootstraps the webhook serving certificate when none is mounted and registers
+// the validating webhooks with the manager
+func setupWebhooks(mgr ctrl.Manager, clusterRulesEnabled bool) error {
+	caBundle, err := webhook.EnsureServingCertificate(cfg.WebhookCertDir, cfg.WebhookServiceName, cfg.WebhookNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to bootstrap serving certificate: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := admissionregistrationv1.AddToScheme(scheme); err != nil {
+		return fmt.Errorf("failed to add admissionregistrationv1 to scheme: %w", err)
+	}
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		return fmt.Errorf("failed to add apiextensionsv1 to scheme: %w", err)
+	}
+	uncachedClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	// Approvals are only signed, and only restricted to approvers, by a webhook that fails closed
+	if cfg.RequireApproval {
+		if err := webhook.CheckApprovalWebhooks(context.Background(), uncachedClient); err != nil {
+			return err
+		}
+	}
+
+	// A generated certificate is only trusted once its CA is in the webhook configurations and
+	// the conversion webhooks of the CRDs. Mounted certificates are expected to be injected by
+	// cert-manager.
+	if caBundle != nil {
+		if err := webhook.InjectCABundle(context.Background(), uncachedClient, webhook.ValidatingWebhookConfigurationName, caBundle); err != nil {
+			return err
+		}
+		if err := webhook.InjectMutatingCABundle(context.Background(), uncachedClient, webhook.MutatingWebhookConfigurationName, caBundle); err != nil {
+			return err
+		}
+		for _, crdName := range []string{config.PortForwardRulesCRDName, config.ClusterPortForwardRulesCRDName} {
+			if err := webhook.InjectConversionCABundle(context.Background(), uncachedClient, crdName, caBundle); err != nil {
+				return err
+			}
+		}
+	}
+
+	webhook.SetupWithManager(mgr, clusterRulesEnabled, []byte(cfg.ApprovalSigningKey))
+	return nil
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	files, _ := cmd.Flags().GetStringSlice("filename")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	offline, _ := cmd.Flags().GetBool("offline")
+	output, _ := cmd.Flags().GetString("output")
+
+	planConfig := planner.Config{
+		Host:       cfg.Host,
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		Site:       cfg.Site,
+		APIKey:     cfg.APIKey,
+		Controller: &cfg,
+		Files:      files,
+		Namespace:  namespace,
+		Offline:    offline,
+		Output:     output,
+	}
+
+	return planner.Run(cmd.Context(), planConfig, cmd.OutOrStdout())
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	ports, _ := cmd.Flags().GetIntSlice("port")
+	nameRegex, _ := cmd.Flags().GetString("name-regex")
+	subnet, _ := cmd.Flags().GetString("subnet")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	offline, _ := cmd.Flags().GetBool("offline")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	rename, _ := cmd.Flags().GetBool("rename")
+
+	importConfig := importer.Config{
+		Host:      cfg.Host,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+		Site:      cfg.Site,
+		APIKey:    cfg.APIKey,
+		Namespace: namespace,
+		Ports:     ports,
+		NameRegex: nameRegex,
+		Subnet:    subnet,
+		Offline:   offline,
+		OutputDir: outputDir,
+		Rename:    rename,
+	}
+
+	return importer.Run(cmd.Context(), importConfig, cmd.OutOrStdout(), cmd.ErrOrStderr())
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	file, _ := cmd.Flags().GetString("file")
+	output, _ := cmd.Flags().GetString("output")
+
+	exportConfig := exporter.Config{
+		Host:     cfg.Host,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		Site:     cfg.Site,
+		APIKey:   cfg.APIKey,
+		File:     file,
+		Format:   output,
+	}
+
+	return exporter.Run(cmd.Context(), exportConfig, cmd.OutOrStdout(), cmd.ErrOrStderr())
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	file, _ := cmd.Flags().GetString("file")
+	ids, _ := cmd.Flags().GetStringSlice("id")
+	ports, _ := cmd.Flags().GetIntSlice("port")
+	nameRegex, _ := cmd.Flags().GetString("name-regex")
+	reset, _ := cmd.Flags().GetBool("reset")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	output, _ := cmd.Flags().GetString("output")
+
+	restoreConfig := restorer.Config{
+		Host:      cfg.Host,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+		Site:      cfg.Site,
+		APIKey:    cfg.APIKey,
+		File:      file,
+		IDs:       ids,
+		Ports:     ports,
+		NameRegex: nameRegex,
+		Reset:     reset,
+		DryRun:    dryRun,
+		Output:    output,
+	}
+
+	return restorer.Run(cmd.Context(), restoreConfig, cmd.InOrStdin(), cmd.OutOrStdout())
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	ports, _ := cmd.Flags().GetIntSlice("port")
+	owners, _ := cmd.Flags().GetStringSlice("owner")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	drifted, _ := cmd.Flags().GetBool("drifted")
+	output, _ := cmd.Flags().GetString("output")
+
+	statusConfig := reporter.Config{
+		Host:       cfg.Host,
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		Site:       cfg.Site,
+		APIKey:     cfg.APIKey,
+		Controller: &cfg,
+		Filter: controller.StatusFilter{
+			Ports:       ports,
+			OwnerKinds:  owners,
+			Namespace:   namespace,
+			DriftedOnly: drifted,
+		},
+		Output: output,
+	}
+
+	return reporter.Run(cmd.Context(), statusConfig, cmd.OutOrStdout())
+}
+
+func runUninstall(cmd *cobra.Command, args []string) error {
+	deleteCRDs, _ := cmd.Flags().GetBool("delete-crds")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+	output, _ := cmd.Flags().GetString("output")
+
+	uninstallConfig := uninstaller.Config{
+		Host:       cfg.Host,
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		Site:       cfg.Site,
+		APIKey:     cfg.APIKey,
+		Controller: &cfg,
+		DeleteCRDs: deleteCRDs,
+		DryRun:     dryRun,
+		Yes:        yes,
+		Output:     output,
+	}
+
+	return uninstaller.Run(cmd.Context(), uninstallConfig, cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr())
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	readOnly, _ := cmd.Flags().GetBool("read-only")
+	skipCluster, _ := cmd.Flags().GetBool("skip-cluster")
+	output, _ := cmd.Flags().GetString("output")
+
+	doctorConfig := diagnoser.Config{
+		Host:        cfg.Host,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		Site:        cfg.Site,
+		APIKey:      cfg.APIKey,
+		ReadOnly:    readOnly,
+		SkipCluster: skipCluster,
+		Output:      output,
+	}
+
+	return diagnoser.Run(cmd.Context(), doctorConfig, cmd.OutOrStdout())
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	files, _ := cmd.Flags().GetStringSlice("filename")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	allowPorts, _ := cmd.Flags().GetStringSlice("allow-ports")
+	denyPorts, _ := cmd.Flags().GetStringSlice("deny-ports")
+	output, _ := cmd.Flags().GetString("output")
+
+	lintConfig := linter.Config{
+		Files:        append(files, args...),
+		Namespace:    namespace,
+		AllowedPorts: allowPorts,
+		DeniedPorts:  denyPorts,
+		Output:       output,
+	}
+
+	return linter.Run(lintConfig, cmd.InOrStdin(), cmd.OutOrStdout())
+}
+
+func runSimulate(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	stateFile, _ := cmd.Flags().GetString("state-file")
+	latency, _ := cmd.Flags().GetDuration("latency")
+	sessionTTL, _ := cmd.Flags().GetDuration("session-ttl")
+	version, _ := cmd.Flags().GetString("network-version")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+
+	sites := []string{unifisim.DefaultSite}
+	if cfg.Site != unifisim.DefaultSite {
+		sites = append(sites, cfg.Site)
+	}
+
+	simulateConfig := simulator.Config{
+		Listen: listen,
+		Simulator: unifisim.Config{
+			Username:   cfg.Username,
+			Password:   cfg.Password,
+			APIKey:     cfg.APIKey,
+			Sites:      sites,
+			Version:    version,
+			SessionTTL: sessionTTL,
+			Latency:    latency,
+			StateFile:  stateFile,
+		},
+		Quiet: quiet,
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return simulator.Run(ctx, simulateConfig, cmd.OutOrStdout())
+}
+
 func runClean(cmd *cobra.Command, args []string) error {
 	// Get CLI flags
 	portMappingsStr, _ := cmd.Flags().GetString("port-mappings")
 	portMappingsFile, _ := cmd.Flags().GetString("port-mappings-file")
+	namePrefix, _ := cmd.Flags().GetString("name-prefix")
+	nameRegex, _ := cmd.Flags().GetString("name-regex")
+	managedOnly, _ := cmd.Flags().GetBool("managed-only")
+	destCIDRs, _ := cmd.Flags().GetStringSlice("dest-cidr")
+	ports, _ := cmd.Flags().GetStringSlice("port")
+	orphans, _ := cmd.Flags().GetBool("orphans")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+	output, _ := cmd.Flags().GetString("output")
 
 	var portMaps map[string]string
 	var err error
@@ -235,8 +797,6 @@ func runClean(cmd *cobra.Command, args []string) error {
 	} else if portMappingsStr != "" {
 		// Parse CLI string
 		portMaps, err = parsePortMappingsString(portMappingsStr)
-	} else {
-		return fmt.Errorf("--port-mappings or --port-mappings-file is required")
 	}
 
 	if err != nil {
@@ -245,14 +805,25 @@ func runClean(cmd *cobra.Command, args []string) error {
 
 	// Create cleaner config from global config
 	cleanConfig := cleaner.Config{
-		Host:     cfg.Host,
-		Username: cfg.Username,
-		Password: cfg.Password,
-		Site:     cfg.Site,
-		APIKey:   cfg.APIKey,
+		Host:             cfg.Host,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		Site:             cfg.Site,
+		APIKey:           cfg.APIKey,
+		Controller:       &cfg,
+		Mappings:         portMaps,
+		NamePrefix:       namePrefix,
+		NameRegex:        nameRegex,
+		ManagedOnly:      managedOnly,
+		DestinationCIDRs: destCIDRs,
+		Ports:            ports,
+		Orphans:          orphans,
+		DryRun:           dryRun,
+		Yes:              yes,
+		Output:           output,
 	}
 
-	return cleaner.Run(cleanConfig, portMaps)
+	return cleaner.Run(cmd.Context(), cleanConfig, cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr())
 }
 
 // parsePortMappingsString parses CLI string format: "83:192.168.27.130,8080:192.168.27.131"
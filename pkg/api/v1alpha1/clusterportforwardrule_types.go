@@ -0,0 +1,71 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PortForwardRuleObject is implemented by PortForwardRule and ClusterPortForwardRule so
+// controllers can share the logic that applies either kind to the router
+// +kubebuilder:object:generate=false
+type PortForwardRuleObject interface {
+	client.Object
+
+	// GetRuleSpec returns the rule's spec
+	GetRuleSpec() *PortForwardRuleSpec
+
+	// GetRuleStatus returns the rule's status
+	GetRuleStatus() *PortForwardRuleStatus
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="External Port",type="integer",JSONPath=".spec.externalPort"
+//+kubebuilder:printcolumn:name="Protocol",type="string",JSONPath=".spec.protocol"
+//+kubebuilder:printcolumn:name="Service",type="string",JSONPath=".spec.serviceRef.name"
+//+kubebuilder:printcolumn:name="Enabled",type="boolean",JSONPath=".spec.enabled"
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterPortForwardRule is the Schema for the clusterportforwardrules API. It is a
+// cluster-scoped PortForwardRule for platform-owned forwards whose ports namespaced
+// rules cannot take over.
+type ClusterPortForwardRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PortForwardRuleSpec   `json:"spec,omitempty"`
+	Status PortForwardRuleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterPortForwardRuleList contains a list of ClusterPortForwardRule
+type ClusterPortForwardRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterPortForwardRule `json:"items"`
+}
+
+// GetRuleSpec returns the rule's spec
+func (r *PortForwardRule) GetRuleSpec() *PortForwardRuleSpec {
+	return &r.Spec
+}
+
+// GetRuleStatus returns the rule's status
+func (r *PortForwardRule) GetRuleStatus() *PortForwardRuleStatus {
+	return &r.Status
+}
+
+// GetRuleSpec returns the rule's spec
+func (r *ClusterPortForwardRule) GetRuleSpec() *PortForwardRuleSpec {
+	return &r.Spec
+}
+
+// GetRuleStatus returns the rule's status
+func (r *ClusterPortForwardRule) GetRuleStatus() *PortForwardRuleStatus {
+	return &r.Status
+}
@@ -0,0 +1,239 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PortForwardPolicySpec defines which port forwards PortForwardRules and annotated Services in
+// the selected namespaces may create
+type PortForwardPolicySpec struct {
+	// NamespaceSelector selects the namespaces the policy applies to (empty selects all namespaces)
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// AllowedPorts restricts external ports to these ranges (empty allows every port not denied)
+	AllowedPorts []PortRange `json:"allowedPorts,omitempty"`
+
+	// DeniedPorts lists external port ranges that may never be forwarded
+	DeniedPorts []PortRange `json:"deniedPorts,omitempty"`
+
+	// AllowedProtocols restricts the protocols that may be forwarded (empty allows all). A
+	// forward of both protocols needs both, or "both", to be allowed.
+	// +kubebuilder:validation:items:Enum=tcp;udp;both
+	AllowedProtocols []string `json:"allowedProtocols,omitempty"`
+
+	// AllowedSourceRanges requires forwards to restrict their source to an address within one
+	// of these CIDRs (empty allows any source). Annotated Services cannot restrict their source.
+	AllowedSourceRanges []string `json:"allowedSourceRanges,omitempty"`
+
+	// MaxRules limits the number of forwarded ports of PortForwardRules and annotated Services
+	// in each selected namespace; 0 forbids any forward (unset is unlimited)
+	// +kubebuilder:validation:Minimum=0
+	MaxRules *int32 `json:"maxRules,omitempty"`
+}
+
+// PortRange is an inclusive range of external ports
+type PortRange struct {
+	// From is the first port of the range
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:required
+	From int `json:"from"`
+
+	// To is the last port of the range (defaults to From)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	To int `json:"to,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Max Rules",type="integer",JSONPath=".spec.maxRules"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PortForwardPolicy restricts the port forwards of PortForwardRules and annotated Services in the
+// namespaces it selects. Every policy selecting a namespace applies.
+type PortForwardPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PortForwardPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PortForwardPolicyList contains a list of PortForwardPolicy
+type PortForwardPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PortForwardPolicy `json:"items"`
+}
+
+// Kinds of resources whose port forwards are checked against PortForwardPolicies
+const (
+	PolicyKindPortForwardRule = "PortForwardRule"
+	PolicyKindService         = "Service"
+)
+
+// PolicyPort is an external port forwarded by a PortForwardRule or annotated Service
+type PolicyPort struct {
+	ExternalPort int
+	Protocol     string
+}
+
+// PolicyRequest describes the port forwards of a PortForwardRule or annotated Service that are
+// checked against PortForwardPolicies
+type PolicyRequest struct {
+	// Kind, Namespace and Name identify the resource requesting the forwards
+	Kind      string
+	Namespace string
+	Name      string
+
+	Ports []PolicyPort
+
+	// SourceIP is the source restriction of the forwards, empty for any source
+	SourceIP string
+
+	// CreatedAt is when the resource was created, zero for resources being created. Only
+	// resources created earlier count towards the maxRules limit of existing resources, so
+	// the oldest forwards keep working when a limit is lowered.
+	CreatedAt time.Time
+}
+
+// PolicyRequest returns the port forwards of the rule that are checked against PortForwardPolicies
+func (r *PortForwardRule) PolicyRequest() PolicyRequest {
+	request := PolicyRequest{Kind: PolicyKindPortForwardRule, Namespace: r.Namespace, Name: r.Name, CreatedAt: r.CreationTimestamp.Time}
+	for _, port := range r.Spec.EffectivePorts() {
+		request.Ports = append(request.Ports, PolicyPort{ExternalPort: port.ExternalPort, Protocol: port.Protocol})
+	}
+	if r.Spec.SourceIPRestriction != nil {
+		request.SourceIP = *r.Spec.SourceIPRestriction
+	}
+	return request
+}
+
+// CreatedBefore reports whether a resource of the given kind, name and creation time was
+// created before the resource making the request. Resources created at the same time are
+// ordered by kind and name.
+func (r PolicyRequest) CreatedBefore(kind, name string, created time.Time) bool {
+	if r.CreatedAt.IsZero() {
+		return true
+	}
+	if !created.Equal(r.CreatedAt) {
+		return created.Before(r.CreatedAt)
+	}
+	return kind+"/"+name < r.Kind+"/"+r.Name
+}
+
+// Contains reports whether port lies within the range
+func (r PortRange) Contains(port int) bool {
+	to := r.To
+	if to == 0 {
+		to = r.From
+	}
+	return port >= r.From && port <= to
+}
+
+// String formats the range as from-to, or a single port
+func (r PortRange) String() string {
+	if r.To == 0 || r.To == r.From {
+		return fmt.Sprintf("%d", r.From)
+	}
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// Selects reports whether the policy applies to a namespace with the given labels
+func (p *PortForwardPolicy) Selects(namespaceLabels map[string]string) (bool, error) {
+	if p.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespaceSelector of PortForwardPolicy %s: %w", p.Name, err)
+	}
+	return selector.Matches(labels.Set(namespaceLabels)), nil
+}
+
+// Check returns the violations of the request against the ports, protocols and source ranges
+// of the policy. The rule count is checked separately since it depends on the whole namespace.
+func (p *PortForwardPolicy) Check(request PolicyRequest) []string {
+	var violations []string
+
+	for _, port := range request.Ports {
+		for _, denied := range p.Spec.DeniedPorts {
+			if denied.Contains(port.ExternalPort) {
+				violations = append(violations, fmt.Sprintf("port %d is denied", port.ExternalPort))
+				break
+			}
+		}
+		if len(p.Spec.AllowedPorts) > 0 && !p.portAllowed(port.ExternalPort) {
+			violations = append(violations, fmt.Sprintf("port %d is outside the allowed ports %s", port.ExternalPort, formatPortRanges(p.Spec.AllowedPorts)))
+		}
+		if len(p.Spec.AllowedProtocols) > 0 && !p.protocolAllowed(port.Protocol) {
+			violations = append(violations, fmt.Sprintf("protocol %s of port %d is not allowed", port.Protocol, port.ExternalPort))
+		}
+	}
+
+	if len(p.Spec.AllowedSourceRanges) > 0 && !p.sourceAllowed(request.SourceIP) {
+		source := request.SourceIP
+		if source == "" {
+			source = "any"
+		}
+		violations = append(violations, fmt.Sprintf("source %s is not within the allowed source ranges %s",
+			source, strings.Join(p.Spec.AllowedSourceRanges, ", ")))
+	}
+
+	return violations
+}
+
+// portAllowed reports whether port lies within one of the allowed ranges
+func (p *PortForwardPolicy) portAllowed(port int) bool {
+	for _, allowed := range p.Spec.AllowedPorts {
+		if allowed.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// protocolAllowed reports whether the allowed protocols cover every protocol forwarded by protocol
+func (p *PortForwardPolicy) protocolAllowed(protocol string) bool {
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if contains(p.Spec.AllowedProtocols, "both") || contains(p.Spec.AllowedProtocols, protocol) {
+		return true
+	}
+	return protocol == "both" && contains(p.Spec.AllowedProtocols, "tcp") && contains(p.Spec.AllowedProtocols, "udp")
+}
+
+// sourceAllowed reports whether the source IP lies within one of the allowed source ranges
+func (p *PortForwardPolicy) sourceAllowed(sourceIP string) bool {
+	ip := net.ParseIP(sourceIP)
+	if ip == nil {
+		return false
+	}
+	for _, sourceRange := range p.Spec.AllowedSourceRanges {
+		_, network, err := net.ParseCIDR(sourceRange)
+		if err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPortRanges formats port ranges as a comma separated list
+func formatPortRanges(ranges []PortRange) string {
+	formatted := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		formatted = append(formatted, r.String())
+	}
+	return strings.Join(formatted, ", ")
+}
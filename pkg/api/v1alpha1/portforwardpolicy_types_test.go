@@ -0,0 +1,120 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPortForwardPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       PortForwardPolicySpec
+		request    PolicyRequest
+		violations []string
+	}{
+		{
+			name:    "empty policy allows everything",
+			request: PolicyRequest{Ports: []PolicyPort{{ExternalPort: 22, Protocol: "tcp"}}},
+		},
+		{
+			name:       "denied port",
+			spec:       PortForwardPolicySpec{DeniedPorts: []PortRange{{From: 22}, {From: 443}}},
+			request:    PolicyRequest{Ports: []PolicyPort{{ExternalPort: 443, Protocol: "tcp"}}},
+			violations: []string{"port 443 is denied"},
+		},
+		{
+			name:    "port inside the allowed range",
+			spec:    PortForwardPolicySpec{AllowedPorts: []PortRange{{From: 27000, To: 27100}}},
+			request: PolicyRequest{Ports: []PolicyPort{{ExternalPort: 27100, Protocol: "udp"}}},
+		},
+		{
+			name:       "port outside the allowed ranges",
+			spec:       PortForwardPolicySpec{AllowedPorts: []PortRange{{From: 27000, To: 27100}, {From: 8080}}},
+			request:    PolicyRequest{Ports: []PolicyPort{{ExternalPort: 8081, Protocol: "tcp"}}},
+			violations: []string{"port 8081 is outside the allowed ports 27000-27100, 8080"},
+		},
+		{
+			name:       "protocol not allowed",
+			spec:       PortForwardPolicySpec{AllowedProtocols: []string{"tcp"}},
+			request:    PolicyRequest{Ports: []PolicyPort{{ExternalPort: 8080, Protocol: "udp"}}},
+			violations: []string{"protocol udp of port 8080 is not allowed"},
+		},
+		{
+			name:    "both is allowed by tcp and udp",
+			spec:    PortForwardPolicySpec{AllowedProtocols: []string{"tcp", "udp"}},
+			request: PolicyRequest{Ports: []PolicyPort{{ExternalPort: 8080, Protocol: "both"}}},
+		},
+		{
+			name:       "both is not allowed by tcp alone",
+			spec:       PortForwardPolicySpec{AllowedProtocols: []string{"tcp"}},
+			request:    PolicyRequest{Ports: []PolicyPort{{ExternalPort: 8080, Protocol: "both"}}},
+			violations: []string{"protocol both of port 8080 is not allowed"},
+		},
+		{
+			name:    "source within the allowed ranges",
+			spec:    PortForwardPolicySpec{AllowedSourceRanges: []string{"203.0.113.0/24"}},
+			request: PolicyRequest{Ports: []PolicyPort{{ExternalPort: 8080, Protocol: "tcp"}}, SourceIP: "203.0.113.7"},
+		},
+		{
+			name:       "unrestricted source",
+			spec:       PortForwardPolicySpec{AllowedSourceRanges: []string{"203.0.113.0/24"}},
+			request:    PolicyRequest{Ports: []PolicyPort{{ExternalPort: 8080, Protocol: "tcp"}}},
+			violations: []string{"source any is not within the allowed source ranges 203.0.113.0/24"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &PortForwardPolicy{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: tt.spec}
+			violations := policy.Check(tt.request)
+			if strings.Join(violations, "; ") != strings.Join(tt.violations, "; ") {
+				t.Errorf("Expected violations %q, got %q", tt.violations, violations)
+			}
+		})
+	}
+}
+
+func TestPortForwardPolicy_Selects(t *testing.T) {
+	all := &PortForwardPolicy{}
+	if selected, err := all.Selects(nil); err != nil || !selected {
+		t.Errorf("Expected a policy without selector to select every namespace, got %v, %v", selected, err)
+	}
+
+	games := &PortForwardPolicy{Spec: PortForwardPolicySpec{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "games"}},
+	}}
+	if selected, err := games.Selects(map[string]string{"team": "games"}); err != nil || !selected {
+		t.Errorf("Expected matching namespace to be selected, got %v, %v", selected, err)
+	}
+	if selected, err := games.Selects(map[string]string{"team": "web"}); err != nil || selected {
+		t.Errorf("Expected other namespace not to be selected, got %v, %v", selected, err)
+	}
+
+	invalid := &PortForwardPolicy{Spec: PortForwardPolicySpec{
+		NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Unknown"}}},
+	}}
+	if _, err := invalid.Selects(nil); err == nil {
+		t.Error("Expected an invalid selector to return an error")
+	}
+}
+
+func TestPolicyRequest_CreatedBefore(t *testing.T) {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	request := PolicyRequest{Kind: PolicyKindPortForwardRule, Name: "b", CreatedAt: created}
+
+	if !request.CreatedBefore(PolicyKindService, "z", created.Add(-time.Minute)) {
+		t.Error("Expected an older resource to be counted")
+	}
+	if request.CreatedBefore(PolicyKindService, "a", created.Add(time.Minute)) {
+		t.Error("Expected a newer resource not to be counted")
+	}
+	if !request.CreatedBefore(PolicyKindPortForwardRule, "a", created) {
+		t.Error("Expected resources created at the same time to be ordered by name")
+	}
+	if !(PolicyRequest{}).CreatedBefore(PolicyKindService, "a", created) {
+		t.Error("Expected every existing resource to count for a resource being created")
+	}
+}
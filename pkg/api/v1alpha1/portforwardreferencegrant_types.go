@@ -0,0 +1,76 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PortForwardReferenceGrantSpec defines which namespaces may reference Services in the grant's namespace
+type PortForwardReferenceGrantSpec struct {
+	// From lists the namespaces whose PortForwardRules may reference Services in this namespace
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:required
+	From []ReferenceGrantFrom `json:"from"`
+
+	// To restricts which Services in this namespace may be referenced (empty allows all Services)
+	To []ReferenceGrantTo `json:"to,omitempty"`
+}
+
+// ReferenceGrantFrom identifies a namespace that is allowed to reference Services
+type ReferenceGrantFrom struct {
+	// Namespace is the namespace of the referencing PortForwardRules
+	// +kubebuilder:required
+	Namespace string `json:"namespace"`
+}
+
+// ReferenceGrantTo identifies a Service that may be referenced
+type ReferenceGrantTo struct {
+	// Name is the Service name (empty allows all Services in the namespace)
+	Name string `json:"name,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Namespaced
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PortForwardReferenceGrant allows PortForwardRules in other namespaces to reference Services in its namespace
+type PortForwardReferenceGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PortForwardReferenceGrantSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PortForwardReferenceGrantList contains a list of PortForwardReferenceGrant
+type PortForwardReferenceGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PortForwardReferenceGrant `json:"items"`
+}
+
+// Permits reports whether the grant allows rules in fromNamespace to reference the named Service
+func (g *PortForwardReferenceGrant) Permits(fromNamespace, serviceName string) bool {
+	fromAllowed := false
+	for _, from := range g.Spec.From {
+		if from.Namespace == fromNamespace {
+			fromAllowed = true
+			break
+		}
+	}
+	if !fromAllowed {
+		return false
+	}
+
+	if len(g.Spec.To) == 0 {
+		return true
+	}
+	for _, to := range g.Spec.To {
+		if to.Name == "" || to.Name == serviceName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package v1alpha1
+
+import "testing"
+
+func TestPortForwardReferenceGrant_Permits(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          PortForwardReferenceGrantSpec
+		fromNamespace string
+		serviceName   string
+		expected      bool
+	}{
+		{
+			name:          "namespace allowed, all services",
+			spec:          PortForwardReferenceGrantSpec{From: []ReferenceGrantFrom{{Namespace: "edge"}}},
+			fromNamespace: "edge",
+			serviceName:   "web",
+			expected:      true,
+		},
+		{
+			name:          "namespace not listed",
+			spec:          PortForwardReferenceGrantSpec{From: []ReferenceGrantFrom{{Namespace: "edge"}}},
+			fromNamespace: "other",
+			serviceName:   "web",
+			expected:      false,
+		},
+		{
+			name: "service listed",
+			spec: PortForwardReferenceGrantSpec{
+				From: []ReferenceGrantFrom{{Namespace: "edge"}},
+				To:   []ReferenceGrantTo{{Name: "api"}, {Name: "web"}},
+			},
+			fromNamespace: "edge",
+			serviceName:   "web",
+			expected:      true,
+		},
+		{
+			name: "service not listed",
+			spec: PortForwardReferenceGrantSpec{
+				From: []ReferenceGrantFrom{{Namespace: "edge"}},
+				To:   []ReferenceGrantTo{{Name: "api"}},
+			},
+			fromNamespace: "edge",
+			serviceName:   "web",
+			expected:      false,
+		},
+		{
+			name: "empty service name allows all services",
+			spec: PortForwardReferenceGrantSpec{
+				From: []ReferenceGrantFrom{{Namespace: "edge"}},
+				To:   []ReferenceGrantTo{{Name: ""}},
+			},
+			fromNamespace: "edge",
+			serviceName:   "web",
+			expected:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grant := &PortForwardReferenceGrant{Spec: tt.spec}
+			if got := grant.Permits(tt.fromNamespace, tt.serviceName); got != tt.expected {
+				t.Errorf("Permits(%q, %q) = %v, expected %v", tt.fromNamespace, tt.serviceName, got, tt.expected)
+			}
+		})
+	}
+}
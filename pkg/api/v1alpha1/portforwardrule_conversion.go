@@ -0,0 +1,662 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"unifi-port-forward/pkg/api/v1beta1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConversionDataAnnotation holds the parts of a v1beta1 spec that v1alpha1 cannot represent,
+// so they survive updates made through the v1alpha1 API
+const ConversionDataAnnotation = "unifi-port-forward.fiskhe.st/conversion-data"
+
+// conversionData is the content of ConversionDataAnnotation
+type conversionData struct {
+	// PortName is the name of a single port mapped onto externalPort
+	PortName string `json:"portName,omitempty"`
+
+	// TargetPortDefaulted records that a single port mapped onto externalPort had no targetPort
+	TargetPortDefaulted bool `json:"targetPortDefaulted,omitempty"`
+}
+
+// isEmpty reports whether nothing needs to be preserved
+func (d *conversionData) isEmpty() bool {
+	return d.PortName == "" && !d.TargetPortDefaulted
+}
+
+var (
+	_ conversion.Convertible = &PortForwardRule{}
+	_ conversion.Convertible = &ClusterPortForwardRule{}
+)
+
+// ConvertTo converts this PortForwardRule to the v1beta1 hub version
+func (r *PortForwardRule) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.PortForwardRule)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 PortForwardRule but got %T", dstRaw)
+	}
+
+	objectMeta, data, err := popConversionData(r.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	dst.ObjectMeta = objectMeta
+	convertSpecToHub(&r.Spec, &dst.Spec, data)
+	convertStatusToHub(&r.Status, &dst.Status)
+	return nil
+}
+
+// ConvertFrom converts the v1beta1 hub version to this PortForwardRule
+func (r *PortForwardRule) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.PortForwardRule)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 PortForwardRule but got %T", srcRaw)
+	}
+
+	data := convertSpecFromHub(&src.Spec, &r.Spec)
+	objectMeta, err := pushConversionData(src.ObjectMeta, data)
+	if err != nil {
+		return err
+	}
+	r.ObjectMeta = objectMeta
+	convertStatusFromHub(&src.Status, &r.Status)
+	return nil
+}
+
+// ConvertTo converts this ClusterPortForwardRule to the v1beta1 hub version
+func (r *ClusterPortForwardRule) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.ClusterPortForwardRule)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 ClusterPortForwardRule but got %T", dstRaw)
+	}
+
+	objectMeta, data, err := popConversionData(r.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	dst.ObjectMeta = objectMeta
+	convertSpecToHub(&r.Spec, &dst.Spec, data)
+	convertStatusToHub(&r.Status, &dst.Status)
+	return nil
+}
+
+// ConvertFrom converts the v1beta1 hub version to this ClusterPortForwardRule
+func (r *ClusterPortForwardRule) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.ClusterPortForwardRule)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 ClusterPortForwardRule but got %T", srcRaw)
+	}
+
+	data := convertSpecFromHub(&src.Spec, &r.Spec)
+	objectMeta, err := pushConversionData(src.ObjectMeta, data)
+	if err != nil {
+		return err
+	}
+	r.ObjectMeta = objectMeta
+	convertStatusFromHub(&src.Status, &r.Status)
+	return nil
+}
+
+// popConversionData returns a copy of the object metadata without ConversionDataAnnotation,
+// together with the data it held
+func popConversionData(objectMeta metav1.ObjectMeta) (metav1.ObjectMeta, conversionData, error) {
+	var data conversionData
+	out := *objectMeta.DeepCopy()
+
+	value, exists := out.Annotations[ConversionDataAnnotation]
+	if !exists {
+		return out, data, nil
+	}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return out, data, fmt.Errorf("invalid %s annotation: %w", ConversionDataAnnotation, err)
+	}
+
+	delete(out.Annotations, ConversionDataAnnotation)
+	if len(out.Annotations) == 0 {
+		out.Annotations = nil
+	}
+	return out, data, nil
+}
+
+// pushConversionData returns a copy of the object metadata with data stored in
+// ConversionDataAnnotation, if there is anything to preserve
+func pushConversionData(objectMeta metav1.ObjectMeta, data conversionData) (metav1.ObjectMeta, error) {
+	out := *objectMeta.DeepCopy()
+	if data.isEmpty() {
+		return out, nil
+	}
+
+	value, err := json.Marshal(data)
+	if err != nil {
+		return out, fmt.Errorf("failed to encode %s annotation: %w", ConversionDataAnnotation, err)
+	}
+	if out.Annotations == nil {
+		out.Annotations = make(map[string]string)
+	}
+	out.Annotations[ConversionDataAnnotation] = string(value)
+	return out, nil
+}
+
+// convertSpecToHub converts a v1alpha1 spec to v1beta1, restoring the preserved data
+func convertSpecToHub(src *PortForwardRuleSpec, dst *v1beta1.PortForwardRuleSpec, data conversionData) {
+	if src.ServiceRef != nil {
+		dst.ServiceRef = &v1beta1.ServiceReference{
+			Name:      src.ServiceRef.Name,
+			Namespace: copyString(src.ServiceRef.Namespace),
+		}
+	}
+
+	if len(src.Ports) > 0 {
+		dst.Ports = nil
+		for i := range src.Ports {
+			dst.Ports = append(dst.Ports, convertPortToHub(&src.Ports[i]))
+		}
+	} else {
+		port := v1beta1.PortForwardPort{
+			Name:         data.PortName,
+			ExternalPort: src.ExternalPort,
+			Protocol:     src.Protocol,
+		}
+
+		var targetPort *intstr.IntOrString
+		if src.ServiceRef != nil && src.ServiceRef.Port != "" {
+			parsed := intstr.Parse(src.ServiceRef.Port)
+			targetPort = &parsed
+		} else if src.ServiceRef == nil && src.DestinationPort != nil {
+			parsed := intstr.FromInt32(int32(*src.DestinationPort))
+			targetPort = &parsed
+		}
+		// Keep targetPort unset if it was unset in v1beta1 and still holds the default
+		if data.TargetPortDefaulted && targetPort != nil && targetPort.String() == fmt.Sprintf("%d", src.ExternalPort) {
+			targetPort = nil
+		}
+		port.TargetPort = targetPort
+
+		dst.Ports = []v1beta1.PortForwardPort{port}
+	}
+
+	dst.Cutover = convertCutoverToHub(src.Cutover)
+
+	if src.DestinationIP != nil {
+		dst.DestinationIPs = []string{*src.DestinationIP}
+	}
+	dst.Failover = convertFailoverToHub(src.Failover)
+
+	enabled := src.Enabled
+	dst.Enabled = &enabled
+	dst.Schedule = convertScheduleToHub(src.Schedule)
+	dst.ExpiresAt = src.ExpiresAt.DeepCopy()
+	dst.TTL = copyDuration(src.TTL)
+	dst.DeleteOnExpiry = src.DeleteOnExpiry
+	dst.ReadinessGate = src.ReadinessGate
+	dst.Description = src.Description
+
+	if src.SourceIPRestriction != nil && *src.SourceIPRestriction != "" {
+		dst.SourceIPRestrictions = []string{*src.SourceIPRestriction}
+	}
+
+	dst.Interface = src.Interface
+	dst.Priority = src.Priority
+	dst.ConflictPolicy = src.ConflictPolicy
+	dst.LogEnabled = src.LogEnabled
+}
+
+// convertSpecFromHub converts a v1beta1 spec to v1alpha1, returning what v1alpha1 cannot hold
+func convertSpecFromHub(src *v1beta1.PortForwardRuleSpec, dst *PortForwardRuleSpec) conversionData {
+	var data conversionData
+
+	// A single port without overrides maps onto the single-port fields older clients understand
+	singlePort := len(src.Ports) == 1 && !hasPortOverrides(&src.Ports[0])
+
+	var targetPort string
+	if singlePort {
+		port := src.Ports[0]
+		dst.ExternalPort = port.ExternalPort
+		dst.Protocol = port.Protocol
+		data.PortName = port.Name
+
+		if port.TargetPort != nil {
+			targetPort = port.TargetPort.String()
+		} else {
+			targetPort = fmt.Sprintf("%d", port.ExternalPort)
+			data.TargetPortDefaulted = true
+		}
+	} else {
+		dst.Ports = nil
+		for i := range src.Ports {
+			dst.Ports = append(dst.Ports, convertPortFromHub(&src.Ports[i]))
+		}
+	}
+
+	if src.ServiceRef != nil {
+		dst.ServiceRef = &ServiceReference{
+			Name:      src.ServiceRef.Name,
+			Namespace: copyString(src.ServiceRef.Namespace),
+			Port:      targetPort,
+		}
+	}
+
+	dst.Cutover = convertCutoverFromHub(src.Cutover)
+
+	// The v1beta1 schema allows at most one destination and one source restriction
+	if len(src.DestinationIPs) > 0 {
+		destinationIP := src.DestinationIPs[0]
+		dst.DestinationIP = &destinationIP
+	}
+	dst.Failover = convertFailoverFromHub(src.Failover)
+
+	if singlePort && (dst.DestinationIP != nil || dst.Failover != nil) {
+		destinationPort := src.Ports[0].ExternalPort
+		if tp := src.Ports[0].TargetPort; tp != nil && tp.Type == intstr.Int {
+			destinationPort = tp.IntValue()
+		}
+		dst.DestinationPort = &destinationPort
+	}
+
+	dst.Enabled = src.Enabled == nil || *src.Enabled
+	dst.Schedule = convertScheduleFromHub(src.Schedule)
+	dst.ExpiresAt = src.ExpiresAt.DeepCopy()
+	dst.TTL = copyDuration(src.TTL)
+	dst.DeleteOnExpiry = src.DeleteOnExpiry
+	dst.ReadinessGate = src.ReadinessGate
+	dst.Description = src.Description
+
+	if len(src.SourceIPRestrictions) > 0 {
+		sourceIPRestriction := src.SourceIPRestrictions[0]
+		dst.SourceIPRestriction = &sourceIPRestriction
+	}
+
+	dst.Interface = src.Interface
+	dst.Priority = src.Priority
+	dst.ConflictPolicy = src.ConflictPolicy
+	dst.LogEnabled = src.LogEnabled
+
+	return data
+}
+
+// hasPortOverrides reports whether the port overrides any rule-level setting
+func hasPortOverrides(port *v1beta1.PortForwardPort) bool {
+	return port.Enabled != nil || port.Interface != "" || port.LogEnabled != nil
+}
+
+// convertPortToHub converts a v1alpha1 port to v1beta1
+func convertPortToHub(src *PortForwardPort) v1beta1.PortForwardPort {
+	dst := v1beta1.PortForwardPort{
+		Name:         src.Name,
+		ExternalPort: src.ExternalPort,
+		Protocol:     src.Protocol,
+		Enabled:      copyBool(src.Enabled),
+		Interface:    src.Interface,
+		LogEnabled:   copyBool(src.LogEnabled),
+	}
+	if src.TargetPort != nil {
+		targetPort := *src.TargetPort
+		dst.TargetPort = &targetPort
+	}
+	return dst
+}
+
+// convertPortFromHub converts a v1beta1 port to v1alpha1
+func convertPortFromHub(src *v1beta1.PortForwardPort) PortForwardPort {
+	dst := PortForwardPort{
+		Name:         src.Name,
+		ExternalPort: src.ExternalPort,
+		Protocol:     src.Protocol,
+		Enabled:      copyBool(src.Enabled),
+		Interface:    src.Interface,
+		LogEnabled:   copyBool(src.LogEnabled),
+	}
+	if src.TargetPort != nil {
+		targetPort := *src.TargetPort
+		dst.TargetPort = &targetPort
+	}
+	return dst
+}
+
+// convertScheduleToHub converts a v1alpha1 schedule to v1beta1
+func convertScheduleToHub(src *PortForwardSchedule) *v1beta1.PortForwardSchedule {
+	if src == nil {
+		return nil
+	}
+	dst := &v1beta1.PortForwardSchedule{TimeZone: src.TimeZone}
+	for _, window := range src.Windows {
+		dst.Windows = append(dst.Windows, v1beta1.ScheduleWindow(window))
+	}
+	return dst
+}
+
+// convertScheduleFromHub converts a v1beta1 schedule to v1alpha1
+func convertScheduleFromHub(src *v1beta1.PortForwardSchedule) *PortForwardSchedule {
+	if src == nil {
+		return nil
+	}
+	dst := &PortForwardSchedule{TimeZone: src.TimeZone}
+	for _, window := range src.Windows {
+		dst.Windows = append(dst.Windows, ScheduleWindow(window))
+	}
+	return dst
+}
+
+// convertFailoverToHub converts a v1alpha1 failover to v1beta1
+func convertFailoverToHub(src *DestinationFailover) *v1beta1.DestinationFailover {
+	if src == nil {
+		return nil
+	}
+	dst := &v1beta1.DestinationFailover{HealthCheck: convertHealthCheckToHub(src.HealthCheck)}
+	for _, destination := range src.Destinations {
+		dst.Destinations = append(dst.Destinations, v1beta1.FailoverDestination(destination))
+	}
+	return dst
+}
+
+// convertFailoverFromHub converts a v1beta1 failover to v1alpha1
+func convertFailoverFromHub(src *v1beta1.DestinationFailover) *DestinationFailover {
+	if src == nil {
+		return nil
+	}
+	dst := &DestinationFailover{HealthCheck: convertHealthCheckFromHub(src.HealthCheck)}
+	for _, destination := range src.Destinations {
+		dst.Destinations = append(dst.Destinations, FailoverDestination(destination))
+	}
+	return dst
+}
+
+// convertHealthCheckToHub converts a v1alpha1 health check to v1beta1
+func convertHealthCheckToHub(src HealthCheck) v1beta1.HealthCheck {
+	return v1beta1.HealthCheck{
+		Protocol:         src.Protocol,
+		Port:             src.Port,
+		Path:             src.Path,
+		Interval:         copyDuration(src.Interval),
+		Timeout:          copyDuration(src.Timeout),
+		FailureThreshold: src.FailureThreshold,
+		SuccessThreshold: src.SuccessThreshold,
+	}
+}
+
+// convertHealthCheckFromHub converts a v1beta1 health check to v1alpha1
+func convertHealthCheckFromHub(src v1beta1.HealthCheck) HealthCheck {
+	return HealthCheck{
+		Protocol:         src.Protocol,
+		Port:             src.Port,
+		Path:             src.Path,
+		Interval:         copyDuration(src.Interval),
+		Timeout:          copyDuration(src.Timeout),
+		FailureThreshold: src.FailureThreshold,
+		SuccessThreshold: src.SuccessThreshold,
+	}
+}
+
+// convertCutoverToHub converts a v1alpha1 cutover to v1beta1
+func convertCutoverToHub(src *ServiceCutover) *v1beta1.ServiceCutover {
+	if src == nil {
+		return nil
+	}
+	dst := &v1beta1.ServiceCutover{
+		Green: v1beta1.CutoverService{
+			Name:      src.Green.Name,
+			Namespace: copyString(src.Green.Namespace),
+		},
+		Active: src.Active,
+	}
+	if src.HealthCheck != nil {
+		healthCheck := convertHealthCheckToHub(*src.HealthCheck)
+		dst.HealthCheck = &healthCheck
+	}
+	return dst
+}
+
+// convertCutoverFromHub converts a v1beta1 cutover to v1alpha1
+func convertCutoverFromHub(src *v1beta1.ServiceCutover) *ServiceCutover {
+	if src == nil {
+		return nil
+	}
+	dst := &ServiceCutover{
+		Green: CutoverService{
+			Name:      src.Green.Name,
+			Namespace: copyString(src.Green.Namespace),
+		},
+		Active: src.Active,
+	}
+	if src.HealthCheck != nil {
+		healthCheck := convertHealthCheckFromHub(*src.HealthCheck)
+		dst.HealthCheck = &healthCheck
+	}
+	return dst
+}
+
+// convertCutoverStatusToHub converts a v1alpha1 cutover status to v1beta1
+func convertCutoverStatusToHub(src *CutoverStatus) *v1beta1.CutoverStatus {
+	if src == nil {
+		return nil
+	}
+	return &v1beta1.CutoverStatus{
+		Active:               src.Active,
+		Phase:                src.Phase,
+		LastCutoverTime:      src.LastCutoverTime.DeepCopy(),
+		RolledBackGeneration: src.RolledBackGeneration,
+		ConsecutiveFailures:  src.ConsecutiveFailures,
+		ConsecutiveSuccesses: src.ConsecutiveSuccesses,
+		LastProbeTime:        src.LastProbeTime.DeepCopy(),
+		Message:              src.Message,
+	}
+}
+
+// convertCutoverStatusFromHub converts a v1beta1 cutover status to v1alpha1
+func convertCutoverStatusFromHub(src *v1beta1.CutoverStatus) *CutoverStatus {
+	if src == nil {
+		return nil
+	}
+	return &CutoverStatus{
+		Active:               src.Active,
+		Phase:                src.Phase,
+		LastCutoverTime:      src.LastCutoverTime.DeepCopy(),
+		RolledBackGeneration: src.RolledBackGeneration,
+		ConsecutiveFailures:  src.ConsecutiveFailures,
+		ConsecutiveSuccesses: src.ConsecutiveSuccesses,
+		LastProbeTime:        src.LastProbeTime.DeepCopy(),
+		Message:              src.Message,
+	}
+}
+
+// convertFailoverStatusToHub converts a v1alpha1 failover status to v1beta1
+func convertFailoverStatusToHub(src *FailoverStatus) *v1beta1.FailoverStatus {
+	if src == nil {
+		return nil
+	}
+	dst := &v1beta1.FailoverStatus{ActiveDestination: src.ActiveDestination}
+	for _, destination := range src.Destinations {
+		dst.Destinations = append(dst.Destinations, v1beta1.DestinationHealth{
+			IP:                   destination.IP,
+			Healthy:              destination.Healthy,
+			ConsecutiveFailures:  destination.ConsecutiveFailures,
+			ConsecutiveSuccesses: destination.ConsecutiveSuccesses,
+			LastProbeTime:        destination.LastProbeTime.DeepCopy(),
+			Message:              destination.Message,
+		})
+	}
+	for _, event := range src.History {
+		dst.History = append(dst.History, v1beta1.FailoverEvent{
+			Time:   *event.Time.DeepCopy(),
+			From:   event.From,
+			To:     event.To,
+			Reason: event.Reason,
+		})
+	}
+	return dst
+}
+
+// convertFailoverStatusFromHub converts a v1beta1 failover status to v1alpha1
+func convertFailoverStatusFromHub(src *v1beta1.FailoverStatus) *FailoverStatus {
+	if src == nil {
+		return nil
+	}
+	dst := &FailoverStatus{ActiveDestination: src.ActiveDestination}
+	for _, destination := range src.Destinations {
+		dst.Destinations = append(dst.Destinations, DestinationHealth{
+			IP:                   destination.IP,
+			Healthy:              destination.Healthy,
+			ConsecutiveFailures:  destination.ConsecutiveFailures,
+			ConsecutiveSuccesses: destination.ConsecutiveSuccesses,
+			LastProbeTime:        destination.LastProbeTime.DeepCopy(),
+			Message:              destination.Message,
+		})
+	}
+	for _, event := range src.History {
+		dst.History = append(dst.History, FailoverEvent{
+			Time:   *event.Time.DeepCopy(),
+			From:   event.From,
+			To:     event.To,
+			Reason: event.Reason,
+		})
+	}
+	return dst
+}
+
+// convertStatusToHub converts a v1alpha1 status to v1beta1
+func convertStatusToHub(src *PortForwardRuleStatus, dst *v1beta1.PortForwardRuleStatus) {
+	dst.Phase = src.Phase
+	dst.ObservedGeneration = src.ObservedGeneration
+	dst.LastAppliedTime = src.LastAppliedTime.DeepCopy()
+	dst.NextScheduleChange = src.NextScheduleChange.DeepCopy()
+	dst.ExpiresAt = src.ExpiresAt.DeepCopy()
+	dst.ExposureHash = src.ExposureHash
+	dst.RouterRuleID = src.RouterRuleID
+	dst.AppliedConfigHash = src.AppliedConfigHash
+
+	dst.Ports = nil
+	for _, port := range src.Ports {
+		dst.Ports = append(dst.Ports, v1beta1.PortStatus(port))
+	}
+
+	dst.ServiceStatus = nil
+	if src.ServiceStatus != nil {
+		dst.ServiceStatus = &v1beta1.ServiceStatus{
+			Name:            src.ServiceStatus.Name,
+			Namespace:       src.ServiceStatus.Namespace,
+			LoadBalancerIP:  src.ServiceStatus.LoadBalancerIP,
+			ServicePort:     src.ServiceStatus.ServicePort,
+			ServicePortName: src.ServiceStatus.ServicePortName,
+		}
+	}
+
+	dst.Failover = convertFailoverStatusToHub(src.Failover)
+	dst.Cutover = convertCutoverStatusToHub(src.Cutover)
+	dst.Conditions = copyConditions(src.Conditions)
+
+	dst.Conflicts = nil
+	for _, conflict := range src.Conflicts {
+		dst.Conflicts = append(dst.Conflicts, v1beta1.PortConflict{
+			ConflictingNamespace: conflict.ConflictingNamespace,
+			ConflictingResource:  conflict.ConflictingResource,
+			ConflictType:         conflict.ConflictType,
+			Description:          conflict.Description,
+			Severity:             conflict.Severity,
+			Timestamp:            conflict.Timestamp.DeepCopy(),
+		})
+	}
+
+	dst.ErrorInfo = nil
+	if src.ErrorInfo != nil {
+		dst.ErrorInfo = &v1beta1.ErrorInfo{
+			Code:            src.ErrorInfo.Code,
+			Message:         src.ErrorInfo.Message,
+			LastFailureTime: src.ErrorInfo.LastFailureTime.DeepCopy(),
+			RetryCount:      src.ErrorInfo.RetryCount,
+		}
+	}
+}
+
+// convertStatusFromHub converts a v1beta1 status to v1alpha1
+func convertStatusFromHub(src *v1beta1.PortForwardRuleStatus, dst *PortForwardRuleStatus) {
+	dst.Phase = src.Phase
+	dst.ObservedGeneration = src.ObservedGeneration
+	dst.LastAppliedTime = src.LastAppliedTime.DeepCopy()
+	dst.NextScheduleChange = src.NextScheduleChange.DeepCopy()
+	dst.ExpiresAt = src.ExpiresAt.DeepCopy()
+	dst.ExposureHash = src.ExposureHash
+	dst.RouterRuleID = src.RouterRuleID
+	dst.AppliedConfigHash = src.AppliedConfigHash
+
+	dst.Ports = nil
+	for _, port := range src.Ports {
+		dst.Ports = append(dst.Ports, PortStatus(port))
+	}
+
+	dst.ServiceStatus = nil
+	if src.ServiceStatus != nil {
+		dst.ServiceStatus = &ServiceStatus{
+			Name:            src.ServiceStatus.Name,
+			Namespace:       src.ServiceStatus.Namespace,
+			LoadBalancerIP:  src.ServiceStatus.LoadBalancerIP,
+			ServicePort:     src.ServiceStatus.ServicePort,
+			ServicePortName: src.ServiceStatus.ServicePortName,
+		}
+	}
+
+	dst.Failover = convertFailoverStatusFromHub(src.Failover)
+	dst.Cutover = convertCutoverStatusFromHub(src.Cutover)
+	dst.Conditions = copyConditions(src.Conditions)
+
+	dst.Conflicts = nil
+	for _, conflict := range src.Conflicts {
+		dst.Conflicts = append(dst.Conflicts, PortConflict{
+			ConflictingNamespace: conflict.ConflictingNamespace,
+			ConflictingResource:  conflict.ConflictingResource,
+			ConflictType:         conflict.ConflictType,
+			Description:          conflict.Description,
+			Severity:             conflict.Severity,
+			Timestamp:            conflict.Timestamp.DeepCopy(),
+		})
+	}
+
+	dst.ErrorInfo = nil
+	if src.ErrorInfo != nil {
+		dst.ErrorInfo = &ErrorInfo{
+			Code:            src.ErrorInfo.Code,
+			Message:         src.ErrorInfo.Message,
+			LastFailureTime: src.ErrorInfo.LastFailureTime.DeepCopy(),
+			RetryCount:      src.ErrorInfo.RetryCount,
+		}
+	}
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	out := *s
+	return &out
+}
+
+func copyBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	out := *b
+	return &out
+}
+
+func copyDuration(d *metav1.Duration) *metav1.Duration {
+	if d == nil {
+		return nil
+	}
+	out := *d
+	return &out
+}
+
+func copyConditions(conditions []metav1.Condition) []metav1.Condition {
+	if conditions == nil {
+		return nil
+	}
+	out := make([]metav1.Condition, len(conditions))
+	for i := range conditions {
+		conditions[i].DeepCopyInto(&out[i])
+	}
+	return out
+}
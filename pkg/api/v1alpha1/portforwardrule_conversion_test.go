@@ -0,0 +1,332 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1beta1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestPortForwardRule_ConvertFromHub_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		hub  *v1beta1.PortForwardRule
+	}{
+		{
+			name: "multi-port serviceRef rule",
+			hub: &v1beta1.PortForwardRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "mail", Namespace: "default", Labels: map[string]string{"app": "mail"}},
+				Spec: v1beta1.PortForwardRuleSpec{
+					Ports: []v1beta1.PortForwardPort{
+						{Name: "smtp", ExternalPort: 25, Protocol: "tcp", TargetPort: intOrStringPtr(intstr.FromString("smtp"))},
+						{Name: "imaps", ExternalPort: 993, Protocol: "tcp"},
+					},
+					ServiceRef:     &v1beta1.ServiceReference{Name: "mail", Namespace: stringPtr("mail")},
+					Enabled:        boolPtr(false),
+					ReadinessGate:  true,
+					Interface:      "wan",
+					Priority:       100,
+					ConflictPolicy: "warn",
+				},
+				Status: v1beta1.PortForwardRuleStatus{
+					Phase:        "Active",
+					RouterRuleID: "abc123",
+					ExposureHash: "0123456789abcdef",
+					ServiceStatus: &v1beta1.ServiceStatus{
+						Name:           "mail",
+						LoadBalancerIP: "192.168.1.50",
+					},
+					Conditions: []metav1.Condition{{Type: "InSync", Status: metav1.ConditionTrue}},
+				},
+			},
+		},
+		{
+			name: "multi-port rule with per-port overrides and status",
+			hub: &v1beta1.PortForwardRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "game", Namespace: "games"},
+				Spec: v1beta1.PortForwardRuleSpec{
+					Ports: []v1beta1.PortForwardPort{
+						{Name: "game", ExternalPort: 27015, Protocol: "both", TargetPort: intOrStringPtr(intstr.FromString("game"))},
+						{Name: "rcon", ExternalPort: 27020, Protocol: "tcp", Enabled: boolPtr(false), Interface: "wan2", LogEnabled: boolPtr(true)},
+					},
+					ServiceRef: &v1beta1.ServiceReference{Name: "game"},
+					Enabled:    boolPtr(true),
+				},
+				Status: v1beta1.PortForwardRuleStatus{
+					Phase:        "Active",
+					RouterRuleID: "game123",
+					Ports: []v1beta1.PortStatus{
+						{Name: "game", ExternalPort: 27015, Protocol: "both", Phase: "Active", RouterRuleID: "game123"},
+						{Name: "rcon", ExternalPort: 27020, Protocol: "tcp", Phase: "Failed", Message: "port conflict"},
+					},
+				},
+			},
+		},
+		{
+			name: "scheduled rule",
+			hub: &v1beta1.PortForwardRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "office", Namespace: "default"},
+				Spec: v1beta1.PortForwardRuleSpec{
+					Ports:      []v1beta1.PortForwardPort{{ExternalPort: 3389, Protocol: "tcp"}},
+					ServiceRef: &v1beta1.ServiceReference{Name: "rdp"},
+					Enabled:    boolPtr(true),
+					Schedule: &v1beta1.PortForwardSchedule{
+						TimeZone: "Europe/Stockholm",
+						Windows: []v1beta1.ScheduleWindow{
+							{Start: "0 8 * * mon-fri", Duration: metav1.Duration{Duration: 9 * time.Hour}},
+						},
+					},
+				},
+				Status: v1beta1.PortForwardRuleStatus{
+					Phase:              "Active",
+					NextScheduleChange: &metav1.Time{Time: time.Date(2026, 3, 4, 16, 0, 0, 0, time.UTC)},
+				},
+			},
+		},
+		{
+			name: "expiring rule",
+			hub: &v1beta1.PortForwardRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"},
+				Spec: v1beta1.PortForwardRuleSpec{
+					Ports:          []v1beta1.PortForwardPort{{ExternalPort: 2222, Protocol: "tcp"}},
+					ServiceRef:     &v1beta1.ServiceReference{Name: "ssh"},
+					Enabled:        boolPtr(true),
+					TTL:            &metav1.Duration{Duration: 2 * time.Hour},
+					DeleteOnExpiry: true,
+				},
+				Status: v1beta1.PortForwardRuleStatus{
+					Phase:     "Expired",
+					ExpiresAt: &metav1.Time{Time: time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+				},
+			},
+		},
+		{
+			name: "standalone rule with a source restriction",
+			hub: &v1beta1.PortForwardRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "nas", Namespace: "default"},
+				Spec: v1beta1.PortForwardRuleSpec{
+					Ports:                []v1beta1.PortForwardPort{{ExternalPort: 8443, Protocol: "both"}},
+					DestinationIPs:       []string{"192.168.1.10"},
+					SourceIPRestrictions: []string{"198.51.100.7"},
+					Enabled:              boolPtr(true),
+					LogEnabled:           true,
+				},
+			},
+		},
+		{
+			name: "serviceRef rule with cutover",
+			hub: &v1beta1.PortForwardRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+				Spec: v1beta1.PortForwardRuleSpec{
+					Ports:      []v1beta1.PortForwardPort{{ExternalPort: 443, Protocol: "tcp", TargetPort: intOrStringPtr(intstr.FromString("https"))}},
+					ServiceRef: &v1beta1.ServiceReference{Name: "shop-blue"},
+					Cutover: &v1beta1.ServiceCutover{
+						Green:       v1beta1.CutoverService{Name: "shop-green", Namespace: stringPtr("shop")},
+						Active:      "green",
+						HealthCheck: &v1beta1.HealthCheck{Protocol: "http", Path: "/healthz", SuccessThreshold: 3},
+					},
+					Enabled: boolPtr(true),
+				},
+				Status: v1beta1.PortForwardRuleStatus{
+					Phase: "Active",
+					Cutover: &v1beta1.CutoverStatus{
+						Active:               "blue",
+						Phase:                "RolledBack",
+						LastCutoverTime:      &metav1.Time{Time: time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+						RolledBackGeneration: 2,
+						ConsecutiveFailures:  3,
+						Message:              "http status 503",
+					},
+				},
+			},
+		},
+		{
+			name: "standalone rule with failover",
+			hub: &v1beta1.PortForwardRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "vpn", Namespace: "default"},
+				Spec: v1beta1.PortForwardRuleSpec{
+					Ports: []v1beta1.PortForwardPort{{ExternalPort: 1194, Protocol: "udp", TargetPort: intOrStringPtr(intstr.FromInt32(1194))}},
+					Failover: &v1beta1.DestinationFailover{
+						Destinations: []v1beta1.FailoverDestination{{IP: "192.168.1.20", Priority: 100}, {IP: "192.168.1.21"}},
+						HealthCheck: v1beta1.HealthCheck{
+							Protocol:         "http",
+							Port:             8080,
+							Path:             "/healthz",
+							Interval:         &metav1.Duration{Duration: 5 * time.Second},
+							FailureThreshold: 2,
+						},
+					},
+					Enabled: boolPtr(true),
+				},
+				Status: v1beta1.PortForwardRuleStatus{
+					Phase: "Active",
+					Failover: &v1beta1.FailoverStatus{
+						ActiveDestination: "192.168.1.21",
+						Destinations: []v1beta1.DestinationHealth{
+							{IP: "192.168.1.20", ConsecutiveFailures: 2, Message: "connection refused"},
+							{IP: "192.168.1.21", Healthy: true, ConsecutiveSuccesses: 4},
+						},
+						History: []v1beta1.FailoverEvent{
+							{Time: metav1.Time{Time: time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)}, From: "192.168.1.20", To: "192.168.1.21", Reason: "Failover"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spoke := &PortForwardRule{}
+			if err := spoke.ConvertFrom(tt.hub); err != nil {
+				t.Fatalf("ConvertFrom failed: %v", err)
+			}
+
+			restored := &v1beta1.PortForwardRule{}
+			if err := spoke.ConvertTo(restored); err != nil {
+				t.Fatalf("ConvertTo failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.hub.ObjectMeta, restored.ObjectMeta) {
+				t.Errorf("ObjectMeta not preserved:\nwant %+v\ngot  %+v", tt.hub.ObjectMeta, restored.ObjectMeta)
+			}
+			if !reflect.DeepEqual(tt.hub.Spec, restored.Spec) {
+				t.Errorf("Spec not preserved:\nwant %+v\ngot  %+v", tt.hub.Spec, restored.Spec)
+			}
+			if !reflect.DeepEqual(tt.hub.Status, restored.Status) {
+				t.Errorf("Status not preserved:\nwant %+v\ngot  %+v", tt.hub.Status, restored.Status)
+			}
+		})
+	}
+}
+
+func TestPortForwardRule_ConvertFromHub(t *testing.T) {
+	hub := &v1beta1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "nas", Namespace: "default"},
+		Spec: v1beta1.PortForwardRuleSpec{
+			Ports:          []v1beta1.PortForwardPort{{ExternalPort: 8443, Protocol: "tcp", TargetPort: intOrStringPtr(intstr.FromInt32(443))}},
+			DestinationIPs: []string{"192.168.1.10"},
+			Enabled:        boolPtr(false),
+		},
+	}
+
+	spoke := &PortForwardRule{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+
+	if spoke.Spec.ExternalPort != 8443 || spoke.Spec.Protocol != "tcp" {
+		t.Errorf("Expected first port to map to externalPort/protocol, got %d/%s", spoke.Spec.ExternalPort, spoke.Spec.Protocol)
+	}
+	if spoke.Spec.DestinationIP == nil || *spoke.Spec.DestinationIP != "192.168.1.10" {
+		t.Errorf("Expected destinationIP 192.168.1.10, got %v", spoke.Spec.DestinationIP)
+	}
+	if spoke.Spec.DestinationPort == nil || *spoke.Spec.DestinationPort != 443 {
+		t.Errorf("Expected destinationPort 443, got %v", spoke.Spec.DestinationPort)
+	}
+	if spoke.Spec.Enabled {
+		t.Error("Expected disabled rule to stay disabled")
+	}
+	if _, exists := spoke.Annotations[ConversionDataAnnotation]; exists {
+		t.Error("Expected no conversion data for a fully representable rule")
+	}
+}
+
+func TestPortForwardRule_ConvertTo_ChangedThroughV1alpha1(t *testing.T) {
+	hub := &v1beta1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1beta1.PortForwardRuleSpec{
+			Ports:      []v1beta1.PortForwardPort{{ExternalPort: 8080, Protocol: "tcp"}},
+			ServiceRef: &v1beta1.ServiceReference{Name: "web"},
+		},
+	}
+
+	spoke := &PortForwardRule{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	if spoke.Spec.ServiceRef.Port != "8080" {
+		t.Fatalf("Expected unset targetPort to default to the external port, got %q", spoke.Spec.ServiceRef.Port)
+	}
+
+	// A v1alpha1 client changing the service port must not be overridden by the preserved default
+	spoke.Spec.ServiceRef.Port = "http"
+	restored := &v1beta1.PortForwardRule{}
+	if err := spoke.ConvertTo(restored); err != nil {
+		t.Fatalf("ConvertTo failed: %v", err)
+	}
+	if restored.Spec.Ports[0].TargetPort == nil || restored.Spec.Ports[0].TargetPort.String() != "http" {
+		t.Errorf("Expected targetPort http, got %v", restored.Spec.Ports[0].TargetPort)
+	}
+}
+
+func TestClusterPortForwardRule_ConvertRoundTrip(t *testing.T) {
+	hub := &v1beta1.ClusterPortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress"},
+		Spec: v1beta1.PortForwardRuleSpec{
+			Ports: []v1beta1.PortForwardPort{
+				{Name: "http", ExternalPort: 80, Protocol: "tcp"},
+				{Name: "https", ExternalPort: 443, Protocol: "tcp"},
+			},
+			ServiceRef: &v1beta1.ServiceReference{Name: "ingress-nginx", Namespace: stringPtr("ingress-nginx")},
+			Enabled:    boolPtr(true),
+		},
+	}
+
+	spoke := &ClusterPortForwardRule{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	restored := &v1beta1.ClusterPortForwardRule{}
+	if err := spoke.ConvertTo(restored); err != nil {
+		t.Fatalf("ConvertTo failed: %v", err)
+	}
+	if !reflect.DeepEqual(hub.Spec, restored.Spec) {
+		t.Errorf("Spec not preserved:\nwant %+v\ngot  %+v", hub.Spec, restored.Spec)
+	}
+}
+
+func TestPortForwardRule_ConvertFromHub_MultiPort(t *testing.T) {
+	hub := &v1beta1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1beta1.PortForwardRuleSpec{
+			Ports: []v1beta1.PortForwardPort{
+				{Name: "http", ExternalPort: 80, Protocol: "tcp", TargetPort: intOrStringPtr(intstr.FromString("http"))},
+				{Name: "https", ExternalPort: 443, Protocol: "tcp", TargetPort: intOrStringPtr(intstr.FromString("https"))},
+			},
+			ServiceRef:     &v1beta1.ServiceReference{Name: "web"},
+			Enabled:        boolPtr(true),
+			ConflictPolicy: "warn",
+		},
+	}
+
+	spoke := &PortForwardRule{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+
+	if spoke.Spec.ExternalPort != 0 || spoke.Spec.ServiceRef.Port != "" {
+		t.Errorf("Expected single-port fields to stay empty, got externalPort %d and serviceRef.port %q", spoke.Spec.ExternalPort, spoke.Spec.ServiceRef.Port)
+	}
+	if len(spoke.Spec.Ports) != 2 || spoke.Spec.Ports[1].Name != "https" || spoke.Spec.Ports[1].Target() != "https" {
+		t.Errorf("Expected both ports in spec.ports, got %+v", spoke.Spec.Ports)
+	}
+	if _, exists := spoke.Annotations[ConversionDataAnnotation]; exists {
+		t.Error("Expected no conversion data for ports v1alpha1 can express")
+	}
+	if errs := spoke.ValidateCreate(); len(errs) > 0 {
+		t.Errorf("Expected converted rule to be valid, got %v", errs)
+	}
+}
@@ -0,0 +1,80 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Services of a cutover
+const (
+	CutoverBlue  = "blue"
+	CutoverGreen = "green"
+)
+
+// Cutover phases
+const (
+	CutoverPhaseStable     = "Stable"
+	CutoverPhasePending    = "Pending"
+	CutoverPhaseVerifying  = "Verifying"
+	CutoverPhaseRolledBack = "RolledBack"
+)
+
+var validCutoverServices = []string{CutoverBlue, CutoverGreen}
+
+// Selected returns the Service the cutover selects, defaulting to blue
+func (c *ServiceCutover) Selected() string {
+	if c.Active == "" {
+		return CutoverBlue
+	}
+	return c.Active
+}
+
+// CutoverServiceRef returns the reference to the blue or green Service of the spec. Both
+// Services forward to the port of serviceRef or the target ports of ports.
+func (s *PortForwardRuleSpec) CutoverServiceRef(service string) *ServiceReference {
+	if s.ServiceRef == nil || s.Cutover == nil || service != CutoverGreen {
+		return s.ServiceRef
+	}
+	return &ServiceReference{
+		Name:      s.Cutover.Green.Name,
+		Namespace: s.Cutover.Green.Namespace,
+		Port:      s.ServiceRef.Port,
+	}
+}
+
+// ActiveServiceRef returns the reference to the Service the rule forwards to: serviceRef, or
+// the green Service once status records the cutover to it
+func (s *PortForwardRuleSpec) ActiveServiceRef(status *PortForwardRuleStatus) *ServiceReference {
+	if status.Cutover == nil {
+		return s.ServiceRef
+	}
+	return s.CutoverServiceRef(status.Cutover.Active)
+}
+
+// validateCutover validates the cutover field of a spec
+func validateCutover(s *PortForwardRuleSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	c := s.Cutover
+	cutoverPath := specPath.Child("cutover")
+
+	if s.ServiceRef == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("serviceRef"), "cutover requires serviceRef"))
+	}
+
+	greenPath := cutoverPath.Child("green")
+	if !isValidDNSName(c.Green.Name) {
+		allErrs = append(allErrs, field.Invalid(greenPath.Child("name"), c.Green.Name, "service name must be a valid DNS name"))
+	}
+	if c.Green.Namespace != nil && !isValidDNSName(*c.Green.Namespace) {
+		allErrs = append(allErrs, field.Invalid(greenPath.Child("namespace"), *c.Green.Namespace, "namespace must be a valid DNS name"))
+	}
+
+	if c.Active != "" && !contains(validCutoverServices, c.Active) {
+		allErrs = append(allErrs, field.NotSupported(cutoverPath.Child("active"), c.Active, validCutoverServices))
+	}
+
+	if c.HealthCheck != nil {
+		allErrs = append(allErrs, validateHealthCheck(c.HealthCheck, cutoverPath.Child("healthCheck"))...)
+	}
+
+	return allErrs
+}
@@ -0,0 +1,33 @@
+package v1alpha1
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ExpiryTime returns when a rule created at created expires, or the zero time if it does not
+func (s *PortForwardRuleSpec) ExpiryTime(created time.Time) time.Time {
+	switch {
+	case s.ExpiresAt != nil:
+		return s.ExpiresAt.Time
+	case s.TTL != nil:
+		return created.Add(s.TTL.Duration)
+	default:
+		return time.Time{}
+	}
+}
+
+// validateExpiry validates the expiresAt and ttl fields of a spec
+func validateExpiry(s *PortForwardRuleSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if s.ExpiresAt != nil && s.TTL != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("ttl"), "expiresAt and ttl are mutually exclusive"))
+	}
+	if s.TTL != nil && s.TTL.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("ttl"), s.TTL.Duration.String(), "ttl must be positive"))
+	}
+
+	return allErrs
+}
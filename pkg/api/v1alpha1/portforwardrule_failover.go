@@ -0,0 +1,155 @@
+package v1alpha1
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Health check defaults
+const (
+	DefaultHealthCheckInterval         = 10 * time.Second
+	DefaultHealthCheckTimeout          = 3 * time.Second
+	DefaultHealthCheckFailureThreshold = 3
+	DefaultHealthCheckSuccessThreshold = 2
+)
+
+// Failover reasons recorded in the failover history
+const (
+	FailoverReasonFailover           = "Failover"
+	FailoverReasonFailback           = "Failback"
+	FailoverReasonDestinationRemoved = "DestinationRemoved"
+)
+
+// MaxFailoverHistory is the number of failover events kept in status
+const MaxFailoverHistory = 10
+
+var validHealthCheckProtocols = []string{"tcp", "udp", "http"}
+
+// Preferred returns the destinations from most to least preferred: by descending priority,
+// keeping list order for equal priorities
+func (f *DestinationFailover) Preferred() []FailoverDestination {
+	destinations := append([]FailoverDestination(nil), f.Destinations...)
+	sort.SliceStable(destinations, func(i, j int) bool {
+		return destinations[i].Priority > destinations[j].Priority
+	})
+	return destinations
+}
+
+// IPs returns the destination IPs in list order
+func (f *DestinationFailover) IPs() []string {
+	ips := make([]string, 0, len(f.Destinations))
+	for _, destination := range f.Destinations {
+		ips = append(ips, destination.IP)
+	}
+	return ips
+}
+
+// ProbeProtocol returns the protocol of the health check, defaulting to tcp
+func (h *HealthCheck) ProbeProtocol() string {
+	if h.Protocol == "" {
+		return "tcp"
+	}
+	return h.Protocol
+}
+
+// ProbePath returns the path requested by http health checks, defaulting to /
+func (h *HealthCheck) ProbePath() string {
+	if h.Path == "" {
+		return "/"
+	}
+	return h.Path
+}
+
+// ProbeInterval returns the time between probes of a destination
+func (h *HealthCheck) ProbeInterval() time.Duration {
+	if h.Interval == nil || h.Interval.Duration <= 0 {
+		return DefaultHealthCheckInterval
+	}
+	return h.Interval.Duration
+}
+
+// ProbeTimeout returns the time a single probe may take
+func (h *HealthCheck) ProbeTimeout() time.Duration {
+	if h.Timeout == nil || h.Timeout.Duration <= 0 {
+		return DefaultHealthCheckTimeout
+	}
+	return h.Timeout.Duration
+}
+
+// Failures returns the number of failed probes in a row that make a destination unhealthy
+func (h *HealthCheck) Failures() int32 {
+	if h.FailureThreshold <= 0 {
+		return DefaultHealthCheckFailureThreshold
+	}
+	return h.FailureThreshold
+}
+
+// Successes returns the number of successful probes in a row that make a destination healthy
+func (h *HealthCheck) Successes() int32 {
+	if h.SuccessThreshold <= 0 {
+		return DefaultHealthCheckSuccessThreshold
+	}
+	return h.SuccessThreshold
+}
+
+// validateFailover validates the destinations and health check of a failover
+func validateFailover(f *DestinationFailover, failoverPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	destinationsPath := failoverPath.Child("destinations")
+	if len(f.Destinations) == 0 {
+		allErrs = append(allErrs, field.Required(destinationsPath, "at least one destination is required"))
+	}
+	seen := make(map[string]bool)
+	for i, destination := range f.Destinations {
+		ipPath := destinationsPath.Index(i).Child("ip")
+		if net.ParseIP(destination.IP) == nil {
+			allErrs = append(allErrs, field.Invalid(ipPath, destination.IP, "must be a valid IP address"))
+			continue
+		}
+		if seen[destination.IP] {
+			allErrs = append(allErrs, field.Duplicate(ipPath, destination.IP))
+		}
+		seen[destination.IP] = true
+	}
+
+	allErrs = append(allErrs, validateHealthCheck(&f.HealthCheck, failoverPath.Child("healthCheck"))...)
+
+	return allErrs
+}
+
+// validateHealthCheck validates the protocol, port, path, thresholds and durations of a health check
+func validateHealthCheck(check *HealthCheck, checkPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if check.Protocol != "" && !contains(validHealthCheckProtocols, check.Protocol) {
+		allErrs = append(allErrs, field.NotSupported(checkPath.Child("protocol"), check.Protocol, validHealthCheckProtocols))
+	}
+	if check.Port < 0 || check.Port > 65535 {
+		allErrs = append(allErrs, field.Invalid(checkPath.Child("port"), check.Port, "port must be between 1 and 65535"))
+	}
+	if check.Path != "" && check.Path[0] != '/' {
+		allErrs = append(allErrs, field.Invalid(checkPath.Child("path"), check.Path, "path must start with /"))
+	}
+	if check.FailureThreshold < 0 {
+		allErrs = append(allErrs, field.Invalid(checkPath.Child("failureThreshold"), check.FailureThreshold, "must be positive"))
+	}
+	if check.SuccessThreshold < 0 {
+		allErrs = append(allErrs, field.Invalid(checkPath.Child("successThreshold"), check.SuccessThreshold, "must be positive"))
+	}
+	if check.Interval != nil && check.Interval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(checkPath.Child("interval"), check.Interval.Duration.String(), "interval must be positive"))
+	}
+	if check.Timeout != nil && check.Timeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(checkPath.Child("timeout"), check.Timeout.Duration.String(), "timeout must be positive"))
+	}
+	if check.ProbeTimeout() > check.ProbeInterval() {
+		allErrs = append(allErrs, field.Invalid(checkPath.Child("timeout"), check.ProbeTimeout().String(),
+			"timeout must not exceed the interval"))
+	}
+
+	return allErrs
+}
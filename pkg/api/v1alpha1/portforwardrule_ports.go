@@ -0,0 +1,74 @@
+package v1alpha1
+
+import (
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// EffectivePorts returns the ports the rule forwards: the ports list, or else a single port
+// built from externalPort, protocol and serviceRef.port or destinationPort
+func (s *PortForwardRuleSpec) EffectivePorts() []PortForwardPort {
+	if len(s.Ports) > 0 {
+		return s.Ports
+	}
+
+	port := PortForwardPort{
+		ExternalPort: s.ExternalPort,
+		Protocol:     s.Protocol,
+	}
+	if s.ServiceRef != nil && s.ServiceRef.Port != "" {
+		targetPort := intstr.Parse(s.ServiceRef.Port)
+		port.TargetPort = &targetPort
+	} else if s.ServiceRef == nil && s.DestinationPort != nil {
+		targetPort := intstr.FromInt32(int32(*s.DestinationPort))
+		port.TargetPort = &targetPort
+	}
+	return []PortForwardPort{port}
+}
+
+// portsPath returns the path reported for port conflicts of the spec
+func (s *PortForwardRuleSpec) portsPath() *field.Path {
+	if len(s.Ports) > 0 {
+		return field.NewPath("spec").Child("ports")
+	}
+	return field.NewPath("spec").Child("externalPort")
+}
+
+// Target returns the Service port name or number, or the destination port, the port forwards
+// to. It defaults to the external port.
+func (p *PortForwardPort) Target() string {
+	if p.TargetPort == nil || p.TargetPort.String() == "" {
+		return strconv.Itoa(p.ExternalPort)
+	}
+	return p.TargetPort.String()
+}
+
+// IsEnabled reports whether the port is forwarded, given the Enabled setting of its rule
+func (p *PortForwardPort) IsEnabled(ruleEnabled bool) bool {
+	if p.Enabled != nil {
+		return *p.Enabled
+	}
+	return ruleEnabled
+}
+
+// IsLogEnabled reports whether forwarded traffic is logged, given the LogEnabled setting of its rule
+func (p *PortForwardPort) IsLogEnabled(ruleLogEnabled bool) bool {
+	if p.LogEnabled != nil {
+		return *p.LogEnabled
+	}
+	return ruleLogEnabled
+}
+
+// overlappingPort returns the first external port forwarded by both port lists with overlapping protocols
+func overlappingPort(a, b []PortForwardPort) (int, bool) {
+	for _, portA := range a {
+		for _, portB := range b {
+			if portA.ExternalPort == portB.ExternalPort && protocolsOverlap(portA.Protocol, portB.Protocol) {
+				return portA.ExternalPort, true
+			}
+		}
+	}
+	return 0, false
+}
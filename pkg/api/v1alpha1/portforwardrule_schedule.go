@@ -0,0 +1,43 @@
+package v1alpha1
+
+import (
+	"time"
+
+	"unifi-port-forward/pkg/schedule"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Parse returns the evaluable form of the schedule
+func (s *PortForwardSchedule) Parse() (*schedule.Schedule, error) {
+	windows := make([]schedule.Window, 0, len(s.Windows))
+	for _, w := range s.Windows {
+		windows = append(windows, schedule.Window{Start: w.Start, Duration: w.Duration.Duration})
+	}
+	return schedule.New(s.TimeZone, windows)
+}
+
+// validateSchedule validates the time zone and windows of a schedule
+func validateSchedule(s *PortForwardSchedule, schedulePath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if _, err := time.LoadLocation(s.TimeZone); err != nil {
+		allErrs = append(allErrs, field.Invalid(
+			schedulePath.Child("timeZone"),
+			s.TimeZone,
+			"must be an IANA time zone such as Europe/Stockholm",
+		))
+	}
+
+	if len(s.Windows) == 0 {
+		allErrs = append(allErrs, field.Required(schedulePath.Child("windows"), "at least one window is required"))
+	}
+	for i, w := range s.Windows {
+		err := schedule.ValidateWindow(schedule.Window{Start: w.Start, Duration: w.Duration.Duration})
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(schedulePath.Child("windows").Index(i), w.Start, err.Error()))
+		}
+	}
+
+	return allErrs
+}
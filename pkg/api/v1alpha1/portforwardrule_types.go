@@ -5,36 +5,77 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // PortForwardRuleSpec defines the desired state of PortForwardRule
 type PortForwardRuleSpec struct {
-	// ExternalPort is the WAN port to forward
+	// ExternalPort is the WAN port to forward (mutually exclusive with Ports)
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Maximum=65535
-	// +kubebuilder:required
-	ExternalPort int `json:"externalPort"`
+	// +optional
+	ExternalPort int `json:"externalPort,omitempty"`
 
 	// Protocol specifies the forwarding protocol
 	// +kubebuilder:validation:Enum=tcp;udp;both
 	// +kubebuilder:default=tcp
 	Protocol string `json:"protocol,omitempty"`
 
-	// ServiceRef references a Service for destination (mutually exclusive with DestinationIP)
+	// Ports lists several WAN ports to forward, each to its own router rule. When set it
+	// replaces ExternalPort, Protocol, ServiceRef.Port and DestinationPort.
+	// +optional
+	Ports []PortForwardPort `json:"ports,omitempty"`
+
+	// ServiceRef references a Service for destination (mutually exclusive with DestinationIP and Failover)
 	ServiceRef *ServiceReference `json:"serviceRef,omitempty"`
 
-	// DestinationIP is the target IP address (mutually exclusive with ServiceRef)
+	// Cutover switches the rule between serviceRef, the blue Service, and a green Service
+	// (requires ServiceRef)
+	// +optional
+	Cutover *ServiceCutover `json:"cutover,omitempty"`
+
+	// DestinationIP is the target IP address (mutually exclusive with ServiceRef and Failover)
 	// +kubebuilder:validation:Format=ipv4
 	DestinationIP *string `json:"destinationIP,omitempty"`
 
-	// DestinationPort is the target port (required if DestinationIP is set)
+	// Failover forwards to the healthiest of several destinations (mutually exclusive with
+	// ServiceRef and DestinationIP)
+	// +optional
+	Failover *DestinationFailover `json:"failover,omitempty"`
+
+	// DestinationPort is the target port (required if DestinationIP or Failover is set and Ports is not)
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Maximum=65535
 	DestinationPort *int `json:"destinationPort,omitempty"`
 
-	// Enabled controls whether this rule is active
+	// Enabled controls whether this rule is active. It is always serialized so that a
+	// disabled rule is not defaulted back to enabled when written by a client.
 	// +kubebuilder:default=true
-	Enabled bool `json:"enabled,omitempty"`
+	// +optional
+	Enabled bool `json:"enabled"`
+
+	// Schedule limits the rule to time windows; without it the rule is always enabled
+	// +optional
+	Schedule *PortForwardSchedule `json:"schedule,omitempty"`
+
+	// ExpiresAt is when the rule lapses and its router rule is removed (mutually exclusive with TTL).
+	// Patch it to a later time to extend the rule.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// TTL is how long after its creation the rule lapses, such as 8h (mutually exclusive with
+	// ExpiresAt). Patch it to a longer duration to extend the rule.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// DeleteOnExpiry deletes the rule once it has expired instead of keeping it in the Expired phase
+	// +optional
+	DeleteOnExpiry bool `json:"deleteOnExpiry,omitempty"`
+
+	// ReadinessGate disables the router rule while the referenced Service has no ready endpoints.
+	// Requires ServiceRef.
+	// +optional
+	ReadinessGate bool `json:"readinessGate,omitempty"`
 
 	// Description provides a human-readable description
 	// +kubebuilder:validation:MaxLength=256
@@ -64,14 +105,168 @@ type PortForwardRuleSpec struct {
 	LogEnabled bool `json:"logEnabled,omitempty"`
 }
 
+// PortForwardPort is a single forwarded WAN port of a rule with a ports list
+type PortForwardPort struct {
+	// Name identifies the port within the rule
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name,omitempty"`
+
+	// ExternalPort is the WAN port to forward
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:required
+	ExternalPort int `json:"externalPort"`
+
+	// Protocol specifies the forwarding protocol
+	// +kubebuilder:validation:Enum=tcp;udp;both
+	// +kubebuilder:default=tcp
+	Protocol string `json:"protocol,omitempty"`
+
+	// TargetPort is the Service port name or number for serviceRef rules, or the destination
+	// port for destinationIP rules. Defaults to ExternalPort.
+	TargetPort *intstr.IntOrString `json:"targetPort,omitempty"`
+
+	// Enabled overrides the rule's Enabled for this port
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Interface overrides the rule's Interface for this port
+	Interface string `json:"interface,omitempty"`
+
+	// LogEnabled overrides the rule's LogEnabled for this port
+	LogEnabled *bool `json:"logEnabled,omitempty"`
+}
+
+// PortForwardSchedule limits a rule to time windows. Outside its windows the router rule is
+// kept but disabled.
+type PortForwardSchedule struct {
+	// TimeZone is the IANA time zone the windows are evaluated in, such as Europe/Stockholm
+	// +kubebuilder:default=UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows are the periods during which the rule is enabled
+	// +kubebuilder:validation:MinItems=1
+	Windows []ScheduleWindow `json:"windows"`
+}
+
+// ScheduleWindow is a period that opens at every time matched by a cron expression
+type ScheduleWindow struct {
+	// Start is a five-field cron expression (minute hour day-of-month month day-of-week)
+	// +kubebuilder:required
+	Start string `json:"start"`
+
+	// Duration is how long the window stays open after each start, such as 9h
+	// +kubebuilder:required
+	Duration metav1.Duration `json:"duration"`
+}
+
 // Phase constants
 const (
-	PhasePending = "Pending"
-	PhaseActive  = "Active"
-	PhaseFailed  = "Failed"
-	PhaseUnknown = "Unknown"
+	PhasePending         = "Pending"
+	PhasePendingApproval = "PendingApproval"
+	PhaseActive          = "Active"
+	PhaseFailed          = "Failed"
+	PhaseExpired         = "Expired"
+	PhaseUnknown         = "Unknown"
 )
 
+// DestinationFailover points a standalone rule at the healthiest of several destinations.
+// The rule forwards to the healthy destination with the highest priority.
+type DestinationFailover struct {
+	// Destinations are the candidate destinations
+	// +kubebuilder:validation:MinItems=1
+	Destinations []FailoverDestination `json:"destinations"`
+
+	// HealthCheck probes every destination
+	// +optional
+	HealthCheck HealthCheck `json:"healthCheck,omitempty"`
+}
+
+// FailoverDestination is a candidate destination of a rule with failover
+type FailoverDestination struct {
+	// IP is the destination address
+	// +kubebuilder:required
+	IP string `json:"ip"`
+
+	// Priority orders the destinations; higher is preferred and equal priorities keep list order
+	// +optional
+	Priority int `json:"priority,omitempty"`
+}
+
+// HealthCheck describes how destinations are probed. A destination becomes unhealthy after
+// FailureThreshold failed probes in a row and healthy again after SuccessThreshold successful
+// ones, so a flapping destination does not move the rule back and forth.
+type HealthCheck struct {
+	// Protocol is tcp to connect, udp to send a datagram that fails only on an ICMP port
+	// unreachable, or http to expect a status below 400
+	// +kubebuilder:validation:Enum=tcp;udp;http
+	// +kubebuilder:default=tcp
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+
+	// Port is the probed port (defaults to the destination port of the rule's first port)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// Path is requested by http probes
+	// +kubebuilder:default=/
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Interval is the time between probes of a destination (default 10s)
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Timeout bounds a single probe (default 3s)
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// FailureThreshold is the number of failed probes in a row that make a destination unhealthy
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+
+	// SuccessThreshold is the number of successful probes in a row that make an unhealthy
+	// destination healthy again
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=2
+	// +optional
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+}
+
+// ServiceCutover switches a rule between two Services: serviceRef, the blue Service, and a green
+// Service. The router rule is updated in place, so the port stays forwarded during the switch.
+type ServiceCutover struct {
+	// Green is the Service the rule cuts over to. It must expose the rule's target ports.
+	// +kubebuilder:required
+	Green CutoverService `json:"green"`
+
+	// Active selects the Service the rule forwards to: blue for serviceRef or green
+	// +kubebuilder:validation:Enum=blue;green
+	// +kubebuilder:default=blue
+	// +optional
+	Active string `json:"active,omitempty"`
+
+	// HealthCheck verifies the Service the rule cut over to; when it fails, the rule is rolled
+	// back to the previous Service
+	// +optional
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+}
+
+// CutoverService references the green Service of a cutover
+type CutoverService struct {
+	// Name is the Service name (required)
+	// +kubebuilder:required
+	Name string `json:"name"`
+
+	// Namespace is the Service namespace (defaults to rule namespace)
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
+}
+
 // ServiceReference references a Kubernetes Service
 type ServiceReference struct {
 	// Name is the Service name (required)
@@ -81,15 +276,14 @@ type ServiceReference struct {
 	// Namespace is the Service namespace (defaults to rule namespace)
 	Namespace *string `json:"namespace,omitempty"`
 
-	// Port is the service port name or number (required)
-	// +kubebuilder:required
-	Port string `json:"port"`
+	// Port is the service port name or number (required unless Ports is set)
+	Port string `json:"port,omitempty"`
 }
 
 // PortForwardRuleStatus defines the observed state of PortForwardRule
 type PortForwardRuleStatus struct {
 	// Phase is the current phase of the rule
-	// +kubebuilder:validation:Enum=Pending;Active;Failed;Unknown
+	// +kubebuilder:validation:Enum=Pending;PendingApproval;Active;Failed;Expired;Unknown
 	Phase string `json:"phase,omitempty"`
 
 	// ObservedGeneration is the generation observed by the controller
@@ -98,12 +292,35 @@ type PortForwardRuleStatus struct {
 	// LastAppliedTime is when the rule was last applied
 	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
 
-	// RouterRuleID is the ID of the rule on the router
+	// NextScheduleChange is when the schedule next enables or disables the rule
+	NextScheduleChange *metav1.Time `json:"nextScheduleChange,omitempty"`
+
+	// ExpiresAt is when the rule expires, from spec.expiresAt or spec.ttl
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// ExposureHash is a hash of the external ports and destinations of the rule. When approval
+	// is required the rule is only forwarded once its approved annotation holds this hash.
+	ExposureHash string `json:"exposureHash,omitempty"`
+
+	// RouterRuleID is the ID of the rule on the router. For rules with several ports it is
+	// the ID of the first port's rule; see Ports for the others.
 	RouterRuleID string `json:"routerRuleID,omitempty"`
 
+	// AppliedConfigHash is a hash of the router rule configuration last applied
+	AppliedConfigHash string `json:"appliedConfigHash,omitempty"`
+
+	// Ports contains the status of every forwarded port
+	Ports []PortStatus `json:"ports,omitempty"`
+
 	// ServiceStatus contains service-specific status
 	ServiceStatus *ServiceStatus `json:"serviceStatus,omitempty"`
 
+	// Failover contains the destination health and failover history of rules with failover
+	Failover *FailoverStatus `json:"failover,omitempty"`
+
+	// Cutover contains the progress of the cutover of rules with cutover
+	Cutover *CutoverStatus `json:"cutover,omitempty"`
+
 	// Conditions represent the latest available observations of the rule's state
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
@@ -114,6 +331,105 @@ type PortForwardRuleStatus struct {
 	ErrorInfo *ErrorInfo `json:"errorInfo,omitempty"`
 }
 
+// PortStatus contains the status of a single forwarded port
+type PortStatus struct {
+	// Name is the name of the port in the rule's ports list
+	Name string `json:"name,omitempty"`
+
+	// ExternalPort is the forwarded WAN port
+	ExternalPort int `json:"externalPort"`
+
+	// Protocol is the forwarding protocol
+	Protocol string `json:"protocol,omitempty"`
+
+	// Phase is the current phase of the port
+	// +kubebuilder:validation:Enum=Pending;Active;Failed;Unknown
+	Phase string `json:"phase,omitempty"`
+
+	// RouterRuleID is the ID of the port's rule on the router
+	RouterRuleID string `json:"routerRuleID,omitempty"`
+
+	// AppliedConfigHash is a hash of the router rule configuration last applied
+	AppliedConfigHash string `json:"appliedConfigHash,omitempty"`
+
+	// ServicePort is the resolved service port number
+	ServicePort int32 `json:"servicePort,omitempty"`
+
+	// Message explains a Failed phase
+	Message string `json:"message,omitempty"`
+}
+
+// CutoverStatus reports the progress of the cutover of a rule between its blue and green Services
+type CutoverStatus struct {
+	// Active is the Service the router rule forwards to: blue or green
+	Active string `json:"active,omitempty"`
+
+	// Phase is Stable, Pending while the selected Service is not ready, Verifying while the
+	// health check verifies the new Service, or RolledBack
+	Phase string `json:"phase,omitempty"`
+
+	// LastCutoverTime is when the rule last switched Services
+	LastCutoverTime *metav1.Time `json:"lastCutoverTime,omitempty"`
+
+	// RolledBackGeneration is the generation whose cutover was rolled back. The cutover is
+	// retried once the spec changes.
+	RolledBackGeneration int64 `json:"rolledBackGeneration,omitempty"`
+
+	// ConsecutiveFailures and ConsecutiveSuccesses count the latest verification probes in a row
+	ConsecutiveFailures  int32 `json:"consecutiveFailures,omitempty"`
+	ConsecutiveSuccesses int32 `json:"consecutiveSuccesses,omitempty"`
+
+	// LastProbeTime is when the new Service was last probed
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+
+	// Message explains the phase
+	Message string `json:"message,omitempty"`
+}
+
+// FailoverStatus reports the destination health and failover history of a rule with failover
+type FailoverStatus struct {
+	// ActiveDestination is the destination the router rule forwards to
+	ActiveDestination string `json:"activeDestination,omitempty"`
+
+	// Destinations holds the probe results of every destination
+	Destinations []DestinationHealth `json:"destinations,omitempty"`
+
+	// History lists the latest changes of the active destination, most recent first
+	History []FailoverEvent `json:"history,omitempty"`
+}
+
+// DestinationHealth is the health of a single destination of a rule with failover
+type DestinationHealth struct {
+	// IP is the destination address
+	IP string `json:"ip"`
+
+	// Healthy reports whether the destination passes its health check
+	Healthy bool `json:"healthy"`
+
+	// ConsecutiveFailures and ConsecutiveSuccesses count the latest probe results in a row
+	ConsecutiveFailures  int32 `json:"consecutiveFailures,omitempty"`
+	ConsecutiveSuccesses int32 `json:"consecutiveSuccesses,omitempty"`
+
+	// LastProbeTime is when the destination was last probed
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+
+	// Message explains the last failed probe
+	Message string `json:"message,omitempty"`
+}
+
+// FailoverEvent records a change of the active destination
+type FailoverEvent struct {
+	// Time is when the active destination changed
+	Time metav1.Time `json:"time"`
+
+	// From and To are the previous and new active destinations
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+
+	// Reason is Failover, Failback or DestinationRemoved
+	Reason string `json:"reason"`
+}
+
 // ServiceStatus contains status information about the referenced service
 type ServiceStatus struct {
 	// Name is the service name
@@ -7,7 +7,10 @@ import (
 	"regexp"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -38,23 +41,26 @@ func (r *PortForwardRule) validateSpec() field.ErrorList {
 	var allErrs field.ErrorList
 	specPath := field.NewPath("spec")
 
-	// Validate external port
-	if r.Spec.ExternalPort < 1 || r.Spec.ExternalPort > 65535 {
-		allErrs = append(allErrs, field.Invalid(
-			specPath.Child("externalPort"),
-			r.Spec.ExternalPort,
-			"external port must be between 1 and 65535",
-		))
-	}
+	if len(r.Spec.Ports) > 0 {
+		allErrs = append(allErrs, r.validatePorts(specPath)...)
+	} else {
+		// Validate external port
+		if r.Spec.ExternalPort < 1 || r.Spec.ExternalPort > 65535 {
+			allErrs = append(allErrs, field.Invalid(
+				specPath.Child("externalPort"),
+				r.Spec.ExternalPort,
+				"external port must be between 1 and 65535",
+			))
+		}
 
-	// Validate protocol
-	validProtocols := []string{"tcp", "udp", "both"}
-	if !contains(validProtocols, r.Spec.Protocol) {
-		allErrs = append(allErrs, field.NotSupported(
-			specPath.Child("protocol"),
-			r.Spec.Protocol,
-			validProtocols,
-		))
+		// Validate protocol
+		if !contains(validProtocols, r.Spec.Protocol) {
+			allErrs = append(allErrs, field.NotSupported(
+				specPath.Child("protocol"),
+				r.Spec.Protocol,
+				validProtocols,
+			))
+		}
 	}
 
 	// Validate destination IP if specified
@@ -90,6 +96,28 @@ func (r *PortForwardRule) validateSpec() field.ErrorList {
 		}
 	}
 
+	if r.Spec.Schedule != nil {
+		allErrs = append(allErrs, validateSchedule(r.Spec.Schedule, specPath.Child("schedule"))...)
+	}
+
+	allErrs = append(allErrs, validateExpiry(&r.Spec, specPath)...)
+
+	if r.Spec.Failover != nil {
+		allErrs = append(allErrs, validateFailover(r.Spec.Failover, specPath.Child("failover"))...)
+	}
+
+	if r.Spec.Cutover != nil {
+		allErrs = append(allErrs, validateCutover(&r.Spec, specPath)...)
+	}
+
+	if r.Spec.ReadinessGate && r.Spec.ServiceRef == nil {
+		allErrs = append(allErrs, field.Invalid(
+			specPath.Child("readinessGate"),
+			r.Spec.ReadinessGate,
+			"readinessGate requires serviceRef",
+		))
+	}
+
 	// Validate priority
 	if r.Spec.Priority < 0 || r.Spec.Priority > 1000 {
 		allErrs = append(allErrs, field.Invalid(
@@ -117,6 +145,83 @@ func (r *PortForwardRule) validateSpec() field.ErrorList {
 	return allErrs
 }
 
+// validatePorts validates the ports list, which replaces the single-port fields
+func (r *PortForwardRule) validatePorts(specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	portsPath := specPath.Child("ports")
+
+	if r.Spec.ExternalPort != 0 {
+		allErrs = append(allErrs, field.Forbidden(
+			specPath.Child("externalPort"),
+			"externalPort cannot be specified when ports is specified",
+		))
+	}
+	if r.Spec.DestinationPort != nil {
+		allErrs = append(allErrs, field.Forbidden(
+			specPath.Child("destinationPort"),
+			"destinationPort cannot be specified when ports is specified, use ports[].targetPort",
+		))
+	}
+	if r.Spec.ServiceRef != nil && r.Spec.ServiceRef.Port != "" {
+		allErrs = append(allErrs, field.Forbidden(
+			specPath.Child("serviceRef", "port"),
+			"serviceRef.port cannot be specified when ports is specified, use ports[].targetPort",
+		))
+	}
+
+	names := make(map[string]bool)
+	for i, port := range r.Spec.Ports {
+		portPath := portsPath.Index(i)
+
+		if port.Name != "" {
+			if names[port.Name] {
+				allErrs = append(allErrs, field.Duplicate(portPath.Child("name"), port.Name))
+			}
+			names[port.Name] = true
+		}
+
+		if port.ExternalPort < 1 || port.ExternalPort > 65535 {
+			allErrs = append(allErrs, field.Invalid(
+				portPath.Child("externalPort"),
+				port.ExternalPort,
+				"external port must be between 1 and 65535",
+			))
+		}
+
+		if !contains(validProtocols, port.Protocol) {
+			allErrs = append(allErrs, field.NotSupported(
+				portPath.Child("protocol"),
+				port.Protocol,
+				validProtocols,
+			))
+		}
+
+		if port.TargetPort != nil {
+			switch {
+			case port.TargetPort.Type == intstr.Int && (port.TargetPort.IntVal < 1 || port.TargetPort.IntVal > 65535):
+				allErrs = append(allErrs, field.Invalid(
+					portPath.Child("targetPort"),
+					port.TargetPort.IntVal,
+					"target port must be between 1 and 65535",
+				))
+			case port.TargetPort.Type == intstr.String && r.Spec.ServiceRef == nil:
+				allErrs = append(allErrs, field.Invalid(
+					portPath.Child("targetPort"),
+					port.TargetPort.StrVal,
+					"target port must be a number when destinationIP is specified",
+				))
+			}
+		}
+
+		// Two entries forwarding the same traffic would fight over one router rule
+		if _, overlaps := overlappingPort(r.Spec.Ports[:i], []PortForwardPort{port}); overlaps {
+			allErrs = append(allErrs, field.Duplicate(portPath.Child("externalPort"), port.ExternalPort))
+		}
+	}
+
+	return allErrs
+}
+
 // validateServiceRef validates the service reference
 func (r *PortForwardRule) validateServiceRef(path *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
@@ -142,7 +247,7 @@ func (r *PortForwardRule) validateServiceRef(path *field.Path) field.ErrorList {
 	}
 
 	// Validate port name/number
-	if r.Spec.ServiceRef.Port == "" {
+	if r.Spec.ServiceRef.Port == "" && len(r.Spec.Ports) == 0 {
 		allErrs = append(allErrs, field.Required(
 			path.Child("port"),
 			"service port must be specified",
@@ -162,13 +267,14 @@ func isValidDNSName(name string) bool {
 	return matched
 }
 
-// validateMutuallyExclusiveFields validates that serviceRef and destinationIP are mutually exclusive
+// validateMutuallyExclusiveFields validates that serviceRef, destinationIP and failover are mutually exclusive
 func (r *PortForwardRule) validateMutuallyExclusiveFields() field.ErrorList {
 	var allErrs field.ErrorList
 	specPath := field.NewPath("spec")
 
 	hasServiceRef := r.Spec.ServiceRef != nil
 	hasDestinationIP := r.Spec.DestinationIP != nil
+	hasFailover := r.Spec.Failover != nil
 
 	if hasServiceRef && hasDestinationIP {
 		allErrs = append(allErrs, field.Forbidden(
@@ -177,18 +283,25 @@ func (r *PortForwardRule) validateMutuallyExclusiveFields() field.ErrorList {
 		))
 	}
 
-	if !hasServiceRef && !hasDestinationIP {
+	if hasFailover && (hasServiceRef || hasDestinationIP) {
+		allErrs = append(allErrs, field.Forbidden(
+			specPath.Child("failover"),
+			"failover cannot be specified when serviceRef or destinationIP is specified",
+		))
+	}
+
+	if !hasServiceRef && !hasDestinationIP && !hasFailover {
 		allErrs = append(allErrs, field.Required(
 			specPath,
-			"either serviceRef or destinationIP must be specified",
+			"either serviceRef, destinationIP or failover must be specified",
 		))
 	}
 
-	// If destinationIP is specified, destinationPort must also be specified
-	if hasDestinationIP && r.Spec.DestinationPort == nil {
+	// Standalone destinations need destinationPort unless ports set target ports
+	if (hasDestinationIP || hasFailover) && r.Spec.DestinationPort == nil && len(r.Spec.Ports) == 0 {
 		allErrs = append(allErrs, field.Required(
 			specPath.Child("destinationPort"),
-			"destinationPort is required when destinationIP is specified",
+			"destinationPort is required when destinationIP or failover is specified",
 		))
 	}
 
@@ -198,7 +311,6 @@ func (r *PortForwardRule) validateMutuallyExclusiveFields() field.ErrorList {
 // ValidateCrossNamespacePortConflict checks for port conflicts across all namespaces
 func (r *PortForwardRule) ValidateCrossNamespacePortConflict(ctx context.Context, client client.Client) field.ErrorList {
 	var allErrs field.ErrorList
-	specPath := field.NewPath("spec")
 
 	// If client is nil, we can't validate conflicts
 	if client == nil {
@@ -219,14 +331,12 @@ func (r *PortForwardRule) ValidateCrossNamespacePortConflict(ctx context.Context
 		}
 
 		// Check port conflict
-		if existingRule.Spec.ExternalPort == r.Spec.ExternalPort &&
-			(existingRule.Spec.Protocol == r.Spec.Protocol || existingRule.Spec.Protocol == "both" || r.Spec.Protocol == "both") {
-
+		if port, overlaps := overlappingPort(existingRule.Spec.EffectivePorts(), r.Spec.EffectivePorts()); overlaps {
 			// Same namespace conflict = error
 			if existingRule.Namespace == r.Namespace {
 				allErrs = append(allErrs, field.Forbidden(
-					specPath.Child("externalPort"),
-					fmt.Sprintf("port %d conflicts with existing rule %s in same namespace", r.Spec.ExternalPort, existingRule.Name),
+					r.Spec.portsPath(),
+					fmt.Sprintf("port %d conflicts with existing rule %s in same namespace", port, existingRule.Name),
 				))
 			}
 		}
@@ -239,13 +349,13 @@ func (r *PortForwardRule) ValidateCrossNamespacePortConflict(ctx context.Context
 
 		for _, service := range serviceList.Items {
 			if port, hasAnnotation := service.Annotations["port-forwarder.unifi.com/external-port"]; hasAnnotation {
-				if servicePort, protocol := parseServiceAnnotation(port); servicePort == r.Spec.ExternalPort &&
-					(protocol == r.Spec.Protocol || protocol == "both" || r.Spec.Protocol == "both") {
-
+				servicePort, protocol := parseServiceAnnotation(port)
+				annotationPorts := []PortForwardPort{{ExternalPort: servicePort, Protocol: protocol}}
+				if _, overlaps := overlappingPort(annotationPorts, r.Spec.EffectivePorts()); overlaps && servicePort != 0 {
 					if service.Namespace == r.Namespace {
 						allErrs = append(allErrs, field.Forbidden(
-							specPath.Child("externalPort"),
-							fmt.Sprintf("port %d conflicts with existing Service annotation on %s/%s", r.Spec.ExternalPort, service.Namespace, service.Name),
+							r.Spec.portsPath(),
+							fmt.Sprintf("port %d conflicts with existing Service annotation on %s/%s", servicePort, service.Namespace, service.Name),
 						))
 					}
 				}
@@ -255,7 +365,8 @@ func (r *PortForwardRule) ValidateCrossNamespacePortConflict(ctx context.Context
 	return allErrs
 }
 
-// ValidateServiceExists validates that the referenced service exists
+// ValidateServiceExists validates that the referenced service exists. Rules with cutover are
+// validated against the Service they currently forward to.
 func (r *PortForwardRule) ValidateServiceExists(ctx context.Context, client client.Client) field.ErrorList {
 	var allErrs field.ErrorList
 	specPath := field.NewPath("spec")
@@ -264,50 +375,210 @@ func (r *PortForwardRule) ValidateServiceExists(ctx context.Context, client clie
 		return allErrs
 	}
 
+	serviceRef := r.Spec.ActiveServiceRef(&r.Status)
+	refPath := specPath.Child("serviceRef")
+	if serviceRef != r.Spec.ServiceRef {
+		refPath = specPath.Child("cutover", "green")
+	}
+
 	// Determine namespace
 	namespace := r.Namespace
-	if r.Spec.ServiceRef.Namespace != nil {
-		namespace = *r.Spec.ServiceRef.Namespace
+	if serviceRef.Namespace != nil {
+		namespace = *serviceRef.Namespace
+	}
+
+	// Cross-namespace references need a grant in the target namespace
+	if namespace != r.Namespace {
+		granted, err := ReferenceGranted(ctx, client, r.Namespace, namespace, serviceRef.Name)
+		if err != nil {
+			allErrs = append(allErrs, field.InternalError(refPath.Child("namespace"), err))
+			return allErrs
+		}
+		if !granted {
+			allErrs = append(allErrs, field.Forbidden(
+				refPath.Child("namespace"),
+				fmt.Sprintf("no PortForwardReferenceGrant in namespace %s allows references from namespace %s to service %s",
+					namespace, r.Namespace, serviceRef.Name),
+			))
+			return allErrs
+		}
 	}
 
 	// Get the service
 	var service corev1.Service
 	err := client.Get(ctx, types.NamespacedName{
-		Name:      r.Spec.ServiceRef.Name,
+		Name:      serviceRef.Name,
 		Namespace: namespace,
 	}, &service)
 
 	if err != nil {
 		allErrs = append(allErrs, field.Invalid(
-			specPath.Child("serviceRef"),
-			r.Spec.ServiceRef.Name,
-			fmt.Sprintf("service %s/%s not found: %v", namespace, r.Spec.ServiceRef.Name, err),
+			refPath,
+			serviceRef.Name,
+			fmt.Sprintf("service %s/%s not found: %v", namespace, serviceRef.Name, err),
 		))
 		return allErrs
 	}
 
-	// Validate that the service port exists
-	portFound := false
-	for _, port := range service.Spec.Ports {
-		if port.Name == r.Spec.ServiceRef.Port || fmt.Sprintf("%d", port.Port) == r.Spec.ServiceRef.Port {
-			portFound = true
-			break
+	// Validate that the service ports exist
+	for i, rulePort := range r.Spec.EffectivePorts() {
+		target := rulePort.Target()
+		portFound := false
+		for _, port := range service.Spec.Ports {
+			if port.Name == target || fmt.Sprintf("%d", port.Port) == target {
+				portFound = true
+				break
+			}
+		}
+
+		if !portFound {
+			targetPath := specPath.Child("serviceRef").Child("port")
+			if len(r.Spec.Ports) > 0 {
+				targetPath = specPath.Child("ports").Index(i).Child("targetPort")
+			}
+			allErrs = append(allErrs, field.Invalid(
+				targetPath,
+				target,
+				fmt.Sprintf("port %s not found in service %s/%s", target, namespace, serviceRef.Name),
+			))
 		}
 	}
 
-	if !portFound {
-		allErrs = append(allErrs, field.Invalid(
-			specPath.Child("serviceRef").Child("port"),
-			r.Spec.ServiceRef.Port,
-			fmt.Sprintf("port %s not found in service %s/%s", r.Spec.ServiceRef.Port, namespace, r.Spec.ServiceRef.Name),
+	return allErrs
+}
+
+// ValidateClusterPortConflict checks that the rule's port is not reserved by a ClusterPortForwardRule
+func (r *PortForwardRule) ValidateClusterPortConflict(ctx context.Context, c client.Client) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, conflict := range ClusterPortConflicts(ctx, c, r.Spec.EffectivePorts()) {
+		allErrs = append(allErrs, field.Forbidden(r.Spec.portsPath(), conflict))
+	}
+	return allErrs
+}
+
+// ClusterPortConflicts describes the ports of ports reserved by a ClusterPortForwardRule. It
+// also checks the port forwards of annotated Services, which claim ports like rules do.
+func ClusterPortConflicts(ctx context.Context, c client.Client, ports []PortForwardPort) []string {
+	if c == nil {
+		return nil
+	}
+
+	var clusterRules ClusterPortForwardRuleList
+	if err := c.List(ctx, &clusterRules); err != nil {
+		// Without the ClusterPortForwardRule CRD there is nothing to conflict with
+		return nil
+	}
+
+	var conflicts []string
+	for _, clusterRule := range clusterRules.Items {
+		if port, overlaps := overlappingPort(clusterRule.Spec.EffectivePorts(), ports); overlaps {
+			conflicts = append(conflicts, fmt.Sprintf("port %d is reserved by ClusterPortForwardRule %s", port, clusterRule.Name))
+		}
+	}
+	return conflicts
+}
+
+// ValidateCreate validates the ClusterPortForwardRule on creation
+func (r *ClusterPortForwardRule) ValidateCreate() field.ErrorList {
+	allErrs := r.namespacedView().ValidateCreate()
+
+	// There is no rule namespace to default to, so service references must name one
+	if r.Spec.ServiceRef != nil && (r.Spec.ServiceRef.Namespace == nil || *r.Spec.ServiceRef.Namespace == "") {
+		allErrs = append(allErrs, field.Required(
+			field.NewPath("spec").Child("serviceRef", "namespace"),
+			"namespace is required for ClusterPortForwardRule service references",
 		))
 	}
+	if r.Spec.Cutover != nil && (r.Spec.Cutover.Green.Namespace == nil || *r.Spec.Cutover.Green.Namespace == "") {
+		allErrs = append(allErrs, field.Required(
+			field.NewPath("spec").Child("cutover", "green", "namespace"),
+			"namespace is required for ClusterPortForwardRule service references",
+		))
+	}
+	return allErrs
+}
+
+// ValidateServiceExists validates that the referenced service exists. Cluster rules are
+// created by cluster admins, so no PortForwardReferenceGrant is required.
+func (r *ClusterPortForwardRule) ValidateServiceExists(ctx context.Context, c client.Client) field.ErrorList {
+	return r.namespacedView().ValidateServiceExists(ctx, c)
+}
 
+// ValidatePortConflict checks for port conflicts with other ClusterPortForwardRules
+func (r *ClusterPortForwardRule) ValidatePortConflict(ctx context.Context, c client.Client) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if c == nil {
+		return allErrs
+	}
+
+	var clusterRules ClusterPortForwardRuleList
+	if err := c.List(ctx, &clusterRules); err != nil {
+		return allErrs
+	}
+
+	for _, existingRule := range clusterRules.Items {
+		if existingRule.Name == r.Name {
+			continue
+		}
+		if port, overlaps := overlappingPort(existingRule.Spec.EffectivePorts(), r.Spec.EffectivePorts()); overlaps {
+			allErrs = append(allErrs, field.Forbidden(
+				r.Spec.portsPath(),
+				fmt.Sprintf("port %d conflicts with existing ClusterPortForwardRule %s", port, existingRule.Name),
+			))
+		}
+	}
 	return allErrs
 }
 
+// namespacedView returns a PortForwardRule with the same spec, living in the namespace of the
+// referenced Service, so the namespaced validation can be reused without requiring a grant
+func (r *ClusterPortForwardRule) namespacedView() *PortForwardRule {
+	view := &PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: r.Name},
+		Spec:       r.Spec,
+		Status:     r.Status,
+	}
+	if serviceRef := r.Spec.ActiveServiceRef(&r.Status); serviceRef != nil && serviceRef.Namespace != nil {
+		view.Namespace = *serviceRef.Namespace
+	}
+	return view
+}
+
+// ReferenceGranted reports whether a PortForwardReferenceGrant in toNamespace allows PortForwardRules
+// in fromNamespace to reference the named Service. Same-namespace references are always allowed.
+func ReferenceGranted(ctx context.Context, c client.Client, fromNamespace, toNamespace, serviceName string) (bool, error) {
+	if fromNamespace == toNamespace {
+		return true, nil
+	}
+
+	var grants PortForwardReferenceGrantList
+	if err := c.List(ctx, &grants, client.InNamespace(toNamespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			// Grant CRD not installed, so nothing is granted
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to list reference grants in namespace %s: %w", toNamespace, err)
+	}
+
+	for i := range grants.Items {
+		if grants.Items[i].Permits(fromNamespace, serviceName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Helper functions
 
+// validProtocols are the protocols a rule can forward
+var validProtocols = []string{"tcp", "udp", "both"}
+
+// protocolsOverlap reports whether two rule protocols forward any common traffic
+func protocolsOverlap(a, b string) bool {
+	return a == b || a == "both" || b == "both"
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
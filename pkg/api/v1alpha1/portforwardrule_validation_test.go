@@ -3,7 +3,10 @@ package v1alpha1
 import (
 	"context"
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
@@ -539,6 +542,429 @@ func TestValidateMutuallyExclusiveFields(t *testing.T) {
 	}
 }
 
+func TestPortForwardRule_ValidatePorts(t *testing.T) {
+	serviceRef := &ServiceReference{Name: "game-server"}
+
+	tests := []struct {
+		name       string
+		spec       PortForwardRuleSpec
+		wantErrors []string
+	}{
+		{
+			name: "serviceRef rule with named target ports",
+			spec: PortForwardRuleSpec{
+				Ports: []PortForwardPort{
+					{Name: "game", ExternalPort: 27015, Protocol: "both", TargetPort: intOrStringPtr(intstr.FromString("game"))},
+					{Name: "query", ExternalPort: 27016, Protocol: "udp"},
+				},
+				ServiceRef:     serviceRef,
+				ConflictPolicy: "warn",
+			},
+		},
+		{
+			name: "destinationIP rule with numeric target ports",
+			spec: PortForwardRuleSpec{
+				Ports: []PortForwardPort{
+					{ExternalPort: 8443, Protocol: "tcp", TargetPort: intOrStringPtr(intstr.FromInt32(443))},
+					{ExternalPort: 8443, Protocol: "udp"},
+				},
+				DestinationIP:  stringPtr("192.168.1.100"),
+				ConflictPolicy: "warn",
+			},
+		},
+		{
+			name: "single-port fields alongside ports",
+			spec: PortForwardRuleSpec{
+				ExternalPort:   8080,
+				Ports:          []PortForwardPort{{ExternalPort: 8081, Protocol: "tcp"}},
+				ServiceRef:     &ServiceReference{Name: "web", Port: "http"},
+				ConflictPolicy: "warn",
+			},
+			wantErrors: []string{"spec.externalPort", "spec.serviceRef.port"},
+		},
+		{
+			name: "destinationPort alongside ports",
+			spec: PortForwardRuleSpec{
+				Ports:           []PortForwardPort{{ExternalPort: 8081, Protocol: "tcp"}},
+				DestinationIP:   stringPtr("192.168.1.100"),
+				DestinationPort: intPtr(80),
+				ConflictPolicy:  "warn",
+			},
+			wantErrors: []string{"spec.destinationPort"},
+		},
+		{
+			name: "duplicate names and overlapping ports",
+			spec: PortForwardRuleSpec{
+				Ports: []PortForwardPort{
+					{Name: "web", ExternalPort: 443, Protocol: "tcp"},
+					{Name: "web", ExternalPort: 443, Protocol: "both"},
+				},
+				ServiceRef:     serviceRef,
+				ConflictPolicy: "warn",
+			},
+			wantErrors: []string{"spec.ports[1].name", "spec.ports[1].externalPort"},
+		},
+		{
+			name: "invalid port values",
+			spec: PortForwardRuleSpec{
+				Ports: []PortForwardPort{
+					{ExternalPort: 70000, Protocol: "tcp"},
+					{ExternalPort: 8080, Protocol: "sctp", TargetPort: intOrStringPtr(intstr.FromInt32(0))},
+				},
+				ServiceRef:     serviceRef,
+				ConflictPolicy: "warn",
+			},
+			wantErrors: []string{"spec.ports[0].externalPort", "spec.ports[1].protocol", "spec.ports[1].targetPort"},
+		},
+		{
+			name: "named target port without serviceRef",
+			spec: PortForwardRuleSpec{
+				Ports:          []PortForwardPort{{ExternalPort: 8080, Protocol: "tcp", TargetPort: intOrStringPtr(intstr.FromString("http"))}},
+				DestinationIP:  stringPtr("192.168.1.100"),
+				ConflictPolicy: "warn",
+			},
+			wantErrors: []string{"spec.ports[0].targetPort"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &PortForwardRule{Spec: tt.spec}
+			errs := rule.ValidateCreate()
+
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("Expected errors for %v, got %v", tt.wantErrors, errs)
+			}
+			for i, want := range tt.wantErrors {
+				if errs[i].Field != want {
+					t.Errorf("Expected error %d on %s, got %v", i, want, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPortForwardRule_ValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name       string
+		schedule   *PortForwardSchedule
+		wantErrors []string
+	}{
+		{
+			name: "office hours",
+			schedule: &PortForwardSchedule{
+				TimeZone: "Europe/Stockholm",
+				Windows:  []ScheduleWindow{{Start: "0 8 * * mon-fri", Duration: metav1.Duration{Duration: 9 * time.Hour}}},
+			},
+		},
+		{
+			name: "unknown time zone",
+			schedule: &PortForwardSchedule{
+				TimeZone: "Mars/Olympus",
+				Windows:  []ScheduleWindow{{Start: "0 8 * * *", Duration: metav1.Duration{Duration: time.Hour}}},
+			},
+			wantErrors: []string{"spec.schedule.timeZone"},
+		},
+		{
+			name:       "no windows",
+			schedule:   &PortForwardSchedule{},
+			wantErrors: []string{"spec.schedule.windows"},
+		},
+		{
+			name: "invalid windows",
+			schedule: &PortForwardSchedule{
+				Windows: []ScheduleWindow{
+					{Start: "0 8 * *", Duration: metav1.Duration{Duration: time.Hour}},
+					{Start: "0 8 * * *", Duration: metav1.Duration{Duration: time.Second}},
+				},
+			},
+			wantErrors: []string{"spec.schedule.windows[0]", "spec.schedule.windows[1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &PortForwardRule{Spec: PortForwardRuleSpec{
+				ExternalPort:   3389,
+				Protocol:       "tcp",
+				ServiceRef:     &ServiceReference{Name: "rdp", Port: "rdp"},
+				ConflictPolicy: "warn",
+				Schedule:       tt.schedule,
+			}}
+			errs := rule.ValidateCreate()
+
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("Expected errors for %v, got %v", tt.wantErrors, errs)
+			}
+			for i, want := range tt.wantErrors {
+				if errs[i].Field != want {
+					t.Errorf("Expected error %d on %s, got %v", i, want, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPortForwardRule_ValidateExpiry(t *testing.T) {
+	expiresAt := &metav1.Time{Time: time.Date(2026, 3, 4, 18, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name       string
+		expiresAt  *metav1.Time
+		ttl        *metav1.Duration
+		wantErrors []string
+	}{
+		{name: "expiresAt", expiresAt: expiresAt},
+		{name: "ttl", ttl: &metav1.Duration{Duration: 2 * time.Hour}},
+		{name: "expiresAt and ttl", expiresAt: expiresAt, ttl: &metav1.Duration{Duration: 2 * time.Hour}, wantErrors: []string{"spec.ttl"}},
+		{name: "negative ttl", ttl: &metav1.Duration{Duration: -time.Hour}, wantErrors: []string{"spec.ttl"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &PortForwardRule{Spec: PortForwardRuleSpec{
+				ExternalPort:   2222,
+				Protocol:       "tcp",
+				ServiceRef:     &ServiceReference{Name: "ssh", Port: "ssh"},
+				ConflictPolicy: "warn",
+				ExpiresAt:      tt.expiresAt,
+				TTL:            tt.ttl,
+			}}
+			errs := rule.ValidateCreate()
+
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("Expected errors for %v, got %v", tt.wantErrors, errs)
+			}
+			for i, want := range tt.wantErrors {
+				if errs[i].Field != want {
+					t.Errorf("Expected error %d on %s, got %v", i, want, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPortForwardRule_ValidateReadinessGate(t *testing.T) {
+	serviceRule := &PortForwardRule{Spec: PortForwardRuleSpec{
+		ExternalPort:   27015,
+		Protocol:       "udp",
+		ServiceRef:     &ServiceReference{Name: "game", Port: "game"},
+		ConflictPolicy: "warn",
+		ReadinessGate:  true,
+	}}
+	if errs := serviceRule.ValidateCreate(); len(errs) != 0 {
+		t.Errorf("Expected readinessGate with serviceRef to be valid, got %v", errs)
+	}
+
+	destinationRule := &PortForwardRule{Spec: PortForwardRuleSpec{
+		ExternalPort:    27015,
+		Protocol:        "udp",
+		DestinationIP:   stringPtr("192.168.1.100"),
+		DestinationPort: intPtr(27015),
+		ConflictPolicy:  "warn",
+		ReadinessGate:   true,
+	}}
+	errs := destinationRule.ValidateCreate()
+	if len(errs) != 1 || errs[0].Field != "spec.readinessGate" {
+		t.Errorf("Expected readinessGate without serviceRef to be rejected, got %v", errs)
+	}
+}
+
+func TestPortForwardRule_ValidateFailover(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(spec *PortForwardRuleSpec)
+		wantErrors []string
+	}{
+		{name: "valid failover"},
+		{
+			name:       "duplicate destination",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.Failover.Destinations[1].IP = "192.168.1.20" },
+			wantErrors: []string{"spec.failover.destinations[1].ip"},
+		},
+		{
+			name:       "invalid destination",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.Failover.Destinations[0].IP = "backup" },
+			wantErrors: []string{"spec.failover.destinations[0].ip"},
+		},
+		{
+			name:       "no destinations",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.Failover.Destinations = nil },
+			wantErrors: []string{"spec.failover.destinations"},
+		},
+		{
+			name:       "unsupported protocol",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.Failover.HealthCheck.Protocol = "icmp" },
+			wantErrors: []string{"spec.failover.healthCheck.protocol"},
+		},
+		{
+			name:       "relative path",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.Failover.HealthCheck.Path = "healthz" },
+			wantErrors: []string{"spec.failover.healthCheck.path"},
+		},
+		{
+			name: "timeout longer than interval",
+			mutate: func(spec *PortForwardRuleSpec) {
+				spec.Failover.HealthCheck.Interval = &metav1.Duration{Duration: 2 * time.Second}
+			},
+			wantErrors: []string{"spec.failover.healthCheck.timeout"},
+		},
+		{
+			name:       "failover with destinationIP",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.DestinationIP = stringPtr("192.168.1.22") },
+			wantErrors: []string{"spec.failover"},
+		},
+		{
+			name:       "failover without destinationPort",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.DestinationPort = nil },
+			wantErrors: []string{"spec.destinationPort"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &PortForwardRule{Spec: PortForwardRuleSpec{
+				ExternalPort:    1194,
+				Protocol:        "udp",
+				DestinationPort: intPtr(1194),
+				ConflictPolicy:  "warn",
+				Failover: &DestinationFailover{
+					Destinations: []FailoverDestination{{IP: "192.168.1.20", Priority: 100}, {IP: "192.168.1.21"}},
+					HealthCheck:  HealthCheck{Protocol: "tcp", Port: 22},
+				},
+			}}
+			if tt.mutate != nil {
+				tt.mutate(&rule.Spec)
+			}
+			errs := rule.ValidateCreate()
+
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("Expected errors for %v, got %v", tt.wantErrors, errs)
+			}
+			for i, want := range tt.wantErrors {
+				if errs[i].Field != want {
+					t.Errorf("Expected error %d on %s, got %v", i, want, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDestinationFailover_Preferred(t *testing.T) {
+	failover := &DestinationFailover{Destinations: []FailoverDestination{
+		{IP: "192.168.1.21"}, {IP: "192.168.1.20", Priority: 100}, {IP: "192.168.1.22"},
+	}}
+	preferred := failover.Preferred()
+	want := []string{"192.168.1.20", "192.168.1.21", "192.168.1.22"}
+	for i, destination := range preferred {
+		if destination.IP != want[i] {
+			t.Errorf("Expected destination %d to be %s, got %s", i, want[i], destination.IP)
+		}
+	}
+	if failover.Destinations[0].IP != "192.168.1.21" {
+		t.Error("Expected Preferred not to reorder the spec")
+	}
+}
+
+func TestPortForwardRule_ValidateCutover(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(spec *PortForwardRuleSpec)
+		wantErrors []string
+	}{
+		{name: "valid cutover"},
+		{
+			name: "cutover without serviceRef",
+			mutate: func(spec *PortForwardRuleSpec) {
+				spec.ServiceRef = nil
+				spec.DestinationIP = stringPtr("192.168.1.20")
+				spec.DestinationPort = intPtr(8080)
+			},
+			wantErrors: []string{"spec.serviceRef"},
+		},
+		{
+			name:       "invalid green service",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.Cutover.Green.Name = "Web_Green" },
+			wantErrors: []string{"spec.cutover.green.name"},
+		},
+		{
+			name:       "invalid green namespace",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.Cutover.Green.Namespace = stringPtr("Apps") },
+			wantErrors: []string{"spec.cutover.green.namespace"},
+		},
+		{
+			name:       "unsupported active service",
+			mutate:     func(spec *PortForwardRuleSpec) { spec.Cutover.Active = "red" },
+			wantErrors: []string{"spec.cutover.active"},
+		},
+		{
+			name: "invalid health check",
+			mutate: func(spec *PortForwardRuleSpec) {
+				spec.Cutover.HealthCheck = &HealthCheck{Protocol: "http", Path: "healthz"}
+			},
+			wantErrors: []string{"spec.cutover.healthCheck.path"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &PortForwardRule{Spec: PortForwardRuleSpec{
+				ExternalPort:   8080,
+				Protocol:       "tcp",
+				ConflictPolicy: "warn",
+				ServiceRef:     &ServiceReference{Name: "web", Port: "http"},
+				Cutover: &ServiceCutover{
+					Green:  CutoverService{Name: "web-green"},
+					Active: CutoverGreen,
+				},
+			}}
+			if tt.mutate != nil {
+				tt.mutate(&rule.Spec)
+			}
+			errs := rule.ValidateCreate()
+
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("Expected errors for %v, got %v", tt.wantErrors, errs)
+			}
+			for i, want := range tt.wantErrors {
+				if errs[i].Field != want {
+					t.Errorf("Expected error %d on %s, got %v", i, want, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPortForwardRuleSpec_ActiveServiceRef(t *testing.T) {
+	spec := &PortForwardRuleSpec{
+		ServiceRef: &ServiceReference{Name: "web", Port: "http"},
+		Cutover:    &ServiceCutover{Green: CutoverService{Name: "web-green", Namespace: stringPtr("apps")}, Active: CutoverGreen},
+	}
+
+	if ref := spec.ActiveServiceRef(&PortForwardRuleStatus{}); ref != spec.ServiceRef {
+		t.Errorf("Expected serviceRef before the first cutover, got %+v", ref)
+	}
+
+	ref := spec.ActiveServiceRef(&PortForwardRuleStatus{Cutover: &CutoverStatus{Active: CutoverGreen}})
+	if ref.Name != "web-green" || ref.Namespace == nil || *ref.Namespace != "apps" || ref.Port != "http" {
+		t.Errorf("Expected green Service on port http, got %+v", ref)
+	}
+}
+
+func TestPortForwardRuleSpec_ExpiryTime(t *testing.T) {
+	created := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	expiresAt := created.Add(30 * time.Minute)
+
+	if got := (&PortForwardRuleSpec{}).ExpiryTime(created); !got.IsZero() {
+		t.Errorf("Expected no expiry, got %v", got)
+	}
+	if got := (&PortForwardRuleSpec{ExpiresAt: &metav1.Time{Time: expiresAt}}).ExpiryTime(created); !got.Equal(expiresAt) {
+		t.Errorf("Expected expiry %v, got %v", expiresAt, got)
+	}
+	if got := (&PortForwardRuleSpec{TTL: &metav1.Duration{Duration: 2 * time.Hour}}).ExpiryTime(created); !got.Equal(created.Add(2 * time.Hour)) {
+		t.Errorf("Expected expiry two hours after creation, got %v", got)
+	}
+}
+
 func TestPortForwardRule_ValidateCrossNamespacePortConflict(t *testing.T) {
 	// This test requires a fake client to work properly
 	// For now, we'll test that the method doesn't panic with nil input
@@ -598,6 +1024,44 @@ func TestPortForwardRule_ValidateServiceExists(t *testing.T) {
 	}
 }
 
+func TestClusterPortForwardRule_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		namespace   *string
+		expectError bool
+	}{
+		{name: "service reference with namespace", namespace: stringPtr("ingress-nginx"), expectError: false},
+		{name: "service reference without namespace", namespace: nil, expectError: true},
+		{name: "service reference with empty namespace", namespace: stringPtr(""), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &ClusterPortForwardRule{
+				Spec: PortForwardRuleSpec{
+					ExternalPort:   443,
+					Protocol:       "tcp",
+					Priority:       100,
+					ConflictPolicy: "warn",
+					ServiceRef: &ServiceReference{
+						Name:      "ingress-nginx-controller",
+						Namespace: tt.namespace,
+						Port:      "https",
+					},
+				},
+			}
+
+			errs := rule.ValidateCreate()
+			if tt.expectError && len(errs) == 0 {
+				t.Error("Expected validation error, got none")
+			}
+			if !tt.expectError && len(errs) > 0 {
+				t.Errorf("Expected no validation errors, got %v", errs)
+			}
+		})
+	}
+}
+
 // Helper functions for tests
 func stringPtr(s string) *string {
 	return &s
@@ -18,7 +18,10 @@ var (
 	AddToScheme = SchemeBuilder.AddToScheme
 )
 
-// Register PortForwardRule and PortForwardRuleList types with the SchemeBuilder
+// Register the API types with the SchemeBuilder
 func init() {
 	SchemeBuilder.Register(&PortForwardRule{}, &PortForwardRuleList{})
+	SchemeBuilder.Register(&PortForwardReferenceGrant{}, &PortForwardReferenceGrantList{})
+	SchemeBuilder.Register(&ClusterPortForwardRule{}, &ClusterPortForwardRuleList{})
+	SchemeBuilder.Register(&PortForwardPolicy{}, &PortForwardPolicyList{})
 }
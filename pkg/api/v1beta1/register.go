@@ -0,0 +1,23 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// SchemeGroupVersion is group version used to register these objects
+	SchemeGroupVersion = schema.GroupVersion{Group: "unifi-port-forward.fiskhe.st", Version: "v1beta1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: SchemeGroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+// Register the API types with the SchemeBuilder
+func init() {
+	SchemeBuilder.Register(&PortForwardRule{}, &PortForwardRuleList{})
+	SchemeBuilder.Register(&ClusterPortForwardRule{}, &ClusterPortForwardRuleList{})
+}
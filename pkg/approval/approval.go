@@ -0,0 +1,55 @@
+// Package approval signs and verifies the approved annotation of Services and PortForwardRules.
+// Approvers set the annotation to the exposure hash of a forward; the admission webhook checks
+// that they may approve it and signs the hash with a key only the controller holds. The
+// controller only applies forwards whose annotation carries a valid signature, so setting the
+// annotation while the webhook is unavailable or bypassed approves nothing.
+package approval
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// MinKeyLength is the shortest signing key accepted
+const MinKeyLength = 32
+
+// separator separates the exposure hash from its signature in the approved annotation
+const separator = "."
+
+// Sign returns the approved annotation value approving hash for the object of kind in namespace
+// with name. The signature covers the object, so it cannot be copied to another one.
+func Sign(key []byte, kind, namespace, name, hash string) string {
+	return hash + separator + hex.EncodeToString(mac(key, kind, namespace, name, hash))
+}
+
+// Verify reports whether value is an approved annotation value approving hash for the object
+// of kind in namespace with name. Nothing is approved without a key.
+func Verify(key []byte, kind, namespace, name, hash, value string) bool {
+	if len(key) == 0 {
+		return false
+	}
+	approved, signature, found := strings.Cut(value, separator)
+	if !found || approved != hash {
+		return false
+	}
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(decoded, mac(key, kind, namespace, name, hash))
+}
+
+// Hash returns the exposure hash of an approved annotation value, signed or not
+func Hash(value string) string {
+	hash, _, _ := strings.Cut(value, separator)
+	return hash
+}
+
+func mac(key []byte, kind, namespace, name, hash string) []byte {
+	h := hmac.New(sha256.New, key)
+	// Fields are joined with a byte no Kubernetes name or hash contains
+	h.Write([]byte(strings.Join([]string{kind, namespace, name, hash}, "\x00")))
+	return h.Sum(nil)
+}
@@ -0,0 +1,41 @@
+package approval
+
+import "testing"
+
+func TestSignAndVerify(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	value := Sign(key, "Service", "apps", "web", "a1b2c3d4e5f60718")
+
+	if Hash(value) != "a1b2c3d4e5f60718" {
+		t.Errorf("Hash(%q) = %q, want the signed hash", value, Hash(value))
+	}
+	if !Verify(key, "Service", "apps", "web", "a1b2c3d4e5f60718", value) {
+		t.Errorf("Verify() rejected the signed value %q", value)
+	}
+
+	tests := []struct {
+		name  string
+		key   []byte
+		kind  string
+		ns    string
+		obj   string
+		hash  string
+		value string
+	}{
+		{name: "unsigned hash", key: key, kind: "Service", ns: "apps", obj: "web", hash: "a1b2c3d4e5f60718", value: "a1b2c3d4e5f60718"},
+		{name: "other key", key: []byte("fedcba9876543210fedcba9876543210"), kind: "Service", ns: "apps", obj: "web", hash: "a1b2c3d4e5f60718", value: value},
+		{name: "no key", kind: "Service", ns: "apps", obj: "web", hash: "a1b2c3d4e5f60718", value: value},
+		{name: "other object", key: key, kind: "Service", ns: "apps", obj: "api", hash: "a1b2c3d4e5f60718", value: value},
+		{name: "other namespace", key: key, kind: "Service", ns: "other", obj: "web", hash: "a1b2c3d4e5f60718", value: value},
+		{name: "other kind", key: key, kind: "PortForwardRule", ns: "apps", obj: "web", hash: "a1b2c3d4e5f60718", value: value},
+		{name: "changed exposure", key: key, kind: "Service", ns: "apps", obj: "web", hash: "0000000000000000", value: value},
+		{name: "invalid signature", key: key, kind: "Service", ns: "apps", obj: "web", hash: "a1b2c3d4e5f60718", value: "a1b2c3d4e5f60718.zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Verify(tt.key, tt.kind, tt.ns, tt.obj, tt.hash, tt.value) {
+				t.Errorf("Verify(%q) = true, want false", tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/filipowm/go-unifi/unifi"
+	"sigs.k8s.io/yaml"
+)
+
+// APIVersion and Kind identify the backup format. The version is bumped whenever a change would
+// make older releases restore a backup incorrectly.
+const (
+	APIVersion = "unifi-port-forward.fiskhe.st/backup/v1"
+	Kind       = "PortForwardBackup"
+)
+
+// Formats a backup can be written in
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// Backup is a snapshot of every port forward rule on a site, managed by the controller or not
+type Backup struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Metadata   Metadata `json:"metadata"`
+
+	// Rules hold the router rules exactly as the UniFi API returned them
+	Rules []unifi.PortForward `json:"rules"`
+}
+
+// Metadata describes where and when a backup was taken
+type Metadata struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Host      string    `json:"host,omitempty"`
+	Site      string    `json:"site,omitempty"`
+}
+
+// New returns a backup of rules taken from site on host at createdAt
+func New(rules []*unifi.PortForward, host, site string, createdAt time.Time) *Backup {
+	backup := &Backup{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Metadata: Metadata{
+			CreatedAt: createdAt.UTC(),
+			Host:      host,
+			Site:      site,
+		},
+		Rules: make([]unifi.PortForward, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		backup.Rules = append(backup.Rules, *rule)
+	}
+	return backup
+}
+
+// Encode writes the backup to w in the given format
+func Encode(w io.Writer, backup *Backup, format string) error {
+	var data []byte
+	var err error
+	switch format {
+	case FormatYAML:
+		data, err = yaml.Marshal(backup)
+	case FormatJSON:
+		data, err = json.MarshalIndent(backup, "", "  ")
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("unsupported backup format %q (expected %q or %q)", format, FormatYAML, FormatJSON)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// Decode reads a backup in either format and checks that this release can restore it
+func Decode(data []byte) (*Backup, error) {
+	var backup Backup
+	if err := yaml.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("failed to decode backup: %w", err)
+	}
+
+	if backup.Kind != Kind {
+		return nil, fmt.Errorf("not a backup: kind is %q, expected %q", backup.Kind, Kind)
+	}
+	if backup.APIVersion != APIVersion {
+		return nil, fmt.Errorf("unsupported backup version %q (expected %q)", backup.APIVersion, APIVersion)
+	}
+	return &backup, nil
+}
@@ -0,0 +1,197 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"unifi-port-forward/testutils"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+func backupRules() []unifi.PortForward {
+	return []unifi.PortForward{
+		{
+			ID: "a1", SiteID: "site1", Name: "Minecraft", DstPort: "25565", FwdPort: "25565", Fwd: "192.168.1.20",
+			Proto: "tcp_udp", Enabled: false, PfwdInterface: "wan", Src: "any", DestinationIP: "any",
+		},
+		{
+			ID: "a2", SiteID: "site1", Name: "default/web:443", DstPort: "443", FwdPort: "8443", Fwd: "192.168.1.100",
+			Proto: "tcp", Enabled: true, Log: true, PfwdInterface: "both", Src: "any",
+			DestinationIPs: []unifi.PortForwardDestinationIPs{{DestinationIP: "any", Interface: "wan"}},
+		},
+		{
+			ID: "a3", SiteID: "site1", Name: "SSH", DstPort: "2222", FwdPort: "22", Fwd: "192.168.1.10",
+			Proto: "tcp", Enabled: true, PfwdInterface: "wan", SrcFirewallGroupID: "fg1",
+			SrcLimitingEnabled: true, SrcLimitingType: "firewall_group",
+		},
+	}
+}
+
+func rulePointers(rules []unifi.PortForward) []*unifi.PortForward {
+	pointers := make([]*unifi.PortForward, len(rules))
+	for i := range rules {
+		pointers[i] = &rules[i]
+	}
+	return pointers
+}
+
+func TestEncodeDecode(t *testing.T) {
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	backup := New(rulePointers(backupRules()), "https://192.168.1.1", "default", createdAt)
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, backup, format); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if !strings.Contains(buf.String(), "src_firewall_group_id") {
+				t.Errorf("Encode() does not use the UniFi API field names:\n%s", buf.String())
+			}
+
+			decoded, err := Decode(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(decoded, backup) {
+				t.Errorf("Decode() = %+v, want %+v", decoded, backup)
+			}
+		})
+	}
+
+	if err := Encode(&bytes.Buffer{}, backup, "xml"); err == nil {
+		t.Error("Encode() with an unsupported format succeeded")
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "other kind", data: "apiVersion: " + APIVersion + "\nkind: PortForwardRule\n"},
+		{name: "other version", data: "apiVersion: unifi-port-forward.fiskhe.st/backup/v2\nkind: " + Kind + "\n"},
+		{name: "not yaml", data: "rules: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode([]byte(tt.data)); err == nil {
+				t.Error("Decode() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	backup := New(rulePointers(backupRules()), "", "default", time.Now())
+
+	newRouter := func() *testutils.MockRouter {
+		router := testutils.NewMockRouter()
+		// a1 is unchanged, a2 was deleted and a3 was recreated with another ID and destination
+		rules := backupRules()
+		router.AddPortForwardRule(rules[0])
+		recreated := rules[2]
+		recreated.ID = "b3"
+		recreated.Fwd = "192.168.1.11"
+		router.AddPortForwardRule(recreated)
+		return router
+	}
+
+	t.Run("without reset", func(t *testing.T) {
+		router := newRouter()
+		results, err := Restore(context.Background(), router, backup, RestoreOptions{})
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+
+		want := []Action{ActionUnchanged, ActionCreate, ActionSkip}
+		if got := actions(results); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Restore() actions = %v, want %v", got, want)
+		}
+		if results[2].RouterID != "b3" || !reflect.DeepEqual(results[2].Fields, []string{"fwd"}) {
+			t.Errorf("changed rule result = %+v, want router ID b3 and field fwd", results[2])
+		}
+
+		created := router.GetPortForwardRuleByName("default/web:443")
+		if created == nil || created.ID != results[1].RouterID || len(created.DestinationIPs) != 1 || created.FwdPort != "8443" {
+			t.Errorf("created rule = %+v, want a copy of the backed up rule", created)
+		}
+		if router.GetCallCount("UpdatePortForward") != 0 {
+			t.Error("Restore() without reset updated a rule")
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		router := newRouter()
+		results, err := Restore(context.Background(), router, backup, RestoreOptions{Reset: true})
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if results[2].Action != ActionReset {
+			t.Fatalf("changed rule action = %s, want %s", results[2].Action, ActionReset)
+		}
+
+		reset := router.GetPortForwardRuleByName("SSH")
+		if reset == nil || reset.ID != "b3" || reset.Fwd != "192.168.1.10" || reset.SrcFirewallGroupID != "fg1" {
+			t.Errorf("reset rule = %+v, want the backed up rule under ID b3", reset)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		router := newRouter()
+		results, err := Restore(context.Background(), router, backup, RestoreOptions{Reset: true, DryRun: true})
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		want := []Action{ActionUnchanged, ActionCreate, ActionReset}
+		if got := actions(results); !reflect.DeepEqual(got, want) {
+			t.Errorf("Restore() actions = %v, want %v", got, want)
+		}
+		if router.GetCallCount("CreatePortForward")+router.GetCallCount("UpdatePortForward") != 0 {
+			t.Error("Restore() with dry run changed the router")
+		}
+	})
+
+	t.Run("selection", func(t *testing.T) {
+		router := newRouter()
+		opts := RestoreOptions{Selector: Selector{Ports: []int{443, 2222}, Name: regexp.MustCompile(`^default/`)}}
+		results, err := Restore(context.Background(), router, backup, opts)
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if len(results) != 1 || results[0].BackupID != "a2" {
+			t.Errorf("Restore() results = %+v, want only a2", results)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		router := newRouter()
+		router.SetSimulatedFailure("CreatePortForward", true)
+		results, err := Restore(context.Background(), router, backup, RestoreOptions{Reset: true})
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		want := []Action{ActionUnchanged, ActionFail, ActionReset}
+		if got := actions(results); !reflect.DeepEqual(got, want) {
+			t.Errorf("Restore() actions = %v, want %v", got, want)
+		}
+		if results[1].Reason == "" {
+			t.Error("failed rule has no reason")
+		}
+	})
+}
+
+func actions(results []Result) []Action {
+	got := make([]Action, len(results))
+	for i, result := range results {
+		got[i] = result.Action
+	}
+	return got
+}
@@ -0,0 +1,247 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+// Router is the part of a router a backup is restored to. Rules are written whole, so fields a
+// routers.PortConfig cannot describe, such as source firewall groups, are restored as well.
+type Router interface {
+	ListAllPortForwards(ctx context.Context) ([]*unifi.PortForward, error)
+	CreatePortForward(ctx context.Context, pf *unifi.PortForward) (*unifi.PortForward, error)
+	UpdatePortForward(ctx context.Context, pf *unifi.PortForward) (*unifi.PortForward, error)
+}
+
+// Selector limits a restore to some of the rules in a backup. Empty fields match every rule.
+type Selector struct {
+	// IDs are the router IDs the rules had when the backup was taken
+	IDs []string
+
+	// Ports are the external ports of the rules
+	Ports []int
+
+	// Name matches the rule name
+	Name *regexp.Regexp
+}
+
+// matches reports whether a backed up rule passes the selector
+func (s Selector) matches(rule *unifi.PortForward) bool {
+	if len(s.IDs) > 0 && !containsString(s.IDs, rule.ID) {
+		return false
+	}
+	if len(s.Ports) > 0 {
+		port, err := strconv.Atoi(rule.DstPort)
+		if err != nil || !containsInt(s.Ports, port) {
+			return false
+		}
+	}
+	if s.Name != nil && !s.Name.MatchString(rule.Name) {
+		return false
+	}
+	return true
+}
+
+// RestoreOptions control how a backup is restored
+type RestoreOptions struct {
+	Selector Selector
+
+	// Reset overwrites rules that changed since the backup; without it they are left alone
+	Reset bool
+
+	// DryRun reports what would be done without changing the router
+	DryRun bool
+}
+
+// Action is what a restore did, or would do, with a rule
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionReset     Action = "reset"
+	ActionUnchanged Action = "unchanged"
+	ActionSkip      Action = "skip"
+	ActionFail      Action = "fail"
+)
+
+// Result reports the restore of a single rule
+type Result struct {
+	// BackupID is the ID of the rule in the backup, RouterID that of the rule on the router
+	BackupID string `json:"backupId"`
+	RouterID string `json:"routerId,omitempty"`
+
+	Name     string `json:"name"`
+	DstPort  string `json:"dstPort"`
+	Protocol string `json:"protocol"`
+
+	Action Action `json:"action"`
+
+	// Fields are the fields of a changed rule that differ from the backup
+	Fields []string `json:"fields,omitempty"`
+
+	// Reason explains skipped and failed rules
+	Reason string `json:"reason,omitempty"`
+}
+
+// Restore recreates the selected rules of backup that are missing from the router and, with
+// Reset, overwrites those that changed. A backed up rule is the router rule with the same ID or,
+// when the rule was deleted and recreated since, with the same external port and protocol.
+// Failing rules do not stop the restore; they are reported in their Result.
+func Restore(ctx context.Context, router Router, backup *Backup, opts RestoreOptions) ([]Result, error) {
+	current, err := router.ListAllPortForwards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+
+	byID := make(map[string]unifi.PortForward, len(current))
+	byPort := make(map[string]unifi.PortForward, len(current))
+	for _, rule := range current {
+		byID[rule.ID] = *rule
+		if key := portKey(rule); byPort[key].ID == "" {
+			byPort[key] = *rule
+		}
+	}
+
+	selected := make([]unifi.PortForward, 0, len(backup.Rules))
+	for _, rule := range backup.Rules {
+		if opts.Selector.matches(&rule) {
+			selected = append(selected, rule)
+		}
+	}
+
+	// Match by ID first, so a rule recreated on the port of another backed up rule is not
+	// taken for it
+	matches := make([]*unifi.PortForward, len(selected))
+	matched := make(map[string]bool)
+	for i := range selected {
+		if existing, ok := byID[selected[i].ID]; ok {
+			matches[i] = &existing
+			matched[existing.ID] = true
+		}
+	}
+	for i := range selected {
+		if matches[i] != nil {
+			continue
+		}
+		if existing, ok := byPort[portKey(&selected[i])]; ok && !matched[existing.ID] {
+			matches[i] = &existing
+			matched[existing.ID] = true
+		}
+	}
+
+	results := make([]Result, 0, len(selected))
+	for i, rule := range selected {
+		result := Result{BackupID: rule.ID, Name: rule.Name, DstPort: rule.DstPort, Protocol: rule.Proto}
+		existing := matches[i]
+
+		if existing == nil {
+			result.Action = ActionCreate
+			if !opts.DryRun {
+				created, err := router.CreatePortForward(ctx, &rule)
+				if err != nil {
+					result.Action, result.Reason = ActionFail, fmt.Sprintf("create failed: %v", err)
+				} else {
+					result.RouterID = created.ID
+				}
+			}
+			results = append(results, result)
+			continue
+		}
+
+		result.RouterID = existing.ID
+		result.Fields, err = changedFields(&rule, existing)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case len(result.Fields) == 0:
+			result.Action = ActionUnchanged
+		case !opts.Reset:
+			result.Action, result.Reason = ActionSkip, "changed since the backup"
+		default:
+			result.Action = ActionReset
+			if !opts.DryRun {
+				rule.ID = existing.ID
+				if _, err := router.UpdatePortForward(ctx, &rule); err != nil {
+					result.Action, result.Reason = ActionFail, fmt.Sprintf("reset failed: %v", err)
+				}
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// portKey identifies a rule by what the router forwards
+func portKey(rule *unifi.PortForward) string {
+	return rule.DstPort + "/" + rule.Proto
+}
+
+// changedFields returns the API names of the fields of a router rule that differ from its
+// backup, ignoring the IDs
+func changedFields(backedUp, current *unifi.PortForward) ([]string, error) {
+	want, err := ruleFields(backedUp)
+	if err != nil {
+		return nil, err
+	}
+	got, err := ruleFields(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for field := range want {
+		if !reflect.DeepEqual(want[field], got[field]) {
+			fields = append(fields, field)
+		}
+	}
+	for field := range got {
+		if _, ok := want[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// ruleFields returns the fields of a rule as the UniFi API names them
+func ruleFields(rule *unifi.PortForward) (map[string]interface{}, error) {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare rule %s: %w", rule.ID, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to compare rule %s: %w", rule.ID, err)
+	}
+	delete(fields, "_id")
+	delete(fields, "site_id")
+	return fields, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
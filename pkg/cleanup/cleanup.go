@@ -0,0 +1,200 @@
+// Package cleanup selects router port forward rules for bulk deletion, by name, destination,
+// external port or ownership, and deletes them
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/helpers"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+// PortRange is an inclusive range of external ports
+type PortRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// ParsePortRange parses a port such as 8080 or a range such as 8000-8100
+func ParsePortRange(value string) (PortRange, error) {
+	fromValue, toValue, isRange := strings.Cut(strings.TrimSpace(value), "-")
+	if !isRange {
+		toValue = fromValue
+	}
+	from, err := strconv.Atoi(strings.TrimSpace(fromValue))
+	if err != nil || from < 1 || from > 65535 {
+		return PortRange{}, fmt.Errorf("invalid port range %q, expected a port or from-to between 1 and 65535", value)
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(toValue))
+	if err != nil || to < from || to > 65535 {
+		return PortRange{}, fmt.Errorf("invalid port range %q, expected a port or from-to between 1 and 65535", value)
+	}
+	return PortRange{From: from, To: to}, nil
+}
+
+// Contains reports whether port is in the range
+func (r PortRange) Contains(port int) bool {
+	return port >= r.From && port <= r.To
+}
+
+// Selector selects router rules for deletion. A rule must pass every set criterion; a
+// Selector with no criteria set selects nothing, so a missing flag never deletes every rule.
+type Selector struct {
+	// Mappings select rules by forward port and destination IP, keyed by forward port
+	Mappings map[string]string
+
+	// NamePrefix and Name match the rule name
+	NamePrefix string
+	Name       *regexp.Regexp
+
+	// ManagedOnly selects rules named like the controller names its rules
+	ManagedOnly bool
+
+	// DestinationCIDRs select rules forwarding into one of the networks
+	DestinationCIDRs []*net.IPNet
+
+	// Ports select rules whose external port is in one of the ranges
+	Ports []PortRange
+
+	// IDs select rules by router ID, such as the orphans found by Orphans
+	IDs map[string]bool
+}
+
+// Empty reports whether no criterion is set
+func (s Selector) Empty() bool {
+	return s.Mappings == nil && s.NamePrefix == "" && s.Name == nil && !s.ManagedOnly &&
+		len(s.DestinationCIDRs) == 0 && len(s.Ports) == 0 && s.IDs == nil
+}
+
+// Matches reports whether a router rule passes every criterion of the selector
+func (s Selector) Matches(rule *unifi.PortForward) bool {
+	if s.Empty() {
+		return false
+	}
+	if s.Mappings != nil {
+		ip, exists := s.Mappings[rule.FwdPort]
+		if !exists || ip != rule.Fwd {
+			return false
+		}
+	}
+	if s.NamePrefix != "" && !strings.HasPrefix(rule.Name, s.NamePrefix) {
+		return false
+	}
+	if s.Name != nil && !s.Name.MatchString(rule.Name) {
+		return false
+	}
+	if s.ManagedOnly && !helpers.IsManagedRule(rule.Name) {
+		return false
+	}
+	if len(s.DestinationCIDRs) > 0 {
+		ip := net.ParseIP(rule.Fwd)
+		found := false
+		for _, network := range s.DestinationCIDRs {
+			if ip != nil && network.Contains(ip) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.Ports) > 0 {
+		port := helpers.ParseIntField(rule.DstPort)
+		found := false
+		for _, r := range s.Ports {
+			if r.Contains(port) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if s.IDs != nil && !s.IDs[rule.ID] {
+		return false
+	}
+	return true
+}
+
+// Select returns the router rules passing the selector
+func Select(rules []*unifi.PortForward, selector Selector) []*unifi.PortForward {
+	var selected []*unifi.PortForward
+	for _, rule := range rules {
+		if selector.Matches(rule) {
+			selected = append(selected, rule)
+		}
+	}
+	return selected
+}
+
+// Orphans returns the IDs of the router rules named like managed rules whose Service or rule
+// no longer exists
+func Orphans(statuses []controller.RouterRuleStatus) map[string]bool {
+	ids := make(map[string]bool)
+	for _, status := range statuses {
+		if status.OwnerKind == controller.OwnerOrphaned {
+			ids[status.ID] = true
+		}
+	}
+	return ids
+}
+
+// Router is the part of a router rules are deleted from
+type Router interface {
+	DeletePortForwardByID(ctx context.Context, ruleID string) error
+}
+
+// Action is what a clean did, or would do, with a rule
+type Action string
+
+const (
+	ActionDelete Action = "delete"
+	ActionFail   Action = "fail"
+)
+
+// Result reports the deletion of a single rule
+type Result struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DstPort     string `json:"dstPort"`
+	Protocol    string `json:"protocol"`
+	Destination string `json:"destination"`
+	ForwardPort string `json:"forwardPort"`
+	Action      Action `json:"action"`
+
+	// Reason explains failed rules
+	Reason string `json:"reason,omitempty"`
+}
+
+// Delete deletes the rules by ID, or with dryRun only reports them. Failing rules do not stop
+// the clean; they are reported in their Result.
+func Delete(ctx context.Context, router Router, rules []*unifi.PortForward, dryRun bool) []Result {
+	results := make([]Result, 0, len(rules))
+	for _, rule := range rules {
+		result := Result{
+			ID:          rule.ID,
+			Name:        rule.Name,
+			DstPort:     rule.DstPort,
+			Protocol:    rule.Proto,
+			Destination: rule.Fwd,
+			ForwardPort: rule.FwdPort,
+			Action:      ActionDelete,
+		}
+		if !dryRun {
+			if err := router.DeletePortForwardByID(ctx, rule.ID); err != nil {
+				result.Action, result.Reason = ActionFail, fmt.Sprintf("delete failed: %v", err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
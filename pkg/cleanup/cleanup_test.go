@@ -0,0 +1,136 @@
+package cleanup
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/testutils"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+func testRules() []*unifi.PortForward {
+	return []*unifi.PortForward{
+		{ID: "1", Name: "default/web:http", DstPort: "8080", FwdPort: "80", Fwd: "192.168.1.100", Proto: "tcp"},
+		{ID: "2", Name: "default/gone:http", DstPort: "9000", FwdPort: "9000", Fwd: "192.168.1.104", Proto: "tcp"},
+		{ID: "3", Name: "Printer", DstPort: "631", FwdPort: "631", Fwd: "192.168.2.25", Proto: "tcp"},
+		{ID: "4", Name: "Minecraft", DstPort: "25565", FwdPort: "25565", Fwd: "192.168.1.20", Proto: "tcp_udp"},
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PortRange
+		wantErr bool
+	}{
+		{value: "8080", want: PortRange{From: 8080, To: 8080}},
+		{value: "8000-8100", want: PortRange{From: 8000, To: 8100}},
+		{value: "8100-8000", wantErr: true},
+		{value: "http", wantErr: true},
+		{value: "0-10", wantErr: true},
+		{value: "60000-70000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePortRange(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePortRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParsePortRange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	_, lan, _ := net.ParseCIDR("192.168.1.0/24")
+
+	tests := []struct {
+		name     string
+		selector Selector
+		want     []string
+	}{
+		{name: "empty selects nothing", selector: Selector{}, want: nil},
+		{name: "mappings", selector: Selector{Mappings: map[string]string{"80": "192.168.1.100", "631": "192.168.1.25"}}, want: []string{"1"}},
+		{name: "name prefix", selector: Selector{NamePrefix: "default/"}, want: []string{"1", "2"}},
+		{name: "name regex", selector: Selector{Name: regexp.MustCompile(`(?i)^mine`)}, want: []string{"4"}},
+		{name: "managed only", selector: Selector{ManagedOnly: true}, want: []string{"1", "2"}},
+		{name: "destination cidr", selector: Selector{DestinationCIDRs: []*net.IPNet{lan}}, want: []string{"1", "2", "4"}},
+		{name: "port ranges", selector: Selector{Ports: []PortRange{{From: 600, To: 700}, {From: 9000, To: 9000}}}, want: []string{"2", "3"}},
+		{name: "ids", selector: Selector{IDs: map[string]bool{"2": true}}, want: []string{"2"}},
+		{name: "criteria combine", selector: Selector{DestinationCIDRs: []*net.IPNet{lan}, Ports: []PortRange{{From: 8000, To: 30000}}}, want: []string{"1", "2", "4"}},
+		{name: "criteria narrow", selector: Selector{DestinationCIDRs: []*net.IPNet{lan}, NamePrefix: "Mine"}, want: []string{"4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, rule := range Select(testRules(), tt.selector) {
+				got = append(got, rule.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	statuses := []controller.RouterRuleStatus{
+		{ID: "1", OwnerKind: "Service", Owner: "default/web"},
+		{ID: "2", OwnerKind: controller.OwnerOrphaned, Owner: "default/gone"},
+		{ID: "3", OwnerKind: controller.OwnerManual},
+	}
+	if got := Orphans(statuses); !reflect.DeepEqual(got, map[string]bool{"2": true}) {
+		t.Errorf("Orphans() = %v, want only rule 2", got)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	newRouter := func() *testutils.MockRouter {
+		router := testutils.NewMockRouter()
+		for _, rule := range testRules() {
+			router.AddPortForwardRule(*rule)
+		}
+		return router
+	}
+	selected := testRules()[:2]
+
+	t.Run("dry run", func(t *testing.T) {
+		router := newRouter()
+		results := Delete(context.Background(), router, selected, true)
+		if len(results) != 2 || results[0].Action != ActionDelete {
+			t.Errorf("Delete() = %+v, want two deletes", results)
+		}
+		if router.GetCallCount("DeletePortForwardByID") != 0 {
+			t.Error("Delete() with dry run deleted a rule")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		router := newRouter()
+		results := Delete(context.Background(), router, selected, false)
+		if len(results) != 2 || results[1].Action != ActionDelete {
+			t.Errorf("Delete() = %+v, want two deletes", results)
+		}
+		if remaining := router.GetPortForwardRules(); len(remaining) != 2 || remaining[0].ID != "3" {
+			t.Errorf("remaining rules = %+v, want rules 3 and 4", remaining)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		router := newRouter()
+		router.SetSimulatedFailure("DeletePortForwardByID", true)
+		results := Delete(context.Background(), router, selected, false)
+		if results[0].Action != ActionFail || results[0].Reason == "" {
+			t.Errorf("Delete() = %+v, want failures with a reason", results)
+		}
+	})
+}
@@ -6,17 +6,35 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"unifi-port-forward/pkg/approval"
 )
 
 // Annotations and Labels that we are owners of
 const (
-	FilterAnnotation          = "unifi-port-forward.fiskhe.st/mapping"
-	FinalizerLabel            = "unifi-port-forward.fiskhe.st/router-rule-protection"
-	CleanupStatusAnnotation   = "unifi-port-forward.fiskhe.st/cleanup-status"
-	CleanupAttemptsAnnotation = "unifi-port-forward.fiskhe.st/cleanup-attempts"
-	PortForwardRulesCRDName   = "portforwardrules.unifi-port-forward.fiskhe.st"
+	FilterAnnotation           = "unifi-port-forward.fiskhe.st/mapping"
+	FinalizerLabel             = "unifi-port-forward.fiskhe.st/router-rule-protection"
+	CleanupStatusAnnotation    = "unifi-port-forward.fiskhe.st/cleanup-status"
+	CleanupAttemptsAnnotation  = "unifi-port-forward.fiskhe.st/cleanup-attempts"
+	RuleIDsAnnotation          = "unifi-port-forward.fiskhe.st/rule-ids"
+	PortConflictAnnotation     = "unifi-port-forward.fiskhe.st/port-conflict"
+	ScheduleAnnotation         = "unifi-port-forward.fiskhe.st/schedule"
+	ScheduleTimeZoneAnnotation = "unifi-port-forward.fiskhe.st/schedule-timezone"
+	ExpiresAtAnnotation        = "unifi-port-forward.fiskhe.st/expires-at"
+	TTLAnnotation              = "unifi-port-forward.fiskhe.st/ttl"
+	ExpiryStatusAnnotation     = "unifi-port-forward.fiskhe.st/expiry-status"
+	ApprovedAnnotation         = "unifi-port-forward.fiskhe.st/approved"
+	PendingApprovalAnnotation  = "unifi-port-forward.fiskhe.st/pending-approval"
+	PolicyViolationAnnotation  = "unifi-port-forward.fiskhe.st/policy-violation"
+	ReadinessGateAnnotation    = "unifi-port-forward.fiskhe.st/readiness-gate"
+	BackendReadyAnnotation     = "unifi-port-forward.fiskhe.st/backend-ready"
+	DisabledPortsAnnotation    = "unifi-port-forward.fiskhe.st/disabled-ports"
+	PortForwardRulesCRDName    = "portforwardrules.unifi-port-forward.fiskhe.st"
+
+	ClusterPortForwardRulesCRDName = "clusterportforwardrules.unifi-port-forward.fiskhe.st"
 )
 
 type Config struct {
@@ -35,6 +53,30 @@ type Config struct {
 	FinalizerMaxRetries    int           `env:"FINALIZER_MAX_RETRIES" default:"3" json:"finalizerMaxRetries"`
 	FinalizerRetryInterval time.Duration `env:"FINALIZER_RETRY_INTERVAL" default:"30s" json:"finalizerRetryInterval"`
 
+	// Expiry settings
+	ExpiryWarning time.Duration `env:"EXPIRY_WARNING" default:"1h" json:"expiryWarning"`
+
+	// Readiness settings
+	ReadinessDebounce time.Duration `env:"READINESS_DEBOUNCE" default:"30s" json:"readinessDebounce"`
+
+	// Approval settings
+	RequireApproval bool `env:"REQUIRE_APPROVAL" default:"false" json:"requireApproval"`
+	// ApprovalSigningKey signs approvals in the admission webhook; only signed approvals apply
+	ApprovalSigningKey string `env:"APPROVAL_SIGNING_KEY" json:"-"`
+
+	// Admission webhook settings
+	WebhookEnabled     bool   `env:"WEBHOOK_ENABLED" default:"false" json:"webhookEnabled"`
+	WebhookPort        int    `env:"WEBHOOK_PORT" default:"9443" json:"webhookPort"`
+	WebhookCertDir     string `env:"WEBHOOK_CERT_DIR" default:"/tmp/k8s-webhook-server/serving-certs" json:"webhookCertDir"`
+	WebhookServiceName string `env:"WEBHOOK_SERVICE_NAME" default:"unifi-port-forward-webhook" json:"webhookServiceName"`
+	WebhookNamespace   string `env:"WEBHOOK_NAMESPACE" default:"unifi-port-forward" json:"webhookNamespace"`
+
+	// Read-only router state API settings
+	StateAPIEnabled bool `env:"STATE_API_ENABLED" default:"false" json:"stateApiEnabled"`
+	StateAPIPort    int  `env:"STATE_API_PORT" default:"8085" json:"stateApiPort"`
+	// StateAPICertDir holds the state API serving certificate; without one it is self-signed
+	StateAPICertDir string `env:"STATE_API_CERT_DIR" json:"stateApiCertDir"`
+
 	// Runtime values (derived from settings)
 	Host string `json:"-"`
 }
@@ -65,6 +107,15 @@ func (c *Config) Validate() error {
 		errors = append(errors, "sync interval cannot happen more often than every five minutes")
 	}
 
+	// Only the admission webhook restricts who may set the approved annotation, and signs it
+	// so the controller can verify approvals itself
+	if c.RequireApproval && !c.WebhookEnabled {
+		errors = append(errors, "approval requires the admission webhook to be enabled")
+	}
+	if c.RequireApproval && len(c.ApprovalSigningKey) < approval.MinKeyLength {
+		errors = append(errors, fmt.Sprintf("approval requires a signing key of at least %d characters", approval.MinKeyLength))
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("configuration validation failed: %s", strings.Join(errors, "; "))
 	}
@@ -91,12 +142,20 @@ func (c *Config) ToURL() (*url.URL, error) {
 	return url.Parse(c.Host)
 }
 
-// validateIP performs IP address validation using Go's net package
+// validateIP performs IP address validation using Go's net package. A port may follow the
+// address, for routers or simulators that serve on a port other than 443.
 func validateIP(ip string) error {
 	if ip == "" {
 		return fmt.Errorf("empty string")
 	}
 
+	if host, port, err := net.SplitHostPort(ip); err == nil {
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+		ip = host
+	}
+
 	parsedIP := net.ParseIP(ip)
 	if parsedIP == nil {
 		return fmt.Errorf("invalid IP address format")
@@ -132,6 +191,58 @@ func InitFromEnv(cfg *Config) {
 	if envDebug := os.Getenv("DEBUG"); envDebug != "" {
 		cfg.Debug = envDebug != ""
 	}
+	if envExpiryWarning := os.Getenv("EXPIRY_WARNING"); envExpiryWarning != "" {
+		expiryWarning, err := time.ParseDuration(envExpiryWarning)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.ExpiryWarning = expiryWarning
+	}
+	if envReadinessDebounce := os.Getenv("READINESS_DEBOUNCE"); envReadinessDebounce != "" {
+		readinessDebounce, err := time.ParseDuration(envReadinessDebounce)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.ReadinessDebounce = readinessDebounce
+	}
+	if envRequireApproval := os.Getenv("REQUIRE_APPROVAL"); envRequireApproval != "" {
+		cfg.RequireApproval = strings.EqualFold(envRequireApproval, "true")
+	}
+	if envApprovalSigningKey := os.Getenv("APPROVAL_SIGNING_KEY"); envApprovalSigningKey != "" {
+		cfg.ApprovalSigningKey = envApprovalSigningKey
+	}
+	if envWebhookEnabled := os.Getenv("WEBHOOK_ENABLED"); envWebhookEnabled != "" {
+		cfg.WebhookEnabled = strings.EqualFold(envWebhookEnabled, "true")
+	}
+	if envWebhookPort := os.Getenv("WEBHOOK_PORT"); envWebhookPort != "" {
+		webhookPort, err := strconv.Atoi(envWebhookPort)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.WebhookPort = webhookPort
+	}
+	if envWebhookCertDir := os.Getenv("WEBHOOK_CERT_DIR"); envWebhookCertDir != "" {
+		cfg.WebhookCertDir = envWebhookCertDir
+	}
+	if envWebhookServiceName := os.Getenv("WEBHOOK_SERVICE_NAME"); envWebhookServiceName != "" {
+		cfg.WebhookServiceName = envWebhookServiceName
+	}
+	if envWebhookNamespace := os.Getenv("WEBHOOK_NAMESPACE"); envWebhookNamespace != "" {
+		cfg.WebhookNamespace = envWebhookNamespace
+	}
+	if envStateAPIEnabled := os.Getenv("STATE_API_ENABLED"); envStateAPIEnabled != "" {
+		cfg.StateAPIEnabled = strings.EqualFold(envStateAPIEnabled, "true")
+	}
+	if envStateAPIPort := os.Getenv("STATE_API_PORT"); envStateAPIPort != "" {
+		stateAPIPort, err := strconv.Atoi(envStateAPIPort)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.StateAPIPort = stateAPIPort
+	}
+	if envStateAPICertDir := os.Getenv("STATE_API_CERT_DIR"); envStateAPICertDir != "" {
+		cfg.StateAPICertDir = envStateAPICertDir
+	}
 }
 
 // SetDefaults sets the default values for configuration
@@ -148,6 +259,27 @@ func (c *Config) SetDefaults() {
 	if c.SyncInterval == 0 {
 		c.SyncInterval = 15 * time.Minute
 	}
+	if c.ExpiryWarning == 0 {
+		c.ExpiryWarning = time.Hour
+	}
+	if c.ReadinessDebounce == 0 {
+		c.ReadinessDebounce = 30 * time.Second
+	}
+	if c.WebhookPort == 0 {
+		c.WebhookPort = 9443
+	}
+	if c.WebhookCertDir == "" {
+		c.WebhookCertDir = "/tmp/k8s-webhook-server/serving-certs"
+	}
+	if c.WebhookServiceName == "" {
+		c.WebhookServiceName = "unifi-port-forward-webhook"
+	}
+	if c.WebhookNamespace == "" {
+		c.WebhookNamespace = "unifi-port-forward"
+	}
+	if c.StateAPIPort == 0 {
+		c.StateAPIPort = 8085
+	}
 }
 
 // Load loads configuration from environment variables and applies defaults
@@ -46,6 +46,27 @@ func TestConfig_Validate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "invalid router IP format",
 		},
+		{
+			name: "router IP with port",
+			config: &Config{
+				RouterIP:     "127.0.0.1:8443",
+				Password:     "password123",
+				Site:         "default",
+				SyncInterval: 15 * time.Minute,
+			},
+			expectError: false,
+		},
+		{
+			name: "router IP with invalid port",
+			config: &Config{
+				RouterIP:     "127.0.0.1:70000",
+				Password:     "password123",
+				Site:         "default",
+				SyncInterval: 15 * time.Minute,
+			},
+			expectError: true,
+			errorMsg:    "invalid router IP format",
+		},
 		{
 			name: "missing both password and API key",
 			config: &Config{
@@ -80,6 +101,45 @@ func TestConfig_Validate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "sync interval cannot happen more often than every five minutes",
 		},
+		{
+			name: "approval without webhook",
+			config: &Config{
+				RouterIP:        "192.168.1.1",
+				Password:        "password123",
+				Site:            "default",
+				SyncInterval:    15 * time.Minute,
+				RequireApproval: true,
+			},
+			expectError: true,
+			errorMsg:    "approval requires the admission webhook to be enabled",
+		},
+		{
+			name: "approval without signing key",
+			config: &Config{
+				RouterIP:           "192.168.1.1",
+				Password:           "password123",
+				Site:               "default",
+				SyncInterval:       15 * time.Minute,
+				RequireApproval:    true,
+				WebhookEnabled:     true,
+				ApprovalSigningKey: "too-short",
+			},
+			expectError: true,
+			errorMsg:    "approval requires a signing key of at least 32 characters",
+		},
+		{
+			name: "approval with webhook",
+			config: &Config{
+				RouterIP:           "192.168.1.1",
+				Password:           "password123",
+				Site:               "default",
+				SyncInterval:       15 * time.Minute,
+				RequireApproval:    true,
+				WebhookEnabled:     true,
+				ApprovalSigningKey: "0123456789abcdef0123456789abcdef",
+			},
+			expectError: false,
+		},
 		{
 			name: "valid with API key instead of password",
 			config: &Config{
@@ -189,6 +249,27 @@ func TestConfig_SetDefaults(t *testing.T) {
 	if config.SyncInterval != 15*time.Minute {
 		t.Errorf("Expected default SyncInterval '15m', got '%v'", config.SyncInterval)
 	}
+	if config.ExpiryWarning != time.Hour {
+		t.Errorf("Expected default ExpiryWarning '1h', got '%v'", config.ExpiryWarning)
+	}
+	if config.ReadinessDebounce != 30*time.Second {
+		t.Errorf("Expected default ReadinessDebounce '30s', got '%v'", config.ReadinessDebounce)
+	}
+	if config.WebhookEnabled {
+		t.Errorf("Expected webhook to be disabled by default")
+	}
+	if config.WebhookPort != 9443 {
+		t.Errorf("Expected default WebhookPort 9443, got %d", config.WebhookPort)
+	}
+	if config.WebhookServiceName != "unifi-port-forward-webhook" {
+		t.Errorf("Expected default WebhookServiceName 'unifi-port-forward-webhook', got '%s'", config.WebhookServiceName)
+	}
+	if config.StateAPIEnabled {
+		t.Errorf("Expected state API to be disabled by default")
+	}
+	if config.StateAPIPort != 8085 {
+		t.Errorf("Expected default StateAPIPort 8085, got %d", config.StateAPIPort)
+	}
 }
 
 func TestConfig_InitFromEnv(t *testing.T) {
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/approval"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/routers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// exposureHash returns a stable hash of the external ports and destinations of configs. An
+// approver approves a forward by setting its approved annotation to this hash, which the
+// admission webhook signs, so changing an external port or destination invalidates the approval.
+func exposureHash(configs []routers.PortConfig) string {
+	entries := make([]string, 0, len(configs))
+	for _, c := range configs {
+		entries = append(entries, fmt.Sprintf("%s|%d|%s|%d", c.Protocol, c.DstPort, c.DstIP, c.FwdPort))
+	}
+	sort.Strings(entries)
+	sum := sha256.Sum256([]byte(strings.Join(entries, ",")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// isApproved reports whether the approved annotation of obj, of kind, holds hash signed with
+// key. An unsigned hash was not set through the admission webhook and approves nothing.
+func isApproved(key []byte, obj metav1.Object, kind, hash string) bool {
+	return approval.Verify(key, kind, obj.GetNamespace(), obj.GetName(), hash, obj.GetAnnotations()[config.ApprovedAnnotation])
+}
+
+// approvalKey returns the key approvals are signed with
+func approvalKey(cfg *config.Config) []byte {
+	if cfg == nil {
+		return nil
+	}
+	return []byte(cfg.ApprovalSigningKey)
+}
+
+// approvalRequired reports whether new forwards are held until they are approved
+func (r *PortForwardRuleReconciler) approvalRequired() bool {
+	return r.Config != nil && r.Config.RequireApproval
+}
+
+// ruleNeedsApproval reports whether the rule is subject to approval. ClusterPortForwardRules
+// can only be created by cluster admins and are never held.
+func ruleNeedsApproval(rule v1alpha1.PortForwardRuleObject) bool {
+	_, namespaced := rule.(*v1alpha1.PortForwardRule)
+	return namespaced
+}
+
+// ruleExposureHash returns the exposure hash of the desired router configuration of a rule.
+// Rules with failover are hashed with all their candidate destinations and rules with cutover
+// with both their Services, so failing or cutting over does not invalidate their approval.
+func ruleExposureHash(rule v1alpha1.PortForwardRuleObject, desired []rulePortConfig) string {
+	configs := make([]routers.PortConfig, 0, len(desired))
+	for _, port := range desired {
+		portConfig := port.Config
+		if failover := rule.GetRuleSpec().Failover; failover != nil {
+			portConfig.DstIP = failoverExposure(failover)
+		}
+		if rule.GetRuleSpec().Cutover != nil {
+			portConfig.DstIP = cutoverExposure(rule)
+		}
+		configs = append(configs, portConfig)
+	}
+	return exposureHash(configs)
+}
+
+// handleApproval holds a rule whose external ports and destinations have not been approved:
+// its router rules are removed and it stays PendingApproval until its approved annotation
+// holds its exposure hash. It reports whether the rule is pending approval.
+func (r *PortForwardRuleReconciler) handleApproval(ctx context.Context, rule v1alpha1.PortForwardRuleObject) (bool, error) {
+	logger := ctrllog.FromContext(ctx)
+
+	if !r.approvalRequired() || !ruleNeedsApproval(rule) {
+		return false, nil
+	}
+
+	desired, err := buildRuleRouterConfigs(ctx, r.Client, rule, r.now())
+	if err != nil {
+		// Nothing is applied either; reconcilePortForwardRule reports the error
+		return false, nil
+	}
+
+	hash := ruleExposureHash(rule, desired)
+	status := rule.GetRuleStatus()
+	status.ExposureHash = hash
+	previous := meta.FindStatusCondition(status.Conditions, ConditionTypeApproved)
+
+	if isApproved(approvalKey(r.Config), rule, "PortForwardRule", hash) {
+		if previous != nil && previous.Status != metav1.ConditionTrue {
+			logger.Info("Rule approved", "exposureHash", hash)
+			r.Recorder.Event(rule, corev1.EventTypeNormal, "Approved", "External ports and destinations approved")
+		}
+		if previous == nil || previous.Status != metav1.ConditionTrue {
+			setRuleCondition(rule, ConditionTypeApproved, metav1.ConditionTrue, "Approved", "External ports and destinations are approved")
+		}
+		return false, nil
+	}
+
+	if err := r.deleteRouterRuleByID(ctx, rule); err != nil {
+		logger.Error(err, "Failed to remove router rule of rule pending approval")
+		return true, err
+	}
+
+	reason := "PendingApproval"
+	message := fmt.Sprintf("Waiting for approval, approve by setting the %s annotation to %s", config.ApprovedAnnotation, hash)
+	if rule.GetAnnotations()[config.ApprovedAnnotation] != "" {
+		reason = "ApprovalInvalidated"
+		message = fmt.Sprintf("External ports or destinations changed since approval, approve by setting the %s annotation to %s",
+			config.ApprovedAnnotation, hash)
+	}
+
+	if previous == nil || previous.Status != metav1.ConditionFalse || previous.Reason != reason || previous.Message != message {
+		logger.Info("Rule pending approval", "reason", reason, "exposureHash", hash)
+		r.Recorder.Event(rule, corev1.EventTypeWarning, "ApprovalRequired", message)
+	}
+
+	r.updateRuleStatusWithMutation(ctx, rule, v1alpha1.PhasePendingApproval, message, func(rule v1alpha1.PortForwardRuleObject) {
+		status := rule.GetRuleStatus()
+		status.ExposureHash = hash
+		status.RouterRuleID = ""
+		status.AppliedConfigHash = ""
+		status.Ports = nil
+		setRuleCondition(rule, ConditionTypeApproved, metav1.ConditionFalse, reason, message)
+	})
+
+	// Setting the approved annotation or changing the spec requeues the rule
+	return true, nil
+}
+
+// approvalRequired reports whether new forwards are held until they are approved
+func (r *PortForwardReconciler) approvalRequired() bool {
+	return r.Config != nil && r.Config.RequireApproval
+}
+
+// applyServiceApproval drops the port configs of a service whose external ports and
+// destinations have not been approved with key, so no router rules exist for it
+func applyServiceApproval(service *corev1.Service, configs []routers.PortConfig, required bool, key []byte) []routers.PortConfig {
+	if !required || len(configs) == 0 || isApproved(key, service, "Service", exposureHash(configs)) {
+		return configs
+	}
+	return nil
+}
+
+// recordServiceApproval records the exposure hash of a service waiting for approval in its
+// pending-approval annotation and emits events when it starts waiting and once it is approved
+func (r *PortForwardReconciler) recordServiceApproval(ctx context.Context, service *corev1.Service) error {
+	var pending string
+	if r.approvalRequired() {
+		// Hash exactly what applyServiceApproval checks
+		configs, err := helpers.BuildPortConfigs(service, helpers.GetLBIP(service), config.FilterAnnotation)
+		if err != nil {
+			return err
+		}
+		configs, err = filterServicePortConfigs(ctx, r.Client, service, configs, r.now())
+		if err != nil {
+			return err
+		}
+		if hash := exposureHash(configs); len(configs) > 0 && !isApproved(approvalKey(r.Config), service, "Service", hash) {
+			pending = hash
+		}
+	}
+
+	previous := service.Annotations[config.PendingApprovalAnnotation]
+	if pending == previous {
+		return nil
+	}
+
+	if r.Recorder != nil {
+		switch {
+		case pending != "":
+			r.Recorder.Event(service, corev1.EventTypeWarning, "ApprovalRequired",
+				fmt.Sprintf("Port forwards wait for approval, approve by setting the %s annotation to %s", config.ApprovedAnnotation, pending))
+		case r.approvalRequired():
+			r.Recorder.Event(service, corev1.EventTypeNormal, "Approved", "Port forwards approved")
+		}
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if pending == "" {
+		delete(service.Annotations, config.PendingApprovalAnnotation)
+	} else {
+		service.Annotations[config.PendingApprovalAnnotation] = pending
+	}
+	if err := r.Patch(ctx, service, patch); err != nil {
+		return fmt.Errorf("failed to record pending approval on service %s/%s: %w", service.Namespace, service.Name, err)
+	}
+	return nil
+}
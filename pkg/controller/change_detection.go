@@ -105,6 +105,33 @@ func analyzeChanges(oldSvc, newSvc *corev1.Service) *ChangeContext {
 			context.OldAnnotation = oldPortAnn
 			context.NewAnnotation = newPortAnn
 		}
+
+		// A changed schedule can enable or disable the port forwards right away
+		if oldAnn[config.ScheduleAnnotation] != newAnn[config.ScheduleAnnotation] ||
+			oldAnn[config.ScheduleTimeZoneAnnotation] != newAnn[config.ScheduleTimeZoneAnnotation] {
+			context.AnnotationChanged = true
+		}
+
+		// Extending an expired service restores its port forwards
+		if oldAnn[config.ExpiresAtAnnotation] != newAnn[config.ExpiresAtAnnotation] ||
+			oldAnn[config.TTLAnnotation] != newAnn[config.TTLAnnotation] {
+			context.AnnotationChanged = true
+		}
+
+		// Approving a service creates its port forwards
+		if oldAnn[config.ApprovedAnnotation] != newAnn[config.ApprovedAnnotation] {
+			context.AnnotationChanged = true
+		}
+
+		// Turning the readiness gate on or off can enable or disable the port forwards
+		if oldAnn[config.ReadinessGateAnnotation] != newAnn[config.ReadinessGateAnnotation] {
+			context.AnnotationChanged = true
+		}
+
+		// Disabling or enabling single ports updates their port forwards
+		if oldAnn[config.DisabledPortsAnnotation] != newAnn[config.DisabledPortsAnnotation] {
+			context.AnnotationChanged = true
+		}
 	}
 
 	// Port spec changes - detect changes in service port specifications
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -35,6 +38,7 @@ type PortForwardEventData struct {
 	Reason           string `json:"reason"`
 	Message          string `json:"message"`
 	Error            string `json:"error,omitempty"`
+	RuleKey          string `json:"rule_key,omitempty"`
 }
 
 type EventPublisher struct {
@@ -162,7 +166,7 @@ func (ep *EventPublisher) PublishPortForwardFailedEvent(ctx context.Context, ser
 	logger.V(1).Info("Published PortForwardFailed event", "port_mapping", portMapping, "reason", reason)
 }
 
-func (ep *EventPublisher) createEvent(ctx context.Context, service *corev1.Service, eventType, message string, eventData *PortForwardEventData) error {
+func (ep *EventPublisher) createEvent(ctx context.Context, object runtime.Object, eventType, message string, eventData *PortForwardEventData) error {
 	logger := ctrllog.FromContext(ctx)
 
 	annotations := map[string]string{}
@@ -180,9 +184,9 @@ func (ep *EventPublisher) createEvent(ctx context.Context, service *corev1.Servi
 
 		// Use annotated event to include metadata
 		if len(annotations) > 0 {
-			ep.recorder.AnnotatedEventf(service, annotations, eventTypeValue, eventType, "%s", message)
+			ep.recorder.AnnotatedEventf(object, annotations, eventTypeValue, eventType, "%s", message)
 		} else {
-			ep.recorder.Eventf(service, eventTypeValue, eventType, "%s", message)
+			ep.recorder.Eventf(object, eventTypeValue, eventType, "%s", message)
 		}
 	} else {
 		logger.Info("Event recorder not available, skipping event publication")
@@ -304,3 +308,71 @@ func (ep *EventPublisher) PublishServicePeriodicReconciliationCompletedEvent(ctx
 			"failed_operations", failedOperations)
 	}
 }
+
+// PublishRuleDriftDetectedEvent publishes an event on a PortForwardRule when its router rule has drifted
+func (ep *EventPublisher) PublishRuleDriftDetectedEvent(ctx context.Context, rule v1alpha1.PortForwardRuleObject, analysis *RuleDriftAnalysis) {
+	logger := ctrllog.FromContext(ctx)
+
+	drift := "missing"
+	if analysis.Current != nil {
+		drift = strings.Join(analysis.Mismatches, ", ")
+	}
+
+	eventData := &PortForwardEventData{
+		RuleKey:      analysis.RuleName,
+		ExternalIP:   analysis.Desired.DstIP,
+		ExternalPort: analysis.Desired.DstPort,
+		Protocol:     analysis.Desired.Protocol,
+		Reason:       "DriftDetected",
+		Message:      fmt.Sprintf("Drift detected - %s", drift),
+	}
+
+	message := fmt.Sprintf("Drift detected for router rule %s (%s) - corrective actions will be taken", analysis.Desired.Name, drift)
+
+	if err := ep.createEvent(ctx, rule, EventDriftDetected, message, eventData); err != nil {
+		logger.Error(err, "Failed to publish DriftDetected event")
+	}
+}
+
+// PublishRuleDriftCorrectedEvent publishes an event on a PortForwardRule when drift was corrected
+func (ep *EventPublisher) PublishRuleDriftCorrectedEvent(ctx context.Context, rule v1alpha1.PortForwardRuleObject, analysis *RuleDriftAnalysis) {
+	logger := ctrllog.FromContext(ctx)
+
+	eventData := &PortForwardEventData{
+		RuleKey:      analysis.RuleName,
+		ExternalIP:   analysis.Desired.DstIP,
+		ExternalPort: analysis.Desired.DstPort,
+		Protocol:     analysis.Desired.Protocol,
+		Reason:       "DriftCorrected",
+		Message:      "Router rule restored to desired configuration",
+	}
+
+	message := fmt.Sprintf("Drift corrected for router rule %s", analysis.Desired.Name)
+
+	if err := ep.createEvent(ctx, rule, EventDriftCorrected, message, eventData); err != nil {
+		logger.Error(err, "Failed to publish DriftCorrected event")
+	}
+}
+
+// PublishRuleDriftCorrectionFailedEvent publishes an event on a PortForwardRule when drift correction fails
+func (ep *EventPublisher) PublishRuleDriftCorrectionFailedEvent(ctx context.Context, rule v1alpha1.PortForwardRuleObject, analysis *RuleDriftAnalysis, err error) {
+	logger := ctrllog.FromContext(ctx)
+
+	eventData := &PortForwardEventData{
+		RuleKey:      analysis.RuleName,
+		ExternalIP:   analysis.Desired.DstIP,
+		ExternalPort: analysis.Desired.DstPort,
+		Protocol:     analysis.Desired.Protocol,
+		Reason:       "DriftCorrectionFailed",
+		Message:      "Failed to correct drift",
+		Error:        err.Error(),
+	}
+
+	message := fmt.Sprintf("Failed to correct drift for router rule %s - %s", analysis.Desired.Name, err.Error())
+
+	if createErr := ep.createEvent(ctx, rule, EventPortForwardFailed, message, eventData); createErr != nil {
+		logger.Error(createErr, "Failed to publish DriftCorrectionFailed event")
+	} else {
+		logger.Info("Published DriftCorrectionFailed event", "portforwardrule", analysis.RuleName, "error", err.Error())
+	}
+}
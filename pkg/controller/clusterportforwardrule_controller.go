@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/health"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/routers"
+	"unifi-port-forward/testutils"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ClusterPortForwardRuleReconciler reconciles ClusterPortForwardRule resources. Applying a
+// rule to the router is shared with PortForwardRuleReconciler.
+type ClusterPortForwardRuleReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Router   routers.Router
+	Config   *config.Config
+	Recorder record.EventRecorder
+
+	// readiness debounces the backend readiness of rules with readinessGate. It lives here since
+	// every reconciliation gets its own PortForwardRuleReconciler.
+	readiness *readinessTracker
+
+	// prober probes the destinations of rules with failover; nil probes over the network
+	prober health.Prober
+
+	// clock evaluates schedules; nil means the system clock
+	clock testutils.TimeProvider
+}
+
+// ruleReconciler returns a PortForwardRuleReconciler sharing this reconciler's clients
+func (r *ClusterPortForwardRuleReconciler) ruleReconciler() *PortForwardRuleReconciler {
+	return &PortForwardRuleReconciler{
+		Client:    r.Client,
+		Scheme:    r.Scheme,
+		Router:    r.Router,
+		Config:    r.Config,
+		Recorder:  r.Recorder,
+		readiness: r.readiness,
+		prober:    r.prober,
+		clock:     r.clock,
+	}
+}
+
+// Reconcile implements the reconciliation logic for ClusterPortForwardRule resources
+func (r *ClusterPortForwardRuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("clusterportforwardrule", req.Name)
+	rules := r.ruleReconciler()
+
+	rule := &v1alpha1.ClusterPortForwardRule{}
+	if err := r.Get(ctx, req.NamespacedName, rule); err != nil {
+		if errors.IsNotFound(err) {
+			logger.V(1).Info("ClusterPortForwardRule not found, likely already deleted")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get ClusterPortForwardRule")
+		return ctrl.Result{}, err
+	}
+
+	if !rule.DeletionTimestamp.IsZero() {
+		return r.handleDeletion(ctx, rule)
+	}
+
+	if !controllerutil.ContainsFinalizer(rule, config.FinalizerLabel) {
+		controllerutil.AddFinalizer(rule, config.FinalizerLabel)
+		if err := r.Update(ctx, rule); err != nil {
+			logger.Error(err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if expired, err := rules.handleExpiry(ctx, rule); expired || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := rules.handleCutover(ctx, rule); err != nil {
+		logger.Error(err, "Failed to check cutover")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.validateRule(ctx, rule); err != nil {
+		logger.Error(err, "Rule validation failed")
+		rules.updateRuleStatusWithRetry(ctx, rule, v1alpha1.PhaseFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if rule.Spec.ServiceRef != nil {
+		reason, message, err := rules.checkReferencedService(ctx, rule)
+		if err != nil {
+			logger.Error(err, "Failed to get referenced Service")
+			return ctrl.Result{}, err
+		}
+		if reason != "" {
+			logger.Info("Referenced Service not ready, rule is pending", "reason", reason, "message", message)
+			rules.updateRuleStatusWithMutation(ctx, rule, v1alpha1.PhasePending, message, func(rule v1alpha1.PortForwardRuleObject) {
+				setRuleCondition(rule, ConditionTypeServiceReady, metav1.ConditionFalse, reason, message)
+			})
+			return rules.scheduleRequeue(rule, ctrl.Result{}), nil
+		}
+
+		if validationErrs := rule.ValidateServiceExists(ctx, r.Client); len(validationErrs) > 0 {
+			err := fmt.Errorf("service validation failed: %v", validationErrs)
+			rules.updateRuleStatusWithRetry(ctx, rule, v1alpha1.PhaseFailed, err.Error())
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := rules.handleReadiness(ctx, rule); err != nil {
+		logger.Error(err, "Failed to check backend readiness")
+		return ctrl.Result{}, err
+	}
+
+	if err := rules.handleFailover(ctx, rule); err != nil {
+		logger.Error(err, "Failed to check destination health")
+		rules.updateRuleStatusWithRetry(ctx, rule, v1alpha1.PhaseFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if err := rules.reconcilePortForwardRule(ctx, rule); err != nil {
+		logger.Info("Port forward reconciliation failed, applying backoff", "error", err.Error())
+		if err == errPortForwardOverlaps {
+			rules.updateRuleStatusWithRetry(ctx, rule, v1alpha1.PhaseFailed, "Port forward overlap conflict")
+			return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+		}
+		rules.updateRuleStatusWithRetry(ctx, rule, v1alpha1.PhaseFailed, err.Error())
+		return ctrl.Result{RequeueAfter: time.Minute * 1}, nil
+	}
+
+	rules.updateRuleStatusWithMutation(ctx, rule, v1alpha1.PhaseActive, "", func(rule v1alpha1.PortForwardRuleObject) {
+		if rule.GetRuleSpec().ServiceRef != nil {
+			setRuleCondition(rule, ConditionTypeServiceReady, metav1.ConditionTrue, "ServiceReady", "Referenced Service has a LoadBalancer IP")
+		}
+	})
+
+	logger.V(1).Info("Successfully reconciled ClusterPortForwardRule")
+	return rules.scheduleRequeue(rule, ctrl.Result{RequeueAfter: time.Minute * 5}), nil
+}
+
+// validateRule validates the ClusterPortForwardRule
+func (r *ClusterPortForwardRuleReconciler) validateRule(ctx context.Context, rule *v1alpha1.ClusterPortForwardRule) error {
+	if err := rule.ValidateCreate(); len(err) > 0 {
+		return fmt.Errorf("validation failed: %v", err)
+	}
+
+	for _, err := range rule.ValidatePortConflict(ctx, r.Client) {
+		if err.Type == field.ErrorTypeForbidden {
+			return fmt.Errorf("port conflict: %s", err.Detail)
+		}
+	}
+
+	return nil
+}
+
+// handleDeletion removes the router rule and then the finalizer of a ClusterPortForwardRule
+func (r *ClusterPortForwardRuleReconciler) handleDeletion(ctx context.Context, rule *v1alpha1.ClusterPortForwardRule) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(rule, config.FinalizerLabel) {
+		return ctrl.Result{}, nil
+	}
+
+	if rule.Status.RouterRuleID != "" || len(rule.Status.Ports) > 0 {
+		if err := r.ruleReconciler().deleteRouterRuleByID(ctx, rule); err != nil {
+			logger.Error(err, "Failed to delete router rule", "routerRuleID", rule.Status.RouterRuleID)
+			// Keep the finalizer so deletion is retried
+			return ctrl.Result{}, fmt.Errorf("router rule deletion failed: %w", err)
+		}
+		logger.Info("Successfully deleted router rule during rule deletion", "routerRuleID", rule.Status.RouterRuleID)
+	}
+
+	controllerutil.RemoveFinalizer(rule, config.FinalizerLabel)
+	if err := r.Update(ctx, rule); err != nil {
+		logger.Error(err, "Failed to remove finalizer")
+		return ctrl.Result{}, err
+	}
+
+	r.Recorder.Event(rule, corev1.EventTypeNormal, "RuleDeleted", "Port forwarding rule removed from router")
+	return ctrl.Result{}, nil
+}
+
+// mapServiceToClusterRules returns reconcile requests for every ClusterPortForwardRule referencing the service
+func (r *ClusterPortForwardRuleReconciler) mapServiceToClusterRules(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := ctrllog.FromContext(ctx)
+
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	var ruleList v1alpha1.ClusterPortForwardRuleList
+	if err := r.List(ctx, &ruleList, client.MatchingFields{ServiceRefIndexKey: key.String()}); err != nil {
+		logger.Error(err, "Failed to list ClusterPortForwardRules referencing service", "service", key)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(ruleList.Items))
+	for _, rule := range ruleList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: rule.Name}})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ClusterPortForwardRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := indexServiceRef(context.Background(), mgr.GetFieldIndexer(), &v1alpha1.ClusterPortForwardRule{}); err != nil {
+		return fmt.Errorf("failed to index ClusterPortForwardRules by serviceRef: %w", err)
+	}
+
+	r.readiness = newReadinessTracker()
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterPortForwardRule{}).
+		Watches(&corev1.Service{}, handler.EnqueueRequestsFromMapFunc(r.mapServiceToClusterRules)).
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(r.mapEndpointSliceToClusterRules)).
+		Complete(r)
+}
+
+// isClusterRuleName reports whether a router rule is named for a ClusterPortForwardRule.
+// Services never take over such rules.
+func isClusterRuleName(name string) bool {
+	return strings.HasPrefix(name, ClusterRuleNamespace+"/")
+}
+
+// applyServiceClusterReservations drops the port configs of a service whose ports are reserved by
+// a ClusterPortForwardRule, and returns why they were dropped
+func applyServiceClusterReservations(ctx context.Context, c client.Client, configs []routers.PortConfig) ([]routers.PortConfig, []string) {
+	var allowed []routers.PortConfig
+	var conflicts []string
+	for _, portConfig := range configs {
+		port := v1alpha1.PortForwardPort{ExternalPort: portConfig.DstPort, Protocol: portConfig.Protocol}
+		if reserved := v1alpha1.ClusterPortConflicts(ctx, c, []v1alpha1.PortForwardPort{port}); len(reserved) > 0 {
+			conflicts = append(conflicts, reserved...)
+			continue
+		}
+		allowed = append(allowed, portConfig)
+	}
+	return allowed, conflicts
+}
+
+// recordServiceClusterReservations records the ports of a service reserved by a
+// ClusterPortForwardRule in its port-conflict annotation and emits events when it starts and
+// stops claiming reserved ports
+func (r *PortForwardReconciler) recordServiceClusterReservations(ctx context.Context, service *corev1.Service) error {
+	var message string
+	configs, err := helpers.BuildPortConfigs(service, helpers.GetLBIP(service), config.FilterAnnotation)
+	if err == nil {
+		_, conflicts := applyServiceClusterReservations(ctx, r.Client, configs)
+		message = strings.Join(conflicts, "; ")
+	}
+
+	previous := service.Annotations[config.PortConflictAnnotation]
+	if message == previous {
+		return nil
+	}
+
+	if r.Recorder != nil {
+		if message != "" {
+			r.Recorder.Event(service, corev1.EventTypeWarning, "PortConflict", message)
+		} else {
+			r.Recorder.Event(service, corev1.EventTypeNormal, "PortConflictResolved", "No port is reserved by a ClusterPortForwardRule")
+		}
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if message == "" {
+		delete(service.Annotations, config.PortConflictAnnotation)
+	} else {
+		service.Annotations[config.PortConflictAnnotation] = message
+	}
+	if err := r.Patch(ctx, service, patch); err != nil {
+		return fmt.Errorf("failed to record port conflict on service %s/%s: %w", service.Namespace, service.Name, err)
+	}
+	return nil
+}
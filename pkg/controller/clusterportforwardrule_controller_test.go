@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/filipowm/go-unifi/unifi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/routers"
+	"unifi-port-forward/testutils"
+)
+
+func TestClusterPortForwardRuleReconciler_ReservesPort(t *testing.T) {
+	ruleController, mockRouter, recorder := newRuleIDTestController(t)
+	ctx := context.Background()
+
+	clusterController := &ClusterPortForwardRuleReconciler{
+		Client:   ruleController.Client,
+		Scheme:   ruleController.Scheme,
+		Router:   mockRouter,
+		Config:   ruleController.Config,
+		Recorder: recorder,
+	}
+
+	clusterRule := &v1alpha1.ClusterPortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "wireguard"},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			ExternalPort:    51820,
+			Protocol:        "udp",
+			Enabled:         true,
+			ConflictPolicy:  "warn",
+			DestinationIP:   stringPtr("192.168.1.10"),
+			DestinationPort: intPtr(51820),
+		},
+	}
+	if err := clusterController.Create(ctx, clusterRule); err != nil {
+		t.Fatalf("Failed to create cluster rule: %v", err)
+	}
+	clusterRequest := ctrl.Request{NamespacedName: types.NamespacedName{Name: "wireguard"}}
+
+	// First pass adds the finalizer, second applies the rule
+	for range 2 {
+		if _, err := clusterController.Reconcile(ctx, clusterRequest); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	routerRule := mockRouter.GetPortForwardRuleByName("_cluster/wireguard:51820")
+	if routerRule == nil {
+		t.Fatalf("Expected router rule _cluster/wireguard:51820, got %+v", mockRouter.GetPortForwardRules())
+	}
+	if err := clusterController.Get(ctx, clusterRequest.NamespacedName, clusterRule); err != nil {
+		t.Fatalf("Failed to get cluster rule: %v", err)
+	}
+	if clusterRule.Status.Phase != v1alpha1.PhaseActive || clusterRule.Status.RouterRuleID != routerRule.ID {
+		t.Errorf("Expected Active status tracking %s, got phase %s and ID %s", routerRule.ID, clusterRule.Status.Phase, clusterRule.Status.RouterRuleID)
+	}
+
+	// A namespaced rule cannot take the reserved port over
+	namespacedRule := newStandaloneRule(51820)
+	namespacedRule.Spec.Protocol = "both"
+	namespacedRule.Spec.ConflictPolicy = "warn"
+	namespacedRule.Finalizers = []string{config.FinalizerLabel}
+	if err := ruleController.Create(ctx, namespacedRule); err != nil {
+		t.Fatalf("Failed to create namespaced rule: %v", err)
+	}
+	namespacedRequest := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test-rule"}}
+	if _, err := ruleController.Reconcile(ctx, namespacedRequest); err == nil || !strings.Contains(err.Error(), "reserved by ClusterPortForwardRule wireguard") {
+		t.Errorf("Expected reserved port error, got %v", err)
+	}
+	if current := mockRouter.GetPortForwardRuleByName("_cluster/wireguard:51820"); current == nil || current.Fwd != "192.168.1.10" {
+		t.Errorf("Expected cluster router rule to be untouched, got %+v", current)
+	}
+
+	// Deleting the cluster rule removes its router rule and finalizer
+	if err := clusterController.Get(ctx, clusterRequest.NamespacedName, clusterRule); err != nil {
+		t.Fatalf("Failed to get cluster rule: %v", err)
+	}
+	clusterRule.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+	if err := clusterController.Update(ctx, clusterRule); err != nil {
+		t.Fatalf("Failed to mark cluster rule deleted: %v", err)
+	}
+	if _, err := clusterController.Reconcile(ctx, clusterRequest); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mockRouter.GetPortForwardRuleByName("_cluster/wireguard:51820") != nil {
+		t.Error("Expected router rule to be removed")
+	}
+	if err := clusterController.Get(ctx, clusterRequest.NamespacedName, clusterRule); err != nil {
+		t.Fatalf("Failed to get cluster rule: %v", err)
+	}
+	if len(clusterRule.Finalizers) != 0 {
+		t.Errorf("Expected finalizer to be removed, got %v", clusterRule.Finalizers)
+	}
+}
+
+func TestMapServiceToClusterRules_UsesServiceRefIndex(t *testing.T) {
+	ruleController, mockRouter, recorder := newRuleIDTestController(t)
+	ctx := context.Background()
+
+	clusterController := &ClusterPortForwardRuleReconciler{
+		Client:   ruleController.Client,
+		Scheme:   ruleController.Scheme,
+		Router:   mockRouter,
+		Config:   ruleController.Config,
+		Recorder: recorder,
+	}
+	if err := indexServiceRef(ctx, ruleController.Client.(client.FieldIndexer), &v1alpha1.ClusterPortForwardRule{}); err != nil {
+		t.Fatalf("Failed to register index: %v", err)
+	}
+
+	for _, name := range []string{"ingress", "unrelated"} {
+		serviceName := "ingress-nginx-controller"
+		if name == "unrelated" {
+			serviceName = "other"
+		}
+		clusterRule := &v1alpha1.ClusterPortForwardRule{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.PortForwardRuleSpec{
+				ExternalPort: 443,
+				ServiceRef: &v1alpha1.ServiceReference{
+					Name:      serviceName,
+					Namespace: stringPtr("ingress-nginx"),
+					Port:      "https",
+				},
+			},
+		}
+		if err := clusterController.Create(ctx, clusterRule); err != nil {
+			t.Fatalf("Failed to create cluster rule: %v", err)
+		}
+	}
+
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "ingress-nginx-controller", Namespace: "ingress-nginx"}}
+	requests := clusterController.mapServiceToClusterRules(ctx, service)
+	if len(requests) != 1 || requests[0].Name != "ingress" || requests[0].Namespace != "" {
+		t.Errorf("Expected a single request for cluster rule ingress, got %v", requests)
+	}
+}
+
+func TestPortForwardReconciler_ServiceCannotTakeOverClusterRule(t *testing.T) {
+	ruleController, mockRouter, recorder := newRuleIDTestController(t)
+	ctx := context.Background()
+
+	clusterController := &ClusterPortForwardRuleReconciler{
+		Client:   ruleController.Client,
+		Scheme:   ruleController.Scheme,
+		Router:   mockRouter,
+		Config:   ruleController.Config,
+		Recorder: recorder,
+	}
+	clusterRule := &v1alpha1.ClusterPortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge"},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			ExternalPort:    8443,
+			Protocol:        "tcp",
+			Enabled:         true,
+			ConflictPolicy:  "warn",
+			DestinationIP:   stringPtr("192.168.1.10"),
+			DestinationPort: intPtr(8443),
+		},
+	}
+	if err := clusterController.Create(ctx, clusterRule); err != nil {
+		t.Fatalf("Failed to create cluster rule: %v", err)
+	}
+	for range 2 {
+		if _, err := clusterController.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "edge"}}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	clusterRouterRule := mockRouter.GetPortForwardRuleByName("_cluster/edge:8443")
+	if clusterRouterRule == nil {
+		t.Fatalf("Expected router rule _cluster/edge:8443, got %+v", mockRouter.GetPortForwardRules())
+	}
+	// Drain the events of the cluster rule
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+
+	serviceController := &PortForwardReconciler{
+		Client:   ruleController.Client,
+		Router:   mockRouter,
+		Scheme:   ruleController.Scheme,
+		Config:   ruleController.Config,
+		Recorder: recorder,
+	}
+	service := testutils.CreateTestMultiPortService("web", "default",
+		[]testutils.TestPort{{Name: "https", Port: 8443, Protocol: corev1.ProtocolTCP}}, "192.168.1.20", "8443:https")
+
+	if err := serviceController.Create(ctx, service); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// The reserved port is refused
+	desired, err := serviceController.calculateDesiredState(ctx, service)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(desired) != 0 {
+		t.Errorf("Expected no port forwards for the reserved port, got %+v", desired)
+	}
+
+	// The conflict is recorded once, however often the service is reconciled
+	for range 2 {
+		if err := serviceController.recordServiceClusterReservations(ctx, service); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if message := service.Annotations[config.PortConflictAnnotation]; !strings.Contains(message, "reserved by ClusterPortForwardRule edge") {
+		t.Errorf("Expected port conflict annotation, got %q", message)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("Expected a single PortConflict event, got %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "PortConflict") || !strings.Contains(event, "reserved by ClusterPortForwardRule edge") {
+		t.Errorf("Expected PortConflict event, got %q", event)
+	}
+
+	// A router rule named for a cluster rule is never taken over, even when its ports match
+	conflicting := routers.PortConfig{
+		Name:     "default/web:8443",
+		DstPort:  8443,
+		FwdPort:  8443,
+		DstIP:    "192.168.1.20",
+		Protocol: "tcp",
+		Enabled:  true,
+	}
+	currentRules := []*unifi.PortForward{clusterRouterRule}
+	if ops := serviceController.detectPortConflicts(currentRules, []routers.PortConfig{conflicting}, service); len(ops) != 0 {
+		t.Errorf("Expected no takeover of the cluster router rule, got %+v", ops)
+	}
+
+	analysis := &DriftAnalysis{ServiceName: "default/web", DesiredRules: []routers.PortConfig{conflicting}}
+	(&DriftDetector{}).findMatchingRulesByPortAndProtocol(analysis, currentRules)
+	if len(analysis.WrongRules) != 0 {
+		t.Errorf("Expected drift detection not to take over the cluster router rule, got %+v", analysis.WrongRules)
+	}
+}
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/health"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// handleCutover records which Service of a rule with cutover the rule forwards to, which
+// serviceRefKey and so buildRuleRouterConfigs follow. When cutover.active selects the other
+// Service, the rule switches once that Service is permitted and has a LoadBalancer IP, so the
+// router rule is updated in place from the old IP to the new one. With a health check the new
+// Service is then probed until it passes the success threshold, or the rule is rolled back to
+// the previous Service once it fails the failure threshold. A rolled back cutover is retried
+// when the spec changes. The cutover status is persisted with the next status update.
+func (r *PortForwardRuleReconciler) handleCutover(ctx context.Context, rule v1alpha1.PortForwardRuleObject) error {
+	logger := ctrllog.FromContext(ctx)
+	spec := rule.GetRuleSpec()
+	status := rule.GetRuleStatus()
+
+	if spec.Cutover == nil || spec.ServiceRef == nil {
+		status.Cutover = nil
+		return nil
+	}
+
+	selected := spec.Cutover.Selected()
+	if status.Cutover == nil {
+		// A new rule forwards to the selected Service right away
+		status.Cutover = &v1alpha1.CutoverStatus{Active: selected, Phase: v1alpha1.CutoverPhaseStable}
+		return nil
+	}
+
+	cutover := status.Cutover
+	now := r.now()
+	rolledBack := cutover.Phase == v1alpha1.CutoverPhaseRolledBack && cutover.RolledBackGeneration == rule.GetGeneration()
+
+	switch {
+	case cutover.Active == selected:
+		if cutover.Phase == v1alpha1.CutoverPhasePending || cutover.Phase == v1alpha1.CutoverPhaseRolledBack {
+			// The selection was reverted before the cutover happened or after it was rolled back
+			cutover.Phase = v1alpha1.CutoverPhaseStable
+			cutover.Message = ""
+		}
+
+	case !rolledBack:
+		from, _ := cutoverServiceKey(rule, cutover.Active)
+		to, _ := cutoverServiceKey(rule, selected)
+
+		reason, err := r.cutoverTargetNotReady(ctx, rule, to)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			if cutover.Phase != v1alpha1.CutoverPhasePending || cutover.Message != reason {
+				logger.Info("Cutover pending", "from", from, "to", to, "reason", reason)
+				r.Recorder.Event(rule, corev1.EventTypeWarning, "CutoverPending",
+					fmt.Sprintf("Cutover from Service %s to %s is pending: %s", from, to, reason))
+			}
+			cutover.Phase = v1alpha1.CutoverPhasePending
+			cutover.Message = reason
+			return nil
+		}
+
+		logger.Info("Cutting over", "from", from, "to", to)
+		*cutover = v1alpha1.CutoverStatus{
+			Active:          selected,
+			Phase:           v1alpha1.CutoverPhaseStable,
+			LastCutoverTime: &metav1.Time{Time: now},
+			Message:         fmt.Sprintf("Cut over from Service %s", from),
+		}
+		if spec.Cutover.HealthCheck != nil {
+			cutover.Phase = v1alpha1.CutoverPhaseVerifying
+		}
+		r.Recorder.Event(rule, corev1.EventTypeNormal, "CutoverStarted",
+			fmt.Sprintf("Router rule switched from Service %s to %s", from, to))
+	}
+
+	if cutover.Phase != v1alpha1.CutoverPhaseVerifying {
+		return nil
+	}
+	return r.verifyCutover(ctx, rule, now)
+}
+
+// verifyCutover probes the Service a rule with cutover switched to, if a probe is due, and
+// completes or rolls back the cutover once the health check reaches a threshold
+func (r *PortForwardRuleReconciler) verifyCutover(ctx context.Context, rule v1alpha1.PortForwardRuleObject, now time.Time) error {
+	logger := ctrllog.FromContext(ctx)
+	spec := rule.GetRuleSpec()
+	cutover := rule.GetRuleStatus().Cutover
+	check := spec.Cutover.HealthCheck
+
+	if cutover.LastProbeTime != nil && now.Before(cutover.LastProbeTime.Add(check.ProbeInterval())) {
+		return nil
+	}
+
+	key, _ := cutoverServiceKey(rule, cutover.Active)
+	probeErr := r.probeCutoverService(ctx, rule, key, check)
+	cutover.LastProbeTime = &metav1.Time{Time: now}
+
+	if probeErr == nil {
+		cutover.ConsecutiveFailures = 0
+		cutover.ConsecutiveSuccesses++
+		if cutover.ConsecutiveSuccesses >= check.Successes() {
+			logger.Info("Cutover verified", "service", key)
+			cutover.Phase = v1alpha1.CutoverPhaseStable
+			cutover.Message = fmt.Sprintf("Service %s passed its health check", key)
+			r.Recorder.Event(rule, corev1.EventTypeNormal, "CutoverSucceeded",
+				fmt.Sprintf("Service %s passed its health check after the cutover", key))
+		}
+		return nil
+	}
+
+	cutover.ConsecutiveSuccesses = 0
+	cutover.ConsecutiveFailures++
+	cutover.Message = probeErr.Error()
+	if cutover.ConsecutiveFailures < check.Failures() {
+		return nil
+	}
+
+	previous := v1alpha1.CutoverBlue
+	if cutover.Active == v1alpha1.CutoverBlue {
+		previous = v1alpha1.CutoverGreen
+	}
+	previousKey, _ := cutoverServiceKey(rule, previous)
+
+	logger.Info("Cutover failed its health check, rolling back", "service", key, "rollbackTo", previousKey, "error", probeErr.Error())
+	*cutover = v1alpha1.CutoverStatus{
+		Active:               previous,
+		Phase:                v1alpha1.CutoverPhaseRolledBack,
+		LastCutoverTime:      &metav1.Time{Time: now},
+		RolledBackGeneration: rule.GetGeneration(),
+		Message:              fmt.Sprintf("Service %s failed its health check: %v", key, probeErr),
+	}
+	r.Recorder.Event(rule, corev1.EventTypeWarning, "CutoverRolledBack",
+		fmt.Sprintf("Service %s failed its health check (%v), router rule switched back to Service %s", key, probeErr, previousKey))
+	return nil
+}
+
+// probeCutoverService probes the LoadBalancer IP of a Service with the health check of a cutover
+func (r *PortForwardRuleReconciler) probeCutoverService(ctx context.Context, rule v1alpha1.PortForwardRuleObject, key types.NamespacedName, check *v1alpha1.HealthCheck) error {
+	var service corev1.Service
+	if err := r.Get(ctx, key, &service); err != nil {
+		return fmt.Errorf("failed to get service %s: %w", key, err)
+	}
+
+	ip := loadBalancerIP(&service)
+	if ip == "" {
+		return fmt.Errorf("service %s has no LoadBalancer IP", key)
+	}
+
+	port := check.Port
+	if port == 0 {
+		target := rule.GetRuleSpec().EffectivePorts()[0].Target()
+		port = servicePortNumber(&service, target)
+		if port == 0 {
+			return fmt.Errorf("port %s not found in service %s", target, key)
+		}
+	}
+
+	return r.healthProber().Probe(ctx, health.Check{
+		Protocol: check.ProbeProtocol(),
+		IP:       ip,
+		Port:     port,
+		Path:     check.ProbePath(),
+		Timeout:  check.ProbeTimeout(),
+	})
+}
+
+// cutoverTargetNotReady explains why a rule cannot cut over to a Service yet. An empty reason
+// means the reference is permitted and the Service has a LoadBalancer IP.
+func (r *PortForwardRuleReconciler) cutoverTargetNotReady(ctx context.Context, rule v1alpha1.PortForwardRuleObject, key types.NamespacedName) (string, error) {
+	// Cluster-scoped rules are admin-owned and may reference any namespace
+	if rule.GetNamespace() != "" && key.Namespace != rule.GetNamespace() {
+		granted, err := v1alpha1.ReferenceGranted(ctx, r.Client, rule.GetNamespace(), key.Namespace, key.Name)
+		if err != nil {
+			return "", err
+		}
+		if !granted {
+			return fmt.Sprintf("reference to Service %s is not permitted by any PortForwardReferenceGrant", key), nil
+		}
+	}
+
+	var service corev1.Service
+	if err := r.Get(ctx, key, &service); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("Service %s not found", key), nil
+		}
+		return "", err
+	}
+
+	if loadBalancerIP(&service) == "" {
+		return fmt.Sprintf("Service %s has no LoadBalancer IP", key), nil
+	}
+	return "", nil
+}
+
+// cutoverServiceKey returns the namespaced name of the blue or green Service of a rule
+func cutoverServiceKey(rule v1alpha1.PortForwardRuleObject, service string) (types.NamespacedName, bool) {
+	return serviceReferenceKey(rule, rule.GetRuleSpec().CutoverServiceRef(service))
+}
+
+// referencedServiceKeys returns the namespaced names of every Service a rule references: its
+// serviceRef and, with cutover, its green Service
+func referencedServiceKeys(rule v1alpha1.PortForwardRuleObject) []types.NamespacedName {
+	var keys []types.NamespacedName
+	if key, ok := cutoverServiceKey(rule, v1alpha1.CutoverBlue); ok {
+		keys = append(keys, key)
+	}
+	if rule.GetRuleSpec().Cutover != nil {
+		if key, ok := cutoverServiceKey(rule, v1alpha1.CutoverGreen); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// nextCutoverProbe returns when the Service a rule cut over to is due for its next verification
+// probe, or the zero time when the cutover is not being verified
+func nextCutoverProbe(rule v1alpha1.PortForwardRuleObject) time.Time {
+	spec := rule.GetRuleSpec()
+	cutover := rule.GetRuleStatus().Cutover
+	if spec.Cutover == nil || spec.Cutover.HealthCheck == nil || cutover == nil ||
+		cutover.Phase != v1alpha1.CutoverPhaseVerifying || cutover.LastProbeTime == nil {
+		return time.Time{}
+	}
+	return cutover.LastProbeTime.Add(spec.Cutover.HealthCheck.ProbeInterval())
+}
+
+// cutoverExposure returns the Services of a rule with cutover as they take part in its exposure
+// hash: both Services, so that cutting over between them does not invalidate an approval
+func cutoverExposure(rule v1alpha1.PortForwardRuleObject) string {
+	var keys []string
+	for _, key := range referencedServiceKeys(rule) {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "+")
+}
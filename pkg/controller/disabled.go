@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/routers"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// applyServiceDisabledPorts disables the port configs of a service whose external ports are
+// listed in its disabled-ports annotation, leaving the rest of its port forwards alone
+func applyServiceDisabledPorts(service *corev1.Service, configs []routers.PortConfig) error {
+	disabled, err := helpers.GetDisabledPorts(service, config.DisabledPortsAnnotation)
+	if err != nil {
+		return err
+	}
+	for i := range configs {
+		if disabled[configs[i].DstPort] {
+			configs[i].Enabled = false
+		}
+	}
+	return nil
+}
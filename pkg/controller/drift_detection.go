@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/filipowm/go-unifi/unifi"
+	"unifi-port-forward/pkg/api/v1alpha1"
 	"unifi-port-forward/pkg/config"
 	"unifi-port-forward/pkg/helpers"
 	"unifi-port-forward/pkg/routers"
@@ -37,10 +39,42 @@ type RuleMismatch struct {
 	MismatchType string // "name", "ip", "port", "protocol", "enabled", "ownership"
 }
 
+// RuleDriftAnalysis contains the analysis of drift for a single port of a PortForwardRule
+type RuleDriftAnalysis struct {
+	RuleName string // namespace/name of the PortForwardRule
+	Rule     v1alpha1.PortForwardRuleObject
+	Port     v1alpha1.PortForwardPort
+	Desired  routers.PortConfig
+	Current  *unifi.PortForward // nil when the router rule is missing
+
+	// Mismatches lists the drifted fields: "name", "ip", "port", "fwdport", "protocol",
+	// "enabled", "source", "interface", "log"
+	Mismatches []string
+	HasDrift   bool
+}
+
+// RequiresRecreate reports whether the drift can only be corrected by deleting
+// and recreating the router rule
+func (a *RuleDriftAnalysis) RequiresRecreate() bool {
+	if a.Current == nil {
+		return false
+	}
+	for _, mismatch := range a.Mismatches {
+		if !isSafeUpdate(mismatch) {
+			return true
+		}
+	}
+	return false
+}
+
 // DriftDetector analyzes drift between desired state and actual router state
 type DriftDetector struct {
 	client.Client
 	Router routers.Router
+
+	// RequireApproval leaves forwards that have not been approved with ApprovalKey off the router
+	RequireApproval bool
+	ApprovalKey     []byte
 }
 
 // AnalyzeAllServicesDrift performs drift analysis for all managed services
@@ -74,7 +108,7 @@ func (d *DriftDetector) analyzeServiceDrift(ctx context.Context, service *corev1
 	}
 
 	// 1. Get desired rules for this service
-	desiredRules, err := d.calculateDesiredRulesForService(service)
+	desiredRules, err := d.calculateDesiredRulesForService(ctx, service)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate desired rules: %w", err)
 	}
@@ -98,7 +132,7 @@ func (d *DriftDetector) analyzeServiceDrift(ctx context.Context, service *corev1
 }
 
 // calculateDesiredRulesForService calculates desired port configurations for a service
-func (d *DriftDetector) calculateDesiredRulesForService(service *corev1.Service) ([]routers.PortConfig, error) {
+func (d *DriftDetector) calculateDesiredRulesForService(ctx context.Context, service *corev1.Service) ([]routers.PortConfig, error) {
 	lbIP := helpers.GetLBIP(service)
 	if lbIP == "" {
 		return nil, fmt.Errorf("service has no LoadBalancer IP")
@@ -109,7 +143,12 @@ func (d *DriftDetector) calculateDesiredRulesForService(service *corev1.Service)
 		return nil, fmt.Errorf("failed to get port configurations: %w", err)
 	}
 
-	return portConfigs, nil
+	portConfigs, err = filterServicePortConfigs(ctx, d.Client, service, portConfigs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return applyServiceApproval(service, portConfigs, d.RequireApproval, d.ApprovalKey), nil
 }
 
 // findMatchingRulesByPortAndProtocol finds router rules that match desired port+protocol
@@ -141,6 +180,11 @@ func (d *DriftDetector) findMatchingRulesByPortAndProtocol(analysis *DriftAnalys
 
 		// First check for exact match (full dstPort+fwdPort+protocol match)
 		if existingRule, exists := exactMatchMap[exactKey]; exists {
+			// Router rules of ClusterPortForwardRules are never taken over
+			if isClusterRuleName(existingRule.Name) {
+				continue
+			}
+
 			// Found exact match - check if we need to take ownership
 			shouldTakeOwnership := false
 			mismatchType := ""
@@ -176,6 +220,10 @@ func (d *DriftDetector) findMatchingRulesByPortAndProtocol(analysis *DriftAnalys
 			// No exact match found - check if there are rules with same dstPort+protocol but different fwdPort
 			if matchingRules, exists := dstPortOnlyMap[dstPortOnlyKey]; exists {
 				for _, existingRule := range matchingRules {
+					if isClusterRuleName(existingRule.Name) {
+						continue
+					}
+
 					// This rule matches dstPort+protocol but has different fwdPort
 					// This is the case where user manually changed FwdPort on router
 					shouldTakeOwnership := false
@@ -262,3 +310,156 @@ func (d *DriftDetector) analyzeDesiredVsCurrent(analysis *DriftAnalysis, process
 		}
 	}
 }
+
+// AnalyzeAllRulesDrift performs drift analysis for every port of the given PortForwardRule
+// resources. Rules whose destination cannot currently be resolved are skipped; the rule
+// controller reports those through the rule status.
+func (d *DriftDetector) AnalyzeAllRulesDrift(ctx context.Context, rules []v1alpha1.PortForwardRuleObject, allRouterRules []*unifi.PortForward) ([]*RuleDriftAnalysis, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("component", "drift-detector")
+
+	var analyses []*RuleDriftAnalysis
+
+	for _, rule := range rules {
+		ruleName := fmt.Sprintf("%s/%s", rule.GetNamespace(), rule.GetName())
+		logger.V(1).Info("Analyzing drift for PortForwardRule", "portforwardrule", ruleName)
+
+		now := time.Now()
+		if expiresAt := ruleExpiry(rule); !expiresAt.IsZero() && !now.Before(expiresAt) {
+			// The rule controller removes the router rules of expired rules
+			logger.V(1).Info("Skipping drift analysis for expired PortForwardRule", "portforwardrule", ruleName)
+			continue
+		}
+
+		desiredPorts, err := buildRuleRouterConfigs(ctx, d.Client, rule, now)
+		if err != nil {
+			logger.V(1).Info("Skipping drift analysis for PortForwardRule",
+				"portforwardrule", ruleName,
+				"reason", err.Error())
+			continue
+		}
+
+		if d.RequireApproval && ruleNeedsApproval(rule) && !isApproved(d.ApprovalKey, rule, "PortForwardRule", ruleExposureHash(rule, desiredPorts)) {
+			// The rule controller keeps rules pending approval off the router
+			logger.V(1).Info("Skipping drift analysis for PortForwardRule pending approval", "portforwardrule", ruleName)
+			continue
+		}
+
+		if namespaced, ok := rule.(*v1alpha1.PortForwardRule); ok {
+			violations, err := helpers.CheckPortForwardPolicies(ctx, d.Client, namespaced.PolicyRequest(), config.FilterAnnotation)
+			if err != nil || len(violations) > 0 {
+				// The rule controller keeps rules violating a policy off the router
+				logger.V(1).Info("Skipping drift analysis for PortForwardRule violating a policy", "portforwardrule", ruleName)
+				continue
+			}
+		}
+
+		previous := previousPortStatuses(rule)
+		for _, desired := range desiredPorts {
+			var routerRuleID string
+			if i := matchPortStatus(previous, desired.Port, len(desiredPorts) == 1); i >= 0 {
+				routerRuleID = previous[i].RouterRuleID
+			}
+
+			analysis := analyzeRuleDrift(ruleName, rule, desired.Config, routerRuleID, allRouterRules)
+			analysis.Port = desired.Port
+			analyses = append(analyses, analysis)
+		}
+	}
+
+	return analyses, nil
+}
+
+// analyzeRuleDrift compares the desired configuration of a port of a PortForwardRule with its
+// router rule: the rule with the ID recorded in status, or else the rule holding its external
+// port. A rule on the same port with the expected name but another protocol is treated as the
+// same rule with a protocol mismatch.
+func analyzeRuleDrift(ruleName string, rule v1alpha1.PortForwardRuleObject, desired routers.PortConfig, routerRuleID string, allRouterRules []*unifi.PortForward) *RuleDriftAnalysis {
+	analysis := &RuleDriftAnalysis{
+		RuleName: ruleName,
+		Rule:     rule,
+		Desired:  desired,
+	}
+
+	// The router ID recorded in status identifies the rule even if its port was changed
+	if isRouterRuleID(routerRuleID) {
+		for _, current := range allRouterRules {
+			if current.ID == routerRuleID {
+				analysis.Current = current
+				break
+			}
+		}
+	}
+
+	var sameName *unifi.PortForward
+	for _, current := range allRouterRules {
+		if analysis.Current != nil {
+			break
+		}
+		if helpers.ParseIntField(current.DstPort) != desired.DstPort {
+			continue
+		}
+		if strings.EqualFold(current.Proto, desired.Protocol) {
+			analysis.Current = current
+			break
+		}
+		if current.Name == desired.Name && sameName == nil {
+			sameName = current
+		}
+	}
+	if analysis.Current == nil {
+		analysis.Current = sameName
+	}
+
+	if analysis.Current == nil {
+		analysis.HasDrift = true
+		return analysis
+	}
+
+	analysis.Mismatches = ruleConfigMismatches(analysis.Current, desired)
+	analysis.HasDrift = len(analysis.Mismatches) > 0
+
+	return analysis
+}
+
+// ruleConfigMismatches returns the fields in which a router rule differs from the desired configuration
+func ruleConfigMismatches(current *unifi.PortForward, desired routers.PortConfig) []string {
+	var mismatches []string
+
+	if current.Name != desired.Name {
+		mismatches = append(mismatches, "name")
+	}
+	if current.Fwd != desired.DstIP {
+		mismatches = append(mismatches, "ip")
+	}
+	if current.DstPort != strconv.Itoa(desired.DstPort) {
+		mismatches = append(mismatches, "port")
+	}
+	if current.FwdPort != strconv.Itoa(desired.FwdPort) {
+		mismatches = append(mismatches, "fwdport")
+	}
+	if !strings.EqualFold(current.Proto, desired.Protocol) {
+		mismatches = append(mismatches, "protocol")
+	}
+	if current.Enabled != desired.Enabled {
+		mismatches = append(mismatches, "enabled")
+	}
+	if normalizeSource(current.Src) != normalizeSource(desired.SrcIP) {
+		mismatches = append(mismatches, "source")
+	}
+	if desired.Interface != "" && current.PfwdInterface != desired.Interface {
+		mismatches = append(mismatches, "interface")
+	}
+	if current.Log != desired.Log {
+		mismatches = append(mismatches, "log")
+	}
+
+	return mismatches
+}
+
+// normalizeSource treats an empty source restriction as "any"
+func normalizeSource(src string) string {
+	if src == "" {
+		return "any"
+	}
+	return src
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/filipowm/go-unifi/unifi"
+	"unifi-port-forward/pkg/api/v1alpha1"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -460,3 +461,172 @@ func createTestServiceWithLB(namespace, name string, annotations map[string]stri
 		},
 	}
 }
+
+func TestDriftDetector_AnalyzeAllRulesDrift(t *testing.T) {
+	destIP := "192.168.1.50"
+	destPort := 8080
+
+	newRule := func(protocol string, logEnabled bool) *v1alpha1.PortForwardRule {
+		return &v1alpha1.PortForwardRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: v1alpha1.PortForwardRuleSpec{
+				ExternalPort:    8443,
+				Protocol:        protocol,
+				DestinationIP:   &destIP,
+				DestinationPort: &destPort,
+				Enabled:         true,
+				Interface:       "wan",
+				LogEnabled:      logEnabled,
+			},
+		}
+	}
+
+	inSync := func() *unifi.PortForward {
+		return &unifi.PortForward{
+			ID:            "rule-1",
+			Name:          "default/web:8443",
+			DstPort:       "8443",
+			FwdPort:       "8080",
+			Fwd:           "192.168.1.50",
+			Proto:         "tcp",
+			Enabled:       true,
+			PfwdInterface: "wan",
+			Src:           "any",
+		}
+	}
+
+	tests := []struct {
+		name             string
+		rule             *v1alpha1.PortForwardRule
+		current          func() *unifi.PortForward
+		expectDrift      bool
+		expectMissing    bool
+		expectMismatches []string
+		expectRecreate   bool
+	}{
+		{
+			name:    "in sync",
+			rule:    newRule("tcp", false),
+			current: inSync,
+		},
+		{
+			name:          "missing router rule",
+			rule:          newRule("tcp", false),
+			current:       func() *unifi.PortForward { return nil },
+			expectDrift:   true,
+			expectMissing: true,
+		},
+		{
+			name: "disabled and log toggled on router",
+			rule: newRule("tcp", true),
+			current: func() *unifi.PortForward {
+				pf := inSync()
+				pf.Enabled = false
+				return pf
+			},
+			expectDrift:      true,
+			expectMismatches: []string{"enabled", "log"},
+		},
+		{
+			name: "source and interface changed on router",
+			rule: newRule("tcp", false),
+			current: func() *unifi.PortForward {
+				pf := inSync()
+				pf.Src = "10.0.0.0/8"
+				pf.PfwdInterface = "wan2"
+				return pf
+			},
+			expectDrift:      true,
+			expectMismatches: []string{"source", "interface"},
+		},
+		{
+			name: "forward port changed on router",
+			rule: newRule("tcp", false),
+			current: func() *unifi.PortForward {
+				pf := inSync()
+				pf.FwdPort = "9090"
+				return pf
+			},
+			expectDrift:      true,
+			expectMismatches: []string{"fwdport"},
+			expectRecreate:   true,
+		},
+		{
+			name: "protocol changed on router",
+			rule: newRule("tcp", false),
+			current: func() *unifi.PortForward {
+				pf := inSync()
+				pf.Proto = "udp"
+				return pf
+			},
+			expectDrift:      true,
+			expectMismatches: []string{"protocol"},
+			expectRecreate:   true,
+		},
+		{
+			name: "both protocol maps to tcp_udp",
+			rule: newRule("both", false),
+			current: func() *unifi.PortForward {
+				pf := inSync()
+				pf.Proto = "tcp_udp"
+				return pf
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var routerRules []*unifi.PortForward
+			if current := tt.current(); current != nil {
+				routerRules = append(routerRules, current)
+			}
+
+			detector := &DriftDetector{}
+			analyses, err := detector.AnalyzeAllRulesDrift(context.Background(), []v1alpha1.PortForwardRuleObject{tt.rule}, routerRules)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(analyses) != 1 {
+				t.Fatalf("Expected 1 analysis, got %d", len(analyses))
+			}
+
+			analysis := analyses[0]
+			if analysis.HasDrift != tt.expectDrift {
+				t.Errorf("Expected HasDrift=%v, got %v (mismatches: %v)", tt.expectDrift, analysis.HasDrift, analysis.Mismatches)
+			}
+			if (analysis.Current == nil) != tt.expectMissing {
+				t.Errorf("Expected missing=%v, got current=%v", tt.expectMissing, analysis.Current)
+			}
+			if strings.Join(analysis.Mismatches, ",") != strings.Join(tt.expectMismatches, ",") {
+				t.Errorf("Expected mismatches %v, got %v", tt.expectMismatches, analysis.Mismatches)
+			}
+			if analysis.RequiresRecreate() != tt.expectRecreate {
+				t.Errorf("Expected RequiresRecreate=%v, got %v", tt.expectRecreate, analysis.RequiresRecreate())
+			}
+		})
+	}
+}
+
+func TestDriftDetector_AnalyzeAllRulesDrift_SkipsUnresolvableRules(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	rule := &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			ExternalPort: 8443,
+			Protocol:     "tcp",
+			ServiceRef:   &v1alpha1.ServiceReference{Name: "missing", Port: "http"},
+			Enabled:      true,
+		},
+	}
+
+	detector := &DriftDetector{Client: env.FakeClient}
+	analyses, err := detector.AnalyzeAllRulesDrift(context.Background(), []v1alpha1.PortForwardRuleObject{rule}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(analyses) != 0 {
+		t.Errorf("Expected rule with unresolvable service to be skipped, got %d analyses", len(analyses))
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/routers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ExpiryStatusExpiringSoon marks a Service whose port forwards expire within the expiry warning
+	ExpiryStatusExpiringSoon = "ExpiringSoon"
+
+	// ExpiryStatusExpired marks a Service whose port forwards have expired and were removed
+	ExpiryStatusExpired = "Expired"
+)
+
+// ruleExpiry returns when the rule expires, or the zero time if it does not
+func ruleExpiry(rule v1alpha1.PortForwardRuleObject) time.Time {
+	return rule.GetRuleSpec().ExpiryTime(rule.GetCreationTimestamp().Time)
+}
+
+// expiryWarning returns how long before expiry a warning is emitted
+func (r *PortForwardRuleReconciler) expiryWarning() time.Duration {
+	if r.Config == nil {
+		return 0
+	}
+	return r.Config.ExpiryWarning
+}
+
+// handleExpiry removes the router rules of a rule whose expiry has passed and marks it
+// Expired, or deletes it when deleteOnExpiry is set. Rules that have not expired get their
+// expiry recorded in status. It reports whether the rule has expired.
+func (r *PortForwardRuleReconciler) handleExpiry(ctx context.Context, rule v1alpha1.PortForwardRuleObject) (bool, error) {
+	logger := ctrllog.FromContext(ctx)
+
+	now := r.now()
+	expiresAt := ruleExpiry(rule)
+	if expiresAt.IsZero() || now.Before(expiresAt) {
+		r.recordExpiryState(rule, expiresAt, now)
+		return false, nil
+	}
+
+	if err := r.deleteRouterRuleByID(ctx, rule); err != nil {
+		logger.Error(err, "Failed to remove router rule of expired rule")
+		return true, err
+	}
+
+	message := fmt.Sprintf("Rule expired at %s", expiresAt.UTC().Format(time.RFC3339))
+	if rule.GetRuleStatus().Phase != v1alpha1.PhaseExpired {
+		logger.Info("Rule expired, router rule removed", "expiresAt", expiresAt)
+		r.Recorder.Event(rule, corev1.EventTypeWarning, "Expired", message+", router rule removed")
+	}
+
+	if rule.GetRuleSpec().DeleteOnExpiry {
+		// The finalizer removes anything left on the router
+		if err := r.Delete(ctx, rule); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete expired rule")
+			return true, err
+		}
+		logger.Info("Deleted expired rule")
+		return true, nil
+	}
+
+	r.updateRuleStatusWithMutation(ctx, rule, v1alpha1.PhaseExpired, message, func(rule v1alpha1.PortForwardRuleObject) {
+		status := rule.GetRuleStatus()
+		status.ExpiresAt = &metav1.Time{Time: expiresAt}
+		status.NextScheduleChange = nil
+		status.RouterRuleID = ""
+		status.AppliedConfigHash = ""
+		status.Ports = nil
+		setRuleCondition(rule, ConditionTypeExpired, metav1.ConditionTrue, "Expired", message)
+	})
+
+	// Extending expiresAt or ttl changes the spec, which requeues the rule
+	return true, nil
+}
+
+// recordExpiryState records the expiry of a rule that has not expired in its status, and
+// emits a warning once it is within the expiry warning
+func (r *PortForwardRuleReconciler) recordExpiryState(rule v1alpha1.PortForwardRuleObject, expiresAt, now time.Time) {
+	status := rule.GetRuleStatus()
+	previous := meta.FindStatusCondition(status.Conditions, ConditionTypeExpired)
+	if expiresAt.IsZero() {
+		status.ExpiresAt = nil
+		meta.RemoveStatusCondition(&status.Conditions, ConditionTypeExpired)
+		if previous != nil && previous.Status == metav1.ConditionTrue {
+			r.Recorder.Event(rule, corev1.EventTypeNormal, "ExpiryExtended", "Expiry removed, rule no longer expires")
+		}
+		return
+	}
+
+	status.ExpiresAt = &metav1.Time{Time: expiresAt}
+
+	reason := "NotExpired"
+	message := fmt.Sprintf("Rule expires at %s", expiresAt.UTC().Format(time.RFC3339))
+	if remaining := expiresAt.Sub(now); remaining <= r.expiryWarning() {
+		reason = ExpiryStatusExpiringSoon
+		if previous == nil || previous.Reason != reason {
+			r.Recorder.Event(rule, corev1.EventTypeWarning, "ExpiringSoon",
+				fmt.Sprintf("%s, in %s; extend it by raising spec.expiresAt or spec.ttl", message, remaining.Round(time.Second)))
+		}
+	}
+
+	if previous != nil && previous.Status == metav1.ConditionTrue {
+		r.Recorder.Event(rule, corev1.EventTypeNormal, "ExpiryExtended", fmt.Sprintf("Expiry extended, %s", message))
+	}
+
+	if previous == nil || previous.Status != metav1.ConditionFalse || previous.Reason != reason || previous.Message != message {
+		setRuleCondition(rule, ConditionTypeExpired, metav1.ConditionFalse, reason, message)
+	}
+}
+
+// serviceExpiry returns when the port forwards of a service expire according to its
+// expires-at or ttl annotation, or the zero time if they do not
+func serviceExpiry(service *corev1.Service) (time.Time, error) {
+	return helpers.GetExpiry(service, config.ExpiresAtAnnotation, config.TTLAnnotation)
+}
+
+// applyServiceExpiry drops the port configs of a service whose expiry has passed, so its
+// router rules are removed
+func applyServiceExpiry(service *corev1.Service, configs []routers.PortConfig, now time.Time) ([]routers.PortConfig, error) {
+	expiresAt, err := serviceExpiry(service)
+	if err != nil {
+		return nil, err
+	}
+	if !expiresAt.IsZero() && !now.Before(expiresAt) {
+		return nil, nil
+	}
+	return configs, nil
+}
+
+// recordServiceExpiry records the expiry state of a service in its expiry-status annotation and
+// emits events when the port forwards are about to expire, have expired or were extended
+func (r *PortForwardReconciler) recordServiceExpiry(ctx context.Context, service *corev1.Service, now time.Time) error {
+	expiresAt, err := serviceExpiry(service)
+	if err != nil {
+		return err
+	}
+
+	var state string
+	if !expiresAt.IsZero() {
+		remaining := expiresAt.Sub(now)
+		switch {
+		case remaining <= 0:
+			state = ExpiryStatusExpired
+		case r.Config != nil && remaining <= r.Config.ExpiryWarning:
+			state = ExpiryStatusExpiringSoon
+		}
+	}
+
+	previous := service.Annotations[config.ExpiryStatusAnnotation]
+	if state == previous {
+		return nil
+	}
+
+	expiry := expiresAt.UTC().Format(time.RFC3339)
+	if r.Recorder != nil {
+		switch {
+		case state == ExpiryStatusExpiringSoon:
+			r.Recorder.Event(service, corev1.EventTypeWarning, "ExpiringSoon",
+				fmt.Sprintf("Port forwards expire at %s; extend them by raising the %s or %s annotation",
+					expiry, config.ExpiresAtAnnotation, config.TTLAnnotation))
+		case state == ExpiryStatusExpired:
+			r.Recorder.Event(service, corev1.EventTypeWarning, "Expired",
+				fmt.Sprintf("Port forwards expired at %s, router rules removed", expiry))
+		case previous == ExpiryStatusExpired:
+			r.Recorder.Event(service, corev1.EventTypeNormal, "ExpiryExtended", "Expiry extended, port forwards restored")
+		}
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if state == "" {
+		delete(service.Annotations, config.ExpiryStatusAnnotation)
+	} else {
+		service.Annotations[config.ExpiryStatusAnnotation] = state
+	}
+	if err := r.Patch(ctx, service, patch); err != nil {
+		return fmt.Errorf("failed to record expiry status on service %s/%s: %w", service.Namespace, service.Name, err)
+	}
+	return nil
+}
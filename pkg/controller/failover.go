@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/health"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionTypeDestinationHealthy reports whether a rule with failover has a healthy destination
+// to forward to
+const ConditionTypeDestinationHealthy = "DestinationHealthy"
+
+// healthProber returns the prober of the reconciler, probing over the network by default
+func (r *PortForwardRuleReconciler) healthProber() health.Prober {
+	if r.prober != nil {
+		return r.prober
+	}
+	return health.NetProber{}
+}
+
+// handleFailover probes the destinations of a rule with failover whose interval has passed and
+// records the active destination buildRuleRouterConfigs forwards to: the healthy destination
+// with the highest priority. When no destination is healthy the active one is kept. Changes of
+// the active destination are recorded in the failover history and as events. The failover
+// status is persisted with the next status update.
+func (r *PortForwardRuleReconciler) handleFailover(ctx context.Context, rule v1alpha1.PortForwardRuleObject) error {
+	logger := ctrllog.FromContext(ctx)
+	spec := rule.GetRuleSpec()
+	status := rule.GetRuleStatus()
+
+	if spec.Failover == nil {
+		status.Failover = nil
+		meta.RemoveStatusCondition(&status.Conditions, ConditionTypeDestinationHealthy)
+		return nil
+	}
+
+	port, err := failoverProbePort(spec)
+	if err != nil {
+		return err
+	}
+
+	if status.Failover == nil {
+		status.Failover = &v1alpha1.FailoverStatus{}
+	}
+	failoverStatus := status.Failover
+	check := &spec.Failover.HealthCheck
+	now := r.now()
+
+	destinations := failoverDestinationHealth(spec.Failover, failoverStatus.Destinations)
+	results := make([]error, len(destinations))
+	due := make([]bool, len(destinations))
+
+	var wg sync.WaitGroup
+	for i := range destinations {
+		if !probeDue(&destinations[i], check, now) {
+			continue
+		}
+		due[i] = true
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = r.healthProber().Probe(ctx, health.Check{
+				Protocol: check.ProbeProtocol(),
+				IP:       destinations[i].IP,
+				Port:     port,
+				Path:     check.ProbePath(),
+				Timeout:  check.ProbeTimeout(),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range destinations {
+		if due[i] {
+			recordProbe(&destinations[i], results[i], check, now)
+		}
+	}
+	failoverStatus.Destinations = destinations
+
+	previous := failoverStatus.ActiveDestination
+	active, anyHealthy := selectDestination(spec.Failover, destinations, previous)
+	if active != previous {
+		failoverStatus.ActiveDestination = active
+		if previous != "" {
+			reason := failoverReason(spec.Failover, destinations, previous)
+			logger.Info("Active destination changed", "from", previous, "to", active, "reason", reason)
+			recordFailoverEvent(failoverStatus, v1alpha1.FailoverEvent{
+				Time:   metav1.Time{Time: now},
+				From:   previous,
+				To:     active,
+				Reason: reason,
+			})
+			if reason == v1alpha1.FailoverReasonFailback {
+				r.Recorder.Event(rule, corev1.EventTypeNormal, "DestinationFailback",
+					fmt.Sprintf("Destination %s is healthy again, forwarding to it instead of %s", active, previous))
+			} else {
+				r.Recorder.Event(rule, corev1.EventTypeWarning, "DestinationFailover",
+					fmt.Sprintf("Destination %s is %s, forwarding to %s", previous, failoverReasonMessage(reason), active))
+			}
+		}
+	}
+
+	condition := meta.FindStatusCondition(status.Conditions, ConditionTypeDestinationHealthy)
+	if anyHealthy {
+		message := fmt.Sprintf("Forwarding to healthy destination %s", active)
+		if condition == nil || condition.Status != metav1.ConditionTrue || condition.Message != message {
+			setRuleCondition(rule, ConditionTypeDestinationHealthy, metav1.ConditionTrue, "DestinationHealthy", message)
+		}
+		return nil
+	}
+
+	message := fmt.Sprintf("No destination passes its health check, keeping %s", active)
+	if condition == nil || condition.Status != metav1.ConditionFalse {
+		r.Recorder.Event(rule, corev1.EventTypeWarning, "NoHealthyDestination", message)
+	}
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Message != message {
+		setRuleCondition(rule, ConditionTypeDestinationHealthy, metav1.ConditionFalse, "NoHealthyDestination", message)
+	}
+	return nil
+}
+
+// failoverProbePort returns the port health checks probe: the configured one, or else the
+// destination port of the rule's first port
+func failoverProbePort(spec *v1alpha1.PortForwardRuleSpec) (int, error) {
+	if spec.Failover.HealthCheck.Port != 0 {
+		return spec.Failover.HealthCheck.Port, nil
+	}
+
+	port := spec.EffectivePorts()[0]
+	target, err := strconv.Atoi(port.Target())
+	if err != nil {
+		return 0, fmt.Errorf("invalid rule: target port %q of port %d is not a number", port.Target(), port.ExternalPort)
+	}
+	return target, nil
+}
+
+// failoverDestinationHealth returns the recorded health of every destination in the spec, in
+// spec order. Destinations that were never probed start unhealthy and without a probe time.
+func failoverDestinationHealth(failover *v1alpha1.DestinationFailover, recorded []v1alpha1.DestinationHealth) []v1alpha1.DestinationHealth {
+	byIP := make(map[string]v1alpha1.DestinationHealth, len(recorded))
+	for _, destination := range recorded {
+		byIP[destination.IP] = destination
+	}
+
+	destinations := make([]v1alpha1.DestinationHealth, 0, len(failover.Destinations))
+	for _, destination := range failover.Destinations {
+		entry, exists := byIP[destination.IP]
+		if !exists {
+			entry = v1alpha1.DestinationHealth{IP: destination.IP}
+		}
+		destinations = append(destinations, entry)
+	}
+	return destinations
+}
+
+// probeDue reports whether a destination is due for its next probe at now
+func probeDue(destination *v1alpha1.DestinationHealth, check *v1alpha1.HealthCheck, now time.Time) bool {
+	if destination.LastProbeTime == nil {
+		return true
+	}
+	return !now.Before(destination.LastProbeTime.Add(check.ProbeInterval()))
+}
+
+// recordProbe records the result of a probe. The first probe of a destination sets its health
+// right away; after that the health only changes once the failure or success threshold of the
+// health check is reached.
+func recordProbe(destination *v1alpha1.DestinationHealth, err error, check *v1alpha1.HealthCheck, now time.Time) {
+	first := destination.LastProbeTime == nil
+	destination.LastProbeTime = &metav1.Time{Time: now}
+
+	if err == nil {
+		destination.ConsecutiveFailures = 0
+		destination.ConsecutiveSuccesses++
+		destination.Message = ""
+		if first || destination.ConsecutiveSuccesses >= check.Successes() {
+			destination.Healthy = true
+		}
+		return
+	}
+
+	destination.ConsecutiveSuccesses = 0
+	destination.ConsecutiveFailures++
+	destination.Message = err.Error()
+	if first || destination.ConsecutiveFailures >= check.Failures() {
+		destination.Healthy = false
+	}
+}
+
+// selectDestination returns the destination to forward to and whether any destination is
+// healthy: the most preferred healthy destination, or else the current destination while it is
+// still in the spec, or else the most preferred destination
+func selectDestination(failover *v1alpha1.DestinationFailover, destinations []v1alpha1.DestinationHealth, current string) (string, bool) {
+	healthy := make(map[string]bool, len(destinations))
+	for _, destination := range destinations {
+		healthy[destination.IP] = destination.Healthy
+	}
+
+	preferred := failover.Preferred()
+	for _, destination := range preferred {
+		if healthy[destination.IP] {
+			return destination.IP, true
+		}
+	}
+
+	if _, exists := healthy[current]; exists || len(preferred) == 0 {
+		return current, false
+	}
+	return preferred[0].IP, false
+}
+
+// failoverReason returns why the active destination moved away from previous
+func failoverReason(failover *v1alpha1.DestinationFailover, destinations []v1alpha1.DestinationHealth, previous string) string {
+	for _, destination := range destinations {
+		if destination.IP != previous {
+			continue
+		}
+		if destination.Healthy {
+			return v1alpha1.FailoverReasonFailback
+		}
+		return v1alpha1.FailoverReasonFailover
+	}
+	return v1alpha1.FailoverReasonDestinationRemoved
+}
+
+// failoverReasonMessage describes what happened to the previous destination for a reason
+func failoverReasonMessage(reason string) string {
+	if reason == v1alpha1.FailoverReasonDestinationRemoved {
+		return "no longer a destination"
+	}
+	return "unhealthy"
+}
+
+// recordFailoverEvent prepends event to the failover history, keeping the latest events
+func recordFailoverEvent(status *v1alpha1.FailoverStatus, event v1alpha1.FailoverEvent) {
+	status.History = append([]v1alpha1.FailoverEvent{event}, status.History...)
+	if len(status.History) > v1alpha1.MaxFailoverHistory {
+		status.History = status.History[:v1alpha1.MaxFailoverHistory]
+	}
+}
+
+// nextFailoverProbe returns when the next destination of a rule with failover is due for a
+// probe, or the zero time for rules without failover
+func nextFailoverProbe(rule v1alpha1.PortForwardRuleObject) time.Time {
+	spec := rule.GetRuleSpec()
+	status := rule.GetRuleStatus()
+	if spec.Failover == nil || status.Failover == nil {
+		return time.Time{}
+	}
+
+	var next time.Time
+	for _, destination := range status.Failover.Destinations {
+		if destination.LastProbeTime == nil {
+			continue
+		}
+		at := destination.LastProbeTime.Add(spec.Failover.HealthCheck.ProbeInterval())
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	return next
+}
+
+// failoverDestination returns the destination a rule with failover forwards to: the active
+// destination recorded by handleFailover, or the most preferred destination before the first
+// probe
+func failoverDestination(rule v1alpha1.PortForwardRuleObject) string {
+	failover := rule.GetRuleSpec().Failover
+	if status := rule.GetRuleStatus().Failover; status != nil && status.ActiveDestination != "" {
+		for _, destination := range failover.Destinations {
+			if destination.IP == status.ActiveDestination {
+				return status.ActiveDestination
+			}
+		}
+	}
+	if len(failover.Destinations) == 0 {
+		return ""
+	}
+	return failover.Preferred()[0].IP
+}
+
+// failoverExposure returns the destinations of a rule with failover as they take part in its
+// exposure hash: every candidate, so that moving between them does not invalidate an approval
+func failoverExposure(failover *v1alpha1.DestinationFailover) string {
+	ips := failover.IPs()
+	sort.Strings(ips)
+	return strings.Join(ips, "+")
+}
@@ -0,0 +1,270 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/routers"
+
+	"github.com/filipowm/go-unifi/unifi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImportFilter selects the router rules to import. Empty fields match every rule.
+type ImportFilter struct {
+	// Ports are the external ports to import
+	Ports []int
+
+	// Name matches the router rule name
+	Name *regexp.Regexp
+
+	// Subnet contains the destination IP
+	Subnet *net.IPNet
+}
+
+// matches reports whether a router rule passes the filter
+func (f ImportFilter) matches(rule *unifi.PortForward) bool {
+	if len(f.Ports) > 0 {
+		port := helpers.ParseIntField(rule.DstPort)
+		found := false
+		for _, p := range f.Ports {
+			if p == port {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Name != nil && !f.Name.MatchString(rule.Name) {
+		return false
+	}
+	if f.Subnet != nil {
+		ip := net.ParseIP(rule.Fwd)
+		if ip == nil || !f.Subnet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportedRule is a router rule mapped to a PortForwardRule
+type ImportedRule struct {
+	RouterRule *unifi.PortForward
+	Rule       *v1alpha1.PortForwardRule
+
+	// RouterName is the name the controller gives the router rule once it owns it
+	RouterName string
+}
+
+// AdoptedConfig returns the configuration of the router rule renamed to RouterName, which lets
+// the controller adopt it in place
+func (i ImportedRule) AdoptedConfig() routers.PortConfig {
+	config := routerRuleConfig(i.RouterRule)
+	config.Name = i.RouterName
+	config.FwdPort = routerForwardPort(i.RouterRule)
+	return config
+}
+
+// SkippedRouterRule is a router rule that matched the filter but cannot be imported
+type SkippedRouterRule struct {
+	RouterRule *unifi.PortForward
+	Reason     string
+}
+
+// ImportRouterRules maps the router rules passing filter to standalone PortForwardRules in
+// namespace. A rule forwarding to the LoadBalancer IP and a port of one of services is mapped
+// to a serviceRef in the namespace of that Service instead. Rules already managed by the
+// controller are skipped. Rule names are derived from the router rule names and made unique
+// among themselves, the existing rules and the services, whose router rules share the naming
+// scheme.
+func ImportRouterRules(routerRules []*unifi.PortForward, services []corev1.Service, existing []v1alpha1.PortForwardRule, namespace string, filter ImportFilter) ([]ImportedRule, []SkippedRouterRule) {
+	var imported []ImportedRule
+	var skipped []SkippedRouterRule
+
+	taken := make(map[string]bool)
+	for _, service := range services {
+		taken[service.Namespace+"/"+service.Name] = true
+	}
+	for _, rule := range existing {
+		taken[rule.Namespace+"/"+rule.Name] = true
+	}
+
+	for _, routerRule := range routerRules {
+		if !filter.matches(routerRule) {
+			continue
+		}
+		if helpers.IsManagedRule(routerRule.Name) {
+			skipped = append(skipped, SkippedRouterRule{RouterRule: routerRule, Reason: "already managed by the controller"})
+			continue
+		}
+
+		rule, err := importedRule(routerRule, services, namespace)
+		if err != nil {
+			skipped = append(skipped, SkippedRouterRule{RouterRule: routerRule, Reason: err.Error()})
+			continue
+		}
+
+		rule.Name = uniqueRuleName(importedRuleName(routerRule), routerRule, rule.Namespace, taken)
+		taken[rule.Namespace+"/"+rule.Name] = true
+
+		imported = append(imported, ImportedRule{
+			RouterRule: routerRule,
+			Rule:       rule,
+			RouterName: ruleRouterName(rule, rule.Spec.ExternalPort),
+		})
+	}
+
+	return imported, skipped
+}
+
+// importedRule maps a router rule to a PortForwardRule without a name
+func importedRule(routerRule *unifi.PortForward, services []corev1.Service, namespace string) (*v1alpha1.PortForwardRule, error) {
+	externalPort := helpers.ParseIntField(routerRule.DstPort)
+	if externalPort < 1 || externalPort > 65535 {
+		return nil, fmt.Errorf("external port %q is not a single port", routerRule.DstPort)
+	}
+	forwardPort := routerForwardPort(routerRule)
+	if forwardPort < 1 || forwardPort > 65535 {
+		return nil, fmt.Errorf("forward port %q is not a single port", routerRule.FwdPort)
+	}
+
+	protocol := strings.ToLower(routerRule.Proto)
+	switch protocol {
+	case "tcp", "udp":
+	case "tcp_udp":
+		protocol = "both"
+	default:
+		return nil, fmt.Errorf("protocol %q is not supported", routerRule.Proto)
+	}
+
+	if net.ParseIP(routerRule.Fwd).To4() == nil {
+		return nil, fmt.Errorf("destination %q is not an IPv4 address", routerRule.Fwd)
+	}
+
+	spec := v1alpha1.PortForwardRuleSpec{
+		ExternalPort: externalPort,
+		Protocol:     protocol,
+		Enabled:      routerRule.Enabled,
+		Interface:    routerRule.PfwdInterface,
+		LogEnabled:   routerRule.Log,
+		Description:  importedDescription(routerRule),
+	}
+
+	if src := routerRule.Src; src != "" && src != "any" {
+		if net.ParseIP(src).To4() == nil {
+			return nil, fmt.Errorf("source restriction %q is not a single IPv4 address", src)
+		}
+		spec.SourceIPRestriction = &src
+	}
+
+	if service, port := matchImportService(services, routerRule.Fwd, forwardPort); service != nil {
+		namespace = service.Namespace
+		spec.ServiceRef = &v1alpha1.ServiceReference{Name: service.Name, Port: port}
+	} else {
+		destinationIP := routerRule.Fwd
+		spec.DestinationIP = &destinationIP
+		spec.DestinationPort = &forwardPort
+	}
+
+	return &v1alpha1.PortForwardRule{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "PortForwardRule",
+		},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		Spec:       spec,
+	}, nil
+}
+
+// matchImportService returns the first LoadBalancer Service, by namespace and name, with the
+// given IP and a port with the given number, and the name or number of that port
+func matchImportService(services []corev1.Service, ip string, port int) (*corev1.Service, string) {
+	candidates := make([]*corev1.Service, 0, len(services))
+	for i := range services {
+		if loadBalancerIP(&services[i]) == ip {
+			candidates = append(candidates, &services[i])
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Namespace != candidates[j].Namespace {
+			return candidates[i].Namespace < candidates[j].Namespace
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	for _, service := range candidates {
+		for _, servicePort := range service.Spec.Ports {
+			if int(servicePort.Port) != port {
+				continue
+			}
+			if servicePort.Name != "" {
+				return service, servicePort.Name
+			}
+			return service, strconv.Itoa(port)
+		}
+	}
+	return nil, ""
+}
+
+// routerForwardPort returns the forward port of a router rule; an empty forward port forwards
+// to the external port
+func routerForwardPort(rule *unifi.PortForward) int {
+	if rule.FwdPort == "" {
+		return helpers.ParseIntField(rule.DstPort)
+	}
+	return helpers.ParseIntField(rule.FwdPort)
+}
+
+// importedDescription records the original name of an imported router rule
+func importedDescription(rule *unifi.PortForward) string {
+	description := fmt.Sprintf("Imported from router rule %q", rule.Name)
+	if len(description) > 256 {
+		description = description[:256]
+	}
+	return description
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// importedRuleName derives a DNS-1123 label from a router rule name
+func importedRuleName(rule *unifi.PortForward) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(rule.Name), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > 50 {
+		name = strings.Trim(name[:50], "-")
+	}
+	if name == "" {
+		name = "port-" + strconv.Itoa(helpers.ParseIntField(rule.DstPort))
+	}
+	return name
+}
+
+// uniqueRuleName returns name, or name with the port and protocol of the router rule appended
+// when it is taken in namespace
+func uniqueRuleName(name string, rule *unifi.PortForward, namespace string, taken map[string]bool) string {
+	candidates := []string{
+		name,
+		fmt.Sprintf("%s-%d", name, helpers.ParseIntField(rule.DstPort)),
+		fmt.Sprintf("%s-%d-%s", name, helpers.ParseIntField(rule.DstPort), strings.ReplaceAll(strings.ToLower(rule.Proto), "_", "-")),
+	}
+	for _, candidate := range candidates {
+		if !taken[namespace+"/"+candidate] {
+			return candidate
+		}
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", candidates[2], i)
+		if !taken[namespace+"/"+candidate] {
+			return candidate
+		}
+	}
+}
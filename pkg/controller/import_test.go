@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"net"
+	"regexp"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+
+	"github.com/filipowm/go-unifi/unifi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImportRouterRules(t *testing.T) {
+	services := []corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Name: "https", Port: 443}},
+			},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "192.168.1.100"}},
+			}},
+		},
+	}
+	existing := []v1alpha1.PortForwardRule{
+		{ObjectMeta: metav1.ObjectMeta{Name: "minecraft", Namespace: "imported"}},
+	}
+
+	routerRules := []*unifi.PortForward{
+		{ID: "1", Name: "Web HTTPS", DstPort: "443", FwdPort: "443", Fwd: "192.168.1.100", Proto: "tcp", Enabled: true, PfwdInterface: "wan", Src: "any"},
+		{ID: "2", Name: "Minecraft", DstPort: "25565", FwdPort: "", Fwd: "192.168.1.20", Proto: "tcp_udp", Enabled: false, PfwdInterface: "wan"},
+		{ID: "3", Name: "Game range", DstPort: "27015-27030", FwdPort: "27015-27030", Fwd: "192.168.1.21", Proto: "udp", Enabled: true},
+		{ID: "4", Name: "default/managed:http", DstPort: "8080", FwdPort: "8080", Fwd: "192.168.1.100", Proto: "tcp", Enabled: true},
+		{ID: "5", Name: "Printer", DstPort: "631", FwdPort: "631", Fwd: "10.0.0.5", Proto: "tcp", Enabled: true},
+	}
+
+	_, subnet, _ := net.ParseCIDR("192.168.1.0/24")
+	imported, skipped := ImportRouterRules(routerRules, services, existing, "imported", ImportFilter{Subnet: subnet})
+
+	if len(imported) != 2 {
+		t.Fatalf("ImportRouterRules() imported %d rules, want 2", len(imported))
+	}
+
+	web := imported[0]
+	if web.Rule.Namespace != "apps" || web.Rule.Name != "web-https" {
+		t.Errorf("service rule = %s/%s, want apps/web-https", web.Rule.Namespace, web.Rule.Name)
+	}
+	if ref := web.Rule.Spec.ServiceRef; ref == nil || ref.Name != "web" || ref.Port != "https" {
+		t.Errorf("service rule serviceRef = %+v, want web:https", ref)
+	}
+	if web.Rule.Spec.DestinationIP != nil || web.Rule.Spec.SourceIPRestriction != nil {
+		t.Errorf("service rule spec = %+v, want no destinationIP and no source restriction", web.Rule.Spec)
+	}
+	if web.RouterName != "apps/web-https:443" {
+		t.Errorf("RouterName = %q, want apps/web-https:443", web.RouterName)
+	}
+	if config := web.AdoptedConfig(); config.Name != web.RouterName || config.DstIP != "192.168.1.100" || config.FwdPort != 443 {
+		t.Errorf("AdoptedConfig() = %+v", config)
+	}
+
+	minecraft := imported[1]
+	if minecraft.Rule.Namespace != "imported" || minecraft.Rule.Name != "minecraft-25565" {
+		t.Errorf("standalone rule = %s/%s, want imported/minecraft-25565 next to the existing rule", minecraft.Rule.Namespace, minecraft.Rule.Name)
+	}
+	spec := minecraft.Rule.Spec
+	if spec.Protocol != "both" || spec.Enabled || spec.DestinationIP == nil || *spec.DestinationIP != "192.168.1.20" ||
+		spec.DestinationPort == nil || *spec.DestinationPort != 25565 {
+		t.Errorf("standalone rule spec = %+v", spec)
+	}
+
+	if len(skipped) != 2 {
+		t.Fatalf("ImportRouterRules() skipped %d rules, want the port range and the managed rule", len(skipped))
+	}
+	if skipped[0].RouterRule.ID != "3" || skipped[1].RouterRule.ID != "4" {
+		t.Errorf("skipped = %s (%s), %s (%s)", skipped[0].RouterRule.ID, skipped[0].Reason, skipped[1].RouterRule.ID, skipped[1].Reason)
+	}
+}
+
+func TestImportFilter(t *testing.T) {
+	rule := &unifi.PortForward{Name: "SSH bastion", DstPort: "2222", Fwd: "192.168.1.10"}
+	_, lan, _ := net.ParseCIDR("192.168.1.0/24")
+	_, other, _ := net.ParseCIDR("10.0.0.0/8")
+
+	tests := []struct {
+		name   string
+		filter ImportFilter
+		want   bool
+	}{
+		{name: "empty", filter: ImportFilter{}, want: true},
+		{name: "port", filter: ImportFilter{Ports: []int{22, 2222}}, want: true},
+		{name: "other port", filter: ImportFilter{Ports: []int{22}}, want: false},
+		{name: "name", filter: ImportFilter{Name: regexp.MustCompile(`(?i)^ssh`)}, want: true},
+		{name: "other name", filter: ImportFilter{Name: regexp.MustCompile(`^web`)}, want: false},
+		{name: "subnet", filter: ImportFilter{Subnet: lan}, want: true},
+		{name: "other subnet", filter: ImportFilter{Subnet: other}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(rule); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,14 +3,19 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"unifi-port-forward/pkg/api/v1alpha1"
 	"unifi-port-forward/pkg/config"
 	"unifi-port-forward/pkg/helpers"
 	"unifi-port-forward/pkg/routers"
 
+	"github.com/filipowm/go-unifi/unifi"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -18,13 +23,16 @@ import (
 )
 
 // PeriodicReconciler handles periodic full reconciliation to detect and correct drift
-// between Kubernetes Service state and UniFi router port forwarding rules.
+// between Kubernetes Service and PortForwardRule state and UniFi router port forwarding rules.
 type PeriodicReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Router routers.Router
 	Config *config.Config
 
+	// PortForwardRulesEnabled extends drift correction to PortForwardRule resources
+	PortForwardRulesEnabled bool
+
 	// Periodic reconciliation specific
 	ticker         *time.Ticker
 	stopCh         chan struct{}
@@ -126,7 +134,12 @@ func (r *PeriodicReconciler) performFullReconciliation(ctx context.Context, star
 	}
 	logger.V(1).Info("Retrieved managed services", "count", len(managedServices))
 
-	driftDetector := &DriftDetector{Client: r.Client, Router: r.Router}
+	driftDetector := &DriftDetector{
+		Client:          r.Client,
+		Router:          r.Router,
+		RequireApproval: r.Config != nil && r.Config.RequireApproval,
+		ApprovalKey:     approvalKey(r.Config),
+	}
 	driftAnalyses, err := driftDetector.AnalyzeAllServicesDrift(ctx, managedServices, allRouterRules)
 	if err != nil {
 		return fmt.Errorf("failed to analyze drift: %w", err)
@@ -180,10 +193,35 @@ func (r *PeriodicReconciler) performFullReconciliation(ctx context.Context, star
 		}
 	}
 
+	// Service corrections may have changed the router, so work from a fresh listing
+	if servicesWithDrift > 0 {
+		allRouterRules, err = r.Router.ListAllPortForwards(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list router rules: %w", err)
+		}
+	}
+
+	serviceReconciler := r.newServiceReconciler()
+	for _, service := range managedServices {
+		if err := serviceReconciler.recordServiceRuleIDs(ctx, service, allRouterRules); err != nil {
+			logger.Error(err, "Failed to record router rule IDs on service",
+				"service", fmt.Sprintf("%s/%s", service.Namespace, service.Name))
+		}
+	}
+
+	rulesWithDrift := 0
+	if r.PortForwardRulesEnabled {
+		rulesWithDrift, err = r.reconcilePortForwardRulesDrift(ctx, driftDetector, allRouterRules)
+		if err != nil {
+			return err
+		}
+	}
+
 	duration := time.Since(startTime)
 	logger.Info("Synced router and control plane state",
 		"total_services", len(managedServices),
 		"services_with_drift", servicesWithDrift,
+		"portforwardrules_with_drift", rulesWithDrift,
 		"corrected_rules", correctedRules,
 		"failed_operations", failedOperations,
 		"duration", duration.String())
@@ -191,6 +229,238 @@ func (r *PeriodicReconciler) performFullReconciliation(ctx context.Context, star
 	return nil
 }
 
+// reconcilePortForwardRulesDrift detects and corrects drift for PortForwardRule resources.
+// It returns the number of rules that had drift.
+func (r *PeriodicReconciler) reconcilePortForwardRulesDrift(ctx context.Context, driftDetector *DriftDetector, allRouterRules []*unifi.PortForward) (int, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("component", "periodic-reconciler")
+
+	rules, err := r.getAllPortForwardRules(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get port forward rules: %w", err)
+	}
+	logger.V(1).Info("Retrieved port forward rules", "count", len(rules))
+
+	analyses, err := driftDetector.AnalyzeAllRulesDrift(ctx, rules, allRouterRules)
+	if err != nil {
+		return 0, fmt.Errorf("failed to analyze port forward rule drift: %w", err)
+	}
+
+	rulesWithDrift := 0
+	for _, analysis := range analyses {
+		if !analysis.HasDrift {
+			continue
+		}
+		rulesWithDrift++
+
+		logger.Info("Drift detected for PortForwardRule",
+			"portforwardrule", analysis.RuleName,
+			"missing", analysis.Current == nil,
+			"mismatches", analysis.Mismatches)
+
+		if r.eventPublisher != nil {
+			r.eventPublisher.PublishRuleDriftDetectedEvent(ctx, analysis.Rule, analysis)
+		}
+
+		correctionErr := r.correctRuleDrift(ctx, analysis)
+		if correctionErr != nil {
+			logger.Error(correctionErr, "Failed to correct drift for PortForwardRule", "portforwardrule", analysis.RuleName)
+			if r.eventPublisher != nil {
+				r.eventPublisher.PublishRuleDriftCorrectionFailedEvent(ctx, analysis.Rule, analysis, correctionErr)
+			}
+		} else if r.eventPublisher != nil {
+			r.eventPublisher.PublishRuleDriftCorrectedEvent(ctx, analysis.Rule, analysis)
+		}
+
+		r.updateRuleDriftStatus(ctx, analysis, correctionErr)
+	}
+
+	return rulesWithDrift, nil
+}
+
+// correctRuleDrift applies corrections for a PortForwardRule that has drift
+func (r *PeriodicReconciler) correctRuleDrift(ctx context.Context, analysis *RuleDriftAnalysis) error {
+	result, err := r.executeOperations(ctx, ruleDriftOperations(analysis))
+	if err != nil {
+		return fmt.Errorf("failed to execute drift correction operations: %w", err)
+	}
+
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d operations failed during drift correction", len(result.Failed))
+	}
+
+	return nil
+}
+
+// ruleDriftOperations returns the operations correcting the drift of a port of a PortForwardRule
+func ruleDriftOperations(analysis *RuleDriftAnalysis) []PortOperation {
+	var operations []PortOperation
+
+	switch {
+	case analysis.Current == nil:
+		operations = append(operations, PortOperation{
+			Type:   OpCreate,
+			Config: analysis.Desired,
+			Reason: "drift_missing_rule",
+		})
+	case analysis.RequiresRecreate():
+		// Risky change: delete then recreate
+		current := analysis.Current
+		operations = append(operations,
+			PortOperation{
+				Type: OpDelete,
+				Config: routers.PortConfig{
+					Name:      current.Name,
+					DstPort:   helpers.ParseIntField(current.DstPort),
+					FwdPort:   helpers.ParseIntField(current.FwdPort),
+					DstIP:     current.Fwd,
+					Protocol:  current.Proto,
+					Enabled:   current.Enabled,
+					Interface: current.PfwdInterface,
+					SrcIP:     current.Src,
+					Log:       current.Log,
+				},
+				ExistingRule: current,
+				Reason:       "drift_wrong_rule_delete",
+			},
+			PortOperation{
+				Type:   OpCreate,
+				Config: analysis.Desired,
+				Reason: "drift_wrong_rule_create",
+			})
+	default:
+		operations = append(operations, PortOperation{
+			Type:         OpUpdate,
+			Config:       analysis.Desired,
+			ExistingRule: analysis.Current,
+			Reason:       "drift_wrong_rule_safe",
+		})
+	}
+	return operations
+}
+
+// updateRuleDriftStatus records the outcome of a drift correction in the PortForwardRule status
+func (r *PeriodicReconciler) updateRuleDriftStatus(ctx context.Context, analysis *RuleDriftAnalysis, correctionErr error) {
+	ruleReconciler := &PortForwardRuleReconciler{
+		Client:   r.Client,
+		Scheme:   r.Scheme,
+		Router:   r.Router,
+		Config:   r.Config,
+		Recorder: r.recorder,
+	}
+
+	drift := "router rule was missing"
+	if analysis.Current != nil {
+		drift = fmt.Sprintf("router rule drifted (%s)", strings.Join(analysis.Mismatches, ", "))
+	}
+
+	if len(analysis.Rule.GetRuleSpec().EffectivePorts()) > 1 {
+		drift = fmt.Sprintf("port %d: %s", analysis.Desired.DstPort, drift)
+	}
+
+	if correctionErr != nil {
+		ruleReconciler.updateRuleStatusWithMutation(ctx, analysis.Rule, v1alpha1.PhaseFailed, correctionErr.Error(), func(rule v1alpha1.PortForwardRuleObject) {
+			recordPortStatus(rule, analysis.Port, func(portStatus *v1alpha1.PortStatus) {
+				portStatus.Phase = v1alpha1.PhaseFailed
+				portStatus.Message = correctionErr.Error()
+			})
+			setRuleCondition(rule, ConditionTypeInSync, metav1.ConditionFalse, "DriftCorrectionFailed",
+				fmt.Sprintf("%s: %s", drift, correctionErr.Error()))
+		})
+		return
+	}
+
+	// Updates keep the router rule recorded in status. Recreated rules are found by their name,
+	// port and protocol, since the name only encodes the external port.
+	ruleID := ""
+	if analysis.Current != nil && !analysis.RequiresRecreate() {
+		ruleID = analysis.Current.ID
+	} else if routerRules, err := r.Router.ListAllPortForwards(ctx); err != nil {
+		ctrllog.FromContext(ctx).Error(err, "Failed to look up corrected router rule", "portforwardrule", analysis.RuleName)
+	} else {
+		for _, routerRule := range routerRules {
+			if routerRule.Name == analysis.Desired.Name &&
+				helpers.ParseIntField(routerRule.DstPort) == analysis.Desired.DstPort &&
+				strings.EqualFold(routerRule.Proto, analysis.Desired.Protocol) {
+				ruleID = routerRule.ID
+				break
+			}
+		}
+	}
+
+	// The rule stays failed while any of its other ports is
+	phase, message := v1alpha1.PhaseActive, ""
+	if failures := otherPortFailures(analysis.Rule, analysis.Port); len(failures) > 0 {
+		phase = v1alpha1.PhaseFailed
+		message = fmt.Sprintf("%d of %d ports failed: %s", len(failures), len(analysis.Rule.GetRuleSpec().EffectivePorts()), strings.Join(failures, "; "))
+	}
+
+	now := metav1.Now()
+	ruleReconciler.updateRuleStatusWithMutation(ctx, analysis.Rule, phase, message, func(rule v1alpha1.PortForwardRuleObject) {
+		recordPortStatus(rule, analysis.Port, func(portStatus *v1alpha1.PortStatus) {
+			portStatus.Phase = v1alpha1.PhaseActive
+			portStatus.Message = ""
+			if ruleID != "" {
+				portStatus.RouterRuleID = ruleID
+			}
+			portStatus.AppliedConfigHash = analysis.Desired.Hash()
+		})
+		rule.GetRuleStatus().LastAppliedTime = &now
+		setRuleCondition(rule, ConditionTypeInSync, metav1.ConditionTrue, "DriftCorrected", drift+" and was corrected")
+	})
+}
+
+// otherPortFailures describes the failed ports of a rule other than port, as recorded in its status
+func otherPortFailures(rule v1alpha1.PortForwardRuleObject, port v1alpha1.PortForwardPort) []string {
+	ports := previousPortStatuses(rule)
+	current := matchPortStatus(ports, port, len(rule.GetRuleSpec().EffectivePorts()) == 1)
+
+	var failures []string
+	for i, portStatus := range ports {
+		if i == current || portStatus.Phase != v1alpha1.PhaseFailed {
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("port %d: %s", portStatus.ExternalPort, portStatus.Message))
+	}
+	return failures
+}
+
+// getAllPortForwardRules retrieves all PortForwardRule and ClusterPortForwardRule resources eligible
+// for drift correction. Only active rules are considered; failed and pending rules are left to the rule controllers.
+func (r *PeriodicReconciler) getAllPortForwardRules(ctx context.Context) ([]v1alpha1.PortForwardRuleObject, error) {
+	var rules []v1alpha1.PortForwardRuleObject
+
+	var ruleList v1alpha1.PortForwardRuleList
+	if err := r.List(ctx, &ruleList, client.InNamespace("")); err != nil {
+		if meta.IsNoMatchError(err) {
+			// CRD not installed
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+	for i := range ruleList.Items {
+		rules = append(rules, &ruleList.Items[i])
+	}
+
+	var clusterRuleList v1alpha1.ClusterPortForwardRuleList
+	if err := r.List(ctx, &clusterRuleList); err != nil {
+		if !meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("failed to list cluster port forward rules: %w", err)
+		}
+	}
+	for i := range clusterRuleList.Items {
+		rules = append(rules, &clusterRuleList.Items[i])
+	}
+
+	eligible := rules[:0]
+	for _, rule := range rules {
+		if rule.GetDeletionTimestamp().IsZero() && rule.GetRuleStatus().Phase == v1alpha1.PhaseActive {
+			eligible = append(eligible, rule)
+		}
+	}
+
+	return eligible, nil
+}
+
 // correctServiceDrift applies corrections for a service that has drift
 func (r *PeriodicReconciler) correctServiceDrift(ctx context.Context, analysis *DriftAnalysis) error {
 	_ = ctrllog.FromContext(ctx).WithValues("component", "periodic-reconciler", "service", analysis.ServiceName)
@@ -283,7 +553,12 @@ func (r *PeriodicReconciler) correctServiceDrift(ctx context.Context, analysis *
 // This reuses the existing operation execution logic from unified_operations.go
 func (r *PeriodicReconciler) executeOperations(ctx context.Context, operations []PortOperation) (*OperationResult, error) {
 	// Create a temporary reconciler to reuse existing operation execution logic
-	tempReconciler := &PortForwardReconciler{
+	return r.newServiceReconciler().executeOperations(ctx, operations)
+}
+
+// newServiceReconciler creates a temporary service reconciler sharing this reconciler's dependencies
+func (r *PeriodicReconciler) newServiceReconciler() *PortForwardReconciler {
+	return &PortForwardReconciler{
 		Client:         r.Client,
 		Scheme:         r.Scheme,
 		Router:         r.Router,
@@ -291,8 +566,6 @@ func (r *PeriodicReconciler) executeOperations(ctx context.Context, operations [
 		EventPublisher: r.eventPublisher,
 		Recorder:       r.recorder,
 	}
-
-	return tempReconciler.executeOperations(ctx, operations)
 }
 
 // getAllManagedServices retrieves all Kubernetes services that should be managed by the controller
@@ -1,12 +1,16 @@
 package controller
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"unifi-port-forward/pkg/api/v1alpha1"
 	"unifi-port-forward/pkg/config"
 	"unifi-port-forward/pkg/helpers"
 
+	"github.com/filipowm/go-unifi/unifi"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -192,3 +196,271 @@ func TestPeriodicReconciler_isSafeUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestPeriodicReconciler_CorrectsPortForwardRuleDrift(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	if err := v1alpha1.AddToScheme(env.Controller.Scheme); err != nil {
+		t.Fatalf("Failed to add v1alpha1 to scheme: %v", err)
+	}
+
+	destIP := "192.168.1.50"
+	destPort := 8080
+	rule := &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			ExternalPort:    8443,
+			Protocol:        "tcp",
+			DestinationIP:   &destIP,
+			DestinationPort: &destPort,
+			Enabled:         true,
+			Interface:       "wan",
+			LogEnabled:      true,
+		},
+		Status: v1alpha1.PortForwardRuleStatus{Phase: v1alpha1.PhaseActive},
+	}
+	if err := env.FakeClient.Create(context.Background(), rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// Router rule was disabled and had logging turned off manually
+	env.MockRouter.AddPortForwardRule(unifi.PortForward{
+		ID:            "rule-1",
+		Name:          "default/web:8443",
+		DstPort:       "8443",
+		FwdPort:       "8080",
+		Fwd:           destIP,
+		Proto:         "tcp",
+		Enabled:       false,
+		PfwdInterface: "wan",
+		Src:           "any",
+	})
+
+	recorder := record.NewFakeRecorder(10)
+	cfg := &config.Config{SyncInterval: time.Minute}
+	reconciler := NewPeriodicReconciler(env.FakeClient, env.Controller.Scheme, env.MockRouter, cfg,
+		NewEventPublisher(env.FakeClient, recorder, env.Controller.Scheme), recorder)
+	reconciler.PortForwardRulesEnabled = true
+
+	if err := reconciler.performFullReconciliation(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	corrected := env.MockRouter.GetPortForwardRuleByName("default/web:8443")
+	if corrected == nil {
+		t.Fatal("Expected router rule to exist after correction")
+	}
+	if !corrected.Enabled || !corrected.Log {
+		t.Errorf("Expected router rule to be enabled with logging, got enabled=%v log=%v", corrected.Enabled, corrected.Log)
+	}
+
+	updated := env.FakeClient.Rules["default/web"]
+	if updated.Status.Phase != v1alpha1.PhaseActive {
+		t.Errorf("Expected phase %s, got %s", v1alpha1.PhaseActive, updated.Status.Phase)
+	}
+	if updated.Status.LastAppliedTime == nil {
+		t.Error("Expected LastAppliedTime to be set")
+	}
+	foundCondition := false
+	for _, condition := range updated.Status.Conditions {
+		if condition.Type == ConditionTypeInSync {
+			foundCondition = true
+			if condition.Status != metav1.ConditionTrue || condition.Reason != "DriftCorrected" {
+				t.Errorf("Unexpected InSync condition: %+v", condition)
+			}
+		}
+	}
+	if !foundCondition {
+		t.Error("Expected InSync condition to be set")
+	}
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	joined := strings.Join(events, "\n")
+	if !strings.Contains(joined, EventDriftDetected) || !strings.Contains(joined, EventDriftCorrected) {
+		t.Errorf("Expected DriftDetected and DriftCorrected events, got %v", events)
+	}
+}
+
+func TestPeriodicReconciler_IgnoresInactivePortForwardRules(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	destIP := "192.168.1.50"
+	destPort := 8080
+	rule := &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			ExternalPort:    8443,
+			Protocol:        "tcp",
+			DestinationIP:   &destIP,
+			DestinationPort: &destPort,
+			Enabled:         true,
+		},
+		Status: v1alpha1.PortForwardRuleStatus{Phase: v1alpha1.PhaseFailed},
+	}
+	if err := env.FakeClient.Create(context.Background(), rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	cfg := &config.Config{SyncInterval: time.Minute}
+	reconciler := NewPeriodicReconciler(env.FakeClient, env.Controller.Scheme, env.MockRouter, cfg, nil, nil)
+	reconciler.PortForwardRulesEnabled = true
+
+	if err := reconciler.performFullReconciliation(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if env.MockRouter.GetCallCount("AddPort") != 0 {
+		t.Errorf("Expected failed rule to be left to the rule controller, AddPort called %d times", env.MockRouter.GetCallCount("AddPort"))
+	}
+}
+
+func TestPeriodicReconciler_RuleDriftStatusCoversEveryPort(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	if err := v1alpha1.AddToScheme(env.Controller.Scheme); err != nil {
+		t.Fatalf("Failed to add v1alpha1 to scheme: %v", err)
+	}
+
+	destIP := "192.168.1.50"
+	rule := &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			Ports: []v1alpha1.PortForwardPort{
+				{Name: "a", ExternalPort: 8443, Protocol: "tcp"},
+				{Name: "b", ExternalPort: 9443, Protocol: "tcp"},
+			},
+			DestinationIP: &destIP,
+			Enabled:       true,
+			Interface:     "wan",
+		},
+		Status: v1alpha1.PortForwardRuleStatus{
+			Phase: v1alpha1.PhaseActive,
+			Ports: []v1alpha1.PortStatus{
+				{Name: "a", ExternalPort: 8443, Protocol: "tcp", Phase: v1alpha1.PhaseActive, RouterRuleID: "rule-a"},
+				{Name: "b", ExternalPort: 9443, Protocol: "tcp", Phase: v1alpha1.PhaseActive, RouterRuleID: "rule-b"},
+			},
+		},
+	}
+	if err := env.FakeClient.Create(context.Background(), rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// Port a's router rule was deleted and cannot be recreated, port b's was disabled. Another
+	// rule on port b's port comes first, so a lookup by port would pick it.
+	env.MockRouter.AddPortForwardRule(unifi.PortForward{
+		ID: "manual", Name: "manual", DstPort: "9443", FwdPort: "9443", Fwd: "192.168.1.60", Proto: "tcp", Enabled: true,
+	})
+	env.MockRouter.AddPortForwardRule(unifi.PortForward{
+		ID: "rule-b", Name: "default/web:9443", DstPort: "9443", FwdPort: "9443", Fwd: destIP, Proto: "tcp",
+		Enabled: false, PfwdInterface: "wan", Src: "any",
+	})
+	env.MockRouter.SetSimulatedFailure("AddPort", true)
+
+	recorder := record.NewFakeRecorder(20)
+	cfg := &config.Config{SyncInterval: time.Minute}
+	reconciler := NewPeriodicReconciler(env.FakeClient, env.Controller.Scheme, env.MockRouter, cfg,
+		NewEventPublisher(env.FakeClient, recorder, env.Controller.Scheme), recorder)
+	reconciler.PortForwardRulesEnabled = true
+
+	if err := reconciler.performFullReconciliation(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updated := env.FakeClient.Rules["default/web"]
+	if updated.Status.Phase != v1alpha1.PhaseFailed {
+		t.Errorf("Expected phase %s while port a failed, got %s", v1alpha1.PhaseFailed, updated.Status.Phase)
+	}
+	if len(updated.Status.Ports) != 2 {
+		t.Fatalf("Expected 2 port statuses, got %+v", updated.Status.Ports)
+	}
+	if port := updated.Status.Ports[0]; port.Phase != v1alpha1.PhaseFailed {
+		t.Errorf("Expected port a to be failed, got %+v", port)
+	}
+	if port := updated.Status.Ports[1]; port.Phase != v1alpha1.PhaseActive || port.RouterRuleID != "rule-b" {
+		t.Errorf("Expected port b to be active tracking rule-b, got %+v", port)
+	}
+	if manual := env.MockRouter.GetPortForwardRuleByName("manual"); manual == nil || manual.Fwd != "192.168.1.60" {
+		t.Errorf("Expected the manual rule to be untouched, got %+v", manual)
+	}
+}
+
+func TestPeriodicReconciler_RecreatedRuleIDMatchesProtocol(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	if err := v1alpha1.AddToScheme(env.Controller.Scheme); err != nil {
+		t.Fatalf("Failed to add v1alpha1 to scheme: %v", err)
+	}
+
+	destIP := "192.168.1.50"
+	rule := &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "default"},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			Ports: []v1alpha1.PortForwardPort{
+				{Name: "tcp", ExternalPort: 53, Protocol: "tcp"},
+				{Name: "udp", ExternalPort: 53, Protocol: "udp"},
+			},
+			DestinationIP: &destIP,
+			Enabled:       true,
+			Interface:     "wan",
+		},
+		Status: v1alpha1.PortForwardRuleStatus{
+			Phase: v1alpha1.PhaseActive,
+			Ports: []v1alpha1.PortStatus{
+				{Name: "tcp", ExternalPort: 53, Protocol: "tcp", Phase: v1alpha1.PhaseActive, RouterRuleID: "rule-tcp"},
+				{Name: "udp", ExternalPort: 53, Protocol: "udp", Phase: v1alpha1.PhaseActive, RouterRuleID: "rule-udp"},
+			},
+		},
+	}
+	if err := env.FakeClient.Create(context.Background(), rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// The forwarded port of the tcp router rule was changed, so it is recreated under the name it
+	// shares with the udp one
+	env.MockRouter.AddPortForwardRule(unifi.PortForward{
+		ID: "rule-udp", Name: "default/dns:53", DstPort: "53", FwdPort: "53", Fwd: destIP, Proto: "udp",
+		Enabled: true, PfwdInterface: "wan", Src: "any",
+	})
+	env.MockRouter.AddPortForwardRule(unifi.PortForward{
+		ID: "rule-tcp", Name: "default/dns:53", DstPort: "53", FwdPort: "5353", Fwd: destIP, Proto: "tcp",
+		Enabled: true, PfwdInterface: "wan", Src: "any",
+	})
+
+	recorder := record.NewFakeRecorder(20)
+	cfg := &config.Config{SyncInterval: time.Minute}
+	reconciler := NewPeriodicReconciler(env.FakeClient, env.Controller.Scheme, env.MockRouter, cfg,
+		NewEventPublisher(env.FakeClient, recorder, env.Controller.Scheme), recorder)
+	reconciler.PortForwardRulesEnabled = true
+
+	if err := reconciler.performFullReconciliation(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var tcpRuleID string
+	for _, routerRule := range env.MockRouter.GetPortForwardRules() {
+		if routerRule.Proto == "tcp" {
+			tcpRuleID = routerRule.ID
+		}
+	}
+	if tcpRuleID == "" || tcpRuleID == "rule-tcp" {
+		t.Fatalf("Expected the tcp router rule to be recreated, got %+v", env.MockRouter.GetPortForwardRules())
+	}
+
+	updated := env.FakeClient.Rules["default/dns"]
+	if len(updated.Status.Ports) != 2 {
+		t.Fatalf("Expected 2 port statuses, got %+v", updated.Status.Ports)
+	}
+	if port := updated.Status.Ports[0]; port.RouterRuleID != tcpRuleID {
+		t.Errorf("Expected tcp port to track %s, got %+v", tcpRuleID, port)
+	}
+	if port := updated.Status.Ports[1]; port.RouterRuleID != "rule-udp" {
+		t.Errorf("Expected udp port to keep tracking rule-udp, got %+v", port)
+	}
+}
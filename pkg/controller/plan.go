@@ -0,0 +1,391 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/routers"
+
+	"github.com/filipowm/go-unifi/unifi"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Plan lists the router changes the controllers would make to reach the desired state
+type Plan struct {
+	Changes []PlannedChange `json:"changes"`
+
+	// Skipped lists the objects that could not be planned
+	Skipped []SkippedObject `json:"skipped,omitempty"`
+}
+
+// PlannedChange is a single router rule operation of a plan
+type PlannedChange struct {
+	// Kind and Owner identify the Service or rule the change is made for
+	Kind  string `json:"kind"`
+	Owner string `json:"owner"`
+
+	Action       OperationType `json:"action"`
+	RouterRule   string        `json:"routerRule"`
+	ExternalPort int           `json:"externalPort"`
+	Protocol     string        `json:"protocol"`
+	Reason       string        `json:"reason"`
+
+	// Diff lists the router rule fields the change sets, updates or removes
+	Diff []FieldChange `json:"diff,omitempty"`
+}
+
+// FieldChange is the old and new value of a router rule field; From is empty for created rules
+// and To is empty for deleted rules
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// SkippedObject is an object that could not be planned, with the reason
+type SkippedObject struct {
+	Kind   string `json:"kind"`
+	Owner  string `json:"owner"`
+	Reason string `json:"reason"`
+}
+
+// Counts returns the number of router rules the plan creates, updates and deletes
+func (p *Plan) Counts() (create, update, remove int) {
+	for _, change := range p.Changes {
+		switch change.Action {
+		case OpCreate:
+			create++
+		case OpUpdate:
+			update++
+		case OpDelete:
+			remove++
+		}
+	}
+	return create, update, remove
+}
+
+// HasChanges reports whether the plan changes the router
+func (p *Plan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// Planner computes the router changes the Service and rule controllers would make, without
+// making them. It reads through Client, which may be a cluster client or a manifests store.
+type Planner struct {
+	client.Client
+	Config *config.Config
+}
+
+// Plan compares the desired state with the given router rules. Services go through the same
+// desired state calculation and delta as the Service controller, and rules through the drift
+// detection of the periodic reconciler.
+func (p *Planner) Plan(ctx context.Context, routerRules []*unifi.PortForward) (*Plan, error) {
+	plan := &Plan{}
+
+	if err := p.planServices(ctx, plan, routerRules); err != nil {
+		return nil, err
+	}
+	if err := p.planRules(ctx, plan, routerRules); err != nil {
+		return nil, err
+	}
+
+	plan.sort()
+	return plan, nil
+}
+
+// planServices adds the changes of every Service, following the decisions of Reconcile
+func (p *Planner) planServices(ctx context.Context, plan *Plan, routerRules []*unifi.PortForward) error {
+	reconciler := &PortForwardReconciler{Client: p.Client, Config: p.Config}
+
+	var services corev1.ServiceList
+	if err := p.List(ctx, &services); err != nil {
+		return fmt.Errorf("failed to list services: %w", err)
+	}
+
+	for i := range services.Items {
+		service := &services.Items[i]
+		owner := client.ObjectKeyFromObject(service).String()
+		_, annotated := service.Annotations[config.FilterAnnotation]
+		hasFinalizer := controllerutil.ContainsFinalizer(service, config.FinalizerLabel)
+
+		var operations []PortOperation
+		switch {
+		case !service.DeletionTimestamp.IsZero():
+			if !hasFinalizer {
+				continue
+			}
+			operations = serviceCleanupOperations(service, routerRules)
+		case helpers.GetLBIP(service) == "":
+			if annotated {
+				plan.Skipped = append(plan.Skipped, SkippedObject{Kind: "Service", Owner: owner, Reason: "no LoadBalancer IP assigned"})
+			}
+			continue
+		case !annotated:
+			if !hasFinalizer {
+				continue
+			}
+			operations = serviceCleanupOperations(service, routerRules)
+		default:
+			desired, err := reconciler.calculateDesiredState(ctx, service)
+			if err != nil {
+				plan.Skipped = append(plan.Skipped, SkippedObject{Kind: "Service", Owner: owner, Reason: err.Error()})
+				continue
+			}
+			operations = reconciler.calculateDelta(serviceRouterRules(service, routerRules), desired, &ChangeContext{}, service)
+		}
+
+		plan.add("Service", owner, operations)
+	}
+	return nil
+}
+
+// planRules adds the changes of every PortForwardRule and ClusterPortForwardRule. Deleted and
+// expired rules lose their router rules; the others are corrected like drift.
+func (p *Planner) planRules(ctx context.Context, plan *Plan, routerRules []*unifi.PortForward) error {
+	rules, err := listRuleObjects(ctx, p.Client)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var live []v1alpha1.PortForwardRuleObject
+	for _, rule := range rules {
+		expiresAt := ruleExpiry(rule)
+		if !rule.GetDeletionTimestamp().IsZero() || (!expiresAt.IsZero() && !now.Before(expiresAt)) {
+			plan.add(ruleKind(rule), ruleOwner(rule), ruleCleanupOperations(rule, routerRules))
+			continue
+		}
+		live = append(live, rule)
+	}
+
+	detector := &DriftDetector{
+		Client:          p.Client,
+		RequireApproval: p.Config != nil && p.Config.RequireApproval,
+		ApprovalKey:     approvalKey(p.Config),
+	}
+	analyses, err := detector.AnalyzeAllRulesDrift(ctx, live, routerRules)
+	if err != nil {
+		return fmt.Errorf("failed to analyze port forward rules: %w", err)
+	}
+
+	analyzed := make(map[v1alpha1.PortForwardRuleObject]bool)
+	for _, analysis := range analyses {
+		analyzed[analysis.Rule] = true
+		if analysis.HasDrift {
+			plan.add(ruleKind(analysis.Rule), ruleOwner(analysis.Rule), ruleDriftOperations(analysis))
+		}
+	}
+
+	// The drift detector leaves out rules the rule controller keeps off the router
+	for _, rule := range live {
+		if !analyzed[rule] {
+			plan.Skipped = append(plan.Skipped, SkippedObject{
+				Kind:   ruleKind(rule),
+				Owner:  ruleOwner(rule),
+				Reason: "destination cannot be resolved, approval is pending or a policy is violated",
+			})
+		}
+	}
+	return nil
+}
+
+// listRuleObjects returns every PortForwardRule and ClusterPortForwardRule; kinds whose CRD is
+// not installed are left out
+func listRuleObjects(ctx context.Context, reader client.Reader) ([]v1alpha1.PortForwardRuleObject, error) {
+	var rules []v1alpha1.PortForwardRuleObject
+
+	var ruleList v1alpha1.PortForwardRuleList
+	if err := reader.List(ctx, &ruleList); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+	for i := range ruleList.Items {
+		rules = append(rules, &ruleList.Items[i])
+	}
+
+	var clusterRuleList v1alpha1.ClusterPortForwardRuleList
+	if err := reader.List(ctx, &clusterRuleList); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("failed to list cluster port forward rules: %w", err)
+	}
+	for i := range clusterRuleList.Items {
+		rules = append(rules, &clusterRuleList.Items[i])
+	}
+
+	return rules, nil
+}
+
+// ruleCleanupOperations returns the operations deleting the router rules of a rule: the rules
+// named after it and unmanaged rules with an ID recorded in its status. Router rules taken
+// over by another rule are left alone, as deletePortRouterRule does.
+func ruleCleanupOperations(rule v1alpha1.PortForwardRuleObject, allRules []*unifi.PortForward) []PortOperation {
+	recordedIDs := make(map[string]bool)
+	for _, portStatus := range previousPortStatuses(rule) {
+		if isRouterRuleID(portStatus.RouterRuleID) {
+			recordedIDs[portStatus.RouterRuleID] = true
+		}
+	}
+
+	prefix := ruleRouterNamePrefix(rule)
+	var operations []PortOperation
+	for _, current := range allRules {
+		owned := strings.HasPrefix(current.Name, prefix) ||
+			(recordedIDs[current.ID] && !helpers.IsManagedRule(current.Name))
+		if !owned {
+			continue
+		}
+		operations = append(operations, PortOperation{
+			Type:         OpDelete,
+			Config:       routerRuleConfig(current),
+			ExistingRule: current,
+			Reason:       "rule_deletion",
+		})
+	}
+	return operations
+}
+
+// routerRuleConfig returns the configuration of an existing router rule
+func routerRuleConfig(rule *unifi.PortForward) routers.PortConfig {
+	return routers.PortConfig{
+		Name:      rule.Name,
+		DstPort:   helpers.ParseIntField(rule.DstPort),
+		FwdPort:   helpers.ParseIntField(rule.FwdPort),
+		DstIP:     rule.Fwd,
+		Protocol:  rule.Proto,
+		Enabled:   rule.Enabled,
+		Interface: rule.PfwdInterface,
+		SrcIP:     rule.Src,
+		Log:       rule.Log,
+	}
+}
+
+// ruleKind returns the kind of a rule
+func ruleKind(rule v1alpha1.PortForwardRuleObject) string {
+	if _, ok := rule.(*v1alpha1.ClusterPortForwardRule); ok {
+		return "ClusterPortForwardRule"
+	}
+	return "PortForwardRule"
+}
+
+// ruleOwner returns the name of a rule, namespaced for PortForwardRules
+func ruleOwner(rule v1alpha1.PortForwardRuleObject) string {
+	if rule.GetNamespace() == "" {
+		return rule.GetName()
+	}
+	return rule.GetNamespace() + "/" + rule.GetName()
+}
+
+// add records the operations of an owner as planned changes
+func (p *Plan) add(kind, owner string, operations []PortOperation) {
+	for _, op := range operations {
+		change := PlannedChange{
+			Kind:         kind,
+			Owner:        owner,
+			Action:       op.Type,
+			RouterRule:   op.Config.Name,
+			ExternalPort: op.Config.DstPort,
+			Protocol:     op.Config.Protocol,
+			Reason:       op.Reason,
+		}
+
+		switch op.Type {
+		case OpCreate:
+			for _, field := range portConfigFields(op.Config) {
+				if field.value != "" {
+					change.Diff = append(change.Diff, FieldChange{Field: field.name, To: field.value})
+				}
+			}
+		case OpDelete:
+			current := op.Config
+			if op.ExistingRule != nil {
+				current = routerRuleConfig(op.ExistingRule)
+			}
+			for _, field := range portConfigFields(current) {
+				if field.value != "" {
+					change.Diff = append(change.Diff, FieldChange{Field: field.name, From: field.value})
+				}
+			}
+		case OpUpdate:
+			if op.ExistingRule != nil {
+				change.RouterRule = op.ExistingRule.Name
+				change.Diff = updateDiff(op.ExistingRule, op.Config)
+			}
+		}
+
+		p.Changes = append(p.Changes, change)
+	}
+}
+
+// sort orders the changes by owner, then by port with deletions before updates and creations,
+// in the order the operations are executed
+func (p *Plan) sort() {
+	actionOrder := map[OperationType]int{OpDelete: 0, OpUpdate: 1, OpCreate: 2}
+	sort.SliceStable(p.Changes, func(i, j int) bool {
+		a, b := p.Changes[i], p.Changes[j]
+		if a.Kind != b.Kind {
+			return a.Kind > b.Kind // Services first
+		}
+		if a.Owner != b.Owner {
+			return a.Owner < b.Owner
+		}
+		if a.ExternalPort != b.ExternalPort {
+			return a.ExternalPort < b.ExternalPort
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return actionOrder[a.Action] < actionOrder[b.Action]
+	})
+	sort.SliceStable(p.Skipped, func(i, j int) bool {
+		if p.Skipped[i].Kind != p.Skipped[j].Kind {
+			return p.Skipped[i].Kind > p.Skipped[j].Kind
+		}
+		return p.Skipped[i].Owner < p.Skipped[j].Owner
+	})
+}
+
+// planField is a router rule field, named as in ruleConfigMismatches
+type planField struct {
+	name  string
+	value string
+}
+
+// portConfigFields returns the fields of a router rule configuration
+func portConfigFields(cfg routers.PortConfig) []planField {
+	return []planField{
+		{"name", cfg.Name},
+		{"ip", cfg.DstIP},
+		{"port", strconv.Itoa(cfg.DstPort)},
+		{"fwdport", strconv.Itoa(cfg.FwdPort)},
+		{"protocol", cfg.Protocol},
+		{"enabled", strconv.FormatBool(cfg.Enabled)},
+		{"source", normalizeSource(cfg.SrcIP)},
+		{"interface", cfg.Interface},
+		{"log", strconv.FormatBool(cfg.Log)},
+	}
+}
+
+// updateDiff returns the fields an update changes on a router rule
+func updateDiff(current *unifi.PortForward, desired routers.PortConfig) []FieldChange {
+	mismatched := make(map[string]bool)
+	for _, field := range ruleConfigMismatches(current, desired) {
+		mismatched[field] = true
+	}
+
+	from := portConfigFields(routerRuleConfig(current))
+	var diff []FieldChange
+	for i, field := range portConfigFields(desired) {
+		if mismatched[field.name] {
+			diff = append(diff, FieldChange{Field: field.name, From: from[i].value, To: field.value})
+		}
+	}
+	return diff
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/manifests"
+
+	"github.com/filipowm/go-unifi/unifi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPlanner_Plan(t *testing.T) {
+	helpers.ClearPortConflictTracking()
+	defer helpers.ClearPortConflictTracking()
+
+	destIP := "192.168.1.50"
+	destPort := 8080
+	deleted := metav1.NewTime(time.Now())
+
+	objects := []client.Object{
+		// New service: both ports are created
+		createTestServiceWithLB("default", "web", map[string]string{
+			config.FilterAnnotation: "8080:http,8443:https",
+		}, "192.168.1.100"),
+		// Service whose LoadBalancer IP changed: its rule is updated in place
+		createTestServiceWithLB("default", "moved", map[string]string{
+			config.FilterAnnotation: "9000:http",
+		}, "192.168.1.101"),
+		// Service that lost its annotation while holding the finalizer: its rule is deleted
+		func() client.Object {
+			service := createTestServiceWithLB("default", "retired", map[string]string{}, "192.168.1.102")
+			service.Finalizers = []string{config.FinalizerLabel}
+			return service
+		}(),
+		// Annotated service waiting for a LoadBalancer IP
+		createTestServiceWithLB("default", "pending", map[string]string{
+			config.FilterAnnotation: "7000:http",
+		}, ""),
+		// Rule whose router rule forwards to a stale port: it is recreated
+		&v1alpha1.PortForwardRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec: v1alpha1.PortForwardRuleSpec{
+				ExternalPort:    6443,
+				Protocol:        "tcp",
+				DestinationIP:   &destIP,
+				DestinationPort: &destPort,
+				Enabled:         true,
+			},
+		},
+		// Rule being deleted: its router rule is deleted
+		&v1alpha1.ClusterPortForwardRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "ssh", DeletionTimestamp: &deleted, Finalizers: []string{config.FinalizerLabel}},
+			Spec: v1alpha1.PortForwardRuleSpec{
+				ExternalPort:    22,
+				Protocol:        "tcp",
+				DestinationIP:   &destIP,
+				DestinationPort: intPtr(22),
+				Enabled:         true,
+			},
+		},
+	}
+
+	routerRules := []*unifi.PortForward{
+		{ID: "1", Name: "default/moved:http", DstPort: "9000", FwdPort: "8080", Fwd: "192.168.1.90", Proto: "tcp", Enabled: true, PfwdInterface: "wan"},
+		{ID: "2", Name: "default/retired:http", DstPort: "5000", FwdPort: "5000", Fwd: "192.168.1.102", Proto: "tcp", Enabled: true},
+		{ID: "3", Name: "default/api:6443", DstPort: "6443", FwdPort: "9090", Fwd: destIP, Proto: "tcp", Enabled: true},
+		{ID: "4", Name: "_cluster/ssh:22", DstPort: "22", FwdPort: "22", Fwd: destIP, Proto: "tcp", Enabled: true},
+		{ID: "5", Name: "manual rule", DstPort: "25", FwdPort: "25", Fwd: "192.168.1.25", Proto: "tcp", Enabled: true},
+	}
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := manifests.NewStore(scheme, objects...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planner := &Planner{Client: store, Config: &config.Config{}}
+	plan, err := planner.Plan(context.Background(), routerRules)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	type change struct {
+		kind, owner string
+		action      OperationType
+		port        int
+	}
+	expected := []change{
+		{"Service", "default/moved", OpUpdate, 9000},
+		{"Service", "default/retired", OpDelete, 5000},
+		{"Service", "default/web", OpCreate, 8080},
+		{"Service", "default/web", OpCreate, 8443},
+		{"PortForwardRule", "default/api", OpDelete, 6443},
+		{"PortForwardRule", "default/api", OpCreate, 6443},
+		{"ClusterPortForwardRule", "ssh", OpDelete, 22},
+	}
+	if len(plan.Changes) != len(expected) {
+		t.Fatalf("Plan() returned %d changes, want %d: %+v", len(plan.Changes), len(expected), plan.Changes)
+	}
+	for i, want := range expected {
+		got := plan.Changes[i]
+		if got.Kind != want.kind || got.Owner != want.owner || got.Action != want.action || got.ExternalPort != want.port {
+			t.Errorf("change %d = %s %s %s %d, want %s %s %s %d", i,
+				got.Kind, got.Owner, got.Action, got.ExternalPort, want.kind, want.owner, want.action, want.port)
+		}
+	}
+
+	update := plan.Changes[0]
+	if len(update.Diff) != 1 || update.Diff[0] != (FieldChange{Field: "ip", From: "192.168.1.90", To: "192.168.1.101"}) {
+		t.Errorf("update diff = %+v, want only the ip change", update.Diff)
+	}
+	if create := plan.Changes[2]; create.Diff[0] != (FieldChange{Field: "name", To: "default/web:http"}) {
+		t.Errorf("create diff = %+v, want the new rule's fields", create.Diff)
+	}
+
+	if create, update, remove := plan.Counts(); create != 3 || update != 1 || remove != 3 {
+		t.Errorf("Counts() = %d, %d, %d, want 3, 1, 3", create, update, remove)
+	}
+
+	if len(plan.Skipped) != 1 || plan.Skipped[0].Owner != "default/pending" {
+		t.Errorf("Skipped = %+v, want the service without LoadBalancer IP", plan.Skipped)
+	}
+}
+
+func TestPlanner_Plan_InSync(t *testing.T) {
+	helpers.ClearPortConflictTracking()
+	defer helpers.ClearPortConflictTracking()
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := manifests.NewStore(scheme, createTestServiceWithLB("default", "web", map[string]string{
+		config.FilterAnnotation: "8080:http",
+	}, "192.168.1.100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routerRules := []*unifi.PortForward{
+		{ID: "1", Name: "default/web:http", DstPort: "8080", FwdPort: "8080", Fwd: "192.168.1.100", Proto: "tcp", Enabled: true},
+	}
+
+	planner := &Planner{Client: store, Config: &config.Config{}}
+	plan, err := planner.Plan(context.Background(), routerRules)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("Plan() of a service in sync = %+v, want no changes", plan.Changes)
+	}
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/routers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// handlePolicies removes the router rules of a rule violating a PortForwardPolicy and marks it
+// Failed until the rule or the policies change. It reports whether the rule violates a policy.
+// ClusterPortForwardRules are not subject to policies.
+func (r *PortForwardRuleReconciler) handlePolicies(ctx context.Context, rule *v1alpha1.PortForwardRule) (bool, error) {
+	logger := ctrllog.FromContext(ctx)
+
+	violations, err := helpers.CheckPortForwardPolicies(ctx, r.Client, rule.PolicyRequest(), config.FilterAnnotation)
+	if err != nil {
+		return false, err
+	}
+
+	previous := meta.FindStatusCondition(rule.Status.Conditions, ConditionTypePolicyCompliant)
+	if len(violations) == 0 {
+		if previous != nil && previous.Status != metav1.ConditionTrue {
+			logger.Info("Rule complies with port forward policies")
+			r.Recorder.Event(rule, corev1.EventTypeNormal, "PolicyCompliant", "Port forwards comply with every PortForwardPolicy")
+			setRuleCondition(rule, ConditionTypePolicyCompliant, metav1.ConditionTrue, "PolicyCompliant", "Port forwards comply with every PortForwardPolicy")
+		}
+		return false, nil
+	}
+
+	if err := r.deleteRouterRuleByID(ctx, rule); err != nil {
+		logger.Error(err, "Failed to remove router rule of rule violating a policy")
+		return true, err
+	}
+
+	message := strings.Join(violations, "; ")
+	if previous == nil || previous.Status != metav1.ConditionFalse || previous.Message != message {
+		logger.Info("Rule violates port forward policies", "violations", violations)
+		r.Recorder.Event(rule, corev1.EventTypeWarning, "PolicyViolation", message)
+	}
+
+	r.updateRuleStatusWithMutation(ctx, rule, v1alpha1.PhaseFailed, message, func(rule v1alpha1.PortForwardRuleObject) {
+		status := rule.GetRuleStatus()
+		status.RouterRuleID = ""
+		status.AppliedConfigHash = ""
+		status.Ports = nil
+		setRuleCondition(rule, ConditionTypePolicyCompliant, metav1.ConditionFalse, "PolicyViolation", message)
+	})
+	return true, nil
+}
+
+// mapPolicyToRules returns reconcile requests for every PortForwardRule, since a policy change can
+// affect any namespace
+func (r *PortForwardRuleReconciler) mapPolicyToRules(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := ctrllog.FromContext(ctx)
+
+	var ruleList v1alpha1.PortForwardRuleList
+	if err := r.List(ctx, &ruleList); err != nil {
+		logger.Error(err, "Failed to list PortForwardRules for policy", "policy", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(ruleList.Items))
+	for _, rule := range ruleList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name},
+		})
+	}
+	return requests
+}
+
+// applyServicePolicies drops the port configs of a service violating a PortForwardPolicy, so no
+// router rules exist for it
+func applyServicePolicies(ctx context.Context, c client.Client, service *corev1.Service, configs []routers.PortConfig) ([]routers.PortConfig, error) {
+	if len(configs) == 0 {
+		return configs, nil
+	}
+	violations, err := helpers.CheckPortForwardPolicies(ctx, c, helpers.ServicePolicyRequest(service, configs), config.FilterAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		return nil, nil
+	}
+	return configs, nil
+}
+
+// recordServicePolicies records the PortForwardPolicy violations of a service in its
+// policy-violation annotation and emits events when it starts and stops violating a policy
+func (r *PortForwardReconciler) recordServicePolicies(ctx context.Context, service *corev1.Service) error {
+	var message string
+	configs, err := helpers.BuildPortConfigs(service, helpers.GetLBIP(service), config.FilterAnnotation)
+	if err == nil && len(configs) > 0 {
+		violations, err := helpers.CheckPortForwardPolicies(ctx, r.Client, helpers.ServicePolicyRequest(service, configs), config.FilterAnnotation)
+		if err != nil {
+			return err
+		}
+		message = strings.Join(violations, "; ")
+	}
+
+	previous := service.Annotations[config.PolicyViolationAnnotation]
+	if message == previous {
+		return nil
+	}
+
+	if r.Recorder != nil {
+		if message != "" {
+			r.Recorder.Event(service, corev1.EventTypeWarning, "PolicyViolation", message)
+		} else {
+			r.Recorder.Event(service, corev1.EventTypeNormal, "PolicyCompliant", "Port forwards comply with every PortForwardPolicy")
+		}
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if message == "" {
+		delete(service.Annotations, config.PolicyViolationAnnotation)
+	} else {
+		service.Annotations[config.PolicyViolationAnnotation] = message
+	}
+	if err := r.Patch(ctx, service, patch); err != nil {
+		return fmt.Errorf("failed to record policy violation on service %s/%s: %w", service.Namespace, service.Name, err)
+	}
+	return nil
+}
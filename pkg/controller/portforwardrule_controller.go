@@ -3,24 +3,64 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"unifi-port-forward/pkg/api/v1alpha1"
 	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/health"
+	"unifi-port-forward/pkg/helpers"
 	"unifi-port-forward/pkg/routers"
+	"unifi-port-forward/testutils"
 
+	"github.com/filipowm/go-unifi/unifi"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ConditionTypeInSync reports whether the router rule matched the PortForwardRule at the last drift check
+	ConditionTypeInSync = "InSync"
+
+	// ConditionTypeServiceReady reports whether the Service referenced by serviceRef exists and has an IP
+	ConditionTypeServiceReady = "ServiceReady"
+
+	// ConditionTypeReferenceGranted reports whether a cross-namespace serviceRef is permitted by a PortForwardReferenceGrant
+	ConditionTypeReferenceGranted = "ReferenceGranted"
+
+	// ConditionTypeScheduleOpen reports whether the rule's schedule currently enables it on the router
+	ConditionTypeScheduleOpen = "ScheduleOpen"
+
+	// ConditionTypeExpired reports whether the rule's expiresAt or ttl has passed
+	ConditionTypeExpired = "Expired"
+
+	// ConditionTypeApproved reports whether the rule's external ports and destinations are approved
+	ConditionTypeApproved = "Approved"
+
+	// ConditionTypePolicyCompliant reports whether the rule's port forwards comply with every PortForwardPolicy
+	ConditionTypePolicyCompliant = "PolicyCompliant"
+
+	// ServiceRefIndexKey indexes PortForwardRules by the namespace/name of their referenced Service
+	ServiceRefIndexKey = "spec.serviceRef"
+
+	// ClusterRuleNamespace takes the place of the namespace in router rule names of
+	// ClusterPortForwardRules. No namespace can contain an underscore, so it never collides with
+	// the router rules of namespaced owners.
+	ClusterRuleNamespace = "_cluster"
 )
 
 // PortForwardRuleReconciler reconciles PortForwardRule resources
@@ -31,8 +71,31 @@ type PortForwardRuleReconciler struct {
 	Config   *config.Config
 	Recorder record.EventRecorder
 
+	// ReferenceGrantsEnabled watches PortForwardReferenceGrants so revoked or added grants take effect immediately
+	ReferenceGrantsEnabled bool
+
+	// PoliciesEnabled watches PortForwardPolicies so changed policies take effect immediately
+	PoliciesEnabled bool
+
 	// activeReconciliations tracks ongoing reconciliations per resource
 	activeReconciliations sync.Map
+
+	// readiness debounces the backend readiness of rules with readinessGate
+	readiness *readinessTracker
+
+	// prober probes the destinations of rules with failover; nil probes over the network
+	prober health.Prober
+
+	// clock evaluates schedules; nil means the system clock
+	clock testutils.TimeProvider
+}
+
+// now returns the current time of the reconciler's clock
+func (r *PortForwardRuleReconciler) now() time.Time {
+	if r.clock != nil {
+		return r.clock.Now()
+	}
+	return time.Now()
 }
 
 // Reconcile implements the reconciliation logic for PortForwardRule resources
@@ -70,15 +133,78 @@ func (r *PortForwardRuleReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return r.handleRuleDeletion(ctx, req.NamespacedName)
 	}
 
+	if expired, err := r.handleExpiry(ctx, rule); expired || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.handleCutover(ctx, rule); err != nil {
+		logger.Error(err, "Failed to check cutover")
+		return ctrl.Result{}, err
+	}
+
+	if rule.Spec.ServiceRef != nil {
+		granted, message, err := r.checkReferenceGrant(ctx, rule)
+		if err != nil {
+			logger.Error(err, "Failed to check reference grants")
+			return ctrl.Result{}, err
+		}
+		if !granted {
+			return r.handleReferenceNotPermitted(ctx, rule, message)
+		}
+
+		reason, message, err := r.checkReferencedService(ctx, rule)
+		if err != nil {
+			logger.Error(err, "Failed to get referenced Service")
+			return ctrl.Result{}, err
+		}
+		if reason != "" {
+			// The Service watch requeues the rule once the Service appears or gets an IP
+			logger.Info("Referenced Service not ready, rule is pending", "reason", reason, "message", message)
+			r.updateRuleStatusWithMutation(ctx, rule, v1alpha1.PhasePending, message, func(rule v1alpha1.PortForwardRuleObject) {
+				setRuleCondition(rule, ConditionTypeServiceReady, metav1.ConditionFalse, reason, message)
+			})
+			return r.scheduleRequeue(rule, ctrl.Result{}), nil
+		}
+	}
+
 	if err := r.validateRule(ctx, rule); err != nil {
 		logger.Error(err, "Rule validation failed")
 		r.updateRuleStatusWithRetry(ctx, rule, v1alpha1.PhaseFailed, err.Error())
 		return ctrl.Result{}, err
 	}
 
+	violated, err := r.handlePolicies(ctx, rule)
+	if err != nil {
+		logger.Error(err, "Failed to check port forward policies")
+		return ctrl.Result{}, err
+	}
+	if violated {
+		// Policies and the rule are watched; the requeue picks up rules leaving the namespace
+		return r.scheduleRequeue(rule, ctrl.Result{RequeueAfter: time.Minute * 5}), nil
+	}
+
+	pendingApproval, err := r.handleApproval(ctx, rule)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if pendingApproval {
+		return r.scheduleRequeue(rule, ctrl.Result{}), nil
+	}
+
+	if err := r.handleReadiness(ctx, rule); err != nil {
+		logger.Error(err, "Failed to check backend readiness")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.handleFailover(ctx, rule); err != nil {
+		logger.Error(err, "Failed to check destination health")
+		r.updateRuleStatusWithRetry(ctx, rule, v1alpha1.PhaseFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
 	if err := r.reconcilePortForwardRule(ctx, rule); err != nil {
 		// Check for special overlap error that needs backoff
-		if err.Error() == "PortForwardOverlaps: requires backoff" {
+		if err == errPortForwardOverlaps {
 			logger.Info("Port forward overlap detected, applying exponential backoff",
 				"rule", rule.Name,
 				"namespace", rule.Namespace)
@@ -95,10 +221,95 @@ func (r *PortForwardRuleReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		}
 	}
 
-	r.updateRuleStatusWithRetry(ctx, rule, v1alpha1.PhaseActive, "")
+	r.updateRuleStatusWithMutation(ctx, rule, v1alpha1.PhaseActive, "", func(rule v1alpha1.PortForwardRuleObject) {
+		if rule.GetRuleSpec().ServiceRef != nil {
+			setRuleCondition(rule, ConditionTypeServiceReady, metav1.ConditionTrue, "ServiceReady", "Referenced Service has a LoadBalancer IP")
+		}
+		if key, ok := serviceRefKey(rule); ok && key.Namespace != rule.GetNamespace() {
+			setRuleCondition(rule, ConditionTypeReferenceGranted, metav1.ConditionTrue, "ReferenceGranted",
+				fmt.Sprintf("Reference to Service %s is permitted by a PortForwardReferenceGrant", key))
+		}
+	})
 
 	logger.V(1).Info("Successfully reconciled PortForwardRule")
-	return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+	return r.scheduleRequeue(rule, ctrl.Result{RequeueAfter: time.Minute * 5}), nil
+}
+
+// scheduleRequeue shortens the requeue of result to the next schedule change of the rule, or
+// to its expiry warning, expiry, pending backend readiness change, next destination probe or
+// next cutover probe if those come first
+func (r *PortForwardRuleReconciler) scheduleRequeue(rule v1alpha1.PortForwardRuleObject, result ctrl.Result) ctrl.Result {
+	now := r.now()
+	status := rule.GetRuleStatus()
+	if next := status.NextScheduleChange; next != nil {
+		result = requeueAt(result, next.Time, now)
+	}
+	if expiresAt := status.ExpiresAt; expiresAt != nil {
+		if warnAt := expiresAt.Add(-r.expiryWarning()); warnAt.After(now) {
+			result = requeueAt(result, warnAt, now)
+		}
+		result = requeueAt(result, expiresAt.Time, now)
+	}
+	result = requeueAt(result, r.readiness.pending(client.ObjectKeyFromObject(rule).String()), now)
+	result = requeueAt(result, nextFailoverProbe(rule), now)
+	return requeueAt(result, nextCutoverProbe(rule), now)
+}
+
+// checkReferenceGrant reports whether the rule may reference its Service. References into
+// another namespace need a PortForwardReferenceGrant there that permits the rule's namespace.
+func (r *PortForwardRuleReconciler) checkReferenceGrant(ctx context.Context, rule *v1alpha1.PortForwardRule) (bool, string, error) {
+	key, _ := serviceRefKey(rule)
+
+	granted, err := v1alpha1.ReferenceGranted(ctx, r.Client, rule.Namespace, key.Namespace, key.Name)
+	if err != nil || granted {
+		return granted, "", err
+	}
+
+	return false, fmt.Sprintf("Reference to Service %s from namespace %s is not permitted by any PortForwardReferenceGrant in namespace %s",
+		key, rule.Namespace, key.Namespace), nil
+}
+
+// handleReferenceNotPermitted removes any router rule created while the reference was permitted
+// and marks the rule Failed, so a revoked grant stops exposing the Service
+func (r *PortForwardRuleReconciler) handleReferenceNotPermitted(ctx context.Context, rule *v1alpha1.PortForwardRule, message string) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx)
+	logger.Info("Cross-namespace service reference not permitted", "message", message)
+
+	if err := r.deleteRouterRuleByID(ctx, rule); err != nil {
+		logger.Error(err, "Failed to remove router rule for unpermitted reference")
+		return ctrl.Result{}, err
+	}
+
+	r.Recorder.Event(rule, corev1.EventTypeWarning, "ReferenceNotPermitted", message)
+	r.updateRuleStatusWithMutation(ctx, rule, v1alpha1.PhaseFailed, message, func(rule v1alpha1.PortForwardRuleObject) {
+		setRuleCondition(rule, ConditionTypeReferenceGranted, metav1.ConditionFalse, "ReferenceNotPermitted", message)
+		rule.GetRuleStatus().RouterRuleID = ""
+		rule.GetRuleStatus().AppliedConfigHash = ""
+		rule.GetRuleStatus().Ports = nil
+	})
+
+	// The grant watch requeues the rule once a grant is created
+	return ctrl.Result{}, nil
+}
+
+// checkReferencedService reports why the Service referenced by the rule cannot be used yet.
+// An empty reason means the Service exists and has a LoadBalancer IP.
+func (r *PortForwardRuleReconciler) checkReferencedService(ctx context.Context, rule v1alpha1.PortForwardRuleObject) (string, string, error) {
+	key, _ := serviceRefKey(rule)
+
+	var service corev1.Service
+	if err := r.Get(ctx, key, &service); err != nil {
+		if errors.IsNotFound(err) {
+			return "ServiceNotFound", fmt.Sprintf("Service %s not found", key), nil
+		}
+		return "", "", err
+	}
+
+	if loadBalancerIP(&service) == "" {
+		return "ServiceHasNoIP", fmt.Sprintf("Service %s has no LoadBalancer IP", key), nil
+	}
+
+	return "", "", nil
 }
 
 // validateRule validates the PortForwardRule
@@ -123,53 +334,146 @@ func (r *PortForwardRuleReconciler) validateRule(ctx context.Context, rule *v1al
 		}
 	}
 
+	// Ports of ClusterPortForwardRules are reserved for the platform
+	if conflictErrs := rule.ValidateClusterPortConflict(ctx, r.Client); len(conflictErrs) > 0 {
+		return fmt.Errorf("port conflict: %s", conflictErrs[0].Detail)
+	}
+
 	return nil
 }
 
-// reconcilePortForwardRule creates/updates the port forwarding rule on the router
-func (r *PortForwardRuleReconciler) reconcilePortForwardRule(ctx context.Context, rule *v1alpha1.PortForwardRule) error {
-	logger := ctrllog.FromContext(ctx)
+// errPortForwardOverlaps reports that the router rejected a rule as overlapping another one,
+// which needs a longer backoff
+var errPortForwardOverlaps = fmt.Errorf("PortForwardOverlaps: requires backoff")
 
-	// Create special error type for overlap scenario
-	ErrPortForwardOverlaps := fmt.Errorf("PortForwardOverlaps: requires backoff")
+// rulePortConfig is the desired router configuration of one port of a rule
+type rulePortConfig struct {
+	Port   v1alpha1.PortForwardPort
+	Config routers.PortConfig
+}
 
-	var destIP string
-	var destPort int
-	var err error
+// reconcilePortForwardRule creates/updates the router rules of every port of the rule, removes
+// the router rules of ports that were dropped from the spec and records per-port status
+func (r *PortForwardRuleReconciler) reconcilePortForwardRule(ctx context.Context, rule v1alpha1.PortForwardRuleObject) error {
+	logger := ctrllog.FromContext(ctx)
 
-	if rule.Spec.ServiceRef != nil {
-		destIP, destPort, err = r.getServiceDestination(ctx, rule)
-	} else if rule.Spec.DestinationIP != nil && rule.Spec.DestinationPort != nil {
-		destIP = *rule.Spec.DestinationIP
-		destPort = *rule.Spec.DestinationPort
-	} else {
-		return fmt.Errorf("invalid rule: neither serviceRef nor destinationIP specified")
+	now := r.now()
+	desiredPorts, err := buildRuleRouterConfigs(ctx, r.Client, rule, now)
+	if err != nil {
+		return err
 	}
 
+	scheduleOpen, nextScheduleChange, err := ruleScheduleState(rule, now)
 	if err != nil {
-		return fmt.Errorf("failed to get destination: %w", err)
+		return err
 	}
+	recordScheduleState(rule, r.Recorder, scheduleOpen, nextScheduleChange)
 
-	srcIP := ""
-	if rule.Spec.SourceIPRestriction != nil {
-		srcIP = *rule.Spec.SourceIPRestriction
+	previous := previousPortStatuses(rule)
+	matched := make([]bool, len(previous))
+
+	var portStatuses []v1alpha1.PortStatus
+	var failures []string
+	overlaps := 0
+
+	for _, desired := range desiredPorts {
+		portStatus := v1alpha1.PortStatus{
+			Name:         desired.Port.Name,
+			ExternalPort: desired.Port.ExternalPort,
+			Protocol:     desired.Port.Protocol,
+		}
+		if rule.GetRuleSpec().ServiceRef != nil {
+			portStatus.ServicePort = int32(desired.Config.FwdPort)
+		}
+
+		var previousRuleID string
+		if i := matchPortStatus(previous, desired.Port, len(desiredPorts) == 1); i >= 0 {
+			matched[i] = true
+			previousRuleID = previous[i].RouterRuleID
+			portStatus.AppliedConfigHash = previous[i].AppliedConfigHash
+		}
+
+		ruleID, err := r.applyRulePort(ctx, rule, desired.Config, previousRuleID)
+		if err != nil {
+			if err == errPortForwardOverlaps {
+				overlaps++
+			}
+			// Keep tracking the previous router rule so it is not orphaned
+			portStatus.RouterRuleID = previousRuleID
+			portStatus.Phase = v1alpha1.PhaseFailed
+			portStatus.Message = err.Error()
+			failures = append(failures, fmt.Sprintf("port %d: %v", desired.Port.ExternalPort, err))
+		} else {
+			portStatus.RouterRuleID = ruleID
+			portStatus.AppliedConfigHash = desired.Config.Hash()
+			portStatus.Phase = v1alpha1.PhaseActive
+		}
+		portStatuses = append(portStatuses, portStatus)
 	}
 
-	routerRule := routers.PortConfig{
-		Name:      fmt.Sprintf("%s/%s:%d", rule.Namespace, rule.Name, rule.Spec.ExternalPort),
-		Enabled:   rule.Spec.Enabled,
-		Interface: rule.Spec.Interface,
-		DstPort:   rule.Spec.ExternalPort, // External port (what users connect to)
-		FwdPort:   destPort,               // Internal port (what service listens on)
-		SrcIP:     srcIP,
-		DstIP:     destIP,
-		Protocol:  rule.Spec.Protocol,
+	// Remove the router rules of ports that are no longer in the spec
+	for i, removed := range previous {
+		if matched[i] {
+			continue
+		}
+		if err := r.deletePortRouterRule(ctx, rule, removed.RouterRuleID, removed.ExternalPort, removed.Protocol); err != nil {
+			logger.Error(err, "Failed to remove router rule of removed port",
+				"port", removed.ExternalPort,
+				"routerRuleID", removed.RouterRuleID)
+			removed.Phase = v1alpha1.PhaseFailed
+			removed.Message = fmt.Sprintf("port was removed from the spec but its router rule could not be deleted: %v", err)
+			portStatuses = append(portStatuses, removed)
+			failures = append(failures, fmt.Sprintf("removed port %d: %v", removed.ExternalPort, err))
+			continue
+		}
+		logger.Info("Removed router rule of port no longer in the spec",
+			"port", removed.ExternalPort,
+			"routerRuleID", removed.RouterRuleID)
+		r.Recorder.Event(rule, corev1.EventTypeNormal, "PortRemoved",
+			fmt.Sprintf("Router rule for removed port %d deleted", removed.ExternalPort))
 	}
 
-	// Property-based discovery: find rule by port+protocol (annotation controller pattern)
-	existingRule, exists, err := r.Router.CheckPort(ctx, rule.Spec.ExternalPort, rule.Spec.Protocol)
+	status := rule.GetRuleStatus()
+	status.Ports = portStatuses
+	status.RouterRuleID = portStatuses[0].RouterRuleID
+	status.AppliedConfigHash = portStatuses[0].AppliedConfigHash
+
+	if len(failures) > 0 {
+		if overlaps == len(failures) {
+			return errPortForwardOverlaps
+		}
+		if len(desiredPorts) == 1 && len(failures) == 1 {
+			return fmt.Errorf("%s", portStatuses[0].Message)
+		}
+		return fmt.Errorf("%d of %d ports failed: %s", len(failures), len(desiredPorts), strings.Join(failures, "; "))
+	}
+
+	status.LastAppliedTime = &metav1.Time{Time: now}
+	status.ObservedGeneration = rule.GetGeneration()
+
+	if key, ok := serviceRefKey(rule); ok {
+		status.ServiceStatus = &v1alpha1.ServiceStatus{
+			Name:           key.Name,
+			Namespace:      key.Namespace,
+			LoadBalancerIP: desiredPorts[0].Config.DstIP,
+			ServicePort:    int32(desiredPorts[0].Config.FwdPort),
+		}
+	}
+
+	logger.V(1).Info("Successfully applied port forwarding rule", "ports", len(desiredPorts), "routerRuleID", status.RouterRuleID)
+	return nil
+}
+
+// applyRulePort creates or updates the router rule of a single port and returns its ID.
+// previousRuleID is the router rule the port was tracking, if any.
+func (r *PortForwardRuleReconciler) applyRulePort(ctx context.Context, rule v1alpha1.PortForwardRuleObject, routerRule routers.PortConfig, previousRuleID string) (string, error) {
+	logger := ctrllog.FromContext(ctx)
+	var ruleID string
+
+	// Find the rule by the ID recorded in status, falling back to port+protocol discovery
+	existingRule, exists, err := r.findRouterRule(ctx, previousRuleID, routerRule)
 	if err != nil {
-		return fmt.Errorf("failed to check existing router rule: %w", err)
+		return "", fmt.Errorf("failed to check existing router rule: %w", err)
 	}
 
 	if exists && existingRule != nil {
@@ -178,7 +482,7 @@ func (r *PortForwardRuleReconciler) reconcilePortForwardRule(ctx context.Context
 		reason := ""
 
 		// Check if we need to take ownership or update existing rule
-		if !strings.HasPrefix(existingRule.Name, fmt.Sprintf("%s/%s:", rule.Namespace, rule.Name)) {
+		if !strings.HasPrefix(existingRule.Name, ruleRouterNamePrefix(rule)) {
 			needsOwnership = true
 			reason = "ownership_takeover"
 		} else if existingRule.Name != routerRule.Name {
@@ -190,128 +494,351 @@ func (r *PortForwardRuleReconciler) reconcilePortForwardRule(ctx context.Context
 		} else if existingRule.Enabled != routerRule.Enabled {
 			needsOwnership = true
 			reason = "enabled_mismatch"
+		} else if len(ruleConfigMismatches(existingRule, routerRule)) > 0 {
+			needsOwnership = true
+			reason = "config_mismatch"
 		}
 
 		if needsOwnership {
 			logger.Info("Taking ownership of existing port forward rule",
-				"port", rule.Spec.ExternalPort,
-				"protocol", rule.Spec.Protocol,
+				"port", routerRule.DstPort,
+				"protocol", routerRule.Protocol,
 				"existing_rule_id", existingRule.ID,
 				"existing_rule_name", existingRule.Name,
 				"new_rule_name", routerRule.Name,
 				"reason", reason)
 
 			// Update the rule to take ownership and fix configuration
-			if err := r.Router.UpdatePort(ctx, rule.Spec.ExternalPort, routerRule); err != nil {
+			if err := r.Router.UpdatePortByID(ctx, existingRule.ID, routerRule); err != nil {
 				if strings.Contains(err.Error(), "PortForwardOverlaps") {
 					logger.Info("Port forward overlap detected during ownership takeover, applying exponential backoff",
-						"port", rule.Spec.ExternalPort,
-						"protocol", rule.Spec.Protocol,
+						"port", routerRule.DstPort,
+						"protocol", routerRule.Protocol,
 						"rule_name", routerRule.Name)
-					return ErrPortForwardOverlaps
+					return "", errPortForwardOverlaps
 				}
-				return fmt.Errorf("failed to update router rule during ownership takeover: %w", err)
+				return "", fmt.Errorf("failed to update router rule during ownership takeover: %w", err)
 			}
 			logger.Info("Successfully took ownership of port forward rule",
-				"port", rule.Spec.ExternalPort,
-				"protocol", rule.Spec.Protocol,
+				"port", routerRule.DstPort,
+				"protocol", routerRule.Protocol,
 				"rule_id", existingRule.ID)
 		} else {
 			logger.V(1).Info("Port forward rule exists and matches desired configuration",
-				"port", rule.Spec.ExternalPort,
-				"protocol", rule.Spec.Protocol,
+				"port", routerRule.DstPort,
+				"protocol", routerRule.Protocol,
 				"rule_id", existingRule.ID)
 		}
+		ruleID = existingRule.ID
 	} else {
 		// No existing rule found - create new one
-		if err := r.Router.AddPort(ctx, routerRule); err != nil {
+		created, err := r.Router.CreatePort(ctx, routerRule)
+		if err != nil {
 			if strings.Contains(err.Error(), "PortForwardOverlaps") {
 				logger.Info("Port forward overlap detected during creation, applying exponential backoff",
-					"port", rule.Spec.ExternalPort,
-					"protocol", rule.Spec.Protocol,
+					"port", routerRule.DstPort,
+					"protocol", routerRule.Protocol,
 					"rule_name", routerRule.Name)
-				return ErrPortForwardOverlaps
+				return "", errPortForwardOverlaps
 			}
-			return fmt.Errorf("failed to create router rule: %w", err)
+			return "", fmt.Errorf("failed to create router rule: %w", err)
 		}
 		logger.Info("Successfully created new port forward rule",
-			"port", rule.Spec.ExternalPort,
-			"protocol", rule.Spec.Protocol,
-			"rule_name", routerRule.Name)
+			"port", routerRule.DstPort,
+			"protocol", routerRule.Protocol,
+			"rule_name", routerRule.Name,
+			"rule_id", created.ID)
+		ruleID = created.ID
 	}
 
-	ruleID := fmt.Sprintf("%s/%s:%d", rule.Namespace, rule.Name, rule.Spec.ExternalPort)
+	if isRouterRuleID(previousRuleID) && previousRuleID != ruleID {
+		r.Recorder.Event(rule, corev1.EventTypeWarning, "RouterRuleReadopted",
+			fmt.Sprintf("Router rule %s no longer exists, now tracking %s", previousRuleID, ruleID))
+	}
 
-	now := metav1.Now()
-	rule.Status.RouterRuleID = ruleID
-	rule.Status.LastAppliedTime = &now
-	rule.Status.ObservedGeneration = rule.Generation
+	r.Recorder.Event(rule, corev1.EventTypeNormal, "RuleApplied",
+		fmt.Sprintf("Port forwarding rule for port %d applied to router (ID: %s)", routerRule.DstPort, ruleID))
+	return ruleID, nil
+}
 
-	if rule.Spec.ServiceRef != nil {
-		namespace := rule.Namespace
-		if rule.Spec.ServiceRef.Namespace != nil {
-			namespace = *rule.Spec.ServiceRef.Namespace
-		}
+// previousPortStatuses returns the per-port status recorded by the last reconciliation. Rules
+// last reconciled by older releases only recorded a single router rule ID for their one port.
+func previousPortStatuses(rule v1alpha1.PortForwardRuleObject) []v1alpha1.PortStatus {
+	status := rule.GetRuleStatus()
+	if len(status.Ports) > 0 {
+		return status.Ports
+	}
+	if status.RouterRuleID == "" {
+		return nil
+	}
 
-		rule.Status.ServiceStatus = &v1alpha1.ServiceStatus{
-			Name:           rule.Spec.ServiceRef.Name,
-			Namespace:      namespace,
-			LoadBalancerIP: destIP,
-			ServicePort:    int32(destPort),
+	previous := v1alpha1.PortStatus{
+		RouterRuleID:      status.RouterRuleID,
+		AppliedConfigHash: status.AppliedConfigHash,
+	}
+	if ports := rule.GetRuleSpec().EffectivePorts(); len(ports) == 1 {
+		previous.ExternalPort = ports[0].ExternalPort
+		previous.Protocol = ports[0].Protocol
+	}
+	return []v1alpha1.PortStatus{previous}
+}
+
+// matchPortStatus returns the index of the status entry tracking port, or -1. Named ports are
+// matched by name and unnamed ports by external port and protocol. The router rule of a rule
+// with a single port follows that port, so changing its external port updates the rule in place.
+func matchPortStatus(previous []v1alpha1.PortStatus, port v1alpha1.PortForwardPort, singlePort bool) int {
+	if singlePort && len(previous) == 1 {
+		return 0
+	}
+	for i, portStatus := range previous {
+		if port.Name != "" {
+			if portStatus.Name == port.Name {
+				return i
+			}
+			continue
+		}
+		if portStatus.Name == "" && portStatus.ExternalPort == port.ExternalPort && portStatus.Protocol == port.Protocol {
+			return i
 		}
 	}
+	return -1
+}
 
-	r.Recorder.Event(rule, corev1.EventTypeNormal, "RuleApplied",
-		fmt.Sprintf("Port forwarding rule applied to router (ID: %s)", ruleID))
+// recordPortStatus applies update to the status entry of port, adding the entry if the port
+// has none yet, and mirrors the first port into the rule-level router rule fields
+func recordPortStatus(rule v1alpha1.PortForwardRuleObject, port v1alpha1.PortForwardPort, update func(*v1alpha1.PortStatus)) {
+	status := rule.GetRuleStatus()
+	ports := append([]v1alpha1.PortStatus(nil), previousPortStatuses(rule)...)
 
-	logger.V(1).Info("Successfully applied port forwarding rule", "routerRuleID", ruleID)
-	return nil
+	i := matchPortStatus(ports, port, len(rule.GetRuleSpec().EffectivePorts()) == 1)
+	if i < 0 {
+		ports = append(ports, v1alpha1.PortStatus{})
+		i = len(ports) - 1
+	}
+	ports[i].Name = port.Name
+	ports[i].ExternalPort = port.ExternalPort
+	ports[i].Protocol = port.Protocol
+	update(&ports[i])
+
+	status.Ports = ports
+	status.RouterRuleID = ports[0].RouterRuleID
+	status.AppliedConfigHash = ports[0].AppliedConfigHash
+}
+
+// ruleRouterName returns the name of the router rule a PortForwardRule owns for an external port
+func ruleRouterName(rule v1alpha1.PortForwardRuleObject, externalPort int) string {
+	return fmt.Sprintf("%s%d", ruleRouterNamePrefix(rule), externalPort)
 }
 
-// getServiceDestination gets the destination IP and port from a service reference
-func (r *PortForwardRuleReconciler) getServiceDestination(ctx context.Context, rule *v1alpha1.PortForwardRule) (string, int, error) {
-	namespace := rule.Namespace
-	if rule.Spec.ServiceRef.Namespace != nil {
-		namespace = *rule.Spec.ServiceRef.Namespace
+// ruleRouterNamePrefix returns the "namespace/name:" prefix of the rule's router rule names.
+// Cluster-scoped rules use ClusterRuleNamespace in place of a namespace.
+func ruleRouterNamePrefix(rule v1alpha1.PortForwardRuleObject) string {
+	namespace := rule.GetNamespace()
+	if namespace == "" {
+		namespace = ClusterRuleNamespace
 	}
+	return fmt.Sprintf("%s/%s:", namespace, rule.GetName())
+}
 
-	var service corev1.Service
-	if err := r.Get(ctx, client.ObjectKey{Name: rule.Spec.ServiceRef.Name, Namespace: namespace}, &service); err != nil {
-		return "", 0, fmt.Errorf("failed to get service: %w", err)
+// routerProtocol maps a PortForwardRule protocol to the value used by the UniFi API
+func routerProtocol(protocol string) string {
+	if protocol == "both" {
+		return "tcp_udp"
+	}
+	return protocol
+}
+
+// isRouterRuleID reports whether id is a router-assigned rule ID. Older releases recorded
+// the synthetic rule name (namespace/name:port) in status instead.
+func isRouterRuleID(id string) bool {
+	return id != "" && !strings.Contains(id, "/")
+}
+
+// findRouterRule locates the router rule backing a port of a PortForwardRule. The ID recorded
+// in status is authoritative; when it is unknown or no longer exists on the router, the rule
+// is rediscovered by external port and protocol so it can be re-adopted.
+func (r *PortForwardRuleReconciler) findRouterRule(ctx context.Context, routerRuleID string, desired routers.PortConfig) (*unifi.PortForward, bool, error) {
+	logger := ctrllog.FromContext(ctx)
+
+	if isRouterRuleID(routerRuleID) {
+		pf, exists, err := r.Router.GetPortForwardByID(ctx, routerRuleID)
+		if err != nil {
+			return nil, false, err
+		}
+		if exists {
+			return pf, true, nil
+		}
+		logger.Info("Router rule recorded in status no longer exists, rediscovering by port",
+			"routerRuleID", routerRuleID,
+			"port", desired.DstPort,
+			"protocol", desired.Protocol)
 	}
 
+	return r.Router.CheckPort(ctx, desired.DstPort, desired.Protocol)
+}
+
+// buildRuleRouterConfigs resolves the destination of a PortForwardRule and returns the router
+// configuration each of its ports should have at now. It is shared by the rule controller and
+// the periodic drift detection so both agree on the desired state.
+func buildRuleRouterConfigs(ctx context.Context, c client.Client, rule v1alpha1.PortForwardRuleObject, now time.Time) ([]rulePortConfig, error) {
+	spec := rule.GetRuleSpec()
+
+	// Outside its schedule windows the rule stays on the router, disabled
+	scheduleOpen, _, err := ruleScheduleState(rule, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without ready endpoints a gated rule stays on the router, disabled
+	backendReady := ruleBackendReady(rule)
+
 	var destIP string
-	if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
-		for _, ingress := range service.Status.LoadBalancer.Ingress {
-			if ingress.IP != "" {
-				destIP = ingress.IP
-				break
+	var service *corev1.Service
+
+	if spec.ServiceRef != nil {
+		destIP, service, err = getServiceDestination(ctx, c, rule)
+	} else if spec.DestinationIP != nil {
+		destIP = *spec.DestinationIP
+	} else if spec.Failover != nil && len(spec.Failover.Destinations) > 0 {
+		destIP = failoverDestination(rule)
+	} else {
+		return nil, fmt.Errorf("invalid rule: neither serviceRef, destinationIP nor failover specified")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination: %w", err)
+	}
+
+	srcIP := "any"
+	if spec.SourceIPRestriction != nil && *spec.SourceIPRestriction != "" {
+		srcIP = *spec.SourceIPRestriction
+	}
+
+	var configs []rulePortConfig
+	for _, port := range spec.EffectivePorts() {
+		var destPort int
+		if service != nil {
+			destPort = servicePortNumber(service, port.Target())
+			if destPort == 0 {
+				return nil, fmt.Errorf("failed to get destination: port %s not found in service %s/%s",
+					port.Target(), service.Namespace, service.Name)
 			}
+		} else {
+			destPort, err = strconv.Atoi(port.Target())
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule: target port %q of port %d is not a number", port.Target(), port.ExternalPort)
+			}
+		}
+
+		iface := port.Interface
+		if iface == "" {
+			iface = spec.Interface
 		}
+		if iface == "" {
+			iface = "wan"
+		}
+
+		configs = append(configs, rulePortConfig{
+			Port: port,
+			Config: routers.PortConfig{
+				Name:      ruleRouterName(rule, port.ExternalPort),
+				Enabled:   port.IsEnabled(spec.Enabled) && scheduleOpen && backendReady,
+				Interface: iface,
+				DstPort:   port.ExternalPort, // External port (what users connect to)
+				FwdPort:   destPort,          // Internal port (what service listens on)
+				SrcIP:     srcIP,
+				DstIP:     destIP,
+				Protocol:  routerProtocol(port.Protocol),
+				Log:       port.IsLogEnabled(spec.LogEnabled),
+			},
+		})
 	}
 
-	if destIP == "" {
-		return "", 0, fmt.Errorf("service %s/%s has no LoadBalancer IP", namespace, rule.Spec.ServiceRef.Name)
+	return configs, nil
+}
+
+// serviceRefKey returns the namespaced name of the Service the rule forwards to: its serviceRef,
+// or with cutover the active Service recorded by handleCutover
+func serviceRefKey(rule v1alpha1.PortForwardRuleObject) (types.NamespacedName, bool) {
+	return serviceReferenceKey(rule, rule.GetRuleSpec().ActiveServiceRef(rule.GetRuleStatus()))
+}
+
+// serviceReferenceKey returns the namespaced name of a Service reference of the rule,
+// resolving an omitted namespace to the rule's own namespace
+func serviceReferenceKey(rule v1alpha1.PortForwardRuleObject, ref *v1alpha1.ServiceReference) (types.NamespacedName, bool) {
+	if ref == nil {
+		return types.NamespacedName{}, false
+	}
+
+	namespace := rule.GetNamespace()
+	if ref.Namespace != nil && *ref.Namespace != "" {
+		namespace = *ref.Namespace
 	}
 
-	// Find the service port
-	var destPort int
+	return types.NamespacedName{Namespace: namespace, Name: ref.Name}, true
+}
+
+// loadBalancerIP returns the first LoadBalancer ingress IP of the service, or "" if it has none
+func loadBalancerIP(service *corev1.Service) string {
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return ""
+	}
+
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return ingress.IP
+		}
+	}
+	return ""
+}
+
+// servicePortNumber returns the number of the service port with the given name or number, or 0
+func servicePortNumber(service *corev1.Service, target string) int {
 	for _, port := range service.Spec.Ports {
-		if port.Name == rule.Spec.ServiceRef.Port || fmt.Sprintf("%d", port.Port) == rule.Spec.ServiceRef.Port {
-			destPort = int(port.Port)
-			break
+		if port.Name == target || fmt.Sprintf("%d", port.Port) == target {
+			return int(port.Port)
 		}
 	}
+	return 0
+}
+
+// getServiceDestination gets the destination IP and the Service of a service reference
+func getServiceDestination(ctx context.Context, c client.Client, rule v1alpha1.PortForwardRuleObject) (string, *corev1.Service, error) {
+	key, _ := serviceRefKey(rule)
+	namespace := key.Namespace
 
-	if destPort == 0 {
-		return "", 0, fmt.Errorf("port %s not found in service %s/%s", rule.Spec.ServiceRef.Port, namespace, rule.Spec.ServiceRef.Name)
+	// Cluster-scoped rules are admin-owned and may reference any namespace
+	if rule.GetNamespace() != "" {
+		granted, err := v1alpha1.ReferenceGranted(ctx, c, rule.GetNamespace(), namespace, key.Name)
+		if err != nil {
+			return "", nil, err
+		}
+		if !granted {
+			return "", nil, fmt.Errorf("reference to service %s from namespace %s is not permitted by any PortForwardReferenceGrant", key, rule.GetNamespace())
+		}
 	}
 
-	return destIP, destPort, nil
+	var service corev1.Service
+	if err := c.Get(ctx, key, &service); err != nil {
+		return "", nil, fmt.Errorf("failed to get service: %w", err)
+	}
+
+	destIP := loadBalancerIP(&service)
+	if destIP == "" {
+		return "", nil, fmt.Errorf("service %s/%s has no LoadBalancer IP", namespace, key.Name)
+	}
+
+	return destIP, &service, nil
 }
 
 // updateRuleStatusWithRetry updates status of PortForwardRule with retry logic for conflicts
-func (r *PortForwardRuleReconciler) updateRuleStatusWithRetry(ctx context.Context, rule *v1alpha1.PortForwardRule, phase, errorMsg string) {
+func (r *PortForwardRuleReconciler) updateRuleStatusWithRetry(ctx context.Context, rule v1alpha1.PortForwardRuleObject, phase, errorMsg string) {
+	r.updateRuleStatusWithMutation(ctx, rule, phase, errorMsg, nil)
+}
+
+// updateRuleStatusWithMutation behaves like updateRuleStatusWithRetry but additionally
+// applies mutate to the status on every attempt, so extra fields survive conflict refreshes
+func (r *PortForwardRuleReconciler) updateRuleStatusWithMutation(ctx context.Context, rule v1alpha1.PortForwardRuleObject, phase, errorMsg string, mutate func(v1alpha1.PortForwardRuleObject)) {
 	logger := ctrllog.FromContext(ctx)
 
 	// Use the existing updateRuleStatus logic but with retry
@@ -324,23 +851,23 @@ func (r *PortForwardRuleReconciler) updateRuleStatusWithRetry(ctx context.Contex
 				"attempt", attempt+1,
 				"maxAttempts", maxAttempts,
 				"backoff", backoffDuration.String(),
-				"rule", rule.Name,
-				"namespace", rule.Namespace)
+				"rule", rule.GetName(),
+				"namespace", rule.GetNamespace())
 			time.Sleep(backoffDuration)
 			backoffDuration *= 2 // Exponential backoff
 
 			// Refresh resource to get latest version
-			if getErr := r.Get(ctx, client.ObjectKey{Namespace: rule.Namespace, Name: rule.Name}, rule); getErr != nil {
+			if getErr := r.Get(ctx, client.ObjectKeyFromObject(rule), rule); getErr != nil {
 				logger.Error(getErr, "Failed to refresh resource for retry",
 					"attempt", attempt+1,
-					"rule", rule.Name,
-					"namespace", rule.Namespace)
+					"rule", rule.GetName(),
+					"namespace", rule.GetNamespace())
 				return // Can't retry without refreshed resource
 			}
 		}
 
 		// Apply status updates (this will modify the rule in-place)
-		rule.Status.Phase = phase
+		rule.GetRuleStatus().Phase = phase
 
 		conditionType := "RuleReady"
 		status := metav1.ConditionFalse
@@ -351,51 +878,34 @@ func (r *PortForwardRuleReconciler) updateRuleStatusWithRetry(ctx context.Contex
 			status = metav1.ConditionTrue
 			reason = "RuleApplied"
 			message = "Port forwarding rule successfully applied"
+		} else if phase == v1alpha1.PhasePending {
+			reason = "Pending"
+		} else if phase == v1alpha1.PhaseExpired {
+			reason = "Expired"
+		} else if phase == v1alpha1.PhasePendingApproval {
+			reason = "PendingApproval"
 		}
 
-		// Update or add condition
-		conditions := rule.Status.Conditions
-		updatedConditions := make([]metav1.Condition, len(conditions))
-		copy(updatedConditions, conditions)
-
-		conditionFound := false
-		for i, condition := range updatedConditions {
-			if condition.Type == conditionType {
-				updatedConditions[i].Status = status
-				updatedConditions[i].Reason = reason
-				updatedConditions[i].Message = message
-				updatedConditions[i].LastTransitionTime = metav1.Now()
-				conditionFound = true
-				break
-			}
-		}
+		setRuleCondition(rule, conditionType, status, reason, message)
 
-		if !conditionFound {
-			updatedConditions = append(updatedConditions, metav1.Condition{
-				Type:               conditionType,
-				Status:             status,
-				LastTransitionTime: metav1.Now(),
-				Reason:             reason,
-				Message:            message,
-			})
+		if mutate != nil {
+			mutate(rule)
 		}
 
-		rule.Status.Conditions = updatedConditions
-
 		// Update error info if failed
 		if phase == v1alpha1.PhaseFailed {
 			var retryCount int
-			if rule.Status.ErrorInfo != nil {
-				retryCount = rule.Status.ErrorInfo.RetryCount + 1
+			if rule.GetRuleStatus().ErrorInfo != nil {
+				retryCount = rule.GetRuleStatus().ErrorInfo.RetryCount + 1
 			}
-			rule.Status.ErrorInfo = &v1alpha1.ErrorInfo{
+			rule.GetRuleStatus().ErrorInfo = &v1alpha1.ErrorInfo{
 				Code:            "ReconciliationError",
 				Message:         errorMsg,
 				LastFailureTime: &metav1.Time{Time: time.Now()},
 				RetryCount:      retryCount,
 			}
 		} else {
-			rule.Status.ErrorInfo = nil
+			rule.GetRuleStatus().ErrorInfo = nil
 		}
 
 		// Try to update status
@@ -404,8 +914,8 @@ func (r *PortForwardRuleReconciler) updateRuleStatusWithRetry(ctx context.Contex
 			logger.V(1).Info("Successfully updated rule status",
 				"attempt", attempt+1,
 				"phase", phase,
-				"rule", rule.Name,
-				"namespace", rule.Namespace)
+				"rule", rule.GetName(),
+				"namespace", rule.GetNamespace())
 			return // Success
 		}
 
@@ -413,16 +923,16 @@ func (r *PortForwardRuleReconciler) updateRuleStatusWithRetry(ctx context.Contex
 			logger.Info("Status update conflict detected, will retry",
 				"attempt", attempt+1,
 				"error", err.Error(),
-				"rule", rule.Name,
-				"namespace", rule.Namespace)
+				"rule", rule.GetName(),
+				"namespace", rule.GetNamespace())
 			// Continue to next attempt with refreshed resource
 			continue
 		} else {
 			// Non-conflict error, don't retry
 			logger.Error(err, "Failed to update rule status (non-conflict error)",
 				"attempt", attempt+1,
-				"rule", rule.Name,
-				"namespace", rule.Namespace)
+				"rule", rule.GetName(),
+				"namespace", rule.GetNamespace())
 			return
 		}
 	}
@@ -430,16 +940,96 @@ func (r *PortForwardRuleReconciler) updateRuleStatusWithRetry(ctx context.Contex
 	// Max retries exceeded
 	logger.Error(nil, "Failed to update status after maximum retries",
 		"maxRetries", maxAttempts,
-		"rule", rule.Name,
-		"namespace", rule.Namespace)
+		"rule", rule.GetName(),
+		"namespace", rule.GetNamespace())
+}
+
+// setRuleCondition updates or adds a condition on the PortForwardRule status
+func setRuleCondition(rule v1alpha1.PortForwardRuleObject, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	conditions := rule.GetRuleStatus().Conditions
+	updatedConditions := make([]metav1.Condition, len(conditions))
+	copy(updatedConditions, conditions)
+
+	conditionFound := false
+	for i, condition := range updatedConditions {
+		if condition.Type == conditionType {
+			updatedConditions[i].Status = status
+			updatedConditions[i].Reason = reason
+			updatedConditions[i].Message = message
+			updatedConditions[i].LastTransitionTime = metav1.Now()
+			conditionFound = true
+			break
+		}
+	}
+
+	if !conditionFound {
+		updatedConditions = append(updatedConditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			LastTransitionTime: metav1.Now(),
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	rule.GetRuleStatus().Conditions = updatedConditions
+}
+
+// deleteRouterRuleByID deletes the router rules of every port of the rule, identifying them
+// by the IDs recorded in status
+func (r *PortForwardRuleReconciler) deleteRouterRuleByID(ctx context.Context, rule v1alpha1.PortForwardRuleObject) error {
+	previous := previousPortStatuses(rule)
+	for _, portStatus := range previous {
+		if err := r.deletePortRouterRule(ctx, rule, portStatus.RouterRuleID, portStatus.ExternalPort, portStatus.Protocol); err != nil {
+			return err
+		}
+	}
+
+	// Ports without recorded status may still have a router rule from an interrupted reconciliation
+	for _, port := range rule.GetRuleSpec().EffectivePorts() {
+		if matchPortStatus(previous, port, false) >= 0 {
+			continue
+		}
+		if err := r.deletePortRouterRule(ctx, rule, "", port.ExternalPort, port.Protocol); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// deleteRouterRuleByID deletes router rule using proper identification
-func (r *PortForwardRuleReconciler) deleteRouterRuleByID(ctx context.Context, rule *v1alpha1.PortForwardRule) error {
+// deletePortRouterRule deletes the router rule of a single port, found by its recorded ID or
+// else by external port and protocol. Router rules owned by others are left alone.
+func (r *PortForwardRuleReconciler) deletePortRouterRule(ctx context.Context, rule v1alpha1.PortForwardRuleObject, routerRuleID string, externalPort int, protocol string) error {
 	logger := ctrllog.FromContext(ctx)
 
-	// Use CheckPort to find the actual UniFi router rule ID
-	pf, exists, err := r.Router.CheckPort(ctx, rule.Spec.ExternalPort, rule.Spec.Protocol)
+	if isRouterRuleID(routerRuleID) {
+		pf, exists, err := r.Router.GetPortForwardByID(ctx, routerRuleID)
+		if err != nil {
+			return fmt.Errorf("failed to find router rule for deletion: %w", err)
+		}
+		if exists && helpers.IsManagedRule(pf.Name) && !strings.HasPrefix(pf.Name, ruleRouterNamePrefix(rule)) {
+			// Another rule, such as a ClusterPortForwardRule, has taken the router rule over
+			logger.Info("Router rule recorded in status is owned by another rule, skipping deletion",
+				"routerRuleID", routerRuleID,
+				"router_rule_name", pf.Name)
+			return nil
+		}
+		if exists {
+			logger.V(1).Info("Deleting router rule by ID", "routerRuleID", routerRuleID)
+			return r.Router.DeletePortForwardByID(ctx, routerRuleID)
+		}
+		logger.Info("Router rule recorded in status no longer exists, rediscovering by port",
+			"routerRuleID", routerRuleID,
+			"port", externalPort,
+			"protocol", protocol)
+	}
+
+	if externalPort == 0 {
+		return nil
+	}
+
+	// Fall back to property-based discovery of the actual UniFi router rule ID
+	pf, exists, err := r.Router.CheckPort(ctx, externalPort, routerProtocol(protocol))
 	if err != nil {
 		return fmt.Errorf("failed to find router rule for deletion: %w", err)
 	}
@@ -447,17 +1037,26 @@ func (r *PortForwardRuleReconciler) deleteRouterRuleByID(ctx context.Context, ru
 	if !exists {
 		// Rule doesn't exist on router - consider this success
 		logger.V(1).Info("Router rule not found during deletion, assuming already cleaned up",
-			"port", rule.Spec.ExternalPort,
-			"protocol", rule.Spec.Protocol,
-			"routerRuleID", rule.Status.RouterRuleID)
+			"port", externalPort,
+			"protocol", protocol,
+			"routerRuleID", routerRuleID)
+		return nil
+	}
+
+	// Never delete a rule on the same port that belongs to someone else
+	if pf.Name != ruleRouterName(rule, externalPort) {
+		logger.Info("Router rule on port is not owned by this PortForwardRule, skipping deletion",
+			"port", externalPort,
+			"protocol", protocol,
+			"router_rule_name", pf.Name)
 		return nil
 	}
 
 	// Delete using the actual UniFi router rule ID
 	logger.V(1).Info("Deleting router rule by ID",
 		"routerRuleID", pf.ID,
-		"port", rule.Spec.ExternalPort,
-		"protocol", rule.Spec.Protocol)
+		"port", externalPort,
+		"protocol", protocol)
 
 	return r.Router.DeletePortForwardByID(ctx, pf.ID)
 }
@@ -472,7 +1071,7 @@ func (r *PortForwardRuleReconciler) handleRuleDeletion(ctx context.Context, name
 
 	if err == nil {
 		// Rule still exists - handle router deletion and finalizer removal
-		if rule.Status.RouterRuleID != "" {
+		if rule.Status.RouterRuleID != "" || len(rule.Status.Ports) > 0 {
 			if delErr := r.deleteRouterRuleByID(ctx, rule); delErr != nil {
 				logger.Error(delErr, "Failed to delete router rule", "routerRuleID", rule.Status.RouterRuleID)
 				// CRITICAL: Don't remove finalizer if router deletion failed
@@ -500,10 +1099,90 @@ func (r *PortForwardRuleReconciler) handleRuleDeletion(ctx context.Context, name
 	return ctrl.Result{}, nil
 }
 
+// indexServiceRef registers the ServiceRefIndexKey field index for the given rule kind, indexing
+// both Services of a rule with cutover
+func indexServiceRef(ctx context.Context, indexer client.FieldIndexer, ruleType v1alpha1.PortForwardRuleObject) error {
+	return indexer.IndexField(ctx, ruleType, ServiceRefIndexKey, func(obj client.Object) []string {
+		rule, ok := obj.(v1alpha1.PortForwardRuleObject)
+		if !ok {
+			return nil
+		}
+		var keys []string
+		for _, key := range referencedServiceKeys(rule) {
+			keys = append(keys, key.String())
+		}
+		return keys
+	})
+}
+
+// mapServiceToRules returns reconcile requests for every PortForwardRule referencing the service,
+// including rules in other namespaces
+func (r *PortForwardRuleReconciler) mapServiceToRules(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := ctrllog.FromContext(ctx)
+
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	var ruleList v1alpha1.PortForwardRuleList
+	if err := r.List(ctx, &ruleList, client.MatchingFields{ServiceRefIndexKey: key.String()}); err != nil {
+		logger.Error(err, "Failed to list PortForwardRules referencing service", "service", key)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(ruleList.Items))
+	for _, rule := range ruleList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name},
+		})
+	}
+	return requests
+}
+
+// mapReferenceGrantToRules returns reconcile requests for every PortForwardRule in another namespace
+// that references a Service in the grant's namespace
+func (r *PortForwardRuleReconciler) mapReferenceGrantToRules(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := ctrllog.FromContext(ctx)
+
+	var ruleList v1alpha1.PortForwardRuleList
+	if err := r.List(ctx, &ruleList); err != nil {
+		logger.Error(err, "Failed to list PortForwardRules for reference grant", "grant", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range ruleList.Items {
+		rule := &ruleList.Items[i]
+		for _, key := range referencedServiceKeys(rule) {
+			if key.Namespace != obj.GetNamespace() || rule.Namespace == key.Namespace {
+				continue
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name},
+			})
+			break
+		}
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager
 func (r *PortForwardRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := indexServiceRef(context.Background(), mgr.GetFieldIndexer(), &v1alpha1.PortForwardRule{}); err != nil {
+		return fmt.Errorf("failed to index PortForwardRules by serviceRef: %w", err)
+	}
+
+	r.readiness = newReadinessTracker()
+
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.PortForwardRule{}).
-		Owns(&corev1.Service{}). // Watch services that are referenced by rules
-		Complete(r)
+		// Rules don't own the Services they reference, so map Service events back to the referencing rules
+		Watches(&corev1.Service{}, handler.EnqueueRequestsFromMapFunc(r.mapServiceToRules)).
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(r.mapEndpointSliceToRules))
+
+	if r.ReferenceGrantsEnabled {
+		builder = builder.Watches(&v1alpha1.PortForwardReferenceGrant{}, handler.EnqueueRequestsFromMapFunc(r.mapReferenceGrantToRules))
+	}
+	if r.PoliciesEnabled {
+		builder = builder.Watches(&v1alpha1.PortForwardPolicy{}, handler.EnqueueRequestsFromMapFunc(r.mapPolicyToRules))
+	}
+
+	return builder.Complete(r)
 }
@@ -2,17 +2,27 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/filipowm/go-unifi/unifi"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
 
 	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/approval"
 	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/health"
+	"unifi-port-forward/pkg/routers"
 	"unifi-port-forward/testutils"
 )
 
@@ -126,3 +136,1171 @@ func stringPtr(s string) *string {
 func intPtr(i int) *int {
 	return &i
 }
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func newRuleIDTestController(t *testing.T) (*PortForwardRuleReconciler, *testutils.MockRouter, *record.FakeRecorder) {
+	mockRouter := testutils.NewMockRouter()
+	mockRouter.ClearAllPortForwards()
+	mockRouter.ResetOperationCounts()
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add core v1 to scheme: %v", err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add v1alpha1 to scheme: %v", err)
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	controller := &PortForwardRuleReconciler{
+		Client:   testutils.NewFakeKubernetesClient(t, scheme),
+		Router:   mockRouter,
+		Scheme:   scheme,
+		Config:   &config.Config{Debug: true},
+		Recorder: recorder,
+	}
+	return controller, mockRouter, recorder
+}
+
+func newStandaloneRule(externalPort int) *v1alpha1.PortForwardRule {
+	return &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-rule", Namespace: "default"},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			ExternalPort:    externalPort,
+			Protocol:        "tcp",
+			Interface:       "wan",
+			Enabled:         true,
+			DestinationIP:   stringPtr("192.168.1.100"),
+			DestinationPort: intPtr(80),
+		},
+	}
+}
+
+func TestReconcilePortForwardRule_RecordsRouterRuleID(t *testing.T) {
+	controller, mockRouter, _ := newRuleIDTestController(t)
+	rule := newStandaloneRule(8080)
+
+	if err := controller.reconcilePortForwardRule(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	created := mockRouter.GetPortForwardRuleByName("default/test-rule:8080")
+	if created == nil {
+		t.Fatal("Expected router rule to be created")
+	}
+	if rule.Status.RouterRuleID != created.ID {
+		t.Errorf("Expected RouterRuleID %q, got %q", created.ID, rule.Status.RouterRuleID)
+	}
+	if rule.Status.AppliedConfigHash == "" {
+		t.Error("Expected AppliedConfigHash to be set")
+	}
+}
+
+func TestReconcilePortForwardRule_UpdatesByRouterRuleID(t *testing.T) {
+	controller, mockRouter, _ := newRuleIDTestController(t)
+
+	// The tracked rule still forwards the old external port
+	mockRouter.AddPortForwardRule(unifi.PortForward{
+		ID:            "abc123",
+		Name:          "default/test-rule:8080",
+		DstPort:       "8080",
+		FwdPort:       "80",
+		Fwd:           "192.168.1.100",
+		Proto:         "tcp",
+		Enabled:       true,
+		PfwdInterface: "wan",
+		Src:           "any",
+	})
+
+	rule := newStandaloneRule(9090)
+	rule.Status.RouterRuleID = "abc123"
+
+	if err := controller.reconcilePortForwardRule(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if mockRouter.GetCallCount("UpdatePortByID") != 1 {
+		t.Errorf("Expected rule to be updated by ID, UpdatePortByID called %d times", mockRouter.GetCallCount("UpdatePortByID"))
+	}
+	if mockRouter.GetCallCount("CreatePort") != 0 {
+		t.Errorf("Expected no new rule, CreatePort called %d times", mockRouter.GetCallCount("CreatePort"))
+	}
+
+	rules := mockRouter.GetPortForwardRules()
+	if len(rules) != 1 || rules[0].ID != "abc123" || rules[0].DstPort != "9090" || rules[0].Name != "default/test-rule:9090" {
+		t.Errorf("Expected tracked rule to move to port 9090, got %+v", rules)
+	}
+	if rule.Status.RouterRuleID != "abc123" {
+		t.Errorf("Expected RouterRuleID to stay abc123, got %q", rule.Status.RouterRuleID)
+	}
+}
+
+func TestReconcilePortForwardRule_ReadoptsWhenRouterRuleIDDisappears(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+
+	// The tracked ID is gone, but a rule for the port exists under a new ID
+	mockRouter.AddPortForwardRule(unifi.PortForward{
+		ID:            "new456",
+		Name:          "default/test-rule:8080",
+		DstPort:       "8080",
+		FwdPort:       "80",
+		Fwd:           "192.168.1.100",
+		Proto:         "tcp",
+		Enabled:       true,
+		PfwdInterface: "wan",
+		Src:           "any",
+	})
+
+	rule := newStandaloneRule(8080)
+	rule.Status.RouterRuleID = "gone123"
+
+	if err := controller.reconcilePortForwardRule(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if rule.Status.RouterRuleID != "new456" {
+		t.Errorf("Expected RouterRuleID to be re-adopted as new456, got %q", rule.Status.RouterRuleID)
+	}
+
+	foundEvent := false
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, "RouterRuleReadopted") {
+			foundEvent = true
+		}
+	}
+	if !foundEvent {
+		t.Error("Expected RouterRuleReadopted event")
+	}
+}
+
+func TestDeleteRouterRuleByID_PrefersRecordedID(t *testing.T) {
+	controller, mockRouter, _ := newRuleIDTestController(t)
+
+	// Tracked rule was renamed on the router; another rule now holds the spec port
+	mockRouter.AddPortForwardRule(unifi.PortForward{ID: "abc123", Name: "renamed", DstPort: "9090", Proto: "tcp"})
+	mockRouter.AddPortForwardRule(unifi.PortForward{ID: "other", Name: "manual", DstPort: "8080", Proto: "tcp"})
+
+	rule := newStandaloneRule(8080)
+	rule.Status.RouterRuleID = "abc123"
+
+	if err := controller.deleteRouterRuleByID(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mockRouter.LastDeletedRuleID != "abc123" {
+		t.Errorf("Expected tracked rule abc123 to be deleted, got %q", mockRouter.LastDeletedRuleID)
+	}
+
+	// With the tracked rule gone, the fallback must not delete a rule owned by someone else
+	mockRouter.ResetOperationCounts()
+	if err := controller.deleteRouterRuleByID(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mockRouter.DeletePortForwardByIDCalled {
+		t.Errorf("Expected foreign rule on port 8080 to be left alone, deleted %q", mockRouter.LastDeletedRuleID)
+	}
+}
+
+func newMultiPortRule() *v1alpha1.PortForwardRule {
+	rule := newStandaloneRule(0)
+	rule.Spec.DestinationPort = nil
+	rule.Spec.Protocol = ""
+	rule.Spec.Ports = []v1alpha1.PortForwardPort{
+		{Name: "game", ExternalPort: 27015, Protocol: "both"},
+		{Name: "rcon", ExternalPort: 27020, Protocol: "tcp", TargetPort: intOrStringPtr(intstr.FromInt32(2720)), Enabled: boolPtr(false)},
+	}
+	return rule
+}
+
+func TestReconcilePortForwardRule_MultiPort(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+	rule := newMultiPortRule()
+
+	if err := controller.reconcilePortForwardRule(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	game := mockRouter.GetPortForwardRuleByName("default/test-rule:27015")
+	rcon := mockRouter.GetPortForwardRuleByName("default/test-rule:27020")
+	if game == nil || rcon == nil {
+		t.Fatalf("Expected a router rule per port, got %+v", mockRouter.GetPortForwardRules())
+	}
+	if game.FwdPort != "27015" || game.Proto != "tcp_udp" || !game.Enabled {
+		t.Errorf("Expected game port forwarded to 27015 over tcp_udp, got %+v", game)
+	}
+	if rcon.FwdPort != "2720" || rcon.Enabled {
+		t.Errorf("Expected disabled rcon port forwarded to 2720, got %+v", rcon)
+	}
+
+	if len(rule.Status.Ports) != 2 {
+		t.Fatalf("Expected status for both ports, got %+v", rule.Status.Ports)
+	}
+	for i, id := range []string{game.ID, rcon.ID} {
+		if rule.Status.Ports[i].RouterRuleID != id || rule.Status.Ports[i].Phase != v1alpha1.PhaseActive {
+			t.Errorf("Expected port %d Active with router rule %s, got %+v", i, id, rule.Status.Ports[i])
+		}
+	}
+	if rule.Status.RouterRuleID != game.ID {
+		t.Errorf("Expected RouterRuleID to mirror the first port, got %q", rule.Status.RouterRuleID)
+	}
+
+	// Removing a port deletes its router rule and keeps the other one
+	rule.Spec.Ports = rule.Spec.Ports[:1]
+	if err := controller.reconcilePortForwardRule(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mockRouter.GetPortForwardRuleByName("default/test-rule:27020") != nil {
+		t.Error("Expected router rule of removed port to be deleted")
+	}
+	if len(rule.Status.Ports) != 1 || rule.Status.Ports[0].RouterRuleID != game.ID {
+		t.Errorf("Expected only the game port in status, got %+v", rule.Status.Ports)
+	}
+
+	foundEvent := false
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, "PortRemoved") {
+			foundEvent = true
+		}
+	}
+	if !foundEvent {
+		t.Error("Expected PortRemoved event")
+	}
+}
+
+func TestDeleteRouterRuleByID_MultiPort(t *testing.T) {
+	controller, mockRouter, _ := newRuleIDTestController(t)
+	rule := newMultiPortRule()
+
+	if err := controller.reconcilePortForwardRule(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := controller.deleteRouterRuleByID(context.Background(), rule); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if rules := mockRouter.GetPortForwardRules(); len(rules) != 0 {
+		t.Errorf("Expected router rules of every port to be deleted, got %+v", rules)
+	}
+}
+
+func newServiceRefRule(name, namespace string, serviceNamespace *string) *v1alpha1.PortForwardRule {
+	return &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  namespace,
+			Finalizers: []string{config.FinalizerLabel},
+		},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			ExternalPort:   8080,
+			Protocol:       "tcp",
+			Enabled:        true,
+			ConflictPolicy: "warn",
+			ServiceRef: &v1alpha1.ServiceReference{
+				Name:      "web",
+				Namespace: serviceNamespace,
+				Port:      "http",
+			},
+		},
+	}
+}
+
+func TestMapServiceToRules_UsesServiceRefIndex(t *testing.T) {
+	controller, _, _ := newRuleIDTestController(t)
+	fakeClient := controller.Client.(*testutils.FakeKubernetesClient)
+	ctx := context.Background()
+
+	if err := indexServiceRef(ctx, fakeClient, &v1alpha1.PortForwardRule{}); err != nil {
+		t.Fatalf("Failed to register index: %v", err)
+	}
+
+	rules := []*v1alpha1.PortForwardRule{
+		newServiceRefRule("same-namespace", "apps", nil),
+		newServiceRefRule("cross-namespace", "edge", stringPtr("apps")),
+		newServiceRefRule("other-service", "edge", nil),
+		newStandaloneRule(9090),
+	}
+	for _, rule := range rules {
+		if err := fakeClient.Create(ctx, rule); err != nil {
+			t.Fatalf("Failed to create rule %s: %v", rule.Name, err)
+		}
+	}
+
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"}}
+	requests := controller.mapServiceToRules(ctx, service)
+
+	got := make(map[string]bool)
+	for _, request := range requests {
+		got[request.NamespacedName.String()] = true
+	}
+	if len(got) != 2 || !got["apps/same-namespace"] || !got["edge/cross-namespace"] {
+		t.Errorf("Expected requests for apps/same-namespace and edge/cross-namespace, got %v", requests)
+	}
+}
+
+func TestReconcile_ServiceRefPendingUntilServiceReady(t *testing.T) {
+	controller, mockRouter, _ := newRuleIDTestController(t)
+	ctx := context.Background()
+
+	rule := newServiceRefRule("web-rule", "apps", nil)
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "web-rule"}}
+
+	assertServiceReady := func(wantPhase string, wantStatus metav1.ConditionStatus, wantReason string) {
+		t.Helper()
+		if _, err := controller.Reconcile(ctx, request); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		if updated.Status.Phase != wantPhase {
+			t.Errorf("Expected phase %s, got %s", wantPhase, updated.Status.Phase)
+		}
+		condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeServiceReady)
+		if condition == nil || condition.Status != wantStatus || condition.Reason != wantReason {
+			t.Errorf("Expected ServiceReady=%s (%s), got %+v", wantStatus, wantReason, condition)
+		}
+	}
+
+	// Referenced Service does not exist yet
+	assertServiceReady(v1alpha1.PhasePending, metav1.ConditionFalse, "ServiceNotFound")
+
+	// Service exists but has not been assigned an IP
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+	if err := controller.Create(ctx, service); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	assertServiceReady(v1alpha1.PhasePending, metav1.ConditionFalse, "ServiceHasNoIP")
+
+	if mockRouter.GetCallCount("CreatePort") != 0 {
+		t.Errorf("Expected no router rule while pending, CreatePort called %d times", mockRouter.GetCallCount("CreatePort"))
+	}
+
+	// Service gets its IP
+	service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "192.168.1.50"}}
+	if err := controller.Update(ctx, service); err != nil {
+		t.Fatalf("Failed to update service: %v", err)
+	}
+	assertServiceReady(v1alpha1.PhaseActive, metav1.ConditionTrue, "ServiceReady")
+
+	created := mockRouter.GetPortForwardRuleByName("apps/web-rule:8080")
+	if created == nil || created.Fwd != "192.168.1.50" {
+		t.Errorf("Expected router rule forwarding to 192.168.1.50, got %+v", created)
+	}
+}
+
+func TestReconcile_CrossNamespaceServiceRefRequiresGrant(t *testing.T) {
+	controller, mockRouter, _ := newRuleIDTestController(t)
+	ctx := context.Background()
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "192.168.1.50"}}},
+		},
+	}
+	if err := controller.Create(ctx, service); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	rule := newServiceRefRule("web-rule", "edge", stringPtr("apps"))
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "edge", Name: "web-rule"}}
+
+	assertReferenceGranted := func(wantPhase string, wantStatus metav1.ConditionStatus) {
+		t.Helper()
+		if _, err := controller.Reconcile(ctx, request); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		if updated.Status.Phase != wantPhase {
+			t.Errorf("Expected phase %s, got %s", wantPhase, updated.Status.Phase)
+		}
+		condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeReferenceGranted)
+		if condition == nil || condition.Status != wantStatus {
+			t.Errorf("Expected ReferenceGranted=%s, got %+v", wantStatus, condition)
+		}
+	}
+
+	// No grant in the target namespace
+	assertReferenceGranted(v1alpha1.PhaseFailed, metav1.ConditionFalse)
+	if len(mockRouter.GetPortForwardRules()) != 0 {
+		t.Fatalf("Expected no router rule without a grant, got %+v", mockRouter.GetPortForwardRules())
+	}
+
+	grant := &v1alpha1.PortForwardReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-edge", Namespace: "apps"},
+		Spec: v1alpha1.PortForwardReferenceGrantSpec{
+			From: []v1alpha1.ReferenceGrantFrom{{Namespace: "edge"}},
+		},
+	}
+	if err := controller.Create(ctx, grant); err != nil {
+		t.Fatalf("Failed to create grant: %v", err)
+	}
+
+	requests := controller.mapReferenceGrantToRules(ctx, grant)
+	if len(requests) != 1 || requests[0].NamespacedName != request.NamespacedName {
+		t.Errorf("Expected grant to map to %s, got %v", request.NamespacedName, requests)
+	}
+
+	assertReferenceGranted(v1alpha1.PhaseActive, metav1.ConditionTrue)
+	if mockRouter.GetPortForwardRuleByName("edge/web-rule:8080") == nil {
+		t.Fatal("Expected router rule once the grant exists")
+	}
+
+	// Revoking the grant removes the router rule again
+	if err := controller.Delete(ctx, grant); err != nil {
+		t.Fatalf("Failed to delete grant: %v", err)
+	}
+	assertReferenceGranted(v1alpha1.PhaseFailed, metav1.ConditionFalse)
+	if len(mockRouter.GetPortForwardRules()) != 0 {
+		t.Errorf("Expected router rule to be removed after the grant was revoked, got %+v", mockRouter.GetPortForwardRules())
+	}
+}
+
+func TestReconcilePortForwardRule_Schedule(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+	// Wednesday 2026-03-04 07:58 UTC, just before office hours
+	clock := testutils.NewMockClock(time.Date(2026, 3, 4, 7, 58, 0, 0, time.UTC))
+	controller.clock = clock
+
+	rule := newStandaloneRule(8080)
+	rule.Spec.Schedule = &v1alpha1.PortForwardSchedule{
+		TimeZone: "UTC",
+		Windows: []v1alpha1.ScheduleWindow{
+			{Start: "0 8 * * mon-fri", Duration: metav1.Duration{Duration: 9 * time.Hour}},
+		},
+	}
+
+	assertSchedule := func(wantEnabled bool, wantStatus metav1.ConditionStatus, wantNext time.Time) {
+		t.Helper()
+		if err := controller.reconcilePortForwardRule(context.Background(), rule); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		routerRule := mockRouter.GetPortForwardRuleByName("default/test-rule:8080")
+		if routerRule == nil {
+			t.Fatal("Expected router rule to exist outside the schedule windows as well")
+		}
+		if routerRule.Enabled != wantEnabled {
+			t.Errorf("Expected router rule enabled=%t, got %t", wantEnabled, routerRule.Enabled)
+		}
+		condition := meta.FindStatusCondition(rule.Status.Conditions, ConditionTypeScheduleOpen)
+		if condition == nil || condition.Status != wantStatus {
+			t.Errorf("Expected ScheduleOpen condition %s, got %+v", wantStatus, condition)
+		}
+		if rule.Status.NextScheduleChange == nil || !rule.Status.NextScheduleChange.Time.Equal(wantNext) {
+			t.Errorf("Expected next schedule change at %s, got %v", wantNext, rule.Status.NextScheduleChange)
+		}
+	}
+
+	assertSchedule(false, metav1.ConditionFalse, time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC))
+
+	result := controller.scheduleRequeue(rule, ctrl.Result{RequeueAfter: 5 * time.Minute})
+	if result.RequeueAfter != 2*time.Minute+time.Second {
+		t.Errorf("Expected requeue just past the window start, got %s", result.RequeueAfter)
+	}
+
+	clock.Advance(2*time.Minute + time.Second)
+	assertSchedule(true, metav1.ConditionTrue, time.Date(2026, 3, 4, 17, 0, 0, 0, time.UTC))
+
+	opened := false
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, "ScheduleWindowOpened") {
+			opened = true
+		}
+	}
+	if !opened {
+		t.Error("Expected a ScheduleWindowOpened event when the window opened")
+	}
+
+	result = controller.scheduleRequeue(rule, ctrl.Result{RequeueAfter: 5 * time.Minute})
+	if result.RequeueAfter != 5*time.Minute {
+		t.Errorf("Expected the regular requeue when the next change is further away, got %s", result.RequeueAfter)
+	}
+}
+
+func TestReconcile_RuleExpiry(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+	controller.Config.ExpiryWarning = time.Hour
+	start := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	clock := testutils.NewMockClock(start)
+	controller.clock = clock
+	ctx := context.Background()
+
+	rule := newStandaloneRule(8080)
+	rule.CreationTimestamp = metav1.Time{Time: start}
+	rule.Finalizers = []string{config.FinalizerLabel}
+	rule.Spec.ConflictPolicy = "warn"
+	rule.Spec.TTL = &metav1.Duration{Duration: 2 * time.Hour}
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test-rule"}}
+
+	reconcile := func() *v1alpha1.PortForwardRule {
+		t.Helper()
+		if _, err := controller.Reconcile(ctx, request); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		return updated
+	}
+	assertEvent := func(reason string) {
+		t.Helper()
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, reason) {
+				return
+			}
+		}
+		t.Errorf("Expected a %s event", reason)
+	}
+
+	updated := reconcile()
+	if updated.Status.Phase != v1alpha1.PhaseActive {
+		t.Fatalf("Expected phase Active, got %s", updated.Status.Phase)
+	}
+	if updated.Status.ExpiresAt == nil || !updated.Status.ExpiresAt.Time.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("Expected status.expiresAt two hours after creation, got %v", updated.Status.ExpiresAt)
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeExpired); condition == nil || condition.Reason != "NotExpired" {
+		t.Errorf("Expected Expired condition with reason NotExpired, got %+v", condition)
+	}
+
+	clock.Advance(90 * time.Minute)
+	updated = reconcile()
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeExpired); condition == nil || condition.Reason != "ExpiringSoon" {
+		t.Errorf("Expected Expired condition with reason ExpiringSoon, got %+v", condition)
+	}
+	assertEvent("ExpiringSoon")
+
+	clock.Advance(30 * time.Minute)
+	updated = reconcile()
+	if updated.Status.Phase != v1alpha1.PhaseExpired {
+		t.Errorf("Expected phase Expired, got %s", updated.Status.Phase)
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeExpired); condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("Expected Expired condition True, got %+v", condition)
+	}
+	if len(mockRouter.GetPortForwardRules()) != 0 {
+		t.Errorf("Expected router rule to be removed on expiry, got %+v", mockRouter.GetPortForwardRules())
+	}
+	assertEvent("Expired")
+
+	// Raising the ttl restores the rule
+	updated.Spec.TTL = &metav1.Duration{Duration: 4 * time.Hour}
+	if err := controller.Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	updated = reconcile()
+	if updated.Status.Phase != v1alpha1.PhaseActive {
+		t.Errorf("Expected phase Active after extending the ttl, got %s", updated.Status.Phase)
+	}
+	if mockRouter.GetPortForwardRuleByName("default/test-rule:8080") == nil {
+		t.Error("Expected router rule to be recreated after extending the ttl")
+	}
+	assertEvent("ExpiryExtended")
+}
+
+func TestReconcile_RuleDeleteOnExpiry(t *testing.T) {
+	controller, mockRouter, _ := newRuleIDTestController(t)
+	now := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	controller.clock = testutils.NewMockClock(now)
+	ctx := context.Background()
+
+	rule := newStandaloneRule(8080)
+	rule.Finalizers = []string{config.FinalizerLabel}
+	rule.Spec.ConflictPolicy = "warn"
+	rule.Spec.ExpiresAt = &metav1.Time{Time: now.Add(-time.Minute)}
+	rule.Spec.DeleteOnExpiry = true
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	if _, err := mockRouter.CreatePort(ctx, routers.PortConfig{Name: "default/test-rule:8080", DstPort: 8080, FwdPort: 80, DstIP: "192.168.1.100", Protocol: "tcp", Enabled: true}); err != nil {
+		t.Fatalf("Failed to create router rule: %v", err)
+	}
+
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test-rule"}}
+	if _, err := controller.Reconcile(ctx, request); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := controller.Get(ctx, request.NamespacedName, &v1alpha1.PortForwardRule{}); err == nil {
+		t.Error("Expected expired rule with deleteOnExpiry to be deleted")
+	}
+	if len(mockRouter.GetPortForwardRules()) != 0 {
+		t.Errorf("Expected router rule to be removed, got %+v", mockRouter.GetPortForwardRules())
+	}
+}
+
+// testApprovalKey signs approvals in tests like the admission webhook does
+const testApprovalKey = "0123456789abcdef0123456789abcdef"
+
+func TestReconcile_RuleApproval(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+	controller.Config.RequireApproval = true
+	controller.Config.ApprovalSigningKey = testApprovalKey
+	ctx := context.Background()
+
+	rule := newStandaloneRule(8080)
+	rule.Finalizers = []string{config.FinalizerLabel}
+	rule.Spec.ConflictPolicy = "warn"
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test-rule"}}
+
+	reconcile := func() *v1alpha1.PortForwardRule {
+		t.Helper()
+		if _, err := controller.Reconcile(ctx, request); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		return updated
+	}
+	assertEvent := func(reason string) {
+		t.Helper()
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, reason) {
+				return
+			}
+		}
+		t.Errorf("Expected a %s event", reason)
+	}
+
+	updated := reconcile()
+	if updated.Status.Phase != v1alpha1.PhasePendingApproval {
+		t.Fatalf("Expected phase PendingApproval, got %s", updated.Status.Phase)
+	}
+	if len(mockRouter.GetPortForwardRules()) != 0 {
+		t.Errorf("Expected no router rule before approval, got %+v", mockRouter.GetPortForwardRules())
+	}
+	if updated.Status.ExposureHash == "" {
+		t.Fatal("Expected status.exposureHash to be set")
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeApproved); condition == nil || condition.Reason != "PendingApproval" {
+		t.Errorf("Expected Approved condition with reason PendingApproval, got %+v", condition)
+	}
+	assertEvent("ApprovalRequired")
+
+	// The bare exposure hash is what reaches the controller when the webhook is bypassed
+	updated.Annotations = map[string]string{config.ApprovedAnnotation: updated.Status.ExposureHash}
+	if err := controller.Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to annotate rule: %v", err)
+	}
+	updated = reconcile()
+	if updated.Status.Phase != v1alpha1.PhasePendingApproval || len(mockRouter.GetPortForwardRules()) != 0 {
+		t.Fatalf("Expected an unsigned approval to keep the rule pending, got phase %s and router rules %+v",
+			updated.Status.Phase, mockRouter.GetPortForwardRules())
+	}
+
+	updated.Annotations[config.ApprovedAnnotation] = approval.Sign([]byte(testApprovalKey), "PortForwardRule",
+		updated.Namespace, updated.Name, updated.Status.ExposureHash)
+	if err := controller.Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to approve rule: %v", err)
+	}
+	updated = reconcile()
+	if updated.Status.Phase != v1alpha1.PhaseActive {
+		t.Errorf("Expected phase Active after approval, got %s", updated.Status.Phase)
+	}
+	if mockRouter.GetPortForwardRuleByName("default/test-rule:8080") == nil {
+		t.Error("Expected router rule to be created after approval")
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeApproved); condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("Expected Approved condition True, got %+v", condition)
+	}
+	assertEvent("Approved")
+
+	// A new destination needs another approval
+	updated.Spec.DestinationIP = stringPtr("192.168.1.200")
+	if err := controller.Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	updated = reconcile()
+	if updated.Status.Phase != v1alpha1.PhasePendingApproval {
+		t.Errorf("Expected phase PendingApproval after changing the destination, got %s", updated.Status.Phase)
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeApproved); condition == nil || condition.Reason != "ApprovalInvalidated" {
+		t.Errorf("Expected Approved condition with reason ApprovalInvalidated, got %+v", condition)
+	}
+	if len(mockRouter.GetPortForwardRules()) != 0 {
+		t.Errorf("Expected router rule to be removed until re-approved, got %+v", mockRouter.GetPortForwardRules())
+	}
+	assertEvent("ApprovalRequired")
+}
+
+func TestReconcile_RulePolicyViolation(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+	ctx := context.Background()
+
+	policy := &v1alpha1.PortForwardPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "reserved-ports"},
+		Spec:       v1alpha1.PortForwardPolicySpec{DeniedPorts: []v1alpha1.PortRange{{From: 8000, To: 8999}}},
+	}
+	if err := controller.Create(ctx, policy); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	rule := newStandaloneRule(8080)
+	rule.Finalizers = []string{config.FinalizerLabel}
+	rule.Spec.ConflictPolicy = "warn"
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test-rule"}}
+
+	reconcile := func() *v1alpha1.PortForwardRule {
+		t.Helper()
+		if _, err := controller.Reconcile(ctx, request); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		return updated
+	}
+	assertEvent := func(reason string) {
+		t.Helper()
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, reason) {
+				return
+			}
+		}
+		t.Errorf("Expected a %s event", reason)
+	}
+
+	updated := reconcile()
+	if updated.Status.Phase != v1alpha1.PhaseFailed {
+		t.Fatalf("Expected phase Failed, got %s", updated.Status.Phase)
+	}
+	if len(mockRouter.GetPortForwardRules()) != 0 {
+		t.Errorf("Expected no router rule for a rule violating a policy, got %+v", mockRouter.GetPortForwardRules())
+	}
+	condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypePolicyCompliant)
+	if condition == nil || condition.Status != metav1.ConditionFalse || !strings.Contains(condition.Message, "reserved-ports") {
+		t.Errorf("Expected PolicyCompliant condition False naming the policy, got %+v", condition)
+	}
+	assertEvent("PolicyViolation")
+
+	if err := controller.Delete(ctx, policy); err != nil {
+		t.Fatalf("Failed to delete policy: %v", err)
+	}
+	updated = reconcile()
+	if updated.Status.Phase != v1alpha1.PhaseActive {
+		t.Errorf("Expected phase Active once the policy is removed, got %s", updated.Status.Phase)
+	}
+	if mockRouter.GetPortForwardRuleByName("default/test-rule:8080") == nil {
+		t.Error("Expected router rule to be created once the policy is removed")
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypePolicyCompliant); condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("Expected PolicyCompliant condition True, got %+v", condition)
+	}
+	assertEvent("PolicyCompliant")
+}
+
+func TestReconcile_RuleReadinessGate(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+	clock := testutils.NewMockClock(time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC))
+	controller.clock = clock
+	controller.Config.ReadinessDebounce = 30 * time.Second
+	controller.readiness = newReadinessTracker()
+	ctx := context.Background()
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "192.168.1.50"}}},
+		},
+	}
+	if err := controller.Create(ctx, service); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	slice := testutils.CreateTestEndpointSlice("web-abc", "apps", "web", true)
+	if err := controller.Create(ctx, slice); err != nil {
+		t.Fatalf("Failed to create endpoint slice: %v", err)
+	}
+
+	rule := newServiceRefRule("web-rule", "apps", nil)
+	rule.Spec.ReadinessGate = true
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "web-rule"}}
+
+	reconcile := func() (*v1alpha1.PortForwardRule, ctrl.Result) {
+		t.Helper()
+		result, err := controller.Reconcile(ctx, request)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		return updated, result
+	}
+	assertBackendReady := func(updated *v1alpha1.PortForwardRule, want metav1.ConditionStatus) {
+		t.Helper()
+		routerRule := mockRouter.GetPortForwardRuleByName("apps/web-rule:8080")
+		if routerRule == nil {
+			t.Fatal("Expected the router rule to be kept")
+		}
+		if routerRule.Enabled != (want == metav1.ConditionTrue) {
+			t.Errorf("Expected router rule enabled=%t, got %t", want == metav1.ConditionTrue, routerRule.Enabled)
+		}
+		if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeBackendReady); condition == nil || condition.Status != want {
+			t.Errorf("Expected BackendReady condition %s, got %+v", want, condition)
+		}
+	}
+	assertEvent := func(reason string) {
+		t.Helper()
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, reason) {
+				return
+			}
+		}
+		t.Errorf("Expected a %s event", reason)
+	}
+
+	updated, _ := reconcile()
+	assertBackendReady(updated, metav1.ConditionTrue)
+
+	// Losing every ready endpoint disables the rule once the debounce has passed
+	if err := controller.Delete(ctx, slice); err != nil {
+		t.Fatalf("Failed to delete endpoint slice: %v", err)
+	}
+	updated, result := reconcile()
+	assertBackendReady(updated, metav1.ConditionTrue)
+	if result.RequeueAfter != 31*time.Second {
+		t.Errorf("Expected a requeue right after the debounce, got %s", result.RequeueAfter)
+	}
+
+	clock.Advance(31 * time.Second)
+	updated, _ = reconcile()
+	assertBackendReady(updated, metav1.ConditionFalse)
+	if updated.Status.Phase != v1alpha1.PhaseActive {
+		t.Errorf("Expected phase Active while disabled, got %s", updated.Status.Phase)
+	}
+	assertEvent("BackendUnavailable")
+
+	// A blip shorter than the debounce keeps the rule disabled
+	if err := controller.Create(ctx, slice); err != nil {
+		t.Fatalf("Failed to create endpoint slice: %v", err)
+	}
+	updated, _ = reconcile()
+	assertBackendReady(updated, metav1.ConditionFalse)
+
+	clock.Advance(31 * time.Second)
+	updated, _ = reconcile()
+	assertBackendReady(updated, metav1.ConditionTrue)
+	assertEvent("BackendAvailable")
+}
+
+func TestReconcile_RuleCutover(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+	clock := testutils.NewMockClock(time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC))
+	controller.clock = clock
+	prober := &fakeProber{down: make(map[string]bool)}
+	controller.prober = prober
+	ctx := context.Background()
+
+	for name, ip := range map[string]string{"web": "192.168.1.50", "web-green": "192.168.1.60"} {
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+			},
+			Status: corev1.ServiceStatus{
+				LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: ip}}},
+			},
+		}
+		if err := controller.Create(ctx, service); err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+	}
+
+	rule := newServiceRefRule("web-rule", "apps", nil)
+	rule.Spec.Cutover = &v1alpha1.ServiceCutover{
+		Green:       v1alpha1.CutoverService{Name: "web-green"},
+		HealthCheck: &v1alpha1.HealthCheck{Protocol: "tcp"},
+	}
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "web-rule"}}
+
+	reconcile := func() (*v1alpha1.PortForwardRule, ctrl.Result) {
+		t.Helper()
+		result, err := controller.Reconcile(ctx, request)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		return updated, result
+	}
+	// probe advances to the next probe and reconciles
+	probe := func() *v1alpha1.PortForwardRule {
+		t.Helper()
+		clock.Advance(10 * time.Second)
+		updated, _ := reconcile()
+		return updated
+	}
+	selectService := func(active string) {
+		t.Helper()
+		current := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, current); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		current.Spec.Cutover.Active = active
+		current.Generation++
+		if err := controller.Update(ctx, current); err != nil {
+			t.Fatalf("Failed to update rule: %v", err)
+		}
+	}
+	assertCutover := func(updated *v1alpha1.PortForwardRule, active, phase, ip string) {
+		t.Helper()
+		if updated.Status.Cutover == nil || updated.Status.Cutover.Active != active || updated.Status.Cutover.Phase != phase {
+			t.Errorf("Expected %s Service active in phase %s, got %+v", active, phase, updated.Status.Cutover)
+		}
+		if rules := mockRouter.GetPortForwardRules(); len(rules) != 1 {
+			t.Errorf("Expected the router rule to be updated in place, got %+v", rules)
+		}
+		routerRule := mockRouter.GetPortForwardRuleByName("apps/web-rule:8080")
+		if routerRule == nil || routerRule.Fwd != ip {
+			t.Errorf("Expected the router rule to forward to %s, got %+v", ip, routerRule)
+		}
+	}
+	assertEvent := func(reason string) {
+		t.Helper()
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, reason) {
+				return
+			}
+		}
+		t.Errorf("Expected a %s event", reason)
+	}
+
+	updated, _ := reconcile()
+	assertCutover(updated, v1alpha1.CutoverBlue, v1alpha1.CutoverPhaseStable, "192.168.1.50")
+	desired, err := buildRuleRouterConfigs(ctx, controller.Client, updated, clock.Now())
+	if err != nil {
+		t.Fatalf("Failed to build router configs: %v", err)
+	}
+	approvedHash := ruleExposureHash(updated, desired)
+
+	// Selecting green switches the router rule right away and verifies the green Service
+	selectService(v1alpha1.CutoverGreen)
+	updated, result := reconcile()
+	assertCutover(updated, v1alpha1.CutoverGreen, v1alpha1.CutoverPhaseVerifying, "192.168.1.60")
+	assertEvent("CutoverStarted")
+	if result.RequeueAfter != 11*time.Second {
+		t.Errorf("Expected a requeue right after the next probe, got %s", result.RequeueAfter)
+	}
+	desired, err = buildRuleRouterConfigs(ctx, controller.Client, updated, clock.Now())
+	if err != nil {
+		t.Fatalf("Failed to build router configs: %v", err)
+	}
+	if hash := ruleExposureHash(updated, desired); hash != approvedHash {
+		t.Errorf("Expected cutting over to keep the exposure hash %s, got %s", approvedHash, hash)
+	}
+
+	updated = probe()
+	assertCutover(updated, v1alpha1.CutoverGreen, v1alpha1.CutoverPhaseStable, "192.168.1.60")
+	assertEvent("CutoverSucceeded")
+
+	// Cutting back to a Service failing its health check is rolled back
+	prober.setDown("192.168.1.50", true)
+	selectService(v1alpha1.CutoverBlue)
+	updated, _ = reconcile()
+	assertCutover(updated, v1alpha1.CutoverBlue, v1alpha1.CutoverPhaseVerifying, "192.168.1.50")
+	probe()
+	updated = probe()
+	assertCutover(updated, v1alpha1.CutoverGreen, v1alpha1.CutoverPhaseRolledBack, "192.168.1.60")
+	assertEvent("CutoverRolledBack")
+
+	// The rolled back cutover is not retried until the spec changes
+	prober.setDown("192.168.1.50", false)
+	updated = probe()
+	assertCutover(updated, v1alpha1.CutoverGreen, v1alpha1.CutoverPhaseRolledBack, "192.168.1.60")
+
+	// Selecting green again settles the cutover
+	selectService(v1alpha1.CutoverGreen)
+	updated, _ = reconcile()
+	assertCutover(updated, v1alpha1.CutoverGreen, v1alpha1.CutoverPhaseStable, "192.168.1.60")
+}
+
+// fakeProber fails the probes of the destinations in down
+type fakeProber struct {
+	mu   sync.Mutex
+	down map[string]bool
+}
+
+func (p *fakeProber) Probe(_ context.Context, check health.Check) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down[check.IP] {
+		return fmt.Errorf("dial tcp %s:%d: connection refused", check.IP, check.Port)
+	}
+	return nil
+}
+
+func (p *fakeProber) setDown(ip string, down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down[ip] = down
+}
+
+func TestReconcile_RuleFailover(t *testing.T) {
+	controller, mockRouter, recorder := newRuleIDTestController(t)
+	clock := testutils.NewMockClock(time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC))
+	controller.clock = clock
+	prober := &fakeProber{down: make(map[string]bool)}
+	controller.prober = prober
+	ctx := context.Background()
+
+	rule := newStandaloneRule(1194)
+	rule.Finalizers = []string{config.FinalizerLabel}
+	rule.Spec.ConflictPolicy = "warn"
+	rule.Spec.DestinationIP = nil
+	rule.Spec.DestinationPort = intPtr(1194)
+	rule.Spec.Failover = &v1alpha1.DestinationFailover{
+		Destinations: []v1alpha1.FailoverDestination{{IP: "192.168.1.21"}, {IP: "192.168.1.20", Priority: 100}},
+	}
+	if err := controller.Create(ctx, rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test-rule"}}
+
+	reconcile := func() (*v1alpha1.PortForwardRule, ctrl.Result) {
+		t.Helper()
+		result, err := controller.Reconcile(ctx, request)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &v1alpha1.PortForwardRule{}
+		if err := controller.Get(ctx, request.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		return updated, result
+	}
+	// probe advances to the next probe and reconciles
+	probe := func() *v1alpha1.PortForwardRule {
+		t.Helper()
+		clock.Advance(10 * time.Second)
+		updated, _ := reconcile()
+		return updated
+	}
+	assertDestination := func(updated *v1alpha1.PortForwardRule, want string) {
+		t.Helper()
+		if updated.Status.Failover == nil || updated.Status.Failover.ActiveDestination != want {
+			t.Errorf("Expected active destination %s, got %+v", want, updated.Status.Failover)
+		}
+		routerRule := mockRouter.GetPortForwardRuleByName("default/test-rule:1194")
+		if routerRule == nil || routerRule.Fwd != want {
+			t.Errorf("Expected the router rule to forward to %s, got %+v", want, routerRule)
+		}
+	}
+	assertEvent := func(reason string) {
+		t.Helper()
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, reason) {
+				return
+			}
+		}
+		t.Errorf("Expected a %s event", reason)
+	}
+
+	// The highest priority destination is preferred over list order
+	updated, result := reconcile()
+	assertDestination(updated, "192.168.1.20")
+	if result.RequeueAfter != 11*time.Second {
+		t.Errorf("Expected a requeue right after the next probe, got %s", result.RequeueAfter)
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeDestinationHealthy); condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("Expected DestinationHealthy condition True, got %+v", condition)
+	}
+	desired, err := buildRuleRouterConfigs(ctx, controller.Client, updated, clock.Now())
+	if err != nil {
+		t.Fatalf("Failed to build router configs: %v", err)
+	}
+	approvedHash := ruleExposureHash(updated, desired)
+
+	// The primary stays active until it fails the failure threshold in a row
+	prober.setDown("192.168.1.20", true)
+	assertDestination(probe(), "192.168.1.20")
+	assertDestination(probe(), "192.168.1.20")
+	updated = probe()
+	assertDestination(updated, "192.168.1.21")
+	assertEvent("DestinationFailover")
+	if history := updated.Status.Failover.History; len(history) != 1 || history[0].Reason != v1alpha1.FailoverReasonFailover {
+		t.Errorf("Expected a Failover history entry, got %+v", history)
+	}
+	desired, err = buildRuleRouterConfigs(ctx, controller.Client, updated, clock.Now())
+	if err != nil {
+		t.Fatalf("Failed to build router configs: %v", err)
+	}
+	if hash := ruleExposureHash(updated, desired); hash != approvedHash {
+		t.Errorf("Expected failing over to keep the exposure hash %s, got %s", approvedHash, hash)
+	}
+
+	// The primary takes over again once it passes the success threshold in a row
+	prober.setDown("192.168.1.20", false)
+	assertDestination(probe(), "192.168.1.21")
+	updated = probe()
+	assertDestination(updated, "192.168.1.20")
+	assertEvent("DestinationFailback")
+	history := updated.Status.Failover.History
+	if len(history) != 2 || history[0].Reason != v1alpha1.FailoverReasonFailback || history[0].To != "192.168.1.20" {
+		t.Errorf("Expected the Failback entry first in the history, got %+v", history)
+	}
+
+	// Without a healthy destination the active one is kept
+	prober.setDown("192.168.1.20", true)
+	prober.setDown("192.168.1.21", true)
+	probe()
+	probe()
+	updated = probe()
+	assertDestination(updated, "192.168.1.20")
+	assertEvent("NoHealthyDestination")
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, ConditionTypeDestinationHealthy); condition == nil || condition.Status != metav1.ConditionFalse {
+		t.Errorf("Expected DestinationHealthy condition False, got %+v", condition)
+	}
+}
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/routers"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ConditionTypeBackendReady reports whether the Service of a rule with readinessGate has ready
+// endpoints, and so whether the rule is enabled on the router
+const ConditionTypeBackendReady = "BackendReady"
+
+// readinessTracker debounces changes in the backend readiness of readiness-gated Services and
+// rules, so that a pod restart or a rolling update does not flap the router rules
+type readinessTracker struct {
+	mu       sync.Mutex
+	observed map[string]readinessObservation
+}
+
+// readinessObservation is a backend readiness that differs from the applied one
+type readinessObservation struct {
+	ready bool
+	since time.Time
+	until time.Time
+}
+
+func newReadinessTracker() *readinessTracker {
+	return &readinessTracker{observed: make(map[string]readinessObservation)}
+}
+
+// debounce returns the backend readiness to apply for key given the readiness applied so far
+// and the one observed at now. A change is applied once it has been observed for window;
+// until then the applied readiness is kept. Without an applied readiness, or without a
+// tracker, the observed readiness applies right away.
+func (t *readinessTracker) debounce(key string, applied *bool, ready bool, now time.Time, window time.Duration) bool {
+	if t == nil || applied == nil || *applied == ready || window <= 0 {
+		t.forget(key)
+		return ready
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	observation, exists := t.observed[key]
+	if !exists || observation.ready != ready {
+		observation = readinessObservation{ready: ready, since: now, until: now.Add(window)}
+		t.observed[key] = observation
+	}
+	if now.Before(observation.until) {
+		return *applied
+	}
+
+	delete(t.observed, key)
+	return ready
+}
+
+// pending returns when the readiness change observed for key is applied, or the zero time
+// when no change is pending
+func (t *readinessTracker) pending(key string) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.observed[key].until
+}
+
+// forget drops a pending readiness change for key
+func (t *readinessTracker) forget(key string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.observed, key)
+}
+
+// readyEndpoints counts the ready endpoints across the EndpointSlices of a Service. Endpoints
+// without a ready condition count as ready, as the EndpointSlice API specifies.
+func readyEndpoints(ctx context.Context, c client.Client, service types.NamespacedName) (int, error) {
+	var slices discoveryv1.EndpointSliceList
+	if err := c.List(ctx, &slices, client.InNamespace(service.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: service.Name}); err != nil {
+		return 0, fmt.Errorf("failed to list endpoint slices of service %s: %w", service, err)
+	}
+
+	count := 0
+	for i := range slices.Items {
+		for _, endpoint := range slices.Items[i].Endpoints {
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// endpointSliceService returns the Service an EndpointSlice belongs to
+func endpointSliceService(obj client.Object) (types.NamespacedName, bool) {
+	name := obj.GetLabels()[discoveryv1.LabelServiceName]
+	if name == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}, true
+}
+
+// serviceReadinessGated reports whether the port forwards of a service follow the readiness of
+// its endpoints
+func serviceReadinessGated(service *corev1.Service) bool {
+	return service.Annotations[config.ReadinessGateAnnotation] == "true"
+}
+
+// applyServiceReadiness disables the port configs of a readiness-gated service whose backend
+// was last recorded as not ready
+func applyServiceReadiness(service *corev1.Service, configs []routers.PortConfig) {
+	if !serviceReadinessGated(service) || service.Annotations[config.BackendReadyAnnotation] != "false" {
+		return
+	}
+	for i := range configs {
+		configs[i].Enabled = false
+	}
+}
+
+// readinessDebounce returns how long a readiness change must hold before it is applied
+func (r *PortForwardReconciler) readinessDebounce() time.Duration {
+	if r.Config == nil {
+		return 0
+	}
+	return r.Config.ReadinessDebounce
+}
+
+// updateServiceReadiness records the debounced backend readiness of a readiness-gated service
+// in its backend-ready annotation, which calculateDesiredState turns into enabled or disabled
+// port forwards, and emits an event when the backend became unavailable or available again
+func (r *PortForwardReconciler) updateServiceReadiness(ctx context.Context, service *corev1.Service) error {
+	key := client.ObjectKeyFromObject(service)
+	previous, recorded := service.Annotations[config.BackendReadyAnnotation]
+
+	if !serviceReadinessGated(service) {
+		r.readiness.forget(key.String())
+		if !recorded {
+			return nil
+		}
+		patch := client.MergeFrom(service.DeepCopy())
+		delete(service.Annotations, config.BackendReadyAnnotation)
+		if err := r.Patch(ctx, service, patch); err != nil {
+			return fmt.Errorf("failed to clear backend readiness on service %s: %w", key, err)
+		}
+		return nil
+	}
+
+	count, err := readyEndpoints(ctx, r.Client, key)
+	if err != nil {
+		return err
+	}
+
+	var applied *bool
+	if recorded {
+		wasReady := previous == "true"
+		applied = &wasReady
+	}
+	ready := r.readiness.debounce(key.String(), applied, count > 0, r.now(), r.readinessDebounce())
+	if applied != nil && *applied == ready {
+		return nil
+	}
+
+	if r.Recorder != nil {
+		if !ready {
+			r.Recorder.Event(service, corev1.EventTypeWarning, "BackendUnavailable",
+				"Service has no ready endpoints, port forwards disabled on the router")
+		} else if applied != nil {
+			r.Recorder.Event(service, corev1.EventTypeNormal, "BackendAvailable",
+				"Service has ready endpoints again, port forwards enabled on the router")
+		}
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	service.Annotations[config.BackendReadyAnnotation] = strconv.FormatBool(ready)
+	if err := r.Patch(ctx, service, patch); err != nil {
+		return fmt.Errorf("failed to record backend readiness on service %s: %w", key, err)
+	}
+	return nil
+}
+
+// mapEndpointSliceToService returns a reconcile request for the readiness-gated Service an
+// EndpointSlice belongs to
+func (r *PortForwardReconciler) mapEndpointSliceToService(ctx context.Context, obj client.Object) []reconcile.Request {
+	key, ok := endpointSliceService(obj)
+	if !ok {
+		return nil
+	}
+
+	service := &corev1.Service{}
+	if err := r.Get(ctx, key, service); err != nil || !serviceReadinessGated(service) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: key}}
+}
+
+// readinessDebounce returns how long a readiness change must hold before it is applied
+func (r *PortForwardRuleReconciler) readinessDebounce() time.Duration {
+	if r.Config == nil {
+		return 0
+	}
+	return r.Config.ReadinessDebounce
+}
+
+// handleReadiness records the debounced backend readiness of a rule with readinessGate in its
+// BackendReady condition, which buildRuleRouterConfigs turns into an enabled or disabled router
+// rule, and emits an event when the backend became unavailable or available again. The
+// condition is persisted with the next status update.
+func (r *PortForwardRuleReconciler) handleReadiness(ctx context.Context, rule v1alpha1.PortForwardRuleObject) error {
+	key := client.ObjectKeyFromObject(rule).String()
+	status := rule.GetRuleStatus()
+
+	service, ok := serviceRefKey(rule)
+	if !rule.GetRuleSpec().ReadinessGate || !ok {
+		r.readiness.forget(key)
+		meta.RemoveStatusCondition(&status.Conditions, ConditionTypeBackendReady)
+		return nil
+	}
+
+	count, err := readyEndpoints(ctx, r.Client, service)
+	if err != nil {
+		return err
+	}
+
+	var applied *bool
+	previous := meta.FindStatusCondition(status.Conditions, ConditionTypeBackendReady)
+	if previous != nil {
+		wasReady := previous.Status == metav1.ConditionTrue
+		applied = &wasReady
+	}
+	ready := r.readiness.debounce(key, applied, count > 0, r.now(), r.readinessDebounce())
+	if applied != nil && *applied == ready {
+		return nil
+	}
+
+	if !ready {
+		r.Recorder.Event(rule, corev1.EventTypeWarning, "BackendUnavailable",
+			fmt.Sprintf("Service %s has no ready endpoints, rule disabled on the router", service))
+		setRuleCondition(rule, ConditionTypeBackendReady, metav1.ConditionFalse, "NoReadyEndpoints",
+			fmt.Sprintf("Service %s has no ready endpoints", service))
+		return nil
+	}
+
+	if applied != nil {
+		r.Recorder.Event(rule, corev1.EventTypeNormal, "BackendAvailable",
+			fmt.Sprintf("Service %s has ready endpoints again, rule enabled on the router", service))
+	}
+	setRuleCondition(rule, ConditionTypeBackendReady, metav1.ConditionTrue, "EndpointsReady",
+		fmt.Sprintf("Service %s has ready endpoints", service))
+	return nil
+}
+
+// ruleBackendReady reports whether a rule may be enabled on the router given the backend
+// readiness recorded by handleReadiness. Rules without readinessGate are always ready.
+func ruleBackendReady(rule v1alpha1.PortForwardRuleObject) bool {
+	if !rule.GetRuleSpec().ReadinessGate {
+		return true
+	}
+	return !meta.IsStatusConditionFalse(rule.GetRuleStatus().Conditions, ConditionTypeBackendReady)
+}
+
+// mapEndpointSliceToRules returns reconcile requests for every PortForwardRule with
+// readinessGate referencing the Service an EndpointSlice belongs to
+func (r *PortForwardRuleReconciler) mapEndpointSliceToRules(ctx context.Context, obj client.Object) []reconcile.Request {
+	key, ok := endpointSliceService(obj)
+	if !ok {
+		return nil
+	}
+
+	var ruleList v1alpha1.PortForwardRuleList
+	if err := r.List(ctx, &ruleList, client.MatchingFields{ServiceRefIndexKey: key.String()}); err != nil {
+		ctrllog.FromContext(ctx).Error(err, "Failed to list PortForwardRules referencing service", "service", key)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, rule := range ruleList.Items {
+		if rule.Spec.ReadinessGate {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// mapEndpointSliceToClusterRules returns reconcile requests for every ClusterPortForwardRule
+// with readinessGate referencing the Service an EndpointSlice belongs to
+func (r *ClusterPortForwardRuleReconciler) mapEndpointSliceToClusterRules(ctx context.Context, obj client.Object) []reconcile.Request {
+	key, ok := endpointSliceService(obj)
+	if !ok {
+		return nil
+	}
+
+	var ruleList v1alpha1.ClusterPortForwardRuleList
+	if err := r.List(ctx, &ruleList, client.MatchingFields{ServiceRefIndexKey: key.String()}); err != nil {
+		ctrllog.FromContext(ctx).Error(err, "Failed to list ClusterPortForwardRules referencing service", "service", key)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, rule := range ruleList.Items {
+		if rule.Spec.ReadinessGate {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: rule.Name}})
+		}
+	}
+	return requests
+}
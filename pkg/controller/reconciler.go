@@ -12,16 +12,20 @@ import (
 	"unifi-port-forward/pkg/config"
 	"unifi-port-forward/pkg/helpers"
 	"unifi-port-forward/pkg/routers"
+	"unifi-port-forward/testutils"
 
 	"github.com/filipowm/go-unifi/unifi"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -50,6 +54,20 @@ type PortForwardReconciler struct {
 
 	// Cleanup retry tracking
 	cleanupRetryCount map[string]int // serviceKey -> retry count
+
+	// readiness debounces the backend readiness of readiness-gated services
+	readiness *readinessTracker
+
+	// clock evaluates schedules; nil means the system clock
+	clock testutils.TimeProvider
+}
+
+// now returns the current time of the reconciler's clock
+func (r *PortForwardReconciler) now() time.Time {
+	if r.clock != nil {
+		return r.clock.Now()
+	}
+	return time.Now()
 }
 
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
@@ -183,6 +201,11 @@ func (r *PortForwardReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
+	// Record the backend readiness first so the desired state reflects it
+	if err := r.updateServiceReadiness(ctx, service); err != nil {
+		logger.Error(err, "Failed to update service backend readiness")
+	}
+
 	// Get current router state once per reconcile to ensure data consistency
 	allCurrentRules, err := r.Router.ListAllPortForwards(ctx)
 	if err != nil {
@@ -193,18 +216,8 @@ func (r *PortForwardReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Create change context for this reconciliation using fresh router state
 	changeContext := r.detectChanges(ctx, service, serviceKey, allCurrentRules)
 
-	// Filter rules for this specific service for logging
-	var currentRules []*unifi.PortForward
-	for _, rule := range allCurrentRules {
-		// Extract service key from rule name (format: "namespace/service-name:port-name")
-		parts := strings.SplitN(rule.Name, ":", 3)
-		if len(parts) >= 2 {
-			ruleServiceKey := parts[0] // parts[0] is "namespace/service-name"
-			if ruleServiceKey == serviceKey {
-				currentRules = append(currentRules, rule)
-			}
-		}
-	}
+	// Filter rules for this specific service
+	currentRules := serviceRouterRules(service, allCurrentRules)
 
 	// Log service vs router state differences for debugging (using filtered service-specific rules)
 	logServiceVsRouterStateDifferences(lbIP, currentRules, service.Name, service.Namespace)
@@ -229,6 +242,14 @@ func (r *PortForwardReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return result, err
 		}
 
+		if len(operations) > 0 {
+			if updatedRules, err := r.Router.ListAllPortForwards(ctx); err != nil {
+				logger.Error(err, "Failed to list port forwards for rule ID tracking")
+			} else if err := r.recordServiceRuleIDs(ctx, service, updatedRules); err != nil {
+				logger.Error(err, "Failed to record router rule IDs on service")
+			}
+		}
+
 		// Publish ownership-taking events
 		if r.EventPublisher != nil {
 			for _, op := range operations {
@@ -242,8 +263,53 @@ func (r *PortForwardReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
+	if err := r.recordServiceClusterReservations(ctx, service); err != nil {
+		logger.Error(err, "Failed to record service port conflicts")
+	}
+	if err := r.recordServiceExpiry(ctx, service, r.now()); err != nil {
+		logger.Error(err, "Failed to record service expiry")
+	}
+	if err := r.recordServiceApproval(ctx, service); err != nil {
+		logger.Error(err, "Failed to record service approval")
+	}
+	if err := r.recordServicePolicies(ctx, service); err != nil {
+		logger.Error(err, "Failed to record service policy violations")
+	}
+
 	logger.V(1).Info("No relevant changes detected")
-	return ctrl.Result{}, nil
+	return r.scheduleRequeue(ctx, service), nil
+}
+
+// scheduleRequeue requeues a service with a schedule or expiry annotation right after its next
+// schedule change, expiry warning or expiry
+func (r *PortForwardReconciler) scheduleRequeue(ctx context.Context, service *corev1.Service) ctrl.Result {
+	logger := ctrllog.FromContext(ctx)
+	now := r.now()
+	result := ctrl.Result{}
+
+	_, next, err := serviceScheduleState(service, now)
+	if err != nil {
+		logger.Error(err, "Failed to evaluate service schedule")
+	} else if !next.IsZero() {
+		logger.V(1).Info("Service schedule changes next", "next_change", next)
+		result = requeueAt(result, next, now)
+	}
+
+	expiresAt, err := serviceExpiry(service)
+	if err != nil {
+		logger.Error(err, "Failed to evaluate service expiry")
+	} else if !expiresAt.IsZero() && now.Before(expiresAt) {
+		if r.Config != nil {
+			if warnAt := expiresAt.Add(-r.Config.ExpiryWarning); warnAt.After(now) {
+				result = requeueAt(result, warnAt, now)
+			}
+		}
+		result = requeueAt(result, expiresAt, now)
+	}
+
+	// A pending backend readiness change is applied once its debounce has passed
+	result = requeueAt(result, r.readiness.pending(client.ObjectKeyFromObject(service).String()), now)
+	return result
 }
 
 // shouldProcessService checks if a service needs port forwarding processing
@@ -279,7 +345,7 @@ func (r *PortForwardReconciler) shouldProcessService(ctx context.Context, servic
 func (r *PortForwardReconciler) processAllChanges(ctx context.Context, service *corev1.Service, changeContext *ChangeContext, currentRules []*unifi.PortForward) ([]PortOperation, ctrl.Result, error) {
 	logger := ctrllog.FromContext(ctx)
 	// Step 1: Determine desired end state
-	desiredConfigs, err := r.calculateDesiredState(service)
+	desiredConfigs, err := r.calculateDesiredState(ctx, service)
 	if err != nil {
 		logger.Error(err, "calculating desired state while processing all changes")
 
@@ -418,9 +484,61 @@ func (r *PortForwardReconciler) finalizeService(ctx context.Context, service *co
 	}
 
 	// Generate DELETE operations for rules belonging to this service
+	operations := serviceCleanupOperations(service, currentRules)
+
+	// Execute cleanup operations with proper logging and rollback
+	result, err := r.executeCleanupOperations(ctx, operations, "ServiceCleanup")
+	if err != nil {
+		logger.Error(err, "cleanup operations failed",
+			"service", fmt.Sprintf("%s/%s", service.Namespace, service.Name),
+			"operations_planned", len(operations))
+		return err // Return error to block finalizer removal
+	}
+
+	// Publish deletion events for successfully removed ports
+	if r.EventPublisher != nil {
+		for _, deletedConfig := range result.Deleted {
+			portName := helpers.GetPortNameByNumber(service, deletedConfig.FwdPort)
+			r.EventPublisher.PublishPortForwardDeletedEvent(ctx, service,
+				portName, fmt.Sprintf("%d:%d", deletedConfig.DstPort, deletedConfig.FwdPort),
+				deletedConfig.DstPort, deletedConfig.Protocol, "ServiceCleanup")
+		}
+	}
+
+	logger.V(1).Info("service cleanup completed successfully",
+		"service", fmt.Sprintf("%s/%s", service.Namespace, service.Name),
+		"rules_removed", len(result.Deleted))
+
+	return nil
+}
+
+// serviceRouterRules returns the router rules of a service: the rules named after it and rules
+// renamed on the router that are still tracked through the rule-ids annotation
+func serviceRouterRules(service *corev1.Service, allRules []*unifi.PortForward) []*unifi.PortForward {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	recordedIDs := recordedRuleIDSet(service)
+
+	var rules []*unifi.PortForward
+	for _, rule := range allRules {
+		if rule.ID != "" && recordedIDs[rule.ID] {
+			rules = append(rules, rule)
+			continue
+		}
+		// Extract service key from rule name (format: "namespace/service-name:port-name")
+		parts := strings.SplitN(rule.Name, ":", 3)
+		if len(parts) >= 2 && parts[0] == serviceKey {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// serviceCleanupOperations returns the operations deleting every router rule of a service
+func serviceCleanupOperations(service *corev1.Service, allRules []*unifi.PortForward) []PortOperation {
+	recordedIDs := recordedRuleIDSet(service)
 	var operations []PortOperation
-	for _, rule := range currentRules {
-		if helpers.RuleBelongsToService(rule.Name, service.Namespace, service.Name) {
+	for _, rule := range allRules {
+		if helpers.RuleBelongsToService(rule.Name, service.Namespace, service.Name) || (rule.ID != "" && recordedIDs[rule.ID]) {
 			// Convert string ports to int for PortConfig
 			dstPort := 0
 			if rule.DstPort != "" {
@@ -454,30 +572,80 @@ func (r *PortForwardReconciler) finalizeService(ctx context.Context, service *co
 			})
 		}
 	}
+	return operations
+}
 
-	// Execute cleanup operations with proper logging and rollback
-	result, err := r.executeCleanupOperations(ctx, operations, "ServiceCleanup")
-	if err != nil {
-		logger.Error(err, "cleanup operations failed",
-			"service", fmt.Sprintf("%s/%s", service.Namespace, service.Name),
-			"operations_planned", len(operations))
-		return err // Return error to block finalizer removal
+// recordedRuleIDSet returns the router rule IDs recorded in the service's rule-ids annotation
+func recordedRuleIDSet(service *corev1.Service) map[string]bool {
+	idSet := make(map[string]bool)
+	for _, id := range helpers.GetRuleIDs(service, config.RuleIDsAnnotation) {
+		idSet[id] = true
 	}
+	return idSet
+}
 
-	// Publish deletion events for successfully removed ports
-	if r.EventPublisher != nil {
-		for _, deletedConfig := range result.Deleted {
-			portName := helpers.GetPortNameByNumber(service, deletedConfig.FwdPort)
-			r.EventPublisher.PublishPortForwardDeletedEvent(ctx, service,
-				portName, fmt.Sprintf("%d:%d", deletedConfig.DstPort, deletedConfig.FwdPort),
-				deletedConfig.DstPort, deletedConfig.Protocol, "ServiceCleanup")
+// recordServiceRuleIDs stores the router IDs of the service's rules in the rule-ids annotation,
+// so the rules can still be found by ID if they are renamed on the router. Recorded IDs are kept
+// while their rules exist on the router, whatever they are named now.
+func (r *PortForwardReconciler) recordServiceRuleIDs(ctx context.Context, service *corev1.Service, allRules []*unifi.PortForward) error {
+	ruleIDs := make(map[string]string)
+	routerRules := make(map[string]*unifi.PortForward)
+	recordedIDs := make(map[string]bool)
+	for _, rule := range allRules {
+		if rule.ID == "" {
+			continue
+		}
+		routerRules[rule.ID] = rule
+		if helpers.RuleBelongsToService(rule.Name, service.Namespace, service.Name) {
+			ruleIDs[rule.Name] = rule.ID
+			recordedIDs[rule.ID] = true
 		}
 	}
 
-	logger.V(1).Info("service cleanup completed successfully",
-		"service", fmt.Sprintf("%s/%s", service.Namespace, service.Name),
-		"rules_removed", len(result.Deleted))
+	// Renamed rules keep the name they were recorded under, unless another rule has taken it
+	for name, id := range helpers.GetRuleIDs(service, config.RuleIDsAnnotation) {
+		rule, exists := routerRules[id]
+		if !exists || recordedIDs[id] {
+			continue
+		}
+		if _, taken := ruleIDs[name]; taken {
+			name = rule.Name
+		}
+		if _, taken := ruleIDs[name]; taken {
+			name = id
+		}
+		ruleIDs[name] = id
+		recordedIDs[id] = true
+	}
+
+	current, hasAnnotation := service.Annotations[config.RuleIDsAnnotation]
+	desired := helpers.FormatRuleIDs(ruleIDs)
+	if len(ruleIDs) == 0 {
+		if !hasAnnotation {
+			return nil
+		}
+		desired = ""
+	} else if hasAnnotation && current == desired {
+		return nil
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if desired == "" {
+		delete(service.Annotations, config.RuleIDsAnnotation)
+	} else {
+		if service.Annotations == nil {
+			service.Annotations = make(map[string]string)
+		}
+		service.Annotations[config.RuleIDsAnnotation] = desired
+	}
 
+	if err := r.Patch(ctx, service, patch); err != nil {
+		return fmt.Errorf("failed to record rule IDs on service %s/%s: %w", service.Namespace, service.Name, err)
+	}
+
+	ctrllog.FromContext(ctx).V(1).Info("Recorded router rule IDs on service",
+		"service", fmt.Sprintf("%s/%s", service.Namespace, service.Name),
+		"rule_ids", desired)
 	return nil
 }
 
@@ -523,7 +691,7 @@ func (r *PortForwardReconciler) detectChanges(ctx context.Context, service *core
 	}
 
 	// Calculate desired state for optimization comparison
-	desiredConfigs, err := r.calculateDesiredState(service)
+	desiredConfigs, err := r.calculateDesiredState(ctx, service)
 	if err != nil {
 		// Log error but don't fail - fall back to IP-based detection
 		ctrllog.FromContext(ctx).Error(err, "Failed to calculate desired state for optimization")
@@ -790,11 +958,14 @@ func (r *PortForwardReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Initialize cleanup retry tracking
 	r.cleanupRetryCount = make(map[string]int)
 
+	r.readiness = newReadinessTracker()
+
 	eventFilter := ServiceChangePredicate{}
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Service{}).
-		WithEventFilter(eventFilter).
+		For(&corev1.Service{}, builder.WithPredicates(eventFilter)).
+		// Readiness-gated services follow the ready endpoints of their EndpointSlices
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(r.mapEndpointSliceToService)).
 		Named("port-forward-controller").
 		Complete(r)
 }
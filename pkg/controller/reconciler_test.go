@@ -3,11 +3,18 @@ package controller
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/approval"
 	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/routers"
+	"unifi-port-forward/testutils"
 
+	"github.com/filipowm/go-unifi/unifi"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -336,8 +343,8 @@ func TestReconcile_FailedCleanup(t *testing.T) {
 	// Verify rule exists
 	env.AssertRuleExistsByName(t, "default/cleanup-test:http")
 
-	// Enable simulated failure for RemovePort operation
-	env.MockRouter.SetSimulatedFailure("RemovePort", true)
+	// Enable simulated failure for DeletePortForwardByID operation
+	env.MockRouter.SetSimulatedFailure("DeletePortForwardByID", true)
 
 	// Delete service
 	if err := env.DeleteServiceByName(ctx, "default", "cleanup-test"); err != nil {
@@ -347,8 +354,8 @@ func TestReconcile_FailedCleanup(t *testing.T) {
 	// Reset operation counts to ensure clean state
 	env.MockRouter.ResetOperationCounts()
 
-	// Enable simulated failure for RemovePort operation
-	env.MockRouter.SetSimulatedFailure("RemovePort", true)
+	// Enable simulated failure for DeletePortForwardByID operation
+	env.MockRouter.SetSimulatedFailure("DeletePortForwardByID", true)
 
 	// Reconcile deletion - should fail due to simulated failure
 	firstResult, err := env.Controller.Reconcile(ctx, ctrl.Request{
@@ -369,14 +376,14 @@ func TestReconcile_FailedCleanup(t *testing.T) {
 		t.Errorf("Expected no Requeue for missing service cleanup, got result: %+v", firstResult)
 	}
 
-	// Verify RemovePort was attempted
+	// Verify DeletePortForwardByID was attempted
 	ops := env.MockRouter.GetOperationCounts()
-	if count, exists := ops["RemovePort"]; !exists || count == 0 {
-		t.Errorf("Expected RemovePort to be attempted during cleanup, got: %v", ops)
+	if count, exists := ops["DeletePortForwardByID"]; !exists || count == 0 {
+		t.Errorf("Expected DeletePortForwardByID to be attempted during cleanup, got: %v", ops)
 	}
 
 	// Disable simulated failure
-	env.MockRouter.SetSimulatedFailure("RemovePort", false)
+	env.MockRouter.SetSimulatedFailure("DeletePortForwardByID", false)
 
 	// Reconcile again - should succeed
 	var result ctrl.Result
@@ -2601,3 +2608,490 @@ func TestReconcile_ControllerRestartExistingRules(t *testing.T) {
 
 	t.Log("✅ Controller restart with existing rules test passed")
 }
+
+func TestRecordServiceRuleIDs_WritesAnnotation(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	ctx := context.Background()
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+	}
+	if err := env.FakeClient.Create(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	allRules := []*unifi.PortForward{
+		{ID: "id-http", Name: "default/web:http"},
+		{ID: "id-other", Name: "default/other:http"},
+	}
+	if err := env.Controller.recordServiceRuleIDs(ctx, service, allRules); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updated := &corev1.Service{}
+	if err := env.FakeClient.Get(ctx, types.NamespacedName{Name: "web", Namespace: "default"}, updated); err != nil {
+		t.Fatalf("Failed to get updated service: %v", err)
+	}
+	ruleIDs := helpers.GetRuleIDs(updated, config.RuleIDsAnnotation)
+	if len(ruleIDs) != 1 || ruleIDs["default/web:http"] != "id-http" {
+		t.Errorf("Expected only the service's rule ID to be recorded, got %v", ruleIDs)
+	}
+}
+
+func TestFinalizeService_RemovesRenamedRuleByRecordedID(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	// Rule was renamed on the router, so only its recorded ID ties it to the service
+	env.MockRouter.AddPortForwardRule(unifi.PortForward{ID: "id-http", Name: "renamed by admin", DstPort: "8080", Proto: "tcp"})
+	env.MockRouter.AddPortForwardRule(unifi.PortForward{ID: "id-manual", Name: "manual", DstPort: "9090", Proto: "tcp"})
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			Annotations: map[string]string{
+				config.RuleIDsAnnotation: helpers.FormatRuleIDs(map[string]string{"default/web:http": "id-http"}),
+			},
+		},
+	}
+
+	if err := env.Controller.finalizeService(context.Background(), service); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	rules := env.MockRouter.GetPortForwardRules()
+	if len(rules) != 1 || rules[0].ID != "id-manual" {
+		t.Errorf("Expected only the manual rule to remain, got %+v", rules)
+	}
+}
+
+func TestRecordServiceRuleIDs_KeepsRenamedRules(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	ctx := context.Background()
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			Annotations: map[string]string{
+				config.RuleIDsAnnotation: helpers.FormatRuleIDs(map[string]string{
+					"default/web:http":  "id-http",
+					"default/web:https": "id-https",
+					"default/web:gone":  "id-gone",
+				}),
+			},
+		},
+	}
+	if err := env.FakeClient.Create(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	// id-http was renamed on the router, id-gone was removed and a new rule took the https name
+	allRules := []*unifi.PortForward{
+		{ID: "id-http", Name: "renamed by admin"},
+		{ID: "id-https", Name: "also renamed"},
+		{ID: "id-https-new", Name: "default/web:https"},
+		{ID: "id-other", Name: "default/other:http"},
+	}
+	if err := env.Controller.recordServiceRuleIDs(ctx, service, allRules); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updated := &corev1.Service{}
+	if err := env.FakeClient.Get(ctx, types.NamespacedName{Name: "web", Namespace: "default"}, updated); err != nil {
+		t.Fatalf("Failed to get updated service: %v", err)
+	}
+	expected := map[string]string{
+		"default/web:http":  "id-http",
+		"default/web:https": "id-https-new",
+		"also renamed":      "id-https",
+	}
+	if ruleIDs := helpers.GetRuleIDs(updated, config.RuleIDsAnnotation); !reflect.DeepEqual(ruleIDs, expected) {
+		t.Errorf("Expected rule IDs %v, got %v", expected, ruleIDs)
+	}
+}
+
+func TestReconcile_ServiceExpiry(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+	env.Controller.clock = env.Clock
+	env.Controller.Config.ExpiryWarning = time.Hour
+
+	ctx := context.Background()
+	expiresAt := env.Clock.Now().Add(2 * time.Hour)
+	service := env.CreateTestService("default", "debug", map[string]string{
+		config.FilterAnnotation:    "2222:ssh",
+		config.ExpiresAtAnnotation: expiresAt.Format(time.RFC3339),
+	}, []corev1.ServicePort{{Name: "ssh", Port: 22, Protocol: corev1.ProtocolTCP}}, "192.168.1.100")
+	service.Finalizers = []string{config.FinalizerLabel}
+	if err := env.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "debug", Namespace: "default"}}
+	reconcile := func() *corev1.Service {
+		t.Helper()
+		if _, err := env.Controller.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &corev1.Service{}
+		if err := env.FakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get service: %v", err)
+		}
+		return updated
+	}
+
+	updated := reconcile()
+	if len(env.MockRouter.GetPortForwardRules()) != 1 {
+		t.Fatalf("Expected one router rule before expiry, got %+v", env.MockRouter.GetPortForwardRules())
+	}
+	if status, ok := updated.Annotations[config.ExpiryStatusAnnotation]; ok {
+		t.Errorf("Expected no expiry status before the warning, got %q", status)
+	}
+
+	env.Clock.Advance(90 * time.Minute)
+	updated = reconcile()
+	if status := updated.Annotations[config.ExpiryStatusAnnotation]; status != ExpiryStatusExpiringSoon {
+		t.Errorf("Expected expiry status %q, got %q", ExpiryStatusExpiringSoon, status)
+	}
+
+	env.Clock.Advance(30 * time.Minute)
+	updated = reconcile()
+	if len(env.MockRouter.GetPortForwardRules()) != 0 {
+		t.Errorf("Expected router rules to be removed on expiry, got %+v", env.MockRouter.GetPortForwardRules())
+	}
+	if status := updated.Annotations[config.ExpiryStatusAnnotation]; status != ExpiryStatusExpired {
+		t.Errorf("Expected expiry status %q, got %q", ExpiryStatusExpired, status)
+	}
+}
+
+func TestReconcile_ServiceApproval(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+	env.Controller.Config.RequireApproval = true
+	env.Controller.Config.ApprovalSigningKey = testApprovalKey
+
+	ctx := context.Background()
+	service := env.CreateTestService("default", "game", map[string]string{config.FilterAnnotation: "27015:game"},
+		[]corev1.ServicePort{{Name: "game", Port: 27015, Protocol: corev1.ProtocolTCP}}, "192.168.1.100")
+	service.Finalizers = []string{config.FinalizerLabel}
+	if err := env.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "game", Namespace: "default"}}
+	reconcile := func() *corev1.Service {
+		t.Helper()
+		if _, err := env.Controller.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &corev1.Service{}
+		if err := env.FakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get service: %v", err)
+		}
+		return updated
+	}
+
+	updated := reconcile()
+	if len(env.MockRouter.GetPortForwardRules()) != 0 {
+		t.Fatalf("Expected no router rules before approval, got %+v", env.MockRouter.GetPortForwardRules())
+	}
+	hash := updated.Annotations[config.PendingApprovalAnnotation]
+	if hash == "" {
+		t.Fatal("Expected the pending-approval annotation to hold the exposure hash")
+	}
+
+	// The bare exposure hash is what reaches the controller when the webhook is bypassed
+	updated.Annotations[config.ApprovedAnnotation] = hash
+	if err := env.UpdateService(ctx, updated); err != nil {
+		t.Fatalf("Failed to annotate service: %v", err)
+	}
+	updated = reconcile()
+	if len(env.MockRouter.GetPortForwardRules()) != 0 {
+		t.Fatalf("Expected no router rules for an unsigned approval, got %+v", env.MockRouter.GetPortForwardRules())
+	}
+
+	updated.Annotations[config.ApprovedAnnotation] = approval.Sign([]byte(testApprovalKey), "Service", "default", "game", hash)
+	if err := env.UpdateService(ctx, updated); err != nil {
+		t.Fatalf("Failed to approve service: %v", err)
+	}
+	updated = reconcile()
+	if len(env.MockRouter.GetPortForwardRules()) != 1 {
+		t.Errorf("Expected one router rule after approval, got %+v", env.MockRouter.GetPortForwardRules())
+	}
+	if pending, ok := updated.Annotations[config.PendingApprovalAnnotation]; ok {
+		t.Errorf("Expected pending-approval annotation to be removed, got %q", pending)
+	}
+}
+
+func TestReconcile_ServiceApprovalHashesFilteredPorts(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+	env.Controller.Config.RequireApproval = true
+	env.Controller.Config.ApprovalSigningKey = testApprovalKey
+
+	// One of the two ports is reserved, so only the other one is ever forwarded and approved
+	env.FakeClient.ClusterRules["edge"] = &v1alpha1.ClusterPortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge"},
+		Spec:       v1alpha1.PortForwardRuleSpec{ExternalPort: 443, Protocol: "tcp"},
+	}
+
+	ctx := context.Background()
+	service := env.CreateTestService("default", "web", map[string]string{config.FilterAnnotation: "8080:http,443:https"},
+		[]corev1.ServicePort{
+			{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP},
+			{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP},
+		}, "192.168.1.100")
+	service.Finalizers = []string{config.FinalizerLabel}
+	if err := env.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}}
+	reconcile := func() *corev1.Service {
+		t.Helper()
+		if _, err := env.Controller.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &corev1.Service{}
+		if err := env.FakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get service: %v", err)
+		}
+		return updated
+	}
+
+	updated := reconcile()
+	hash := updated.Annotations[config.PendingApprovalAnnotation]
+	if hash == "" {
+		t.Fatal("Expected the pending-approval annotation to hold the exposure hash")
+	}
+
+	updated.Annotations[config.ApprovedAnnotation] = approval.Sign([]byte(testApprovalKey), "Service", "default", "web", hash)
+	if err := env.UpdateService(ctx, updated); err != nil {
+		t.Fatalf("Failed to approve service: %v", err)
+	}
+	updated = reconcile()
+	rules := env.MockRouter.GetPortForwardRules()
+	if len(rules) != 1 || rules[0].DstPort != "8080" {
+		t.Errorf("Expected only the unreserved port to be forwarded after approval, got %+v", rules)
+	}
+	if pending, ok := updated.Annotations[config.PendingApprovalAnnotation]; ok {
+		t.Errorf("Expected pending-approval annotation to be removed, got %q", pending)
+	}
+}
+
+func TestReconcile_ServicePolicyViolation(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	ctx := context.Background()
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "locked", Labels: map[string]string{"port-forwards": "locked"}}}
+	if err := env.FakeClient.Create(ctx, namespace); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	maxRules := int32(0)
+	policy := &v1alpha1.PortForwardPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "locked"},
+		Spec: v1alpha1.PortForwardPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"port-forwards": "locked"}},
+			MaxRules:          &maxRules,
+		},
+	}
+	if err := env.FakeClient.Create(ctx, policy); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	service := env.CreateTestService("locked", "game", map[string]string{config.FilterAnnotation: "27015:game"},
+		[]corev1.ServicePort{{Name: "game", Port: 27015, Protocol: corev1.ProtocolTCP}}, "192.168.1.100")
+	service.Finalizers = []string{config.FinalizerLabel}
+	if err := env.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "game", Namespace: "locked"}}
+	reconcile := func() *corev1.Service {
+		t.Helper()
+		if _, err := env.Controller.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &corev1.Service{}
+		if err := env.FakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get service: %v", err)
+		}
+		return updated
+	}
+
+	updated := reconcile()
+	if len(env.MockRouter.GetPortForwardRules()) != 0 {
+		t.Fatalf("Expected no router rules for a service violating a policy, got %+v", env.MockRouter.GetPortForwardRules())
+	}
+	if violation := updated.Annotations[config.PolicyViolationAnnotation]; !strings.Contains(violation, "PortForwardPolicy locked") {
+		t.Errorf("Expected the policy-violation annotation to name the policy, got %q", violation)
+	}
+
+	if err := env.FakeClient.Delete(ctx, policy); err != nil {
+		t.Fatalf("Failed to delete policy: %v", err)
+	}
+	updated = reconcile()
+	if len(env.MockRouter.GetPortForwardRules()) != 1 {
+		t.Errorf("Expected one router rule once the policy is removed, got %+v", env.MockRouter.GetPortForwardRules())
+	}
+	if violation, ok := updated.Annotations[config.PolicyViolationAnnotation]; ok {
+		t.Errorf("Expected policy-violation annotation to be removed, got %q", violation)
+	}
+}
+
+func TestReconcile_ServiceReadinessGate(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+	env.Controller.clock = env.Clock
+	env.Controller.Config.ReadinessDebounce = 30 * time.Second
+	env.Controller.readiness = newReadinessTracker()
+
+	ctx := context.Background()
+	service := env.CreateTestService("default", "game", map[string]string{
+		config.FilterAnnotation:        "27015:game",
+		config.ReadinessGateAnnotation: "true",
+	}, []corev1.ServicePort{{Name: "game", Port: 27015, Protocol: corev1.ProtocolTCP}}, "192.168.1.100")
+	service.Finalizers = []string{config.FinalizerLabel}
+	if err := env.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "game", Namespace: "default"}}
+	reconcile := func() (*corev1.Service, ctrl.Result) {
+		t.Helper()
+		result, err := env.Controller.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		updated := &corev1.Service{}
+		if err := env.FakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get service: %v", err)
+		}
+		return updated, result
+	}
+	assertEnabled := func(want bool) {
+		t.Helper()
+		rules := env.MockRouter.GetPortForwardRules()
+		if len(rules) != 1 {
+			t.Fatalf("Expected the router rule to be kept, got %+v", rules)
+		}
+		if rules[0].Enabled != want {
+			t.Errorf("Expected router rule enabled=%t, got %t", want, rules[0].Enabled)
+		}
+	}
+
+	// Without ready endpoints the port forward is created disabled right away
+	updated, _ := reconcile()
+	assertEnabled(false)
+	if ready := updated.Annotations[config.BackendReadyAnnotation]; ready != "false" {
+		t.Errorf("Expected backend-ready annotation false, got %q", ready)
+	}
+
+	// Recovered endpoints are only applied once they stay ready for the debounce
+	if err := env.FakeClient.Create(ctx, testutils.CreateTestEndpointSlice("game-abc", "default", "game", false, true)); err != nil {
+		t.Fatalf("Failed to create endpoint slice: %v", err)
+	}
+	_, result := reconcile()
+	assertEnabled(false)
+	if result.RequeueAfter != 31*time.Second {
+		t.Errorf("Expected a requeue right after the debounce, got %s", result.RequeueAfter)
+	}
+
+	env.Clock.Advance(31 * time.Second)
+	updated, _ = reconcile()
+	assertEnabled(true)
+	if ready := updated.Annotations[config.BackendReadyAnnotation]; ready != "true" {
+		t.Errorf("Expected backend-ready annotation true, got %q", ready)
+	}
+
+	// Turning the gate off clears the recorded readiness
+	updated.Annotations[config.ReadinessGateAnnotation] = "false"
+	if err := env.UpdateService(ctx, updated); err != nil {
+		t.Fatalf("Failed to update service: %v", err)
+	}
+	updated, _ = reconcile()
+	if ready, ok := updated.Annotations[config.BackendReadyAnnotation]; ok {
+		t.Errorf("Expected backend-ready annotation to be removed, got %q", ready)
+	}
+}
+
+func TestReconcile_ServiceDisabledPorts(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	defer env.Cleanup()
+
+	ctx := context.Background()
+	service := env.CreateTestService("default", "game", map[string]string{
+		config.FilterAnnotation:        "27015:game,27020:rcon",
+		config.DisabledPortsAnnotation: "27020",
+	}, []corev1.ServicePort{
+		{Name: "game", Port: 27015, Protocol: corev1.ProtocolTCP},
+		{Name: "rcon", Port: 27020, Protocol: corev1.ProtocolTCP},
+	}, "192.168.1.100")
+	service.Finalizers = []string{config.FinalizerLabel}
+	if err := env.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "game", Namespace: "default"}}
+	assertEnabled := func(want map[string]bool) {
+		t.Helper()
+		if _, err := env.Controller.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		rules := env.MockRouter.GetPortForwardRules()
+		if len(rules) != len(want) {
+			t.Fatalf("Expected %d router rules, got %+v", len(want), rules)
+		}
+		for _, rule := range rules {
+			if rule.Enabled != want[rule.DstPort] {
+				t.Errorf("Expected router rule for port %s enabled=%t, got %t", rule.DstPort, want[rule.DstPort], rule.Enabled)
+			}
+		}
+	}
+
+	// The disabled port is kept on the router, disabled, next to the enabled one
+	assertEnabled(map[string]bool{"27015": true, "27020": false})
+
+	// Removing it from the annotation enables it again
+	updated := &corev1.Service{}
+	if err := env.FakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	updated.Annotations[config.DisabledPortsAnnotation] = ""
+	if err := env.UpdateService(ctx, updated); err != nil {
+		t.Fatalf("Failed to update service: %v", err)
+	}
+	assertEnabled(map[string]bool{"27015": true, "27020": true})
+}
+
+func TestExposureHash(t *testing.T) {
+	game := routers.PortConfig{Name: "default/game:27015", DstPort: 27015, FwdPort: 27015, DstIP: "192.168.1.100", Protocol: "tcp_udp", Enabled: true}
+	rcon := routers.PortConfig{Name: "default/game:27020", DstPort: 27020, FwdPort: 27020, DstIP: "192.168.1.100", Protocol: "tcp", Enabled: true}
+	hash := exposureHash([]routers.PortConfig{game, rcon})
+
+	if exposureHash([]routers.PortConfig{rcon, game}) != hash {
+		t.Error("Expected exposure hash to not depend on port order")
+	}
+
+	disabled := game
+	disabled.Enabled = false
+	if exposureHash([]routers.PortConfig{disabled, rcon}) != hash {
+		t.Error("Expected exposure hash to ignore the enabled state")
+	}
+
+	moved := game
+	moved.DstIP = "192.168.1.200"
+	if exposureHash([]routers.PortConfig{moved, rcon}) == hash {
+		t.Error("Expected exposure hash to change with the destination")
+	}
+
+	rcon.DstPort = 27021
+	if exposureHash([]routers.PortConfig{game, rcon}) == hash {
+		t.Error("Expected exposure hash to change with the external port")
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/routers"
+	"unifi-port-forward/pkg/schedule"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// ruleScheduleState reports whether the rule's schedule enables it at now and when that next
+// changes. Rules without a schedule are always enabled and never change.
+func ruleScheduleState(rule v1alpha1.PortForwardRuleObject, now time.Time) (bool, time.Time, error) {
+	spec := rule.GetRuleSpec()
+	if spec.Schedule == nil {
+		return true, time.Time{}, nil
+	}
+
+	s, err := spec.Schedule.Parse()
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid schedule: %w", err)
+	}
+	open, next := s.State(now)
+	return open, next, nil
+}
+
+// serviceScheduleState reports whether the schedule annotation of the service enables its
+// port forwards at now and when that next changes
+func serviceScheduleState(service *corev1.Service, now time.Time) (bool, time.Time, error) {
+	value, exists := service.Annotations[config.ScheduleAnnotation]
+	if !exists {
+		return true, time.Time{}, nil
+	}
+
+	windows, err := schedule.ParseWindows(value)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid %s annotation: %w", config.ScheduleAnnotation, err)
+	}
+	s, err := schedule.New(service.Annotations[config.ScheduleTimeZoneAnnotation], windows)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid %s annotation: %w", config.ScheduleAnnotation, err)
+	}
+	open, next := s.State(now)
+	return open, next, nil
+}
+
+// applyServiceSchedule disables the port configs of a service outside its schedule windows
+func applyServiceSchedule(service *corev1.Service, configs []routers.PortConfig, now time.Time) error {
+	open, _, err := serviceScheduleState(service, now)
+	if err != nil {
+		return err
+	}
+	if !open {
+		for i := range configs {
+			configs[i].Enabled = false
+		}
+	}
+	return nil
+}
+
+// recordScheduleState records the schedule state of a rule in its status and emits an event
+// when the schedule opened or closed since the last reconciliation
+func recordScheduleState(rule v1alpha1.PortForwardRuleObject, recorder record.EventRecorder, open bool, next time.Time) {
+	status := rule.GetRuleStatus()
+	if rule.GetRuleSpec().Schedule == nil {
+		status.NextScheduleChange = nil
+		meta.RemoveStatusCondition(&status.Conditions, ConditionTypeScheduleOpen)
+		return
+	}
+
+	status.NextScheduleChange = nil
+	if !next.IsZero() {
+		status.NextScheduleChange = &metav1.Time{Time: next}
+	}
+
+	conditionStatus := metav1.ConditionFalse
+	reason := "WindowClosed"
+	message := "Rule is outside its schedule windows and disabled on the router"
+	if open {
+		conditionStatus = metav1.ConditionTrue
+		reason = "WindowOpen"
+		message = "Rule is inside a schedule window"
+	}
+	if !next.IsZero() {
+		message = fmt.Sprintf("%s until %s", message, next.UTC().Format(time.RFC3339))
+	}
+
+	previous := meta.FindStatusCondition(status.Conditions, ConditionTypeScheduleOpen)
+	if previous != nil && previous.Status != conditionStatus {
+		if open {
+			recorder.Event(rule, corev1.EventTypeNormal, "ScheduleWindowOpened", "Schedule window opened, rule enabled on the router")
+		} else {
+			recorder.Event(rule, corev1.EventTypeNormal, "ScheduleWindowClosed", "Schedule window closed, rule disabled on the router")
+		}
+	}
+
+	if previous == nil || previous.Status != conditionStatus || previous.Message != message {
+		setRuleCondition(rule, ConditionTypeScheduleOpen, conditionStatus, reason, message)
+	}
+}
+
+// requeueAt shortens the requeue of result so the object is reconciled right after at, such
+// as the next schedule change or the expiry of a rule
+func requeueAt(result ctrl.Result, at, now time.Time) ctrl.Result {
+	if at.IsZero() {
+		return result
+	}
+
+	// Land just past the boundary so the new state is in effect when it is evaluated
+	wait := at.Sub(now) + time.Second
+	if wait < time.Second {
+		wait = time.Second
+	}
+	if result.RequeueAfter == 0 || wait < result.RequeueAfter {
+		result.RequeueAfter = wait
+	}
+	return result
+}
@@ -0,0 +1,369 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+
+	"github.com/filipowm/go-unifi/unifi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Owner kinds of router rules that no Service or rule owns
+const (
+	// OwnerManual is a router rule made by hand, outside the controller's naming scheme
+	OwnerManual = "manual"
+
+	// OwnerOrphaned is a router rule named like a managed rule whose owner no longer exists
+	OwnerOrphaned = "orphaned"
+)
+
+// Drift states of router rules
+const (
+	DriftInSync  = "in-sync"
+	DriftDrifted = "drifted"
+
+	// DriftExtra is a rule its owner no longer wants; the controller deletes it
+	DriftExtra = "extra"
+
+	// DriftUnknown is a rule whose owner could not be analyzed, for example a Service without
+	// a LoadBalancer IP or a rule pending approval
+	DriftUnknown = "unknown"
+)
+
+// RouterRuleStatus is a router rule joined with the Service or rule that owns it
+type RouterRuleStatus struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	ExternalPort string `json:"externalPort"`
+	Protocol     string `json:"protocol"`
+	Destination  string `json:"destination"`
+	ForwardPort  string `json:"forwardPort"`
+	Enabled      bool   `json:"enabled"`
+	Interface    string `json:"interface,omitempty"`
+	Source       string `json:"source,omitempty"`
+
+	// OwnerKind is Service, PortForwardRule, ClusterPortForwardRule, manual or orphaned. Owner
+	// is the name of the owner, or for orphaned rules the name of the former owner.
+	OwnerKind string `json:"ownerKind"`
+	Owner     string `json:"owner,omitempty"`
+
+	// Drift is empty for manual and orphaned rules
+	Drift       string   `json:"drift,omitempty"`
+	DriftFields []string `json:"driftFields,omitempty"`
+
+	// LastApplied is when the owning rule was last applied; Services do not record it
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+}
+
+// StatusFilter selects router rule statuses. Empty fields match every rule.
+type StatusFilter struct {
+	// Ports are external ports
+	Ports []int
+
+	// OwnerKinds are owner kinds, matched case-insensitively
+	OwnerKinds []string
+
+	// Namespace is the namespace of the owner; it excludes manual, orphaned and cluster rules
+	Namespace string
+
+	// DriftedOnly selects rules that are drifted, extra or unknown
+	DriftedOnly bool
+}
+
+// Matches reports whether a router rule status passes the filter
+func (f StatusFilter) Matches(status RouterRuleStatus) bool {
+	if len(f.Ports) > 0 {
+		port := helpers.ParseIntField(status.ExternalPort)
+		found := false
+		for _, p := range f.Ports {
+			if p == port {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.OwnerKinds) > 0 {
+		found := false
+		for _, kind := range f.OwnerKinds {
+			if strings.EqualFold(kind, status.OwnerKind) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Namespace != "" {
+		if status.OwnerKind != "Service" && status.OwnerKind != "PortForwardRule" {
+			return false
+		}
+		if namespace, _, _ := strings.Cut(status.Owner, "/"); namespace != f.Namespace {
+			return false
+		}
+	}
+	if f.DriftedOnly && (status.Drift == "" || status.Drift == DriftInSync) {
+		return false
+	}
+	return true
+}
+
+// StatusReporter joins router rules with the Services and rules owning them. It reads
+// through Client and analyzes drift with the drift detector of the periodic reconciler.
+type StatusReporter struct {
+	client.Client
+	Config *config.Config
+}
+
+// ruleOwnerRef identifies the owner of a router rule
+type ruleOwnerRef struct {
+	kind string
+	name string
+}
+
+// Status returns the status of every router rule, ordered by external port and protocol
+func (s *StatusReporter) Status(ctx context.Context, routerRules []*unifi.PortForward) ([]RouterRuleStatus, error) {
+	var services corev1.ServiceList
+	if err := s.List(ctx, &services); err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	rules, err := listRuleObjects(ctx, s.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	ownership := &routerRuleOwnership{
+		owners: make(map[*unifi.PortForward]ruleOwnerRef),
+		drift:  make(map[*unifi.PortForward]routerRuleDrift),
+	}
+	lastApplied := make(map[ruleOwnerRef]*metav1.Time)
+
+	// Router rules recorded by ID are claimed before those matched by name, as the
+	// controllers find them
+	for i := range services.Items {
+		service := &services.Items[i]
+		recordedIDs := recordedRuleIDSet(service)
+		for _, routerRule := range routerRules {
+			if routerRule.ID != "" && recordedIDs[routerRule.ID] {
+				ownership.claim(routerRule, ruleOwnerRef{kind: "Service", name: client.ObjectKeyFromObject(service).String()})
+			}
+		}
+	}
+	for _, rule := range rules {
+		owner := ruleOwnerRef{kind: ruleKind(rule), name: ruleOwner(rule)}
+		lastApplied[owner] = rule.GetRuleStatus().LastAppliedTime
+		for _, portStatus := range previousPortStatuses(rule) {
+			for _, routerRule := range routerRules {
+				if isRouterRuleID(portStatus.RouterRuleID) && routerRule.ID == portStatus.RouterRuleID {
+					ownership.claim(routerRule, owner)
+				}
+			}
+		}
+	}
+	for i := range services.Items {
+		service := &services.Items[i]
+		for _, routerRule := range serviceRouterRules(service, routerRules) {
+			ownership.claim(routerRule, ruleOwnerRef{kind: "Service", name: client.ObjectKeyFromObject(service).String()})
+		}
+	}
+	for _, rule := range rules {
+		prefix := ruleRouterNamePrefix(rule)
+		for _, routerRule := range routerRules {
+			if strings.HasPrefix(routerRule.Name, prefix) {
+				ownership.claim(routerRule, ruleOwnerRef{kind: ruleKind(rule), name: ruleOwner(rule)})
+			}
+		}
+	}
+
+	detector := &DriftDetector{
+		Client:          s.Client,
+		RequireApproval: s.Config != nil && s.Config.RequireApproval,
+		ApprovalKey:     approvalKey(s.Config),
+	}
+
+	serviceDrift(ctx, detector, services.Items, routerRules, ownership)
+	if err := ruleDrift(ctx, detector, rules, routerRules, ownership); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]RouterRuleStatus, 0, len(routerRules))
+	for _, routerRule := range routerRules {
+		status := RouterRuleStatus{
+			ID:           routerRule.ID,
+			Name:         routerRule.Name,
+			ExternalPort: routerRule.DstPort,
+			Protocol:     routerRule.Proto,
+			Destination:  routerRule.Fwd,
+			ForwardPort:  routerRule.FwdPort,
+			Enabled:      routerRule.Enabled,
+			Interface:    routerRule.PfwdInterface,
+			Source:       routerRule.Src,
+		}
+
+		owner, owned := ownership.owners[routerRule]
+		switch {
+		case owned:
+			status.OwnerKind = owner.kind
+			status.Owner = owner.name
+			status.Drift = DriftUnknown
+			if state, analyzed := ownership.drift[routerRule]; analyzed {
+				status.Drift = state.state
+				status.DriftFields = state.fields
+			}
+			status.LastApplied = lastApplied[owner]
+		case helpers.IsManagedRule(routerRule.Name):
+			// The name still tells which Service or rule the router rule belonged to
+			status.OwnerKind = OwnerOrphaned
+			status.Owner = helpers.ExtractServiceKeyFromRuleName(routerRule.Name)
+		default:
+			status.OwnerKind = OwnerManual
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		a, b := helpers.ParseIntField(statuses[i].ExternalPort), helpers.ParseIntField(statuses[j].ExternalPort)
+		if a != b {
+			return a < b
+		}
+		return statuses[i].Protocol < statuses[j].Protocol
+	})
+	return statuses, nil
+}
+
+// serviceDrift records the drift of the router rules of every Service, following the
+// decisions of Reconcile: rules of deleted and unannotated Services are extra
+func serviceDrift(ctx context.Context, detector *DriftDetector, services []corev1.Service, routerRules []*unifi.PortForward, ownership *routerRuleOwnership) {
+	for i := range services {
+		service := &services[i]
+		owner := ruleOwnerRef{kind: "Service", name: client.ObjectKeyFromObject(service).String()}
+		_, annotated := service.Annotations[config.FilterAnnotation]
+
+		if !service.DeletionTimestamp.IsZero() || !annotated {
+			ownership.setOwnerDrift(owner, DriftExtra)
+			continue
+		}
+		if helpers.GetLBIP(service) == "" {
+			continue
+		}
+
+		analyses, err := detector.AnalyzeAllServicesDrift(ctx, []*corev1.Service{service}, routerRules)
+		if err != nil {
+			// Reported as unknown drift, as the Service controller reports it through events
+			continue
+		}
+		analysis := analyses[0]
+
+		for _, routerRule := range analysis.CurrentRules {
+			ownership.setDrift(routerRule, DriftInSync, nil)
+		}
+		for _, mismatch := range analysis.WrongRules {
+			// A rule of another owner on a desired port is taken over by the Service
+			ownership.claim(mismatch.Current, owner)
+			if ownership.owners[mismatch.Current] == owner {
+				ownership.setDrift(mismatch.Current, DriftDrifted, []string{mismatch.MismatchType})
+			}
+		}
+		for _, routerRule := range analysis.ExtraRules {
+			ownership.setDrift(routerRule, DriftExtra, nil)
+		}
+	}
+}
+
+// ruleDrift records the drift of the router rules of every PortForwardRule and
+// ClusterPortForwardRule. Rules of deleted and expired rules, and rules for ports a rule no
+// longer has, are extra.
+func ruleDrift(ctx context.Context, detector *DriftDetector, rules []v1alpha1.PortForwardRuleObject, routerRules []*unifi.PortForward, ownership *routerRuleOwnership) error {
+	now := time.Now()
+	var live []v1alpha1.PortForwardRuleObject
+	for _, rule := range rules {
+		expiresAt := ruleExpiry(rule)
+		if !rule.GetDeletionTimestamp().IsZero() || (!expiresAt.IsZero() && !now.Before(expiresAt)) {
+			owner := ruleOwnerRef{kind: ruleKind(rule), name: ruleOwner(rule)}
+			ownership.setOwnerDrift(owner, DriftExtra)
+			continue
+		}
+		live = append(live, rule)
+	}
+
+	analyses, err := detector.AnalyzeAllRulesDrift(ctx, live, routerRules)
+	if err != nil {
+		return fmt.Errorf("failed to analyze port forward rules: %w", err)
+	}
+
+	analyzed := make(map[ruleOwnerRef]bool)
+	current := make(map[*unifi.PortForward]bool)
+	for _, analysis := range analyses {
+		owner := ruleOwnerRef{kind: ruleKind(analysis.Rule), name: ruleOwner(analysis.Rule)}
+		analyzed[owner] = true
+		if analysis.Current == nil {
+			continue
+		}
+
+		// A rule of another owner on the port of the rule is taken over by it
+		ownership.claim(analysis.Current, owner)
+		if ownership.owners[analysis.Current] != owner {
+			continue
+		}
+		current[analysis.Current] = true
+		if analysis.HasDrift {
+			ownership.setDrift(analysis.Current, DriftDrifted, analysis.Mismatches)
+		} else {
+			ownership.setDrift(analysis.Current, DriftInSync, nil)
+		}
+	}
+
+	// The other router rules of analyzed rules belong to ports the rules no longer have
+	for routerRule, owner := range ownership.owners {
+		if analyzed[owner] && !current[routerRule] {
+			ownership.setDrift(routerRule, DriftExtra, nil)
+		}
+	}
+	return nil
+}
+
+// routerRuleOwnership tracks the owner and drift of router rules while statuses are built
+type routerRuleOwnership struct {
+	owners map[*unifi.PortForward]ruleOwnerRef
+	drift  map[*unifi.PortForward]routerRuleDrift
+}
+
+// routerRuleDrift is the drift state of a router rule and the drifted fields
+type routerRuleDrift struct {
+	state  string
+	fields []string
+}
+
+// claim makes owner the owner of a router rule nobody has claimed yet
+func (o *routerRuleOwnership) claim(routerRule *unifi.PortForward, owner ruleOwnerRef) {
+	if _, claimed := o.owners[routerRule]; !claimed {
+		o.owners[routerRule] = owner
+	}
+}
+
+// setDrift records the drift of a router rule
+func (o *routerRuleOwnership) setDrift(routerRule *unifi.PortForward, state string, fields []string) {
+	o.drift[routerRule] = routerRuleDrift{state: state, fields: fields}
+}
+
+// setOwnerDrift records the same drift for every router rule of owner
+func (o *routerRuleOwnership) setOwnerDrift(owner ruleOwnerRef, state string) {
+	for routerRule, ruleOwner := range o.owners {
+		if ruleOwner == owner {
+			o.setDrift(routerRule, state, nil)
+		}
+	}
+}
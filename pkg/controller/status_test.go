@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/manifests"
+
+	"github.com/filipowm/go-unifi/unifi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestStatusReporter_Status(t *testing.T) {
+	helpers.ClearPortConflictTracking()
+	defer helpers.ClearPortConflictTracking()
+
+	destIP := "192.168.1.50"
+	destPort := 8080
+	applied := metav1.NewTime(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+
+	objects := []client.Object{
+		createTestServiceWithLB("default", "web", map[string]string{
+			config.FilterAnnotation: "8080:http",
+		}, "192.168.1.100"),
+		func() client.Object {
+			service := createTestServiceWithLB("default", "retired", map[string]string{}, "192.168.1.102")
+			service.Finalizers = []string{config.FinalizerLabel}
+			return service
+		}(),
+		&v1alpha1.PortForwardRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps"},
+			Spec: v1alpha1.PortForwardRuleSpec{
+				ExternalPort:    6443,
+				Protocol:        "tcp",
+				DestinationIP:   &destIP,
+				DestinationPort: &destPort,
+				Enabled:         true,
+			},
+			Status: v1alpha1.PortForwardRuleStatus{LastAppliedTime: &applied, RouterRuleID: "3"},
+		},
+	}
+
+	routerRules := []*unifi.PortForward{
+		{ID: "1", Name: "default/web:http", DstPort: "8080", FwdPort: "8080", Fwd: "192.168.1.100", Proto: "tcp", Enabled: true},
+		{ID: "2", Name: "default/retired:http", DstPort: "5000", FwdPort: "5000", Fwd: "192.168.1.102", Proto: "tcp", Enabled: true},
+		// Renamed on the router, still found by the ID recorded in status
+		{ID: "3", Name: "api renamed", DstPort: "6443", FwdPort: "8080", Fwd: "192.168.1.60", Proto: "tcp", Enabled: true, PfwdInterface: "wan"},
+		{ID: "4", Name: "default/gone:http", DstPort: "9000", FwdPort: "9000", Fwd: "192.168.1.104", Proto: "tcp", Enabled: true},
+		{ID: "5", Name: "Printer", DstPort: "631", FwdPort: "631", Fwd: "192.168.1.25", Proto: "tcp", Enabled: false},
+	}
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := manifests.NewStore(scheme, objects...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reporter := &StatusReporter{Client: store, Config: &config.Config{}}
+	statuses, err := reporter.Status(context.Background(), routerRules)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+
+	type row struct {
+		id, ownerKind, owner, drift string
+	}
+	expected := []row{
+		{"5", OwnerManual, "", ""},
+		{"2", "Service", "default/retired", DriftExtra},
+		{"3", "PortForwardRule", "apps/api", DriftDrifted},
+		{"1", "Service", "default/web", DriftInSync},
+		{"4", OwnerOrphaned, "default/gone", ""},
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Status() returned %d rules, want %d: %+v", len(statuses), len(expected), statuses)
+	}
+	for i, want := range expected {
+		got := statuses[i]
+		if got.ID != want.id || got.OwnerKind != want.ownerKind || got.Owner != want.owner || got.Drift != want.drift {
+			t.Errorf("status %d = %s %s %q %s, want %s %s %q %s", i,
+				got.ID, got.OwnerKind, got.Owner, got.Drift, want.id, want.ownerKind, want.owner, want.drift)
+		}
+	}
+
+	api := statuses[2]
+	if !reflect.DeepEqual(api.DriftFields, []string{"name", "ip"}) {
+		t.Errorf("drift fields = %v, want name and ip", api.DriftFields)
+	}
+	if api.LastApplied == nil || !api.LastApplied.Equal(&applied) {
+		t.Errorf("LastApplied = %v, want %v", api.LastApplied, applied)
+	}
+}
+
+func TestStatusFilter_Matches(t *testing.T) {
+	status := RouterRuleStatus{ExternalPort: "8443", OwnerKind: "Service", Owner: "apps/web", Drift: DriftDrifted}
+
+	tests := []struct {
+		name   string
+		filter StatusFilter
+		want   bool
+	}{
+		{name: "empty", filter: StatusFilter{}, want: true},
+		{name: "port", filter: StatusFilter{Ports: []int{443, 8443}}, want: true},
+		{name: "other port", filter: StatusFilter{Ports: []int{443}}, want: false},
+		{name: "owner kind", filter: StatusFilter{OwnerKinds: []string{"service"}}, want: true},
+		{name: "other owner kind", filter: StatusFilter{OwnerKinds: []string{OwnerManual, OwnerOrphaned}}, want: false},
+		{name: "namespace", filter: StatusFilter{Namespace: "apps"}, want: true},
+		{name: "other namespace", filter: StatusFilter{Namespace: "default"}, want: false},
+		{name: "drifted", filter: StatusFilter{DriftedOnly: true}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(status); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if (StatusFilter{DriftedOnly: true}).Matches(RouterRuleStatus{OwnerKind: OwnerManual}) {
+		t.Error("Matches() selected a manual rule as drifted")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"unifi-port-forward/pkg/config"
 	"unifi-port-forward/pkg/helpers"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/filipowm/go-unifi/unifi"
 	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -58,6 +60,9 @@ func isSafeUpdate(mismatchType string) bool {
 		"ip":        true,
 		"enabled":   true,
 		"ownership": true,
+		"source":    true,
+		"interface": true,
+		"log":       true,
 	}
 	return safeTypes[mismatchType]
 }
@@ -273,12 +278,12 @@ func (r *PortForwardReconciler) executeOperations(ctx context.Context, operation
 				result.Created = append(result.Created, op.Config)
 			}
 		case OpUpdate:
-			err = r.Router.UpdatePort(ctx, op.Config.DstPort, op.Config)
+			err = r.updateRouterRule(ctx, op, op.Config)
 			if err == nil {
 				result.Updated = append(result.Updated, op.Config)
 			}
 		case OpDelete:
-			err = r.Router.RemovePort(ctx, op.Config)
+			err = r.removeRouterRule(ctx, op)
 			if err == nil {
 				result.Deleted = append(result.Deleted, op.Config)
 				// Clean up port tracking to free the port for reuse
@@ -317,6 +322,24 @@ func (r *PortForwardReconciler) executeOperations(ctx context.Context, operation
 	return result, nil
 }
 
+// updateRouterRule applies config to the router rule of an operation by its ID. Rules without a
+// recorded ID are looked up by port.
+func (r *PortForwardReconciler) updateRouterRule(ctx context.Context, op PortOperation, config routers.PortConfig) error {
+	if op.ExistingRule != nil && op.ExistingRule.ID != "" {
+		return r.Router.UpdatePortByID(ctx, op.ExistingRule.ID, config)
+	}
+	return r.Router.UpdatePort(ctx, op.Config.DstPort, config)
+}
+
+// removeRouterRule deletes the router rule of an operation by its ID. Rules without a recorded ID
+// are looked up by port.
+func (r *PortForwardReconciler) removeRouterRule(ctx context.Context, op PortOperation) error {
+	if op.ExistingRule != nil && op.ExistingRule.ID != "" {
+		return r.Router.DeletePortForwardByID(ctx, op.ExistingRule.ID)
+	}
+	return r.Router.RemovePort(ctx, op.Config)
+}
+
 // rollbackOperations attempts to rollback completed operations
 func (r *PortForwardReconciler) rollbackOperations(ctx context.Context, operations []PortOperation) error {
 	ctrllog.FromContext(ctx).Info("Rolling back completed operations",
@@ -344,7 +367,7 @@ func (r *PortForwardReconciler) rollbackOperations(ctx context.Context, operatio
 					Interface: op.ExistingRule.PfwdInterface,
 					SrcIP:     op.ExistingRule.Src,
 				}
-				err = r.Router.UpdatePort(ctx, op.Config.DstPort, rollbackConfig)
+				err = r.updateRouterRule(ctx, op, rollbackConfig)
 				// If UpdatePort fails with "not found", convert to CREATE instead
 				if err != nil && strings.Contains(err.Error(), "not found") {
 					// Try to create the rule instead of updating
@@ -400,7 +423,7 @@ func (r *PortForwardReconciler) executeCleanupOperations(ctx context.Context, op
 
 		switch op.Type {
 		case OpDelete:
-			err = r.Router.RemovePort(ctx, op.Config)
+			err = r.removeRouterRule(ctx, op)
 			if err == nil {
 				result.Deleted = append(result.Deleted, op.Config)
 				// Clean up port tracking to free the port for reuse
@@ -459,7 +482,7 @@ func (r *PortForwardReconciler) rollbackCleanupOperations(ctx context.Context, o
 }
 
 // calculateDesiredState generates the desired port configurations for a service
-func (r *PortForwardReconciler) calculateDesiredState(service *corev1.Service) ([]routers.PortConfig, error) {
+func (r *PortForwardReconciler) calculateDesiredState(ctx context.Context, service *corev1.Service) ([]routers.PortConfig, error) {
 	lbIP := helpers.GetLBIP(service)
 	if lbIP == "" {
 		return nil, fmt.Errorf("service has no LoadBalancer IP")
@@ -471,6 +494,47 @@ func (r *PortForwardReconciler) calculateDesiredState(service *corev1.Service) (
 		return nil, fmt.Errorf("failed to get port configurations: %w", err)
 	}
 
+	portConfigs, err = filterServicePortConfigs(ctx, r.Client, service, portConfigs, r.now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Until approved no port forwards are created
+	return applyServiceApproval(service, portConfigs, r.approvalRequired(), approvalKey(r.Config)), nil
+}
+
+// filterServicePortConfigs applies everything but approval to the port configs of a service:
+// its schedule, backend readiness, disabled ports, expiry, PortForwardPolicies and the ports
+// reserved by ClusterPortForwardRules. Approval is checked against the result.
+func filterServicePortConfigs(ctx context.Context, c client.Client, service *corev1.Service, portConfigs []routers.PortConfig, now time.Time) ([]routers.PortConfig, error) {
+	// Outside the schedule windows the port forwards stay on the router, disabled
+	if err := applyServiceSchedule(service, portConfigs, now); err != nil {
+		return nil, err
+	}
+
+	// Without ready endpoints the port forwards stay on the router, disabled
+	applyServiceReadiness(service, portConfigs)
+
+	// Ports disabled by hand stay on the router, disabled
+	if err := applyServiceDisabledPorts(service, portConfigs); err != nil {
+		return nil, err
+	}
+
+	// Once expired the port forwards are removed from the router
+	portConfigs, err := applyServiceExpiry(service, portConfigs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Port forwards violating a PortForwardPolicy are removed from the router
+	portConfigs, err = applyServicePolicies(ctx, c, service, portConfigs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ports reserved by ClusterPortForwardRules are left to them
+	portConfigs, _ = applyServiceClusterReservations(ctx, c, portConfigs)
+
 	return portConfigs, nil
 }
 
@@ -502,6 +566,15 @@ func (r *PortForwardReconciler) detectPortConflicts(currentRules []*unifi.PortFo
 			continue
 		}
 
+		// Router rules of ClusterPortForwardRules are never taken over
+		if isClusterRuleName(rule.Name) {
+			logger.Info("Skipping router rule owned by a ClusterPortForwardRule",
+				"existing_rule", rule.Name,
+				"service", service.Name,
+				"namespace", service.Namespace)
+			continue
+		}
+
 		// Check if this exact port configuration conflicts with our desired rules
 		// Use same key format as calculateDelta for consistency: dstPort-fwdPort-protocol
 		portKey := fmt.Sprintf("%d-%d-%s", dstPort, fwdPort, rule.Proto)
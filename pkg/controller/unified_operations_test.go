@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"testing"
 
 	"unifi-port-forward/pkg/config"
@@ -604,3 +605,49 @@ func TestPortConflicts_InternalPortOnly(t *testing.T) {
 		t.Errorf("Expected 0 conflict operations when only internal port matches, got %d", len(operations))
 	}
 }
+
+func TestExecuteOperations_TargetsRecordedRuleID(t *testing.T) {
+	env := NewControllerTestEnv(t)
+	ctx := context.Background()
+
+	// Two router rules share the external port, only the udp one belongs to the service
+	other := unifi.PortForward{ID: "other", Name: "manual-dns", DstPort: "53", FwdPort: "53", Fwd: "192.168.1.100", Proto: "tcp", Enabled: true}
+	owned := unifi.PortForward{ID: "owned", Name: "default/dns:53", DstPort: "53", FwdPort: "53", Fwd: "192.168.1.100", Proto: "udp", Enabled: true}
+	env.MockRouter.AddPortForwardRule(other)
+	env.MockRouter.AddPortForwardRule(owned)
+
+	updated := routers.PortConfig{Name: "default/dns:53", DstPort: 53, FwdPort: 53, DstIP: "192.168.1.100", Protocol: "udp", Enabled: false}
+	if _, err := env.Controller.executeOperations(ctx, []PortOperation{{Type: OpUpdate, Config: updated, ExistingRule: &owned}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rule := env.MockRouter.GetPortForwardRuleByName("default/dns:53"); rule == nil || rule.ID != "owned" || rule.Enabled {
+		t.Errorf("Expected rule owned to be disabled, got %+v", rule)
+	}
+	if rule := env.MockRouter.GetPortForwardRuleByName("manual-dns"); rule == nil || !rule.Enabled {
+		t.Errorf("Expected rule other to be untouched, got %+v", rule)
+	}
+
+	deleted := routers.PortConfig{Name: owned.Name, DstPort: 53, FwdPort: 53, DstIP: "192.168.1.100", Protocol: "udp"}
+	if _, err := env.Controller.executeOperations(ctx, []PortOperation{{Type: OpDelete, Config: deleted, ExistingRule: &owned}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rule := env.MockRouter.GetPortForwardRuleByName("default/dns:53"); rule != nil {
+		t.Errorf("Expected rule owned to be deleted, got %+v", rule)
+	}
+	if rule := env.MockRouter.GetPortForwardRuleByName("manual-dns"); rule == nil {
+		t.Error("Expected rule other to be kept")
+	}
+
+	ops := env.MockRouter.GetOperationCounts()
+	if ops["UpdatePort"] != 0 || ops["RemovePort"] != 0 {
+		t.Errorf("Expected no lookups by port for rules with a recorded ID, got %v", ops)
+	}
+
+	// Without a recorded ID the rule is looked up by port
+	if _, err := env.Controller.executeOperations(ctx, []PortOperation{{Type: OpDelete, Config: routers.PortConfig{DstPort: 53, DstIP: "192.168.1.100"}}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ops := env.MockRouter.GetOperationCounts(); ops["RemovePort"] != 1 {
+		t.Errorf("Expected a lookup by port, got %v", ops)
+	}
+}
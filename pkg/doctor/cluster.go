@@ -0,0 +1,240 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+
+	"github.com/filipowm/go-unifi/unifi"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the cluster checks
+const (
+	CheckPortForwardRuleCRD        = "PortForwardRule CRD"
+	CheckClusterPortForwardRuleCRD = "ClusterPortForwardRule CRD"
+	CheckRBAC                      = "RBAC"
+	CheckLoadBalancerIPs           = "LoadBalancer IPs"
+	CheckNameCollisions            = "naming collisions"
+)
+
+// maxListed bounds the objects named in a check message
+const maxListed = 5
+
+// Permission is an access the controller needs, checked cluster-wide
+type Permission struct {
+	Group       string
+	Resource    string
+	Subresource string
+	Verb        string
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	return p.Verb + " " + resource
+}
+
+// ControllerPermissions are the permissions of the controller's ClusterRole, manifests/rbac
+var ControllerPermissions = func() []Permission {
+	group := v1alpha1.SchemeGroupVersion.Group
+	var permissions []Permission
+	add := func(group, resource, subresource string, verbs ...string) {
+		for _, verb := range verbs {
+			permissions = append(permissions, Permission{Group: group, Resource: resource, Subresource: subresource, Verb: verb})
+		}
+	}
+	add("", "services", "", "get", "list", "watch", "update", "patch")
+	add("", "namespaces", "", "get", "list", "watch")
+	add("", "events", "", "create", "patch")
+	add("discovery.k8s.io", "endpointslices", "", "get", "list", "watch")
+	add(group, "portforwardrules", "", "get", "list", "watch", "update", "patch", "delete")
+	add(group, "portforwardrules", "status", "update", "patch")
+	add(group, "clusterportforwardrules", "", "get", "list", "watch", "update", "patch", "delete")
+	add(group, "clusterportforwardrules", "status", "update", "patch")
+	add(group, "portforwardpolicies", "", "get", "list", "watch")
+	add(group, "portforwardreferencegrants", "", "get", "list", "watch")
+	add("authorization.k8s.io", "subjectaccessreviews", "", "create")
+	return permissions
+}()
+
+// ClusterDoctor checks the cluster side of the controller
+type ClusterDoctor struct {
+	Client client.Client
+
+	// CRDAvailable reports whether the named CRD is installed and established
+	CRDAvailable func(ctx context.Context, crdName string) bool
+}
+
+// Run runs the cluster checks. routerRules are the rules on the router, nil when they could
+// not be listed.
+func (d *ClusterDoctor) Run(ctx context.Context, routerRules []*unifi.PortForward) []Check {
+	return []Check{
+		d.checkCRD(ctx, CheckPortForwardRuleCRD, config.PortForwardRulesCRDName),
+		d.checkCRD(ctx, CheckClusterPortForwardRuleCRD, config.ClusterPortForwardRulesCRDName),
+		d.checkRBAC(ctx),
+		d.checkLoadBalancerIPs(ctx),
+		d.checkNameCollisions(ctx, routerRules),
+	}
+}
+
+func (d *ClusterDoctor) checkCRD(ctx context.Context, name, crdName string) Check {
+	if !d.CRDAvailable(ctx, crdName) {
+		return warn(name, crdName+" not installed, its rules are disabled",
+			"Only needed for CRD based rules: kubectl apply -f manifests/crd")
+	}
+	return pass(name, crdName+" established")
+}
+
+// checkRBAC asks the API server whether the current user has each controller permission
+func (d *ClusterDoctor) checkRBAC(ctx context.Context) Check {
+	var missing []string
+	for _, permission := range ControllerPermissions {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Group:       permission.Group,
+					Resource:    permission.Resource,
+					Subresource: permission.Subresource,
+					Verb:        permission.Verb,
+				},
+			},
+		}
+		if err := d.Client.Create(ctx, review); err != nil {
+			return fail(CheckRBAC, fmt.Sprintf("failed to review access: %v", err),
+				"The API server must allow creating SelfSubjectAccessReviews, which every authenticated user may by default")
+		}
+		if !review.Status.Allowed {
+			missing = append(missing, permission.String())
+		}
+	}
+
+	if len(missing) > 0 {
+		return fail(CheckRBAC, "missing cluster-wide permissions: "+strings.Join(missing, ", "),
+			"Apply manifests/rbac and bind the ClusterRole to the controller's ServiceAccount. Permissions are checked for the kubeconfig user; run doctor in the controller pod to check its ServiceAccount.")
+	}
+	return pass(CheckRBAC, fmt.Sprintf("all %d controller permissions granted", len(ControllerPermissions)))
+}
+
+// checkLoadBalancerIPs finds annotated Services without a LoadBalancer IP to forward to
+func (d *ClusterDoctor) checkLoadBalancerIPs(ctx context.Context) Check {
+	var services corev1.ServiceList
+	if err := d.Client.List(ctx, &services); err != nil {
+		return fail(CheckLoadBalancerIPs, fmt.Sprintf("failed to list services: %v", err), "Grant list on services")
+	}
+
+	annotated := 0
+	var notLoadBalancer, pending []string
+	for i := range services.Items {
+		service := &services.Items[i]
+		if _, ok := service.Annotations[config.FilterAnnotation]; !ok {
+			continue
+		}
+		annotated++
+
+		key := client.ObjectKeyFromObject(service).String()
+		switch {
+		case service.Spec.Type != corev1.ServiceTypeLoadBalancer:
+			notLoadBalancer = append(notLoadBalancer, key)
+		case helpers.GetLBIP(service) == "":
+			pending = append(pending, key)
+		}
+	}
+
+	switch {
+	case annotated == 0:
+		return pass(CheckLoadBalancerIPs, "no Services with the mapping annotation")
+	case len(notLoadBalancer) > 0:
+		return fail(CheckLoadBalancerIPs, fmt.Sprintf("annotated Services not of type LoadBalancer: %s", list(notLoadBalancer)),
+			"Port forwards need a LoadBalancer IP; set spec.type to LoadBalancer or remove the "+config.FilterAnnotation+" annotation")
+	case len(pending) > 0:
+		return fail(CheckLoadBalancerIPs, fmt.Sprintf("annotated Services without a LoadBalancer IP: %s", list(pending)),
+			"No LoadBalancer implementation assigned an IP; install one such as MetalLB or kube-vip and check its address pools")
+	}
+	return pass(CheckLoadBalancerIPs, fmt.Sprintf("all %d annotated Services have a LoadBalancer IP", annotated))
+}
+
+// checkNameCollisions finds owners whose router rules would share names, and router rules
+// sharing a name
+func (d *ClusterDoctor) checkNameCollisions(ctx context.Context, routerRules []*unifi.PortForward) Check {
+	owners := make(map[string][]string)
+
+	var services corev1.ServiceList
+	if err := d.Client.List(ctx, &services); err != nil {
+		return fail(CheckNameCollisions, fmt.Sprintf("failed to list services: %v", err), "Grant list on services")
+	}
+	for i := range services.Items {
+		service := &services.Items[i]
+		if _, ok := service.Annotations[config.FilterAnnotation]; ok {
+			prefix := fmt.Sprintf("%s/%s:", service.Namespace, service.Name)
+			owners[prefix] = append(owners[prefix], "Service "+client.ObjectKeyFromObject(service).String())
+		}
+	}
+
+	var rules v1alpha1.PortForwardRuleList
+	if err := d.Client.List(ctx, &rules); err != nil && !meta.IsNoMatchError(err) {
+		return fail(CheckNameCollisions, fmt.Sprintf("failed to list port forward rules: %v", err), "Grant list on portforwardrules")
+	}
+	for i := range rules.Items {
+		rule := &rules.Items[i]
+		prefix := fmt.Sprintf("%s/%s:", rule.Namespace, rule.Name)
+		owners[prefix] = append(owners[prefix], "PortForwardRule "+client.ObjectKeyFromObject(rule).String())
+	}
+
+	var collisions []string
+	for prefix, names := range owners {
+		if len(names) > 1 {
+			sort.Strings(names)
+			collisions = append(collisions, fmt.Sprintf("%s* (%s)", prefix, strings.Join(names, ", ")))
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return fail(CheckNameCollisions, "owners share router rule names: "+list(collisions),
+			"Router rules are named namespace/name:port. Rename one of the owners so they stop claiming each other's rules.")
+	}
+
+	names := make(map[string]int)
+	for _, rule := range routerRules {
+		if helpers.IsManagedRule(rule.Name) {
+			names[rule.Name]++
+		}
+	}
+	var duplicates []string
+	for name, count := range names {
+		if count > 1 {
+			duplicates = append(duplicates, fmt.Sprintf("%s (%d rules)", name, count))
+		}
+	}
+	if len(duplicates) > 0 {
+		sort.Strings(duplicates)
+		return warn(CheckNameCollisions, "router rules share managed names: "+list(duplicates),
+			"Only one of each is updated by the controller; delete the extra rules, for example with unifi-port-forward clean --name-regex")
+	}
+
+	if routerRules == nil {
+		return pass(CheckNameCollisions, "no owners share router rule names (router rules not checked)")
+	}
+	return pass(CheckNameCollisions, "no owners or router rules share names")
+}
+
+// list joins names, naming at most maxListed of them
+func list(names []string) string {
+	if len(names) <= maxListed {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListed], ", "), len(names)-maxListed)
+}
@@ -0,0 +1,164 @@
+package doctor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/testutils"
+
+	"github.com/filipowm/go-unifi/unifi"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestClusterDoctor(t *testing.T) (*ClusterDoctor, *testutils.FakeKubernetesClient) {
+	t.Helper()
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubeClient := testutils.NewFakeKubernetesClient(t, scheme)
+	kubeClient.Authorize = func(authorizationv1.SubjectAccessReviewSpec) bool { return true }
+
+	return &ClusterDoctor{
+		Client:       kubeClient,
+		CRDAvailable: func(ctx context.Context, crdName string) bool { return crdName == config.PortForwardRulesCRDName },
+	}, kubeClient
+}
+
+func findCheck(t *testing.T, checks []Check, name string) Check {
+	t.Helper()
+	for _, check := range checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("no check %q in %+v", name, checks)
+	return Check{}
+}
+
+func TestClusterDoctor_Run(t *testing.T) {
+	doctor, kubeClient := newTestClusterDoctor(t)
+	kubeClient.Services["default/web"] = testutils.CreateTestMultiPortService("web", "default",
+		[]testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}, "192.168.1.100", "8080:http")
+
+	checks := doctor.Run(context.Background(), nil)
+
+	expected := map[string]Status{
+		CheckPortForwardRuleCRD:        StatusPass,
+		CheckClusterPortForwardRuleCRD: StatusWarn,
+		CheckRBAC:                      StatusPass,
+		CheckLoadBalancerIPs:           StatusPass,
+		CheckNameCollisions:            StatusPass,
+	}
+	if got := statuses(checks); len(got) != len(expected) {
+		t.Errorf("Run() = %v, want %v", got, expected)
+	} else {
+		for name, status := range expected {
+			if got[name] != status {
+				t.Errorf("check %q = %s, want %s", name, got[name], status)
+			}
+		}
+	}
+}
+
+func TestClusterDoctor_RBAC(t *testing.T) {
+	doctor, kubeClient := newTestClusterDoctor(t)
+	kubeClient.Authorize = func(spec authorizationv1.SubjectAccessReviewSpec) bool {
+		attributes := spec.ResourceAttributes
+		return !(attributes.Resource == "services" && attributes.Verb == "update") && attributes.Subresource != "status"
+	}
+
+	check := findCheck(t, doctor.Run(context.Background(), nil), CheckRBAC)
+
+	if check.Status != StatusFail {
+		t.Fatalf("RBAC check = %+v, want fail", check)
+	}
+	for _, missing := range []string{"update services", "update portforwardrules/status.unifi-port-forward.fiskhe.st"} {
+		if !strings.Contains(check.Message, missing) {
+			t.Errorf("RBAC message %q does not name %q", check.Message, missing)
+		}
+	}
+	if strings.Contains(check.Message, "get services") {
+		t.Errorf("RBAC message %q names a granted permission", check.Message)
+	}
+}
+
+func TestClusterDoctor_LoadBalancerIPs(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(*corev1.Service)
+		expected Status
+	}{
+		{"assigned", func(*corev1.Service) {}, StatusPass},
+		{"pending", func(s *corev1.Service) { s.Status.LoadBalancer.Ingress = nil }, StatusFail},
+		{"cluster IP", func(s *corev1.Service) { s.Spec.Type = corev1.ServiceTypeClusterIP }, StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doctor, kubeClient := newTestClusterDoctor(t)
+			service := testutils.CreateTestMultiPortService("web", "default",
+				[]testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}, "192.168.1.100", "8080:http")
+			tt.mutate(service)
+			kubeClient.Services["default/web"] = service
+
+			check := findCheck(t, doctor.Run(context.Background(), nil), CheckLoadBalancerIPs)
+
+			if check.Status != tt.expected {
+				t.Errorf("LoadBalancer IP check = %+v, want %s", check, tt.expected)
+			}
+			if check.Status == StatusFail && !strings.Contains(check.Message, "default/web") {
+				t.Errorf("LoadBalancer IP message %q does not name the service", check.Message)
+			}
+		})
+	}
+}
+
+func TestClusterDoctor_NameCollisions(t *testing.T) {
+	t.Run("owners", func(t *testing.T) {
+		doctor, kubeClient := newTestClusterDoctor(t)
+		kubeClient.Services["default/web"] = testutils.CreateTestMultiPortService("web", "default",
+			[]testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}, "192.168.1.100", "8080:http")
+		kubeClient.Rules["default/web"] = &v1alpha1.PortForwardRule{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		kubeClient.Rules["cluster/edge"] = &v1alpha1.PortForwardRule{ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "cluster"}}
+		kubeClient.ClusterRules["edge"] = &v1alpha1.ClusterPortForwardRule{ObjectMeta: metav1.ObjectMeta{Name: "edge"}}
+
+		check := findCheck(t, doctor.Run(context.Background(), nil), CheckNameCollisions)
+
+		if check.Status != StatusFail {
+			t.Fatalf("naming check = %+v, want fail", check)
+		}
+		if !strings.Contains(check.Message, "default/web:*") {
+			t.Errorf("naming message %q does not name %q", check.Message, "default/web:*")
+		}
+		// ClusterPortForwardRules cannot collide with a namespace, not even one named cluster
+		if strings.Contains(check.Message, "edge") {
+			t.Errorf("naming message %q names the cluster rule", check.Message)
+		}
+	})
+
+	t.Run("router rules", func(t *testing.T) {
+		doctor, _ := newTestClusterDoctor(t)
+		routerRules := []*unifi.PortForward{
+			{ID: "1", Name: "default/web:http"},
+			{ID: "2", Name: "default/web:http"},
+			{ID: "3", Name: "Printer"},
+			{ID: "4", Name: "Printer"},
+		}
+
+		check := findCheck(t, doctor.Run(context.Background(), routerRules), CheckNameCollisions)
+
+		if check.Status != StatusWarn || !strings.Contains(check.Message, "default/web:http (2 rules)") {
+			t.Errorf("naming check = %+v, want a warning for the managed duplicate", check)
+		}
+		if strings.Contains(check.Message, "Printer") {
+			t.Errorf("naming message %q names manual rules", check.Message)
+		}
+	})
+}
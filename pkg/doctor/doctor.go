@@ -0,0 +1,54 @@
+// Package doctor diagnoses the environment of the controller: whether the router is reachable
+// and accepts the configured credentials, and whether the cluster has the CRDs, permissions
+// and LoadBalancer IPs the controllers need. Every check reports a hint on how to fix it.
+package doctor
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+
+	// StatusSkip marks checks that could not run, because they are not configured or an
+	// earlier check failed
+	StatusSkip Status = "skip"
+)
+
+// Check is the result of a single diagnostic
+type Check struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+
+	// Hint tells how to fix a failed or warned check
+	Hint string `json:"hint,omitempty"`
+}
+
+// Failed returns the number of failed checks
+func Failed(checks []Check) int {
+	failed := 0
+	for _, check := range checks {
+		if check.Status == StatusFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+func pass(name, message string) Check {
+	return Check{Name: name, Status: StatusPass, Message: message}
+}
+
+func warn(name, message, hint string) Check {
+	return Check{Name: name, Status: StatusWarn, Message: message, Hint: hint}
+}
+
+func fail(name, message, hint string) Check {
+	return Check{Name: name, Status: StatusFail, Message: message, Hint: hint}
+}
+
+func skip(name, message string) Check {
+	return Check{Name: name, Status: StatusSkip, Message: message}
+}
@@ -0,0 +1,307 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+// Names of the router checks
+const (
+	CheckReachable     = "router reachable"
+	CheckTLS           = "router TLS"
+	CheckPasswordLogin = "password login"
+	CheckAPIKeyLogin   = "API key login"
+	CheckVersion       = "controller version"
+	CheckSite          = "site"
+	CheckListRules     = "list port forwards"
+	CheckWriteRule     = "create and delete a rule"
+)
+
+// TestRuleName is the name of the disabled rule the write check creates and deletes. It does
+// not follow the naming scheme of managed rules, so the controllers never adopt it.
+const TestRuleName = "unifi-port-forward-doctor"
+
+// testRuleDestination is the destination of the test rule, an address of the documentation
+// range that no host uses
+const testRuleDestination = "192.0.2.1"
+
+// requestTimeout bounds the reachability requests
+const requestTimeout = 10 * time.Second
+
+// RouterConfig holds the router connection settings to check
+type RouterConfig struct {
+	URL      string
+	Username string
+	Password string
+	APIKey   string
+	Site     string
+}
+
+// Connect returns a client logged in to the router
+type Connect func(clientConfig *unifi.ClientConfig) (unifi.Client, error)
+
+// ConnectUnifi creates a client and logs in, as the controller does
+func ConnectUnifi(clientConfig *unifi.ClientConfig) (unifi.Client, error) {
+	client, err := unifi.NewClient(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Login(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// RouterDoctor checks the router and the credentials of the controller
+type RouterDoctor struct {
+	Config RouterConfig
+
+	// Connect logs in to the router; ConnectUnifi when nil
+	Connect Connect
+
+	// ReadOnly skips the check creating and deleting a disabled test rule
+	ReadOnly bool
+}
+
+// Run runs the router checks and returns them with the router rules, which are nil when they
+// could not be listed
+func (d *RouterDoctor) Run(ctx context.Context) ([]Check, []*unifi.PortForward) {
+	connect := d.Connect
+	if connect == nil {
+		connect = ConnectUnifi
+	}
+
+	reachable := d.checkReachable(ctx)
+	checks := []Check{reachable}
+	if reachable.Status == StatusFail {
+		for _, name := range []string{CheckTLS, CheckPasswordLogin, CheckAPIKeyLogin, CheckVersion, CheckSite, CheckListRules, CheckWriteRule} {
+			checks = append(checks, skip(name, "router not reachable"))
+		}
+		return checks, nil
+	}
+	checks = append(checks, d.checkTLS(ctx))
+
+	// The controller prefers the API key, as CreateUnifiRouter does
+	passwordCheck, passwordClient := d.checkPasswordLogin(connect)
+	apiKeyCheck, apiKeyClient := d.checkAPIKeyLogin(connect)
+	checks = append(checks, passwordCheck, apiKeyCheck)
+
+	client := passwordClient
+	login := "password"
+	if d.Config.APIKey != "" {
+		client, login = apiKeyClient, "API key"
+	}
+	if client == nil {
+		for _, name := range []string{CheckVersion, CheckSite, CheckListRules, CheckWriteRule} {
+			checks = append(checks, skip(name, fmt.Sprintf("%s login failed", login)))
+		}
+		return checks, nil
+	}
+
+	checks = append(checks, checkVersion(client))
+
+	siteCheck := d.checkSite(ctx, client)
+	checks = append(checks, siteCheck)
+	if siteCheck.Status == StatusFail {
+		checks = append(checks, skip(CheckListRules, "site not found"), skip(CheckWriteRule, "site not found"))
+		return checks, nil
+	}
+
+	listCheck, routerRules := d.checkListRules(ctx, client)
+	checks = append(checks, listCheck)
+	switch {
+	case d.ReadOnly:
+		checks = append(checks, skip(CheckWriteRule, "read-only run"))
+	case routerRules == nil:
+		checks = append(checks, skip(CheckWriteRule, "port forwards could not be listed"))
+	default:
+		checks = append(checks, d.checkWriteRule(ctx, client, routerRules))
+	}
+	return checks, routerRules
+}
+
+// httpClient returns the client of the reachability checks, verifying certificates if asked.
+// The controller does not verify the router certificate.
+func httpClient(verify bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: !verify}
+	return &http.Client{Transport: transport, Timeout: requestTimeout}
+}
+
+func (d *RouterDoctor) checkReachable(ctx context.Context) Check {
+	target, err := url.Parse(d.Config.URL)
+	if err != nil || target.Host == "" {
+		return fail(CheckReachable, fmt.Sprintf("invalid router URL %q", d.Config.URL),
+			"Set UNIFI_ROUTER_IP (or --router-ip) to the router address, such as 192.168.1.1")
+	}
+
+	response, err := get(ctx, httpClient(false), d.Config.URL)
+	if err != nil {
+		return fail(CheckReachable, fmt.Sprintf("%s: %v", d.Config.URL, err),
+			"Check the router address and that the controller can reach it: firewall rules, NetworkPolicies and the port (443, or 8443 for self-hosted controllers)")
+	}
+	return pass(CheckReachable, fmt.Sprintf("HTTP %d from %s", response.StatusCode, d.Config.URL))
+}
+
+func (d *RouterDoctor) checkTLS(ctx context.Context) Check {
+	if !strings.HasPrefix(d.Config.URL, "https://") {
+		return warn(CheckTLS, "router is reached over plain HTTP",
+			"Use an https:// router URL so credentials are not sent in clear text")
+	}
+
+	if _, err := get(ctx, httpClient(true), d.Config.URL); err != nil {
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			return warn(CheckTLS, fmt.Sprintf("certificate not trusted: %v", certErr.Err),
+				"The controller does not verify the router certificate, so this is not an error. Install a trusted certificate on the router to rule out interception.")
+		}
+		return fail(CheckTLS, err.Error(), "The TLS handshake with the router failed; check that the URL points at the UniFi controller port")
+	}
+	return pass(CheckTLS, "certificate trusted")
+}
+
+func (d *RouterDoctor) checkPasswordLogin(connect Connect) (Check, unifi.Client) {
+	if d.Config.Username == "" || d.Config.Password == "" {
+		return skip(CheckPasswordLogin, "no username and password configured"), nil
+	}
+
+	client, err := connect(&unifi.ClientConfig{
+		URL:            d.Config.URL,
+		ValidationMode: unifi.HardValidation,
+		User:           d.Config.Username,
+		Password:       d.Config.Password,
+		RememberMe:     true,
+	})
+	if err != nil {
+		return fail(CheckPasswordLogin, fmt.Sprintf("login as %s failed: %v", d.Config.Username, err),
+			"Check UNIFI_USERNAME and UNIFI_PASSWORD. Use a local account of the UniFi controller: UI.com accounts with two-factor authentication cannot log in."), nil
+	}
+	return pass(CheckPasswordLogin, fmt.Sprintf("logged in as %s", d.Config.Username)), client
+}
+
+func (d *RouterDoctor) checkAPIKeyLogin(connect Connect) (Check, unifi.Client) {
+	if d.Config.APIKey == "" {
+		return skip(CheckAPIKeyLogin, "no API key configured"), nil
+	}
+
+	client, err := connect(&unifi.ClientConfig{
+		URL:            d.Config.URL,
+		ValidationMode: unifi.HardValidation,
+		APIKey:         d.Config.APIKey,
+	})
+	if err != nil {
+		return fail(CheckAPIKeyLogin, fmt.Sprintf("login with the API key failed: %v", err),
+			"Check UNIFI_API_KEY. API keys need UniFi Network 9.0.108 or later and are created under Settings > Control Plane > Integrations."), nil
+	}
+	return pass(CheckAPIKeyLogin, "logged in with the API key"), client
+}
+
+func checkVersion(client unifi.Client) Check {
+	version := client.Version()
+	if version == "" {
+		return warn(CheckVersion, "the controller did not report its version", "")
+	}
+	return pass(CheckVersion, "UniFi Network "+version)
+}
+
+func (d *RouterDoctor) checkSite(ctx context.Context, client unifi.Client) Check {
+	sites, err := client.ListSites(ctx)
+	if err != nil {
+		return fail(CheckSite, fmt.Sprintf("failed to list sites: %v", err),
+			"The account may lack access to the site; give it the Admin role")
+	}
+
+	var names []string
+	for _, site := range sites {
+		if site.Name == d.Config.Site {
+			return pass(CheckSite, fmt.Sprintf("site %s (%s) exists", site.Name, site.Description))
+		}
+		names = append(names, fmt.Sprintf("%s (%s)", site.Name, site.Description))
+	}
+	sort.Strings(names)
+	return fail(CheckSite, fmt.Sprintf("site %q not found, the account sees: %s", d.Config.Site, strings.Join(names, ", ")),
+		"Set UNIFI_SITE to the short site name shown in the controller URL, /manage/<site>/..., not the description")
+}
+
+func (d *RouterDoctor) checkListRules(ctx context.Context, client unifi.Client) (Check, []*unifi.PortForward) {
+	portForwards, err := client.ListPortForward(ctx, d.Config.Site)
+	if err != nil {
+		return fail(CheckListRules, fmt.Sprintf("failed to list port forwards: %v", err),
+			"The account needs access to the site's port forwards; give it the Admin role"), nil
+	}
+
+	routerRules := make([]*unifi.PortForward, 0, len(portForwards))
+	for i := range portForwards {
+		routerRules = append(routerRules, &portForwards[i])
+	}
+	return pass(CheckListRules, fmt.Sprintf("%d port forward rules on site %s", len(routerRules), d.Config.Site)), routerRules
+}
+
+// checkWriteRule creates a disabled rule on a free port and deletes it again
+func (d *RouterDoctor) checkWriteRule(ctx context.Context, client unifi.Client, routerRules []*unifi.PortForward) Check {
+	port := freePort(routerRules)
+	if port == 0 {
+		return skip(CheckWriteRule, "no free external port for a test rule")
+	}
+
+	created, err := client.CreatePortForward(ctx, d.Config.Site, &unifi.PortForward{
+		SiteID:        d.Config.Site,
+		Name:          TestRuleName,
+		Enabled:       false,
+		DestinationIP: "any",
+		Fwd:           testRuleDestination,
+		FwdPort:       strconv.Itoa(port),
+		DstPort:       strconv.Itoa(port),
+		Proto:         "tcp",
+		PfwdInterface: "wan",
+		Src:           "any",
+	})
+	if err != nil {
+		return fail(CheckWriteRule, fmt.Sprintf("failed to create a disabled test rule: %v", err),
+			"The account can read but not change port forwards; give it the Admin or Super Admin role on the site")
+	}
+
+	if err := client.DeletePortForward(ctx, d.Config.Site, created.ID); err != nil {
+		return fail(CheckWriteRule, fmt.Sprintf("created the test rule but failed to delete it: %v", err),
+			fmt.Sprintf("Delete the disabled rule %q (ID %s) on port %d by hand, and give the account the Admin role", TestRuleName, created.ID, port))
+	}
+	return pass(CheckWriteRule, fmt.Sprintf("created and deleted a disabled test rule on port %d", port))
+}
+
+// freePort returns the highest external port no router rule uses
+func freePort(routerRules []*unifi.PortForward) int {
+	used := make(map[string]bool, len(routerRules))
+	for _, rule := range routerRules {
+		used[rule.DstPort] = true
+	}
+	for port := 65535; port > 1024; port-- {
+		if !used[strconv.Itoa(port)] {
+			return port
+		}
+	}
+	return 0
+}
+
+// get requests target and discards the response body
+func get(ctx context.Context, client *http.Client, target string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	return response, nil
+}
@@ -0,0 +1,196 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+// fakeUnifi implements the unifi.Client calls of the router checks
+type fakeUnifi struct {
+	unifi.Client
+
+	sites     []unifi.Site
+	rules     []unifi.PortForward
+	createErr error
+	created   []*unifi.PortForward
+	deleted   []string
+}
+
+func (f *fakeUnifi) Version() string { return "9.0.114" }
+
+func (f *fakeUnifi) ListSites(ctx context.Context) ([]unifi.Site, error) {
+	return f.sites, nil
+}
+
+func (f *fakeUnifi) ListPortForward(ctx context.Context, site string) ([]unifi.PortForward, error) {
+	return f.rules, nil
+}
+
+func (f *fakeUnifi) CreatePortForward(ctx context.Context, site string, p *unifi.PortForward) (*unifi.PortForward, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	created := *p
+	created.ID = "test-id"
+	f.created = append(f.created, &created)
+	return &created, nil
+}
+
+func (f *fakeUnifi) DeletePortForward(ctx context.Context, site string, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func newTestRouterDoctor(t *testing.T, router *fakeUnifi, config RouterConfig) *RouterDoctor {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	config.URL = server.URL
+	return &RouterDoctor{
+		Config: config,
+		Connect: func(clientConfig *unifi.ClientConfig) (unifi.Client, error) {
+			if clientConfig.APIKey == "" && clientConfig.Password != "secret" {
+				return nil, errors.New("api.err.Invalid")
+			}
+			return router, nil
+		},
+	}
+}
+
+func statuses(checks []Check) map[string]Status {
+	got := make(map[string]Status, len(checks))
+	for _, check := range checks {
+		got[check.Name] = check.Status
+	}
+	return got
+}
+
+func TestRouterDoctor_Run(t *testing.T) {
+	router := &fakeUnifi{
+		sites: []unifi.Site{{Name: "default", Description: "Default"}},
+		rules: []unifi.PortForward{{ID: "1", Name: "default/web:http", DstPort: "65535"}},
+	}
+	doctor := newTestRouterDoctor(t, router, RouterConfig{Username: "admin", Password: "secret", Site: "default"})
+
+	checks, routerRules := doctor.Run(context.Background())
+
+	expected := map[string]Status{
+		CheckReachable:     StatusPass,
+		CheckTLS:           StatusWarn,
+		CheckPasswordLogin: StatusPass,
+		CheckAPIKeyLogin:   StatusSkip,
+		CheckVersion:       StatusPass,
+		CheckSite:          StatusPass,
+		CheckListRules:     StatusPass,
+		CheckWriteRule:     StatusPass,
+	}
+	got := statuses(checks)
+	for name, status := range expected {
+		if got[name] != status {
+			t.Errorf("check %q = %s, want %s", name, got[name], status)
+		}
+	}
+	if len(routerRules) != 1 {
+		t.Errorf("Run() returned %d router rules, want 1", len(routerRules))
+	}
+
+	if len(router.created) != 1 || router.created[0].Enabled || router.created[0].DstPort != "65534" {
+		t.Fatalf("created rules = %+v, want one disabled rule on the highest free port", router.created)
+	}
+	if len(router.deleted) != 1 || router.deleted[0] != "test-id" {
+		t.Errorf("deleted rules = %v, want the test rule", router.deleted)
+	}
+}
+
+func TestRouterDoctor_Run_ReadOnly(t *testing.T) {
+	router := &fakeUnifi{sites: []unifi.Site{{Name: "default"}}}
+	doctor := newTestRouterDoctor(t, router, RouterConfig{APIKey: "key", Site: "default"})
+	doctor.ReadOnly = true
+
+	checks, _ := doctor.Run(context.Background())
+
+	got := statuses(checks)
+	if got[CheckAPIKeyLogin] != StatusPass || got[CheckPasswordLogin] != StatusSkip {
+		t.Errorf("login checks = %s, %s, want the API key login only", got[CheckAPIKeyLogin], got[CheckPasswordLogin])
+	}
+	if got[CheckWriteRule] != StatusSkip || len(router.created) != 0 {
+		t.Errorf("read-only run created rules: %+v", router.created)
+	}
+}
+
+func TestRouterDoctor_Run_Failures(t *testing.T) {
+	tests := []struct {
+		name     string
+		router   *fakeUnifi
+		config   RouterConfig
+		expected map[string]Status
+	}{
+		{
+			name:   "wrong password",
+			router: &fakeUnifi{},
+			config: RouterConfig{Username: "admin", Password: "wrong", Site: "default"},
+			expected: map[string]Status{
+				CheckPasswordLogin: StatusFail,
+				CheckSite:          StatusSkip,
+				CheckWriteRule:     StatusSkip,
+			},
+		},
+		{
+			name:   "unknown site",
+			router: &fakeUnifi{sites: []unifi.Site{{Name: "default", Description: "Default"}}},
+			config: RouterConfig{Username: "admin", Password: "secret", Site: "Home"},
+			expected: map[string]Status{
+				CheckSite:      StatusFail,
+				CheckListRules: StatusSkip,
+			},
+		},
+		{
+			name:   "read-only role",
+			router: &fakeUnifi{sites: []unifi.Site{{Name: "default"}}, createErr: errors.New("api.err.NoPermission")},
+			config: RouterConfig{Username: "admin", Password: "secret", Site: "default"},
+			expected: map[string]Status{
+				CheckListRules: StatusPass,
+				CheckWriteRule: StatusFail,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks, _ := newTestRouterDoctor(t, tt.router, tt.config).Run(context.Background())
+
+			got := statuses(checks)
+			for name, status := range tt.expected {
+				if got[name] != status {
+					t.Errorf("check %q = %s, want %s", name, got[name], status)
+				}
+			}
+			for _, check := range checks {
+				if check.Status == StatusFail && check.Hint == "" {
+					t.Errorf("failed check %q has no hint", check.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterDoctor_Run_Unreachable(t *testing.T) {
+	doctor := &RouterDoctor{Config: RouterConfig{URL: "not a url"}}
+
+	checks, routerRules := doctor.Run(context.Background())
+
+	if checks[0].Status != StatusFail || routerRules != nil {
+		t.Errorf("Run() = %+v, want a failed reachability check", checks[0])
+	}
+	for _, check := range checks[1:] {
+		if check.Status != StatusSkip {
+			t.Errorf("check %q = %s, want skip", check.Name, check.Status)
+		}
+	}
+}
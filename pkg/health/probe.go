@@ -0,0 +1,139 @@
+// Package health probes the destinations of port forwards so a rule can fail over between them
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Probe protocols
+const (
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolHTTP = "http"
+)
+
+// Check is a single probe of a destination
+type Check struct {
+	// Protocol is tcp, udp or http; empty means tcp
+	Protocol string
+
+	// IP and Port are the probed address
+	IP   string
+	Port int
+
+	// Path is requested by http probes; empty means /
+	Path string
+
+	// Timeout bounds the probe; zero relies on the deadline of the context
+	Timeout time.Duration
+}
+
+// Prober probes destinations. Probe returns nil when the destination is healthy.
+type Prober interface {
+	Probe(ctx context.Context, check Check) error
+}
+
+// NetProber probes destinations over the network:
+//   - tcp succeeds once a connection is established
+//   - udp sends an empty datagram and fails only when the destination answers with an ICMP
+//     port unreachable; no answer within the timeout counts as healthy
+//   - http fails on connection errors and on responses with a status of 400 or higher
+type NetProber struct{}
+
+// Probe implements Prober
+func (NetProber) Probe(ctx context.Context, check Check) error {
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
+	}
+	address := net.JoinHostPort(check.IP, strconv.Itoa(check.Port))
+
+	switch check.Protocol {
+	case "", ProtocolTCP:
+		return probeTCP(ctx, address)
+	case ProtocolUDP:
+		return probeUDP(ctx, address)
+	case ProtocolHTTP:
+		return probeHTTP(ctx, address, check.Path)
+	default:
+		return fmt.Errorf("unsupported health check protocol %q", check.Protocol)
+	}
+}
+
+// probeTCP connects to address
+func probeTCP(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeUDP sends an empty datagram to address and waits for a port unreachable until the
+// context is done
+func probeUDP(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Second)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	if _, err := conn.Write(nil); err != nil {
+		return err
+	}
+
+	_, err = conn.Read(make([]byte, 1))
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return nil
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("udp port unreachable: %w", err)
+	default:
+		return err
+	}
+}
+
+// probeHTTP requests path from address without following redirects
+func probeHTTP(ctx context.Context, address, path string) error {
+	if path == "" {
+		path = "/"
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+path, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("http status %d", response.StatusCode)
+	}
+	return nil
+}
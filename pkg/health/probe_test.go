@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// splitAddress returns the IP and port of a listener address
+func splitAddress(t *testing.T, address string) (string, int) {
+	t.Helper()
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatalf("Failed to split address %s: %v", address, err)
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		t.Fatalf("Failed to parse port of %s: %v", address, err)
+	}
+	return host, port
+}
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T, network string) int {
+	t.Helper()
+	if network == "udp" {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		_, port := splitAddress(t, conn.LocalAddr().String())
+		conn.Close()
+		return port
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	_, port := splitAddress(t, listener.Addr().String())
+	listener.Close()
+	return port
+}
+
+func TestNetProber_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	ip, port := splitAddress(t, listener.Addr().String())
+
+	prober := NetProber{}
+	if err := prober.Probe(context.Background(), Check{Protocol: ProtocolTCP, IP: ip, Port: port, Timeout: time.Second}); err != nil {
+		t.Errorf("Expected listening port to be healthy, got %v", err)
+	}
+	if err := prober.Probe(context.Background(), Check{IP: ip, Port: closedPort(t, "tcp"), Timeout: time.Second}); err == nil {
+		t.Error("Expected closed port to be unhealthy")
+	}
+}
+
+func TestNetProber_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+	ip, port := splitAddress(t, conn.LocalAddr().String())
+
+	prober := NetProber{}
+	if err := prober.Probe(context.Background(), Check{Protocol: ProtocolUDP, IP: ip, Port: port, Timeout: 200 * time.Millisecond}); err != nil {
+		t.Errorf("Expected silent listening port to be healthy, got %v", err)
+	}
+	if err := prober.Probe(context.Background(), Check{Protocol: ProtocolUDP, IP: ip, Port: closedPort(t, "udp"), Timeout: time.Second}); err == nil {
+		t.Error("Expected unreachable port to be unhealthy")
+	}
+}
+
+func TestNetProber_HTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusOK)
+		case "/login":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	ip, port := splitAddress(t, server.Listener.Addr().String())
+
+	tests := []struct {
+		path    string
+		healthy bool
+	}{
+		{path: "/healthz", healthy: true},
+		{path: "/login", healthy: true},
+		{path: "/", healthy: false},
+	}
+
+	prober := NetProber{}
+	for _, tt := range tests {
+		err := prober.Probe(context.Background(), Check{Protocol: ProtocolHTTP, IP: ip, Port: port, Path: tt.path, Timeout: time.Second})
+		if (err == nil) != tt.healthy {
+			t.Errorf("Expected %s healthy=%t, got %v", tt.path, tt.healthy, err)
+		}
+	}
+}
+
+func TestNetProber_UnsupportedProtocol(t *testing.T) {
+	if err := (NetProber{}).Probe(context.Background(), Check{Protocol: "icmp", IP: "127.0.0.1", Port: 1}); err == nil {
+		t.Error("Expected an unsupported protocol to fail")
+	}
+}
@@ -3,13 +3,16 @@ package helpers
 import (
 	"context"
 	"strconv"
+	"time"
 
+	"unifi-port-forward/pkg/api/v1alpha1"
 	"unifi-port-forward/pkg/routers"
 	"unifi-port-forward/pkg/utils"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -23,6 +26,11 @@ func GetPortConfigs(service *v1.Service, lbIP string, annotationKey string) ([]r
 	return utils.GetPortConfigs(service, lbIP, annotationKey)
 }
 
+// BuildPortConfigs creates the PortConfigs for a service without port conflict tracking using utils package
+func BuildPortConfigs(service *v1.Service, lbIP string, annotationKey string) ([]routers.PortConfig, error) {
+	return utils.BuildPortConfigs(service, lbIP, annotationKey)
+}
+
 // UnmarkPortUsed removes external port from tracking using utils package
 // This function is called during service deletion to free up external ports for reuse
 func UnmarkPortUsed(externalPort int) {
@@ -40,6 +48,36 @@ func RuleBelongsToService(ruleName, namespace, serviceName string) bool {
 	return utils.RuleBelongsToService(ruleName, namespace, serviceName)
 }
 
+// GetRuleIDs returns the router rule IDs recorded on a service using utils package
+func GetRuleIDs(service *v1.Service, annotationKey string) map[string]string {
+	return utils.GetRuleIDs(service, annotationKey)
+}
+
+// FormatRuleIDs encodes router rule IDs for a service annotation using utils package
+func FormatRuleIDs(ruleIDs map[string]string) string {
+	return utils.FormatRuleIDs(ruleIDs)
+}
+
+// GetExpiry returns when the port forwards of a service expire using utils package
+func GetExpiry(service *v1.Service, expiresAtKey, ttlKey string) (time.Time, error) {
+	return utils.GetExpiry(service, expiresAtKey, ttlKey)
+}
+
+// ValidatePortMappings checks a mapping annotation value against a service using utils package
+func ValidatePortMappings(service *v1.Service, annotation string) error {
+	return utils.ValidatePortMappings(service, annotation)
+}
+
+// GetDisabledPorts returns the external ports disabled on a service using utils package
+func GetDisabledPorts(service *v1.Service, annotationKey string) (map[int]bool, error) {
+	return utils.GetDisabledPorts(service, annotationKey)
+}
+
+// FormatDisabledPorts encodes external ports for the disabled ports annotation using utils package
+func FormatDisabledPorts(ports map[int]bool) string {
+	return utils.FormatDisabledPorts(ports)
+}
+
 // ParseIntField parses a string field to int using utils package
 func ParseIntField(input string) int {
 	return utils.ParseIntField(input)
@@ -155,6 +193,31 @@ func IsPortForwardRuleCRDAvailable(ctx context.Context, restConfig *rest.Config,
 	return utils.IsPortForwardRuleCRDAvailable(ctx, restConfig, scheme)
 }
 
+// IsClusterPortForwardRuleCRDAvailable checks if the ClusterPortForwardRule CRD is installed using utils package
+func IsClusterPortForwardRuleCRDAvailable(ctx context.Context, restConfig *rest.Config, scheme *runtime.Scheme) bool {
+	return utils.IsClusterPortForwardRuleCRDAvailable(ctx, restConfig, scheme)
+}
+
+// IsPortForwardReferenceGrantCRDAvailable checks if the PortForwardReferenceGrant CRD is installed using utils package
+func IsPortForwardReferenceGrantCRDAvailable(ctx context.Context, restConfig *rest.Config, scheme *runtime.Scheme) bool {
+	return utils.IsPortForwardReferenceGrantCRDAvailable(ctx, restConfig, scheme)
+}
+
+// IsPortForwardPolicyCRDAvailable checks if the PortForwardPolicy CRD is installed using utils package
+func IsPortForwardPolicyCRDAvailable(ctx context.Context, restConfig *rest.Config, scheme *runtime.Scheme) bool {
+	return utils.IsPortForwardPolicyCRDAvailable(ctx, restConfig, scheme)
+}
+
+// ServicePolicyRequest returns the port forwards of a service checked against PortForwardPolicies using utils package
+func ServicePolicyRequest(service *v1.Service, configs []routers.PortConfig) v1alpha1.PolicyRequest {
+	return utils.ServicePolicyRequest(service, configs)
+}
+
+// CheckPortForwardPolicies returns the PortForwardPolicy violations of a request using utils package
+func CheckPortForwardPolicies(ctx context.Context, c client.Client, request v1alpha1.PolicyRequest, annotationKey string) ([]string, error) {
+	return utils.CheckPortForwardPolicies(ctx, c, request, annotationKey)
+}
+
 // Port conflict tracking functions - delegates to utils package
 
 // CheckPortConflict checks if a port conflicts with existing ports using utils package
@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -335,6 +336,48 @@ func TestParseIntField(t *testing.T) {
 	}
 }
 
+func TestGetDisabledPorts(t *testing.T) {
+	const key = "example.com/disabled-ports"
+	tests := []struct {
+		name     string
+		value    *string
+		expected map[int]bool
+		wantErr  bool
+	}{
+		{name: "no annotation", expected: map[int]bool{}},
+		{name: "empty", value: strPtr(""), expected: map[int]bool{}},
+		{name: "single", value: strPtr("8080"), expected: map[int]bool{8080: true}},
+		{name: "list with spaces", value: strPtr("8443, 8080"), expected: map[int]bool{8080: true, 8443: true}},
+		{name: "not a port", value: strPtr("8080,http"), wantErr: true},
+		{name: "out of range", value: strPtr("70000"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &v1.Service{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+			if tt.value != nil {
+				service.Annotations[key] = *tt.value
+			}
+
+			ports, err := GetDisabledPorts(service, key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDisabledPorts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(ports, tt.expected) {
+				t.Errorf("GetDisabledPorts() = %v, expected %v", ports, tt.expected)
+			}
+		})
+	}
+
+	if got := FormatDisabledPorts(map[int]bool{8443: true, 80: true, 9000: false}); got != "80,8443" {
+		t.Errorf("FormatDisabledPorts() = %q, expected %q", got, "80,8443")
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
 type MockRouter struct {
 	rules []*unifi.PortForward
 }
@@ -347,10 +390,27 @@ func (m *MockRouter) AddPort(ctx context.Context, config routers.PortConfig) err
 	return nil
 }
 
+func (m *MockRouter) CreatePort(ctx context.Context, config routers.PortConfig) (*unifi.PortForward, error) {
+	return &unifi.PortForward{}, nil
+}
+
 func (m *MockRouter) UpdatePort(ctx context.Context, externalPort int, config routers.PortConfig) error {
 	return nil
 }
 
+func (m *MockRouter) UpdatePortByID(ctx context.Context, ruleID string, config routers.PortConfig) error {
+	return nil
+}
+
+func (m *MockRouter) GetPortForwardByID(ctx context.Context, ruleID string) (*unifi.PortForward, bool, error) {
+	for _, rule := range m.rules {
+		if rule.ID == ruleID {
+			return rule, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
 func (m *MockRouter) CheckPort(ctx context.Context, port int, protocol string) (*unifi.PortForward, bool, error) {
 	for _, rule := range m.rules {
 		if rule.DstPort == string(rune(port)) && strings.EqualFold(rule.Proto, protocol) {
@@ -15,10 +15,13 @@ import (
 type Router interface {
 	ListAllPortForwards(ctx context.Context) ([]*unifi.PortForward, error)
 	AddPort(ctx context.Context, config routers.PortConfig) error
+	CreatePort(ctx context.Context, config routers.PortConfig) (*unifi.PortForward, error)
 	UpdatePort(ctx context.Context, externalPort int, config routers.PortConfig) error
+	UpdatePortByID(ctx context.Context, ruleID string, config routers.PortConfig) error
 	RemovePort(ctx context.Context, config routers.PortConfig) error
 	DeletePortForwardByID(ctx context.Context, ruleID string) error
 	CheckPort(ctx context.Context, port int, protocol string) (*unifi.PortForward, bool, error)
+	GetPortForwardByID(ctx context.Context, ruleID string) (*unifi.PortForward, bool, error)
 }
 
 // PortTracker defines the interface for port conflict tracking
@@ -0,0 +1,272 @@
+// Package lint checks port forward manifests without a cluster or router: the mapping
+// annotations of Services, the specs of PortForwardRules and ClusterPortForwardRules, external
+// ports claimed by more than one object, and ports outside the allowed or inside the denied
+// ranges.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/cleanup"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/manifests"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Severity is how serious a finding is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rule IDs of the findings
+const (
+	RuleInvalidAnnotation = "invalid-annotation"
+	RuleInvalidSpec       = "invalid-spec"
+	RuleDuplicatePort     = "duplicate-port"
+	RulePortNotAllowed    = "port-not-allowed"
+	RuleNotLoadBalancer   = "not-load-balancer"
+)
+
+// Rule describes a kind of finding
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+// Rules are the checks run by Lint
+var Rules = []Rule{
+	{RuleInvalidAnnotation, SeverityError, "The mapping annotation of a Service does not parse or references ports the Service does not have"},
+	{RuleInvalidSpec, SeverityError, "The spec of a PortForwardRule or ClusterPortForwardRule is rejected by the admission webhook"},
+	{RuleDuplicatePort, SeverityError, "An external port and protocol is forwarded by more than one object"},
+	{RulePortNotAllowed, SeverityError, "An external port is denied, or outside the allowed ports"},
+	{RuleNotLoadBalancer, SeverityWarning, "A Service with the mapping annotation is not of type LoadBalancer, so it is never forwarded"},
+}
+
+// Finding is a problem found in a manifest object
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+
+	// Object names the object, such as "Service default/web"
+	Object string `json:"object"`
+
+	// Source and Document locate the object, as read by manifests.Load
+	Source   string `json:"source"`
+	Document int    `json:"document"`
+}
+
+// Options configures the port checks
+type Options struct {
+	// AllowedPorts restricts external ports to these ranges (empty allows every port not denied)
+	AllowedPorts []v1alpha1.PortRange
+
+	// DeniedPorts lists external port ranges that may never be forwarded
+	DeniedPorts []v1alpha1.PortRange
+}
+
+// forward is an external port claimed by an object
+type forward struct {
+	port   v1alpha1.PolicyPort
+	object string
+	source manifests.Object
+}
+
+// Lint checks objects and returns the findings, ordered by source and document
+func Lint(objects []manifests.Object, opts Options) []Finding {
+	policy := &v1alpha1.PortForwardPolicy{
+		Spec: v1alpha1.PortForwardPolicySpec{AllowedPorts: opts.AllowedPorts, DeniedPorts: opts.DeniedPorts},
+	}
+
+	var findings []Finding
+	var forwards []forward
+	for _, obj := range objects {
+		var objectFindings []Finding
+		var request *v1alpha1.PolicyRequest
+		var name string
+
+		switch typed := obj.Object.(type) {
+		case *corev1.Service:
+			name = "Service " + typed.Namespace + "/" + typed.Name
+			objectFindings, request = lintService(typed)
+		case *v1alpha1.PortForwardRule:
+			name = "PortForwardRule " + typed.Namespace + "/" + typed.Name
+			rule := typed.DeepCopy()
+			applyDefaults(&rule.Spec)
+			objectFindings = fieldFindings(rule.ValidateCreate())
+			if len(objectFindings) == 0 {
+				r := rule.PolicyRequest()
+				request = &r
+			}
+		case *v1alpha1.ClusterPortForwardRule:
+			name = "ClusterPortForwardRule " + typed.Name
+			rule := typed.DeepCopy()
+			applyDefaults(&rule.Spec)
+			objectFindings = fieldFindings(rule.ValidateCreate())
+			if len(objectFindings) == 0 {
+				r := v1alpha1.PolicyRequest{Name: rule.Name}
+				for _, port := range rule.Spec.EffectivePorts() {
+					r.Ports = append(r.Ports, v1alpha1.PolicyPort{ExternalPort: port.ExternalPort, Protocol: port.Protocol})
+				}
+				request = &r
+			}
+		default:
+			continue
+		}
+
+		if request != nil {
+			for _, violation := range policy.Check(*request) {
+				objectFindings = append(objectFindings, Finding{Rule: RulePortNotAllowed, Severity: SeverityError, Message: violation})
+			}
+			for _, port := range request.Ports {
+				forwards = append(forwards, forward{port: port, object: name, source: obj})
+			}
+		}
+
+		for _, finding := range objectFindings {
+			finding.Object = name
+			finding.Source = obj.Source
+			finding.Document = obj.Document
+			findings = append(findings, finding)
+		}
+	}
+
+	findings = append(findings, duplicatePorts(forwards)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Source != findings[j].Source {
+			return findings[i].Source < findings[j].Source
+		}
+		return findings[i].Document < findings[j].Document
+	})
+	return findings
+}
+
+// lintService checks the mapping annotation of a Service and returns the ports it forwards
+func lintService(service *corev1.Service) ([]Finding, *v1alpha1.PolicyRequest) {
+	if _, ok := service.Annotations[config.FilterAnnotation]; !ok {
+		return nil, nil
+	}
+
+	configs, err := helpers.BuildPortConfigs(service, helpers.GetLBIP(service), config.FilterAnnotation)
+	if err != nil {
+		return []Finding{{Rule: RuleInvalidAnnotation, Severity: SeverityError, Message: err.Error()}}, nil
+	}
+
+	var findings []Finding
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		findings = append(findings, Finding{
+			Rule:     RuleNotLoadBalancer,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("service type is %q, the controller only forwards LoadBalancer Services", serviceType(service)),
+		})
+	}
+	request := helpers.ServicePolicyRequest(service, configs)
+	return findings, &request
+}
+
+// duplicatePorts reports every forward whose port and protocol an earlier object already forwards
+func duplicatePorts(forwards []forward) []Finding {
+	var findings []Finding
+	for i, current := range forwards {
+		for _, earlier := range forwards[:i] {
+			if earlier.object == current.object || earlier.port.ExternalPort != current.port.ExternalPort ||
+				!protocolsOverlap(earlier.port.Protocol, current.port.Protocol) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Rule:     RuleDuplicatePort,
+				Severity: SeverityError,
+				Message: fmt.Sprintf("external port %d/%s is also forwarded by %s (%s)",
+					current.port.ExternalPort, protocol(current.port.Protocol), earlier.object, location(earlier.source)),
+				Object:   current.object,
+				Source:   current.source.Source,
+				Document: current.source.Document,
+			})
+			break
+		}
+	}
+	return findings
+}
+
+// applyDefaults sets the CRD defaults the API server applies before the webhook validates a
+// rule, for the fields the validation checks
+func applyDefaults(spec *v1alpha1.PortForwardRuleSpec) {
+	if spec.Protocol == "" {
+		spec.Protocol = "tcp"
+	}
+	for i := range spec.Ports {
+		if spec.Ports[i].Protocol == "" {
+			spec.Ports[i].Protocol = "tcp"
+		}
+	}
+	if spec.ConflictPolicy == "" {
+		spec.ConflictPolicy = "warn"
+	}
+}
+
+// fieldFindings turns validation errors into invalid-spec findings
+func fieldFindings(errs field.ErrorList) []Finding {
+	findings := make([]Finding, 0, len(errs))
+	for _, err := range errs {
+		findings = append(findings, Finding{Rule: RuleInvalidSpec, Severity: SeverityError, Message: err.Error()})
+	}
+	return findings
+}
+
+// Errors returns the number of findings of error severity
+func Errors(findings []Finding) int {
+	errors := 0
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			errors++
+		}
+	}
+	return errors
+}
+
+// ParsePortRanges parses ports and port ranges such as 22 or 6000-6100, as the clean command
+// selects them
+func ParsePortRanges(values []string) ([]v1alpha1.PortRange, error) {
+	ranges := make([]v1alpha1.PortRange, 0, len(values))
+	for _, value := range values {
+		r, err := cleanup.ParsePortRange(value)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, v1alpha1.PortRange{From: r.From, To: r.To})
+	}
+	return ranges, nil
+}
+
+func location(obj manifests.Object) string {
+	return fmt.Sprintf("%s, document %d", obj.Source, obj.Document)
+}
+
+func serviceType(service *corev1.Service) corev1.ServiceType {
+	if service.Spec.Type == "" {
+		return corev1.ServiceTypeClusterIP
+	}
+	return service.Spec.Type
+}
+
+// protocolsOverlap reports whether two protocols forward any common traffic
+func protocolsOverlap(a, b string) bool {
+	return protocol(a) == protocol(b) || a == "both" || b == "both"
+}
+
+func protocol(p string) string {
+	if p == "" {
+		return "tcp"
+	}
+	return p
+}
@@ -0,0 +1,174 @@
+package lint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/manifests"
+)
+
+const testManifests = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  annotations:
+    unifi-port-forward.fiskhe.st/mapping: "8080:http"
+spec:
+  type: LoadBalancer
+  ports:
+  - name: http
+    port: 80
+    protocol: TCP
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: typo
+  annotations:
+    unifi-port-forward.fiskhe.st/mapping: "8443:htps"
+spec:
+  type: LoadBalancer
+  ports:
+  - name: https
+    port: 443
+    protocol: TCP
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: internal
+  annotations:
+    unifi-port-forward.fiskhe.st/mapping: "9000:metrics"
+spec:
+  ports:
+  - name: metrics
+    port: 9000
+    protocol: TCP
+---
+apiVersion: unifi-port-forward.fiskhe.st/v1beta1
+kind: PortForwardRule
+metadata:
+  name: web-alt
+spec:
+  ports:
+  - externalPort: 8080
+    protocol: both
+    targetPort: http
+  serviceRef:
+    name: web
+---
+apiVersion: unifi-port-forward.fiskhe.st/v1alpha1
+kind: PortForwardRule
+metadata:
+  name: broken
+spec:
+  externalPort: 70000
+  protocol: sctp
+  destinationIP: 192.168.1.10
+  destinationPort: 22
+---
+apiVersion: unifi-port-forward.fiskhe.st/v1alpha1
+kind: ClusterPortForwardRule
+metadata:
+  name: ssh
+spec:
+  externalPort: 22
+  protocol: tcp
+  destinationIP: 192.168.1.10
+  destinationPort: 22
+`
+
+func loadTestManifests(t *testing.T) []manifests.Object {
+	t.Helper()
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := manifests.Load(scheme, []string{manifests.Stdin}, strings.NewReader(testManifests), "default")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return objects
+}
+
+type row struct {
+	rule, object string
+	document     int
+}
+
+func rows(findings []Finding) []row {
+	var got []row
+	for _, finding := range findings {
+		got = append(got, row{finding.Rule, finding.Object, finding.Document})
+	}
+	return got
+}
+
+func TestLint(t *testing.T) {
+	findings := Lint(loadTestManifests(t), Options{})
+
+	expected := []row{
+		{RuleInvalidAnnotation, "Service default/typo", 2},
+		{RuleNotLoadBalancer, "Service default/internal", 3},
+		{RuleDuplicatePort, "PortForwardRule default/web-alt", 4},
+		{RuleInvalidSpec, "PortForwardRule default/broken", 5},
+		{RuleInvalidSpec, "PortForwardRule default/broken", 5},
+	}
+	if got := rows(findings); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Lint() = %v, want %v", got, expected)
+	}
+
+	duplicate := findings[2]
+	if !strings.Contains(duplicate.Message, "8080/both") || !strings.Contains(duplicate.Message, "Service default/web (-, document 1)") {
+		t.Errorf("duplicate message = %q, want the port and the first object", duplicate.Message)
+	}
+	if Errors(findings) != 4 {
+		t.Errorf("Errors() = %d, want 4", Errors(findings))
+	}
+}
+
+func TestLint_PortRanges(t *testing.T) {
+	allowed, err := ParsePortRanges([]string{"8000-9000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied, err := ParsePortRanges([]string{"8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []row
+	for _, finding := range Lint(loadTestManifests(t), Options{AllowedPorts: allowed, DeniedPorts: denied}) {
+		if finding.Rule == RulePortNotAllowed {
+			got = append(got, row{finding.Rule, finding.Object, finding.Document})
+		}
+	}
+
+	expected := []row{
+		{RulePortNotAllowed, "Service default/web", 1},
+		{RulePortNotAllowed, "PortForwardRule default/web-alt", 4},
+		{RulePortNotAllowed, "ClusterPortForwardRule ssh", 6},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Lint() port findings = %v, want %v", got, expected)
+	}
+}
+
+func TestParsePortRanges(t *testing.T) {
+	ranges, err := ParsePortRanges([]string{"22", "6000-6100"})
+	if err != nil {
+		t.Fatalf("ParsePortRanges() error = %v", err)
+	}
+	expected := []v1alpha1.PortRange{{From: 22, To: 22}, {From: 6000, To: 6100}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("ParsePortRanges() = %v, want %v", ranges, expected)
+	}
+
+	for _, invalid := range []string{"0", "100-50", "http", "1-70000"} {
+		if _, err := ParsePortRanges([]string{invalid}); err == nil {
+			t.Errorf("ParsePortRanges(%q) succeeded, want an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	findings := []Finding{
+		{Rule: RuleInvalidAnnotation, Severity: SeverityError, Message: "bad", Object: "Service default/typo", Source: "deploy/web.yaml", Document: 2},
+		{Rule: RuleNotLoadBalancer, Severity: SeverityWarning, Message: "type", Object: "Service default/internal", Source: "-", Document: 1},
+	}
+
+	var out bytes.Buffer
+	if err := WriteSARIF(&out, findings); err != nil {
+		t.Fatalf("WriteSARIF() error = %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("WriteSARIF() wrote invalid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("WriteSARIF() = version %s with %d runs, want one 2.1.0 run", log.Version, len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(Rules) {
+		t.Errorf("driver rules = %d, want %d", len(run.Tool.Driver.Rules), len(Rules))
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("results = %d, want 2", len(run.Results))
+	}
+
+	first := run.Results[0]
+	if first.RuleID != RuleInvalidAnnotation || first.Level != "error" ||
+		first.Locations[0].PhysicalLocation == nil || first.Locations[0].PhysicalLocation.ArtifactLocation.URI != "deploy/web.yaml" {
+		t.Errorf("first result = %+v, want an error located in deploy/web.yaml", first)
+	}
+	second := run.Results[1]
+	if second.Level != "warning" || second.Locations[0].PhysicalLocation != nil ||
+		second.Locations[0].LogicalLocations[0].FullyQualifiedName != "Service default/internal" {
+		t.Errorf("second result = %+v, want a warning located by object only", second)
+	}
+}
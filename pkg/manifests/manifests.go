@@ -0,0 +1,235 @@
+// Package manifests reads Kubernetes objects from manifest files so commands can work on them
+// without a cluster
+package manifests
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/api/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Stdin is the path that reads manifests from standard input
+const Stdin = "-"
+
+// manifestExtensions are the file extensions read from directories
+var manifestExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// Object is a decoded object together with where it was read from
+type Object struct {
+	client.Object
+
+	// Source is the file the object was read from, "-" for standard input
+	Source string
+
+	// Document is the 1-based index of the YAML document within Source
+	Document int
+}
+
+// NewScheme returns a scheme with the kinds the controllers work with: Services, EndpointSlices,
+// Namespaces and the port forward API in both versions
+func NewScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		corev1.AddToScheme,
+		discoveryv1.AddToScheme,
+		v1alpha1.AddToScheme,
+		v1beta1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			return nil, err
+		}
+	}
+	return scheme, nil
+}
+
+// Load reads the objects in the given files and directories. Directories are walked for
+// .yaml, .yml and .json files, and "-" reads stdin. Files may hold several YAML documents and
+// List objects, as kubectl and kustomize produce them. Kinds that scheme does not know are
+// skipped. Rules in v1beta1 are converted to v1alpha1, the version the controllers work with.
+// Namespaced objects without a namespace are placed in namespace.
+func Load(scheme *runtime.Scheme, paths []string, stdin io.Reader, namespace string) ([]Object, error) {
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+
+	var objects []Object
+	for _, path := range paths {
+		if path == Stdin {
+			decoded, err := decodeAll(decoder, stdin, Stdin, namespace)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, decoded...)
+			continue
+		}
+
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			f, err := os.Open(file) // #nosec G304 - reading user supplied manifests is the point
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", file, err)
+			}
+			decoded, err := decodeAll(decoder, f, file, namespace)
+			_ = f.Close()
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, decoded...)
+		}
+	}
+
+	return objects, nil
+}
+
+// manifestFiles returns path itself, or the manifest files below it when it is a directory
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && manifestExtensions[strings.ToLower(filepath.Ext(file))] {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// decodeAll decodes every YAML document of r
+func decodeAll(decoder runtime.Decoder, r io.Reader, source, namespace string) ([]Object, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+
+	var objects []Object
+	for document := 1; ; document++ {
+		data, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read document %d: %w", source, document, err)
+		}
+		if len(bytes.TrimSpace(stripComments(data))) == 0 {
+			continue
+		}
+
+		decoded, err := decode(decoder, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", source, document, err)
+		}
+		for _, obj := range decoded {
+			if obj.GetNamespace() == "" && !clusterScoped(obj) {
+				obj.SetNamespace(namespace)
+			}
+			objects = append(objects, Object{Object: obj, Source: source, Document: document})
+		}
+	}
+}
+
+// decode decodes a single document, expanding List objects into their items
+func decode(decoder runtime.Decoder, data []byte) ([]client.Object, error) {
+	obj, _, err := decoder.Decode(data, nil, nil)
+	if runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := obj.(*corev1.List)
+	if !ok {
+		converted, err := convert(obj)
+		if err != nil || converted == nil {
+			return nil, err
+		}
+		return []client.Object{converted}, nil
+	}
+
+	var objects []client.Object
+	for _, item := range list.Items {
+		decoded, err := decode(decoder, item.Raw)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, decoded...)
+	}
+	return objects, nil
+}
+
+// convert converts rules to v1alpha1 and returns other objects as they are
+func convert(obj runtime.Object) (client.Object, error) {
+	switch hub := obj.(type) {
+	case *v1beta1.PortForwardRule:
+		rule := &v1alpha1.PortForwardRule{}
+		if err := rule.ConvertFrom(hub); err != nil {
+			return nil, fmt.Errorf("failed to convert PortForwardRule %s/%s: %w", hub.Namespace, hub.Name, err)
+		}
+		rule.APIVersion = v1alpha1.SchemeGroupVersion.String()
+		rule.Kind = "PortForwardRule"
+		return rule, nil
+	case *v1beta1.ClusterPortForwardRule:
+		rule := &v1alpha1.ClusterPortForwardRule{}
+		if err := rule.ConvertFrom(hub); err != nil {
+			return nil, fmt.Errorf("failed to convert ClusterPortForwardRule %s: %w", hub.Name, err)
+		}
+		rule.APIVersion = v1alpha1.SchemeGroupVersion.String()
+		rule.Kind = "ClusterPortForwardRule"
+		return rule, nil
+	}
+
+	object, ok := obj.(client.Object)
+	if !ok {
+		return nil, nil
+	}
+	return object, nil
+}
+
+// clusterScoped reports whether obj is of a cluster-scoped kind
+func clusterScoped(obj client.Object) bool {
+	switch obj.(type) {
+	case *v1alpha1.ClusterPortForwardRule, *v1alpha1.PortForwardPolicy, *corev1.Namespace:
+		return true
+	}
+	return false
+}
+
+// stripComments drops comment lines so documents holding only comments are skipped
+func stripComments(data []byte) []byte {
+	var out bytes.Buffer
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			continue
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,194 @@
+package manifests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testManifests = `# A service and a rule
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  type: LoadBalancer
+---
+# comments only
+---
+apiVersion: unifi-port-forward.fiskhe.st/v1beta1
+kind: PortForwardRule
+metadata:
+  name: web
+  namespace: apps
+spec:
+  ports:
+  - externalPort: 8080
+    protocol: tcp
+    targetPort: http
+  serviceRef:
+    name: web
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: db
+- apiVersion: unifi-port-forward.fiskhe.st/v1alpha1
+  kind: ClusterPortForwardRule
+  metadata:
+    name: ssh
+  spec:
+    externalPort: 22
+    protocol: tcp
+    destinationIP: 192.168.1.10
+    destinationPort: 22
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: skipped
+`
+
+func TestLoad(t *testing.T) {
+	scheme, err := NewScheme()
+	if err != nil {
+		t.Fatalf("NewScheme() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "all.yaml"), []byte(testManifests), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := Load(scheme, []string{dir}, nil, "default")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(objects) != 4 {
+		t.Fatalf("Load() returned %d objects, want 4", len(objects))
+	}
+
+	web, ok := objects[0].Object.(*corev1.Service)
+	if !ok || web.Name != "web" || web.Namespace != "default" {
+		t.Errorf("objects[0] = %T %s/%s, want Service default/web", objects[0].Object, objects[0].GetNamespace(), objects[0].GetName())
+	}
+	if objects[0].Document != 1 || !strings.HasSuffix(objects[0].Source, "all.yaml") {
+		t.Errorf("objects[0] read from %s document %d, want all.yaml document 1", objects[0].Source, objects[0].Document)
+	}
+
+	rule, ok := objects[1].Object.(*v1alpha1.PortForwardRule)
+	if !ok {
+		t.Fatalf("objects[1] = %T, want a v1beta1 rule converted to *v1alpha1.PortForwardRule", objects[1].Object)
+	}
+	if rule.Namespace != "apps" || rule.Spec.ExternalPort != 8080 || rule.Spec.ServiceRef == nil {
+		t.Errorf("converted rule = %s/%s %+v", rule.Namespace, rule.Name, rule.Spec)
+	}
+	if objects[1].Document != 3 {
+		t.Errorf("objects[1].Document = %d, want 3", objects[1].Document)
+	}
+
+	if db, ok := objects[2].Object.(*corev1.Service); !ok || db.Namespace != "default" {
+		t.Errorf("objects[2] = %T in %q, want the List item Service in default", objects[2].Object, objects[2].GetNamespace())
+	}
+	if cluster, ok := objects[3].Object.(*v1alpha1.ClusterPortForwardRule); !ok || cluster.Namespace != "" {
+		t.Errorf("objects[3] = %T in %q, want a ClusterPortForwardRule without namespace", objects[3].Object, objects[3].GetNamespace())
+	}
+}
+
+func TestLoad_Stdin(t *testing.T) {
+	scheme, err := NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := Load(scheme, []string{Stdin}, strings.NewReader(testManifests), "default")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(objects) != 4 || objects[0].Source != Stdin {
+		t.Errorf("Load() from stdin returned %d objects from %q, want 4 from %q", len(objects), objects[0].Source, Stdin)
+	}
+
+	if _, err := Load(scheme, []string{Stdin}, strings.NewReader("kind: [broken"), "default"); err == nil {
+		t.Error("Load() of invalid YAML should fail")
+	}
+}
+
+func TestStore(t *testing.T) {
+	scheme, err := NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewStore(scheme,
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", Labels: map[string]string{"app": "web"}}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "web", Labels: map[string]string{"app": "web"}}},
+	)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	var service corev1.Service
+	if err := store.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, &service); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if service.Labels["app"] != "web" {
+		t.Errorf("Get() labels = %v", service.Labels)
+	}
+
+	// The store hands out copies
+	service.Labels["app"] = "changed"
+	if err := store.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, &service); err != nil || service.Labels["app"] != "web" {
+		t.Errorf("Get() after modifying a copy = %v, %v", service.Labels, err)
+	}
+
+	err = store.Get(ctx, types.NamespacedName{Namespace: "default", Name: "missing"}, &service)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Get() of a missing object error = %v, want NotFound", err)
+	}
+
+	var services corev1.ServiceList
+	if err := store.List(ctx, &services); err != nil || len(services.Items) != 3 {
+		t.Errorf("List() = %d items, %v, want 3", len(services.Items), err)
+	}
+	if err := store.List(ctx, &services, client.InNamespace("default"), client.MatchingLabels{"app": "web"}); err != nil || len(services.Items) != 1 {
+		t.Errorf("List() in default with app=web = %d items, %v, want 1", len(services.Items), err)
+	}
+	if err := store.List(ctx, &services, client.MatchingFields{"spec.type": "LoadBalancer"}); err == nil {
+		t.Error("List() with a field selector should fail")
+	}
+
+	if err := store.Update(ctx, &service); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Update() error = %v, want ErrReadOnly", err)
+	}
+	if err := store.Status().Update(ctx, &service); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Status().Update() error = %v, want ErrReadOnly", err)
+	}
+
+	namespaced, err := store.IsObjectNamespaced(&v1alpha1.ClusterPortForwardRule{})
+	if err != nil || namespaced {
+		t.Errorf("IsObjectNamespaced(ClusterPortForwardRule) = %v, %v, want false", namespaced, err)
+	}
+}
@@ -0,0 +1,183 @@
+package manifests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ErrReadOnly is returned by every write to a Store
+var ErrReadOnly = errors.New("manifest store is read-only")
+
+// Store is a read-only client.Client serving a fixed set of objects, so code written against
+// the cluster can run on manifests. Lists support namespace and label selectors.
+type Store struct {
+	scheme  *runtime.Scheme
+	objects map[schema.GroupVersionKind]map[types.NamespacedName]client.Object
+}
+
+var _ client.Client = &Store{}
+
+// NewStore returns a Store serving objects
+func NewStore(scheme *runtime.Scheme, objects ...client.Object) (*Store, error) {
+	s := &Store{
+		scheme:  scheme,
+		objects: make(map[schema.GroupVersionKind]map[types.NamespacedName]client.Object),
+	}
+	for _, obj := range objects {
+		if err := s.Add(obj); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add adds obj to the store, replacing an object of the same kind and name
+func (s *Store) Add(obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, s.scheme)
+	if err != nil {
+		return err
+	}
+	if s.objects[gvk] == nil {
+		s.objects[gvk] = make(map[types.NamespacedName]client.Object)
+	}
+	s.objects[gvk][client.ObjectKeyFromObject(obj)] = obj.DeepCopyObject().(client.Object)
+	return nil
+}
+
+// Get implements client.Reader
+func (s *Store) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	gvk, err := apiutil.GVKForObject(obj, s.scheme)
+	if err != nil {
+		return err
+	}
+
+	stored, exists := s.objects[gvk][key]
+	if !exists {
+		return apierrors.NewNotFound(resourceFor(gvk), key.Name)
+	}
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(stored.DeepCopyObject()).Elem())
+	return nil
+}
+
+// List implements client.Reader
+func (s *Store) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listGVK, err := apiutil.GVKForObject(list, s.scheme)
+	if err != nil {
+		return err
+	}
+	gvk := listGVK.GroupVersion().WithKind(strings.TrimSuffix(listGVK.Kind, "List"))
+
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.FieldSelector != nil && !listOpts.FieldSelector.Empty() {
+		return fmt.Errorf("field selectors are not supported on manifests")
+	}
+
+	var items []runtime.Object
+	for key, obj := range s.objects[gvk] {
+		if listOpts.Namespace != "" && key.Namespace != listOpts.Namespace {
+			continue
+		}
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		items = append(items, obj.DeepCopyObject())
+	}
+	return meta.SetList(list, items)
+}
+
+// Create implements client.Writer
+func (s *Store) Create(context.Context, client.Object, ...client.CreateOption) error {
+	return ErrReadOnly
+}
+
+// Delete implements client.Writer
+func (s *Store) Delete(context.Context, client.Object, ...client.DeleteOption) error {
+	return ErrReadOnly
+}
+
+// Update implements client.Writer
+func (s *Store) Update(context.Context, client.Object, ...client.UpdateOption) error {
+	return ErrReadOnly
+}
+
+// Patch implements client.Writer
+func (s *Store) Patch(context.Context, client.Object, client.Patch, ...client.PatchOption) error {
+	return ErrReadOnly
+}
+
+// DeleteAllOf implements client.Writer
+func (s *Store) DeleteAllOf(context.Context, client.Object, ...client.DeleteAllOfOption) error {
+	return ErrReadOnly
+}
+
+// Status implements client.StatusClient
+func (s *Store) Status() client.SubResourceWriter {
+	return readOnlySubResource{}
+}
+
+// SubResource implements client.SubResourceClientConstructor
+func (s *Store) SubResource(string) client.SubResourceClient {
+	return readOnlySubResource{}
+}
+
+// Scheme implements client.Client
+func (s *Store) Scheme() *runtime.Scheme {
+	return s.scheme
+}
+
+// RESTMapper implements client.Client; manifests carry no REST mapping
+func (s *Store) RESTMapper() meta.RESTMapper {
+	return nil
+}
+
+// GroupVersionKindFor implements client.Client
+func (s *Store) GroupVersionKindFor(obj runtime.Object) (schema.GroupVersionKind, error) {
+	return apiutil.GVKForObject(obj, s.scheme)
+}
+
+// IsObjectNamespaced implements client.Client
+func (s *Store) IsObjectNamespaced(obj runtime.Object) (bool, error) {
+	object, ok := obj.(client.Object)
+	if !ok {
+		return false, fmt.Errorf("%T is not a client.Object", obj)
+	}
+	return !clusterScoped(object), nil
+}
+
+// readOnlySubResource rejects every sub-resource operation of a Store
+type readOnlySubResource struct{}
+
+func (readOnlySubResource) Get(context.Context, client.Object, client.Object, ...client.SubResourceGetOption) error {
+	return ErrReadOnly
+}
+
+func (readOnlySubResource) Create(context.Context, client.Object, client.Object, ...client.SubResourceCreateOption) error {
+	return ErrReadOnly
+}
+
+func (readOnlySubResource) Update(context.Context, client.Object, ...client.SubResourceUpdateOption) error {
+	return ErrReadOnly
+}
+
+func (readOnlySubResource) Patch(context.Context, client.Object, client.Patch, ...client.SubResourcePatchOption) error {
+	return ErrReadOnly
+}
+
+// resourceFor guesses the resource of a kind for NotFound errors
+func resourceFor(gvk schema.GroupVersionKind) schema.GroupResource {
+	plural, _ := meta.UnsafeGuessKindToResource(gvk)
+	return plural.GroupResource()
+}
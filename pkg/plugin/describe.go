@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"unifi-port-forward/pkg/controller"
+)
+
+// Describe writes every field of the router rules, as kubectl describe does for objects
+func Describe(out io.Writer, statuses []controller.RouterRuleStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	for i, status := range statuses {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		owner := status.OwnerKind
+		if status.Owner != "" {
+			owner += " " + status.Owner
+		}
+		destination := status.Destination
+		if status.ForwardPort != "" {
+			destination += ":" + status.ForwardPort
+		}
+
+		fields := [][2]string{
+			{"Name", status.Name},
+			{"ID", status.ID},
+			{"External Port", status.ExternalPort},
+			{"Protocol", status.Protocol},
+			{"Destination", destination},
+			{"Enabled", fmt.Sprint(status.Enabled)},
+			{"Interface", status.Interface},
+			{"Source", status.Source},
+			{"Owner", owner},
+			{"Drift", status.Drift},
+			{"Drifted Fields", strings.Join(status.DriftFields, ", ")},
+		}
+		if status.LastApplied != nil {
+			fields = append(fields, [2]string{"Last Applied", status.LastApplied.Format(time.RFC3339)})
+		}
+
+		for _, field := range fields {
+			value := field[1]
+			if value == "" {
+				value = "<none>"
+			}
+			fmt.Fprintf(w, "%s:\t%s\n", field[0], value)
+		}
+	}
+	return w.Flush()
+}
+
+// WhoOwns writes a line per router rule naming the object owning it
+func WhoOwns(out io.Writer, statuses []controller.RouterRuleStatus) {
+	for _, status := range statuses {
+		switch status.OwnerKind {
+		case controller.OwnerManual:
+			fmt.Fprintf(out, "%s/%s: %q was made by hand on the router\n", status.ExternalPort, status.Protocol, status.Name)
+		case controller.OwnerOrphaned:
+			fmt.Fprintf(out, "%s/%s: %q is orphaned, its owner %s no longer exists\n", status.ExternalPort, status.Protocol, status.Name, status.Owner)
+		default:
+			fmt.Fprintf(out, "%s/%s: %s %s\n", status.ExternalPort, status.Protocol, status.OwnerKind, status.Owner)
+		}
+	}
+}
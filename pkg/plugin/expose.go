@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ParseServiceRef returns the name of the Service in a reference such as svc/web,
+// service/web, services/web or web
+func ParseServiceRef(ref string) (string, error) {
+	kind, name, found := strings.Cut(ref, "/")
+	if !found {
+		kind, name = "svc", ref
+	}
+	switch strings.ToLower(kind) {
+	case "svc", "service", "services":
+	default:
+		return "", fmt.Errorf("only services can be exposed, got %q", ref)
+	}
+	if name == "" {
+		return "", fmt.Errorf("missing service name in %q", ref)
+	}
+	return name, nil
+}
+
+// MergeMappings adds mappings such as 8080:http or https to the value of a mapping annotation.
+// A mapping replaces the existing mapping of the same service port.
+func MergeMappings(existing string, mappings []string) string {
+	var merged []string
+	index := make(map[string]int)
+	add := func(mapping string) {
+		mapping = strings.TrimSpace(mapping)
+		if mapping == "" {
+			return
+		}
+		portName := mapping
+		if _, name, found := strings.Cut(mapping, ":"); found {
+			portName = name
+		}
+		if i, exists := index[portName]; exists {
+			merged[i] = mapping
+			return
+		}
+		index[portName] = len(merged)
+		merged = append(merged, mapping)
+	}
+
+	for _, mapping := range strings.Split(existing, ",") {
+		add(mapping)
+	}
+	for _, mapping := range mappings {
+		add(mapping)
+	}
+	return strings.Join(merged, ",")
+}
+
+// Expose adds mappings to the mapping annotation of a Service and returns the new value. The
+// mappings are checked against the ports of the Service before it is changed.
+func Expose(ctx context.Context, c client.Client, key types.NamespacedName, mappings []string) (string, error) {
+	service := &corev1.Service{}
+	if err := c.Get(ctx, key, service); err != nil {
+		return "", fmt.Errorf("failed to get service %s: %w", key, err)
+	}
+
+	value := MergeMappings(service.Annotations[config.FilterAnnotation], mappings)
+	if err := helpers.ValidatePortMappings(service, value); err != nil {
+		return "", err
+	}
+	if service.Annotations[config.FilterAnnotation] == value {
+		return value, nil
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if service.Annotations == nil {
+		service.Annotations = make(map[string]string)
+	}
+	service.Annotations[config.FilterAnnotation] = value
+	if err := c.Patch(ctx, service, patch); err != nil {
+		return "", fmt.Errorf("failed to annotate service %s: %w", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,296 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/pkg/stateapi"
+	"unifi-port-forward/testutils"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+func newTestClient(t *testing.T) *testutils.FakeKubernetesClient {
+	t.Helper()
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := testutils.NewFakeKubernetesClient(t, scheme)
+	c.Services["default/web"] = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{config.FilterAnnotation: "8080:http"},
+		},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "http", Port: 80},
+			{Name: "https", Port: 443},
+		}},
+	}
+	return c
+}
+
+func TestParseServiceRef(t *testing.T) {
+	for ref, want := range map[string]string{"svc/web": "web", "service/web": "web", "services/web": "web", "web": "web"} {
+		if got, err := ParseServiceRef(ref); err != nil || got != want {
+			t.Errorf("ParseServiceRef(%q) = %q, %v, want %q", ref, got, err, want)
+		}
+	}
+	for _, ref := range []string{"deploy/web", "svc/"} {
+		if _, err := ParseServiceRef(ref); err == nil {
+			t.Errorf("ParseServiceRef(%q) succeeded, want an error", ref)
+		}
+	}
+}
+
+func TestMergeMappings(t *testing.T) {
+	tests := []struct {
+		existing string
+		mappings []string
+		want     string
+	}{
+		{existing: "", mappings: []string{"8080:http"}, want: "8080:http"},
+		{existing: "8080:http", mappings: []string{"8443:https"}, want: "8080:http,8443:https"},
+		{existing: "8080:http, https", mappings: []string{"9090:http"}, want: "9090:http,https"},
+		{existing: "8080:http", mappings: []string{"http"}, want: "http"},
+	}
+
+	for _, tt := range tests {
+		if got := MergeMappings(tt.existing, tt.mappings); got != tt.want {
+			t.Errorf("MergeMappings(%q, %v) = %q, want %q", tt.existing, tt.mappings, got, tt.want)
+		}
+	}
+}
+
+func TestExpose(t *testing.T) {
+	c := newTestClient(t)
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+
+	value, err := Expose(context.Background(), c, key, []string{"8443:https"})
+	if err != nil {
+		t.Fatalf("Expose() error = %v", err)
+	}
+	if value != "8080:http,8443:https" || c.Services["default/web"].Annotations[config.FilterAnnotation] != value {
+		t.Errorf("Expose() = %q, annotation %q, want 8080:http,8443:https", value, c.Services["default/web"].Annotations[config.FilterAnnotation])
+	}
+
+	if _, err := Expose(context.Background(), c, key, []string{"9000:grpc"}); err == nil {
+		t.Error("Expose() of a port the service does not have succeeded")
+	}
+	if c.Services["default/web"].Annotations[config.FilterAnnotation] != value {
+		t.Error("Expose() changed the service despite an invalid mapping")
+	}
+}
+
+func TestSetEnabled(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("service", func(t *testing.T) {
+		c := newTestClient(t)
+
+		changed, err := SetEnabled(ctx, c, "Service", "default/web", 8080, false)
+		if err != nil || !changed {
+			t.Fatalf("SetEnabled(false) = %v, %v, want a change", changed, err)
+		}
+		if got := c.Services["default/web"].Annotations[config.DisabledPortsAnnotation]; got != "8080" {
+			t.Errorf("disabled ports = %q, want 8080", got)
+		}
+
+		if changed, _ := SetEnabled(ctx, c, "Service", "default/web", 8080, false); changed {
+			t.Error("SetEnabled(false) of a disabled port changed the service")
+		}
+
+		if changed, err := SetEnabled(ctx, c, "Service", "default/web", 8080, true); err != nil || !changed {
+			t.Fatalf("SetEnabled(true) = %v, %v, want a change", changed, err)
+		}
+		if _, exists := c.Services["default/web"].Annotations[config.DisabledPortsAnnotation]; exists {
+			t.Error("enabling the last disabled port left the annotation behind")
+		}
+	})
+
+	t.Run("rule", func(t *testing.T) {
+		c := newTestClient(t)
+		c.Rules["apps/api"] = &v1alpha1.PortForwardRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps"},
+			Spec:       v1alpha1.PortForwardRuleSpec{ExternalPort: 6443, Enabled: true},
+		}
+		c.Rules["apps/game"] = &v1alpha1.PortForwardRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "game", Namespace: "apps"},
+			Spec: v1alpha1.PortForwardRuleSpec{Enabled: true, Ports: []v1alpha1.PortForwardPort{
+				{Name: "game", ExternalPort: 27015},
+				{Name: "rcon", ExternalPort: 27020},
+			}},
+		}
+
+		if changed, err := SetEnabled(ctx, c, "PortForwardRule", "apps/api", 6443, false); err != nil || !changed {
+			t.Fatalf("SetEnabled() = %v, %v, want a change", changed, err)
+		}
+		if c.Rules["apps/api"].Spec.Enabled {
+			t.Error("SetEnabled(false) left the rule enabled")
+		}
+
+		// Only the matching port of a multi-port rule is disabled
+		if changed, err := SetEnabled(ctx, c, "PortForwardRule", "apps/game", 27020, false); err != nil || !changed {
+			t.Fatalf("SetEnabled() = %v, %v, want a change", changed, err)
+		}
+		game := c.Rules["apps/game"].Spec
+		if !game.Enabled || game.Ports[0].Enabled != nil || game.Ports[1].Enabled == nil || *game.Ports[1].Enabled {
+			t.Errorf("rule spec = %+v, want only port 27020 disabled", game)
+		}
+
+		if _, err := SetEnabled(ctx, c, "PortForwardRule", "apps/game", 9999, false); err == nil {
+			t.Error("SetEnabled() of a port the rule does not forward succeeded")
+		}
+	})
+
+	t.Run("manual", func(t *testing.T) {
+		if _, err := SetEnabled(ctx, newTestClient(t), controller.OwnerManual, "", 631, false); err == nil {
+			t.Error("SetEnabled() of a manual rule succeeded")
+		}
+	})
+}
+
+func TestRequestStateToken(t *testing.T) {
+	var request authenticationv1.TokenRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost ||
+			r.URL.Path != "/api/v1/namespaces/unifi-port-forward/serviceaccounts/unifi-port-forward-state-reader/token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid TokenRequest: %v", err)
+		}
+		response := request.DeepCopy()
+		response.Status.Token = "state-token"
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	restConfig := &rest.Config{Host: server.URL, BearerToken: "viewer-token"}
+	token, err := RequestStateToken(context.Background(), restConfig, DefaultStateNamespace, DefaultStateServiceAccount)
+	if err != nil {
+		t.Fatalf("RequestStateToken() error = %v", err)
+	}
+	if token != "state-token" {
+		t.Errorf("RequestStateToken() = %q, want the requested token", token)
+	}
+	if !reflect.DeepEqual(request.Spec.Audiences, []string{stateapi.Audience}) ||
+		request.Spec.ExpirationSeconds == nil || *request.Spec.ExpirationSeconds != 600 {
+		t.Errorf("TokenRequest spec = %+v, want the state API audience for 10 minutes", request.Spec)
+	}
+
+	if _, err := RequestStateToken(context.Background(), restConfig, DefaultStateNamespace, "other"); err == nil ||
+		!strings.Contains(err.Error(), "portforward-viewer") {
+		t.Errorf("RequestStateToken() error = %v, want a hint about the viewer role", err)
+	}
+}
+
+func TestStateClient_Rules(t *testing.T) {
+	var query, token, authorization string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/namespaces/unifi-port-forward/services/https:unifi-port-forward-state:https/proxy" + stateapi.RulesPath,
+			stateapi.RulesPath:
+			query, token, authorization = r.URL.RawQuery, r.Header.Get(stateapi.TokenHeader), r.Header.Get("Authorization")
+			_ = json.NewEncoder(w).Encode(stateapi.RulesResponse{Rules: []controller.RouterRuleStatus{
+				{ID: "1", ExternalPort: "8080", OwnerKind: "Service", Owner: "default/web"},
+			}})
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"kind":"Status","message":"services \"other\" is forbidden"}`))
+		}
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	restConfig := &rest.Config{Host: server.URL, BearerToken: "viewer-token"}
+	stateClient, err := NewProxyStateClient(restConfig, DefaultStateNamespace, DefaultStateService, "state-token")
+	if err != nil {
+		t.Fatalf("NewProxyStateClient() error = %v", err)
+	}
+	rules, err := stateClient.Rules(context.Background(), controller.StatusFilter{Ports: []int{8080}})
+	if err != nil {
+		t.Fatalf("Rules() error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].Owner != "default/web" || query != "port=8080" {
+		t.Errorf("Rules() = %+v with query %q, want the web rule for port=8080", rules, query)
+	}
+	// The kubeconfig token only authenticates to the API server, which drops it before proxying
+	if token != "state-token" || authorization != "Bearer viewer-token" {
+		t.Errorf("proxied request carried %q in %s and %q in Authorization, want the state token and the kubeconfig token",
+			token, stateapi.TokenHeader, authorization)
+	}
+
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	token = ""
+	tlsConfig := tlsServer.Client().Transport.(*http.Transport).TLSClientConfig
+	direct := NewStateClient(tlsServer.URL, "state-token", tlsConfig)
+	if _, err := direct.Rules(context.Background(), controller.StatusFilter{}); err != nil || token != "state-token" || authorization != "" {
+		t.Errorf("Rules() error = %v with token %q and Authorization %q, want only the state token", err, token, authorization)
+	}
+
+	forbidden, err := NewProxyStateClient(&rest.Config{Host: server.URL}, DefaultStateNamespace, "other", "state-token")
+	if err != nil {
+		t.Fatalf("NewProxyStateClient() error = %v", err)
+	}
+	if _, err := forbidden.Rules(context.Background(), controller.StatusFilter{}); err == nil ||
+		!strings.Contains(err.Error(), "portforward-viewer") {
+		t.Errorf("Rules() error = %v, want a hint about the viewer role", err)
+	}
+}
+
+func TestStateServerName(t *testing.T) {
+	for service, want := range map[string]string{
+		DefaultStateService:              "unifi-port-forward-state.unifi-port-forward.svc",
+		"unifi-port-forward-state:https": "unifi-port-forward-state.unifi-port-forward.svc",
+		"unifi-port-forward-state":       "unifi-port-forward-state.unifi-port-forward.svc",
+	} {
+		if got := StateServerName(DefaultStateNamespace, service); got != want {
+			t.Errorf("StateServerName(%q) = %q, want %q", service, got, want)
+		}
+	}
+}
+
+func TestDescribeAndWhoOwns(t *testing.T) {
+	statuses := []controller.RouterRuleStatus{
+		{ID: "1", Name: "default/web:http", ExternalPort: "8080", Protocol: "tcp", Destination: "192.168.1.100",
+			ForwardPort: "8080", Enabled: true, OwnerKind: "Service", Owner: "default/web", Drift: controller.DriftDrifted,
+			DriftFields: []string{"ip"}},
+		{ID: "2", Name: "Printer", ExternalPort: "631", Protocol: "tcp", OwnerKind: controller.OwnerManual},
+	}
+
+	var out bytes.Buffer
+	if err := Describe(&out, statuses); err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	for _, want := range []string{"Destination:    192.168.1.100:8080", "Owner:          Service default/web", "Drifted Fields: ip", "Interface:      <none>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Describe() output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	WhoOwns(&out, statuses)
+	want := "8080/tcp: Service default/web\n631/tcp: \"Printer\" was made by hand on the router\n"
+	if out.String() != want {
+		t.Errorf("WhoOwns() = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,178 @@
+// Package plugin implements the kubectl-unifi_pf kubectl plugin. It reads cluster data with
+// the user's kubeconfig and router state from the controller's read-only state API, so users
+// can inspect and toggle their port forwards without router credentials.
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/stateapi"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Defaults locating the state API served by the controller and the ServiceAccount its tokens
+// are requested for
+const (
+	DefaultStateNamespace      = stateapi.DefaultNamespace
+	DefaultStateService        = "https:" + stateapi.DefaultServiceName + ":https"
+	DefaultStateServiceAccount = stateapi.DefaultServiceAccount
+)
+
+// stateTokenExpiration is how long requested state API tokens are valid, the shortest the API
+// server allows
+const stateTokenExpiration = 10 * time.Minute
+
+// StateClient reads router rule statuses from the state API
+type StateClient struct {
+	HTTPClient *http.Client
+
+	// BaseURL is the URL the state API paths are appended to
+	BaseURL string
+
+	// Token is the state API token sent in stateapi.TokenHeader
+	Token string
+}
+
+// RequestStateToken requests a short-lived token for serviceAccount bound to the state API
+// audience. The state API only accepts such tokens, so the user's own credentials are never
+// sent to it; who may read router state is decided by who may request these tokens.
+func RequestStateToken(ctx context.Context, restConfig *rest.Config, namespace, serviceAccount string) (string, error) {
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to create API server client: %w", err)
+	}
+
+	expirationSeconds := int64(stateTokenExpiration.Seconds())
+	tokenRequest := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{
+		Audiences:         []string{stateapi.Audience},
+		ExpirationSeconds: &expirationSeconds,
+	}}
+	tokenRequest, err = clientset.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, serviceAccount, tokenRequest, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to request a state API token for service account %s/%s, ask for the portforward-viewer role: %w",
+			namespace, serviceAccount, err)
+	}
+	return tokenRequest.Status.Token, nil
+}
+
+// NewProxyStateClient returns a client reaching the state API through the API server service
+// proxy, which it authenticates to as the user of restConfig, and presenting token to the state
+// API. service may name the scheme and port as scheme:name:port.
+func NewProxyStateClient(restConfig *rest.Config, namespace, service, token string) (*StateClient, error) {
+	httpClient, err := rest.HTTPClientFor(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API server client: %w", err)
+	}
+
+	host := strings.TrimSuffix(restConfig.Host, "/")
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	return &StateClient{
+		HTTPClient: httpClient,
+		BaseURL: fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s/proxy", host,
+			url.PathEscape(namespace), url.PathEscape(service)),
+		Token: token,
+	}, nil
+}
+
+// NewStateClient returns a client reaching the state API at baseURL, such as a kubectl
+// port-forward, verifying its certificate with tlsConfig and presenting token
+func NewStateClient(baseURL, token string, tlsConfig *tls.Config) *StateClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &StateClient{HTTPClient: &http.Client{Transport: transport}, BaseURL: baseURL, Token: token}
+}
+
+// StateServerName returns the in-cluster DNS name of the state API Service, given as
+// scheme:name:port, name:port or name
+func StateServerName(namespace, service string) string {
+	parts := strings.Split(service, ":")
+	name := parts[0]
+	if len(parts) == 3 {
+		name = parts[1]
+	}
+	return fmt.Sprintf("%s.%s.svc", name, namespace)
+}
+
+// Rules returns the statuses of the router rules passing filter
+func (c *StateClient) Rules(ctx context.Context, filter controller.StatusFilter) ([]controller.RouterRuleStatus, error) {
+	target := strings.TrimSuffix(c.BaseURL, "/") + stateapi.RulesPath
+	if query := stateapi.FilterQuery(filter).Encode(); query != "" {
+		target += "?" + query
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		request.Header.Set(stateapi.TokenHeader, c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the state API: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state API response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, stateError(response.StatusCode, body)
+	}
+
+	var rules stateapi.RulesResponse
+	if err := json.Unmarshal(body, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode the state API response: %w", err)
+	}
+	return rules.Rules, nil
+}
+
+// stateError explains a failed state API request, with a hint for the usual causes
+func stateError(status int, body []byte) error {
+	var apiError struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &apiError); err == nil {
+		// The state API reports errors in error, the API server proxy in message
+		if apiError.Error != "" {
+			message = apiError.Error
+		} else if apiError.Message != "" {
+			message = apiError.Message
+		}
+	}
+
+	switch status {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("state API request unauthenticated, its token may have expired: %s", message)
+	case http.StatusForbidden:
+		return fmt.Errorf("state API request forbidden, ask for the portforward-viewer role: %s", message)
+	case http.StatusNotFound, http.StatusServiceUnavailable:
+		return fmt.Errorf("state API not available, is the controller running with STATE_API_ENABLED=true: %s", message)
+	default:
+		return fmt.Errorf("state API request failed with status %d: %s", status, message)
+	}
+}
@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SetEnabled enables or disables the port forward on an external port through the Kubernetes
+// object owning it, the only way the controller keeps the change: a Service gets the port
+// added to or removed from its disabled-ports annotation, a rule gets its enabled field, or
+// that of the matching entry in ports, set. It reports whether the owner was changed.
+func SetEnabled(ctx context.Context, c client.Client, ownerKind, owner string, externalPort int, enabled bool) (bool, error) {
+	switch ownerKind {
+	case "Service":
+		namespace, name, _ := strings.Cut(owner, "/")
+		return setServicePortEnabled(ctx, c, types.NamespacedName{Namespace: namespace, Name: name}, externalPort, enabled)
+	case "PortForwardRule":
+		namespace, name, _ := strings.Cut(owner, "/")
+		return setRulePortEnabled(ctx, c, &v1alpha1.PortForwardRule{},
+			types.NamespacedName{Namespace: namespace, Name: name}, externalPort, enabled)
+	case "ClusterPortForwardRule":
+		return setRulePortEnabled(ctx, c, &v1alpha1.ClusterPortForwardRule{},
+			types.NamespacedName{Name: owner}, externalPort, enabled)
+	default:
+		return false, fmt.Errorf("the rule on port %d is %s; only rules owned by a Service, PortForwardRule or ClusterPortForwardRule can be enabled or disabled", externalPort, ownerKind)
+	}
+}
+
+func setServicePortEnabled(ctx context.Context, c client.Client, key types.NamespacedName, externalPort int, enabled bool) (bool, error) {
+	service := &corev1.Service{}
+	if err := c.Get(ctx, key, service); err != nil {
+		return false, fmt.Errorf("failed to get service %s: %w", key, err)
+	}
+
+	disabled, err := helpers.GetDisabledPorts(service, config.DisabledPortsAnnotation)
+	if err != nil {
+		return false, err
+	}
+	if disabled[externalPort] == !enabled {
+		return false, nil
+	}
+	if enabled {
+		delete(disabled, externalPort)
+	} else {
+		disabled[externalPort] = true
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if value := helpers.FormatDisabledPorts(disabled); value != "" {
+		if service.Annotations == nil {
+			service.Annotations = make(map[string]string)
+		}
+		service.Annotations[config.DisabledPortsAnnotation] = value
+	} else {
+		delete(service.Annotations, config.DisabledPortsAnnotation)
+	}
+	if err := c.Patch(ctx, service, patch); err != nil {
+		return false, fmt.Errorf("failed to update disabled ports of service %s: %w", key, err)
+	}
+	return true, nil
+}
+
+func setRulePortEnabled(ctx context.Context, c client.Client, rule v1alpha1.PortForwardRuleObject, key types.NamespacedName, externalPort int, enabled bool) (bool, error) {
+	if err := c.Get(ctx, key, rule); err != nil {
+		return false, fmt.Errorf("failed to get %s: %w", ruleName(rule, key), err)
+	}
+
+	patch := client.MergeFrom(rule.DeepCopyObject().(client.Object))
+	spec := rule.GetRuleSpec()
+	if len(spec.Ports) == 0 {
+		if spec.Enabled == enabled {
+			return false, nil
+		}
+		spec.Enabled = enabled
+	} else {
+		index := -1
+		for i := range spec.Ports {
+			if spec.Ports[i].ExternalPort == externalPort {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return false, fmt.Errorf("%s does not forward port %d", ruleName(rule, key), externalPort)
+		}
+		port := &spec.Ports[index]
+		current := spec.Enabled
+		if port.Enabled != nil {
+			current = *port.Enabled
+		}
+		if current == enabled {
+			return false, nil
+		}
+		port.Enabled = &enabled
+	}
+
+	if err := c.Patch(ctx, rule, patch); err != nil {
+		return false, fmt.Errorf("failed to update %s: %w", ruleName(rule, key), err)
+	}
+	return true, nil
+}
+
+// ruleName names a rule in messages, such as PortForwardRule apps/web
+func ruleName(rule v1alpha1.PortForwardRuleObject, key types.NamespacedName) string {
+	kind := "PortForwardRule"
+	if _, ok := rule.(*v1alpha1.ClusterPortForwardRule); ok {
+		kind = "ClusterPortForwardRule"
+	}
+	return kind + " " + strings.TrimPrefix(key.String(), "/")
+}
+
+// ParsePort parses an external port argument
+func ParsePort(arg string) (int, error) {
+	port, err := strconv.Atoi(arg)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q, expected a number between 1 and 65535", arg)
+	}
+	return port, nil
+}
@@ -2,15 +2,25 @@ package routers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 
 	"github.com/filipowm/go-unifi/unifi"
 )
 
+// ErrRuleNotFound is returned by ID based operations when the router has no rule with that ID
+var ErrRuleNotFound = errors.New("port forward rule not found")
+
 type Router interface {
 	AddPort(ctx context.Context, config PortConfig) error
+	CreatePort(ctx context.Context, config PortConfig) (*unifi.PortForward, error)
 	CheckPort(ctx context.Context, port int, protocol string) (*unifi.PortForward, bool, error)
+	GetPortForwardByID(ctx context.Context, ruleID string) (*unifi.PortForward, bool, error)
 	RemovePort(ctx context.Context, config PortConfig) error
 	UpdatePort(ctx context.Context, port int, config PortConfig) error
+	UpdatePortByID(ctx context.Context, ruleID string, config PortConfig) error
 	DeletePortForwardByID(ctx context.Context, ruleID string) error
 	ListAllPortForwards(ctx context.Context) ([]*unifi.PortForward, error)
 }
@@ -24,4 +34,12 @@ type PortConfig struct {
 	SrcIP     string
 	DstIP     string
 	Protocol  string
+	Log       bool // Whether the router logs matching traffic
+}
+
+// Hash returns a stable hash of the configuration, used to record what was last applied to the router
+func (c PortConfig) Hash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%t|%s|%d|%d|%s|%s|%s|%t",
+		c.Name, c.Enabled, c.Interface, c.DstPort, c.FwdPort, c.SrcIP, c.DstIP, c.Protocol, c.Log)))
+	return hex.EncodeToString(sum[:])[:16]
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 
@@ -20,7 +21,9 @@ type UnifiRouter struct {
 	Client unifi.Client
 }
 
-func CreateUnifiRouter(baseURL, username, password, site, apiKey string) (*UnifiRouter, error) {
+// newClientConfig returns the go-unifi client configuration for a password or, when set, an
+// API key
+func newClientConfig(baseURL, username, password, apiKey string) *unifi.ClientConfig {
 	clientConfig := &unifi.ClientConfig{
 		URL:            baseURL,
 		VerifySSL:      false,
@@ -30,12 +33,19 @@ func CreateUnifiRouter(baseURL, username, password, site, apiKey string) (*Unifi
 		RememberMe:     true,
 	}
 
-	// override if using API key (recommended, requires UniFi Controller 9.0.108+)
+	// override if using API key (recommended, requires UniFi Controller 9.0.108+). go-unifi
+	// rejects RememberMe with an API key, as there is no session to remember.
 	if apiKey != "" {
 		clientConfig.APIKey = apiKey
 		clientConfig.User = ""
 		clientConfig.Password = ""
+		clientConfig.RememberMe = false
 	}
+	return clientConfig
+}
+
+func CreateUnifiRouter(baseURL, username, password, site, apiKey string) (*UnifiRouter, error) {
+	clientConfig := newClientConfig(baseURL, username, password, apiKey)
 
 	client, err := unifi.NewClient(clientConfig)
 	if err != nil {
@@ -47,7 +57,8 @@ func CreateUnifiRouter(baseURL, username, password, site, apiKey string) (*Unifi
 		return nil, err
 	}
 
-	fmt.Printf("UniFi Controller Version: %s\n", client.Version())
+	// stderr keeps the output of commands that write manifests or backups to stdout clean
+	fmt.Fprintf(os.Stderr, "UniFi Controller Version: %s\n", client.Version())
 
 	router := &UnifiRouter{
 		SiteID: site,
@@ -124,6 +135,12 @@ func (router *UnifiRouter) CheckPort(ctx context.Context, port int, protocol str
 }
 
 func (router *UnifiRouter) AddPort(ctx context.Context, config PortConfig) error {
+	_, err := router.CreatePort(ctx, config)
+	return err
+}
+
+// CreatePort creates a port forward rule and returns the created rule including its router ID
+func (router *UnifiRouter) CreatePort(ctx context.Context, config PortConfig) (*unifi.PortForward, error) {
 	logger := ctrllog.FromContext(ctx)
 
 	logger.V(1).Info("Creating new port forward rule",
@@ -135,6 +152,7 @@ func (router *UnifiRouter) AddPort(ctx context.Context, config PortConfig) error
 		"protocol", config.Protocol,
 		"interface", config.Interface,
 		"enabled", config.Enabled,
+		"log", config.Log,
 	)
 
 	if config.DstIP == "" {
@@ -142,7 +160,12 @@ func (router *UnifiRouter) AddPort(ctx context.Context, config PortConfig) error
 		logger.Error(err, "Failed validation: destination IP is empty",
 			"config", config,
 		)
-		return err
+		return nil, err
+	}
+
+	src := "any"
+	if config.SrcIP != "" {
+		src = config.SrcIP
 	}
 
 	portforward := &unifi.PortForward{
@@ -152,10 +175,11 @@ func (router *UnifiRouter) AddPort(ctx context.Context, config PortConfig) error
 		Fwd:           config.DstIP,
 		FwdPort:       strconv.Itoa(config.FwdPort),
 		DstPort:       strconv.Itoa(config.DstPort),
+		Log:           config.Log,
 		Name:          config.Name,
 		PfwdInterface: config.Interface,
 		Proto:         config.Protocol,
-		Src:           "any",
+		Src:           src,
 	}
 
 	logger.V(1).Info("Sending port forward creation to UniFi API",
@@ -173,7 +197,7 @@ func (router *UnifiRouter) AddPort(ctx context.Context, config PortConfig) error
 			"config", config,
 			"creation_payload", portforward,
 		)
-		return err
+		return nil, err
 	}
 
 	logger.V(1).Info("Successfully created port forward rule",
@@ -182,7 +206,7 @@ func (router *UnifiRouter) AddPort(ctx context.Context, config PortConfig) error
 		"result", result,
 	)
 
-	return nil
+	return result, nil
 }
 
 func (router *UnifiRouter) UpdatePort(ctx context.Context, port int, config PortConfig) error {
@@ -216,6 +240,40 @@ func (router *UnifiRouter) UpdatePort(ctx context.Context, port int, config Port
 		return errors.New(errorMsg)
 	}
 
+	return router.updatePortForward(ctx, pf, config)
+}
+
+// UpdatePortByID updates the port forward rule with the given router ID
+func (router *UnifiRouter) UpdatePortByID(ctx context.Context, ruleID string, config PortConfig) error {
+	logger := ctrllog.FromContext(ctx)
+
+	logger.Info("Starting port forward rule update by ID",
+		"rule_id", ruleID,
+		"operation", "update_port_by_id",
+		"config_name", config.Name,
+		"config_dst_ip", config.DstIP,
+		"config_protocol", config.Protocol,
+		"config_dst_port", config.DstPort,
+		"config_fwd_port", config.FwdPort,
+		"config_enabled", config.Enabled,
+	)
+
+	pf, exists, err := router.GetPortForwardByID(ctx, ruleID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("port forward rule %s: %w", ruleID, ErrRuleNotFound)
+	}
+
+	return router.updatePortForward(ctx, pf, config)
+}
+
+// updatePortForward applies config to the existing router rule pf
+func (router *UnifiRouter) updatePortForward(ctx context.Context, pf *unifi.PortForward, config PortConfig) error {
+	logger := ctrllog.FromContext(ctx)
+	port := config.DstPort
+
 	logger.V(1).Info("Found existing port forward rule to update",
 		"port", port,
 		"rule_id", pf.ID,
@@ -225,22 +283,29 @@ func (router *UnifiRouter) UpdatePort(ctx context.Context, port int, config Port
 		"new_name", config.Name,
 	)
 
+	// Preserve the existing source filter unless the config specifies one
+	src := pf.Src
+	if config.SrcIP != "" {
+		src = config.SrcIP
+	}
+
 	portforward := &unifi.PortForward{
 		ID:            pf.ID,
 		SiteID:        router.SiteID,
-		DestinationIP: pf.DestinationIP, // Preserve existing source filter
+		DestinationIP: pf.DestinationIP, // Preserve existing destination filter
 		Enabled:       config.Enabled,
 		Fwd:           config.DstIP,
 		FwdPort:       strconv.Itoa(config.FwdPort),
 		DstPort:       strconv.Itoa(config.DstPort),
+		Log:           config.Log,
 		Name:          config.Name,
 		PfwdInterface: config.Interface,
 		Proto:         config.Protocol,
-		Src:           pf.Src, // Preserve existing source filter
+		Src:           src,
 	}
 
 	var result *unifi.PortForward
-	err = router.withAuthRetry(ctx, "UpdatePort", func() error {
+	err := router.withAuthRetry(ctx, "UpdatePort", func() error {
 		var retryErr error
 		result, retryErr = router.Client.UpdatePortForward(ctx, router.SiteID, portforward)
 		return retryErr
@@ -266,6 +331,36 @@ func (router *UnifiRouter) UpdatePort(ctx context.Context, port int, config Port
 	return nil
 }
 
+// CreatePortForward creates a copy of the given rule with every field set as given, for
+// restoring rules that carry more than a PortConfig describes
+func (router *UnifiRouter) CreatePortForward(ctx context.Context, pf *unifi.PortForward) (*unifi.PortForward, error) {
+	portforward := *pf
+	portforward.ID = ""
+	portforward.SiteID = router.SiteID
+
+	var result *unifi.PortForward
+	err := router.withAuthRetry(ctx, "CreatePortForward", func() error {
+		var err error
+		result, err = router.Client.CreatePortForward(ctx, router.SiteID, &portforward)
+		return err
+	})
+	return result, err
+}
+
+// UpdatePortForward replaces every field of the rule with the ID of pf
+func (router *UnifiRouter) UpdatePortForward(ctx context.Context, pf *unifi.PortForward) (*unifi.PortForward, error) {
+	portforward := *pf
+	portforward.SiteID = router.SiteID
+
+	var result *unifi.PortForward
+	err := router.withAuthRetry(ctx, "UpdatePortForward", func() error {
+		var err error
+		result, err = router.Client.UpdatePortForward(ctx, router.SiteID, &portforward)
+		return err
+	})
+	return result, err
+}
+
 func (router *UnifiRouter) DeletePortForwardByID(ctx context.Context, ruleID string) error {
 	err := router.withAuthRetry(ctx, "DeletePortForwardByID", func() error {
 		return router.Client.DeletePortForward(ctx, router.SiteID, ruleID)
@@ -273,6 +368,22 @@ func (router *UnifiRouter) DeletePortForwardByID(ctx context.Context, ruleID str
 	return err
 }
 
+// GetPortForwardByID finds a port forward rule by its router ID
+func (router *UnifiRouter) GetPortForwardByID(ctx context.Context, ruleID string) (*unifi.PortForward, bool, error) {
+	portforwards, err := router.ListAllPortForwards(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, portforward := range portforwards {
+		if portforward.ID == ruleID {
+			return portforward, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
 func (router *UnifiRouter) ListAllPortForwards(ctx context.Context) ([]*unifi.PortForward, error) {
 	var portforwards []unifi.PortForward
 	err := router.withAuthRetry(ctx, "ListAllPortForwards", func() error {
@@ -1,9 +1,13 @@
 package routers
 
 import (
+	"context"
 	"fmt"
+	"net/http/httptest"
 	"testing"
 
+	"unifi-port-forward/pkg/unifisim"
+
 	"github.com/filipowm/go-unifi/unifi"
 )
 
@@ -100,15 +104,36 @@ func TestRouter_Interface(t *testing.T) {
 	var _ Router = &UnifiRouter{}
 }
 
-// TestRouter_RememberMeEnabled verifies that RememberMe is set to true in client config
-func TestRouter_RememberMeEnabled(t *testing.T) {
-	// Test that CreateUnifiRouter sets RememberMe=true
-	// We can't easily test without actual credentials, but we can verify the pattern
-	// This is more of a code review test - ensuring RememberMe is hardcoded
+// TestNewClientConfig verifies that password logins are remembered and that API keys, which
+// go-unifi refuses to combine with RememberMe, are not
+func TestNewClientConfig(t *testing.T) {
+	password := newClientConfig("https://192.168.1.1", "admin", "secret", "")
+	if !password.RememberMe || password.User != "admin" || password.APIKey != "" {
+		t.Errorf("password config = %+v, want a remembered admin login", password)
+	}
+
+	apiKey := newClientConfig("https://192.168.1.1", "admin", "secret", "key")
+	if apiKey.RememberMe || apiKey.User != "" || apiKey.Password != "" || apiKey.APIKey != "key" {
+		t.Errorf("API key config = %+v, want only the API key", apiKey)
+	}
+}
+
+// TestCreateUnifiRouter_APIKey authenticates with an API key against a simulated gateway
+func TestCreateUnifiRouter_APIKey(t *testing.T) {
+	simulator, err := unifisim.New(unifisim.Config{APIKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewTLSServer(simulator.Handler())
+	defer server.Close()
 
-	// This test serves as documentation that RememberMe=true should always be set
-	// The actual verification would be in integration tests with real UniFi controller
-	t.Log("RememberMe should be hardcoded to true in CreateUnifiRouter for persistent sessions")
+	router, err := CreateUnifiRouter(server.URL, "admin", "", unifisim.DefaultSite, "key")
+	if err != nil {
+		t.Fatalf("CreateUnifiRouter() with an API key error = %v", err)
+	}
+	if _, err := router.ListAllPortForwards(context.Background()); err != nil {
+		t.Errorf("ListAllPortForwards() with an API key error = %v", err)
+	}
 }
 
 // TestIsAuthError_SimpleTests tests the 401 status code detection logic
@@ -166,3 +191,36 @@ func TestIsAuthError_SimpleTests(t *testing.T) {
 		})
 	}
 }
+
+// TestPortConfig_Hash tests that the config hash is stable and tracks field changes
+func TestPortConfig_Hash(t *testing.T) {
+	base := PortConfig{
+		Name:      "default/web:8080",
+		DstPort:   8080,
+		FwdPort:   80,
+		Enabled:   true,
+		Interface: "wan",
+		DstIP:     "192.168.1.100",
+		SrcIP:     "any",
+		Protocol:  "tcp",
+	}
+
+	if base.Hash() != base.Hash() {
+		t.Fatal("Expected hash to be stable for identical configs")
+	}
+	if len(base.Hash()) != 16 {
+		t.Errorf("Expected 16 character hash, got %q", base.Hash())
+	}
+
+	changed := base
+	changed.FwdPort = 81
+	if changed.Hash() == base.Hash() {
+		t.Error("Expected hash to change when forward port changes")
+	}
+
+	changed = base
+	changed.Log = true
+	if changed.Hash() == base.Hash() {
+		t.Error("Expected hash to change when log flag changes")
+	}
+}
@@ -0,0 +1,201 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchLimit bounds how far ahead the next matching time of a cron expression is searched
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// cronExpr is a parsed five-field cron expression: minute, hour, day of month, month and day of week
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+
+	// domAny and dowAny record a day field starting with "*"; when both day fields are
+	// restricted a time matches if either of them does, as in cron
+	domAny, dowAny bool
+}
+
+// parseCron parses a five-field cron expression. Fields accept "*", numbers, ranges ("1-5"),
+// steps ("*/15", "8-18/2") and comma separated lists. Months and days of the week may also be
+// given by their three-letter English names, and 7 is accepted for Sunday.
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(fields))
+	}
+
+	var c cronExpr
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field: %w", err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if c.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field: %w", err)
+	}
+
+	// 7 is Sunday as well
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	// As in Vixie cron, a day field starting with "*", such as "*/2", is unrestricted
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+// parseField parses one comma separated cron field into a bit set of the values it matches
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = min, max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := parseValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			low, high = value, value
+			// "5/15" means every 15 starting at 5
+			if step > 1 {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// parseValue parses a number or, if names is set, a three-letter name
+func parseValue(value string, names map[string]int) (int, error) {
+	if number, ok := names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return number, nil
+}
+
+// matchesDay reports whether the day of t matches the day-of-month and day-of-week fields
+func (c *cronExpr) matchesDay(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// everyDay reports whether the day fields and the month field match every day of the year
+func (c *cronExpr) everyDay() bool {
+	const allDays, allWeekdays, allMonths = uint64(1<<32 - 2), uint64(1<<7 - 1), uint64(1<<13 - 2)
+	if c.month&allMonths != allMonths {
+		return false
+	}
+	domAll := c.dom&allDays == allDays
+	dowAll := c.dow&allWeekdays == allWeekdays
+	if c.domAny || c.dowAny {
+		return domAll && dowAll
+	}
+	return domAll || dowAll
+}
+
+// maxDailyGap returns the longest wall clock time between two consecutive times of day matched
+// by the minute and hour fields, wrapping around midnight
+func (c *cronExpr) maxDailyGap() time.Duration {
+	first, last, gap := -1, -1, 0
+	for minuteOfDay := 0; minuteOfDay < 24*60; minuteOfDay++ {
+		if c.hour&(1<<uint(minuteOfDay/60)) == 0 || c.minute&(1<<uint(minuteOfDay%60)) == 0 {
+			continue
+		}
+		if first < 0 {
+			first = minuteOfDay
+		} else {
+			gap = max(gap, minuteOfDay-last)
+		}
+		last = minuteOfDay
+	}
+	gap = max(gap, first+24*60-last)
+	return time.Duration(gap) * time.Minute
+}
+
+// next returns the first time after t, in t's location, matched by the expression, or the
+// zero time if there is none within searchLimit
+func (c *cronExpr) next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(searchLimit)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+
+	for t.Before(limit) {
+		var candidate time.Time
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			candidate = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchesDay(t):
+			candidate = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			candidate = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			candidate = t.Add(time.Minute)
+		default:
+			return t
+		}
+
+		// Daylight saving transitions can normalize a wall clock time to an earlier instant
+		if !candidate.After(t) {
+			candidate = t.Add(time.Minute)
+		}
+		t = candidate
+	}
+	return time.Time{}
+}
@@ -0,0 +1,173 @@
+// Package schedule evaluates the time windows during which a port forward is enabled.
+// A window opens at every time matched by a cron expression and stays open for a fixed
+// duration; a schedule is open while any of its windows is.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Embedded so time zones resolve in images without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// maxMergedStarts bounds how many consecutive window starts are merged when looking for the
+// end of an open period, so a window that never closes does not loop forever
+const maxMergedStarts = 10000
+
+// Window is a period that opens at every time matched by Start and lasts Duration
+type Window struct {
+	// Start is a five-field cron expression (minute hour day-of-month month day-of-week)
+	Start string
+
+	// Duration is how long the window stays open after each start
+	Duration time.Duration
+}
+
+// Schedule is a set of windows evaluated in a time zone
+type Schedule struct {
+	location *time.Location
+	windows  []window
+}
+
+type window struct {
+	start    *cronExpr
+	duration time.Duration
+}
+
+// New parses the windows of a schedule. timeZone is an IANA time zone name such as
+// "Europe/Stockholm"; an empty time zone means UTC.
+func New(timeZone string, windows []Window) (*Schedule, error) {
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", timeZone, err)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("schedule must have at least one window")
+	}
+
+	s := &Schedule{location: location}
+	for i, w := range windows {
+		parsed, err := parseWindow(w)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i, err)
+		}
+		s.windows = append(s.windows, parsed)
+	}
+	return s, nil
+}
+
+// ValidateWindow checks the cron expression and duration of a window
+func ValidateWindow(w Window) error {
+	_, err := parseWindow(w)
+	return err
+}
+
+func parseWindow(w Window) (window, error) {
+	start, err := parseCron(w.Start)
+	if err != nil {
+		return window{}, err
+	}
+	if w.Duration < time.Minute {
+		return window{}, fmt.Errorf("duration must be at least 1m, got %s", w.Duration)
+	}
+	return window{start: start, duration: w.Duration}, nil
+}
+
+// ParseWindows parses the windows of a schedule annotation: a semicolon separated list of
+// cron expressions each followed by a duration, such as "0 8 * * 1-5 9h; 0 18 * * fri 56h"
+func ParseWindows(value string) ([]Window, error) {
+	var windows []Window
+	for _, part := range strings.Split(value, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("window %q must be a five-field cron expression followed by a duration", strings.TrimSpace(part))
+		}
+		duration, err := time.ParseDuration(fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("window %q has an invalid duration: %w", strings.TrimSpace(part), err)
+		}
+		windows = append(windows, Window{Start: strings.Join(fields[:5], " "), Duration: duration})
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("schedule must have at least one window")
+	}
+	return windows, nil
+}
+
+// State reports whether the schedule is open at now and when that next changes. The
+// returned time is zero if the schedule never changes within the search limit.
+func (s *Schedule) State(now time.Time) (bool, time.Time) {
+	now = now.In(s.location)
+
+	// A window that opens again before it closes never changes, and merging its starts one by
+	// one would walk the whole search limit
+	for _, w := range s.windows {
+		if w.alwaysOpen() {
+			return true, time.Time{}
+		}
+	}
+
+	// Extend the open period until no window covers its end, so back-to-back and
+	// overlapping windows are a single open period
+	open := false
+	end := now
+	for extended := true; extended && end.Sub(now) < searchLimit; {
+		extended = false
+		for _, w := range s.windows {
+			if windowEnd, covered := w.cover(end); covered && windowEnd.After(end) {
+				end = windowEnd
+				open = true
+				extended = true
+			}
+		}
+	}
+	if open {
+		return true, end
+	}
+
+	var next time.Time
+	for _, w := range s.windows {
+		if start := w.start.next(now); !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return false, next
+}
+
+// alwaysOpen reports whether the window opens every day, each time before its previous opening
+// ends. Gaps longer than an hour may grow by an hour across a daylight saving transition.
+func (w window) alwaysOpen() bool {
+	if !w.start.everyDay() {
+		return false
+	}
+	gap := w.start.maxDailyGap()
+	if gap > time.Hour {
+		gap += time.Hour
+	}
+	return w.duration >= gap
+}
+
+// cover reports whether the window is open at t and, if so, when the period of
+// consecutive starts covering t ends
+func (w window) cover(t time.Time) (time.Time, bool) {
+	// The first start after t-duration is the earliest one still open at t
+	start := w.start.next(t.Add(-w.duration))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}, false
+	}
+
+	end := start.Add(w.duration)
+	for range maxMergedStarts {
+		start = w.start.next(start)
+		if start.IsZero() || start.After(end) {
+			break
+		}
+		end = start.Add(w.duration)
+	}
+	return end, true
+}
@@ -0,0 +1,264 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return location
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		errorMsg string
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "office hours", expr: "0 8 * * mon-fri"},
+		{name: "steps and lists", expr: "*/15 8-18/2 1,15 jan-jun *"},
+		{name: "sunday as 7", expr: "0 0 * * 7"},
+		{name: "wrong field count", expr: "0 8 * *", errorMsg: "must have 5 fields"},
+		{name: "minute out of range", expr: "60 * * * *", errorMsg: "invalid minute field"},
+		{name: "reversed range", expr: "0 18-8 * * *", errorMsg: "invalid hour field"},
+		{name: "unknown day name", expr: "0 8 * * funday", errorMsg: "invalid day-of-week field"},
+		{name: "zero step", expr: "*/0 * * * *", errorMsg: "invalid step"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCron(tt.expr)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Expected %q to parse, got %v", tt.expr, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	base := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{expr: "* * * * *", from: base, want: base.Add(time.Minute)},
+		{expr: "0 8 * * mon-fri", from: base, want: time.Date(2026, 3, 5, 8, 0, 0, 0, time.UTC)},
+		{expr: "0 18 * * fri", from: base, want: time.Date(2026, 3, 6, 18, 0, 0, 0, time.UTC)},
+		{expr: "45 10 * * *", from: base.Add(15 * time.Second), want: time.Date(2026, 3, 4, 10, 45, 0, 0, time.UTC)},
+		{expr: "0 0 1 jan *", from: base, want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Day-of-month and day-of-week both restricted match either: the 15th or any Sunday
+		{expr: "0 0 15 * sun", from: base, want: time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 31 2 *", from: base, want: time.Time{}},
+		// A day field starting with "*" is unrestricted, so both day fields must match
+		{expr: "0 0 */2 * mon", from: base, want: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 */1 * fri", from: base, want: time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 15 * */2", from: base, want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.expr, err)
+			}
+			if got := c.next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Expected next of %q after %s to be %s, got %s", tt.expr, tt.from, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCronNext_DaylightSaving(t *testing.T) {
+	stockholm := mustLocation(t, "Europe/Stockholm")
+
+	// 02:30 does not exist on 2026-03-29 in Stockholm; the clock jumps from 02:00 to 03:00
+	c, err := parseCron("30 2 * * *")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	from := time.Date(2026, 3, 28, 12, 0, 0, 0, stockholm)
+	got := c.next(from)
+	if want := time.Date(2026, 3, 30, 2, 30, 0, 0, stockholm); !got.Equal(want) {
+		t.Errorf("Expected skipped wall clock time to move to the next day %s, got %s", want, got)
+	}
+}
+
+func TestSchedule_State(t *testing.T) {
+	stockholm := mustLocation(t, "Europe/Stockholm")
+
+	officeHours := []Window{{Start: "0 8 * * mon-fri", Duration: 9 * time.Hour}}
+	weekend := []Window{{Start: "0 18 * * fri", Duration: 54 * time.Hour}}
+	backToBack := []Window{
+		{Start: "0 8 * * *", Duration: 4 * time.Hour},
+		{Start: "0 12 * * *", Duration: 2 * time.Hour},
+	}
+
+	tests := []struct {
+		name     string
+		windows  []Window
+		now      time.Time
+		wantOpen bool
+		wantNext time.Time
+	}{
+		{
+			name:     "inside office hours",
+			windows:  officeHours,
+			now:      time.Date(2026, 3, 4, 10, 30, 0, 0, stockholm),
+			wantOpen: true,
+			wantNext: time.Date(2026, 3, 4, 17, 0, 0, 0, stockholm),
+		},
+		{
+			name:     "window opens at its start",
+			windows:  officeHours,
+			now:      time.Date(2026, 3, 4, 8, 0, 0, 0, stockholm),
+			wantOpen: true,
+			wantNext: time.Date(2026, 3, 4, 17, 0, 0, 0, stockholm),
+		},
+		{
+			name:     "window is closed at its end",
+			windows:  officeHours,
+			now:      time.Date(2026, 3, 4, 17, 0, 0, 0, stockholm),
+			wantOpen: false,
+			wantNext: time.Date(2026, 3, 5, 8, 0, 0, 0, stockholm),
+		},
+		{
+			name:     "friday evening waits for monday",
+			windows:  officeHours,
+			now:      time.Date(2026, 3, 6, 20, 0, 0, 0, stockholm),
+			wantOpen: false,
+			wantNext: time.Date(2026, 3, 9, 8, 0, 0, 0, stockholm),
+		},
+		{
+			name:     "window spanning days",
+			windows:  weekend,
+			now:      time.Date(2026, 3, 8, 12, 0, 0, 0, stockholm),
+			wantOpen: true,
+			wantNext: time.Date(2026, 3, 9, 0, 0, 0, 0, stockholm),
+		},
+		{
+			name:     "back-to-back windows form one period",
+			windows:  backToBack,
+			now:      time.Date(2026, 3, 4, 9, 0, 0, 0, stockholm),
+			wantOpen: true,
+			wantNext: time.Date(2026, 3, 4, 14, 0, 0, 0, stockholm),
+		},
+		{
+			name:     "time is compared in the schedule's time zone",
+			windows:  officeHours,
+			now:      time.Date(2026, 3, 4, 7, 30, 0, 0, time.UTC),
+			wantOpen: true,
+			wantNext: time.Date(2026, 3, 4, 17, 0, 0, 0, stockholm),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New("Europe/Stockholm", tt.windows)
+			if err != nil {
+				t.Fatalf("Failed to create schedule: %v", err)
+			}
+			open, next := s.State(tt.now)
+			if open != tt.wantOpen {
+				t.Errorf("Expected open=%t, got %t", tt.wantOpen, open)
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("Expected next change at %s, got %s", tt.wantNext, next)
+			}
+		})
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeZone string
+		windows  []Window
+		errorMsg string
+	}{
+		{name: "unknown time zone", timeZone: "Mars/Olympus", windows: []Window{{Start: "* * * * *", Duration: time.Hour}}, errorMsg: "invalid time zone"},
+		{name: "no windows", errorMsg: "at least one window"},
+		{name: "invalid cron", windows: []Window{{Start: "0 25 * * *", Duration: time.Hour}}, errorMsg: "window 0: invalid hour field"},
+		{name: "short duration", windows: []Window{{Start: "0 8 * * *", Duration: time.Second}}, errorMsg: "at least 1m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.timeZone, tt.windows)
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows("0 8 * * mon-fri 9h; 0 18 * * fri 54h;")
+	if err != nil {
+		t.Fatalf("Expected windows to parse, got %v", err)
+	}
+	want := []Window{
+		{Start: "0 8 * * mon-fri", Duration: 9 * time.Hour},
+		{Start: "0 18 * * fri", Duration: 54 * time.Hour},
+	}
+	if len(windows) != len(want) || windows[0] != want[0] || windows[1] != want[1] {
+		t.Errorf("Expected %+v, got %+v", want, windows)
+	}
+
+	for _, value := range []string{"", "0 8 * * mon-fri", "0 8 * * mon-fri nine-hours"} {
+		if _, err := ParseWindows(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestSchedule_State_AlwaysOpen(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		windows []Window
+		open    bool
+	}{
+		{name: "every minute", windows: []Window{{Start: "* * * * *", Duration: 2 * time.Minute}}, open: true},
+		{name: "every hour", windows: []Window{{Start: "0 * * * *", Duration: time.Hour}}, open: true},
+		{name: "every day", windows: []Window{{Start: "0 8 * * *", Duration: 25 * time.Hour}}, open: true},
+		{name: "every second day of the month", windows: []Window{{Start: "0 8 */2 * *", Duration: 25 * time.Hour}}},
+		// The day the clocks fall back has 25 hours
+		{name: "every day without daylight saving slack", windows: []Window{{Start: "0 8 * * *", Duration: 24 * time.Hour}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New("Europe/Stockholm", tt.windows)
+			if err != nil {
+				t.Fatalf("Failed to create schedule: %v", err)
+			}
+			if got := s.windows[0].alwaysOpen(); got != tt.open {
+				t.Errorf("Expected alwaysOpen()=%t, got %t", tt.open, got)
+			}
+			if !tt.open {
+				return
+			}
+			open, next := s.State(now)
+			if !open || !next.IsZero() {
+				t.Errorf("Expected the schedule to be open and never change, got open=%t next=%s", open, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+// Package stateapi serves the router rules, joined with the Services and rules owning them,
+// over a small read-only HTTPS API. It lets users without router credentials inspect the router,
+// for example through the kubectl-unifi_pf plugin and the API server service proxy. Callers
+// present a token bound to the state API audience, which is checked with a TokenReview, and
+// must be allowed to proxy to the state API Service.
+package stateapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/webhook"
+
+	"github.com/filipowm/go-unifi/unifi"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Paths served by the state API
+const (
+	RulesPath  = "/api/v1/rules"
+	HealthPath = "/healthz"
+)
+
+// Defaults locating the state API Service, whose services/proxy permission callers need, and
+// the ServiceAccount that state API tokens are requested for
+const (
+	DefaultNamespace      = "unifi-port-forward"
+	DefaultServiceName    = "unifi-port-forward-state"
+	DefaultServiceAccount = "unifi-port-forward-state-reader"
+)
+
+// Audience is the audience state API tokens must be bound to. Tokens for the API server or any
+// other audience are refused, so a caller never hands the state API a token it could replay.
+const Audience = "unifi-port-forward-state"
+
+// TokenHeader carries the caller's state API token through the API server service proxy, which
+// drops the Authorization header of the requests it proxies
+const TokenHeader = "X-Unifi-Port-Forward-Token"
+
+// DefaultCacheTTL is how long router rules are reused between requests, so that a burst of
+// plugin invocations does not turn into a burst of router logins
+const DefaultCacheTTL = 10 * time.Second
+
+// Router lists the port forward rules on the router
+type Router interface {
+	ListAllPortForwards(ctx context.Context) ([]*unifi.PortForward, error)
+}
+
+// RulesResponse is the body of a rules request
+type RulesResponse struct {
+	GeneratedAt time.Time                     `json:"generatedAt"`
+	Rules       []controller.RouterRuleStatus `json:"rules"`
+}
+
+// Server serves the state API. It is a manager.Runnable, so it starts and stops with the
+// controller manager; it serves on every replica, not just the leader.
+type Server struct {
+	Reporter *controller.StatusReporter
+	Router   Router
+
+	// Client reviews the token of every rules request; requests are refused without it
+	Client client.Client
+
+	// Namespace and ServiceName override DefaultNamespace and DefaultServiceName
+	Namespace   string
+	ServiceName string
+
+	// Port is the port the API listens on
+	Port int
+
+	// CertDir holds the serving certificate as tls.crt and tls.key. Without one a self-signed
+	// certificate is generated, which the API server service proxy accepts.
+	CertDir string
+
+	// CacheTTL overrides DefaultCacheTTL; a negative value disables caching
+	CacheTTL time.Duration
+
+	mu       sync.Mutex
+	cached   []*unifi.PortForward
+	cachedAt time.Time
+	now      func() time.Time
+}
+
+// Start serves the state API until ctx is done
+func (s *Server) Start(ctx context.Context) error {
+	logger := ctrllog.FromContext(ctx).WithValues("component", "state-api")
+
+	namespace, name := s.service()
+	keyPair, err := webhook.ServingKeyPair(s.CertDir, name, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to load state API serving certificate: %w", err)
+	}
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort("", strconv.Itoa(s.Port)),
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{keyPair},
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("Serving read-only router state", "port", s.Port)
+		errCh <- server.ListenAndServeTLS("", "")
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("state API stopped: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to stop state API: %w", err)
+		}
+		return nil
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Handler returns the HTTP handler of the state API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(RulesPath, s.handleRules)
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		if !allowRead(w, r) {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	return mux
+}
+
+// handleRules answers with the status of the router rules, filtered by the port, owner,
+// namespace and drifted query parameters, which mirror the flags of the status command
+func (s *Server) handleRules(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) || !s.authorize(w, r) {
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	routerRules, err := s.routerRules(r.Context())
+	if err != nil {
+		ctrllog.FromContext(r.Context()).Error(err, "Failed to list router rules for the state API")
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	statuses, err := s.Reporter.Status(r.Context(), routerRules)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := RulesResponse{GeneratedAt: s.clock().UTC(), Rules: []controller.RouterRuleStatus{}}
+	for _, status := range statuses {
+		if filter.Matches(status) {
+			response.Rules = append(response.Rules, status)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// authorize reviews the caller's bearer token, which must be bound to Audience, and checks that
+// the caller may get the services/proxy subresource of the state API Service. The API listens
+// on the host network, where a NetworkPolicy would not restrict it.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if s.Client == nil {
+		writeError(w, http.StatusInternalServerError, errors.New("state API has no client to review callers"))
+		return false
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		writeError(w, http.StatusUnauthorized, errors.New("a bearer token is required"))
+		return false
+	}
+
+	tokenReview := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{
+		Token:     token,
+		Audiences: []string{Audience},
+	}}
+	if err := s.Client.Create(r.Context(), tokenReview); err != nil {
+		ctrllog.FromContext(r.Context()).Error(err, "Failed to review a state API token")
+		writeError(w, http.StatusInternalServerError, errors.New("failed to review the token"))
+		return false
+	}
+	if !tokenReview.Status.Authenticated || !slices.Contains(tokenReview.Status.Audiences, Audience) {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid bearer token, it must be bound to the audience %s", Audience))
+		return false
+	}
+
+	namespace, name := s.service()
+	user := tokenReview.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	accessReview := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        "get",
+				Resource:    "services",
+				Subresource: "proxy",
+				Name:        name,
+			},
+		},
+	}
+	if err := s.Client.Create(r.Context(), accessReview); err != nil {
+		ctrllog.FromContext(r.Context()).Error(err, "Failed to review state API access")
+		writeError(w, http.StatusInternalServerError, errors.New("failed to review access"))
+		return false
+	}
+	if !accessReview.Status.Allowed {
+		writeError(w, http.StatusForbidden, fmt.Errorf("user %q may not get services/proxy %s in namespace %s",
+			user.Username, name, namespace))
+		return false
+	}
+	return true
+}
+
+// service returns the namespace and name of the state API Service
+func (s *Server) service() (string, string) {
+	namespace, name := s.Namespace, s.ServiceName
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	if name == "" {
+		name = DefaultServiceName
+	}
+	return namespace, name
+}
+
+// bearerToken returns the token of the Authorization header, or of TokenHeader for requests
+// proxied by the API server
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(r.Header.Get(TokenHeader))
+}
+
+// routerRules returns the router rules, reusing those listed within the cache TTL
+func (s *Server) routerRules(ctx context.Context) ([]*unifi.PortForward, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ttl := s.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	now := s.clock()
+	if s.cached != nil && ttl > 0 && now.Sub(s.cachedAt) < ttl {
+		return s.cached, nil
+	}
+
+	rules, err := s.Router.ListAllPortForwards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+	s.cached, s.cachedAt = rules, now
+	return rules, nil
+}
+
+func (s *Server) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// parseFilter reads a status filter from the query parameters of a request. Ports and owners
+// may be repeated or comma-separated.
+func parseFilter(r *http.Request) (controller.StatusFilter, error) {
+	query := r.URL.Query()
+	filter := controller.StatusFilter{
+		OwnerKinds: splitValues(query["owner"]),
+		Namespace:  query.Get("namespace"),
+	}
+
+	for _, value := range splitValues(query["port"]) {
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return filter, fmt.Errorf("invalid port %q", value)
+		}
+		filter.Ports = append(filter.Ports, port)
+	}
+
+	if value := query.Get("drifted"); value != "" {
+		drifted, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid drifted %q", value)
+		}
+		filter.DriftedOnly = drifted
+	}
+	return filter, nil
+}
+
+// FilterQuery encodes a status filter as the query parameters parseFilter reads
+func FilterQuery(filter controller.StatusFilter) url.Values {
+	query := url.Values{}
+	for _, port := range filter.Ports {
+		query.Add("port", strconv.Itoa(port))
+	}
+	for _, kind := range filter.OwnerKinds {
+		query.Add("owner", kind)
+	}
+	if filter.Namespace != "" {
+		query.Set("namespace", filter.Namespace)
+	}
+	if filter.DriftedOnly {
+		query.Set("drifted", "true")
+	}
+	return query
+}
+
+func splitValues(values []string) []string {
+	var split []string
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				split = append(split, field)
+			}
+		}
+	}
+	return split
+}
+
+// allowRead rejects every method but GET and HEAD; the API never changes anything
+func allowRead(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
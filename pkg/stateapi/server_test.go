@@ -0,0 +1,291 @@
+package stateapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/testutils"
+
+	"github.com/filipowm/go-unifi/unifi"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestServer(t *testing.T) (*Server, *testutils.MockRouter) {
+	t.Helper()
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{config.FilterAnnotation: "8080:http"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 8080, Protocol: corev1.ProtocolTCP}},
+		},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "192.168.1.100"}},
+		}},
+	}
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := manifests.NewStore(scheme, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := testutils.NewMockRouter()
+	router.AddPortForwardRule(unifi.PortForward{ID: "1", Name: "default/web:http", DstPort: "8080", FwdPort: "8080",
+		Fwd: "192.168.1.100", Proto: "tcp", Enabled: true})
+	router.AddPortForwardRule(unifi.PortForward{ID: "2", Name: "Printer", DstPort: "631", FwdPort: "631",
+		Fwd: "192.168.1.25", Proto: "tcp"})
+
+	kubeClient := testutils.NewFakeKubernetesClient(t, scheme)
+	kubeClient.Authenticate = func(token string) (authenticationv1.UserInfo, []string, bool) {
+		switch token {
+		case "viewer-token":
+			return authenticationv1.UserInfo{Username: "viewer"}, []string{Audience}, true
+		case "viewer-kubeconfig-token":
+			return authenticationv1.UserInfo{Username: "viewer"}, []string{"https://kubernetes.default.svc"}, true
+		case "other-token":
+			return authenticationv1.UserInfo{Username: "other"}, []string{Audience}, true
+		}
+		return authenticationv1.UserInfo{}, nil, false
+	}
+	kubeClient.Authorize = func(spec authorizationv1.SubjectAccessReviewSpec) bool {
+		attributes := spec.ResourceAttributes
+		return spec.User == "viewer" && attributes != nil && attributes.Verb == "get" &&
+			attributes.Resource == "services" && attributes.Subresource == "proxy" &&
+			attributes.Namespace == DefaultNamespace && attributes.Name == DefaultServiceName
+	}
+
+	server := &Server{
+		Reporter: &controller.StatusReporter{Client: store, Config: &config.Config{}},
+		Router:   router,
+		Client:   kubeClient,
+	}
+	return server, router
+}
+
+// newViewerRequest returns a request carrying the token of a user allowed to read the state API
+func newViewerRequest(method, target string) *http.Request {
+	request := httptest.NewRequest(method, target, nil)
+	request.Header.Set("Authorization", "Bearer viewer-token")
+	return request
+}
+
+func TestServer_Rules(t *testing.T) {
+	server, _ := newTestServer(t)
+	handler := server.Handler()
+
+	get := func(target string) (int, RulesResponse) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, newViewerRequest(http.MethodGet, target))
+		var response RulesResponse
+		if recorder.Code == http.StatusOK {
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+		}
+		return recorder.Code, response
+	}
+
+	code, response := get(RulesPath)
+	if code != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", RulesPath, code)
+	}
+	if len(response.Rules) != 2 || response.Rules[0].OwnerKind != controller.OwnerManual ||
+		response.Rules[1].Owner != "default/web" || response.Rules[1].Drift != controller.DriftInSync {
+		t.Errorf("rules = %+v, want the manual printer rule and the in-sync web rule", response.Rules)
+	}
+
+	_, response = get(RulesPath + "?port=8080")
+	if len(response.Rules) != 1 || response.Rules[0].ID != "1" {
+		t.Errorf("rules for port 8080 = %+v, want rule 1", response.Rules)
+	}
+
+	_, response = get(RulesPath + "?owner=manual,orphaned")
+	if len(response.Rules) != 1 || response.Rules[0].ID != "2" {
+		t.Errorf("manual rules = %+v, want rule 2", response.Rules)
+	}
+
+	if code, _ := get(RulesPath + "?port=http"); code != http.StatusBadRequest {
+		t.Errorf("GET with an invalid port = %d, want 400", code)
+	}
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	server, _ := newTestServer(t)
+	handler := server.Handler()
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, newViewerRequest(method, RulesPath))
+		if recorder.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s = %d, want 405", method, RulesPath, recorder.Code)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("GET %s = %d, want 200", HealthPath, recorder.Code)
+	}
+}
+
+func TestServer_Authorize(t *testing.T) {
+	server, router := newTestServer(t)
+	handler := server.Handler()
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{name: "no token", want: http.StatusUnauthorized},
+		{name: "invalid token", header: "Authorization", value: "Bearer stolen", want: http.StatusUnauthorized},
+		{name: "not a bearer token", header: "Authorization", value: "Basic dmlld2Vy", want: http.StatusUnauthorized},
+		{name: "token of another audience", header: "Authorization", value: "Bearer viewer-kubeconfig-token", want: http.StatusUnauthorized},
+		{name: "user without the viewer role", header: "Authorization", value: "Bearer other-token", want: http.StatusForbidden},
+		{name: "viewer", header: "Authorization", value: "Bearer viewer-token", want: http.StatusOK},
+		{name: "viewer through the API server proxy", header: TokenHeader, value: "viewer-token", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, RulesPath, nil)
+			if tt.header != "" {
+				request.Header.Set(tt.header, tt.value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d: %s", RulesPath, recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+	if calls := router.GetCallCount("ListAllPortForwards"); calls != 1 {
+		t.Errorf("router listed %d times, want once for the allowed requests", calls)
+	}
+
+	server.Client = nil
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newViewerRequest(http.MethodGet, RulesPath))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("GET %s without a client = %d, want 500", RulesPath, recorder.Code)
+	}
+}
+
+func TestServer_RouterRulesCache(t *testing.T) {
+	server, router := newTestServer(t)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	server.now = func() time.Time { return now }
+	handler := server.Handler()
+
+	request := func() int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, newViewerRequest(http.MethodGet, RulesPath))
+		return recorder.Code
+	}
+
+	request()
+	request()
+	if calls := router.GetCallCount("ListAllPortForwards"); calls != 1 {
+		t.Errorf("router listed %d times within the cache TTL, want 1", calls)
+	}
+
+	now = now.Add(DefaultCacheTTL)
+	request()
+	if calls := router.GetCallCount("ListAllPortForwards"); calls != 2 {
+		t.Errorf("router listed %d times after the cache TTL, want 2", calls)
+	}
+
+	now = now.Add(DefaultCacheTTL)
+	router.SetSimulatedFailure("ListAllPortForwards", true)
+	if code := request(); code != http.StatusBadGateway {
+		t.Errorf("GET with a failing router = %d, want 502", code)
+	}
+}
+
+func TestServer_ServesTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	server, _ := newTestServer(t)
+	server.Port = port
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Start(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	}()
+
+	// The generated certificate is trusted by nobody, like by the API server service proxy
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	target := fmt.Sprintf("https://127.0.0.1:%d%s", port, HealthPath)
+	var response *http.Response
+	for attempt := 0; attempt < 50; attempt++ {
+		if response, err = httpClient.Get(target); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("GET %s error = %v", target, err)
+	}
+	_ = response.Body.Close()
+	if response.TLS == nil || response.StatusCode != http.StatusOK {
+		t.Errorf("GET %s = %d over TLS %v, want 200 over TLS", target, response.StatusCode, response.TLS != nil)
+	}
+
+	plain := fmt.Sprintf("http://127.0.0.1:%d%s", port, HealthPath)
+	if response, err := http.Get(plain); err == nil {
+		_ = response.Body.Close()
+		if response.StatusCode == http.StatusOK {
+			t.Errorf("GET %s succeeded without TLS", plain)
+		}
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	filter := controller.StatusFilter{
+		Ports:       []int{443, 8443},
+		OwnerKinds:  []string{"Service", controller.OwnerManual},
+		Namespace:   "apps",
+		DriftedOnly: true,
+	}
+
+	request := httptest.NewRequest(http.MethodGet, RulesPath+"?"+FilterQuery(filter).Encode(), nil)
+	parsed, err := parseFilter(request)
+	if err != nil {
+		t.Fatalf("parseFilter() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed, filter) {
+		t.Errorf("parseFilter(FilterQuery()) = %+v, want %+v", parsed, filter)
+	}
+}
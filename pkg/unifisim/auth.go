@@ -0,0 +1,127 @@
+package unifisim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+// SessionCookie is the name of the UniFi OS session cookie
+const SessionCookie = "TOKEN"
+
+// session is a login session with the CSRF token its changes must carry
+type session struct {
+	csrfToken string
+	expires   time.Time
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Remember bool   `json:"remember"`
+}
+
+// loginError is the body UniFi OS answers failed logins with
+type loginError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleLogin starts a session for valid credentials, setting the session cookie and returning
+// the CSRF token in the X-Csrf-Token header
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var login loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+		writeError(w, http.StatusBadRequest, MsgInvalidPayload, nil)
+		return
+	}
+
+	if s.config.Password == "" || !equal(login.Username, s.config.Username) || !equal(login.Password, s.config.Password) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(loginError{
+			Code:    "AUTHENTICATION_FAILED_INVALID_CREDENTIALS",
+			Message: "Invalid username or password",
+		})
+		return
+	}
+
+	token := randomHex(32)
+	current := session{csrfToken: randomHex(16)}
+
+	s.mu.Lock()
+	if s.config.SessionTTL > 0 {
+		current.expires = s.clock().Add(s.config.SessionTTL)
+	}
+	s.sessions[token] = current
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: token, Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+	w.Header().Set(unifi.CsrfHeader, current.csrfToken)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"username": login.Username,
+		"isOwner":  true,
+	})
+}
+
+// handleLogout ends the session of the request, if any
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		s.mu.Lock()
+		delete(s.sessions, cookie.Value)
+		s.mu.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: "", Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticated passes requests with a valid API key, or with a live session that carries the
+// session's CSRF token on changes, to next
+func (s *Server) authenticated(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(unifi.ApiKeyHeader); key != "" {
+			if s.config.APIKey == "" || !equal(key, s.config.APIKey) {
+				writeError(w, http.StatusUnauthorized, MsgLoginRequired, nil)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(SessionCookie)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, MsgLoginRequired, nil)
+			return
+		}
+
+		s.mu.Lock()
+		current, ok := s.sessions[cookie.Value]
+		if ok && !current.expires.IsZero() && !s.clock().Before(current.expires) {
+			delete(s.sessions, cookie.Value)
+			ok = false
+		}
+		s.mu.Unlock()
+
+		if !ok {
+			writeError(w, http.StatusUnauthorized, MsgLoginRequired, nil)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !equal(r.Header.Get(unifi.CsrfHeader), current.csrfToken) {
+			writeError(w, http.StatusForbidden, MsgInvalidCSRFToken, nil)
+			return
+		}
+
+		w.Header().Set(unifi.CsrfHeader, current.csrfToken)
+		next(w, r)
+	})
+}
+
+// equal compares secrets in constant time
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,428 @@
+package unifisim
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+// Error messages of the Network application reproduced by the simulator. go-unifi reports them
+// as the Message of a *unifi.ServerError.
+const (
+	MsgLoginRequired       = "api.err.LoginRequired"
+	MsgInvalidCSRFToken    = "api.err.InvalidCsrfToken"
+	MsgInvalidPayload      = "api.err.InvalidPayload"
+	MsgNoSiteContext       = "api.err.NoSiteContext"
+	MsgIDInvalid           = "api.err.IdInvalid"
+	MsgNoEdit              = "api.err.NoEdit"
+	MsgNoDelete            = "api.err.NoDelete"
+	MsgPortForwardOverlaps = "api.err.PortForwardOverlaps"
+	MsgNotFound            = "api.err.NotFound"
+	MsgServerError         = "api.err.ServerError"
+)
+
+// Patterns reported with validation errors, shortened from the regular expressions of the
+// Network application
+const (
+	portPattern           = `[1-65535](-[1-65535])?(,[1-65535](-[1-65535])?){0,14}`
+	ipv4Pattern           = `ipv4`
+	addressOrAnyPattern   = `^any$|ipv4`
+	sourcePattern         = `^any$|!?(ipv4|ipv4-ipv4|ipv4/[0-32])`
+	namePattern           = `.{1,128}`
+	protocolPattern       = `tcp_udp|tcp|udp`
+	interfacePattern      = `wan|wan2|both|all`
+	sourceLimitingPattern = `ip|firewall_group`
+)
+
+// maxPortsPerRule is how many ports and port ranges one rule may list
+const maxPortsPerRule = 15
+
+// meta is the envelope status of every Network application response
+type meta struct {
+	RC              string                       `json:"rc"`
+	Message         string                       `json:"msg,omitempty"`
+	ValidationError *unifi.ServerValidationError `json:"validationError,omitempty"`
+}
+
+type response struct {
+	Meta meta `json:"meta"`
+	Data any  `json:"data"`
+}
+
+// writeData answers with data in an "ok" envelope
+func writeData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response{Meta: meta{RC: "ok"}, Data: data})
+}
+
+// writeError answers with an "error" envelope. Validation errors are repeated in the data, where
+// go-unifi reads the field and pattern from.
+func writeError(w http.ResponseWriter, status int, message string, validation *unifi.ServerValidationError) {
+	envelope := meta{RC: "error", Message: message, ValidationError: validation}
+	data := []any{}
+	if validation != nil {
+		data = append(data, map[string]any{"meta": envelope, "validationError": validation})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response{Meta: envelope, Data: data})
+}
+
+// site returns the site named in the request path, answering with NoSiteContext when unknown
+func (s *Server) site(w http.ResponseWriter, r *http.Request) (string, bool) {
+	site := r.PathValue("site")
+	s.mu.Lock()
+	known := slices.Contains(s.sites, site)
+	s.mu.Unlock()
+	if !known {
+		writeError(w, http.StatusBadRequest, MsgNoSiteContext, nil)
+		return "", false
+	}
+	return site, true
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"meta": map[string]any{"rc": "ok", "up": true, "server_version": s.config.Version},
+		"data": []any{},
+	})
+}
+
+func (s *Server) handleSites(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sites := make([]map[string]string, 0, len(s.sites))
+	for _, name := range s.sites {
+		description := name
+		if name == DefaultSite {
+			description = "Default"
+		}
+		sites = append(sites, map[string]string{"_id": siteID(name), "name": name, "desc": description, "role": "admin"})
+	}
+	writeData(w, sites)
+}
+
+func (s *Server) handleSysInfo(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.site(w, r); !ok {
+		return
+	}
+	writeData(w, []unifi.SysInfo{{
+		Timezone:       "UTC",
+		Version:        s.config.Version,
+		Build:          "atag_" + s.config.Version,
+		Name:           "UniFi Simulator",
+		Hostname:       "unifi-simulator",
+		Uptime:         int64(s.clock().Sub(s.started).Seconds()),
+		UBNTDeviceType: "UDMPRO",
+	}})
+}
+
+func (s *Server) handleListPortForwards(w http.ResponseWriter, r *http.Request) {
+	site, ok := s.site(w, r)
+	if !ok {
+		return
+	}
+	rules := s.PortForwards(site)
+	if rules == nil {
+		rules = []unifi.PortForward{}
+	}
+	writeData(w, rules)
+}
+
+// handleGetPortForward answers an unknown ID with no data, which go-unifi reports as
+// unifi.ErrNotFound
+func (s *Server) handleGetPortForward(w http.ResponseWriter, r *http.Request) {
+	site, ok := s.site(w, r)
+	if !ok {
+		return
+	}
+	rules := []unifi.PortForward{}
+	for _, rule := range s.PortForwards(site) {
+		if rule.ID == r.PathValue("id") {
+			rules = append(rules, rule)
+		}
+	}
+	writeData(w, rules)
+}
+
+func (s *Server) handleCreatePortForward(w http.ResponseWriter, r *http.Request) {
+	site, ok := s.site(w, r)
+	if !ok {
+		return
+	}
+
+	var rule unifi.PortForward
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, MsgInvalidPayload, nil)
+		return
+	}
+	// Attributes are the gateway's to set
+	rule.Hidden, rule.HiddenID, rule.NoDelete, rule.NoEdit = false, "", false, false
+	applyDefaults(&rule)
+	if validation := validate(&rule); validation != nil {
+		writeError(w, http.StatusBadRequest, MsgInvalidPayload, validation)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := s.rules[site]
+	if overlapsAny(rule, rules) {
+		writeError(w, http.StatusBadRequest, MsgPortForwardOverlaps, nil)
+		return
+	}
+	rule.ID = newID()
+	rule.SiteID = siteID(site)
+	if err := s.commit(site, append(slices.Clone(rules), rule)); err != nil {
+		writeError(w, http.StatusInternalServerError, MsgServerError, nil)
+		return
+	}
+	writeData(w, []unifi.PortForward{rule})
+}
+
+// handleUpdatePortForward merges the fields of the request into the rule, as the gateway
+// treats a PUT as a partial update
+func (s *Server) handleUpdatePortForward(w http.ResponseWriter, r *http.Request) {
+	site, ok := s.site(w, r)
+	if !ok {
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		writeError(w, http.StatusBadRequest, MsgInvalidPayload, nil)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := s.rules[site]
+	index := slices.IndexFunc(rules, func(rule unifi.PortForward) bool { return rule.ID == r.PathValue("id") })
+	if index < 0 {
+		writeError(w, http.StatusBadRequest, MsgIDInvalid, nil)
+		return
+	}
+	existing := rules[index]
+	if existing.NoEdit {
+		writeError(w, http.StatusBadRequest, MsgNoEdit, nil)
+		return
+	}
+
+	updated, err := merge(existing, fields)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, MsgInvalidPayload, nil)
+		return
+	}
+	updated.ID, updated.SiteID = existing.ID, existing.SiteID
+	updated.Hidden, updated.HiddenID, updated.NoDelete, updated.NoEdit = existing.Hidden, existing.HiddenID, existing.NoDelete, existing.NoEdit
+	applyDefaults(&updated)
+	if validation := validate(&updated); validation != nil {
+		writeError(w, http.StatusBadRequest, MsgInvalidPayload, validation)
+		return
+	}
+	others := slices.Delete(slices.Clone(rules), index, index+1)
+	if overlapsAny(updated, others) {
+		writeError(w, http.StatusBadRequest, MsgPortForwardOverlaps, nil)
+		return
+	}
+
+	next := slices.Clone(rules)
+	next[index] = updated
+	if err := s.commit(site, next); err != nil {
+		writeError(w, http.StatusInternalServerError, MsgServerError, nil)
+		return
+	}
+	writeData(w, []unifi.PortForward{updated})
+}
+
+func (s *Server) handleDeletePortForward(w http.ResponseWriter, r *http.Request) {
+	site, ok := s.site(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := s.rules[site]
+	index := slices.IndexFunc(rules, func(rule unifi.PortForward) bool { return rule.ID == r.PathValue("id") })
+	if index < 0 {
+		writeError(w, http.StatusBadRequest, MsgIDInvalid, nil)
+		return
+	}
+	if rules[index].NoDelete {
+		writeError(w, http.StatusBadRequest, MsgNoDelete, nil)
+		return
+	}
+
+	if err := s.commit(site, slices.Delete(slices.Clone(rules), index, index+1)); err != nil {
+		writeError(w, http.StatusInternalServerError, MsgServerError, nil)
+		return
+	}
+	writeData(w, []any{})
+}
+
+// merge overlays the JSON fields on rule
+func merge(rule unifi.PortForward, fields map[string]json.RawMessage) (unifi.PortForward, error) {
+	current, err := json.Marshal(rule)
+	if err != nil {
+		return rule, err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(current, &merged); err != nil {
+		return rule, err
+	}
+	for field, value := range fields {
+		merged[field] = value
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return rule, err
+	}
+	var updated unifi.PortForward
+	err = json.Unmarshal(data, &updated)
+	return updated, err
+}
+
+// applyDefaults sets the fields the gateway defaults when a rule leaves them out
+func applyDefaults(rule *unifi.PortForward) {
+	if rule.Proto == "" {
+		rule.Proto = "tcp_udp"
+	}
+	if rule.PfwdInterface == "" {
+		rule.PfwdInterface = "wan"
+	}
+	if rule.Src == "" {
+		rule.Src = "any"
+	}
+	if rule.DestinationIP == "" {
+		rule.DestinationIP = "any"
+	}
+}
+
+// validate checks the fields of a rule against the patterns of the Network application,
+// returning the first field that does not match
+func validate(rule *unifi.PortForward) *unifi.ServerValidationError {
+	checks := []struct {
+		field, pattern string
+		valid          bool
+	}{
+		{"name", namePattern, rule.Name != "" && utf8.RuneCountInString(rule.Name) <= 128},
+		{"dst_port", portPattern, parsePorts(rule.DstPort) != nil},
+		{"fwd_port", portPattern, parsePorts(rule.FwdPort) != nil},
+		{"fwd", ipv4Pattern, isIPv4(rule.Fwd)},
+		{"proto", protocolPattern, slices.Contains([]string{"tcp_udp", "tcp", "udp"}, rule.Proto)},
+		{"pfwd_interface", interfacePattern, slices.Contains([]string{"wan", "wan2", "both", "all"}, rule.PfwdInterface)},
+		{"src", sourcePattern, isSource(rule.Src)},
+		{"destination_ip", addressOrAnyPattern, rule.DestinationIP == "any" || isIPv4(rule.DestinationIP)},
+		{"src_limiting_type", sourceLimitingPattern, rule.SrcLimitingType == "" || rule.SrcLimitingType == "ip" || rule.SrcLimitingType == "firewall_group"},
+	}
+	for _, check := range checks {
+		if !check.valid {
+			return &unifi.ServerValidationError{Field: check.field, Pattern: check.pattern}
+		}
+	}
+	return nil
+}
+
+// portRange is an inclusive range of ports
+type portRange struct {
+	from, to int
+}
+
+// parsePorts parses a port, a port range or a comma separated list of up to 15 of them,
+// returning nil when it is not valid
+func parsePorts(value string) []portRange {
+	parts := strings.Split(value, ",")
+	if value == "" || len(parts) > maxPortsPerRule {
+		return nil
+	}
+
+	var ranges []portRange
+	for _, part := range parts {
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		start, startOK := parsePort(from)
+		end, endOK := parsePort(to)
+		if !startOK || !endOK {
+			return nil
+		}
+		ranges = append(ranges, portRange{from: start, to: end})
+	}
+	return ranges
+}
+
+func parsePort(value string) (int, bool) {
+	if value == "" || value[0] == '0' || value[0] == '+' {
+		return 0, false
+	}
+	port, err := strconv.Atoi(value)
+	return port, err == nil && port >= 1 && port <= 65535
+}
+
+func isIPv4(value string) bool {
+	ip := net.ParseIP(value)
+	return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+}
+
+// isSource reports whether value is "any" or an address, address range or CIDR, optionally
+// negated with "!"
+func isSource(value string) bool {
+	if value == "any" {
+		return true
+	}
+	value = strings.TrimPrefix(value, "!")
+	if from, to, isRange := strings.Cut(value, "-"); isRange {
+		return isIPv4(from) && isIPv4(to)
+	}
+	if address, bits, isCIDR := strings.Cut(value, "/"); isCIDR {
+		size, err := strconv.Atoi(bits)
+		return isIPv4(address) && err == nil && size >= 0 && size <= 32 && bits == strconv.Itoa(size)
+	}
+	return isIPv4(value)
+}
+
+// overlapsAny reports whether rule forwards a port that an enabled rule of rules also forwards,
+// on a shared WAN interface with a shared protocol. Disabled rules never overlap, so a rule can
+// be staged disabled next to the one it replaces.
+func overlapsAny(rule unifi.PortForward, rules []unifi.PortForward) bool {
+	if !rule.Enabled {
+		return false
+	}
+	ports := parsePorts(rule.DstPort)
+	for _, other := range rules {
+		if !other.Enabled || !interfacesOverlap(rule.PfwdInterface, other.PfwdInterface) || !protocolsOverlap(rule.Proto, other.Proto) {
+			continue
+		}
+		for _, a := range ports {
+			for _, b := range parsePorts(other.DstPort) {
+				if a.from <= b.to && b.from <= a.to {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func interfacesOverlap(a, b string) bool {
+	return a == b || a == "both" || a == "all" || b == "both" || b == "all"
+}
+
+func protocolsOverlap(a, b string) bool {
+	return a == b || a == "tcp_udp" || b == "tcp_udp"
+}
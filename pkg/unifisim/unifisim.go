@@ -0,0 +1,278 @@
+// Package unifisim simulates the parts of a UniFi OS gateway that go-unifi and the controller
+// talk to: the login and logout endpoints with their session cookie and CSRF token, API keys,
+// sites, system information and port forward CRUD. It reproduces the errors of a real gateway,
+// such as PortForwardOverlaps, 401s on expired sessions, field validation errors and rules that
+// may not be edited, so the HTTP client, authentication retries and error handling can be
+// tested end-to-end without a gateway. Serve Handler with httptest.NewTLSServer in tests, or run
+// it with the simulate command.
+package unifisim
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+// Defaults of a simulated gateway
+const (
+	DefaultSite    = "default"
+	DefaultVersion = "9.0.114"
+)
+
+// Config holds simulator configuration
+type Config struct {
+	// Username and Password are the credentials accepted by the login endpoint; an empty
+	// password disables password logins
+	Username string
+	Password string
+
+	// APIKey is the key accepted in the X-Api-Key header; empty disables API keys
+	APIKey string
+
+	// Sites are the names of the sites served, DefaultSite when empty
+	Sites []string
+
+	// Version is the Network application version reported, DefaultVersion when empty
+	Version string
+
+	// SessionTTL is how long a login session lasts before requests are answered with 401;
+	// zero keeps sessions until logout
+	SessionTTL time.Duration
+
+	// Latency delays every response, as a gateway across a slow link would
+	Latency time.Duration
+
+	// StateFile keeps the port forward rules across restarts when set; it is read by New and
+	// written after every change
+	StateFile string
+}
+
+// Server is a simulated UniFi OS gateway. It is safe for concurrent use.
+type Server struct {
+	config Config
+
+	mu       sync.Mutex
+	sites    []string
+	rules    map[string][]unifi.PortForward
+	sessions map[string]session
+	started  time.Time
+	now      func() time.Time
+}
+
+// stateFile is the format of Config.StateFile
+type stateFile struct {
+	PortForwards map[string][]unifi.PortForward `json:"portForwards"`
+}
+
+// New creates a simulator, loading the rules of cfg.StateFile when it exists
+func New(cfg Config) (*Server, error) {
+	if cfg.Password == "" && cfg.APIKey == "" {
+		return nil, errors.New("either a password or an API key must be accepted")
+	}
+	if cfg.Version == "" {
+		cfg.Version = DefaultVersion
+	}
+	if len(cfg.Sites) == 0 {
+		cfg.Sites = []string{DefaultSite}
+	}
+
+	s := &Server{
+		config:   cfg,
+		sites:    slices.Clone(cfg.Sites),
+		rules:    map[string][]unifi.PortForward{},
+		sessions: map[string]session{},
+	}
+	s.started = s.clock()
+
+	if cfg.StateFile != "" {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Handler returns the HTTP handler of the simulated gateway. UniFi OS serves HTTPS only, and
+// go-unifi connects over HTTPS, so serve it with TLS.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// UniFi OS answers its landing page with 200, which go-unifi takes for the new API style
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<!DOCTYPE html><title>UniFi OS</title>\n"))
+	})
+	mux.HandleFunc("POST /api/auth/login", s.handleLogin)
+	mux.HandleFunc("POST /api/logout", s.handleLogout)
+	mux.HandleFunc("POST /api/auth/logout", s.handleLogout)
+	mux.HandleFunc("GET /proxy/network/status", s.handleStatus)
+
+	mux.Handle("GET /proxy/network/api/self/sites", s.authenticated(s.handleSites))
+	mux.Handle("GET /proxy/network/api/s/{site}/stat/sysinfo", s.authenticated(s.handleSysInfo))
+	mux.Handle("GET /proxy/network/api/s/{site}/rest/portforward", s.authenticated(s.handleListPortForwards))
+	mux.Handle("POST /proxy/network/api/s/{site}/rest/portforward", s.authenticated(s.handleCreatePortForward))
+	mux.Handle("GET /proxy/network/api/s/{site}/rest/portforward/{id}", s.authenticated(s.handleGetPortForward))
+	mux.Handle("PUT /proxy/network/api/s/{site}/rest/portforward/{id}", s.authenticated(s.handleUpdatePortForward))
+	mux.Handle("DELETE /proxy/network/api/s/{site}/rest/portforward/{id}", s.authenticated(s.handleDeletePortForward))
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, MsgNotFound, nil)
+	})
+
+	if s.config.Latency <= 0 {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(s.config.Latency):
+			mux.ServeHTTP(w, r)
+		case <-r.Context().Done():
+		}
+	})
+}
+
+// Seed adds rules to a site as they are, without validation or overlap checks, for setting up
+// rules a gateway would have made itself, such as those with NoEdit or NoDelete set. Rules
+// without an ID are given one. It returns the rules as stored.
+func (s *Server) Seed(site string, rules ...unifi.PortForward) ([]unifi.PortForward, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.Contains(s.sites, site) {
+		return nil, fmt.Errorf("unknown site %q", site)
+	}
+
+	seeded := make([]unifi.PortForward, 0, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			rule.ID = newID()
+		}
+		rule.SiteID = siteID(site)
+		seeded = append(seeded, rule)
+	}
+
+	if err := s.commit(site, append(slices.Clone(s.rules[site]), seeded...)); err != nil {
+		return nil, err
+	}
+	return seeded, nil
+}
+
+// PortForwards returns a copy of the rules of a site
+func (s *Server) PortForwards(site string) []unifi.PortForward {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.rules[site])
+}
+
+// ExpireSessions ends every login session, as the gateway does once a session times out, so
+// that the next request of each client is answered with 401 until it logs in again
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.sessions)
+}
+
+// commit replaces the rules of a site and writes the state file, keeping the previous rules
+// when the file cannot be written. The caller holds the lock.
+func (s *Server) commit(site string, rules []unifi.PortForward) error {
+	previous, existed := s.rules[site]
+	s.rules[site] = rules
+	if err := s.save(); err != nil {
+		if existed {
+			s.rules[site] = previous
+		} else {
+			delete(s.rules, site)
+		}
+		return err
+	}
+	return nil
+}
+
+// load reads the state file; a missing file is an empty state. Sites of the file that are not
+// configured are served as well.
+func (s *Server) load() error {
+	data, err := os.ReadFile(s.config.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", s.config.StateFile, err)
+	}
+	for site, rules := range state.PortForwards {
+		if !slices.Contains(s.sites, site) {
+			s.sites = append(s.sites, site)
+		}
+		s.rules[site] = rules
+	}
+	return nil
+}
+
+// save writes the state file, if any, through a temporary file so a crash never leaves it
+// half written. The caller holds the lock.
+func (s *Server) save() error {
+	if s.config.StateFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(stateFile{PortForwards: s.rules}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.config.StateFile), filepath.Base(s.config.StateFile)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.config.StateFile); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// newID returns a random ID shaped like the MongoDB object IDs of the Network application
+func newID() string {
+	return randomHex(12)
+}
+
+// siteID returns a stable ID for a site name, so IDs survive restarts
+func siteID(site string) string {
+	sum := sha1.Sum([]byte(site))
+	return hex.EncodeToString(sum[:12])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,347 @@
+package unifisim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"unifi-port-forward/pkg/routers"
+
+	"github.com/filipowm/go-unifi/unifi"
+)
+
+func newTestServer(t *testing.T, cfg Config) (*Server, *httptest.Server) {
+	t.Helper()
+
+	if cfg.Password == "" && cfg.APIKey == "" {
+		cfg.Username, cfg.Password = "admin", "secret"
+	}
+	simulator, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	server := httptest.NewTLSServer(simulator.Handler())
+	t.Cleanup(server.Close)
+	return simulator, server
+}
+
+func newTestRouter(t *testing.T, url string) *routers.UnifiRouter {
+	t.Helper()
+	router, err := routers.CreateUnifiRouter(url, "admin", "secret", DefaultSite, "")
+	if err != nil {
+		t.Fatalf("CreateUnifiRouter() error = %v", err)
+	}
+	return router
+}
+
+var webConfig = routers.PortConfig{
+	Name:      "default/web:https",
+	Enabled:   true,
+	Interface: "wan",
+	DstPort:   443,
+	FwdPort:   8443,
+	DstIP:     "192.168.1.100",
+	Protocol:  "tcp",
+}
+
+func TestRouter_CRUD(t *testing.T) {
+	ctx := context.Background()
+	simulator, server := newTestServer(t, Config{})
+	router := newTestRouter(t, server.URL)
+
+	if router.Client.Version() != DefaultVersion {
+		t.Errorf("Version() = %q, want %q", router.Client.Version(), DefaultVersion)
+	}
+
+	created, err := router.CreatePort(ctx, webConfig)
+	if err != nil {
+		t.Fatalf("CreatePort() error = %v", err)
+	}
+	if created.ID == "" || created.Src != "any" || created.SiteID != siteID(DefaultSite) {
+		t.Errorf("CreatePort() = %+v, want an ID, source any and the site ID", created)
+	}
+
+	updated := webConfig
+	updated.DstIP = "192.168.1.101"
+	if err := router.UpdatePortByID(ctx, created.ID, updated); err != nil {
+		t.Fatalf("UpdatePortByID() error = %v", err)
+	}
+	rules := simulator.PortForwards(DefaultSite)
+	if len(rules) != 1 || rules[0].Fwd != "192.168.1.101" || rules[0].ID != created.ID {
+		t.Fatalf("rules after update = %+v, want the rule forwarding to 192.168.1.101", rules)
+	}
+
+	if _, err := router.Client.GetPortForward(ctx, DefaultSite, "000000000000000000000000"); !errors.Is(err, unifi.ErrNotFound) {
+		t.Errorf("GetPortForward() of an unknown ID error = %v, want unifi.ErrNotFound", err)
+	}
+
+	if err := router.DeletePortForwardByID(ctx, created.ID); err != nil {
+		t.Fatalf("DeletePortForwardByID() error = %v", err)
+	}
+	if rules := simulator.PortForwards(DefaultSite); len(rules) != 0 {
+		t.Errorf("rules after delete = %+v, want none", rules)
+	}
+}
+
+func TestRouter_PortForwardOverlaps(t *testing.T) {
+	ctx := context.Background()
+	_, server := newTestServer(t, Config{})
+	router := newTestRouter(t, server.URL)
+
+	if _, err := router.CreatePort(ctx, webConfig); err != nil {
+		t.Fatalf("CreatePort() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		change   func(*routers.PortConfig)
+		overlaps bool
+	}{
+		{"same port and protocol", func(c *routers.PortConfig) {}, true},
+		{"tcp and udp", func(c *routers.PortConfig) { c.Protocol = "tcp_udp" }, true},
+		{"both interfaces", func(c *routers.PortConfig) { c.Interface = "both" }, true},
+		{"other protocol", func(c *routers.PortConfig) { c.Protocol = "udp" }, false},
+		{"other interface", func(c *routers.PortConfig) { c.Interface = "wan2" }, false},
+		{"disabled", func(c *routers.PortConfig) { c.Enabled = false }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := webConfig
+			config.Name = "default/alt:" + tt.name
+			tt.change(&config)
+
+			created, err := router.CreatePort(ctx, config)
+			if tt.overlaps {
+				if err == nil || !strings.Contains(err.Error(), "PortForwardOverlaps") {
+					t.Fatalf("CreatePort() error = %v, want PortForwardOverlaps", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreatePort() error = %v", err)
+			}
+			if err := router.DeletePortForwardByID(ctx, created.ID); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRouter_SessionExpiry(t *testing.T) {
+	ctx := context.Background()
+	simulator, server := newTestServer(t, Config{SessionTTL: time.Hour})
+	now := time.Now()
+	simulator.now = func() time.Time { return now }
+	router := newTestRouter(t, server.URL)
+
+	// A bare client sees the 401 that the router's authentication retry recovers from
+	bare, err := unifi.NewClient(&unifi.ClientConfig{URL: server.URL, User: "admin", Password: "secret", VerifySSL: false})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	var serverErr *unifi.ServerError
+	if _, err := bare.ListPortForward(ctx, DefaultSite); !errors.As(err, &serverErr) ||
+		serverErr.StatusCode != http.StatusUnauthorized || serverErr.Message != MsgLoginRequired {
+		t.Fatalf("ListPortForward() of an expired session error = %v, want 401 %s", err, MsgLoginRequired)
+	}
+	if _, err := router.ListAllPortForwards(ctx); err != nil {
+		t.Fatalf("ListAllPortForwards() after expiry error = %v, want the router to log in again", err)
+	}
+
+	simulator.ExpireSessions()
+	if _, err := router.CreatePort(ctx, webConfig); err != nil {
+		t.Fatalf("CreatePort() after ExpireSessions() error = %v, want the router to log in again", err)
+	}
+}
+
+func TestRouter_ValidationError(t *testing.T) {
+	_, server := newTestServer(t, Config{})
+	router := newTestRouter(t, server.URL)
+
+	config := webConfig
+	config.DstPort = 70000
+	_, err := router.CreatePort(context.Background(), config)
+
+	var serverErr *unifi.ServerError
+	if !errors.As(err, &serverErr) || serverErr.StatusCode != http.StatusBadRequest || serverErr.Message != MsgInvalidPayload {
+		t.Fatalf("CreatePort() error = %v, want 400 %s", err, MsgInvalidPayload)
+	}
+	if len(serverErr.Details) != 1 || serverErr.Details[0].ValidationError.Field != "dst_port" {
+		t.Errorf("error details = %+v, want the dst_port field", serverErr.Details)
+	}
+}
+
+func TestRouter_NoEditNoDelete(t *testing.T) {
+	ctx := context.Background()
+	simulator, server := newTestServer(t, Config{})
+	seeded, err := simulator.Seed(DefaultSite,
+		unifi.PortForward{Name: "UniFi Identity", DstPort: "8443", FwdPort: "8443", Fwd: "192.168.1.1", Proto: "tcp", PfwdInterface: "wan", Enabled: true, NoEdit: true},
+		unifi.PortForward{Name: "Teleport", DstPort: "3478", FwdPort: "3478", Fwd: "192.168.1.1", Proto: "udp", PfwdInterface: "wan", Enabled: true, NoDelete: true},
+	)
+	if err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	router := newTestRouter(t, server.URL)
+
+	if err := router.UpdatePortByID(ctx, seeded[0].ID, webConfig); err == nil || !strings.Contains(err.Error(), MsgNoEdit) {
+		t.Errorf("UpdatePortByID() of a NoEdit rule error = %v, want %s", err, MsgNoEdit)
+	}
+	if err := router.DeletePortForwardByID(ctx, seeded[1].ID); err == nil || !strings.Contains(err.Error(), MsgNoDelete) {
+		t.Errorf("DeletePortForwardByID() of a NoDelete rule error = %v, want %s", err, MsgNoDelete)
+	}
+	if err := router.DeletePortForwardByID(ctx, seeded[0].ID); err != nil {
+		t.Errorf("DeletePortForwardByID() of a NoEdit rule error = %v", err)
+	}
+}
+
+func TestRouter_APIKey(t *testing.T) {
+	_, server := newTestServer(t, Config{APIKey: "key"})
+
+	router, err := routers.CreateUnifiRouter(server.URL, "", "", DefaultSite, "key")
+	if err != nil {
+		t.Fatalf("CreateUnifiRouter() with an API key error = %v", err)
+	}
+	if _, err := router.CreatePort(context.Background(), webConfig); err != nil {
+		t.Errorf("CreatePort() with an API key error = %v", err)
+	}
+
+	// The version is read from the public status endpoint, so a wrong key fails on first use
+	wrong, err := routers.CreateUnifiRouter(server.URL, "", "", DefaultSite, "wrong")
+	if err != nil {
+		t.Fatalf("CreateUnifiRouter() with a wrong API key error = %v", err)
+	}
+	if _, err := wrong.ListAllPortForwards(context.Background()); err == nil || !strings.Contains(err.Error(), MsgLoginRequired) {
+		t.Errorf("ListAllPortForwards() with a wrong API key error = %v, want %s", err, MsgLoginRequired)
+	}
+	if _, err := routers.CreateUnifiRouter(server.URL, "admin", "secret", DefaultSite, ""); err == nil {
+		t.Error("CreateUnifiRouter() with a password succeeded, want password logins disabled")
+	}
+}
+
+func TestLogin_InvalidCredentials(t *testing.T) {
+	_, server := newTestServer(t, Config{})
+
+	_, err := routers.CreateUnifiRouter(server.URL, "admin", "wrong", DefaultSite, "")
+	if err == nil || !strings.Contains(err.Error(), "Invalid username or password") {
+		t.Errorf("CreateUnifiRouter() error = %v, want invalid credentials", err)
+	}
+}
+
+func TestCSRFToken(t *testing.T) {
+	_, server := newTestServer(t, Config{})
+	jar, _ := cookiejar.New(nil)
+	client := server.Client()
+	client.Jar = jar
+
+	login, err := client.Post(server.URL+"/api/auth/login", "application/json", strings.NewReader(`{"username":"admin","password":"secret"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	login.Body.Close()
+	token := login.Header.Get(unifi.CsrfHeader)
+	if login.StatusCode != http.StatusOK || token == "" {
+		t.Fatalf("login = %d with token %q, want 200 with a CSRF token", login.StatusCode, token)
+	}
+
+	rules := server.URL + "/proxy/network/api/s/default/rest/portforward"
+	body := `{"name":"web","dst_port":"80","fwd_port":"80","fwd":"192.168.1.100","enabled":true}`
+	for _, tt := range []struct {
+		token  string
+		status int
+	}{
+		{"", http.StatusForbidden},
+		{"wrong", http.StatusForbidden},
+		{token, http.StatusOK},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, rules, strings.NewReader(body))
+		if tt.token != "" {
+			req.Header.Set(unifi.CsrfHeader, tt.token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("POST with CSRF token %q = %d, want %d", tt.token, resp.StatusCode, tt.status)
+		}
+	}
+
+	logout, err := client.Post(server.URL+"/api/logout", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logout.Body.Close()
+	list, err := client.Get(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list.Body.Close()
+	if list.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET after logout = %d, want 401", list.StatusCode)
+	}
+}
+
+func TestUnknownSite(t *testing.T) {
+	_, server := newTestServer(t, Config{})
+	router := newTestRouter(t, server.URL)
+	router.SiteID = "branch"
+
+	if _, err := router.ListAllPortForwards(context.Background()); err == nil || !strings.Contains(err.Error(), MsgNoSiteContext) {
+		t.Errorf("ListAllPortForwards() of an unknown site error = %v, want %s", err, MsgNoSiteContext)
+	}
+}
+
+func TestStateFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	_, server := newTestServer(t, Config{StateFile: stateFile})
+	router := newTestRouter(t, server.URL)
+
+	created, err := router.CreatePort(context.Background(), webConfig)
+	if err != nil {
+		t.Fatalf("CreatePort() error = %v", err)
+	}
+
+	restarted, err := New(Config{Password: "secret", StateFile: stateFile})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	rules := restarted.PortForwards(DefaultSite)
+	if len(rules) != 1 || !reflect.DeepEqual(rules[0], *created) {
+		t.Errorf("rules after restart = %+v, want %+v", rules, *created)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	_, server := newTestServer(t, Config{Latency: 50 * time.Millisecond})
+
+	start := time.Now()
+	resp, err := server.Client().Get(server.URL + "/proxy/network/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("request took %s, want at least the 50ms latency", elapsed)
+	}
+}
+
+func TestParsePorts(t *testing.T) {
+	if got := parsePorts("80,443,8000-8100"); !reflect.DeepEqual(got, []portRange{{80, 80}, {443, 443}, {8000, 8100}}) {
+		t.Errorf("parsePorts() = %v", got)
+	}
+	for _, invalid := range []string{"", "0", "080", "65536", "http", "80,", "1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16"} {
+		if got := parsePorts(invalid); got != nil {
+			t.Errorf("parsePorts(%q) = %v, want nil", invalid, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package uninstall
+
+import (
+	"context"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/api/v1beta1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// hubClient reads and patches PortForwardRules and ClusterPortForwardRules in v1beta1, the
+// version the CRDs store, converting them to v1alpha1 locally. Serving v1alpha1 needs the
+// conversion webhook of the controller, which is stopped during an uninstall.
+type hubClient struct {
+	client.Client
+}
+
+// NewHubClient returns a client serving v1alpha1 rules without the conversion webhook. The
+// scheme of c must know v1beta1.
+func NewHubClient(c client.Client) client.Client {
+	return &hubClient{Client: c}
+}
+
+// List implements client.Reader
+func (c *hubClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	switch typed := list.(type) {
+	case *v1alpha1.PortForwardRuleList:
+		var hub v1beta1.PortForwardRuleList
+		if err := c.Client.List(ctx, &hub, opts...); err != nil {
+			return err
+		}
+		typed.ListMeta = hub.ListMeta
+		typed.Items = make([]v1alpha1.PortForwardRule, len(hub.Items))
+		for i := range hub.Items {
+			if err := typed.Items[i].ConvertFrom(&hub.Items[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *v1alpha1.ClusterPortForwardRuleList:
+		var hub v1beta1.ClusterPortForwardRuleList
+		if err := c.Client.List(ctx, &hub, opts...); err != nil {
+			return err
+		}
+		typed.ListMeta = hub.ListMeta
+		typed.Items = make([]v1alpha1.ClusterPortForwardRule, len(hub.Items))
+		for i := range hub.Items {
+			if err := typed.Items[i].ConvertFrom(&hub.Items[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+// Patch implements client.Writer. The patch is computed on the v1alpha1 object and sent for
+// the v1beta1 object; release only patches metadata, which both versions share.
+func (c *hubClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	var hub client.Object
+	switch obj.(type) {
+	case *v1alpha1.PortForwardRule:
+		hub = &v1beta1.PortForwardRule{}
+	case *v1alpha1.ClusterPortForwardRule:
+		hub = &v1beta1.ClusterPortForwardRule{}
+	default:
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	hub.SetNamespace(obj.GetNamespace())
+	hub.SetName(obj.GetName())
+	return c.Client.Patch(ctx, hub, client.RawPatch(patch.Type(), data), opts...)
+}
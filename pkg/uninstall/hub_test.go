@@ -0,0 +1,81 @@
+package uninstall
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/api/v1beta1"
+	"unifi-port-forward/pkg/config"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// storedClient serves rules only in v1beta1, as an API server without the conversion webhook
+type storedClient struct {
+	client.Client
+
+	rules   []v1beta1.PortForwardRule
+	patched client.Object
+	patch   []byte
+}
+
+func (c *storedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	switch typed := list.(type) {
+	case *v1beta1.PortForwardRuleList:
+		typed.Items = c.rules
+	case *v1beta1.ClusterPortForwardRuleList:
+	default:
+		return fmt.Errorf("unexpected %T", list)
+	}
+	return nil
+}
+
+func (c *storedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.MergePatchType {
+		return fmt.Errorf("unexpected patch type %s", patch.Type())
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	c.patched, c.patch = obj, data
+	return nil
+}
+
+func TestHubClient(t *testing.T) {
+	stored := &storedClient{rules: []v1beta1.PortForwardRule{{
+		ObjectMeta: metav1.ObjectMeta{Name: "game", Namespace: "apps", Finalizers: []string{config.FinalizerLabel}},
+		Spec:       v1beta1.PortForwardRuleSpec{Ports: []v1beta1.PortForwardPort{{ExternalPort: 25565, Protocol: "tcp"}}},
+	}}}
+	hub := NewHubClient(stored)
+
+	var rules v1alpha1.PortForwardRuleList
+	if err := hub.List(context.Background(), &rules); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(rules.Items) != 1 || rules.Items[0].Spec.ExternalPort != 25565 {
+		t.Fatalf("List() = %+v, want the stored rule converted to v1alpha1", rules.Items)
+	}
+	var clusterRules v1alpha1.ClusterPortForwardRuleList
+	if err := hub.List(context.Background(), &clusterRules); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	rule := &rules.Items[0]
+	original := rule.DeepCopy()
+	controllerutil.RemoveFinalizer(rule, config.FinalizerLabel)
+	if err := hub.Patch(context.Background(), rule, client.MergeFrom(original)); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if _, ok := stored.patched.(*v1beta1.PortForwardRule); !ok || stored.patched.GetName() != "game" {
+		t.Errorf("Patch() sent %T %q, want the v1beta1 rule", stored.patched, stored.patched.GetName())
+	}
+	if string(stored.patch) != `{"metadata":{"finalizers":null}}` {
+		t.Errorf("Patch() sent %s, want only the finalizer change", stored.patch)
+	}
+}
@@ -0,0 +1,295 @@
+// Package uninstall removes an installation of the controller: the router rules it owns, the
+// finalizers and cleanup annotations it put on Services and rules, its validating webhook
+// configuration, and optionally its CRDs
+package uninstall
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/controller"
+	"unifi-port-forward/pkg/webhook"
+
+	"github.com/filipowm/go-unifi/unifi"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Kinds of the objects an uninstall removes or releases
+const (
+	KindRouterRule = "RouterRule"
+	KindWebhook    = "ValidatingWebhookConfiguration"
+	KindCRD        = "CustomResourceDefinition"
+
+	KindMutatingWebhook = "MutatingWebhookConfiguration"
+)
+
+// Action is what an uninstall did, or would do, with an object
+type Action string
+
+const (
+	// ActionDelete deletes a router rule, the webhook configuration or a CRD
+	ActionDelete Action = "delete"
+
+	// ActionRelease removes the finalizer and cleanup annotations from a Service or rule
+	ActionRelease Action = "release"
+
+	// ActionFail reports an object that could not be removed or released
+	ActionFail Action = "fail"
+)
+
+// Result reports the removal of a single object
+type Result struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+
+	// ID is the router ID of router rules
+	ID string `json:"id,omitempty"`
+
+	Action Action `json:"action"`
+
+	// Reason explains failed objects
+	Reason string `json:"reason,omitempty"`
+}
+
+// Router is the part of a router an uninstall removes rules from
+type Router interface {
+	ListAllPortForwards(ctx context.Context) ([]*unifi.PortForward, error)
+	DeletePortForwardByID(ctx context.Context, ruleID string) error
+}
+
+// Options select what an uninstall removes
+type Options struct {
+	// DeleteCRDs also deletes the CRDs of the port forward API, and with them every rule
+	DeleteCRDs bool
+
+	// DryRun reports what would be removed without changing the router or the cluster
+	DryRun bool
+}
+
+// Uninstaller removes the router rules, finalizers and annotations of an installation
+type Uninstaller struct {
+	Client client.Client
+	Router Router
+	Config *config.Config
+}
+
+// Run removes the installation. Router rules go first, while their owners still tell which
+// rules belong to the installation. The validating webhook configuration goes next, as it
+// rejects rule updates while the controller is stopped. Services and rules are released
+// then, so deleting them no longer waits for a controller; CRDs go last. Objects that fail are reported in their
+// Result and do not stop the uninstall; errors are returned only when the router or cluster
+// cannot be read.
+func (u *Uninstaller) Run(ctx context.Context, opts Options) ([]Result, error) {
+	results, err := u.deleteRouterRules(ctx, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := u.deleteWebhook(ctx, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, deleted...)
+
+	released, err := u.release(ctx, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, released...)
+
+	if opts.DeleteCRDs {
+		deleted, err := u.deleteCRDs(ctx, opts.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, deleted...)
+	}
+	return results, nil
+}
+
+// deleteRouterRules deletes every router rule owned by a Service or rule, or named like one.
+// Manual rules are left alone.
+func (u *Uninstaller) deleteRouterRules(ctx context.Context, dryRun bool) ([]Result, error) {
+	routerRules, err := u.Router.ListAllPortForwards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+	reporter := &controller.StatusReporter{Client: u.Client, Config: u.Config}
+	statuses, err := reporter.Status(ctx, routerRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rule owners: %w", err)
+	}
+
+	var results []Result
+	for _, status := range statuses {
+		if status.OwnerKind == controller.OwnerManual {
+			continue
+		}
+		result := Result{Kind: KindRouterRule, Name: status.Name, ID: status.ID, Action: ActionDelete}
+		if !dryRun {
+			if err := u.Router.DeletePortForwardByID(ctx, status.ID); err != nil {
+				result.Action, result.Reason = ActionFail, fmt.Sprintf("delete failed: %v", err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// deleteWebhook deletes the validating and approval signing webhook configurations of the
+// controller
+func (u *Uninstaller) deleteWebhook(ctx context.Context, dryRun bool) ([]Result, error) {
+	var results []Result
+	for _, webhookConfig := range []struct {
+		kind string
+		obj  client.Object
+	}{
+		{KindWebhook, &admissionregistrationv1.ValidatingWebhookConfiguration{}},
+		{KindMutatingWebhook, &admissionregistrationv1.MutatingWebhookConfiguration{}},
+	} {
+		name := webhook.ValidatingWebhookConfigurationName
+		if webhookConfig.kind == KindMutatingWebhook {
+			name = webhook.MutatingWebhookConfigurationName
+		}
+		if err := u.Client.Get(ctx, client.ObjectKey{Name: name}, webhookConfig.obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get webhook configuration: %w", err)
+		}
+
+		result := Result{Kind: webhookConfig.kind, Name: name, Action: ActionDelete}
+		if !dryRun {
+			if err := u.Client.Delete(ctx, webhookConfig.obj); err != nil && !apierrors.IsNotFound(err) {
+				result.Action, result.Reason = ActionFail, fmt.Sprintf("delete failed: %v", err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// release removes the finalizer and cleanup annotations from Services, PortForwardRules and
+// ClusterPortForwardRules
+func (u *Uninstaller) release(ctx context.Context, dryRun bool) ([]Result, error) {
+	var objects []client.Object
+
+	var services corev1.ServiceList
+	if err := u.Client.List(ctx, &services); err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	for i := range services.Items {
+		objects = append(objects, &services.Items[i])
+	}
+
+	var rules v1alpha1.PortForwardRuleList
+	if err := u.Client.List(ctx, &rules); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("failed to list port forward rules: %w", err)
+	}
+	for i := range rules.Items {
+		objects = append(objects, &rules.Items[i])
+	}
+
+	var clusterRules v1alpha1.ClusterPortForwardRuleList
+	if err := u.Client.List(ctx, &clusterRules); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("failed to list cluster port forward rules: %w", err)
+	}
+	for i := range clusterRules.Items {
+		objects = append(objects, &clusterRules.Items[i])
+	}
+
+	var results []Result
+	for _, object := range objects {
+		if !isHeld(object) {
+			continue
+		}
+
+		name := object.GetName()
+		if object.GetNamespace() != "" {
+			name = client.ObjectKeyFromObject(object).String()
+		}
+
+		result := Result{Kind: objectKind(object), Name: name, Action: ActionRelease}
+		if !dryRun {
+			original := object.DeepCopyObject().(client.Object)
+			controllerutil.RemoveFinalizer(object, config.FinalizerLabel)
+			annotations := object.GetAnnotations()
+			delete(annotations, config.CleanupStatusAnnotation)
+			delete(annotations, config.CleanupAttemptsAnnotation)
+			object.SetAnnotations(annotations)
+
+			if err := u.Client.Patch(ctx, object, client.MergeFrom(original)); err != nil && !apierrors.IsNotFound(err) {
+				result.Action, result.Reason = ActionFail, fmt.Sprintf("release failed: %v", err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Kind != results[j].Kind {
+			return kindOrder[results[i].Kind] < kindOrder[results[j].Kind]
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+// deleteCRDs deletes the CRDs of the port forward API group
+func (u *Uninstaller) deleteCRDs(ctx context.Context, dryRun bool) ([]Result, error) {
+	var crds apiextensionsv1.CustomResourceDefinitionList
+	if err := u.Client.List(ctx, &crds); err != nil {
+		return nil, fmt.Errorf("failed to list CRDs: %w", err)
+	}
+
+	var results []Result
+	for i := range crds.Items {
+		crd := &crds.Items[i]
+		if crd.Spec.Group != v1alpha1.SchemeGroupVersion.Group {
+			continue
+		}
+
+		result := Result{Kind: KindCRD, Name: crd.Name, Action: ActionDelete}
+		if !dryRun {
+			if err := u.Client.Delete(ctx, crd); err != nil && !apierrors.IsNotFound(err) {
+				result.Action, result.Reason = ActionFail, fmt.Sprintf("delete failed: %v", err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results, nil
+}
+
+// kindOrder orders released objects in results
+var kindOrder = map[string]int{"Service": 0, "PortForwardRule": 1, "ClusterPortForwardRule": 2}
+
+// isHeld reports whether an object carries the finalizer or a cleanup annotation
+func isHeld(object client.Object) bool {
+	annotations := object.GetAnnotations()
+	_, hasStatus := annotations[config.CleanupStatusAnnotation]
+	_, hasAttempts := annotations[config.CleanupAttemptsAnnotation]
+	return controllerutil.ContainsFinalizer(object, config.FinalizerLabel) || hasStatus || hasAttempts
+}
+
+// objectKind returns the kind of the objects release works with; listed objects do not carry
+// their kind
+func objectKind(object client.Object) string {
+	switch object.(type) {
+	case *corev1.Service:
+		return "Service"
+	case *v1alpha1.ClusterPortForwardRule:
+		return "ClusterPortForwardRule"
+	default:
+		return "PortForwardRule"
+	}
+}
@@ -0,0 +1,176 @@
+package uninstall
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/manifests"
+	"unifi-port-forward/pkg/webhook"
+	"unifi-port-forward/testutils"
+
+	"github.com/filipowm/go-unifi/unifi"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestUninstaller(t *testing.T) (*Uninstaller, *testutils.FakeKubernetesClient, *testutils.MockRouter) {
+	t.Helper()
+	helpers.ClearPortConflictTracking()
+	t.Cleanup(helpers.ClearPortConflictTracking)
+
+	scheme, err := manifests.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubeClient := testutils.NewFakeKubernetesClient(t, scheme)
+
+	web := testutils.CreateTestMultiPortService("web", "default",
+		[]testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}, "192.168.1.100", "8080:http")
+	web.Finalizers = []string{config.FinalizerLabel}
+	web.Annotations[config.CleanupStatusAnnotation] = "failed"
+	kubeClient.Services["default/web"] = web
+
+	plain := testutils.CreateTestMultiPortService("plain", "default",
+		[]testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}, "192.168.1.101", "")
+	kubeClient.Services["default/plain"] = plain
+
+	kubeClient.Rules["apps/game"] = &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "game", Namespace: "apps", Finalizers: []string{config.FinalizerLabel}},
+	}
+	kubeClient.ClusterRules["edge"] = &v1alpha1.ClusterPortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "edge",
+			Annotations: map[string]string{config.CleanupAttemptsAnnotation: "3"},
+		},
+	}
+
+	kubeClient.CRDs[config.PortForwardRulesCRDName] = &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: config.PortForwardRulesCRDName},
+		Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Group: v1alpha1.SchemeGroupVersion.Group},
+	}
+	kubeClient.CRDs["widgets.example.com"] = &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+		Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Group: "example.com"},
+	}
+
+	kubeClient.WebhookConfigurations[webhook.ValidatingWebhookConfigurationName] = &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: webhook.ValidatingWebhookConfigurationName},
+	}
+	kubeClient.MutatingWebhookConfigurations[webhook.MutatingWebhookConfigurationName] = &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: webhook.MutatingWebhookConfigurationName},
+	}
+
+	router := testutils.NewMockRouter()
+	for _, rule := range []unifi.PortForward{
+		{ID: "1", Name: "default/web:http", DstPort: "8080", FwdPort: "80", Fwd: "192.168.1.100", Proto: "tcp", Enabled: true},
+		{ID: "2", Name: "default/gone:http", DstPort: "9000", FwdPort: "9000", Fwd: "192.168.1.104", Proto: "tcp", Enabled: true},
+		{ID: "3", Name: "Printer", DstPort: "631", FwdPort: "631", Fwd: "192.168.1.25", Proto: "tcp", Enabled: true},
+	} {
+		router.AddPortForwardRule(rule)
+	}
+
+	return &Uninstaller{Client: kubeClient, Router: router, Config: &config.Config{}}, kubeClient, router
+}
+
+type row struct {
+	kind, name string
+	action     Action
+}
+
+func rows(results []Result) []row {
+	var got []row
+	for _, result := range results {
+		got = append(got, row{result.Kind, result.Name, result.Action})
+	}
+	return got
+}
+
+func TestRun(t *testing.T) {
+	uninstaller, kubeClient, router := newTestUninstaller(t)
+
+	results, err := uninstaller.Run(context.Background(), Options{DeleteCRDs: true})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	expected := []row{
+		{KindRouterRule, "default/web:http", ActionDelete},
+		{KindRouterRule, "default/gone:http", ActionDelete},
+		{KindWebhook, webhook.ValidatingWebhookConfigurationName, ActionDelete},
+		{KindMutatingWebhook, webhook.MutatingWebhookConfigurationName, ActionDelete},
+		{"Service", "default/web", ActionRelease},
+		{"PortForwardRule", "apps/game", ActionRelease},
+		{"ClusterPortForwardRule", "edge", ActionRelease},
+		{KindCRD, config.PortForwardRulesCRDName, ActionDelete},
+	}
+	if got := rows(results); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Run() = %v, want %v", got, expected)
+	}
+
+	if remaining := router.GetPortForwardRules(); len(remaining) != 1 || remaining[0].Name != "Printer" {
+		t.Errorf("remaining router rules = %+v, want only the manual rule", remaining)
+	}
+	web := kubeClient.Services["default/web"]
+	if len(web.Finalizers) != 0 || web.Annotations[config.CleanupStatusAnnotation] != "" {
+		t.Errorf("service still held: finalizers %v, annotations %v", web.Finalizers, web.Annotations)
+	}
+	if web.Annotations[config.FilterAnnotation] == "" {
+		t.Error("release removed the mapping annotation")
+	}
+	if len(kubeClient.Rules["apps/game"].Finalizers) != 0 {
+		t.Error("port forward rule still has the finalizer")
+	}
+	if _, held := kubeClient.ClusterRules["edge"].Annotations[config.CleanupAttemptsAnnotation]; held {
+		t.Error("cluster rule still has the cleanup attempts annotation")
+	}
+	if len(kubeClient.WebhookConfigurations) != 0 || len(kubeClient.MutatingWebhookConfigurations) != 0 {
+		t.Error("webhook configurations not deleted")
+	}
+	if _, exists := kubeClient.CRDs[config.PortForwardRulesCRDName]; exists {
+		t.Error("port forward rule CRD not deleted")
+	}
+	if _, exists := kubeClient.CRDs["widgets.example.com"]; !exists {
+		t.Error("CRD of another group deleted")
+	}
+}
+
+func TestRun_DryRun(t *testing.T) {
+	uninstaller, kubeClient, router := newTestUninstaller(t)
+
+	results, err := uninstaller.Run(context.Background(), Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 7 {
+		t.Errorf("Run() = %v, want 2 router rules, the 2 webhooks and 3 releases without CRDs", rows(results))
+	}
+	if router.GetCallCount("DeletePortForwardByID") != 0 {
+		t.Error("dry run deleted router rules")
+	}
+	if len(kubeClient.Services["default/web"].Finalizers) != 1 || len(kubeClient.CRDs) != 2 ||
+		len(kubeClient.WebhookConfigurations) != 1 || len(kubeClient.MutatingWebhookConfigurations) != 1 {
+		t.Error("dry run changed the cluster")
+	}
+}
+
+func TestRun_RouterFailure(t *testing.T) {
+	uninstaller, kubeClient, router := newTestUninstaller(t)
+	router.SetSimulatedFailure("DeletePortForwardByID", true)
+
+	results, err := uninstaller.Run(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if results[0].Action != ActionFail || results[0].Reason == "" {
+		t.Errorf("Run() = %+v, want failed router rules with a reason", results[0])
+	}
+	if len(kubeClient.Services["default/web"].Finalizers) != 0 {
+		t.Error("failed router rules stopped the release")
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/routers"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ServicePolicyRequest returns the port forwards of an annotated service that are checked
+// against PortForwardPolicies. Services cannot restrict their source.
+func ServicePolicyRequest(service *v1.Service, configs []routers.PortConfig) v1alpha1.PolicyRequest {
+	request := v1alpha1.PolicyRequest{
+		Kind:      v1alpha1.PolicyKindService,
+		Namespace: service.Namespace,
+		Name:      service.Name,
+		CreatedAt: service.CreationTimestamp.Time,
+	}
+	for _, config := range configs {
+		request.Ports = append(request.Ports, v1alpha1.PolicyPort{ExternalPort: config.DstPort, Protocol: config.Protocol})
+	}
+	return request
+}
+
+// CheckPortForwardPolicies returns the violations of request against every PortForwardPolicy
+// selecting its namespace, each prefixed with the policy name. The maxRules limit of a policy
+// counts the forwards of request together with those of the PortForwardRules and annotated
+// Services created before it in the namespace. Without the PortForwardPolicy CRD nothing is
+// violated.
+func CheckPortForwardPolicies(ctx context.Context, c client.Client, request v1alpha1.PolicyRequest, annotationKey string) ([]string, error) {
+	// Without a client no policies can be checked
+	if c == nil {
+		return nil, nil
+	}
+
+	var policies v1alpha1.PortForwardPolicyList
+	if err := c.List(ctx, &policies); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list port forward policies: %w", err)
+	}
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+
+	namespace := &v1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: request.Namespace}, namespace); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", request.Namespace, err)
+	}
+
+	var violations []string
+	existing := -1
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		selected, err := policy.Selects(namespace.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			continue
+		}
+
+		for _, violation := range policy.Check(request) {
+			violations = append(violations, fmt.Sprintf("PortForwardPolicy %s: %s", policy.Name, violation))
+		}
+
+		if policy.Spec.MaxRules == nil || len(request.Ports) == 0 {
+			continue
+		}
+		if existing < 0 {
+			if existing, err = countNamespaceForwards(ctx, c, request, annotationKey); err != nil {
+				return nil, err
+			}
+		}
+		if total := existing + len(request.Ports); total > int(*policy.Spec.MaxRules) {
+			violations = append(violations, fmt.Sprintf("PortForwardPolicy %s: namespace %s would have %d port forwards, more than the %d allowed",
+				policy.Name, request.Namespace, total, *policy.Spec.MaxRules))
+		}
+	}
+	return violations, nil
+}
+
+// countNamespaceForwards counts the forwarded ports of the PortForwardRules and annotated Services
+// in the namespace of request that were created before the resource making the request
+func countNamespaceForwards(ctx context.Context, c client.Client, request v1alpha1.PolicyRequest, annotationKey string) (int, error) {
+	count := 0
+
+	var rules v1alpha1.PortForwardRuleList
+	if err := c.List(ctx, &rules, client.InNamespace(request.Namespace)); err != nil && !meta.IsNoMatchError(err) {
+		return 0, fmt.Errorf("failed to list port forward rules in namespace %s: %w", request.Namespace, err)
+	}
+	for i := range rules.Items {
+		rule := &rules.Items[i]
+		if !request.CreatedBefore(v1alpha1.PolicyKindPortForwardRule, rule.Name, rule.CreationTimestamp.Time) ||
+			(request.Kind == v1alpha1.PolicyKindPortForwardRule && rule.Name == request.Name) {
+			continue
+		}
+		count += len(rule.Spec.EffectivePorts())
+	}
+
+	var services v1.ServiceList
+	if err := c.List(ctx, &services, client.InNamespace(request.Namespace)); err != nil {
+		return 0, fmt.Errorf("failed to list services in namespace %s: %w", request.Namespace, err)
+	}
+	for i := range services.Items {
+		service := &services.Items[i]
+		if !request.CreatedBefore(v1alpha1.PolicyKindService, service.Name, service.CreationTimestamp.Time) ||
+			(request.Kind == v1alpha1.PolicyKindService && service.Name == request.Name) {
+			continue
+		}
+		if _, exists := service.Annotations[annotationKey]; !exists {
+			continue
+		}
+		configs, err := BuildPortConfigs(service, "", annotationKey)
+		if err != nil {
+			// Invalid annotations forward nothing
+			continue
+		}
+		count += len(configs)
+	}
+
+	return count, nil
+}
@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"unifi-port-forward/pkg/routers"
@@ -87,6 +90,16 @@ func parseSingleMapping(mapping string) (PortMapping, error) {
 	}
 }
 
+// ValidatePortMappings checks that a mapping annotation value parses and only references ports
+// of the service, without consulting port conflict tracking
+func ValidatePortMappings(service *v1.Service, annotation string) error {
+	mappings, err := parsePortMappingAnnotation(annotation)
+	if err != nil {
+		return err
+	}
+	return validatePortMappings(service, mappings)
+}
+
 // validatePortMappings validates that all mapped port names exist in service and no conflicts
 func validatePortMappings(service *v1.Service, mappings []PortMapping) error {
 	// Check that all mapped port names exist in service
@@ -157,6 +170,27 @@ func GetServicePortByName(service *v1.Service, portName string) *v1.ServicePort
 func GetPortConfigs(service *v1.Service, lbIP, annotationKey string) ([]routers.PortConfig, error) {
 	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
 
+	configs, err := BuildPortConfigs(service, lbIP, annotationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		// Check for port conflicts with other services
+		if err := CheckPortConflict(config.DstPort, serviceKey); err != nil {
+			return nil, err
+		}
+
+		// Mark this port as used by this service
+		markPortUsed(config.DstPort, serviceKey)
+	}
+
+	return configs, nil
+}
+
+// BuildPortConfigs creates the PortConfigs requested by a service's mapping annotation without
+// consulting or updating port conflict tracking
+func BuildPortConfigs(service *v1.Service, lbIP, annotationKey string) ([]routers.PortConfig, error) {
 	// Parse annotation
 	annotation := service.Annotations[annotationKey]
 	if annotation == "" {
@@ -198,14 +232,6 @@ func GetPortConfigs(service *v1.Service, lbIP, annotationKey string) ([]routers.
 			continue
 		}
 
-		// Check for port conflicts with other services
-		if err := CheckPortConflict(externalPort, serviceKey); err != nil {
-			return nil, err
-		}
-
-		// Mark this port as used by this service
-		markPortUsed(externalPort, serviceKey)
-
 		protocol := strings.ToLower(string(servicePort.Protocol))
 
 		configs = append(configs, routers.PortConfig{
@@ -222,3 +248,92 @@ func GetPortConfigs(service *v1.Service, lbIP, annotationKey string) ([]routers.
 
 	return configs, nil
 }
+
+// GetRuleIDs returns the router rule IDs recorded on a service, keyed by rule name
+func GetRuleIDs(service *v1.Service, annotationKey string) map[string]string {
+	ruleIDs := make(map[string]string)
+	value, exists := service.Annotations[annotationKey]
+	if !exists || value == "" {
+		return ruleIDs
+	}
+
+	if err := json.Unmarshal([]byte(value), &ruleIDs); err != nil {
+		// A malformed annotation is treated as empty; it is rewritten on the next successful sync
+		return make(map[string]string)
+	}
+	return ruleIDs
+}
+
+// FormatRuleIDs encodes router rule IDs keyed by rule name for storage in an annotation
+func FormatRuleIDs(ruleIDs map[string]string) string {
+	// json.Marshal sorts map keys, so the value is stable across reconciliations
+	data, err := json.Marshal(ruleIDs)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// GetDisabledPorts returns the external ports listed in a service's disabled ports annotation,
+// a comma-separated list such as "8080,8443"
+func GetDisabledPorts(service *v1.Service, annotationKey string) (map[int]bool, error) {
+	ports := make(map[int]bool)
+	value := strings.TrimSpace(service.Annotations[annotationKey])
+	if value == "" {
+		return ports, nil
+	}
+
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		port, err := strconv.Atoi(field)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("annotation %s must list external ports such as 8080,8443, got %q", annotationKey, value)
+		}
+		ports[port] = true
+	}
+	return ports, nil
+}
+
+// FormatDisabledPorts encodes external ports for the disabled ports annotation, in ascending order
+func FormatDisabledPorts(ports map[int]bool) string {
+	sorted := make([]int, 0, len(ports))
+	for port, disabled := range ports {
+		if disabled {
+			sorted = append(sorted, port)
+		}
+	}
+	sort.Ints(sorted)
+
+	fields := make([]string, len(sorted))
+	for i, port := range sorted {
+		fields[i] = strconv.Itoa(port)
+	}
+	return strings.Join(fields, ",")
+}
+
+// GetExpiry returns when the port forwards of a service expire according to its expiry
+// annotations: an RFC 3339 time under expiresAtKey, or a duration after the creation of the
+// service under ttlKey. It returns the zero time if the service has neither.
+func GetExpiry(service *v1.Service, expiresAtKey, ttlKey string) (time.Time, error) {
+	expiresAtValue, hasExpiresAt := service.Annotations[expiresAtKey]
+	ttlValue, hasTTL := service.Annotations[ttlKey]
+
+	switch {
+	case hasExpiresAt && hasTTL:
+		return time.Time{}, fmt.Errorf("annotations %s and %s are mutually exclusive", expiresAtKey, ttlKey)
+	case hasExpiresAt:
+		expiresAt, err := time.Parse(time.RFC3339, expiresAtValue)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("annotation %s must be an RFC 3339 time such as 2026-01-02T18:00:00Z, got %q", expiresAtKey, expiresAtValue)
+		}
+		return expiresAt, nil
+	case hasTTL:
+		ttl, err := time.ParseDuration(ttlValue)
+		if err != nil || ttl <= 0 {
+			return time.Time{}, fmt.Errorf("annotation %s must be a positive duration such as 8h, got %q", ttlKey, ttlValue)
+		}
+		return service.CreationTimestamp.Add(ttl), nil
+	default:
+		return time.Time{}, nil
+	}
+}
@@ -93,8 +93,27 @@ func createUncachedClient(restConfig *rest.Config, scheme *ctrlruntime.Scheme) (
 
 // IsPortForwardRuleCRDAvailable checks if a PortForwardRule CRD is available for the given service
 func IsPortForwardRuleCRDAvailable(ctx context.Context, restConfig *rest.Config, scheme *ctrlruntime.Scheme) bool {
-	logger := ctrllog.FromContext(ctx).WithValues("function", "IsPortForwardRuleCRDAvailable")
-	crdName := "portforwardrules.unifi-port-forward.fiskhe.st"
+	return IsCRDAvailable(ctx, restConfig, scheme, "portforwardrules.unifi-port-forward.fiskhe.st")
+}
+
+// IsPortForwardReferenceGrantCRDAvailable checks if the PortForwardReferenceGrant CRD is installed
+func IsPortForwardReferenceGrantCRDAvailable(ctx context.Context, restConfig *rest.Config, scheme *ctrlruntime.Scheme) bool {
+	return IsCRDAvailable(ctx, restConfig, scheme, "portforwardreferencegrants.unifi-port-forward.fiskhe.st")
+}
+
+// IsPortForwardPolicyCRDAvailable checks if the PortForwardPolicy CRD is installed
+func IsPortForwardPolicyCRDAvailable(ctx context.Context, restConfig *rest.Config, scheme *ctrlruntime.Scheme) bool {
+	return IsCRDAvailable(ctx, restConfig, scheme, "portforwardpolicies.unifi-port-forward.fiskhe.st")
+}
+
+// IsClusterPortForwardRuleCRDAvailable checks if the ClusterPortForwardRule CRD is installed
+func IsClusterPortForwardRuleCRDAvailable(ctx context.Context, restConfig *rest.Config, scheme *ctrlruntime.Scheme) bool {
+	return IsCRDAvailable(ctx, restConfig, scheme, "clusterportforwardrules.unifi-port-forward.fiskhe.st")
+}
+
+// IsCRDAvailable checks if the named CRD is installed and established
+func IsCRDAvailable(ctx context.Context, restConfig *rest.Config, scheme *ctrlruntime.Scheme, crdName string) bool {
+	logger := ctrllog.FromContext(ctx).WithValues("function", "IsCRDAvailable")
 
 	logger.V(1).Info("Checking CRD availability", "crd_name", crdName)
 
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/approval"
+	"unifi-port-forward/pkg/config"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+//+kubebuilder:webhook:path=/mutate-unifi-port-forward-fiskhe-st-v1alpha1-portforwardrule,mutating=true,failurePolicy=fail,sideEffects=None,groups=unifi-port-forward.fiskhe.st,resources=portforwardrules,verbs=create;update,versions=v1alpha1,name=mportforwardrule-approval.unifi-port-forward.fiskhe.st,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/mutate--v1-service,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=services,verbs=create;update,versions=v1,name=mservice-approval.unifi-port-forward.fiskhe.st,admissionReviewVersions=v1
+
+const (
+	// ApproveVerb is the verb an approver needs on the approval subresource of a PortForwardRule
+	// or Service to set its approved annotation
+	ApproveVerb = "approve"
+
+	// ApprovalSubresource is the virtual subresource approvers are granted the approve verb on
+	ApprovalSubresource = "approval"
+)
+
+var (
+	portForwardRuleResource = v1alpha1.SchemeGroupVersion.WithResource("portforwardrules").GroupResource()
+	serviceResource         = corev1.Resource("services")
+)
+
+// authorizeApproval rejects setting or changing the approved annotation of obj unless the
+// requesting user may approve it, which is checked with a SubjectAccessReview for the approve
+// verb on its approval subresource. Removing an approval is always allowed.
+func authorizeApproval(ctx context.Context, c client.Client, oldObj, obj client.Object, resource schema.GroupResource) error {
+	approval := obj.GetAnnotations()[config.ApprovedAnnotation]
+	if approval == "" || (oldObj != nil && oldObj.GetAnnotations()[config.ApprovedAnnotation] == approval) {
+		return nil
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra))
+	for key, value := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			UID:    req.UserInfo.UID,
+			Groups: req.UserInfo.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   obj.GetNamespace(),
+				Verb:        ApproveVerb,
+				Group:       resource.Group,
+				Resource:    resource.Resource,
+				Subresource: ApprovalSubresource,
+				Name:        obj.GetName(),
+			},
+		},
+	}
+	if err := c.Create(ctx, review); err != nil {
+		return apierrors.NewInternalError(fmt.Errorf("failed to review approval permission: %w", err))
+	}
+
+	if !review.Status.Allowed {
+		return apierrors.NewForbidden(resource, obj.GetName(),
+			fmt.Errorf("setting the %s annotation requires the %s verb on %s/%s",
+				config.ApprovedAnnotation, ApproveVerb, resource.Resource, ApprovalSubresource))
+	}
+	return nil
+}
+
+// ApprovalSigner signs the approved annotation of Services and PortForwardRules set by users
+// allowed to approve them, with a key only the controller holds, and rejects approvals by
+// anyone else. The controller only applies approvals carrying a valid signature, so setting the
+// annotation while the webhook is unavailable or bypassed approves nothing. Without a key,
+// approval is not in use and objects are left alone.
+type ApprovalSigner struct {
+	Client client.Client
+	Key    []byte
+}
+
+var _ admission.Handler = &ApprovalSigner{}
+
+// Handle signs a new or changed approved annotation of the object of req
+func (s *ApprovalSigner) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if len(s.Key) == 0 {
+		return admission.Allowed("")
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &obj.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	var oldObj client.Object
+	if len(req.OldObject.Raw) > 0 {
+		old := &unstructured.Unstructured{}
+		if err := json.Unmarshal(req.OldObject.Raw, &old.Object); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		oldObj = old
+	}
+
+	resource := schema.GroupResource{Group: req.Resource.Group, Resource: req.Resource.Resource}
+	if err := authorizeApproval(admission.NewContextWithRequest(ctx, req), s.Client, oldObj, obj, resource); err != nil {
+		var status apierrors.APIStatus
+		if errors.As(err, &status) {
+			return admission.Errored(status.Status().Code, err)
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	annotations := obj.GetAnnotations()
+	value := annotations[config.ApprovedAnnotation]
+	if value == "" || (oldObj != nil && oldObj.GetAnnotations()[config.ApprovedAnnotation] == value) {
+		return admission.Allowed("")
+	}
+
+	signed := approval.Sign(s.Key, req.Kind.Kind, obj.GetNamespace(), obj.GetName(), approval.Hash(value))
+	if signed == value {
+		return admission.Allowed("")
+	}
+	annotations[config.ApprovedAnnotation] = signed
+	obj.SetAnnotations(annotations)
+	mutated, err := json.Marshal(obj.Object)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// CheckApprovalWebhooks returns an error unless the approval signing webhook configuration
+// exists and fails closed, so approvals cannot be set while the webhook is down
+func CheckApprovalWebhooks(ctx context.Context, c client.Reader) error {
+	var mutating admissionregistrationv1.MutatingWebhookConfiguration
+	if err := c.Get(ctx, types.NamespacedName{Name: MutatingWebhookConfigurationName}, &mutating); err != nil {
+		return fmt.Errorf("approval requires the MutatingWebhookConfiguration %s from manifests/webhook/approval: %w",
+			MutatingWebhookConfigurationName, err)
+	}
+	for _, hook := range mutating.Webhooks {
+		if err := checkFailurePolicy(MutatingWebhookConfigurationName, hook.Name, hook.FailurePolicy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFailurePolicy returns an error unless a webhook fails closed, which it does by default
+func checkFailurePolicy(configuration, name string, policy *admissionregistrationv1.FailurePolicyType) error {
+	if policy != nil && *policy != admissionregistrationv1.Fail {
+		return fmt.Errorf("approval requires webhook %s of %s to have failurePolicy Fail, not %s", name, configuration, *policy)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"unifi-port-forward/pkg/approval"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/testutils"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func requestContext(username string) context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: username}},
+	})
+}
+
+func TestAuthorizeApproval(t *testing.T) {
+	fakeClient := newTestClient(t)
+	var reviewed authorizationv1.SubjectAccessReviewSpec
+	fakeClient.Authorize = func(spec authorizationv1.SubjectAccessReviewSpec) bool {
+		reviewed = spec
+		return spec.User == "approver"
+	}
+	ruleValidator := &PortForwardRuleValidator{Client: fakeClient}
+	serviceValidator := &ServiceValidator{Client: fakeClient}
+
+	rule := newTestRule("web", "default", 8080, "warn")
+	rule.Annotations = map[string]string{config.ApprovedAnnotation: "0123456789abcdef"}
+
+	if _, err := ruleValidator.ValidateCreate(requestContext("developer"), rule); !apierrors.IsForbidden(err) {
+		t.Errorf("Expected approval by a developer to be forbidden, got %v", err)
+	}
+	if _, err := ruleValidator.ValidateCreate(requestContext("approver"), rule); err != nil {
+		t.Errorf("Expected approval by an approver to be allowed, got %v", err)
+	}
+	attributes := reviewed.ResourceAttributes
+	if attributes == nil || attributes.Verb != ApproveVerb || attributes.Resource != "portforwardrules" ||
+		attributes.Subresource != ApprovalSubresource || attributes.Namespace != "default" || attributes.Name != "web" {
+		t.Errorf("Unexpected access review %+v", attributes)
+	}
+
+	// Updates that keep the approval need no access review
+	updated := rule.DeepCopy()
+	updated.Spec.Description = "web server"
+	if _, err := ruleValidator.ValidateUpdate(requestContext("developer"), rule, updated); err != nil {
+		t.Errorf("Expected update keeping the approval to be allowed, got %v", err)
+	}
+
+	// Anyone may revoke an approval
+	revoked := rule.DeepCopy()
+	delete(revoked.Annotations, config.ApprovedAnnotation)
+	if _, err := ruleValidator.ValidateUpdate(requestContext("developer"), rule, revoked); err != nil {
+		t.Errorf("Expected revoking the approval to be allowed, got %v", err)
+	}
+
+	ports := []testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}
+	oldService := testutils.CreateTestMultiPortService("web", "default", ports, "192.168.1.42", "8081:http")
+	service := oldService.DeepCopy()
+	service.Annotations[config.ApprovedAnnotation] = "0123456789abcdef"
+
+	if _, err := serviceValidator.ValidateUpdate(requestContext("developer"), oldService, service); !apierrors.IsForbidden(err) {
+		t.Errorf("Expected service approval by a developer to be forbidden, got %v", err)
+	}
+	if _, err := serviceValidator.ValidateUpdate(requestContext("approver"), oldService, service); err != nil {
+		t.Errorf("Expected service approval by an approver to be allowed, got %v", err)
+	}
+	if reviewed.ResourceAttributes == nil || reviewed.ResourceAttributes.Resource != "services" || reviewed.ResourceAttributes.Group != "" {
+		t.Errorf("Unexpected access review %+v", reviewed.ResourceAttributes)
+	}
+}
+
+func TestApprovalSigner(t *testing.T) {
+	fakeClient := newTestClient(t)
+	fakeClient.Authorize = func(spec authorizationv1.SubjectAccessReviewSpec) bool {
+		return spec.User == "approver"
+	}
+	key := []byte("0123456789abcdef0123456789abcdef")
+	signer := &ApprovalSigner{Client: fakeClient, Key: key}
+
+	ports := []testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}
+	oldService := testutils.CreateTestMultiPortService("web", "default", ports, "192.168.1.42", "8081:http")
+	approved := oldService.DeepCopy()
+	approved.Annotations[config.ApprovedAnnotation] = "0123456789abcdef"
+
+	request := func(username string, oldObj, obj runtime.Object) admission.Request {
+		t.Helper()
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldRaw, err := json.Marshal(oldObj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Service"},
+			Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "services"},
+			Namespace: "default",
+			Name:      "web",
+			Operation: admissionv1.Update,
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		}}
+	}
+
+	response := signer.Handle(context.Background(), request("developer", oldService, approved))
+	if response.Allowed || response.Result.Code != http.StatusForbidden {
+		t.Errorf("Expected approval by a developer to be forbidden, got %+v", response.Result)
+	}
+
+	response = signer.Handle(context.Background(), request("approver", oldService, approved))
+	if !response.Allowed || len(response.Patches) != 1 {
+		t.Fatalf("Expected approval by an approver to be signed, got %+v with patches %+v", response.Result, response.Patches)
+	}
+	signed, _ := response.Patches[0].Value.(string)
+	if !approval.Verify(key, "Service", "default", "web", "0123456789abcdef", signed) {
+		t.Errorf("Expected a signed approval, got patch %+v", response.Patches[0])
+	}
+
+	// Updates keeping a signed approval are left alone
+	signedService := approved.DeepCopy()
+	signedService.Annotations[config.ApprovedAnnotation] = signed
+	updated := signedService.DeepCopy()
+	updated.Spec.Ports[0].Port = 81
+	if response := signer.Handle(context.Background(), request("developer", signedService, updated)); !response.Allowed || len(response.Patches) != 0 {
+		t.Errorf("Expected an update keeping the approval to be allowed unchanged, got %+v with patches %+v", response.Result, response.Patches)
+	}
+
+	// A signature copied from another object is signed again, for this one, only for approvers
+	copied := oldService.DeepCopy()
+	copied.Annotations[config.ApprovedAnnotation] = approval.Sign(key, "Service", "default", "other", "0123456789abcdef")
+	if response := signer.Handle(context.Background(), request("developer", oldService, copied)); response.Allowed {
+		t.Error("Expected a copied approval set by a developer to be forbidden")
+	}
+
+	unsigned := &ApprovalSigner{Client: fakeClient}
+	if response := unsigned.Handle(context.Background(), request("developer", oldService, approved)); !response.Allowed || len(response.Patches) != 0 {
+		t.Errorf("Expected objects to be left alone without a signing key, got %+v", response.Result)
+	}
+}
+
+func TestCheckApprovalWebhooks(t *testing.T) {
+	fakeClient := newTestClient(t)
+	if err := CheckApprovalWebhooks(context.Background(), fakeClient); err == nil {
+		t.Error("Expected an error without webhook configurations")
+	}
+
+	// The Service validating webhook fails open, it does not take part in approval
+	ignore := admissionregistrationv1.Ignore
+	fail := admissionregistrationv1.Fail
+	fakeClient.WebhookConfigurations[ValidatingWebhookConfigurationName] = &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ValidatingWebhookConfigurationName},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "vservice.unifi-port-forward.fiskhe.st", FailurePolicy: &ignore},
+		},
+	}
+	fakeClient.MutatingWebhookConfigurations[MutatingWebhookConfigurationName] = &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: MutatingWebhookConfigurationName},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "mportforwardrule-approval.unifi-port-forward.fiskhe.st", FailurePolicy: &fail},
+			{Name: "mservice-approval.unifi-port-forward.fiskhe.st", FailurePolicy: &ignore},
+		},
+	}
+	if err := CheckApprovalWebhooks(context.Background(), fakeClient); err == nil {
+		t.Error("Expected an error for an approval webhook failing open")
+	}
+
+	fakeClient.MutatingWebhookConfigurations[MutatingWebhookConfigurationName].Webhooks[1].FailurePolicy = nil
+	if err := CheckApprovalWebhooks(context.Background(), fakeClient); err != nil {
+		t.Errorf("Expected approval webhooks failing closed to pass, got %v", err)
+	}
+
+	delete(fakeClient.MutatingWebhookConfigurations, MutatingWebhookConfigurationName)
+	if err := CheckApprovalWebhooks(context.Background(), fakeClient); err == nil {
+		t.Error("Expected an error without the approval signing webhook")
+	}
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// File names the webhook server loads its serving certificate from
+const (
+	CertName = "tls.crt"
+	KeyName  = "tls.key"
+	CAName   = "ca.crt"
+)
+
+// certificateValidity is how long bootstrapped certificates are valid for. They are
+// regenerated whenever the controller starts with an empty certificate directory.
+const certificateValidity = 10 * 365 * 24 * time.Hour
+
+// EnsureServingCertificate makes sure certDir holds a serving certificate for the webhook
+// Service. Existing certificates, such as ones issued by cert-manager, are left untouched and
+// nil is returned. Otherwise a self-signed CA and a serving certificate are generated and the
+// PEM encoded CA is returned so it can be injected into the webhook configuration.
+func EnsureServingCertificate(certDir, serviceName, namespace string) ([]byte, error) {
+	certPath := filepath.Join(certDir, CertName)
+	keyPath := filepath.Join(certDir, KeyName)
+
+	if fileExists(certPath) && fileExists(keyPath) {
+		return nil, nil
+	}
+
+	caPEM, certPEM, keyPEM, err := generateServingCertificate(serviceName, namespace, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(certDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create certificate directory %s: %w", certDir, err)
+	}
+	files := map[string][]byte{
+		CAName:   caPEM,
+		CertName: certPEM,
+		KeyName:  keyPEM,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(certDir, name), data, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	return caPEM, nil
+}
+
+// ServingKeyPair returns the key pair in certDir, or a self-signed one for the in-cluster DNS
+// names of the named Service when certDir is empty or holds none. A self-signed key pair only
+// lives in memory and is trusted by nobody, which suits callers such as the API server service
+// proxy that do not verify the certificate.
+func ServingKeyPair(certDir, serviceName, namespace string) (tls.Certificate, error) {
+	certPath := filepath.Join(certDir, CertName)
+	keyPath := filepath.Join(certDir, KeyName)
+	if certDir != "" && fileExists(certPath) && fileExists(keyPath) {
+		keyPair, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to load serving certificate from %s: %w", certDir, err)
+		}
+		return keyPair, nil
+	}
+
+	_, certPEM, keyPEM, err := generateServingCertificate(serviceName, namespace, time.Now())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load generated serving certificate: %w", err)
+	}
+	return keyPair, nil
+}
+
+// InjectCABundle sets the caBundle of every webhook in the named ValidatingWebhookConfiguration.
+// A missing configuration is left alone, since validation is optional while conversion is not.
+func InjectCABundle(ctx context.Context, c client.Client, name string, caBundle []byte) error {
+	var webhookConfig admissionregistrationv1.ValidatingWebhookConfiguration
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, &webhookConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get ValidatingWebhookConfiguration %s: %w", name, err)
+	}
+
+	changed := false
+	for i := range webhookConfig.Webhooks {
+		if !bytes.Equal(webhookConfig.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			webhookConfig.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := c.Update(ctx, &webhookConfig); err != nil {
+		return fmt.Errorf("failed to update caBundle of ValidatingWebhookConfiguration %s: %w", name, err)
+	}
+	return nil
+}
+
+// InjectMutatingCABundle sets the caBundle of every webhook in the named
+// MutatingWebhookConfiguration. A missing configuration is left alone, as it is only needed
+// when approval is required.
+func InjectMutatingCABundle(ctx context.Context, c client.Client, name string, caBundle []byte) error {
+	var webhookConfig admissionregistrationv1.MutatingWebhookConfiguration
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, &webhookConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get MutatingWebhookConfiguration %s: %w", name, err)
+	}
+
+	changed := false
+	for i := range webhookConfig.Webhooks {
+		if !bytes.Equal(webhookConfig.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			webhookConfig.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := c.Update(ctx, &webhookConfig); err != nil {
+		return fmt.Errorf("failed to update caBundle of MutatingWebhookConfiguration %s: %w", name, err)
+	}
+	return nil
+}
+
+// InjectConversionCABundle sets the caBundle of the conversion webhook of the named CRD. CRDs
+// that are not installed or do not use webhook conversion are left alone.
+func InjectConversionCABundle(ctx context.Context, c client.Client, crdName string, caBundle []byte) error {
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := c.Get(ctx, types.NamespacedName{Name: crdName}, &crd); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get CustomResourceDefinition %s: %w", crdName, err)
+	}
+
+	crdConversion := crd.Spec.Conversion
+	if crdConversion == nil || crdConversion.Strategy != apiextensionsv1.WebhookConverter ||
+		crdConversion.Webhook == nil || crdConversion.Webhook.ClientConfig == nil {
+		return nil
+	}
+	if bytes.Equal(crdConversion.Webhook.ClientConfig.CABundle, caBundle) {
+		return nil
+	}
+
+	crdConversion.Webhook.ClientConfig.CABundle = caBundle
+	if err := c.Update(ctx, &crd); err != nil {
+		return fmt.Errorf("failed to update conversion caBundle of CustomResourceDefinition %s: %w", crdName, err)
+	}
+	return nil
+}
+
+// generateServingCertificate creates a CA and a serving certificate for the in-cluster DNS
+// names of the webhook Service, returning the PEM encoded CA, certificate and key
+func generateServingCertificate(serviceName, namespace string, now time.Time) ([]byte, []byte, []byte, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "unifi-port-forward-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certificateValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	servingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to generate serving key: %w", err)
+	}
+	dnsNames := []string{
+		serviceName,
+		fmt.Sprintf("%s.%s", serviceName, namespace),
+		fmt.Sprintf("%s.%s.svc", serviceName, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, namespace),
+	}
+	servingTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: dnsNames[2]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	servingDER, err := x509.CreateCertificate(rand.Reader, servingTemplate, caCert, &servingKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create serving certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(servingKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to marshal serving key: %w", err)
+	}
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: servingDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return caPEM, certPEM, keyPEM, nil
+}
+
+// fileExists reports whether path exists and is a regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureServingCertificate(t *testing.T) {
+	certDir := filepath.Join(t.TempDir(), "certs")
+
+	caBundle, err := EnsureServingCertificate(certDir, "unifi-port-forward-webhook", "unifi-port-forward")
+	if err != nil {
+		t.Fatalf("Expected certificate to be generated, got %v", err)
+	}
+	if caBundle == nil {
+		t.Fatal("Expected a CA bundle for a generated certificate")
+	}
+
+	pair, err := tls.LoadX509KeyPair(filepath.Join(certDir, CertName), filepath.Join(certDir, KeyName))
+	if err != nil {
+		t.Fatalf("Expected a loadable key pair, got %v", err)
+	}
+	servingCert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse serving certificate: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBundle) {
+		t.Fatal("Expected CA bundle to contain a certificate")
+	}
+	if _, err := servingCert.Verify(x509.VerifyOptions{
+		DNSName: "unifi-port-forward-webhook.unifi-port-forward.svc",
+		Roots:   roots,
+	}); err != nil {
+		t.Errorf("Expected serving certificate to be valid for the webhook service, got %v", err)
+	}
+
+	// Existing certificates, e.g. from cert-manager, are kept
+	before, err := os.ReadFile(filepath.Join(certDir, CertName))
+	if err != nil {
+		t.Fatalf("Failed to read certificate: %v", err)
+	}
+	caBundle, err = EnsureServingCertificate(certDir, "unifi-port-forward-webhook", "unifi-port-forward")
+	if err != nil || caBundle != nil {
+		t.Fatalf("Expected existing certificate to be kept, got caBundle=%v err=%v", caBundle != nil, err)
+	}
+	after, err := os.ReadFile(filepath.Join(certDir, CertName))
+	if err != nil {
+		t.Fatalf("Failed to read certificate: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Error("Expected existing certificate to be left untouched")
+	}
+}
+
+func TestServingKeyPair(t *testing.T) {
+	generated, err := ServingKeyPair("", "unifi-port-forward-state", "unifi-port-forward")
+	if err != nil {
+		t.Fatalf("Expected a generated key pair, got %v", err)
+	}
+	servingCert, err := x509.ParseCertificate(generated.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse serving certificate: %v", err)
+	}
+	if err := servingCert.VerifyHostname("unifi-port-forward-state.unifi-port-forward.svc"); err != nil {
+		t.Errorf("Expected generated certificate to be valid for the service, got %v", err)
+	}
+
+	// A mounted key pair is used as is
+	certDir := t.TempDir()
+	if _, err := EnsureServingCertificate(certDir, "mounted", "unifi-port-forward"); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	mounted, err := ServingKeyPair(certDir, "unifi-port-forward-state", "unifi-port-forward")
+	if err != nil {
+		t.Fatalf("Expected the mounted key pair, got %v", err)
+	}
+	servingCert, err = x509.ParseCertificate(mounted.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse serving certificate: %v", err)
+	}
+	if err := servingCert.VerifyHostname("mounted.unifi-port-forward.svc"); err != nil {
+		t.Errorf("Expected the mounted certificate, got one for %v", servingCert.DNSNames)
+	}
+}
@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"context"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// validatePolicies rejects the port forwards of request when they violate a PortForwardPolicy
+// selecting its namespace. Each violation is reported as forbidden at path.
+func validatePolicies(ctx context.Context, c client.Client, request v1alpha1.PolicyRequest, kind schema.GroupKind, path *field.Path) error {
+	violations, err := helpers.CheckPortForwardPolicies(ctx, c, request, config.FilterAnnotation)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	for _, violation := range violations {
+		allErrs = append(allErrs, field.Forbidden(path, violation))
+	}
+	return apierrors.NewInvalid(kind, request.Name, allErrs)
+}
+
+// sourceChanged reports whether the source restriction differs between two rule specs
+func sourceChanged(oldSpec, spec *v1alpha1.PortForwardRuleSpec) bool {
+	var oldSource, source string
+	if oldSpec.SourceIPRestriction != nil {
+		oldSource = *oldSpec.SourceIPRestriction
+	}
+	if spec.SourceIPRestriction != nil {
+		source = *spec.SourceIPRestriction
+	}
+	return oldSource != source
+}
@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/testutils"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidators_RejectPolicyViolations(t *testing.T) {
+	ctx := context.Background()
+	fakeClient := newTestClient(t)
+	fakeClient.Namespaces["games"] = &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "games", Labels: map[string]string{"kubernetes.io/metadata.name": "games"}},
+	}
+	fakeClient.Rules["games/existing"] = newTestRule("existing", "games", 27000, "warn")
+	maxRules := int32(2)
+	fakeClient.Policies["reserved-ports"] = &v1alpha1.PortForwardPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "reserved-ports"},
+		Spec:       v1alpha1.PortForwardPolicySpec{DeniedPorts: []v1alpha1.PortRange{{From: 22}, {From: 443}}},
+	}
+	fakeClient.Policies["games"] = &v1alpha1.PortForwardPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "games"},
+		Spec: v1alpha1.PortForwardPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "games"}},
+			AllowedPorts:      []v1alpha1.PortRange{{From: 27000, To: 27100}},
+			MaxRules:          &maxRules,
+		},
+	}
+	ruleValidator := &PortForwardRuleValidator{Client: fakeClient}
+	serviceValidator := &ServiceValidator{Client: fakeClient}
+
+	ruleTests := []struct {
+		name     string
+		rule     *v1alpha1.PortForwardRule
+		errorMsg string
+	}{
+		{
+			name: "allowed port is accepted",
+			rule: newTestRule("new", "games", 27015, "warn"),
+		},
+		{
+			name:     "denied port is rejected in every namespace",
+			rule:     newTestRule("new", "default", 22, "warn"),
+			errorMsg: "PortForwardPolicy reserved-ports: port 22 is denied",
+		},
+		{
+			name:     "port outside the allowed ports is rejected",
+			rule:     newTestRule("new", "games", 8080, "warn"),
+			errorMsg: "PortForwardPolicy games: port 8080 is outside the allowed ports 27000-27100",
+		},
+		{
+			name:     "exceeding maxRules is rejected",
+			rule:     newMultiPortTestRule("new", "games", 27015, 27016),
+			errorMsg: "namespace games would have 3 port forwards, more than the 2 allowed",
+		},
+		{
+			name: "other namespaces are not limited by the games policy",
+			rule: newTestRule("new", "default", 8080, "warn"),
+		},
+	}
+
+	for _, tt := range ruleTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ruleValidator.ValidateCreate(ctx, tt.rule)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Expected rule to be allowed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+
+	t.Run("service with a denied port is rejected", func(t *testing.T) {
+		service := testutils.CreateTestMultiPortService("web", "default",
+			[]testutils.TestPort{{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP}}, "192.168.1.10", "https")
+		_, err := serviceValidator.ValidateCreate(ctx, service)
+		if err == nil || !strings.Contains(err.Error(), "PortForwardPolicy reserved-ports: port 443 is denied") {
+			t.Errorf("Expected service to be rejected by the reserved-ports policy, got %v", err)
+		}
+	})
+
+	t.Run("update without port or source changes is not checked", func(t *testing.T) {
+		oldRule := newTestRule("legacy", "games", 8080, "warn")
+		rule := oldRule.DeepCopy()
+		rule.Finalizers = []string{"unifi-port-forward.fiskhe.st/router-rule-protection"}
+		if _, err := ruleValidator.ValidateUpdate(ctx, oldRule, rule); err != nil {
+			t.Errorf("Expected finalizer update to be allowed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Kinds of resources that can claim an external port
+const (
+	claimKindService                = "Service"
+	claimKindPortForwardRule        = "PortForwardRule"
+	claimKindClusterPortForwardRule = "ClusterPortForwardRule"
+)
+
+// portClaim is an external port requested by an annotated Service or a port forward rule
+type portClaim struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Port      int
+	Protocol  string
+}
+
+// owner returns a human readable reference to the resource holding the claim
+func (c portClaim) owner() string {
+	if c.Namespace == "" {
+		return fmt.Sprintf("%s %s", c.Kind, c.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", c.Kind, c.Namespace, c.Name)
+}
+
+// isOwnedBy reports whether the claim belongs to the given resource
+func (c portClaim) isOwnedBy(kind, namespace, name string) bool {
+	return c.Kind == kind && c.Namespace == namespace && c.Name == name
+}
+
+// overlaps reports whether the claim forwards any of the same traffic as port/protocol
+func (c portClaim) overlaps(port int, protocol string) bool {
+	return c.Port == port && protocolsOverlap(c.Protocol, protocol)
+}
+
+// listPortClaims collects the external ports claimed by annotated Services, PortForwardRules
+// and ClusterPortForwardRules. Rule kinds whose CRD is not installed claim nothing.
+func listPortClaims(ctx context.Context, c client.Client) ([]portClaim, error) {
+	var claims []portClaim
+
+	var services corev1.ServiceList
+	if err := c.List(ctx, &services); err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	for i := range services.Items {
+		service := &services.Items[i]
+		if _, exists := service.Annotations[config.FilterAnnotation]; !exists {
+			continue
+		}
+		// Invalid mappings are not forwarded, so they claim nothing
+		portConfigs, err := helpers.BuildPortConfigs(service, "", config.FilterAnnotation)
+		if err != nil {
+			continue
+		}
+		for _, portConfig := range portConfigs {
+			claims = append(claims, portClaim{
+				Kind:      claimKindService,
+				Namespace: service.Namespace,
+				Name:      service.Name,
+				Port:      portConfig.DstPort,
+				Protocol:  portConfig.Protocol,
+			})
+		}
+	}
+
+	var rules v1alpha1.PortForwardRuleList
+	if err := c.List(ctx, &rules); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("failed to list PortForwardRules: %w", err)
+	}
+	for _, rule := range rules.Items {
+		for _, port := range rule.Spec.EffectivePorts() {
+			claims = append(claims, portClaim{
+				Kind:      claimKindPortForwardRule,
+				Namespace: rule.Namespace,
+				Name:      rule.Name,
+				Port:      port.ExternalPort,
+				Protocol:  port.Protocol,
+			})
+		}
+	}
+
+	var clusterRules v1alpha1.ClusterPortForwardRuleList
+	if err := c.List(ctx, &clusterRules); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("failed to list ClusterPortForwardRules: %w", err)
+	}
+	for _, rule := range clusterRules.Items {
+		for _, port := range rule.Spec.EffectivePorts() {
+			claims = append(claims, portClaim{
+				Kind:     claimKindClusterPortForwardRule,
+				Name:     rule.Name,
+				Port:     port.ExternalPort,
+				Protocol: port.Protocol,
+			})
+		}
+	}
+
+	return claims, nil
+}
+
+// portsChanged reports whether the external ports or protocols of a rule differ between two specs
+func portsChanged(oldSpec, newSpec *v1alpha1.PortForwardRuleSpec) bool {
+	oldPorts := oldSpec.EffectivePorts()
+	newPorts := newSpec.EffectivePorts()
+	if len(oldPorts) != len(newPorts) {
+		return true
+	}
+	for i := range newPorts {
+		if oldPorts[i].ExternalPort != newPorts[i].ExternalPort || oldPorts[i].Protocol != newPorts[i].Protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// rulePortPath returns the path of the external port of the i-th effective port of a rule
+func rulePortPath(spec *v1alpha1.PortForwardRuleSpec, i int) *field.Path {
+	if len(spec.Ports) > 0 {
+		return field.NewPath("spec").Child("ports").Index(i).Child("externalPort")
+	}
+	return field.NewPath("spec").Child("externalPort")
+}
+
+// protocolsOverlap reports whether two protocols forward any common traffic. Service ports
+// without a protocol default to tcp.
+func protocolsOverlap(a, b string) bool {
+	if a == "" {
+		a = "tcp"
+	}
+	if b == "" {
+		b = "tcp"
+	}
+	return a == b || a == "both" || b == "both"
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-unifi-port-forward-fiskhe-st-v1alpha1-portforwardrule,mutating=false,failurePolicy=fail,sideEffects=None,groups=unifi-port-forward.fiskhe.st,resources=portforwardrules,verbs=create;update,versions=v1alpha1,name=vportforwardrule.unifi-port-forward.fiskhe.st,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-unifi-port-forward-fiskhe-st-v1alpha1-clusterportforwardrule,mutating=false,failurePolicy=fail,sideEffects=None,groups=unifi-port-forward.fiskhe.st,resources=clusterportforwardrules,verbs=create;update,versions=v1alpha1,name=vclusterportforwardrule.unifi-port-forward.fiskhe.st,admissionReviewVersions=v1
+
+var (
+	portForwardRuleKind        = v1alpha1.SchemeGroupVersion.WithKind("PortForwardRule").GroupKind()
+	clusterPortForwardRuleKind = v1alpha1.SchemeGroupVersion.WithKind("ClusterPortForwardRule").GroupKind()
+)
+
+// PortForwardRuleValidator rejects invalid PortForwardRules and rules whose external port is
+// already claimed or that violate a PortForwardPolicy, so the error is reported by kubectl
+// instead of in the rule status. It also rejects approvals by users that may not approve the rule.
+type PortForwardRuleValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &PortForwardRuleValidator{}
+
+// ValidateCreate validates a new PortForwardRule
+func (v *PortForwardRuleValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	rule, ok := obj.(*v1alpha1.PortForwardRule)
+	if !ok {
+		return nil, fmt.Errorf("expected a PortForwardRule but got %T", obj)
+	}
+
+	if err := authorizeApproval(ctx, v.Client, nil, rule, portForwardRuleResource); err != nil {
+		return nil, err
+	}
+	if errs := rule.ValidateCreate(); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(portForwardRuleKind, rule.Name, errs)
+	}
+	if err := validatePolicies(ctx, v.Client, rule.PolicyRequest(), portForwardRuleKind, field.NewPath("spec")); err != nil {
+		return nil, err
+	}
+	return v.validatePortClaims(ctx, rule)
+}
+
+// ValidateUpdate validates a changed PortForwardRule. Port claims and policies are only checked
+// when an external port, protocol or source restriction changes, so the controller can always
+// update finalizers.
+func (v *PortForwardRuleValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	rule, ok := newObj.(*v1alpha1.PortForwardRule)
+	if !ok {
+		return nil, fmt.Errorf("expected a PortForwardRule but got %T", newObj)
+	}
+	oldRule, ok := oldObj.(*v1alpha1.PortForwardRule)
+	if !ok {
+		return nil, fmt.Errorf("expected a PortForwardRule but got %T", oldObj)
+	}
+
+	if !rule.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	if err := authorizeApproval(ctx, v.Client, oldRule, rule, portForwardRuleResource); err != nil {
+		return nil, err
+	}
+
+	if errs := rule.ValidateUpdate(oldRule); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(portForwardRuleKind, rule.Name, errs)
+	}
+	if !portsChanged(&oldRule.Spec, &rule.Spec) && !sourceChanged(&oldRule.Spec, &rule.Spec) {
+		return nil, nil
+	}
+	if err := validatePolicies(ctx, v.Client, rule.PolicyRequest(), portForwardRuleKind, field.NewPath("spec")); err != nil {
+		return nil, err
+	}
+	return v.validatePortClaims(ctx, rule)
+}
+
+// ValidateDelete allows every deletion
+func (v *PortForwardRuleValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validatePortClaims rejects ports reserved by ClusterPortForwardRules or used by another rule in
+// the same namespace. Claims from other namespaces and annotated Services are handled according
+// to the rule's conflictPolicy: error rejects, warn returns warnings and ignore allows.
+func (v *PortForwardRuleValidator) validatePortClaims(ctx context.Context, rule *v1alpha1.PortForwardRule) (admission.Warnings, error) {
+	claims, err := listPortClaims(ctx, v.Client)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+
+	var allErrs field.ErrorList
+	var warnings admission.Warnings
+
+	for i, port := range rule.Spec.EffectivePorts() {
+		portPath := rulePortPath(&rule.Spec, i)
+
+		for _, claim := range claims {
+			if claim.isOwnedBy(claimKindPortForwardRule, rule.Namespace, rule.Name) || !claim.overlaps(port.ExternalPort, port.Protocol) {
+				continue
+			}
+
+			message := fmt.Sprintf("port %d is already claimed by %s", port.ExternalPort, claim.owner())
+			switch {
+			case claim.Kind == claimKindClusterPortForwardRule:
+				allErrs = append(allErrs, field.Forbidden(portPath, fmt.Sprintf("port %d is reserved by %s", port.ExternalPort, claim.owner())))
+			case claim.Kind == claimKindPortForwardRule && claim.Namespace == rule.Namespace:
+				allErrs = append(allErrs, field.Forbidden(portPath, message))
+			case rule.Spec.ConflictPolicy == "error":
+				allErrs = append(allErrs, field.Forbidden(portPath, message))
+			case rule.Spec.ConflictPolicy == "ignore":
+			default:
+				warnings = append(warnings, message)
+			}
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return warnings, apierrors.NewInvalid(portForwardRuleKind, rule.Name, allErrs)
+	}
+	return warnings, nil
+}
+
+// ClusterPortForwardRuleValidator rejects invalid ClusterPortForwardRules and ports already
+// reserved by another cluster rule
+type ClusterPortForwardRuleValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &ClusterPortForwardRuleValidator{}
+
+// ValidateCreate validates a new ClusterPortForwardRule
+func (v *ClusterPortForwardRuleValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	rule, ok := obj.(*v1alpha1.ClusterPortForwardRule)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterPortForwardRule but got %T", obj)
+	}
+	return v.validate(ctx, rule)
+}
+
+// ValidateUpdate validates a changed ClusterPortForwardRule
+func (v *ClusterPortForwardRuleValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	rule, ok := newObj.(*v1alpha1.ClusterPortForwardRule)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterPortForwardRule but got %T", newObj)
+	}
+	oldRule, ok := oldObj.(*v1alpha1.ClusterPortForwardRule)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterPortForwardRule but got %T", oldObj)
+	}
+
+	if !rule.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	if !portsChanged(&oldRule.Spec, &rule.Spec) {
+		if errs := rule.ValidateCreate(); len(errs) > 0 {
+			return nil, apierrors.NewInvalid(clusterPortForwardRuleKind, rule.Name, errs)
+		}
+		return nil, nil
+	}
+	return v.validate(ctx, rule)
+}
+
+// ValidateDelete allows every deletion
+func (v *ClusterPortForwardRuleValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate rejects invalid specs and ports used by other cluster rules. Namespaced claims on the
+// same port are reported as warnings, since the cluster rule takes precedence over them.
+func (v *ClusterPortForwardRuleValidator) validate(ctx context.Context, rule *v1alpha1.ClusterPortForwardRule) (admission.Warnings, error) {
+	allErrs := rule.ValidateCreate()
+	allErrs = append(allErrs, rule.ValidatePortConflict(ctx, v.Client)...)
+	if len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(clusterPortForwardRuleKind, rule.Name, allErrs)
+	}
+
+	claims, err := listPortClaims(ctx, v.Client)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+
+	var warnings admission.Warnings
+	for _, port := range rule.Spec.EffectivePorts() {
+		for _, claim := range claims {
+			if claim.Kind == claimKindClusterPortForwardRule || !claim.overlaps(port.ExternalPort, port.Protocol) {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("port %d is already claimed by %s", port.ExternalPort, claim.owner()))
+		}
+	}
+	return warnings, nil
+}
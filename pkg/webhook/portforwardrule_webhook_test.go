@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+	"unifi-port-forward/testutils"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newTestClient(t *testing.T) *testutils.FakeKubernetesClient {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add corev1 to scheme: %v", err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add v1alpha1 to scheme: %v", err)
+	}
+	return testutils.NewFakeKubernetesClient(t, scheme)
+}
+
+func newTestRule(name, namespace string, port int, policy string) *v1alpha1.PortForwardRule {
+	destinationIP := "192.168.1.100"
+	destinationPort := 80
+	return &v1alpha1.PortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: v1alpha1.PortForwardRuleSpec{
+			ExternalPort:    port,
+			Protocol:        "tcp",
+			DestinationIP:   &destinationIP,
+			DestinationPort: &destinationPort,
+			Enabled:         true,
+			Interface:       "wan",
+			ConflictPolicy:  policy,
+		},
+	}
+}
+
+func newMultiPortTestRule(name, namespace string, ports ...int) *v1alpha1.PortForwardRule {
+	rule := newTestRule(name, namespace, 0, "warn")
+	rule.Spec.Protocol = ""
+	rule.Spec.DestinationPort = nil
+	for _, port := range ports {
+		rule.Spec.Ports = append(rule.Spec.Ports, v1alpha1.PortForwardPort{ExternalPort: port, Protocol: "tcp"})
+	}
+	return rule
+}
+
+func TestPortForwardRuleValidator_ValidateCreate(t *testing.T) {
+	ctx := context.Background()
+	fakeClient := newTestClient(t)
+	fakeClient.Rules["default/existing"] = newTestRule("existing", "default", 8080, "warn")
+	fakeClient.Rules["other/remote"] = newTestRule("remote", "other", 9090, "warn")
+	fakeClient.ClusterRules["ingress"] = &v1alpha1.ClusterPortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress"},
+		Spec:       v1alpha1.PortForwardRuleSpec{ExternalPort: 443, Protocol: "both"},
+	}
+	validator := &PortForwardRuleValidator{Client: fakeClient}
+
+	tests := []struct {
+		name         string
+		rule         *v1alpha1.PortForwardRule
+		errorMsg     string
+		wantWarnings int
+	}{
+		{
+			name: "free port is allowed",
+			rule: newTestRule("new", "default", 7070, "warn"),
+		},
+		{
+			name:     "invalid spec is rejected",
+			rule:     newTestRule("new", "default", 70000, "warn"),
+			errorMsg: "external port must be between 1 and 65535",
+		},
+		{
+			name:     "port of rule in same namespace is rejected",
+			rule:     newTestRule("new", "default", 8080, "ignore"),
+			errorMsg: "already claimed by PortForwardRule default/existing",
+		},
+		{
+			name:     "port reserved by cluster rule is rejected",
+			rule:     newTestRule("new", "default", 443, "ignore"),
+			errorMsg: "reserved by ClusterPortForwardRule ingress",
+		},
+		{
+			name:         "port of rule in other namespace warns by default",
+			rule:         newTestRule("new", "default", 9090, "warn"),
+			wantWarnings: 1,
+		},
+		{
+			name:     "port of rule in other namespace is rejected with error policy",
+			rule:     newTestRule("new", "default", 9090, "error"),
+			errorMsg: "already claimed by PortForwardRule other/remote",
+		},
+		{
+			name: "port of rule in other namespace is allowed with ignore policy",
+			rule: newTestRule("new", "default", 9090, "ignore"),
+		},
+		{
+			name:     "any port of a multi-port rule is checked",
+			rule:     newMultiPortTestRule("new", "default", 7070, 8080),
+			errorMsg: "already claimed by PortForwardRule default/existing",
+		},
+		{
+			name: "multi-port rule with free ports is allowed",
+			rule: newMultiPortTestRule("new", "default", 7070, 7071),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := validator.ValidateCreate(ctx, tt.rule)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected rule to be allowed, got %v", err)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Expected %d warnings, got %v", tt.wantWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestPortForwardRuleValidator_ValidateUpdate(t *testing.T) {
+	ctx := context.Background()
+	fakeClient := newTestClient(t)
+	fakeClient.Rules["default/existing"] = newTestRule("existing", "default", 8080, "warn")
+	fakeClient.Rules["default/duplicate"] = newTestRule("duplicate", "default", 8080, "warn")
+	validator := &PortForwardRuleValidator{Client: fakeClient}
+
+	// A conflict that predates the webhook must not block finalizer updates
+	oldRule := newTestRule("duplicate", "default", 8080, "warn")
+	newRule := oldRule.DeepCopy()
+	newRule.Finalizers = []string{"unifi-port-forward.fiskhe.st/router-rule-protection"}
+	if _, err := validator.ValidateUpdate(ctx, oldRule, newRule); err != nil {
+		t.Errorf("Expected update without port change to be allowed, got %v", err)
+	}
+
+	movedRule := newRule.DeepCopy()
+	movedRule.Name = "moved"
+	oldMovedRule := newTestRule("moved", "default", 7070, "warn")
+	if _, err := validator.ValidateUpdate(ctx, oldMovedRule, movedRule); err == nil {
+		t.Error("Expected update moving onto a claimed port to be rejected")
+	}
+}
+
+func TestClusterPortForwardRuleValidator_ValidateCreate(t *testing.T) {
+	ctx := context.Background()
+	fakeClient := newTestClient(t)
+	fakeClient.Rules["default/existing"] = newTestRule("existing", "default", 8080, "warn")
+	fakeClient.ClusterRules["ingress"] = &v1alpha1.ClusterPortForwardRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress"},
+		Spec:       v1alpha1.PortForwardRuleSpec{ExternalPort: 443, Protocol: "tcp"},
+	}
+	validator := &ClusterPortForwardRuleValidator{Client: fakeClient}
+
+	newClusterRule := func(port int) *v1alpha1.ClusterPortForwardRule {
+		rule := newTestRule("platform", "", port, "warn")
+		return &v1alpha1.ClusterPortForwardRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+			Spec:       rule.Spec,
+		}
+	}
+
+	if _, err := validator.ValidateCreate(ctx, newClusterRule(443)); err == nil {
+		t.Error("Expected port of another cluster rule to be rejected")
+	}
+
+	warnings, err := validator.ValidateCreate(ctx, newClusterRule(8080))
+	if err != nil {
+		t.Fatalf("Expected port of a namespaced rule to be allowed, got %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("Expected a warning about PortForwardRule default/existing, got %v", warnings)
+	}
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/pkg/helpers"
+	"unifi-port-forward/pkg/schedule"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate--v1-service,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=services,verbs=create;update,versions=v1,name=vservice.unifi-port-forward.fiskhe.st,admissionReviewVersions=v1
+
+var serviceKind = corev1.SchemeGroupVersion.WithKind("Service").GroupKind()
+
+// ServiceValidator rejects Services whose port mapping annotation is invalid, references
+// missing service ports, requests external ports already claimed by another resource or
+// forbidden by a PortForwardPolicy, or whose schedule or expiry annotations are invalid. Services without the mapping annotation are
+// allowed unless their approved annotation is set by a user that may not approve them.
+type ServiceValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &ServiceValidator{}
+
+// ValidateCreate validates a new Service
+func (v *ServiceValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		return nil, fmt.Errorf("expected a Service but got %T", obj)
+	}
+
+	if err := authorizeApproval(ctx, v.Client, nil, service, serviceResource); err != nil {
+		return nil, err
+	}
+	if _, exists := service.Annotations[config.FilterAnnotation]; !exists {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, service)
+}
+
+// ValidateUpdate validates a changed Service. Only changes to the mapping, schedule or expiry
+// annotations or the service ports are validated, so unrelated updates such as the
+// controller's own annotations are never rejected.
+func (v *ServiceValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	service, ok := newObj.(*corev1.Service)
+	if !ok {
+		return nil, fmt.Errorf("expected a Service but got %T", newObj)
+	}
+	oldService, ok := oldObj.(*corev1.Service)
+	if !ok {
+		return nil, fmt.Errorf("expected a Service but got %T", oldObj)
+	}
+
+	if err := authorizeApproval(ctx, v.Client, oldService, service, serviceResource); err != nil {
+		return nil, err
+	}
+
+	annotation, exists := service.Annotations[config.FilterAnnotation]
+	if !exists || !service.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	oldAnnotation, oldExists := oldService.Annotations[config.FilterAnnotation]
+	if oldExists && annotation == oldAnnotation && reflect.DeepEqual(service.Spec.Ports, oldService.Spec.Ports) &&
+		!scheduleChanged(oldService, service) && !expiryChanged(oldService, service) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, service)
+}
+
+// ValidateDelete allows every deletion
+func (v *ServiceValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the mapping annotation against the service ports, existing port claims and
+// PortForwardPolicies
+func (v *ServiceValidator) validate(ctx context.Context, service *corev1.Service) error {
+	if errs := validateScheduleAnnotation(service); len(errs) > 0 {
+		return apierrors.NewInvalid(serviceKind, service.Name, errs)
+	}
+	if errs := validateExpiryAnnotations(service); len(errs) > 0 {
+		return apierrors.NewInvalid(serviceKind, service.Name, errs)
+	}
+
+	annotationPath := field.NewPath("metadata", "annotations").Key(config.FilterAnnotation)
+
+	portConfigs, err := helpers.BuildPortConfigs(service, "", config.FilterAnnotation)
+	if err != nil {
+		return apierrors.NewInvalid(serviceKind, service.Name, field.ErrorList{
+			field.Invalid(annotationPath, service.Annotations[config.FilterAnnotation], err.Error()),
+		})
+	}
+
+	claims, err := listPortClaims(ctx, v.Client)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	var allErrs field.ErrorList
+	for _, portConfig := range portConfigs {
+		for _, claim := range claims {
+			if claim.isOwnedBy(claimKindService, service.Namespace, service.Name) {
+				continue
+			}
+			// The controller tracks annotated Services by external port alone
+			if claim.Kind == claimKindService && claim.Port != portConfig.DstPort {
+				continue
+			}
+			if claim.Kind != claimKindService && !claim.overlaps(portConfig.DstPort, portConfig.Protocol) {
+				continue
+			}
+			allErrs = append(allErrs, field.Forbidden(annotationPath,
+				fmt.Sprintf("external port %d is already claimed by %s", portConfig.DstPort, claim.owner())))
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(serviceKind, service.Name, allErrs)
+	}
+	return validatePolicies(ctx, v.Client, helpers.ServicePolicyRequest(service, portConfigs), serviceKind, annotationPath)
+}
+
+// scheduleChanged reports whether the schedule annotations differ between two versions of a service
+func scheduleChanged(oldService, service *corev1.Service) bool {
+	return oldService.Annotations[config.ScheduleAnnotation] != service.Annotations[config.ScheduleAnnotation] ||
+		oldService.Annotations[config.ScheduleTimeZoneAnnotation] != service.Annotations[config.ScheduleTimeZoneAnnotation]
+}
+
+// validateScheduleAnnotation checks the schedule and schedule time zone annotations of a service
+func validateScheduleAnnotation(service *corev1.Service) field.ErrorList {
+	value, exists := service.Annotations[config.ScheduleAnnotation]
+	if !exists {
+		return nil
+	}
+
+	annotationPath := field.NewPath("metadata", "annotations").Key(config.ScheduleAnnotation)
+	windows, err := schedule.ParseWindows(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(annotationPath, value, err.Error())}
+	}
+	if _, err := schedule.New(service.Annotations[config.ScheduleTimeZoneAnnotation], windows); err != nil {
+		return field.ErrorList{field.Invalid(annotationPath, value, err.Error())}
+	}
+	return nil
+}
+
+// expiryChanged reports whether the expiry annotations differ between two versions of a service
+func expiryChanged(oldService, service *corev1.Service) bool {
+	return oldService.Annotations[config.ExpiresAtAnnotation] != service.Annotations[config.ExpiresAtAnnotation] ||
+		oldService.Annotations[config.TTLAnnotation] != service.Annotations[config.TTLAnnotation]
+}
+
+// validateExpiryAnnotations checks the expires-at and ttl annotations of a service
+func validateExpiryAnnotations(service *corev1.Service) field.ErrorList {
+	if _, err := helpers.GetExpiry(service, config.ExpiresAtAnnotation, config.TTLAnnotation); err != nil {
+		key := config.ExpiresAtAnnotation
+		if _, hasTTL := service.Annotations[config.TTLAnnotation]; hasTTL {
+			key = config.TTLAnnotation
+		}
+		return field.ErrorList{field.Invalid(field.NewPath("metadata", "annotations").Key(key), service.Annotations[key], err.Error())}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"unifi-port-forward/pkg/config"
+	"unifi-port-forward/testutils"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestServiceValidator_ValidateCreate(t *testing.T) {
+	ctx := context.Background()
+	fakeClient := newTestClient(t)
+	fakeClient.Services["default/existing"] = testutils.CreateTestMultiPortService("existing", "default",
+		[]testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}, "192.168.1.10", "8080:http")
+	fakeClient.Rules["default/rule"] = newTestRule("rule", "default", 9090, "warn")
+	validator := &ServiceValidator{Client: fakeClient}
+
+	ports := []testutils.TestPort{
+		{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP},
+		{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP},
+	}
+
+	tests := []struct {
+		name       string
+		annotation string
+		errorMsg   string
+	}{
+		{
+			name:       "valid mapping is allowed",
+			annotation: "8081:http,8443:https",
+		},
+		{
+			name:       "missing service port is rejected",
+			annotation: "8081:grpc",
+			errorMsg:   "non-existent port 'grpc'",
+		},
+		{
+			name:       "malformed mapping is rejected",
+			annotation: "abc:http",
+			errorMsg:   "invalid external port 'abc'",
+		},
+		{
+			name:       "port of another service is rejected",
+			annotation: "8080:http",
+			errorMsg:   "already claimed by Service default/existing",
+		},
+		{
+			name:       "port of a PortForwardRule is rejected",
+			annotation: "9090:https",
+			errorMsg:   "already claimed by PortForwardRule default/rule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testutils.CreateTestMultiPortService("web", "default", ports, "192.168.1.20", tt.annotation)
+			_, err := validator.ValidateCreate(ctx, service)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected service to be allowed, got %v", err)
+			}
+		})
+	}
+
+	// Services without the mapping annotation are not validated
+	unannotated := testutils.CreateTestMultiPortService("plain", "default", ports, "192.168.1.30", "")
+	if _, err := validator.ValidateCreate(ctx, unannotated); err != nil {
+		t.Errorf("Expected service without annotation to be allowed, got %v", err)
+	}
+}
+
+func TestServiceValidator_ValidateUpdate_IgnoresUnrelatedChanges(t *testing.T) {
+	ctx := context.Background()
+	fakeClient := newTestClient(t)
+	fakeClient.Services["default/existing"] = testutils.CreateTestMultiPortService("existing", "default",
+		[]testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}, "192.168.1.10", "8080:http")
+	validator := &ServiceValidator{Client: fakeClient}
+
+	// Another service that already conflicts, e.g. created before the webhook was installed
+	oldService := testutils.CreateTestMultiPortService("web", "default",
+		[]testutils.TestPort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}, "192.168.1.20", "8080:http")
+	newService := oldService.DeepCopy()
+	newService.Annotations[config.RuleIDsAnnotation] = `{"default/web:http":"abc"}`
+
+	if _, err := validator.ValidateUpdate(ctx, oldService, newService); err != nil {
+		t.Errorf("Expected unrelated update to be allowed, got %v", err)
+	}
+
+	newService.Annotations[config.FilterAnnotation] = "8080:missing"
+	if _, err := validator.ValidateUpdate(ctx, oldService, newService); err == nil {
+		t.Error("Expected changed mapping to be validated")
+	}
+}
+
+func TestServiceValidator_ValidateSchedule(t *testing.T) {
+	ctx := context.Background()
+	validator := &ServiceValidator{Client: newTestClient(t)}
+	ports := []testutils.TestPort{{Name: "rdp", Port: 3389, Protocol: corev1.ProtocolTCP}}
+
+	tests := []struct {
+		name     string
+		schedule string
+		timeZone string
+		errorMsg string
+	}{
+		{name: "valid schedule is allowed", schedule: "0 8 * * mon-fri 9h; 0 18 * * fri 54h", timeZone: "Europe/Stockholm"},
+		{name: "invalid cron is rejected", schedule: "0 25 * * * 1h", errorMsg: "invalid hour field"},
+		{name: "missing duration is rejected", schedule: "0 8 * * mon-fri", errorMsg: "followed by a duration"},
+		{name: "unknown time zone is rejected", schedule: "0 8 * * * 1h", timeZone: "Mars/Olympus", errorMsg: "invalid time zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testutils.CreateTestMultiPortService("rdp", "default", ports, "192.168.1.40", "3389:rdp")
+			service.Annotations[config.ScheduleAnnotation] = tt.schedule
+			if tt.timeZone != "" {
+				service.Annotations[config.ScheduleTimeZoneAnnotation] = tt.timeZone
+			}
+
+			_, err := validator.ValidateCreate(ctx, service)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected service to be allowed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServiceValidator_ValidateExpiry(t *testing.T) {
+	ctx := context.Background()
+	validator := &ServiceValidator{Client: newTestClient(t)}
+	ports := []testutils.TestPort{{Name: "ssh", Port: 22, Protocol: corev1.ProtocolTCP}}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		errorMsg    string
+	}{
+		{name: "expires-at is allowed", annotations: map[string]string{config.ExpiresAtAnnotation: "2026-03-04T18:00:00Z"}},
+		{name: "ttl is allowed", annotations: map[string]string{config.TTLAnnotation: "2h"}},
+		{name: "invalid expires-at is rejected", annotations: map[string]string{config.ExpiresAtAnnotation: "tomorrow"}, errorMsg: config.ExpiresAtAnnotation},
+		{name: "negative ttl is rejected", annotations: map[string]string{config.TTLAnnotation: "-1h"}, errorMsg: config.TTLAnnotation},
+		{
+			name:        "expires-at and ttl are rejected",
+			annotations: map[string]string{config.ExpiresAtAnnotation: "2026-03-04T18:00:00Z", config.TTLAnnotation: "2h"},
+			errorMsg:    "mutually exclusive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testutils.CreateTestMultiPortService("ssh", "default", ports, "192.168.1.41", "2222:ssh")
+			for key, value := range tt.annotations {
+				service.Annotations[key] = value
+			}
+
+			_, err := validator.ValidateCreate(ctx, service)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected service to be allowed, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"unifi-port-forward/pkg/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Paths the webhooks are served on, matching manifests/webhook
+const (
+	PortForwardRulePath        = "/validate-unifi-port-forward-fiskhe-st-v1alpha1-portforwardrule"
+	ClusterPortForwardRulePath = "/validate-unifi-port-forward-fiskhe-st-v1alpha1-clusterportforwardrule"
+	ServicePath                = "/validate--v1-service"
+	ConversionPath             = "/convert"
+
+	PortForwardRuleApprovalPath = "/mutate-unifi-port-forward-fiskhe-st-v1alpha1-portforwardrule"
+	ServiceApprovalPath         = "/mutate--v1-service"
+
+	// ValidatingWebhookConfigurationName and MutatingWebhookConfigurationName are the names of
+	// the webhook configurations whose caBundle is kept in sync with self-signed serving
+	// certificates
+	ValidatingWebhookConfigurationName = "unifi-port-forward-validating-webhook"
+	MutatingWebhookConfigurationName   = "unifi-port-forward-approval-webhook"
+)
+
+// SetupWithManager registers the validating, approval signing and conversion webhooks with the
+// manager's webhook server. The ClusterPortForwardRule webhook is only registered when its CRD
+// is installed. Approvals are signed with approvalKey.
+func SetupWithManager(mgr ctrl.Manager, clusterRulesEnabled bool, approvalKey []byte) {
+	server := mgr.GetWebhookServer()
+	scheme := mgr.GetScheme()
+
+	server.Register(PortForwardRulePath, admission.WithCustomValidator(scheme,
+		&v1alpha1.PortForwardRule{}, &PortForwardRuleValidator{Client: mgr.GetClient()}))
+
+	if clusterRulesEnabled {
+		server.Register(ClusterPortForwardRulePath, admission.WithCustomValidator(scheme,
+			&v1alpha1.ClusterPortForwardRule{}, &ClusterPortForwardRuleValidator{Client: mgr.GetClient()}))
+	}
+
+	server.Register(ServicePath, admission.WithCustomValidator(scheme,
+		&corev1.Service{}, &ServiceValidator{Client: mgr.GetClient()}))
+
+	signer := &ApprovalSigner{Client: mgr.GetClient(), Key: approvalKey}
+	server.Register(PortForwardRuleApprovalPath, &admission.Webhook{Handler: signer})
+	server.Register(ServiceApprovalPath, &admission.Webhook{Handler: signer})
+
+	// Converts PortForwardRules and ClusterPortForwardRules between v1alpha1 and v1beta1
+	server.Register(ConversionPath, conversion.NewWebhookHandler(scheme))
+}
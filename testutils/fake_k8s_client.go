@@ -4,34 +4,129 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"slices"
 	"sync"
 	"testing"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	client "sigs.k8s.io/controller-runtime/pkg/client"
+	"unifi-port-forward/pkg/api/v1alpha1"
 	"unifi-port-forward/pkg/config"
 )
 
 // FakeKubernetesClient simulates Kubernetes operations for testing
 type FakeKubernetesClient struct {
 	Services map[string]*v1.Service
-	mu       sync.RWMutex
-	scheme   *runtime.Scheme
+	Rules    map[string]*v1alpha1.PortForwardRule
+	Grants   map[string]*v1alpha1.PortForwardReferenceGrant
+
+	// EndpointSlices are keyed by namespace/name like Services
+	EndpointSlices map[string]*discoveryv1.EndpointSlice
+
+	// ClusterRules are keyed by name since ClusterPortForwardRules are cluster-scoped
+	ClusterRules map[string]*v1alpha1.ClusterPortForwardRule
+
+	// Policies and Namespaces are keyed by name since they are cluster-scoped
+	Policies   map[string]*v1alpha1.PortForwardPolicy
+	Namespaces map[string]*v1.Namespace
+
+	// CRDs are keyed by name; CustomResourceDefinitionList needs them set to be listed
+	CRDs map[string]*apiextensionsv1.CustomResourceDefinition
+
+	// WebhookConfigurations and MutatingWebhookConfigurations are keyed by name
+	WebhookConfigurations         map[string]*admissionregistrationv1.ValidatingWebhookConfiguration
+	MutatingWebhookConfigurations map[string]*admissionregistrationv1.MutatingWebhookConfiguration
+
+	// Authorize answers SubjectAccessReviews and SelfSubjectAccessReviews; nil denies every review
+	Authorize func(spec authorizationv1.SubjectAccessReviewSpec) bool
+
+	// Authenticate answers TokenReviews with the user and audiences of a token; nil rejects every
+	// token. Like the API server, a review asking for audiences the token lacks is rejected.
+	Authenticate func(token string) (authenticationv1.UserInfo, []string, bool)
+
+	mu     sync.RWMutex
+	scheme *runtime.Scheme
+
+	// ruleIndexers and clusterRuleIndexers hold field indexes registered through IndexField
+	ruleIndexers        map[string]client.IndexerFunc
+	clusterRuleIndexers map[string]client.IndexerFunc
 }
 
 // NewFakeKubernetesClient creates a new fake Kubernetes client
 func NewFakeKubernetesClient(t *testing.T, scheme *runtime.Scheme) *FakeKubernetesClient {
 	return &FakeKubernetesClient{
 		Services: make(map[string]*v1.Service),
+		Rules:    make(map[string]*v1alpha1.PortForwardRule),
+		Grants:   make(map[string]*v1alpha1.PortForwardReferenceGrant),
 		mu:       sync.RWMutex{},
 		scheme:   scheme,
+
+		ClusterRules: make(map[string]*v1alpha1.ClusterPortForwardRule),
+		Policies:     make(map[string]*v1alpha1.PortForwardPolicy),
+		Namespaces:   make(map[string]*v1.Namespace),
+		CRDs:         make(map[string]*apiextensionsv1.CustomResourceDefinition),
+
+		WebhookConfigurations:         make(map[string]*admissionregistrationv1.ValidatingWebhookConfiguration),
+		MutatingWebhookConfigurations: make(map[string]*admissionregistrationv1.MutatingWebhookConfiguration),
+
+		EndpointSlices: make(map[string]*discoveryv1.EndpointSlice),
+
+		ruleIndexers:        make(map[string]client.IndexerFunc),
+		clusterRuleIndexers: make(map[string]client.IndexerFunc),
+	}
+}
+
+// IndexField implements controller-runtime client.FieldIndexer for PortForwardRule and ClusterPortForwardRule objects
+func (f *FakeKubernetesClient) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch obj.(type) {
+	case *v1alpha1.PortForwardRule:
+		f.ruleIndexers[field] = extractValue
+	case *v1alpha1.ClusterPortForwardRule:
+		f.clusterRuleIndexers[field] = extractValue
+	default:
+		return fmt.Errorf("fake client only supports field indexes on PortForwardRule and ClusterPortForwardRule objects")
+	}
+	return nil
+}
+
+// matchesFields reports whether the object satisfies every exact-match field requirement
+func matchesFields(obj client.Object, indexers map[string]client.IndexerFunc, listOpts *client.ListOptions) (bool, error) {
+	if listOpts.FieldSelector == nil {
+		return true, nil
 	}
+
+	for _, requirement := range listOpts.FieldSelector.Requirements() {
+		extractValue, ok := indexers[requirement.Field]
+		if !ok {
+			return false, fmt.Errorf("field selector %q is not indexed", requirement.Field)
+		}
+		matched := false
+		for _, value := range extractValue(obj) {
+			if value == requirement.Value {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
 // Get implements controller-runtime client.Client interface
@@ -39,6 +134,51 @@ func (f *FakeKubernetesClient) Get(ctx context.Context, key client.ObjectKey, ob
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
+	if rule, ok := obj.(*v1alpha1.PortForwardRule); ok {
+		existing, exists := f.Rules[key.String()]
+		if !exists {
+			return errors.NewNotFound(v1alpha1.SchemeGroupVersion.WithResource("portforwardrules").GroupResource(), key.Name)
+		}
+		existing.DeepCopyInto(rule)
+		return nil
+	}
+
+	if clusterRule, ok := obj.(*v1alpha1.ClusterPortForwardRule); ok {
+		existing, exists := f.ClusterRules[key.Name]
+		if !exists {
+			return errors.NewNotFound(v1alpha1.SchemeGroupVersion.WithResource("clusterportforwardrules").GroupResource(), key.Name)
+		}
+		existing.DeepCopyInto(clusterRule)
+		return nil
+	}
+
+	if namespace, ok := obj.(*v1.Namespace); ok {
+		existing, exists := f.Namespaces[key.Name]
+		if !exists {
+			return errors.NewNotFound(v1.Resource("namespaces"), key.Name)
+		}
+		existing.DeepCopyInto(namespace)
+		return nil
+	}
+
+	if webhookConfig, ok := obj.(*admissionregistrationv1.ValidatingWebhookConfiguration); ok {
+		existing, exists := f.WebhookConfigurations[key.Name]
+		if !exists {
+			return errors.NewNotFound(admissionregistrationv1.Resource("validatingwebhookconfigurations"), key.Name)
+		}
+		existing.DeepCopyInto(webhookConfig)
+		return nil
+	}
+
+	if webhookConfig, ok := obj.(*admissionregistrationv1.MutatingWebhookConfiguration); ok {
+		existing, exists := f.MutatingWebhookConfigurations[key.Name]
+		if !exists {
+			return errors.NewNotFound(admissionregistrationv1.Resource("mutatingwebhookconfigurations"), key.Name)
+		}
+		existing.DeepCopyInto(webhookConfig)
+		return nil
+	}
+
 	service, exists := f.Services[key.String()]
 	if !exists {
 		return errors.NewNotFound(v1.Resource("services"), key.Name)
@@ -58,9 +198,74 @@ func (f *FakeKubernetesClient) Create(ctx context.Context, obj client.Object, op
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if rule, ok := obj.(*v1alpha1.PortForwardRule); ok {
+		f.Rules[fmt.Sprintf("%s/%s", rule.Namespace, rule.Name)] = rule.DeepCopy()
+		return nil
+	}
+
+	if clusterRule, ok := obj.(*v1alpha1.ClusterPortForwardRule); ok {
+		f.ClusterRules[clusterRule.Name] = clusterRule.DeepCopy()
+		return nil
+	}
+
+	if grant, ok := obj.(*v1alpha1.PortForwardReferenceGrant); ok {
+		f.Grants[fmt.Sprintf("%s/%s", grant.Namespace, grant.Name)] = grant.DeepCopy()
+		return nil
+	}
+
+	if policy, ok := obj.(*v1alpha1.PortForwardPolicy); ok {
+		f.Policies[policy.Name] = policy.DeepCopy()
+		return nil
+	}
+
+	if namespace, ok := obj.(*v1.Namespace); ok {
+		f.Namespaces[namespace.Name] = namespace.DeepCopy()
+		return nil
+	}
+
+	if slice, ok := obj.(*discoveryv1.EndpointSlice); ok {
+		f.EndpointSlices[fmt.Sprintf("%s/%s", slice.Namespace, slice.Name)] = slice.DeepCopy()
+		return nil
+	}
+
+	if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+		review.Status.Allowed = f.Authorize != nil && f.Authorize(review.Spec)
+		return nil
+	}
+
+	if review, ok := obj.(*authenticationv1.TokenReview); ok {
+		if f.Authenticate == nil {
+			return nil
+		}
+		user, audiences, authenticated := f.Authenticate(review.Spec.Token)
+		if authenticated && len(review.Spec.Audiences) > 0 {
+			var granted []string
+			for _, audience := range review.Spec.Audiences {
+				if slices.Contains(audiences, audience) {
+					granted = append(granted, audience)
+				}
+			}
+			audiences, authenticated = granted, len(granted) > 0
+		}
+		if authenticated {
+			review.Status.User, review.Status.Audiences, review.Status.Authenticated = user, audiences, true
+		}
+		return nil
+	}
+
+	// SelfSubjectAccessReviews are answered like SubjectAccessReviews of the calling user
+	if review, ok := obj.(*authorizationv1.SelfSubjectAccessReview); ok {
+		spec := authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes:    review.Spec.ResourceAttributes,
+			NonResourceAttributes: review.Spec.NonResourceAttributes,
+		}
+		review.Status.Allowed = f.Authorize != nil && f.Authorize(spec)
+		return nil
+	}
+
 	service, ok := obj.(*v1.Service)
 	if !ok {
-		return fmt.Errorf("fake client only supports Service objects")
+		return fmt.Errorf("fake client only supports Service, Namespace, EndpointSlice, PortForwardRule, ClusterPortForwardRule, PortForwardReferenceGrant, PortForwardPolicy, SubjectAccessReview, SelfSubjectAccessReview and TokenReview objects")
 	}
 
 	// Store a deep copy to avoid reference issues
@@ -74,9 +279,19 @@ func (f *FakeKubernetesClient) Update(ctx context.Context, obj client.Object, op
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if rule, ok := obj.(*v1alpha1.PortForwardRule); ok {
+		f.Rules[fmt.Sprintf("%s/%s", rule.Namespace, rule.Name)] = rule.DeepCopy()
+		return nil
+	}
+
+	if clusterRule, ok := obj.(*v1alpha1.ClusterPortForwardRule); ok {
+		f.ClusterRules[clusterRule.Name] = clusterRule.DeepCopy()
+		return nil
+	}
+
 	service, ok := obj.(*v1.Service)
 	if !ok {
-		return fmt.Errorf("fake client only supports Service objects")
+		return fmt.Errorf("fake client only supports Service, PortForwardRule and ClusterPortForwardRule objects")
 	}
 
 	// Store a deep copy to avoid reference issues
@@ -90,9 +305,58 @@ func (f *FakeKubernetesClient) Delete(ctx context.Context, obj client.Object, op
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if rule, ok := obj.(*v1alpha1.PortForwardRule); ok {
+		delete(f.Rules, fmt.Sprintf("%s/%s", rule.Namespace, rule.Name))
+		return nil
+	}
+
+	if clusterRule, ok := obj.(*v1alpha1.ClusterPortForwardRule); ok {
+		delete(f.ClusterRules, clusterRule.Name)
+		return nil
+	}
+
+	if grant, ok := obj.(*v1alpha1.PortForwardReferenceGrant); ok {
+		delete(f.Grants, fmt.Sprintf("%s/%s", grant.Namespace, grant.Name))
+		return nil
+	}
+
+	if policy, ok := obj.(*v1alpha1.PortForwardPolicy); ok {
+		delete(f.Policies, policy.Name)
+		return nil
+	}
+
+	if slice, ok := obj.(*discoveryv1.EndpointSlice); ok {
+		delete(f.EndpointSlices, fmt.Sprintf("%s/%s", slice.Namespace, slice.Name))
+		return nil
+	}
+
+	if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+		if _, exists := f.CRDs[crd.Name]; !exists {
+			return errors.NewNotFound(apiextensionsv1.Resource("customresourcedefinitions"), crd.Name)
+		}
+		delete(f.CRDs, crd.Name)
+		return nil
+	}
+
+	if webhookConfig, ok := obj.(*admissionregistrationv1.ValidatingWebhookConfiguration); ok {
+		if _, exists := f.WebhookConfigurations[webhookConfig.Name]; !exists {
+			return errors.NewNotFound(admissionregistrationv1.Resource("validatingwebhookconfigurations"), webhookConfig.Name)
+		}
+		delete(f.WebhookConfigurations, webhookConfig.Name)
+		return nil
+	}
+
+	if webhookConfig, ok := obj.(*admissionregistrationv1.MutatingWebhookConfiguration); ok {
+		if _, exists := f.MutatingWebhookConfigurations[webhookConfig.Name]; !exists {
+			return errors.NewNotFound(admissionregistrationv1.Resource("mutatingwebhookconfigurations"), webhookConfig.Name)
+		}
+		delete(f.MutatingWebhookConfigurations, webhookConfig.Name)
+		return nil
+	}
+
 	service, ok := obj.(*v1.Service)
 	if !ok {
-		return fmt.Errorf("fake client only supports Service objects")
+		return fmt.Errorf("fake client only supports Service, EndpointSlice, PortForwardRule, ClusterPortForwardRule, PortForwardReferenceGrant, PortForwardPolicy, CustomResourceDefinition, ValidatingWebhookConfiguration and MutatingWebhookConfiguration objects")
 	}
 
 	key := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
@@ -105,19 +369,83 @@ func (f *FakeKubernetesClient) List(ctx context.Context, list client.ObjectList,
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
-	serviceList, ok := list.(*v1.ServiceList)
-	if !ok {
-		return fmt.Errorf("fake client only supports ServiceList")
-	}
-
-	services := make([]v1.Service, 0, len(f.Services))
-	i := 0
-	for _, service := range f.Services {
-		services[i] = *service
-		i++
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	switch typedList := list.(type) {
+	case *v1.ServiceList:
+		services := make([]v1.Service, 0, len(f.Services))
+		for _, service := range f.Services {
+			if listOpts.Namespace != "" && service.Namespace != listOpts.Namespace {
+				continue
+			}
+			services = append(services, *service.DeepCopy())
+		}
+		typedList.Items = services
+	case *v1alpha1.PortForwardRuleList:
+		rules := make([]v1alpha1.PortForwardRule, 0, len(f.Rules))
+		for _, rule := range f.Rules {
+			if listOpts.Namespace != "" && rule.Namespace != listOpts.Namespace {
+				continue
+			}
+			matches, err := matchesFields(rule, f.ruleIndexers, listOpts)
+			if err != nil {
+				return err
+			}
+			if matches {
+				rules = append(rules, *rule.DeepCopy())
+			}
+		}
+		typedList.Items = rules
+	case *v1alpha1.ClusterPortForwardRuleList:
+		clusterRules := make([]v1alpha1.ClusterPortForwardRule, 0, len(f.ClusterRules))
+		for _, clusterRule := range f.ClusterRules {
+			matches, err := matchesFields(clusterRule, f.clusterRuleIndexers, listOpts)
+			if err != nil {
+				return err
+			}
+			if matches {
+				clusterRules = append(clusterRules, *clusterRule.DeepCopy())
+			}
+		}
+		typedList.Items = clusterRules
+	case *v1alpha1.PortForwardReferenceGrantList:
+		grants := make([]v1alpha1.PortForwardReferenceGrant, 0, len(f.Grants))
+		for _, grant := range f.Grants {
+			if listOpts.Namespace != "" && grant.Namespace != listOpts.Namespace {
+				continue
+			}
+			grants = append(grants, *grant.DeepCopy())
+		}
+		typedList.Items = grants
+	case *v1alpha1.PortForwardPolicyList:
+		policies := make([]v1alpha1.PortForwardPolicy, 0, len(f.Policies))
+		for _, policy := range f.Policies {
+			policies = append(policies, *policy.DeepCopy())
+		}
+		typedList.Items = policies
+	case *discoveryv1.EndpointSliceList:
+		slices := make([]discoveryv1.EndpointSlice, 0, len(f.EndpointSlices))
+		for _, slice := range f.EndpointSlices {
+			if listOpts.Namespace != "" && slice.Namespace != listOpts.Namespace {
+				continue
+			}
+			if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(slice.Labels)) {
+				continue
+			}
+			slices = append(slices, *slice.DeepCopy())
+		}
+		typedList.Items = slices
+	case *apiextensionsv1.CustomResourceDefinitionList:
+		crds := make([]apiextensionsv1.CustomResourceDefinition, 0, len(f.CRDs))
+		for _, crd := range f.CRDs {
+			crds = append(crds, *crd.DeepCopy())
+		}
+		typedList.Items = crds
+	default:
+		return fmt.Errorf("fake client only supports ServiceList, EndpointSliceList, PortForwardRuleList, ClusterPortForwardRuleList, PortForwardReferenceGrantList, PortForwardPolicyList and CustomResourceDefinitionList")
 	}
 
-	serviceList.Items = services
 	return nil
 }
 
@@ -126,9 +454,26 @@ func (f *FakeKubernetesClient) Patch(ctx context.Context, obj client.Object, pat
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if rule, ok := obj.(*v1alpha1.PortForwardRule); ok {
+		key := fmt.Sprintf("%s/%s", rule.Namespace, rule.Name)
+		if _, exists := f.Rules[key]; !exists {
+			return errors.NewNotFound(v1alpha1.SchemeGroupVersion.WithResource("portforwardrules").GroupResource(), rule.Name)
+		}
+		f.Rules[key] = rule.DeepCopy()
+		return nil
+	}
+
+	if clusterRule, ok := obj.(*v1alpha1.ClusterPortForwardRule); ok {
+		if _, exists := f.ClusterRules[clusterRule.Name]; !exists {
+			return errors.NewNotFound(v1alpha1.SchemeGroupVersion.WithResource("clusterportforwardrules").GroupResource(), clusterRule.Name)
+		}
+		f.ClusterRules[clusterRule.Name] = clusterRule.DeepCopy()
+		return nil
+	}
+
 	service, ok := obj.(*v1.Service)
 	if !ok {
-		return fmt.Errorf("fake client only supports Service objects")
+		return fmt.Errorf("fake client only supports Service, PortForwardRule and ClusterPortForwardRule objects")
 	}
 
 	// Get existing service to ensure it exists
@@ -269,6 +614,26 @@ func CreateTestServiceWithInvalidAnnotation(name, namespace string, ip string, i
 	}, ip, invalidAnnotation)
 }
 
+// CreateTestEndpointSlice creates an EndpointSlice of a service with one endpoint per entry of
+// ready, each with that ready condition
+func CreateTestEndpointSlice(name, namespace, serviceName string, ready ...bool) *discoveryv1.EndpointSlice {
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{discoveryv1.LabelServiceName: serviceName},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+	}
+	for i, endpointReady := range ready {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{fmt.Sprintf("10.0.0.%d", i+1)},
+			Conditions: discoveryv1.EndpointConditions{Ready: &endpointReady},
+		})
+	}
+	return slice
+}
+
 // Additional interface methods (minimal implementations)
 func (f *FakeKubernetesClient) Scheme() *runtime.Scheme {
 	return f.scheme
@@ -19,6 +19,7 @@ type MockRouter struct {
 	failCount         int
 	callCount         map[string]int
 	simulatedFailures map[string]bool
+	lastID            int
 
 	// DeletePortForwardByID tracking for tests
 	DeletePortForwardByIDCalled bool
@@ -76,9 +77,38 @@ func (r *MockRouter) AddPort(ctx context.Context, config routers.PortConfig) err
 		return fmt.Errorf("simulated AddPort failure")
 	}
 
+	_, err := r.addPortLocked(config)
+	return err
+}
+
+// CreatePort implements routers.Router.CreatePort
+func (r *MockRouter) CreatePort(ctx context.Context, config routers.PortConfig) (*unifi.PortForward, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callCount["CreatePort"]++
+
+	if r.shouldFail || r.ShouldOperationFail("CreatePort") {
+		r.failCount++
+		return nil, fmt.Errorf("simulated CreatePort failure")
+	}
+
+	return r.addPortLocked(config)
+}
+
+// nextIDLocked returns a rule ID that is unique within the mock router
+func (r *MockRouter) nextIDLocked() string {
+	r.lastID++
+	if r.lastID <= len(r.PortForwards) {
+		r.lastID = len(r.PortForwards) + 1
+	}
+	return fmt.Sprintf("mock-id-%d", r.lastID)
+}
+
+// addPortLocked stores a new rule; callers must hold the lock
+func (r *MockRouter) addPortLocked(config routers.PortConfig) (*unifi.PortForward, error) {
 	// Convert to unifi.PortForward format for internal storage
 	pf := unifi.PortForward{
-		ID:            fmt.Sprintf("mock-id-%d", len(r.PortForwards)+1),
+		ID:            r.nextIDLocked(),
 		Name:          config.Name,
 		DestinationIP: "any",
 		DstPort:       strconv.Itoa(config.DstPort),
@@ -88,18 +118,111 @@ func (r *MockRouter) AddPort(ctx context.Context, config routers.PortConfig) err
 		Enabled:       config.Enabled,
 		PfwdInterface: config.Interface,
 		Src:           config.SrcIP,
+		Log:           config.Log,
 	}
 
 	// Check if port already exists
 	for _, existing := range r.PortForwards {
 		if existing.DstPort == strconv.Itoa(config.DstPort) && existing.DestinationIP == config.DstIP {
-			return fmt.Errorf("port %d to %s already exists", config.DstPort, config.DstIP)
+			return nil, fmt.Errorf("port %d to %s already exists", config.DstPort, config.DstIP)
 		}
 	}
 
 	// Add new rule
 	r.PortForwards = append(r.PortForwards, pf)
-	return nil
+	return &pf, nil
+}
+
+// GetPortForwardByID implements routers.Router.GetPortForwardByID
+func (r *MockRouter) GetPortForwardByID(ctx context.Context, ruleID string) (*unifi.PortForward, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callCount["GetPortForwardByID"]++
+
+	if r.shouldFail || r.ShouldOperationFail("GetPortForwardByID") {
+		r.failCount++
+		return nil, false, fmt.Errorf("simulated GetPortForwardByID failure")
+	}
+
+	for _, pf := range r.PortForwards {
+		if pf.ID == ruleID {
+			return &pf, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// UpdatePortByID implements routers.Router.UpdatePortByID
+func (r *MockRouter) UpdatePortByID(ctx context.Context, ruleID string, config routers.PortConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callCount["UpdatePortByID"]++
+
+	if r.shouldFail || r.ShouldOperationFail("UpdatePortByID") {
+		r.failCount++
+		return fmt.Errorf("simulated UpdatePortByID failure")
+	}
+
+	for i, pf := range r.PortForwards {
+		if pf.ID == ruleID {
+			r.PortForwards[i] = unifi.PortForward{
+				ID:            pf.ID,
+				Name:          config.Name,
+				DestinationIP: "any",
+				DstPort:       strconv.Itoa(config.DstPort),
+				Fwd:           config.DstIP,
+				FwdPort:       strconv.Itoa(config.FwdPort),
+				Proto:         config.Protocol,
+				Enabled:       config.Enabled,
+				PfwdInterface: config.Interface,
+				Src:           config.SrcIP,
+				Log:           config.Log,
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("port forward rule %s: %w", ruleID, routers.ErrRuleNotFound)
+}
+
+// CreatePortForward stores a copy of pf under a new ID, like UnifiRouter.CreatePortForward
+func (r *MockRouter) CreatePortForward(ctx context.Context, pf *unifi.PortForward) (*unifi.PortForward, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callCount["CreatePortForward"]++
+
+	if r.shouldFail || r.ShouldOperationFail("CreatePortForward") {
+		r.failCount++
+		return nil, fmt.Errorf("simulated CreatePortForward failure")
+	}
+
+	created := *pf
+	created.ID = r.nextIDLocked()
+	r.PortForwards = append(r.PortForwards, created)
+	return &created, nil
+}
+
+// UpdatePortForward replaces the rule with the ID of pf, like UnifiRouter.UpdatePortForward
+func (r *MockRouter) UpdatePortForward(ctx context.Context, pf *unifi.PortForward) (*unifi.PortForward, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callCount["UpdatePortForward"]++
+
+	if r.shouldFail || r.ShouldOperationFail("UpdatePortForward") {
+		r.failCount++
+		return nil, fmt.Errorf("simulated UpdatePortForward failure")
+	}
+
+	for i := range r.PortForwards {
+		if r.PortForwards[i].ID == pf.ID {
+			r.PortForwards[i] = *pf
+			updated := *pf
+			return &updated, nil
+		}
+	}
+
+	return nil, fmt.Errorf("port forward rule %s: %w", pf.ID, routers.ErrRuleNotFound)
 }
 
 // CheckPort implements routers.Router.CheckPort
@@ -173,6 +296,7 @@ func (r *MockRouter) UpdatePort(ctx context.Context, port int, config routers.Po
 				Enabled:       config.Enabled,
 				PfwdInterface: config.Interface,
 				Src:           config.SrcIP,
+				Log:           config.Log,
 			}
 			return nil
 		}
@@ -387,7 +511,7 @@ func (r *MockRouter) DeletePortForwardByID(ctx context.Context, ruleID string) e
 		}
 	}
 
-	return fmt.Errorf("port forward rule with ID %s not found", ruleID)
+	return fmt.Errorf("port forward rule with ID %s: %w", ruleID, routers.ErrRuleNotFound)
 }
 
 // Fields for testing DeletePortForwardByID